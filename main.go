@@ -11,6 +11,6 @@ func main() {
 	cliApp := cli.NewCLI()
 	if err := cliApp.Run(os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }
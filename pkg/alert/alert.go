@@ -0,0 +1,172 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
+)
+
+// queueSize bounds the alerts waiting for delivery. Alerts beyond it are
+// dropped, a scanner flapping between states must not pile up hooks.
+const queueSize = 64
+
+// Notifier runs the configured hooks when a scanner changes to one of the
+// alerted health states. Hooks run one alert at a time in the background,
+// so a slow webhook doesn't hold up the health checks.
+type Notifier struct {
+	config *config.AlertsConfig
+	mqtt   mqtt.Client
+	client *http.Client
+	logger *logrus.Logger
+
+	queue  chan *homeassistant.HealthTransition
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewNotifier(cfg *config.AlertsConfig, mqttClient mqtt.Client, logger *logrus.Logger) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Notifier{
+		config: cfg,
+		mqtt:   mqttClient,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+		queue:  make(chan *homeassistant.HealthTransition, queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (n *Notifier) Start() error {
+	n.wg.Add(1)
+	go n.worker()
+	return nil
+}
+
+func (n *Notifier) Stop() error {
+	n.cancel()
+	n.wg.Wait()
+	return nil
+}
+
+// Notify queues the alert hooks for a transition to an alerted state.
+func (n *Notifier) Notify(transition *homeassistant.HealthTransition) {
+	if !slices.Contains(n.config.States, transition.To) {
+		return
+	}
+
+	select {
+	case n.queue <- transition:
+	default:
+		n.logger.WithField("scanner_id", transition.ScannerID).Warn("Alert queue full, dropping health alert")
+	}
+}
+
+func (n *Notifier) worker() {
+	defer n.wg.Done()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case transition := <-n.queue:
+			n.deliver(transition)
+		}
+	}
+}
+
+func (n *Notifier) deliver(transition *homeassistant.HealthTransition) {
+	logger := n.logger.WithFields(map[string]any{
+		"scanner_id": transition.ScannerID,
+		"from":       transition.From,
+		"to":         transition.To,
+	})
+	logger.Warn("Scanner health changed")
+
+	body, err := json.Marshal(transition)
+	if err != nil {
+		logger.WithError(err).Error("Failed to marshal health alert")
+		return
+	}
+
+	if n.config.Webhook != nil {
+		if err := n.sendWebhook(body); err != nil {
+			logger.WithError(err).Error("Failed to send health alert webhook")
+		}
+	}
+	if n.config.MQTTTopic != "" {
+		if err := n.mqtt.Publish(n.config.MQTTTopic, string(body), false); err != nil {
+			logger.WithError(err).Error("Failed to publish health alert")
+		}
+	}
+	if len(n.config.Exec) > 0 {
+		if err := n.runCommand(transition, body); err != nil {
+			logger.WithError(err).Error("Failed to run health alert command")
+		}
+	}
+}
+
+// sendWebhook POSTs the alert, signed like the webhook sinks.
+func (n *Notifier) sendWebhook(body []byte) error {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, n.config.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sink.TimestampHeader, timestamp)
+	if n.config.Webhook.Secret != "" {
+		req.Header.Set(sink.SignatureHeader, sink.Sign(n.config.Webhook.Secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runCommand runs the exec hook with the alert as JSON on stdin and its
+// main fields in the environment, for scripts that don't parse JSON.
+func (n *Notifier) runCommand(transition *homeassistant.HealthTransition, body []byte) error {
+	ctx, cancel := context.WithTimeout(n.ctx, n.config.Timeout)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, n.config.Exec[0], n.config.Exec[1:]...) // #nosec G204 - command from the configuration
+	command.Stdin = bytes.NewReader(body)
+	command.Env = append(os.Environ(),
+		"ALERT_SCANNER_ID="+transition.ScannerID,
+		"ALERT_SCANNER_NAME="+transition.Name,
+		"ALERT_FROM="+transition.From,
+		"ALERT_TO="+transition.To,
+		"ALERT_TIME="+transition.Time.Format(time.RFC3339),
+	)
+
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package alert
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
+)
+
+func newTestTransition(to string) *homeassistant.HealthTransition {
+	return &homeassistant.HealthTransition{
+		ScannerID: "front",
+		Name:      "Front Door",
+		From:      homeassistant.HealthHealthy,
+		To:        to,
+		Time:      time.Now(),
+		Health:    map[string]any{"error_count": 11},
+	}
+}
+
+func TestNotifier_Webhook(t *testing.T) {
+	received := make(chan *homeassistant.HealthTransition, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(sink.SignatureHeader) != sink.Sign("s3cret", r.Header.Get(sink.TimestampHeader), body) {
+			t.Error("Expected request signature to be valid")
+		}
+		var transition homeassistant.HealthTransition
+		if err := json.Unmarshal(body, &transition); err != nil {
+			t.Errorf("Expected JSON alert, got: %v", err)
+		}
+		received <- &transition
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(&config.AlertsConfig{
+		States:  config.DefaultAlertStates,
+		Webhook: &config.AlertWebhookConfig{URL: server.URL, Secret: "s3cret"},
+		Timeout: time.Second,
+	}, nil, logrus.New())
+	if err := notifier.Start(); err != nil {
+		t.Fatalf("Expected no error starting notifier, got: %v", err)
+	}
+	defer func() { _ = notifier.Stop() }()
+
+	notifier.Notify(newTestTransition(homeassistant.HealthDisconnected))
+	notifier.Notify(newTestTransition(homeassistant.HealthDegraded))
+
+	select {
+	case transition := <-received:
+		if transition.To != homeassistant.HealthDegraded || transition.ScannerID != "front" {
+			t.Errorf("Expected only the degraded alert, got %+v", transition)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected webhook to be called")
+	}
+}
+
+func TestNotifier_Exec(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "alert")
+
+	notifier := NewNotifier(&config.AlertsConfig{
+		States:  []string{homeassistant.HealthStale},
+		Exec:    []string{"sh", "-c", `{ echo "$ALERT_SCANNER_ID $ALERT_FROM $ALERT_TO"; cat; } > "$0"`, output},
+		Timeout: 5 * time.Second,
+	}, nil, logrus.New())
+
+	notifier.deliver(newTestTransition(homeassistant.HealthStale))
+
+	data, err := os.ReadFile(output) // #nosec G304
+	if err != nil {
+		t.Fatalf("Expected command to write the alert, got: %v", err)
+	}
+	lines := strings.SplitN(string(data), "\n", 2)
+	if lines[0] != "front healthy stale" {
+		t.Errorf("Expected alert environment, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `"to":"stale"`) {
+		t.Errorf("Expected alert JSON on stdin, got %q", lines[1])
+	}
+}
+
+func TestNotifier_ExecFailure(t *testing.T) {
+	notifier := NewNotifier(&config.AlertsConfig{
+		Exec:    []string{"sh", "-c", "echo broken >&2; exit 3"},
+		Timeout: 5 * time.Second,
+	}, nil, logrus.New())
+
+	err := notifier.runCommand(newTestTransition(homeassistant.HealthStale), []byte("{}"))
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected command failure with its output, got: %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const (
+	adoptListenTimeout = 60 * time.Second
+
+	adoptStateIdle      = "idle"
+	adoptStateListening = "listening"
+	adoptStateDetected  = "detected"
+	adoptStateFailed    = "failed"
+)
+
+type adoptCandidate struct {
+	Path          string `json:"path"`
+	Name          string `json:"name"`
+	VendorID      string `json:"vendor_id"`
+	ProductID     string `json:"product_id"`
+	Serial        string `json:"serial,omitempty"`
+	Interface     int    `json:"interface"`
+	LikelyScanner bool   `json:"likely_scanner"`
+	SuggestedID   string `json:"suggested_id"`
+}
+
+type adoptStatus struct {
+	State           string          `json:"state"`
+	Error           string          `json:"error,omitempty"`
+	Device          *adoptCandidate `json:"device,omitempty"`
+	Barcode         string          `json:"barcode,omitempty"`
+	TerminationChar string          `json:"termination_char,omitempty"`
+}
+
+type adoptListenRequest struct {
+	Paths []string `json:"paths"`
+}
+
+type adoptRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// adoption runs the "scan now to adopt" flow of the web UI: it listens on
+// unconfigured HID devices until one of them produces a scan, then writes
+// that device as a new scanner to the configuration file and reloads it,
+// which starts the scanner.
+type adoption struct {
+	app *Application
+
+	mutex    sync.Mutex
+	status   adoptStatus
+	detected *scanner.DetectedScan
+	cancel   context.CancelFunc
+}
+
+func newAdoption(app *Application) *adoption {
+	return &adoption{app: app, status: adoptStatus{State: adoptStateIdle}}
+}
+
+func newAdoptCandidate(device *hid.DeviceInfo, generateID scanner.IDGenerator) *adoptCandidate {
+	name := scanner.DeviceDisplayName(device)
+	return &adoptCandidate{
+		Path:          device.Path,
+		Name:          name,
+		VendorID:      fmt.Sprintf("%04x", device.VendorID),
+		ProductID:     fmt.Sprintf("%04x", device.ProductID),
+		Serial:        device.Serial,
+		Interface:     device.Interface,
+		LikelyScanner: scanner.IsLikelyBarcodeScanner(device),
+		SuggestedID:   generateID(name, device),
+	}
+}
+
+// candidates returns the HID devices not claimed by a configured scanner.
+func (a *adoption) candidates() []hid.DeviceInfo {
+	a.app.reloadMutex.Lock()
+	defer a.app.reloadMutex.Unlock()
+	return scanner.UnclaimedDevices(a.app.config.Scanners, scanner.ListAllDevices())
+}
+
+// idGenerator names adopted scanners like auto-discovered ones.
+func (a *adoption) idGenerator() scanner.IDGenerator {
+	a.app.reloadMutex.Lock()
+	defer a.app.reloadMutex.Unlock()
+	return a.app.scannerIDGenerator()
+}
+
+func (a *adoption) candidatesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		devices := a.candidates()
+		generateID := a.idGenerator()
+		candidates := make([]*adoptCandidate, 0, len(devices))
+		for i := range devices {
+			candidates = append(candidates, newAdoptCandidate(&devices[i], generateID))
+		}
+		writeJSON(w, http.StatusOK, candidates)
+	})
+}
+
+func (a *adoption) listenHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var request adoptListenRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Paths) == 0 {
+				http.Error(w, "invalid JSON body, expected {\"paths\": [\"...\"]}", http.StatusBadRequest)
+				return
+			}
+			if err := a.listen(request.Paths); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+		case http.MethodDelete:
+			a.stopListening()
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		a.mutex.Lock()
+		status := a.status
+		a.mutex.Unlock()
+		writeJSON(w, http.StatusOK, status)
+	})
+}
+
+// listen opens the selected candidates until one of them produces a scan.
+func (a *adoption) listen(paths []string) error {
+	var devices []hid.DeviceInfo
+	for _, device := range a.candidates() {
+		if slices.Contains(paths, device.Path) {
+			devices = append(devices, device)
+		}
+	}
+	if len(devices) != len(paths) {
+		return fmt.Errorf("selected devices are no longer available")
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.status.State == adoptStateListening {
+		return fmt.Errorf("already listening for a scan")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), adoptListenTimeout)
+	a.cancel = cancel
+	a.detected = nil
+	a.status = adoptStatus{State: adoptStateListening}
+	a.app.logger.WithField("devices", len(devices)).Info("Listening for a scan to adopt a scanner")
+
+	go func() {
+		defer cancel()
+		scan, err := scanner.ListenForScan(ctx, devices, a.app.logger)
+		if err != nil {
+			a.mutex.Lock()
+			a.status = adoptStatus{State: adoptStateFailed, Error: err.Error()}
+			a.mutex.Unlock()
+			return
+		}
+		candidate := newAdoptCandidate(&scan.Device, a.idGenerator())
+
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		a.detected = scan
+		a.status = adoptStatus{
+			State:           adoptStateDetected,
+			Device:          candidate,
+			Barcode:         scan.Barcode,
+			TerminationChar: scan.TerminationChar,
+		}
+	}()
+	return nil
+}
+
+func (a *adoption) stopListening() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+}
+
+func (a *adoption) adoptHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request adoptRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "invalid JSON body, expected {\"id\": \"...\", \"name\": \"...\"}", http.StatusBadRequest)
+			return
+		}
+
+		id, err := a.adopt(&request)
+		if err != nil {
+			a.app.logger.WithError(err).Error("Failed to adopt scanner")
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, http.StatusCreated, adoptRequest{ID: id, Name: request.Name})
+	})
+}
+
+// adopt writes the detected device to the configuration file and reloads
+// it, which starts the new scanner.
+func (a *adoption) adopt(request *adoptRequest) (string, error) {
+	if a.app.configPath == "" {
+		return "", fmt.Errorf("configuration file path not set")
+	}
+
+	a.mutex.Lock()
+	detected := a.detected
+	a.mutex.Unlock()
+	if detected == nil {
+		return "", fmt.Errorf("no scan detected yet")
+	}
+
+	device := detected.Device
+	name := request.Name
+	if name == "" {
+		name = scanner.DeviceDisplayName(&device)
+	}
+	id := request.ID
+	if id == "" {
+		id = a.idGenerator()(name, &device)
+	}
+
+	iface := device.Interface
+	scannerConfig := config.ScannerConfig{
+		Name: name,
+		Identification: config.ScannerIdentification{
+			VendorID:  device.VendorID,
+			ProductID: device.ProductID,
+			Serial:    device.Serial,
+			Interface: &iface,
+		},
+		TerminationChar: detected.TerminationChar,
+	}
+
+	a.app.reloadMutex.Lock()
+	err := config.AddScannerToFile(a.app.configPath, id, &scannerConfig)
+	a.app.reloadMutex.Unlock()
+	if err != nil {
+		return "", err
+	}
+	a.app.logger.WithField("scanner_id", id).Info("Adopted scanner, added it to the configuration file")
+
+	a.mutex.Lock()
+	a.detected = nil
+	a.status = adoptStatus{State: adoptStateIdle}
+	a.mutex.Unlock()
+
+	return id, a.app.Reload()
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
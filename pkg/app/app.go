@@ -1,22 +1,29 @@
 package app
 
 import (
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/alert"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
 )
 
 type Application struct {
-	config   *config.Config
-	logger   *logrus.Logger
-	version  string
-	services *ServiceManager
-	handlers *EventHandlers
+	config     *config.Config
+	configPath string
+	logger     *logrus.Logger
+	version    string
+	services   *ServiceManager
+	handlers   *EventHandlers
+
+	reloadMutex sync.Mutex
 }
 
 func NewApplication(cfg *config.Config, logger *logrus.Logger, version string) *Application {
@@ -32,9 +39,18 @@ func NewApplication(cfg *config.Config, logger *logrus.Logger, version string) *
 	return app
 }
 
+// SetConfigPath enables reloading the configuration when the file changes
+// or on Reload. It must be called before Initialize.
+func (app *Application) SetConfigPath(path string) {
+	app.configPath = path
+}
+
 func (app *Application) Initialize() error {
 	app.logger.Info("Initializing application components...")
 
+	environment := app.collectEnvironment()
+	app.logger.WithFields(environment).Info("Environment report")
+
 	bridgeAvailabilityTopic := homeassistant.GenerateBridgeAvailabilityTopic(&app.config.HomeAssistant)
 
 	mqttClient, err := mqtt.NewClient(
@@ -46,6 +62,8 @@ func (app *Application) Initialize() error {
 		return err
 	}
 
+	logHook := app.createLogHook(mqttClient)
+
 	haManager := homeassistant.NewIntegration(
 		mqttClient,
 		&app.config.HomeAssistant,
@@ -53,8 +71,13 @@ func (app *Application) Initialize() error {
 		app.logger,
 	)
 
+	haManager.SetEnvironment(environment)
+	haManager.SetAutoDiscover(app.config.AutoDiscover)
+
 	scannerManager := scanner.NewScannerManagerFromMap(app.config.Scanners, app.logger)
 	scannerManager.SetReconnectDelay(5 * time.Second)
+	scannerManager.SetAutoDiscover(app.config.AutoDiscover)
+	scannerManager.SetIDGenerator(app.scannerIDGenerator())
 
 	for _, scannerConfig := range app.config.Scanners {
 		scannerName := scannerConfig.Name
@@ -62,17 +85,148 @@ func (app *Application) Initialize() error {
 			scannerName = scannerConfig.ID
 		}
 		haManager.AddScanner(scannerConfig.ID, scannerName, &scannerConfig)
+		if err := app.handlers.SetScannerPipeline(&scannerConfig); err != nil {
+			return err
+		}
+	}
+
+	sinkManager := app.createSinkManager()
+	haManager.SetSinkHealth(func() any {
+		if health := sinkManager.Health(); len(health) > 0 {
+			return health
+		}
+		return nil
+	})
+
+	pauseController := NewPauseController(app.config.DisableFile, app.logger)
+	pauseController.SetOnChangeCallback(func(bool) {
+		if err := haManager.PublishPauseState(); err != nil {
+			app.logger.WithError(err).Error("Failed to publish pause state")
+		}
+	})
+	haManager.SetPauseControl(&homeassistant.PauseControl{
+		IsPaused:  pauseController.IsPaused,
+		SetPaused: pauseController.SetPaused,
+	})
+	haManager.SetRestartControl(app.RestartScanners)
+	haManager.SetScannerEnableControl(&homeassistant.ScannerEnableControl{
+		IsEnabled:  scannerManager.IsScannerEnabled,
+		SetEnabled: scannerManager.SetScannerEnabled,
+	})
+	haManager.SetScanTimeoutControl(&homeassistant.ScanTimeoutControl{
+		Get: scannerManager.CompletionTimeout,
+		Set: scannerManager.SetCompletionTimeout,
+	})
+	haManager.SetKeyboardLayoutControl(&homeassistant.KeyboardLayoutControl{
+		Layouts: scanner.GetAvailableLayouts(),
+		Get:     scannerManager.KeyboardLayout,
+		Set:     scannerManager.SetKeyboardLayout,
+	})
+	if app.config.ScanRelay != nil {
+		haManager.SetScanRelay(app.config.ScanRelay)
 	}
 
 	app.services.Register("mqtt", mqttClient)
+	if logHook != nil {
+		app.services.Register("logstream", logHook)
+	}
+	if app.config.Alerts != nil {
+		notifier := alert.NewNotifier(app.config.Alerts, mqttClient, app.logger)
+		app.services.Register("alerts", notifier)
+		haManager.SetOnHealthChangeCallback(notifier.Notify)
+	}
 	app.services.Register("homeassistant", haManager)
+	if app.config.OfflineQueue != nil {
+		offline, err := newOfflineQueue(app.config.OfflineQueue, haManager, mqttClient, pauseController, app.logger)
+		if err != nil {
+			return err
+		}
+		app.services.Register("offline_queue", offline)
+		app.handlers.SetOfflineQueue(offline)
+	}
+	app.services.Register("sinks", sinkManager)
+	app.services.Register("pause", pauseController)
+	if app.config.Assist != nil {
+		assist := homeassistant.NewAssistForwarder(app.config.Assist, app.logger)
+		app.services.Register("assist", assist)
+		app.handlers.SetAssistForwarder(assist)
+	}
+	if len(app.config.HTTP.Listeners) > 0 {
+		app.services.Register("http", app.createHTTPServer(mqttClient, haManager, pauseController))
+	}
 	app.services.Register("scanner", scannerManager)
+	if app.configPath != "" {
+		app.services.Register("config", NewConfigWatcher(app.configPath, app.Reload, app.logger))
+	}
 
-	app.handlers.SetupHandlers(app.services, haManager, scannerManager)
+	app.handlers.SetupHandlers(app.services, haManager, scannerManager, sinkManager, pauseController)
 
 	return nil
 }
 
+func (app *Application) scannerIDGenerator() scanner.IDGenerator {
+	return scanner.NewIDGenerator(app.config.ScannerIDTemplate, app.config.HomeAssistant.InstanceID)
+}
+
+func (app *Application) createLogHook(mqttClient mqtt.Client) *mqtt.LogHook {
+	streamConfig := app.config.Logging.MQTT
+	if !streamConfig.Enabled {
+		return nil
+	}
+
+	topic := streamConfig.Topic
+	if topic == "" {
+		topic = homeassistant.GenerateBridgeLogTopic(&app.config.HomeAssistant)
+	}
+
+	level, err := logrus.ParseLevel(streamConfig.Level)
+	if err != nil {
+		level = logrus.WarnLevel
+	}
+
+	secrets := []string{app.config.MQTT.Password}
+	for _, webhook := range app.config.Sinks.Webhooks {
+		secrets = append(secrets, webhook.Secret)
+	}
+	for _, grocy := range app.config.Sinks.Grocy {
+		secrets = append(secrets, grocy.APIKey)
+	}
+	for _, listener := range app.config.HTTP.Listeners {
+		secrets = append(secrets, listener.Auth.Password, listener.Auth.Token)
+	}
+	if app.config.Assist != nil {
+		secrets = append(secrets, app.config.Assist.Token)
+	}
+	if app.config.Alerts != nil && app.config.Alerts.Webhook != nil {
+		secrets = append(secrets, app.config.Alerts.Webhook.Secret)
+	}
+
+	hook := mqtt.NewLogHook(mqttClient, topic, level, streamConfig.RateLimit, secrets)
+	app.logger.AddHook(hook)
+	app.logger.WithField("topic", topic).Info("Streaming warnings and errors to MQTT")
+
+	return hook
+}
+
+func (app *Application) createSinkManager() *sink.Manager {
+	sinkManager := sink.NewManager(app.logger)
+
+	for _, entry := range app.sinkEntries(app.config) {
+		sinkManager.Add(entry.create(), entry.scanners)
+		app.logger.WithField("sink", entry.name).Infof("%s sink configured", entry.kind)
+	}
+
+	return sinkManager
+}
+
+func (app *Application) collectEnvironment() map[string]any {
+	environment := common.CollectEnvironment().Fields()
+	for key, value := range scanner.HIDBackendInfo() {
+		environment[key] = value
+	}
+	return environment
+}
+
 func (app *Application) Start() error {
 	return app.services.StartAll()
 }
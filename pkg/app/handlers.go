@@ -1,45 +1,127 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/httpserver"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/pipeline"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
 )
 
 type EventHandlers struct {
-	logger *logrus.Logger
+	logger  *logrus.Logger
+	assist  *homeassistant.AssistForwarder
+	offline *offlineQueue
+
+	// pipelines holds the configured stages of each scanner.
+	pipelines      map[string]*scannerPipeline
+	pipelinesMutex sync.RWMutex
+
+	// processScan runs a scan through the pipeline, set up by SetupHandlers.
+	processScan func(scan *pipeline.Scan) error
+	history     *scanHistory
+	stream      *httpserver.EventStream
 }
 
 func NewEventHandlers(logger *logrus.Logger) *EventHandlers {
 	return &EventHandlers{
-		logger: logger,
+		logger:    logger,
+		pipelines: make(map[string]*scannerPipeline),
+		history:   newScanHistory(),
 	}
 }
 
+// SetScanStream pushes every scan outcome to the WebSocket stream.
+func (h *EventHandlers) SetScanStream(stream *httpserver.EventStream) {
+	h.stream = stream
+}
+
+// SetAssistForwarder routes prefixed scans to Assist. It must be called before SetupHandlers.
+func (h *EventHandlers) SetAssistForwarder(assist *homeassistant.AssistForwarder) {
+	h.assist = assist
+}
+
+// SetOfflineQueue queues scans Home Assistant can't receive. It must be called before SetupHandlers.
+func (h *EventHandlers) SetOfflineQueue(offline *offlineQueue) {
+	h.offline = offline
+}
+
 func (h *EventHandlers) SetupHandlers(
 	services *ServiceManager,
 	haManager *homeassistant.Integration,
 	scannerManager *scanner.ScannerManager,
+	sinkManager *sink.Manager,
+	pauseController *PauseController,
 ) {
-	scannerManager.SetOnScanCallback(h.createBarcodeHandler(haManager))
+	scannerManager.SetOnScanCallback(h.createBarcodeHandler(haManager, sinkManager, pauseController))
 
 	scannerManager.SetOnConnectionChangeCallback(h.createConnectionHandler(services, haManager))
+
+	scannerManager.SetOnBatteryLevelCallback(h.createBatteryHandler(haManager))
+
+	scannerManager.SetOnDockChangeCallback(h.createDockHandler(haManager))
+
+	scannerManager.SetOnErrorCallback(h.createErrorHandler(haManager))
+
+	scannerManager.SetOnScannerDiscoveredCallback(h.createDiscoveryHandler(haManager))
+}
+
+func (h *EventHandlers) createDiscoveryHandler(haManager *homeassistant.Integration) func(*config.ScannerConfig) {
+	return func(cfg *config.ScannerConfig) {
+		// The integration keeps the pointer, so register a copy owned by it.
+		scannerConfig := *cfg
+		haManager.AddScanner(scannerConfig.ID, scannerConfig.Name, &scannerConfig)
+	}
+}
+
+func (h *EventHandlers) createBatteryHandler(haManager *homeassistant.Integration) func(string, int) {
+	return func(scannerID string, level int) {
+		logger := h.logger.WithFields(map[string]any{
+			"scanner_id": scannerID,
+			"battery":    level,
+		})
+		logger.Debug("Scanner battery level changed")
+
+		if err := haManager.SetScannerBatteryLevel(scannerID, level); err != nil {
+			logger.WithError(err).Error("Failed to publish battery level to Home Assistant")
+		}
+	}
+}
+
+func (h *EventHandlers) createDockHandler(haManager *homeassistant.Integration) func(string, bool) {
+	return func(scannerID string, docked bool) {
+		logger := h.logger.WithFields(map[string]any{
+			"scanner_id": scannerID,
+			"docked":     docked,
+		})
+		logger.Info("Scanner dock state changed")
+
+		if err := haManager.SetScannerDocked(scannerID, docked); err != nil {
+			logger.WithError(err).Error("Failed to publish dock state to Home Assistant")
+		}
+	}
 }
 
-func (h *EventHandlers) createBarcodeHandler(haManager *homeassistant.Integration) func(string, string) {
-	return func(scannerID, barcode string) {
+func (h *EventHandlers) createErrorHandler(haManager *homeassistant.Integration) func(string, string, error) {
+	return func(scannerID, category string, deviceErr error) {
 		logger := h.logger.WithFields(map[string]any{
 			"scanner_id": scannerID,
-			"barcode":    barcode,
-			"length":     len(barcode),
+			"category":   category,
 		})
-		logger.Info("Barcode scanned")
+		logger.WithError(deviceErr).Debug("Scanner device error")
 
-		if err := haManager.PublishBarcode(scannerID, barcode); err != nil {
-			logger.WithError(err).Error("Failed to publish barcode to Home Assistant")
+		// Devices failing to open before their first connect have no
+		// health sensor to count them in
+		err := haManager.RecordScannerError(scannerID, category)
+		if err != nil && !errors.Is(err, homeassistant.ErrScannerNotRegistered) {
+			logger.WithError(err).Error("Failed to publish scanner error to Home Assistant")
 		}
 	}
 }
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+type probeStatus struct {
+	Status            string `json:"status"`
+	MQTTConnected     *bool  `json:"mqtt_connected,omitempty"`
+	ScannersDownSince string `json:"scanners_down_since,omitempty"`
+}
+
+// readinessHandler reports ready only while connected to the broker, since
+// scans can't be published before that.
+func readinessHandler(mqttClient mqtt.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		connected := mqttClient.IsConnected()
+		if !connected {
+			writeJSON(w, http.StatusServiceUnavailable, probeStatus{Status: "not ready", MQTTConnected: &connected})
+			return
+		}
+		writeJSON(w, http.StatusOK, probeStatus{Status: "ready", MQTTConnected: &connected})
+	})
+}
+
+// livenessHandler reports unhealthy once all scanners have been disconnected
+// for longer than the threshold, so the orchestrator restarts the bridge,
+// e.g. to recover from a USB bus that stopped enumerating. A lost broker
+// connection is left to the readiness probe, paho reconnects on its own.
+func livenessHandler(haManager *homeassistant.Integration, threshold time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		since, down := haManager.AllScannersDownSince()
+		if !down || time.Since(since) <= threshold {
+			writeJSON(w, http.StatusOK, probeStatus{Status: "healthy"})
+			return
+		}
+		writeJSON(w, http.StatusServiceUnavailable, probeStatus{
+			Status:            "unhealthy",
+			ScannersDownSince: since.Format(time.RFC3339),
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/httpserver"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+type pauseRequest struct {
+	Paused bool `json:"paused"`
+}
+
+func (app *Application) createHTTPServer(
+	mqttClient mqtt.Client,
+	haManager *homeassistant.Integration,
+	pauseController *PauseController,
+) *httpserver.Server {
+	server := httpserver.NewServer(&app.config.HTTP, app.logger)
+
+	server.Handle(config.HTTPServeMetrics, "/metrics", httpserver.MetricsHandler(func() []httpserver.Metric {
+		return collectMetrics(haManager, pauseController)
+	}))
+	server.Handle(config.HTTPServeAPI, "/api/pause", app.pauseHandler(pauseController))
+	server.Handle(config.HTTPServeAPI, "/api/scanners", app.scannersHandler(haManager))
+	server.Handle(config.HTTPServeAPI, "/api/scanners/{id}/scans", app.scannerScansHandler(haManager))
+	server.Handle(config.HTTPServeAPI, "/api/scanners/{id}/simulate", app.simulateHandler(haManager))
+	server.Handle(config.HTTPServeAPI, "/api/scanners/{id}/expect", app.expectScanHandler(haManager))
+
+	adoption := newAdoption(app)
+	server.Handle(config.HTTPServeAPI, "/api/adopt/candidates", adoption.candidatesHandler())
+	server.Handle(config.HTTPServeAPI, "/api/adopt/listen", adoption.listenHandler())
+	server.Handle(config.HTTPServeAPI, "/api/adopt", adoption.adoptHandler())
+	server.Handle(config.HTTPServeUI, "/{$}", httpserver.UIHandler())
+
+	server.Handle(config.HTTPServeHealth, "/readyz", readinessHandler(mqttClient))
+	server.Handle(config.HTTPServeHealth, "/healthz", livenessHandler(haManager, app.config.HTTP.Health.ScannerDownThreshold))
+
+	stream := httpserver.NewEventStream("scanner", app.config.HTTP.Stream.AllowedOrigins, app.logger)
+	app.handlers.SetScanStream(stream)
+	server.Handle(config.HTTPServeStream, "/ws", stream)
+	server.OnStop(stream.Close)
+
+	return server
+}
+
+func (app *Application) pauseHandler(pauseController *PauseController) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var request pauseRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "invalid JSON body, expected {\"paused\": true|false}", http.StatusBadRequest)
+				return
+			}
+			if err := pauseController.SetPaused(request.Paused); err != nil {
+				app.logger.WithError(err).Error("Failed to change pause state from HTTP API")
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(pauseRequest{Paused: pauseController.IsPaused()})
+	})
+}
+
+func collectMetrics(haManager *homeassistant.Integration, pauseController *PauseController) []httpserver.Metric {
+	connected := httpserver.Metric{
+		Name: "barcode_scanner_connected",
+		Help: "Whether the scanner is connected (1) or not (0).",
+		Type: "gauge",
+	}
+	scans := httpserver.Metric{
+		Name: "barcode_scanner_scans_total",
+		Help: "Scans published since the bridge started.",
+		Type: "counter",
+	}
+	reconnects := httpserver.Metric{
+		Name: "barcode_scanner_reconnects_total",
+		Help: "Scanner reconnections since the bridge started.",
+		Type: "counter",
+	}
+	flaps := httpserver.Metric{
+		Name: "barcode_scanner_flaps_total",
+		Help: "Disconnects shorter than the disconnect debounce window.",
+		Type: "counter",
+	}
+	battery := httpserver.Metric{
+		Name: "barcode_scanner_battery_percent",
+		Help: "Scanner battery level.",
+		Type: "gauge",
+	}
+
+	for _, stats := range haManager.ScannerStats() {
+		labels := map[string]string{"scanner": stats.ID}
+		connected.Samples = append(connected.Samples, httpserver.Sample{Labels: labels, Value: boolValue(stats.Connected)})
+		scans.Samples = append(scans.Samples, httpserver.Sample{Labels: labels, Value: float64(stats.TotalScans)})
+		reconnects.Samples = append(reconnects.Samples, httpserver.Sample{Labels: labels, Value: float64(stats.ReconnectCount)})
+		flaps.Samples = append(flaps.Samples, httpserver.Sample{Labels: labels, Value: float64(stats.FlapCount)})
+		if stats.BatteryLevel != nil {
+			battery.Samples = append(battery.Samples, httpserver.Sample{Labels: labels, Value: float64(*stats.BatteryLevel)})
+		}
+	}
+
+	paused := httpserver.Metric{
+		Name:    "barcode_bridge_paused",
+		Help:    "Whether scan publishing is paused (1) or not (0).",
+		Type:    "gauge",
+		Samples: []httpserver.Sample{{Value: boolValue(pauseController.IsPaused())}},
+	}
+
+	return []httpserver.Metric{connected, scans, reconnects, flaps, battery, paused}
+}
+
+func boolValue(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/pipeline"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/queue"
+)
+
+const offlineQueueDrainInterval = time.Second
+
+// offlineQueue keeps the scans Home Assistant could not receive on disk and
+// publishes them in order once the broker is reachable again. While scans
+// are queued new ones are queued behind them, so Home Assistant sees them in
+// the order they were scanned.
+type offlineQueue struct {
+	queue       *queue.FileQueue
+	publish     func(scannerID, barcode string, metadata homeassistant.ScanMetadata) error
+	isConnected func() bool
+	isPaused    func() bool
+	logger      *logrus.Logger
+
+	wake   chan struct{}
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newOfflineQueue(
+	cfg *config.OfflineQueueConfig,
+	haManager *homeassistant.Integration,
+	mqttClient mqtt.Client,
+	pauseController *PauseController,
+	logger *logrus.Logger,
+) (*offlineQueue, error) {
+	fileQueue, err := queue.Open(cfg.Path, cfg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	if pending := fileQueue.Len(); pending > 0 {
+		logger.WithField("path", cfg.Path).Infof("Offline queue holds %d scans, delivering them once MQTT connects", pending)
+	}
+
+	return &offlineQueue{
+		queue:       fileQueue,
+		publish:     haManager.PublishBarcode,
+		isConnected: mqttClient.IsConnected,
+		isPaused:    pauseController.IsPaused,
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+		stopCh:      make(chan struct{}),
+	}, nil
+}
+
+func (o *offlineQueue) Start() error {
+	o.wg.Add(1)
+	go o.run()
+	return nil
+}
+
+// Stop leaves undelivered scans in the queue file for the next start.
+func (o *offlineQueue) Stop() error {
+	close(o.stopCh)
+	o.wg.Wait()
+	return o.queue.Close()
+}
+
+// publishBarcode publishes a scan to Home Assistant, or queues it when older
+// scans are still queued or publishing fails, including for scanners whose
+// Home Assistant device isn't registered yet.
+func (o *offlineQueue) publishBarcode(scan *pipeline.Scan) error {
+	if o.queue.Len() == 0 {
+		err := o.publish(scan.ScannerID, scan.Barcode, scanMetadata(scan))
+		if err == nil || errors.Is(err, homeassistant.ErrScannerNotFound) {
+			return err
+		}
+		o.logger.WithError(err).WithField("scanner_id", scan.ScannerID).Warn("Failed to publish barcode, queueing it")
+	}
+
+	metadata := scanMetadata(scan)
+	entry, err := o.queue.Push(queue.Entry{
+		ScannerID:     scan.ScannerID,
+		Barcode:       scan.Barcode,
+		ScanID:        metadata.ID,
+		CorrelationID: metadata.CorrelationID,
+		Timestamp:     scan.Timestamp,
+		Attributes:    metadata.Attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue barcode: %w", err)
+	}
+	o.logger.WithFields(map[string]any{
+		"scanner_id": scan.ScannerID,
+		"seq":        entry.Seq,
+	}).Debug("Barcode queued until MQTT is available")
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (o *offlineQueue) run() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(offlineQueueDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stopCh:
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+		o.drain()
+	}
+}
+
+// drain publishes the queued scans in order until the queue is empty or a
+// publish fails. A scan is removed from the queue only after the broker
+// acknowledged it, so delivery is at-least-once: when removing it fails the
+// integration skips the retry by its scan ID within the duplicate_window,
+// but a crash in between publishes it again after the restart. While
+// publishing is paused the queued scans are kept for after the resume.
+func (o *offlineQueue) drain() {
+	for o.isConnected() && !o.isPaused() {
+		select {
+		case <-o.stopCh:
+			return
+		default:
+		}
+
+		entry, exists := o.queue.Peek()
+		if !exists {
+			return
+		}
+
+		logger := o.logger.WithFields(map[string]any{
+			"scanner_id": entry.ScannerID,
+			"barcode":    entry.Barcode,
+			"queued_at":  entry.Timestamp,
+		})
+		err := o.publish(entry.ScannerID, entry.Barcode, homeassistant.ScanMetadata{
+			ID:            entry.ScanID,
+			CorrelationID: entry.CorrelationID,
+			Time:          entry.Timestamp,
+			Attributes:    entry.Attributes,
+		})
+		switch {
+		case errors.Is(err, homeassistant.ErrScannerNotFound):
+			logger.Warn("Dropping queued scan of a scanner that is no longer configured")
+		case errors.Is(err, homeassistant.ErrScannerNotRegistered):
+			// After a restart the queue drains before the scanners connected
+			logger.Debug("Waiting for the scanner to connect before delivering queued scans")
+			return
+		case err != nil:
+			logger.WithError(err).Debug("Failed to deliver queued scan, retrying")
+			return
+		default:
+			logger.Info("Delivered queued scan")
+		}
+
+		if err := o.queue.Ack(entry.Seq); err != nil {
+			logger.WithError(err).Error("Failed to remove delivered scan from the offline queue")
+			return
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/pipeline"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/queue"
+)
+
+func TestOfflineQueue_WaitsForRegistration(t *testing.T) {
+	fileQueue, err := queue.Open(filepath.Join(t.TempDir(), "queue.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Expected queue, got error: %v", err)
+	}
+	defer func() { _ = fileQueue.Close() }()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	publishErr := fmt.Errorf("%w: desk", homeassistant.ErrScannerNotRegistered)
+	var published []homeassistant.ScanMetadata
+	offline := &offlineQueue{
+		queue: fileQueue,
+		publish: func(_, _ string, metadata homeassistant.ScanMetadata) error {
+			if publishErr != nil {
+				return publishErr
+			}
+			published = append(published, metadata)
+			return nil
+		},
+		isConnected: func() bool { return true },
+		isPaused:    func() bool { return false },
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+	}
+
+	scannedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	scan := &pipeline.Scan{ID: "scan-1", ScannerID: "desk", Barcode: "123", Timestamp: scannedAt}
+	if err := offline.publishBarcode(scan); err != nil {
+		t.Fatalf("Expected the scan of an unregistered scanner to be queued, got: %v", err)
+	}
+	if fileQueue.Len() != 1 {
+		t.Fatalf("Expected 1 queued scan, got %d", fileQueue.Len())
+	}
+
+	offline.drain()
+	if fileQueue.Len() != 1 || len(published) != 0 {
+		t.Errorf("Expected the scan to stay queued until the scanner registers, got %d queued", fileQueue.Len())
+	}
+
+	publishErr = nil
+	offline.drain()
+	if fileQueue.Len() != 0 || len(published) != 1 {
+		t.Fatalf("Expected the scan to be delivered once registered, got %d queued", fileQueue.Len())
+	}
+	if !published[0].Time.Equal(scannedAt) || published[0].ID != "scan-1" {
+		t.Errorf("Expected the queued scan to keep its scan time and ID, got %+v", published[0])
+	}
+
+	publishErr = fmt.Errorf("%w: desk", homeassistant.ErrScannerNotFound)
+	if err := offline.publishBarcode(scan); err == nil {
+		t.Error("Expected the scan of an unconfigured scanner not to be queued")
+	}
+	if fileQueue.Len() != 0 {
+		t.Errorf("Expected no queued scans, got %d", fileQueue.Len())
+	}
+}
+
+func TestOfflineQueue_HoldsScansWhilePaused(t *testing.T) {
+	fileQueue, err := queue.Open(filepath.Join(t.TempDir(), "queue.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("Expected queue, got error: %v", err)
+	}
+	defer func() { _ = fileQueue.Close() }()
+	if _, err := fileQueue.Push(queue.Entry{ScannerID: "desk", Barcode: "123", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Expected scan to be queued, got: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	controller := newTestPauseController("")
+	published := 0
+	offline := &offlineQueue{
+		queue: fileQueue,
+		publish: func(_, _ string, _ homeassistant.ScanMetadata) error {
+			published++
+			return nil
+		},
+		isConnected: func() bool { return true },
+		isPaused:    controller.IsPaused,
+		logger:      logger,
+		wake:        make(chan struct{}, 1),
+	}
+
+	if err := controller.SetPaused(true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	offline.drain()
+	if published != 0 || fileQueue.Len() != 1 {
+		t.Errorf("Expected the queued scan to be held while paused, got %d published", published)
+	}
+
+	if err := controller.SetPaused(false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	offline.drain()
+	if published != 1 || fileQueue.Len() != 0 {
+		t.Errorf("Expected the queued scan to be delivered after resuming, got %d published", published)
+	}
+}
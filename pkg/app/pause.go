@@ -0,0 +1,153 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const pauseFilePollInterval = time.Second
+
+// PauseController is the instance-wide kill switch for scans. While paused,
+// new scans are dropped before the pipeline, so they reach neither Home
+// Assistant nor the sinks, and the offline queue holds its scans until
+// resumed. Scanners stay open and their availability, health and diagnostics
+// are still published. With a disable file
+// configured the file is the single source of truth, so maintenance scripts
+// and the Home Assistant switch always agree.
+type PauseController struct {
+	path       string
+	paused     atomic.Bool
+	onChange   func(bool)
+	logger     *logrus.Logger
+	stopCh     chan struct{}
+	mutex      sync.Mutex
+	statFailed bool // Guarded by mutex, reports a failing disable file once
+}
+
+func NewPauseController(path string, logger *logrus.Logger) *PauseController {
+	return &PauseController{
+		path:   path,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (p *PauseController) SetOnChangeCallback(callback func(paused bool)) {
+	p.onChange = callback
+}
+
+func (p *PauseController) Start() error {
+	if p.path == "" {
+		return nil
+	}
+
+	p.refresh()
+	go func() {
+		ticker := time.NewTicker(pauseFilePollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+				p.refresh()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (p *PauseController) Stop() error {
+	close(p.stopCh)
+	return nil
+}
+
+// IsPaused checks the disable file on every call, so a scan right after the
+// file was created is already dropped; the poll only reports the change.
+func (p *PauseController) IsPaused() bool {
+	if p.path == "" {
+		return p.paused.Load()
+	}
+	paused, err := p.fileExists()
+	if err != nil {
+		return p.paused.Load()
+	}
+	return paused
+}
+
+// SetPaused creates or removes the disable file, or toggles the in-memory
+// state when no file is configured.
+func (p *PauseController) SetPaused(paused bool) error {
+	if p.path != "" {
+		var err error
+		if paused {
+			err = os.WriteFile(p.path, nil, 0o600)
+		} else if err = os.Remove(p.path); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update disable file %s: %w", p.path, err)
+		}
+		p.refresh()
+		return nil
+	}
+
+	p.update(paused)
+	return nil
+}
+
+// refresh applies the state of the disable file. A file that can't be
+// checked, e.g. for lack of permissions, keeps the current state rather
+// than resuming publishing.
+func (p *PauseController) refresh() {
+	paused, err := p.fileExists()
+	p.mutex.Lock()
+	statFailed := p.statFailed
+	p.statFailed = err != nil
+	p.mutex.Unlock()
+	if err != nil {
+		if !statFailed {
+			p.logger.WithError(err).Error("Failed to check disable file, keeping the pause state")
+		}
+		return
+	}
+	p.update(paused)
+}
+
+func (p *PauseController) fileExists() (bool, error) {
+	_, err := os.Stat(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *PauseController) update(paused bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.paused.Swap(paused) == paused {
+		return
+	}
+
+	if paused {
+		p.logger.Warn("Publishing paused, scans will be dropped until resumed")
+	} else {
+		p.logger.Info("Publishing resumed")
+	}
+
+	if p.onChange != nil {
+		p.onChange(paused)
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestPauseController(path string) *PauseController {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return NewPauseController(path, logger)
+}
+
+func TestPauseController_InMemory(t *testing.T) {
+	controller := newTestPauseController("")
+	var changes []bool
+	controller.SetOnChangeCallback(func(paused bool) { changes = append(changes, paused) })
+
+	if controller.IsPaused() {
+		t.Error("Expected publishing not to be paused initially")
+	}
+	for _, paused := range []bool{true, true, false} {
+		if err := controller.SetPaused(paused); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if controller.IsPaused() != paused {
+			t.Errorf("Expected paused %v, got %v", paused, controller.IsPaused())
+		}
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("Expected one pause and one resume callback, got %v", changes)
+	}
+}
+
+func TestPauseController_DisableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disabled")
+	controller := newTestPauseController(path)
+	if err := controller.Start(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer func() { _ = controller.Stop() }()
+
+	if controller.IsPaused() {
+		t.Error("Expected publishing not to be paused without the disable file")
+	}
+
+	// Scans see the file right away, without waiting for the poll
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create disable file: %v", err)
+	}
+	if !controller.IsPaused() {
+		t.Error("Expected publishing to be paused as soon as the disable file exists")
+	}
+
+	if err := controller.SetPaused(false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected resuming to remove the disable file, got: %v", err)
+	}
+	if err := controller.SetPaused(true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected pausing to create the disable file, got: %v", err)
+	}
+	if !controller.paused.Load() {
+		t.Error("Expected the pause to be reported right after creating the disable file")
+	}
+}
+
+func TestPauseController_StatError(t *testing.T) {
+	dir := t.TempDir()
+	notDir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Checking a path below a file fails with an error other than not found
+	controller := newTestPauseController(filepath.Join(notDir, "disabled"))
+	controller.paused.Store(true)
+	controller.refresh()
+	if !controller.IsPaused() {
+		t.Error("Expected a disable file that can't be checked to keep publishing paused")
+	}
+	if !controller.statFailed {
+		t.Error("Expected the failed check to be reported")
+	}
+}
@@ -0,0 +1,307 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/pipeline"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
+)
+
+// errScanHandled stops the pipeline for scans consumed while routing, such as
+// Assist commands or operator badges, which are logged by the route stage.
+var errScanHandled = errors.New("scan handled")
+
+// errPaused drops scans while publishing is paused.
+var errPaused = fmt.Errorf("%w: publishing paused", pipeline.ErrDropped)
+
+// errQueueFull drops scans of a scanner whose rate_limit queue is full.
+var errQueueFull = fmt.Errorf("%w: rate limit queue full", pipeline.ErrDropped)
+
+// errBadLength rejects scans outside the scanner's min_length and
+// max_length, which are counted as scanner errors.
+var errBadLength = fmt.Errorf("%w: %w: bad length", pipeline.ErrDropped, pipeline.ErrRejected)
+
+// scannerPipeline holds the stages run before routing and the value
+// template run after it, so command barcodes are recognized unrendered.
+type scannerPipeline struct {
+	stages        []pipeline.Stage
+	valueTemplate []pipeline.Stage
+	worker        *pipeline.Worker // Runs the scans of scanners with a queueing rate_limit stage
+}
+
+// lengthStage rejects scans shorter than minLength or longer than a
+// non-zero maxLength.
+func lengthStage(minLength, maxLength int) pipeline.Stage {
+	return pipeline.Stage{Name: "length", Processor: pipeline.ProcessorFunc(func(scan *pipeline.Scan) error {
+		if err := pipeline.CheckLength(scan.Barcode, minLength, maxLength); err != nil {
+			return fmt.Errorf("%w: %w", errBadLength, err)
+		}
+		return nil
+	})}
+}
+
+// SetScannerPipeline builds the configured pipeline stages of a scanner and
+// its value template, replacing any previous ones.
+func (h *EventHandlers) SetScannerPipeline(cfg *config.ScannerConfig) error {
+	stages, err := pipeline.Build(cfg.Pipeline, h.logger)
+	if err != nil {
+		return fmt.Errorf("scanner %s: %w", cfg.ID, err)
+	}
+	if cfg.MinLength > 0 || cfg.MaxLength > 0 {
+		stages = append([]pipeline.Stage{lengthStage(cfg.MinLength, cfg.MaxLength)}, stages...)
+	}
+	scannerStages := &scannerPipeline{stages: stages}
+	if cfg.ValueTemplate != "" {
+		valueTemplate, err := pipeline.NewValueTemplate(cfg.ValueTemplate)
+		if err != nil {
+			return fmt.Errorf("scanner %s: %w", cfg.ID, err)
+		}
+		scannerStages.valueTemplate = []pipeline.Stage{{Name: "value_template", Processor: valueTemplate}}
+	}
+
+	if queueSize := cfg.RateLimitQueueSize(); queueSize > 0 {
+		scannerStages.worker = pipeline.NewWorker(queueSize, func(scan *pipeline.Scan) {
+			h.handleScan(scan, false)
+		})
+	}
+
+	h.pipelinesMutex.Lock()
+	h.closeScannerWorker(cfg.ID)
+	h.pipelines[cfg.ID] = scannerStages
+	h.pipelinesMutex.Unlock()
+	return nil
+}
+
+func (h *EventHandlers) RemoveScannerPipeline(scannerID string) {
+	h.pipelinesMutex.Lock()
+	h.closeScannerWorker(scannerID)
+	delete(h.pipelines, scannerID)
+	h.pipelinesMutex.Unlock()
+}
+
+// closeScannerWorker stops the worker of the scanner's current pipeline, its
+// queued scans still run through the pipeline that replaces it.
+func (h *EventHandlers) closeScannerWorker(scannerID string) {
+	if previous, exists := h.pipelines[scannerID]; exists && previous.worker != nil {
+		previous.worker.Close()
+	}
+}
+
+func (h *EventHandlers) scannerStages(scannerID string) *scannerPipeline {
+	h.pipelinesMutex.RLock()
+	defer h.pipelinesMutex.RUnlock()
+	if stages, exists := h.pipelines[scannerID]; exists {
+		return stages
+	}
+	return &scannerPipeline{}
+}
+
+// createBarcodeHandler runs every scan through the pause check, the scanner's
+// configured stages, routing of command barcodes, the scanner's value
+// template and finally publishing. Scanners with a queueing rate_limit stage
+// hand their scans to the pipeline's worker instead of running it from the
+// read loop.
+func (h *EventHandlers) createBarcodeHandler(
+	haManager *homeassistant.Integration,
+	sinkManager *sink.Manager,
+	pauseController *PauseController,
+) func(string, string) {
+	pause := pipeline.Stage{Name: "pause", Processor: pipeline.ProcessorFunc(func(*pipeline.Scan) error {
+		if pauseController.IsPaused() {
+			return errPaused
+		}
+		return nil
+	})}
+	route := pipeline.Stage{Name: "route", Processor: h.routeProcessor(haManager)}
+	correlate := pipeline.Stage{Name: "correlate", Processor: correlateProcessor(haManager)}
+	publish := pipeline.Stage{Name: "publish", Processor: h.publishProcessor(haManager, sinkManager)}
+
+	h.processScan = func(scan *pipeline.Scan) error {
+		scannerStages := h.scannerStages(scan.ScannerID)
+		stages := append([]pipeline.Stage{pause}, scannerStages.stages...)
+		stages = append(stages, route)
+		stages = append(stages, scannerStages.valueTemplate...)
+		stages = append(stages, correlate, publish)
+
+		err := pipeline.New(stages...).Run(scan)
+		if !errors.Is(err, errPaused) {
+			haManager.RecordRead(scan.ScannerID, errors.Is(err, pipeline.ErrRejected))
+		}
+		if errors.Is(err, errBadLength) {
+			if recordErr := haManager.RecordScannerError(scan.ScannerID, scanner.ErrorCategoryBadLength); recordErr != nil {
+				h.logger.WithError(recordErr).WithField("scanner_id", scan.ScannerID).Error("Failed to publish scanner error to Home Assistant")
+			}
+		}
+		return err
+	}
+
+	return func(scannerID, barcode string) {
+		scan := newScan(scannerID, barcode)
+		worker := h.scannerStages(scannerID).worker
+		if worker == nil {
+			h.handleScan(scan, false)
+			return
+		}
+		if !worker.Push(scan) {
+			haManager.RecordRead(scannerID, false)
+			h.recordScanOutcome(scan, false, errQueueFull)
+		}
+	}
+}
+
+// handleScan processes a scan, then logs and records its outcome.
+func (h *EventHandlers) handleScan(scan *pipeline.Scan, simulated bool) recentScan {
+	return h.recordScanOutcome(scan, simulated, h.processScan(scan))
+}
+
+// recordScanOutcome logs the outcome of a scan and adds it to the history
+// and the scan stream.
+func (h *EventHandlers) recordScanOutcome(scan *pipeline.Scan, simulated bool, err error) recentScan {
+	entry := recentScan{Barcode: scan.Barcode, Timestamp: scan.Timestamp, Result: scanResultPublished, Simulated: simulated}
+	switch {
+	case err == nil:
+	case errors.Is(err, errScanHandled):
+		entry.Result = scanResultHandled
+	case errors.Is(err, pipeline.ErrDropped):
+		entry.Result, entry.Reason = scanResultDropped, err.Error()
+		h.scanLogger(scan.ScannerID, scan.Barcode).Infof("Scan dropped by %v", err)
+	default:
+		entry.Result, entry.Reason = scanResultFailed, err.Error()
+		h.scanLogger(scan.ScannerID, scan.Barcode).WithError(err).Error("Failed to process scan")
+	}
+
+	h.history.record(scan.ScannerID, entry)
+	if h.stream != nil {
+		h.stream.Publish(scan.ScannerID, scanEvent{ScannerID: scan.ScannerID, recentScan: entry})
+	}
+	return entry
+}
+
+// SimulateScan processes a barcode as if the scanner had read it.
+func (h *EventHandlers) SimulateScan(scannerID, barcode string) (recentScan, error) {
+	if h.processScan == nil {
+		return recentScan{}, fmt.Errorf("scan handling is not set up")
+	}
+	h.scanLogger(scannerID, barcode).Info("Simulating scan")
+	return h.handleScan(newScan(scannerID, barcode), true), nil
+}
+
+func newScan(scannerID, barcode string) *pipeline.Scan {
+	return &pipeline.Scan{ID: common.NewUUID(), ScannerID: scannerID, Barcode: barcode, Timestamp: time.Now()}
+}
+
+func (h *EventHandlers) scanLogger(scannerID, barcode string) *logrus.Entry {
+	return h.logger.WithFields(map[string]any{
+		"scanner_id": scannerID,
+		"barcode":    barcode,
+		"length":     len(barcode),
+	})
+}
+
+// routeProcessor consumes Assist commands, pantry quantities and operator
+// badges so they are not published as regular scans.
+func (h *EventHandlers) routeProcessor(haManager *homeassistant.Integration) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(scan *pipeline.Scan) error {
+		logger := h.scanLogger(scan.ScannerID, scan.Barcode)
+
+		if h.assist != nil && h.assist.HandleScan(scan.ScannerID, scan.Barcode) {
+			logger.Info("Assist command scanned")
+			return errScanHandled
+		}
+		if haManager.HandlePantryQuantity(scan.ScannerID, scan.Barcode) {
+			logger.Info("Pantry quantity scanned")
+			return errScanHandled
+		}
+		if isBadge, err := haManager.HandleOperatorBadge(scan.ScannerID, scan.Barcode); isBadge {
+			if err != nil {
+				logger.WithError(err).Error("Failed to publish operator change to Home Assistant")
+			}
+			logger.Info("Operator badge scanned")
+			return errScanHandled
+		}
+		return nil
+	})
+}
+
+// correlateProcessor tags a scan answering an expect_scan request with the
+// correlation ID of the request, for Home Assistant and the sinks.
+func correlateProcessor(haManager *homeassistant.Integration) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(scan *pipeline.Scan) error {
+		correlationID := haManager.TakeExpectedScan(scan.ScannerID, scan.Timestamp)
+		if correlationID == "" {
+			return nil
+		}
+		if scan.Attributes == nil {
+			scan.Attributes = make(map[string]string)
+		}
+		scan.Attributes[homeassistant.CorrelationIDAttribute] = correlationID
+		return nil
+	})
+}
+
+// publishProcessor delivers the scan to Home Assistant and the sinks. A
+// failing destination is logged without stopping the others.
+func (h *EventHandlers) publishProcessor(haManager *homeassistant.Integration, sinkManager *sink.Manager) pipeline.Processor {
+	return pipeline.ProcessorFunc(func(scan *pipeline.Scan) error {
+		logger := h.scanLogger(scan.ScannerID, scan.Barcode)
+		logger.Info("Barcode scanned")
+
+		if err := h.publishBarcode(haManager, scan); err != nil {
+			logger.WithError(err).Error("Failed to publish barcode to Home Assistant")
+		}
+
+		event := sink.Event{
+			ScannerID:  scan.ScannerID,
+			Barcode:    scan.Barcode,
+			Timestamp:  scan.Timestamp,
+			Attributes: scan.Attributes,
+		}
+
+		inventory, err := haManager.PublishInventoryEvent(scan.ScannerID, scan.Barcode)
+		if err != nil {
+			logger.WithError(err).Error("Failed to publish inventory event")
+		}
+		if inventory != nil {
+			event.Action = inventory.Action
+			event.Quantity = inventory.Quantity
+		}
+
+		sinkManager.Dispatch(event)
+		return nil
+	})
+}
+
+// publishBarcode publishes the scan to Home Assistant, through the offline
+// queue when one is configured.
+func (h *EventHandlers) publishBarcode(haManager *homeassistant.Integration, scan *pipeline.Scan) error {
+	if h.offline != nil {
+		return h.offline.publishBarcode(scan)
+	}
+	return haManager.PublishBarcode(scan.ScannerID, scan.Barcode, scanMetadata(scan))
+}
+
+func scanMetadata(scan *pipeline.Scan) homeassistant.ScanMetadata {
+	metadata := homeassistant.ScanMetadata{
+		ID:            scan.ID,
+		CorrelationID: scan.Attributes[homeassistant.CorrelationIDAttribute],
+		Time:          scan.Timestamp,
+	}
+	for key, value := range scan.Attributes {
+		if key == homeassistant.CorrelationIDAttribute {
+			continue
+		}
+		if metadata.Attributes == nil {
+			metadata.Attributes = make(map[string]string, len(scan.Attributes))
+		}
+		metadata.Attributes[key] = value
+	}
+	return metadata
+}
@@ -0,0 +1,234 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const configPollInterval = 2 * time.Second
+
+// ConfigWatcher reloads the application when the configuration file changes.
+// The file is polled rather than watched for events because editors and
+// Kubernetes config maps usually replace it instead of writing in place.
+type ConfigWatcher struct {
+	path    string
+	reload  func() error
+	logger  *logrus.Logger
+	stopCh  chan struct{}
+	modTime time.Time
+	size    int64
+}
+
+func NewConfigWatcher(path string, reload func() error, logger *logrus.Logger) *ConfigWatcher {
+	return &ConfigWatcher{
+		path:   path,
+		reload: reload,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (w *ConfigWatcher) Start() error {
+	w.changed()
+
+	go func() {
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopCh:
+				return
+			case <-ticker.C:
+				if !w.changed() {
+					continue
+				}
+				w.logger.WithField("path", w.path).Info("Configuration file changed, reloading")
+				if err := w.reload(); err != nil {
+					w.logger.WithError(err).Error("Configuration reload failed, keeping the running configuration")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (w *ConfigWatcher) Stop() error {
+	close(w.stopCh)
+	return nil
+}
+
+// changed records the file's modification time and size, reporting whether
+// either differs from the previous call.
+func (w *ConfigWatcher) changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false // Missing while being replaced; check again on the next tick
+	}
+
+	changed := !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+	w.modTime = info.ModTime()
+	w.size = info.Size()
+	return changed
+}
+
+// Reload re-reads the configuration file and applies scanner changes without
+// restarting: removed scanners are stopped and their entities deleted from
+// Home Assistant, added ones are started, and changed ones are restarted so
+// their discovery is republished when they reconnect. Sinks are added,
+// removed and replaced the same way, without interrupting the scanners. A new
+// discovery prefix moves all entities to it. Other sections only take effect
+// after a restart.
+func (app *Application) Reload() error {
+	app.reloadMutex.Lock()
+	defer app.reloadMutex.Unlock()
+
+	if app.configPath == "" {
+		return fmt.Errorf("configuration file path not set")
+	}
+
+	newConfig, err := config.LoadConfig(app.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to reload configuration: %w", err)
+	}
+
+	haManager := app.services.GetHomeAssistantIntegration()
+	scannerManager := app.services.GetScannerManager()
+	sinkManager := app.services.GetSinkManager()
+	if haManager == nil || scannerManager == nil || sinkManager == nil {
+		return fmt.Errorf("services not available for reload")
+	}
+
+	if newPrefix := newConfig.HomeAssistant.DiscoveryPrefix; newPrefix != app.config.HomeAssistant.DiscoveryPrefix {
+		if err := haManager.ChangeDiscoveryPrefix(newPrefix); err != nil {
+			app.logger.WithError(err).Error("Failed to move discovery to the new prefix")
+		}
+	}
+
+	if requiresRestart(app.config, newConfig) {
+		app.logger.Warn("Configuration changes outside of scanners require a restart to take effect")
+	}
+
+	added, removed, changed := diffScanners(app.config.Scanners, newConfig.Scanners)
+	for _, id := range removed {
+		// Unpublished while the integration still knows the scanner's
+		// parsed field sensors
+		if err := haManager.UnpublishScanner(id); err != nil {
+			app.logger.WithField("scanner_id", id).WithError(err).Error("Failed to remove scanner from Home Assistant")
+		}
+		app.stopScanner(haManager, scannerManager, id)
+	}
+	for _, id := range changed {
+		app.stopScanner(haManager, scannerManager, id)
+		app.startScanner(haManager, scannerManager, newConfig.Scanners[id])
+	}
+	for _, id := range added {
+		app.startScanner(haManager, scannerManager, newConfig.Scanners[id])
+	}
+
+	sinksAdded, sinksRemoved, sinksChanged := app.reloadSinks(sinkManager, newConfig)
+	app.config.Scanners = newConfig.Scanners
+	app.config.Sinks = newConfig.Sinks
+
+	app.logger.WithFields(logrus.Fields{
+		"added":         added,
+		"removed":       removed,
+		"changed":       changed,
+		"sinks_added":   sinksAdded,
+		"sinks_removed": sinksRemoved,
+		"sinks_changed": sinksChanged,
+	}).Info("Configuration reloaded")
+	return nil
+}
+
+// RestartScanners stops and starts every configured scanner, e.g. to recover
+// a scanner stuck in a bad state without restarting the process.
+func (app *Application) RestartScanners() error {
+	app.reloadMutex.Lock()
+	defer app.reloadMutex.Unlock()
+
+	haManager := app.services.GetHomeAssistantIntegration()
+	scannerManager := app.services.GetScannerManager()
+	if haManager == nil || scannerManager == nil {
+		return fmt.Errorf("services not available for restart")
+	}
+
+	ids := slices.Sorted(maps.Keys(app.config.Scanners))
+	for _, id := range ids {
+		app.stopScanner(haManager, scannerManager, id)
+		app.startScanner(haManager, scannerManager, app.config.Scanners[id])
+	}
+
+	app.logger.WithField("scanners", ids).Info("Scanners restarted")
+	return nil
+}
+
+func (app *Application) stopScanner(
+	haManager *homeassistant.Integration, scannerManager *scanner.ScannerManager, id string,
+) {
+	scannerManager.RemoveScanner(id)
+	haManager.RemoveScanner(id)
+	app.handlers.RemoveScannerPipeline(id)
+}
+
+func (app *Application) startScanner(
+	haManager *homeassistant.Integration, scannerManager *scanner.ScannerManager, scannerConfig config.ScannerConfig,
+) {
+	scannerName := scannerConfig.Name
+	if scannerName == "" {
+		scannerName = scannerConfig.ID
+	}
+	haManager.AddScanner(scannerConfig.ID, scannerName, &scannerConfig)
+	if err := app.handlers.SetScannerPipeline(&scannerConfig); err != nil {
+		app.logger.WithField("scanner_id", scannerConfig.ID).WithError(err).Error("Failed to build scanner pipeline")
+	}
+
+	if err := scannerManager.AddScanner(scannerConfig); err != nil {
+		app.logger.WithField("scanner_id", scannerConfig.ID).WithError(err).Error("Failed to start scanner")
+	}
+}
+
+// diffScanners returns the sorted IDs of scanners only in newScanners, only
+// in oldScanners, and in both with a different configuration.
+func diffScanners(oldScanners, newScanners map[string]config.ScannerConfig) (added, removed, changed []string) {
+	for id, newScanner := range newScanners {
+		oldScanner, exists := oldScanners[id]
+		switch {
+		case !exists:
+			added = append(added, id)
+		case !reflect.DeepEqual(oldScanner, newScanner):
+			changed = append(changed, id)
+		}
+	}
+	for id := range oldScanners {
+		if _, exists := newScanners[id]; !exists {
+			removed = append(removed, id)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(changed)
+	return added, removed, changed
+}
+
+// requiresRestart reports whether anything besides the scanners, the sinks
+// and the discovery prefix changed.
+func requiresRestart(oldConfig, newConfig *config.Config) bool {
+	oldRest, newRest := *oldConfig, *newConfig
+	oldRest.Scanners, newRest.Scanners = nil, nil
+	oldRest.Sinks, newRest.Sinks = config.SinksConfig{}, config.SinksConfig{}
+	newRest.HomeAssistant.DiscoveryPrefix = oldRest.HomeAssistant.DiscoveryPrefix
+	return !reflect.DeepEqual(oldRest, newRest)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// recentScansLimit is how many scans per scanner the HTTP API returns.
+const recentScansLimit = 50
+
+const (
+	scanResultPublished = "published"
+	scanResultHandled   = "handled"
+	scanResultDropped   = "dropped"
+	scanResultFailed    = "failed"
+)
+
+type recentScan struct {
+	Barcode   string    `json:"barcode"`
+	Timestamp time.Time `json:"timestamp"`
+	Result    string    `json:"result"`
+	Reason    string    `json:"reason,omitempty"`
+	Simulated bool      `json:"simulated,omitempty"`
+}
+
+// scanEvent is pushed to the clients of the WebSocket scan stream.
+type scanEvent struct {
+	ScannerID string `json:"scanner_id"`
+	recentScan
+}
+
+// scanHistory keeps the latest scans of every scanner in memory.
+type scanHistory struct {
+	mutex sync.Mutex
+	scans map[string][]recentScan
+}
+
+func newScanHistory() *scanHistory {
+	return &scanHistory{scans: make(map[string][]recentScan)}
+}
+
+func (h *scanHistory) record(scannerID string, scan recentScan) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	scans := append(h.scans[scannerID], scan)
+	if len(scans) > recentScansLimit {
+		scans = scans[len(scans)-recentScansLimit:]
+	}
+	h.scans[scannerID] = scans
+}
+
+// recent returns the scans of a scanner, newest first.
+func (h *scanHistory) recent(scannerID string) []recentScan {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	scans := slices.Clone(h.scans[scannerID])
+	slices.Reverse(scans)
+	if scans == nil {
+		scans = []recentScan{}
+	}
+	return scans
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+)
+
+type scannerStatus struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Connected      bool       `json:"connected"`
+	TotalScans     int64      `json:"total_scans"`
+	RecentScans    int        `json:"recent_scans"`
+	ReconnectCount int        `json:"reconnect_count"`
+	FlapCount      int        `json:"flap_count"`
+	ErrorCount     int        `json:"error_count"`
+	BatteryLevel   *int       `json:"battery_level,omitempty"`
+	LastScanTime   *time.Time `json:"last_scan_time,omitempty"`
+}
+
+type simulateRequest struct {
+	Barcode string `json:"barcode"`
+}
+
+type expectScanRequest struct {
+	CorrelationID string  `json:"correlation_id"`
+	Timeout       float64 `json:"timeout"`
+}
+
+func newScannerStatus(stats *homeassistant.ScannerStats) scannerStatus {
+	return scannerStatus{
+		ID:             stats.ID,
+		Name:           stats.Name,
+		Connected:      stats.Connected,
+		TotalScans:     stats.TotalScans,
+		RecentScans:    stats.RecentScans,
+		ReconnectCount: stats.ReconnectCount,
+		FlapCount:      stats.FlapCount,
+		ErrorCount:     stats.ErrorCount,
+		BatteryLevel:   stats.BatteryLevel,
+		LastScanTime:   stats.LastScanTime,
+	}
+}
+
+// scannersHandler lists the scanners whose device has been found.
+func (app *Application) scannersHandler(haManager *homeassistant.Integration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		stats := haManager.ScannerStats()
+		scanners := make([]scannerStatus, 0, len(stats))
+		for i := range stats {
+			scanners = append(scanners, newScannerStatus(&stats[i]))
+		}
+		writeJSON(w, http.StatusOK, scanners)
+	})
+}
+
+// scannerScansHandler returns the latest scans of a scanner, newest first,
+// with the pipeline outcome of each.
+func (app *Application) scannerScansHandler(haManager *homeassistant.Integration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		scannerID := r.PathValue("id")
+		if _, exists := haManager.ScannerStatsByID(scannerID); !exists {
+			http.Error(w, "scanner not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, app.handlers.history.recent(scannerID))
+	})
+}
+
+// simulateHandler injects a scan, which goes through the same pipeline as a
+// real one and shows up in the scan history marked as simulated.
+func (app *Application) simulateHandler(haManager *homeassistant.Integration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		scannerID := r.PathValue("id")
+		if _, exists := haManager.ScannerStatsByID(scannerID); !exists {
+			http.Error(w, "scanner not found", http.StatusNotFound)
+			return
+		}
+
+		var request simulateRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Barcode == "" {
+			http.Error(w, "invalid JSON body, expected {\"barcode\": \"...\"}", http.StatusBadRequest)
+			return
+		}
+
+		scan, err := app.handlers.SimulateScan(scannerID, request.Barcode)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, http.StatusOK, scan)
+	})
+}
+
+// expectScanHandler tags the next scan of a scanner, within the timeout in
+// seconds, with the correlation ID, like the expect_scan MQTT topic.
+func (app *Application) expectScanHandler(haManager *homeassistant.Integration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request expectScanRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "invalid JSON body, expected {\"correlation_id\": \"...\", \"timeout\": 30}", http.StatusBadRequest)
+			return
+		}
+
+		scannerID := r.PathValue("id")
+		timeout := time.Duration(request.Timeout * float64(time.Second))
+		err := haManager.ExpectScan(scannerID, request.CorrelationID, timeout)
+		switch {
+		case errors.Is(err, homeassistant.ErrScanRelayDisabled), errors.Is(err, homeassistant.ErrScannerNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, homeassistant.ErrScanRelayForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{
+				"scanner_id":     scannerID,
+				"correlation_id": request.CorrelationID,
+				"expires_at":     time.Now().Add(timeout),
+			})
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
 )
 
 type Service interface {
@@ -42,12 +43,12 @@ func (sm *ServiceManager) Get(name string) Service {
 	return nil
 }
 
-func (sm *ServiceManager) GetMQTTClient() *mqtt.Client {
+func (sm *ServiceManager) GetMQTTClient() mqtt.Client {
 	service := sm.Get("mqtt")
 	if service == nil {
 		return nil
 	}
-	if mqttClient, ok := service.(*mqtt.Client); ok {
+	if mqttClient, ok := service.(mqtt.Client); ok {
 		return mqttClient
 	}
 	sm.logger.WithField("service", "mqtt").Error("Service type assertion failed")
@@ -78,6 +79,18 @@ func (sm *ServiceManager) GetScannerManager() *scanner.ScannerManager {
 	return nil
 }
 
+func (sm *ServiceManager) GetSinkManager() *sink.Manager {
+	service := sm.Get("sinks")
+	if service == nil {
+		return nil
+	}
+	if sinkManager, ok := service.(*sink.Manager); ok {
+		return sinkManager
+	}
+	sm.logger.WithField("service", "sinks").Error("Service type assertion failed")
+	return nil
+}
+
 func (sm *ServiceManager) StartAll() error {
 	sm.logger.Info("Starting application services...")
 
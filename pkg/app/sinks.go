@@ -0,0 +1,93 @@
+package app
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/sink"
+)
+
+// sinkEntry is a configured sink, including the on_scan_exec commands of
+// the scanners. Sink names are unique across all sink types, so a reload
+// matches old and new sinks by name.
+type sinkEntry struct {
+	name     string
+	kind     string
+	config   any
+	scanners []string
+	create   func() sink.Sink
+}
+
+func (app *Application) sinkEntries(cfg *config.Config) []sinkEntry {
+	sinks := &cfg.Sinks
+	entries := make([]sinkEntry, 0, len(sinks.Webhooks)+len(sinks.Grocy))
+	for i := range sinks.Webhooks {
+		webhookConfig := &sinks.Webhooks[i]
+		entries = append(entries, sinkEntry{
+			name:     webhookConfig.Name,
+			kind:     "Webhook",
+			config:   *webhookConfig,
+			scanners: webhookConfig.Scanners,
+			create:   func() sink.Sink { return sink.NewWebhookSink(webhookConfig, app.logger) },
+		})
+	}
+	for i := range sinks.Grocy {
+		grocyConfig := &sinks.Grocy[i]
+		entries = append(entries, sinkEntry{
+			name:     grocyConfig.Name,
+			kind:     "Grocy",
+			config:   *grocyConfig,
+			scanners: grocyConfig.Scanners,
+			create:   func() sink.Sink { return sink.NewGrocySink(grocyConfig, app.logger) },
+		})
+	}
+	for _, scannerID := range slices.Sorted(maps.Keys(cfg.Scanners)) {
+		execConfig := cfg.Scanners[scannerID].OnScanExec
+		if execConfig == nil {
+			continue
+		}
+		entries = append(entries, sinkEntry{
+			name:     sink.ExecSinkName(scannerID),
+			kind:     "Exec",
+			config:   *execConfig,
+			scanners: []string{scannerID},
+			create:   func() sink.Sink { return sink.NewExecSink(scannerID, execConfig, app.logger) },
+		})
+	}
+	return entries
+}
+
+// reloadSinks applies sink changes to the running sink manager: removed
+// sinks are stopped, added ones started, and changed ones replaced. The other
+// sinks keep delivering meanwhile. It returns the sorted names of the sinks
+// in each group.
+func (app *Application) reloadSinks(sinkManager *sink.Manager, newConfig *config.Config) (added, removed, changed []string) {
+	oldEntries := app.sinkEntries(app.config)
+	newEntries := app.sinkEntries(newConfig)
+
+	for _, oldEntry := range oldEntries {
+		index := slices.IndexFunc(newEntries, func(e sinkEntry) bool { return e.name == oldEntry.name })
+		switch {
+		case index < 0:
+			removed = append(removed, oldEntry.name)
+			sinkManager.Remove(oldEntry.name)
+		case !reflect.DeepEqual(oldEntry.config, newEntries[index].config):
+			changed = append(changed, oldEntry.name)
+			sinkManager.Remove(oldEntry.name)
+			sinkManager.Add(newEntries[index].create(), newEntries[index].scanners)
+		}
+	}
+	for _, newEntry := range newEntries {
+		if !slices.ContainsFunc(oldEntries, func(e sinkEntry) bool { return e.name == newEntry.name }) {
+			added = append(added, newEntry.name)
+			sinkManager.Add(newEntry.create(), newEntry.scanners)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(changed)
+	return added, removed, changed
+}
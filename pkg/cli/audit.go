@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+const auditPayloadPreview = 60
+
+// auditedTopic is what was received on one of the bridge's topics while
+// auditing.
+type auditedTopic struct {
+	retained bool
+	live     int
+	payload  string
+	stale    bool
+}
+
+// topicAudit records messages on the topics the bridge would publish to.
+// Nothing is published while auditing, so every live message comes from
+// another client, and retained data from an earlier run or another bridge.
+type topicAudit struct {
+	haConfig   *config.HomeAssistantConfig
+	scannerIDs []string
+	logTopic   string
+
+	mutex  sync.Mutex
+	topics map[string]*auditedTopic
+}
+
+func newTopicAudit(cfg *config.Config) *topicAudit {
+	audit := &topicAudit{
+		haConfig: &cfg.HomeAssistant,
+		logTopic: cfg.Logging.MQTT.Topic,
+		topics:   make(map[string]*auditedTopic),
+	}
+	for id := range cfg.Scanners {
+		audit.scannerIDs = append(audit.scannerIDs, id)
+	}
+	return audit
+}
+
+// subscriptions returns the topic filters covering the bridge's topics. The
+// discovery prefix needs a wildcard since entity object IDs only share a
+// prefix, and record filters the other bridges out.
+func (a *topicAudit) subscriptions() []string {
+	filters := []string{a.haConfig.DiscoveryPrefix + "/#"}
+	if a.logTopic != "" {
+		filters = append(filters, a.logTopic)
+	}
+	return filters
+}
+
+func (a *topicAudit) record(message mqtt.Message) {
+	stale := false
+	if message.Topic != a.logTopic {
+		scannerObject, owned := homeassistant.ParseBridgeTopic(a.haConfig, message.Topic)
+		if !owned {
+			return
+		}
+		stale = scannerObject != "" && !a.isConfiguredScanner(scannerObject)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	topic, exists := a.topics[message.Topic]
+	if !exists {
+		topic = &auditedTopic{stale: stale}
+		a.topics[message.Topic] = topic
+	}
+	if message.Retained {
+		topic.retained = true
+	} else {
+		topic.live++
+	}
+	topic.payload = string(message.Payload)
+}
+
+func (a *topicAudit) isConfiguredScanner(scannerObject string) bool {
+	for _, id := range a.scannerIDs {
+		if scannerObject == id || strings.HasPrefix(scannerObject, id+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// report prints one line per topic and a summary, returning the number of
+// topics another client published to during the audit.
+func (a *topicAudit) report(out io.Writer) int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	names := make([]string, 0, len(a.topics))
+	for name := range a.topics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var retained, stale, live int
+	for _, name := range names {
+		topic := a.topics[name]
+		var notes []string
+		if topic.retained {
+			retained++
+			notes = append(notes, "retained")
+		}
+		if topic.stale {
+			stale++
+			notes = append(notes, "stale: scanner not configured")
+		}
+		if topic.live > 0 {
+			live++
+			notes = append(notes, fmt.Sprintf("%d live messages from another client", topic.live))
+		}
+
+		payload := topic.payload
+		if len(payload) > auditPayloadPreview {
+			payload = payload[:auditPayloadPreview] + "..."
+		}
+		_, _ = fmt.Fprintf(out, "%s %q (%s)\n", name, payload, strings.Join(notes, ", "))
+	}
+
+	_, _ = fmt.Fprintf(out, "\n%d topics in use: %d retained, %d stale, %d published live by another client\n",
+		len(names), retained, stale, live)
+	if live > 0 {
+		_, _ = fmt.Fprintln(out, "Another bridge or client is publishing to these topics - check for a second instance with the same instance_id")
+	}
+	if stale > 0 {
+		_, _ = fmt.Fprintln(out, "Stale topics belong to scanners removed from the configuration - "+
+			"clear them by publishing an empty retained message")
+	}
+	return live
+}
+
+// runAudit connects to the broker without publishing anything, not even a
+// last will, and reports other publishers and retained data on the bridge's
+// topics.
+func (c *CLI) runAudit(cmd *cli.Command, cfg *config.Config) error {
+	mqttConfig := cfg.MQTT
+	// A separate client ID keeps a running bridge from being disconnected
+	mqttConfig.ClientID += "-audit"
+
+	client, err := mqtt.NewClient(&mqttConfig, "", c.logger)
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(); err != nil {
+		client.Disconnect()
+		return err
+	}
+	defer client.Disconnect()
+
+	audit := newTopicAudit(cfg)
+	for _, filter := range audit.subscriptions() {
+		if err := client.SubscribeMessages(filter, audit.record); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", filter, err)
+		}
+	}
+
+	duration := cmd.Duration("audit-duration")
+	fmt.Printf("Auditing the topics of instance '%s' for %s without publishing - press Ctrl+C to stop early\n",
+		cfg.HomeAssistant.InstanceID, duration)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	select {
+	case <-sigCh:
+	case <-time.After(duration):
+	}
+
+	if live := audit.report(os.Stdout); live > 0 {
+		return fmt.Errorf("%d topics are published by another client", live)
+	}
+	return nil
+}
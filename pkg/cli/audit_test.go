@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+func TestTopicAudit(t *testing.T) {
+	cfg := &config.Config{
+		HomeAssistant: config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "home"},
+		Scanners:      map[string]config.ScannerConfig{"office": {}},
+	}
+	audit := newTopicAudit(cfg)
+
+	audit.record(mqtt.Message{Topic: "homeassistant/sensor/ha-barcode-bridge-home/availability", Payload: []byte("online"), Retained: true})
+	audit.record(mqtt.Message{Topic: "homeassistant/sensor/ha-barcode-bridge-home-scanner-office/state", Payload: []byte("123")})
+	audit.record(mqtt.Message{
+		Topic: "homeassistant/sensor/ha-barcode-bridge-home-scanner-garage-health/config", Payload: []byte("{}"), Retained: true,
+	})
+	audit.record(mqtt.Message{Topic: "homeassistant/light/kitchen/config", Payload: []byte("{}"), Retained: true})
+
+	var out bytes.Buffer
+	if live := audit.report(&out); live != 1 {
+		t.Errorf("Expected 1 live topic, got %d", live)
+	}
+
+	expected := []string{
+		`homeassistant/sensor/ha-barcode-bridge-home-scanner-garage-health/config "{}" (retained, stale: scanner not configured)`,
+		`homeassistant/sensor/ha-barcode-bridge-home-scanner-office/state "123" (1 live messages from another client)`,
+		`homeassistant/sensor/ha-barcode-bridge-home/availability "online" (retained)`,
+		"",
+		"3 topics in use: 2 retained, 1 stale, 1 published live by another client",
+	}
+	lines := strings.Split(out.String(), "\n")
+	for i, line := range expected {
+		if i >= len(lines) || lines[i] != line {
+			t.Errorf("Expected line %d %q, got %q", i, line, out.String())
+		}
+	}
+}
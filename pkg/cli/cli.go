@@ -5,9 +5,8 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"regexp"
-	"strings"
 	"syscall"
+	"time"
 
 	"github.com/karalabe/hid"
 	"github.com/sirupsen/logrus"
@@ -17,6 +16,7 @@ import (
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/selftest"
 )
 
 const AppName = "homeassistant-barcode-scanner"
@@ -46,12 +46,134 @@ func (c *CLI) Run(args []string) error {
 				Name:  "list-devices",
 				Usage: "List available HID devices that might be barcode scanners",
 			},
+			&cli.StringFlag{
+				Name:  "id-template",
+				Usage: "Name the scanner IDs suggested by --list-devices and generate-config from `TEMPLATE`, see scanner_id_template",
+			},
+			&cli.BoolFlag{
+				Name:  "confirm-layout",
+				Usage: "Confirm the intended characters for unmapped keycodes recorded in learned_layout files",
+			},
+			&cli.BoolFlag{
+				Name:  "no-publish",
+				Usage: "Audit the broker without publishing: report retained data and other clients on this bridge's topics",
+			},
+			&cli.DurationFlag{
+				Name:  "audit-duration",
+				Usage: "How long --no-publish listens for other publishers",
+				Value: 30 * time.Second,
+			},
 			&cli.StringFlag{
 				Name:  "log-level",
 				Usage: "Set log level (debug, info, warn, error)",
 				Value: "info",
 			},
 		},
+		Commands: []*cli.Command{
+			{
+				Name:  "monitor",
+				Usage: "Print decoded barcodes, raw HID reports and timing of the configured scanners without MQTT",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "scanner",
+						Usage: "Only monitor scanner `ID` (repeatable, default: all configured scanners)",
+					},
+				},
+				Action: c.runMonitor,
+			},
+			{
+				Name:  "generate-config",
+				Usage: "Create a configuration file interactively from the connected HID devices",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the configuration to `FILE`",
+						Value:   "config.yaml",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Overwrite an existing output file",
+					},
+				},
+				Action: c.runGenerateConfig,
+			},
+			{
+				Name:   "doctor",
+				Usage:  "Check HID permissions, the configured devices and the MQTT broker",
+				Action: c.runDoctor,
+			},
+			{
+				Name:  "test-publish",
+				Usage: "Publish a test scanner and confirm Home Assistant discovers it",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "ha-url",
+						Usage: "Home Assistant `URL` to check the test entity through (default: assist.url)",
+					},
+					&cli.StringFlag{
+						Name:  "ha-token",
+						Usage: "Home Assistant long-lived access `TOKEN` (default: assist.token)",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "How long to wait for the test entity to show the scan",
+						Value: 30 * time.Second,
+					},
+					&cli.BoolFlag{
+						Name:  "keep",
+						Usage: "Keep the test entities instead of removing them",
+					},
+				},
+				Action: c.runTestPublish,
+			},
+			{
+				Name:  "selftest",
+				Usage: "Run the Home Assistant integration against an embedded MQTT broker and verify its round trips",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Usage: "How long each round trip may take",
+						Value: selftest.DefaultTimeout,
+					},
+				},
+				Action: c.runSelftest,
+			},
+			{
+				Name:  "export-inventory",
+				Usage: "Export the configured and attached scanners with their state and health counters for asset management",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format, json or csv",
+						Value: inventoryFormatJSON,
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the inventory to `FILE` instead of standard output",
+					},
+					&cli.StringFlag{
+						Name:  "bridge-url",
+						Usage: "`URL` of the running bridge's API (default: the listener serving api)",
+					},
+				},
+				Action: c.runExportInventory,
+			},
+			{
+				Name:  "version",
+				Usage: "Print the version, commit, build date, Go version and the available drivers and HID backends",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output format, text or json",
+						Value:   versionOutputText,
+					},
+				},
+				Action: c.runVersion,
+			},
+		},
 		Action: c.runApp,
 	}
 
@@ -62,7 +184,11 @@ func (c *CLI) runApp(ctx context.Context, cmd *cli.Command) error {
 	c.logger = c.setupLogger(cmd)
 
 	if cmd.Bool("list-devices") {
-		return c.listDevices()
+		idTemplate, err := idTemplateFlag(cmd)
+		if err != nil {
+			return err
+		}
+		return c.listDevices(idTemplate)
 	}
 
 	// If no config file exists at default location and no explicit config provided,
@@ -73,27 +199,34 @@ func (c *CLI) runApp(ctx context.Context, cmd *cli.Command) error {
 			if helpErr := cli.ShowAppHelp(cmd); helpErr != nil {
 				return fmt.Errorf("failed to show help: %w", helpErr)
 			}
-			return fmt.Errorf("no configuration found - create config.yaml or specify with --config")
+			return newExitError(ExitConfigError,
+				fmt.Errorf("no configuration found - create config.yaml or specify with --config"))
 		}
 	}
 
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		return fmt.Errorf("configuration error: %w", err)
+		return newExitError(ExitConfigError, fmt.Errorf("configuration error: %w", err))
 	}
 
 	c.applyConfigLogging(cmd, cfg)
 
-	c.logger.Infof("Starting %s %s", AppName, common.GetVersion())
+	if cmd.Bool("confirm-layout") {
+		return c.confirmLearnedLayouts(cfg, os.Stdin, os.Stdout)
+	}
 
-	c.app = app.NewApplication(cfg, c.logger, common.GetVersion())
-	if err := c.app.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize application: %w", err)
+	if cmd.Bool("no-publish") {
+		return c.runAudit(cmd, cfg)
 	}
 
-	shutdownCh := c.setupSignalHandling()
+	c.logger.Infof("Starting %s %s", AppName, common.GetVersion())
 
-	if err := c.app.Start(); err != nil {
+	if cfg.Failover != nil {
+		return c.runFailover(cfg, configPath)
+	}
+
+	shutdownCh, err := c.startApp(cfg, configPath)
+	if err != nil {
 		return err
 	}
 
@@ -102,6 +235,23 @@ func (c *CLI) runApp(ctx context.Context, cmd *cli.Command) error {
 	return c.app.Stop()
 }
 
+// startApp initializes and starts the application and returns the channel
+// closed on a shutdown signal.
+func (c *CLI) startApp(cfg *config.Config, configPath string) (<-chan struct{}, error) {
+	c.app = app.NewApplication(cfg, c.logger, common.GetVersion())
+	c.app.SetConfigPath(configPath)
+	if err := c.app.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize application: %w", err)
+	}
+
+	shutdownCh := c.setupSignalHandling()
+
+	if err := c.app.Start(); err != nil {
+		return nil, err
+	}
+	return shutdownCh, nil
+}
+
 func (c *CLI) setupLogger(cmd *cli.Command) *logrus.Logger {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
@@ -127,74 +277,69 @@ func (c *CLI) applyConfigLogging(cmd *cli.Command, cfg *config.Config) {
 func (c *CLI) setupSignalHandling() <-chan struct{} {
 	shutdownCh := make(chan struct{})
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		sig := <-sigCh
-		c.logger.Warnf("Received signal: %v", sig)
-		close(shutdownCh)
+		for sig := range sigCh {
+			if sig == syscall.SIGHUP {
+				c.logger.Info("Received SIGHUP, reloading configuration")
+				if err := c.app.Reload(); err != nil {
+					c.logger.WithError(err).Error("Configuration reload failed, keeping the running configuration")
+				}
+				continue
+			}
+
+			c.logger.Warnf("Received signal: %v", sig)
+			close(shutdownCh)
+			return
+		}
 	}()
 
 	return shutdownCh
 }
 
-// generateScannerID creates a valid YAML key from device info
-func generateScannerID(name string, device *hid.DeviceInfo) string {
-	// Convert to lowercase and replace spaces/special chars with underscores
-	id := strings.ToLower(name)
-	// Replace any non-alphanumeric characters with underscores
-	reg := regexp.MustCompile(`[^a-z0-9]+`)
-	id = reg.ReplaceAllString(id, "_")
-	// Remove leading/trailing underscores
-	id = strings.Trim(id, "_")
-
-	// If empty or starts with number, prepend "scanner"
-	if id == "" || (id != "" && id[0] >= '0' && id[0] <= '9') {
-		id = fmt.Sprintf("scanner_%s", id)
-	}
-	// If still empty, use fallback
-	if id == "" || id == "scanner_" {
-		id = "scanner"
-	}
-
-	// Add interface index for same VID:PID devices (only if > 0)
-	if device.Interface > 0 {
-		id = fmt.Sprintf("%s_%d", id, device.Interface)
-	}
+func deviceKey(device *hid.DeviceInfo) string {
+	return fmt.Sprintf("%04x:%04x:%s", device.VendorID, device.ProductID, device.Serial)
+}
 
-	// Add serial suffix if available (for additional uniqueness)
-	if device.Serial != "" {
-		serialSuffix := reg.ReplaceAllString(strings.ToLower(device.Serial), "_")
-		serialSuffix = strings.Trim(serialSuffix, "_")
-		if serialSuffix != "" {
-			id = fmt.Sprintf("%s_%s", id, serialSuffix)
-		}
+// idTemplateFlag returns the validated --id-template.
+func idTemplateFlag(cmd *cli.Command) (string, error) {
+	idTemplate := cmd.String("id-template")
+	if err := config.ValidateScannerIDTemplate(idTemplate); err != nil {
+		return "", newExitError(ExitConfigError, fmt.Errorf("--id-template %w", err))
 	}
+	return idTemplate, nil
+}
 
-	return id
+// defaultInstanceID is the instance_id of a configuration without one, used
+// for {instance} before a configuration exists.
+func defaultInstanceID() string {
+	hostname, _ := os.Hostname()
+	return hostname
 }
 
-func (c *CLI) listDevices() error {
+func (c *CLI) listDevices(idTemplate string) error {
 	allDevices := scanner.ListAllDevices()
 	if len(allDevices) == 0 {
 		fmt.Println("# No HID devices found - check permissions or udev rules")
 		return nil
 	}
 
+	// Devices exposing several HID interfaces need the interface in their
+	// identification, including the one on interface 0.
+	interfaceCounts := make(map[string]int)
+	for _, device := range allDevices {
+		interfaceCounts[deviceKey(&device)]++
+	}
+
+	generateID := scanner.NewIDGenerator(idTemplate, defaultInstanceID())
 	fmt.Println("scanners:")
 
 	for _, device := range allDevices {
-		// Generate a friendly name
-		name := device.Product
-		if name == "" {
-			name = "Unknown Device"
-		}
-		if device.Manufacturer != "" && device.Manufacturer != name {
-			name = fmt.Sprintf("%s %s", device.Manufacturer, name)
-		}
+		multiInterface := interfaceCounts[deviceKey(&device)] > 1
 
-		// Generate scanner ID based on device info
-		scannerID := generateScannerID(name, &device)
+		name := scanner.DeviceDisplayName(&device)
+		scannerID := generateID(name, &device)
 
 		fmt.Printf("  %s:\n", scannerID)
 
@@ -208,7 +353,7 @@ func (c *CLI) listDevices() error {
 			fmt.Printf("    # Product: %s\n", device.Product)
 		}
 
-		if device.Interface > 0 {
+		if multiInterface {
 			fmt.Printf("    # Note: Multiple interfaces found for device %04x:%04x (serial: %s).\n",
 				device.VendorID, device.ProductID, device.Serial)
 			fmt.Printf("    # Test which interface responds to scans.\n")
@@ -221,7 +366,7 @@ func (c *CLI) listDevices() error {
 		if device.Serial != "" {
 			fmt.Printf("      serial: \"%s\"\n", device.Serial)
 		}
-		if device.Interface > 0 {
+		if multiInterface {
 			fmt.Printf("      interface: %d  # Specify which interface to use\n", device.Interface)
 		}
 		fmt.Printf("    termination_char: \"tab\"  # Options: enter, tab, none\n")
@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const doctorPublishTimeout = 5 * time.Second
+
+// udevRuleDirs are searched for rules granting access to the configured
+// vendor IDs, in the order udev reads them.
+var udevRuleDirs = []string{"/etc/udev/rules.d", "/run/udev/rules.d", "/lib/udev/rules.d", "/usr/lib/udev/rules.d"}
+
+// doctorResult is the outcome of a single doctor check. A failed check
+// carries a hint on how to fix it.
+type doctorResult struct {
+	name   string
+	detail string
+	err    error
+	hint   string
+}
+
+func passed(name, detail string) doctorResult {
+	return doctorResult{name: name, detail: detail}
+}
+
+func failed(name string, err error, hint string) doctorResult {
+	return doctorResult{name: name, err: err, hint: hint}
+}
+
+// runDoctor checks HID access, the configured devices and the MQTT broker,
+// printing a pass/fail line with remediation hints for each check.
+func (c *CLI) runDoctor(_ context.Context, cmd *cli.Command) error {
+	c.logger = c.setupLogger(cmd)
+
+	cfg, err := config.LoadConfig(cmd.String("config"))
+	if err != nil {
+		return newExitError(ExitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	c.applyConfigLogging(cmd, cfg)
+	if !cmd.IsSet("log-level") {
+		// Keep driver and client logs from interleaving with the results
+		c.logger.SetLevel(logrus.FatalLevel)
+	}
+
+	results := checkHIDAccess(cfg)
+	results = append(results, checkUdevRules(cfg, udevRuleDirs)...)
+	results = append(results, c.checkScanners(cfg)...)
+	results = append(results, c.checkMQTT(cfg)...)
+
+	if failures := printDoctorResults(os.Stdout, results); failures > 0 {
+		return fmt.Errorf("%d of %d checks failed", failures, len(results))
+	}
+	return nil
+}
+
+// printDoctorResults prints one line per check and a summary, returning the
+// number of failed checks.
+func printDoctorResults(out io.Writer, results []doctorResult) int {
+	failures := 0
+	for _, result := range results {
+		if result.err == nil {
+			_, _ = fmt.Fprintf(out, "[PASS] %s: %s\n", result.name, result.detail)
+			continue
+		}
+
+		failures++
+		_, _ = fmt.Fprintf(out, "[FAIL] %s: %v\n", result.name, result.err)
+		if result.hint != "" {
+			_, _ = fmt.Fprintf(out, "       hint: %s\n", result.hint)
+		}
+	}
+
+	_, _ = fmt.Fprintf(out, "\n%d passed, %d failed\n", len(results)-failures, failures)
+	return failures
+}
+
+func usesHIDDriver(cfg *config.Config) bool {
+	for id := range cfg.Scanners {
+		driver := strings.ToLower(cfg.Scanners[id].Driver)
+		if driver == "" || driver == config.DriverHID {
+			return true
+		}
+	}
+	return cfg.AutoDiscover
+}
+
+// checkHIDAccess reports whether HID support is compiled in and, on Linux,
+// whether the hidraw device nodes can be opened by the current user.
+func checkHIDAccess(cfg *config.Config) []doctorResult {
+	if !usesHIDDriver(cfg) {
+		return nil
+	}
+
+	info := scanner.HIDBackendInfo()
+	if supported, _ := info["hid_supported"].(bool); !supported {
+		return []doctorResult{failed("HID support", errors.New("this build has no HID support"),
+			"use a release binary or build with CGO_ENABLED=1")}
+	}
+	results := []doctorResult{passed("HID support", fmt.Sprintf("%v %v", info["hid_backend"], info["hid_library_version"]))}
+
+	if runtime.GOOS != "linux" {
+		return results
+	}
+
+	nodes, _ := filepath.Glob("/dev/hidraw*")
+	if len(nodes) == 0 {
+		return append(results, failed("HID permissions", errors.New("no /dev/hidraw* device nodes"),
+			"plug in the scanner; in Docker pass the devices with --device or mount /dev"))
+	}
+
+	var denied []string
+	for _, node := range nodes {
+		file, err := os.OpenFile(node, os.O_RDWR, 0) // #nosec G304 - fixed device node pattern
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				denied = append(denied, node)
+			}
+			continue
+		}
+		_ = file.Close()
+	}
+	if len(denied) > 0 {
+		return append(results, failed("HID permissions",
+			fmt.Errorf("%d of %d hidraw devices cannot be opened: %s", len(denied), len(nodes), strings.Join(denied, ", ")),
+			"add a udev rule for your scanners (see Device Permissions in the README) or run as a member of the device group"))
+	}
+	return append(results, passed("HID permissions", fmt.Sprintf("all %d hidraw devices can be opened", len(nodes))))
+}
+
+// checkUdevRules looks for a udev rule matching the vendor ID of each
+// configured HID scanner. It is skipped when running as root.
+func checkUdevRules(cfg *config.Config, ruleDirs []string) []doctorResult {
+	if runtime.GOOS != "linux" || os.Geteuid() == 0 {
+		return nil
+	}
+
+	var vendorIDs []string
+	for id := range cfg.Scanners {
+		scannerConfig := cfg.Scanners[id]
+		driver := strings.ToLower(scannerConfig.Driver)
+		if (driver == "" || driver == config.DriverHID) && scannerConfig.Identification.VendorID != 0 {
+			vendorIDs = append(vendorIDs, fmt.Sprintf("%04x", scannerConfig.Identification.VendorID))
+		}
+	}
+	slices.Sort(vendorIDs)
+	vendorIDs = slices.Compact(vendorIDs)
+
+	rules := readUdevRules(ruleDirs)
+	results := make([]doctorResult, 0, len(vendorIDs))
+	for _, vendorID := range vendorIDs {
+		name := fmt.Sprintf("udev rule for vendor %s", vendorID)
+		if file := findUdevRule(rules, vendorID); file != "" {
+			results = append(results, passed(name, file))
+			continue
+		}
+		results = append(results, failed(name, errors.New("no udev rule grants access to the device"),
+			fmt.Sprintf(`add SUBSYSTEM=="hidraw", ATTRS{idVendor}=="%s", MODE="0666" to /etc/udev/rules.d/99-barcode-scanner.rules`,
+				vendorID)))
+	}
+	return results
+}
+
+// readUdevRules returns the contents of the rule files by path.
+func readUdevRules(ruleDirs []string) map[string]string {
+	rules := make(map[string]string)
+	for _, dir := range ruleDirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.rules"))
+		for _, file := range files {
+			data, err := os.ReadFile(file) // #nosec G304 - udev rule files
+			if err == nil {
+				rules[file] = strings.ToLower(string(data))
+			}
+		}
+	}
+	return rules
+}
+
+// findUdevRule returns the first rule file matching the vendor ID.
+func findUdevRule(rules map[string]string, vendorID string) string {
+	files := make([]string, 0, len(rules))
+	for file := range rules {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+
+	match := fmt.Sprintf(`attrs{idvendor}=="%s"`, strings.ToLower(vendorID))
+	for _, file := range files {
+		if strings.Contains(strings.ReplaceAll(rules[file], " ", ""), match) {
+			return file
+		}
+	}
+	return ""
+}
+
+// checkScanners opens each configured device once.
+func (c *CLI) checkScanners(cfg *config.Config) []doctorResult {
+	scannerIDs := make([]string, 0, len(cfg.Scanners))
+	for id := range cfg.Scanners {
+		scannerIDs = append(scannerIDs, id)
+	}
+	slices.Sort(scannerIDs)
+
+	results := make([]doctorResult, 0, len(scannerIDs))
+	for _, id := range scannerIDs {
+		scannerConfig := cfg.Scanners[id]
+		name := fmt.Sprintf("scanner %s", id)
+
+		device, err := scanner.NewScanner(&scannerConfig, c.logger)
+		if err == nil {
+			err = device.TryInitialConnect()
+		}
+		if err != nil {
+			results = append(results, failed(name, err, scannerOpenHint(err)))
+			continue
+		}
+		results = append(results, passed(name, "device opened"))
+	}
+	return results
+}
+
+func scannerOpenHint(err error) string {
+	switch {
+	case errors.Is(err, scanner.ErrDeviceOpenFailed), errors.Is(err, fs.ErrPermission):
+		return "the device exists but cannot be opened: check the udev rules, or whether another process holds it"
+	case errors.Is(err, scanner.ErrDeviceNotFound), strings.Contains(err.Error(), "not found"):
+		return "check that the scanner is plugged in and compare its identification with --list-devices"
+	default:
+		return "check the scanner configuration"
+	}
+}
+
+// checkMQTT connects to the broker and verifies that messages published
+// under the discovery prefix are delivered, which also catches broker ACLs
+// silently dropping them.
+func (c *CLI) checkMQTT(cfg *config.Config) []doctorResult {
+	client, err := mqtt.NewClient(&cfg.MQTT, "", c.logger)
+	if err != nil {
+		return []doctorResult{failed("MQTT connection", err, "check the mqtt section of the configuration")}
+	}
+
+	if err := client.ConnectWithRetry(0, 0); err != nil {
+		client.Disconnect() // Stop the background connect retries
+		return []doctorResult{failed("MQTT connection", err, mqttConnectHint(err))}
+	}
+	defer client.Disconnect()
+
+	results := []doctorResult{passed("MQTT connection", strings.Join(cfg.MQTT.BrokerURLs(), ", "))}
+
+	name := fmt.Sprintf("discovery prefix '%s'", cfg.HomeAssistant.DiscoveryPrefix)
+	if err := checkTopicWritable(client, doctorTopic(&cfg.HomeAssistant)); err != nil {
+		return append(results, failed(name, err,
+			"allow the MQTT user to publish and subscribe to "+cfg.HomeAssistant.DiscoveryPrefix+"/# in the broker ACL"))
+	}
+	return append(results, passed(name, "writable"))
+}
+
+// doctorTopic is a topic under the discovery prefix that Home Assistant
+// ignores, since it does not end in /config.
+func doctorTopic(haConfig *config.HomeAssistantConfig) string {
+	return strings.TrimSuffix(homeassistant.GenerateBridgeAvailabilityTopic(haConfig), "/availability") + "/doctor"
+}
+
+func checkTopicWritable(client mqtt.Client, topic string) error {
+	payload := fmt.Sprintf("doctor-%d", time.Now().UnixNano())
+	received := make(chan struct{}, 1)
+
+	if err := client.Subscribe(topic, func(_ string, data []byte) {
+		if string(data) == payload {
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	if err := client.Publish(topic, payload, false); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+
+	select {
+	case <-received:
+		return nil
+	case <-time.After(doctorPublishTimeout):
+		return fmt.Errorf("message published to %s was not delivered within %s", topic, doctorPublishTimeout)
+	}
+}
+
+func mqttConnectHint(err error) string {
+	switch {
+	case errors.Is(err, mqtt.ErrAuthRejected):
+		return "check mqtt.username and mqtt.password, and the user's permissions on the broker"
+	case errors.Is(err, mqtt.ErrClientIDRejected):
+		return "set a different mqtt.client_id"
+	case errors.Is(err, mqtt.ErrProtocolRejected):
+		return "the broker does not support MQTT 3.1.1; upgrade it or enable the protocol"
+	default:
+		return "check mqtt.broker_url and that the broker is reachable from this host"
+	}
+}
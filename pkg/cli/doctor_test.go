@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+func TestPrintDoctorResults(t *testing.T) {
+	var out bytes.Buffer
+	failures := printDoctorResults(&out, []doctorResult{
+		passed("MQTT connection", "mqtt://broker:1883"),
+		failed("scanner office", errors.New("device not found"), "plug it in"),
+	})
+
+	if failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", failures)
+	}
+
+	expected := "[PASS] MQTT connection: mqtt://broker:1883\n" +
+		"[FAIL] scanner office: device not found\n" +
+		"       hint: plug it in\n" +
+		"\n1 passed, 1 failed\n"
+	if out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestFindUdevRule(t *testing.T) {
+	dir := t.TempDir()
+	rule := `SUBSYSTEM=="hidraw", ATTRS{idVendor}=="060E", MODE="0666"`
+	if err := os.WriteFile(filepath.Join(dir, "99-barcode-scanner.rules"), []byte(rule), 0600); err != nil {
+		t.Fatalf("Failed to write rule: %v", err)
+	}
+	rules := readUdevRules([]string{dir, filepath.Join(dir, "missing")})
+
+	tests := []struct {
+		vendorID string
+		expected string
+	}{
+		{"060e", filepath.Join(dir, "99-barcode-scanner.rules")},
+		{"05e0", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.vendorID, func(t *testing.T) {
+			if result := findUdevRule(rules, tt.vendorID); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestScannerOpenHint(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"open failed", fmt.Errorf("%w: 060e:16c7: busy", scanner.ErrDeviceOpenFailed), "udev rules"},
+		{"permission", os.ErrPermission, "udev rules"},
+		{"not found", errors.New("060e:16c7 not found"), "--list-devices"},
+		{"other", errors.New("invalid baud rate"), "scanner configuration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hint := scannerOpenHint(tt.err); !strings.Contains(hint, tt.expected) {
+				t.Errorf("Expected hint containing %q, got %q", tt.expected, hint)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+// Process exit codes, so service managers can apply a restart policy per
+// failure class (e.g. systemd RestartPreventExitStatus=2 4).
+const (
+	ExitOK              = 0
+	ExitFailure         = 1 // Unclassified runtime failure
+	ExitConfigError     = 2 // Missing or invalid configuration
+	ExitPermissionError = 3 // Scanner devices exist but cannot be opened
+	ExitMQTTAuthError   = 4 // Broker rejected the MQTT credentials
+	ExitNoDevices       = 5 // None of the configured scanners is present
+	ExitLostLeadership  = 6 // Another bridge took over as failover leader
+)
+
+// ExitError carries the exit code for a classified failure.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func newExitError(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode returns the process exit code for an error returned by Run.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	switch {
+	case errors.Is(err, mqtt.ErrAuthRejected):
+		return ExitMQTTAuthError
+	case errors.Is(err, mqtt.ErrClientIDRejected), errors.Is(err, mqtt.ErrProtocolRejected):
+		return ExitConfigError
+	case errors.Is(err, scanner.ErrPermissionDenied):
+		return ExitPermissionError
+	case errors.Is(err, scanner.ErrNoScannersAvailable):
+		return ExitNoDevices
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"No error", nil, ExitOK},
+		{"Unclassified", errors.New("boom"), ExitFailure},
+		{"Config error", newExitError(ExitConfigError, errors.New("invalid")), ExitConfigError},
+		{"MQTT auth", fmt.Errorf("MQTT connection failed: %w", mqtt.ErrAuthRejected), ExitMQTTAuthError},
+		{
+			"Permission denied",
+			fmt.Errorf("failed to start service scanner: %w", scanner.ErrPermissionDenied),
+			ExitPermissionError,
+		},
+		{
+			"No devices",
+			fmt.Errorf("failed to start service scanner: %w", scanner.ErrNoScannersAvailable),
+			ExitNoDevices,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ExitCode(tt.err); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
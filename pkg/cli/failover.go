@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/failover"
+)
+
+// runFailover waits as standby until this bridge is elected leader, then
+// runs the application until shutdown or until another bridge takes over.
+func (c *CLI) runFailover(cfg *config.Config, configPath string) error {
+	coordinator, err := failover.NewCoordinator(cfg, c.logger)
+	if err != nil {
+		return err
+	}
+	if err := coordinator.Start(); err != nil {
+		return err
+	}
+
+	c.logger.WithField("node_id", cfg.Failover.NodeID).Info("Standing by until elected failover leader")
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-coordinator.Promoted():
+		signal.Stop(sigCh)
+	case sig := <-sigCh:
+		c.logger.Warnf("Received signal: %v", sig)
+		return coordinator.Stop()
+	}
+
+	shutdownCh, err := c.startApp(cfg, configPath)
+	if err != nil {
+		_ = coordinator.Stop()
+		return err
+	}
+
+	select {
+	case <-shutdownCh:
+		// Stop correcting the bridge availability before the application publishes it offline
+		coordinator.Resign()
+		err := c.app.Stop()
+		_ = coordinator.Stop()
+		return err
+	case <-coordinator.Demoted():
+		// Exit without stopping the application: its offline messages would
+		// override the new leader. The MQTT will marks the bridge offline, which
+		// the new leader corrects.
+		_ = coordinator.Stop()
+		return newExitError(ExitLostLeadership, fmt.Errorf("another bridge took over as failover leader"))
+	}
+}
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const (
+	inventoryFormatJSON = "json"
+	inventoryFormatCSV  = "csv"
+
+	inventoryStateConnected    = "connected"
+	inventoryStateDisconnected = "disconnected"
+	inventoryStateUnknown      = "unknown"
+
+	inventoryRequestTimeout = 5 * time.Second
+)
+
+// inventoryEntry is one scanner of the inventory: a configured scanner, or
+// an attached device that looks like a scanner but isn't configured. The
+// health counters come from the running bridge and are missing when it can't
+// be reached.
+type inventoryEntry struct {
+	Host            string     `json:"host"`
+	InstanceID      string     `json:"instance_id"`
+	ScannerID       string     `json:"scanner_id,omitempty"`
+	Name            string     `json:"name"`
+	Driver          string     `json:"driver"`
+	Configured      bool       `json:"configured"`
+	Attached        bool       `json:"attached"`
+	VendorID        string     `json:"vendor_id,omitempty"`
+	ProductID       string     `json:"product_id,omitempty"`
+	Serial          string     `json:"serial,omitempty"`
+	Manufacturer    string     `json:"manufacturer,omitempty"`
+	Product         string     `json:"product,omitempty"`
+	Firmware        string     `json:"firmware,omitempty"`
+	Interface       *int       `json:"interface,omitempty"`
+	Path            string     `json:"path,omitempty"`
+	ConnectionState string     `json:"connection_state"`
+	TotalScans      *int64     `json:"total_scans,omitempty"`
+	ReconnectCount  *int       `json:"reconnect_count,omitempty"`
+	FlapCount       *int       `json:"flap_count,omitempty"`
+	ErrorCount      *int       `json:"error_count,omitempty"`
+	BatteryLevel    *int       `json:"battery_level,omitempty"`
+	LastScanTime    *time.Time `json:"last_scan_time,omitempty"`
+}
+
+// bridgeScannerStatus is an entry of the /api/scanners response of a running
+// bridge.
+type bridgeScannerStatus struct {
+	ID             string     `json:"id"`
+	Connected      bool       `json:"connected"`
+	TotalScans     int64      `json:"total_scans"`
+	ReconnectCount int        `json:"reconnect_count"`
+	FlapCount      int        `json:"flap_count"`
+	ErrorCount     int        `json:"error_count"`
+	BatteryLevel   *int       `json:"battery_level,omitempty"`
+	LastScanTime   *time.Time `json:"last_scan_time,omitempty"`
+}
+
+var inventoryCSVHeader = []string{
+	"host", "instance_id", "scanner_id", "name", "driver", "configured", "attached",
+	"vendor_id", "product_id", "serial", "manufacturer", "product", "firmware", "interface", "path",
+	"connection_state", "total_scans", "reconnect_count", "flap_count", "error_count", "battery_level", "last_scan_time",
+}
+
+// runExportInventory writes the configured and attached scanners of this
+// host, with the connection state and health counters of the running bridge.
+func (c *CLI) runExportInventory(ctx context.Context, cmd *cli.Command) error {
+	c.logger = c.setupLogger(cmd)
+
+	format := strings.ToLower(cmd.String("format"))
+	if format != inventoryFormatJSON && format != inventoryFormatCSV {
+		return newExitError(ExitConfigError, fmt.Errorf("--format '%s' must be one of: %s, %s",
+			format, inventoryFormatJSON, inventoryFormatCSV))
+	}
+
+	cfg, err := config.LoadConfig(cmd.String("config"))
+	if err != nil {
+		return newExitError(ExitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	c.applyConfigLogging(cmd, cfg)
+
+	var statuses map[string]bridgeScannerStatus
+	if bridgeURL, auth := bridgeAPI(cfg, cmd.String("bridge-url")); bridgeURL != "" {
+		httpClient := &http.Client{Timeout: inventoryRequestTimeout}
+		if statuses, err = fetchBridgeScannerStatuses(ctx, httpClient, bridgeURL, auth); err != nil {
+			c.logger.WithError(err).Warnf("Running bridge not reachable at %s, connection states are unknown", bridgeURL)
+		}
+	}
+
+	host, _ := os.Hostname()
+	entries := buildInventory(cfg, scanner.ListAllDevices(), statuses, host)
+
+	out := io.Writer(os.Stdout)
+	if path := cmd.String("output"); path != "" {
+		file, err := os.Create(path) // #nosec G304
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		defer func() { _ = file.Close() }()
+		out = file
+	}
+
+	if format == inventoryFormatCSV {
+		return writeInventoryCSV(out, entries)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// buildInventory lists the configured scanners in ID order, followed by the
+// attached devices that look like scanners but no configured scanner opens.
+// statuses is nil when the running bridge couldn't be reached.
+func buildInventory(
+	cfg *config.Config, devices []hid.DeviceInfo, statuses map[string]bridgeScannerStatus, host string,
+) []inventoryEntry {
+	ids := make([]string, 0, len(cfg.Scanners))
+	for id := range cfg.Scanners {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	entries := make([]inventoryEntry, 0, len(ids))
+	for _, id := range ids {
+		scannerConfig := cfg.Scanners[id]
+		entry := inventoryEntry{
+			Host:            host,
+			InstanceID:      cfg.HomeAssistant.InstanceID,
+			ScannerID:       id,
+			Name:            scannerConfig.Name,
+			Driver:          cmp.Or(strings.ToLower(scannerConfig.Driver), config.DriverHID),
+			Configured:      true,
+			ConnectionState: inventoryStateUnknown,
+		}
+		if device, found := scanner.ClaimedDevice(&scannerConfig, devices); found {
+			entry.setDevice(device)
+		} else if entry.Driver == config.DriverHID {
+			entry.VendorID = fmt.Sprintf("%04x", scannerConfig.Identification.VendorID)
+			entry.ProductID = fmt.Sprintf("%04x", scannerConfig.Identification.ProductID)
+			entry.Serial = scannerConfig.Identification.Serial
+			entry.Interface = scannerConfig.Identification.Interface
+		}
+		if status, exists := statuses[id]; exists {
+			entry.setStatus(&status)
+		} else if statuses != nil {
+			// The bridge only lists scanners whose device it has found
+			entry.ConnectionState = inventoryStateDisconnected
+		}
+		entries = append(entries, entry)
+	}
+
+	unclaimed := scanner.UnclaimedDevices(cfg.Scanners, devices)
+	for i := range unclaimed {
+		if !scanner.IsLikelyBarcodeScanner(&unclaimed[i]) {
+			continue
+		}
+		entry := inventoryEntry{
+			Host:            host,
+			InstanceID:      cfg.HomeAssistant.InstanceID,
+			Name:            scanner.DeviceDisplayName(&unclaimed[i]),
+			Driver:          config.DriverHID,
+			ConnectionState: inventoryStateDisconnected,
+		}
+		entry.setDevice(&unclaimed[i])
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (e *inventoryEntry) setDevice(device *hid.DeviceInfo) {
+	iface := device.Interface
+	e.Attached = true
+	e.VendorID = fmt.Sprintf("%04x", device.VendorID)
+	e.ProductID = fmt.Sprintf("%04x", device.ProductID)
+	e.Serial = device.Serial
+	e.Manufacturer = device.Manufacturer
+	e.Product = device.Product
+	e.Firmware = formatFirmwareRelease(device.Release)
+	e.Interface = &iface
+	e.Path = device.Path
+}
+
+func (e *inventoryEntry) setStatus(status *bridgeScannerStatus) {
+	e.ConnectionState = inventoryStateDisconnected
+	if status.Connected {
+		e.ConnectionState = inventoryStateConnected
+	}
+	e.TotalScans = &status.TotalScans
+	e.ReconnectCount = &status.ReconnectCount
+	e.FlapCount = &status.FlapCount
+	e.ErrorCount = &status.ErrorCount
+	e.BatteryLevel = status.BatteryLevel
+	e.LastScanTime = status.LastScanTime
+}
+
+// formatFirmwareRelease formats the BCD encoded USB device release, e.g.
+// 0x0102 as "1.02". Devices not reporting one have zero.
+func formatFirmwareRelease(release uint16) string {
+	if release == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%x.%02x", release>>8, release&0xff)
+}
+
+func writeInventoryCSV(out io.Writer, entries []inventoryEntry) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		lastScan := ""
+		if entry.LastScanTime != nil {
+			lastScan = entry.LastScanTime.Format(time.RFC3339)
+		}
+		record := []string{
+			entry.Host, entry.InstanceID, entry.ScannerID, entry.Name, entry.Driver,
+			strconv.FormatBool(entry.Configured), strconv.FormatBool(entry.Attached),
+			entry.VendorID, entry.ProductID, entry.Serial, entry.Manufacturer, entry.Product, entry.Firmware,
+			optionalInt(entry.Interface), entry.Path, entry.ConnectionState,
+			optionalInt64(entry.TotalScans), optionalInt(entry.ReconnectCount), optionalInt(entry.FlapCount),
+			optionalInt(entry.ErrorCount), optionalInt(entry.BatteryLevel), lastScan,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func optionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func optionalInt64(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(*value, 10)
+}
+
+// bridgeAPI returns the base URL of the running bridge's API and the
+// credentials of its listener: the --bridge-url flag, or the first listener
+// serving "api", reached through localhost.
+func bridgeAPI(cfg *config.Config, flagURL string) (string, *config.HTTPAuthConfig) {
+	for i := range cfg.HTTP.Listeners {
+		listener := &cfg.HTTP.Listeners[i]
+		if !slices.Contains(listener.Serve, config.HTTPServeAPI) {
+			continue
+		}
+		if flagURL != "" {
+			return flagURL, &listener.Auth
+		}
+		host, port, err := net.SplitHostPort(listener.Address)
+		if err != nil {
+			continue
+		}
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "localhost"
+		}
+		scheme := "http"
+		if listener.TLS.CertFile != "" {
+			scheme = "https"
+		}
+		return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port)), &listener.Auth
+	}
+	return flagURL, &config.HTTPAuthConfig{}
+}
+
+// fetchBridgeScannerStatuses returns the scanners of the running bridge by ID.
+func fetchBridgeScannerStatuses(
+	ctx context.Context, client *http.Client, baseURL string, auth *config.HTTPAuthConfig,
+) (map[string]bridgeScannerStatus, error) {
+	url := strings.TrimRight(baseURL, "/") + "/api/scanners"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	switch {
+	case auth.Token != "":
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case auth.Username != "":
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var scanners []bridgeScannerStatus
+	if err := json.NewDecoder(resp.Body).Decode(&scanners); err != nil {
+		return nil, fmt.Errorf("failed to decode scanners: %w", err)
+	}
+	statuses := make(map[string]bridgeScannerStatus, len(scanners))
+	for _, status := range scanners {
+		statuses[status.ID] = status
+	}
+	return statuses, nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestBuildInventory(t *testing.T) {
+	cfg := &config.Config{
+		HomeAssistant: config.HomeAssistantConfig{InstanceID: "home"},
+		Scanners: map[string]config.ScannerConfig{
+			"office":  {Name: "Office", Identification: config.ScannerIdentification{VendorID: 0x0c2e, ProductID: 0x0b61}},
+			"kitchen": {Name: "Kitchen", Identification: config.ScannerIdentification{VendorID: 0x05e0, ProductID: 0x1200}},
+			"dock":    {Name: "Dock", Driver: "serial"},
+		},
+	}
+	devices := []hid.DeviceInfo{
+		{Path: "/dev/hidraw0", VendorID: 0x0c2e, ProductID: 0x0b61, Serial: "S1", Product: "Voyager", Release: 0x0102},
+		{Path: "/dev/hidraw1", VendorID: 0x046d, ProductID: 0xc31c, Product: "USB Keyboard", UsagePage: 0x01, Usage: 0x06},
+		{Path: "/dev/hidraw2", VendorID: 0x1eab, ProductID: 0x1d06, Product: "Barcode Scanner", UsagePage: 0x8c},
+	}
+	statuses := map[string]bridgeScannerStatus{
+		"office": {ID: "office", Connected: true, TotalScans: 42, ReconnectCount: 1},
+	}
+
+	entries := buildInventory(cfg, devices, statuses, "host1")
+	if len(entries) != 4 {
+		t.Fatalf("Expected 3 configured scanners and 1 unconfigured device, got %d entries", len(entries))
+	}
+
+	dock, kitchen, office, unconfigured := entries[0], entries[1], entries[2], entries[3]
+	if dock.ScannerID != "dock" || dock.Driver != "serial" || dock.Attached || dock.ConnectionState != inventoryStateDisconnected {
+		t.Errorf("Expected detached serial scanner 'dock', got %+v", dock)
+	}
+	if kitchen.Attached || kitchen.VendorID != "05e0" || kitchen.ConnectionState != inventoryStateDisconnected {
+		t.Errorf("Expected detached scanner 'kitchen' with its configured IDs, got %+v", kitchen)
+	}
+	if !office.Attached || office.Serial != "S1" || office.Firmware != "1.02" || office.Path != "/dev/hidraw0" {
+		t.Errorf("Expected attached scanner 'office' with device details, got %+v", office)
+	}
+	if office.ConnectionState != inventoryStateConnected || office.TotalScans == nil || *office.TotalScans != 42 {
+		t.Errorf("Expected connected scanner 'office' with 42 scans, got %+v", office)
+	}
+	if unconfigured.Configured || unconfigured.ScannerID != "" || unconfigured.VendorID != "1eab" {
+		t.Errorf("Expected unconfigured scanner 1eab, got %+v", unconfigured)
+	}
+	for i := range entries {
+		if entries[i].Host != "host1" || entries[i].InstanceID != "home" {
+			t.Errorf("Expected host 'host1' and instance 'home', got %+v", entries[i])
+		}
+	}
+}
+
+func TestBuildInventory_BridgeUnreachable(t *testing.T) {
+	cfg := &config.Config{
+		Scanners: map[string]config.ScannerConfig{"office": {Identification: config.ScannerIdentification{VendorID: 0x0c2e}}},
+	}
+
+	entries := buildInventory(cfg, nil, nil, "host1")
+	if len(entries) != 1 || entries[0].ConnectionState != inventoryStateUnknown || entries[0].TotalScans != nil {
+		t.Errorf("Expected unknown state without counters, got %+v", entries)
+	}
+}
+
+func TestWriteInventoryCSV(t *testing.T) {
+	scans, iface := int64(7), 0
+	entries := []inventoryEntry{
+		{Host: "host1", ScannerID: "office", Name: "Office, front", Driver: "hid", Configured: true, Attached: true,
+			Interface: &iface, ConnectionState: inventoryStateConnected, TotalScans: &scans},
+	}
+
+	var buf bytes.Buffer
+	if err := writeInventoryCSV(&buf, entries); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got %v", err)
+	}
+	if len(records) != 2 || len(records[1]) != len(inventoryCSVHeader) {
+		t.Fatalf("Expected a header and one record of %d fields, got %v", len(inventoryCSVHeader), records)
+	}
+	record := map[string]string{}
+	for i, field := range inventoryCSVHeader {
+		record[field] = records[1][i]
+	}
+	if record["name"] != "Office, front" || record["interface"] != "0" || record["total_scans"] != "7" ||
+		record["battery_level"] != "" || record["connection_state"] != inventoryStateConnected {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
+
+func TestFetchBridgeScannerStatuses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/scanners" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":"office","connected":true,"total_scans":3,"error_count":2}]`))
+	}))
+	defer server.Close()
+
+	statuses, err := fetchBridgeScannerStatuses(context.Background(), server.Client(), server.URL, &config.HTTPAuthConfig{Token: "secret"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status := statuses["office"]; !status.Connected || status.TotalScans != 3 || status.ErrorCount != 2 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	if _, err := fetchBridgeScannerStatuses(context.Background(), server.Client(), server.URL, &config.HTTPAuthConfig{}); err == nil {
+		t.Error("Expected error without credentials")
+	}
+}
+
+func TestBridgeAPI(t *testing.T) {
+	tests := []struct {
+		name      string
+		listeners []config.HTTPListenerConfig
+		flagURL   string
+		expected  string
+	}{
+		{"no API listener", []config.HTTPListenerConfig{{Address: ":9100", Serve: []string{"metrics"}}}, "", ""},
+		{"any address", []config.HTTPListenerConfig{{Address: ":8080", Serve: []string{"api"}}}, "", "http://localhost:8080"},
+		{"TLS", []config.HTTPListenerConfig{
+			{Address: "10.0.0.2:8443", Serve: []string{"api"}, TLS: config.HTTPTLSConfig{CertFile: "cert.pem"}},
+		}, "", "https://10.0.0.2:8443"},
+		{"flag", []config.HTTPListenerConfig{{Address: ":8080", Serve: []string{"api"}}}, "http://bridge:8080", "http://bridge:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{HTTP: config.HTTPConfig{Listeners: tt.listeners}}
+			if url, _ := bridgeAPI(cfg, tt.flagURL); url != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, url)
+			}
+		})
+	}
+}
+
+func TestFormatFirmwareRelease(t *testing.T) {
+	tests := map[uint16]string{0: "", 0x0102: "1.02", 0x1234: "12.34"}
+	for release, expected := range tests {
+		if got := formatFirmwareRelease(release); got != expected {
+			t.Errorf("Expected '%s' for %04x, got '%s'", expected, release, got)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+// confirmLearnedLayouts walks the unmapped keycodes recorded for each scanner
+// with a learned_layout file and asks for the intended character.
+func (c *CLI) confirmLearnedLayouts(cfg *config.Config, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	confirmed := 0
+
+	scannerIDs := make([]string, 0, len(cfg.Scanners))
+	for id := range cfg.Scanners {
+		scannerIDs = append(scannerIDs, id)
+	}
+	slices.Sort(scannerIDs)
+
+	for _, id := range scannerIDs {
+		path := cfg.Scanners[id].LearnedLayout
+		if path == "" {
+			continue
+		}
+
+		learned, err := scanner.LoadLearnedLayout(path)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range learned.Pending() {
+			modifier := ""
+			if key.Shifted {
+				modifier = " with shift"
+			}
+			_, _ = fmt.Fprintf(out, "[%s] keycode 0x%02x%s seen %d time(s) after %q - intended character (empty to skip): ",
+				id, key.KeyCode, modifier, key.Seen, key.Sample)
+
+			line, err := reader.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+
+			char := strings.TrimRight(line, "\r\n")
+			if char != "" {
+				if confirmErr := learned.Confirm(key.KeyCode, key.Shifted, char); confirmErr != nil {
+					_, _ = fmt.Fprintf(out, "  skipped: %v\n", confirmErr)
+				} else {
+					confirmed++
+				}
+			}
+
+			if errors.Is(err, io.EOF) {
+				_, _ = fmt.Fprintln(out)
+				break
+			}
+		}
+	}
+
+	if confirmed > 0 {
+		_, _ = fmt.Fprintf(out, "Confirmed %d character(s). Restart the bridge to apply them.\n", confirmed)
+	} else {
+		_, _ = fmt.Fprintln(out, "No characters confirmed.")
+	}
+	return nil
+}
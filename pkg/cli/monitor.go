@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const monitorTimeFormat = "15:04:05.000"
+
+// scanMonitor prints scanner activity to the console: connection changes,
+// raw keyboard reports with the delay since the previous one, and decoded
+// barcodes with the time the scan took.
+type scanMonitor struct {
+	out   io.Writer
+	now   func() time.Time
+	mutex sync.Mutex
+
+	lastReport  map[string]time.Time
+	scanStart   map[string]time.Time
+	scanReports map[string]int
+}
+
+func newScanMonitor(out io.Writer) *scanMonitor {
+	return &scanMonitor{
+		out:         out,
+		now:         time.Now,
+		lastReport:  make(map[string]time.Time),
+		scanStart:   make(map[string]time.Time),
+		scanReports: make(map[string]int),
+	}
+}
+
+func (m *scanMonitor) printf(now time.Time, scannerID, format string, args ...any) {
+	_, _ = fmt.Fprintf(m.out, "%s [%s] %s\n", now.Format(monitorTimeFormat), scannerID, fmt.Sprintf(format, args...))
+}
+
+func (m *scanMonitor) connectionChanged(scannerID string, connected bool, device scanner.Scanner) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	if !connected {
+		m.printf(now, scannerID, "disconnected")
+		return
+	}
+
+	info := device.GetConnectedDeviceInfo()
+	if info == nil {
+		m.printf(now, scannerID, "connected")
+		return
+	}
+	m.printf(now, scannerID, "connected: %s (%04x:%04x interface %d, %s)",
+		scanner.DeviceDisplayName(info), info.VendorID, info.ProductID, info.Interface, info.Path)
+}
+
+func (m *scanMonitor) rawReport(scannerID string, data []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	delta := ""
+	if last, seen := m.lastReport[scannerID]; seen {
+		delta = fmt.Sprintf(" +%s", now.Sub(last).Round(time.Millisecond))
+	}
+	m.lastReport[scannerID] = now
+
+	if _, started := m.scanStart[scannerID]; !started {
+		m.scanStart[scannerID] = now
+	}
+	m.scanReports[scannerID]++
+
+	hexBytes := make([]string, len(data))
+	for i, b := range data {
+		hexBytes[i] = fmt.Sprintf("%02x", b)
+	}
+	m.printf(now, scannerID, "raw%s %s", delta, strings.Join(hexBytes, " "))
+}
+
+func (m *scanMonitor) scan(scannerID, barcode string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	timing := ""
+	if start, started := m.scanStart[scannerID]; started {
+		timing = fmt.Sprintf(", %d reports in %s", m.scanReports[scannerID], now.Sub(start).Round(time.Millisecond))
+	}
+	delete(m.scanStart, scannerID)
+	delete(m.scanReports, scannerID)
+
+	m.printf(now, scannerID, "scan %q (%d chars%s)", barcode, len(barcode), timing)
+}
+
+// runMonitor opens the configured scanners and prints their activity without
+// connecting to MQTT, until interrupted.
+func (c *CLI) runMonitor(_ context.Context, cmd *cli.Command) error {
+	c.logger = c.setupLogger(cmd)
+
+	cfg, err := config.LoadConfig(cmd.String("config"))
+	if err != nil {
+		return newExitError(ExitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	c.applyConfigLogging(cmd, cfg)
+
+	scannerIDs, err := monitoredScannerIDs(cfg, cmd.StringSlice("scanner"))
+	if err != nil {
+		return newExitError(ExitConfigError, err)
+	}
+
+	monitor := newScanMonitor(os.Stdout)
+	started := make([]scanner.Scanner, 0, len(scannerIDs))
+	defer func() {
+		for _, device := range started {
+			_ = device.Stop()
+		}
+	}()
+
+	for _, id := range scannerIDs {
+		scannerConfig := cfg.Scanners[id]
+		device, err := scanner.NewScanner(&scannerConfig, c.logger)
+		if err != nil {
+			return fmt.Errorf("failed to create scanner %s: %w", id, err)
+		}
+
+		device.SetOnScanCallback(func(barcode string) { monitor.scan(id, barcode) })
+		device.SetOnConnectionChangeCallback(func(connected bool) { monitor.connectionChanged(id, connected, device) })
+		if reporter, ok := device.(scanner.RawReportMonitor); ok {
+			reporter.SetOnRawReportCallback(func(data []byte) { monitor.rawReport(id, data) })
+		}
+
+		if err := device.Start(); err != nil {
+			return fmt.Errorf("failed to start scanner %s: %w", id, err)
+		}
+		started = append(started, device)
+	}
+
+	fmt.Printf("Monitoring %s - press Ctrl+C to stop\n", strings.Join(scannerIDs, ", "))
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	<-sigCh
+
+	return nil
+}
+
+// monitoredScannerIDs returns the scanners selected with --scanner, or all
+// configured scanners, in sorted order.
+func monitoredScannerIDs(cfg *config.Config, selected []string) ([]string, error) {
+	if len(selected) == 0 {
+		for id := range cfg.Scanners {
+			selected = append(selected, id)
+		}
+	}
+
+	for _, id := range selected {
+		if _, exists := cfg.Scanners[id]; !exists {
+			return nil, fmt.Errorf("scanner '%s' is not configured", id)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no scanners configured")
+	}
+
+	slices.Sort(selected)
+	return slices.Compact(selected), nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestScanMonitor(t *testing.T) {
+	var out bytes.Buffer
+	monitor := newScanMonitor(&out)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+
+	monitor.rawReport("office", []byte{0x02, 0x00, 0x04})
+	now = now.Add(8 * time.Millisecond)
+	monitor.rawReport("office", []byte{0x00, 0x00, 0x00})
+	now = now.Add(12 * time.Millisecond)
+	monitor.scan("office", "A")
+	monitor.scan("office", "B")
+
+	expected := []string{
+		"12:00:00.000 [office] raw 02 00 04",
+		"12:00:00.008 [office] raw +8ms 00 00 00",
+		`12:00:00.020 [office] scan "A" (1 chars, 2 reports in 20ms)`,
+		`12:00:00.020 [office] scan "B" (1 chars)`,
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %q", len(expected), len(lines), out.String())
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("Expected line %d %q, got %q", i, expected[i], line)
+		}
+	}
+}
+
+func TestMonitoredScannerIDs(t *testing.T) {
+	cfg := &config.Config{Scanners: map[string]config.ScannerConfig{"b": {}, "a": {}}}
+
+	ids, err := monitoredScannerIDs(cfg, nil)
+	if err != nil || strings.Join(ids, ",") != "a,b" {
+		t.Errorf("Expected all scanners sorted, got %v (%v)", ids, err)
+	}
+
+	ids, err = monitoredScannerIDs(cfg, []string{"b", "b"})
+	if err != nil || strings.Join(ids, ",") != "b" {
+		t.Errorf("Expected selected scanner once, got %v (%v)", ids, err)
+	}
+
+	if _, err := monitoredScannerIDs(cfg, []string{"missing"}); err == nil {
+		t.Error("Expected error for unknown scanner")
+	}
+	if _, err := monitoredScannerIDs(&config.Config{}, nil); err == nil {
+		t.Error("Expected error when no scanners are configured")
+	}
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/selftest"
+)
+
+// runSelftest runs the integration with the configured Home Assistant
+// settings against an embedded broker, so a failure points at the bridge or
+// its configuration rather than at the broker or Home Assistant.
+func (c *CLI) runSelftest(ctx context.Context, cmd *cli.Command) error {
+	c.logger = c.setupLogger(cmd)
+
+	cfg, err := config.LoadConfig(cmd.String("config"))
+	if err != nil {
+		return newExitError(ExitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	c.applyConfigLogging(cmd, cfg)
+	if !cmd.IsSet("log-level") {
+		// Keep the integration logs from interleaving with the results
+		c.logger.SetLevel(logrus.FatalLevel)
+	}
+
+	checks := selftest.Run(ctx, cfg.HomeAssistant, common.GetVersion(), c.logger, cmd.Duration("timeout"))
+	results := make([]doctorResult, 0, len(checks))
+	for _, check := range checks {
+		if check.Err != nil {
+			results = append(results, failed(check.Name, check.Err, ""))
+		} else {
+			results = append(results, passed(check.Name, check.Detail))
+		}
+	}
+
+	if failures := printDoctorResults(os.Stdout, results); failures > 0 {
+		return fmt.Errorf("%d of %d checks failed", failures, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+const (
+	testScannerID      = "test"
+	testPublishSuffix  = "-test"
+	testPollInterval   = time.Second
+	testRequestTimeout = 5 * time.Second
+)
+
+// entityStateFetcher returns the current state of a Home Assistant entity.
+type entityStateFetcher func(ctx context.Context, entityID string) (string, error)
+
+// runTestPublish publishes a test scanner under a separate bridge instance
+// and confirms through the Home Assistant REST API that its sensor shows the
+// fake scan.
+func (c *CLI) runTestPublish(ctx context.Context, cmd *cli.Command) error {
+	c.logger = c.setupLogger(cmd)
+
+	cfg, err := config.LoadConfig(cmd.String("config"))
+	if err != nil {
+		return newExitError(ExitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	c.applyConfigLogging(cmd, cfg)
+
+	// A separate instance and client ID leave a running bridge untouched
+	mqttConfig := cfg.MQTT
+	mqttConfig.ClientID += testPublishSuffix
+	haConfig := cfg.HomeAssistant
+	haConfig.InstanceID += testPublishSuffix
+
+	client, err := mqtt.NewClient(&mqttConfig, homeassistant.GenerateBridgeAvailabilityTopic(&haConfig), c.logger)
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(); err != nil {
+		client.Disconnect()
+		return err
+	}
+	defer client.Disconnect()
+
+	integration := homeassistant.NewIntegration(client, &haConfig, common.GetVersion(), c.logger)
+	scannerConfig := config.ScannerConfig{ID: testScannerID, Name: "Test Scanner", TerminationChar: "enter", KeyboardLayout: "us"}
+	integration.AddScanner(testScannerID, scannerConfig.Name, &scannerConfig)
+	if err := integration.Start(); err != nil {
+		return err
+	}
+	integration.SetScannerDeviceInfo(testScannerID, &hid.DeviceInfo{Manufacturer: AppName, Product: "Test Scanner"})
+	if err := integration.SetScannerConnected(testScannerID, true); err != nil {
+		return fmt.Errorf("failed to publish test scanner availability: %w", err)
+	}
+
+	barcode := fmt.Sprintf("TEST-%d", time.Now().Unix())
+	entityID := integration.ScannerEntityID(testScannerID)
+	publish := func() error {
+		return integration.PublishBarcode(testScannerID, barcode, homeassistant.ScanMetadata{ID: common.NewUUID()})
+	}
+	fmt.Printf("Published discovery for %s under '%s' and a test scan %q\n", entityID, haConfig.DiscoveryPrefix, barcode)
+
+	verifyErr := c.verifyTestPublish(ctx, cmd, cfg, entityID, barcode, publish)
+
+	_ = integration.Stop()
+	if cmd.Bool("keep") {
+		fmt.Printf("Keeping the test entities of instance '%s'\n", haConfig.InstanceID)
+		return verifyErr
+	}
+	if err := integration.ClearDiscoveryConfigs(); err != nil {
+		return fmt.Errorf("failed to remove the test entities: %w", err)
+	}
+	fmt.Println("Removed the test entities")
+	return verifyErr
+}
+
+// verifyTestPublish waits for the sensor to show the test scan, or asks for a
+// manual check when no Home Assistant URL and token are available.
+func (c *CLI) verifyTestPublish(
+	ctx context.Context, cmd *cli.Command, cfg *config.Config, entityID, barcode string, publish func() error,
+) error {
+	url, token := cmd.String("ha-url"), cmd.String("ha-token")
+	if cfg.Assist != nil {
+		url = cmp.Or(url, cfg.Assist.URL)
+		token = cmp.Or(token, cfg.Assist.Token)
+	}
+
+	if url == "" || token == "" {
+		if err := publish(); err != nil {
+			return fmt.Errorf("failed to publish test scan: %w", err)
+		}
+		fmt.Printf("Check that %s shows %q in Home Assistant, then press Enter\n", entityID, barcode)
+		fmt.Println("(pass --ha-url and --ha-token to check automatically)")
+		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+		return nil
+	}
+
+	httpClient := &http.Client{Timeout: testRequestTimeout}
+	fetch := func(ctx context.Context, entityID string) (string, error) {
+		return homeassistant.FetchEntityState(ctx, httpClient, url, token, entityID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cmd.Duration("timeout"))
+	defer cancel()
+	if err := waitForEntityState(ctx, os.Stdout, fetch, publish, entityID, barcode, testPollInterval); err != nil {
+		return fmt.Errorf("%s did not show the test scan: %w - check that MQTT discovery is enabled in Home Assistant "+
+			"and that discovery_prefix matches", entityID, err)
+	}
+	fmt.Printf("%s shows the test scan, MQTT discovery works\n", entityID)
+	return nil
+}
+
+// waitForEntityState republishes the test scan until the entity shows it.
+// The state is not retained, so a scan published before Home Assistant
+// subscribed to the new sensor is lost.
+func waitForEntityState(
+	ctx context.Context, out io.Writer, fetch entityStateFetcher, publish func() error,
+	entityID, expected string, interval time.Duration,
+) error {
+	lastState := ""
+	for {
+		if err := publish(); err != nil {
+			return fmt.Errorf("failed to publish test scan: %w", err)
+		}
+
+		state, err := fetch(ctx, entityID)
+		switch {
+		case err == nil && state == expected:
+			return nil
+		case err == nil && state != lastState:
+			_, _ = fmt.Fprintf(out, "  %s is %q, waiting...\n", entityID, state)
+			lastState = state
+		case err != nil && !errors.Is(err, homeassistant.ErrEntityNotFound):
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastState == "" {
+				return homeassistant.ErrEntityNotFound
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
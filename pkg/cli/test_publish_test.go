@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+)
+
+func TestWaitForEntityState(t *testing.T) {
+	tests := []struct {
+		name      string
+		states    []string
+		expectErr error
+	}{
+		{"Shows scan after discovery", []string{"", "unknown", "TEST-1"}, nil},
+		{"Never discovered", []string{"", "", "", ""}, homeassistant.ErrEntityNotFound},
+		{"Discovered without scan", []string{"unknown", "unknown", "unknown", "unknown"}, context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls, publishes := 0, 0
+			fetch := func(context.Context, string) (string, error) {
+				state := tt.states[min(polls, len(tt.states)-1)]
+				polls++
+				if state == "" {
+					return "", homeassistant.ErrEntityNotFound
+				}
+				return state, nil
+			}
+			publish := func() error {
+				publishes++
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := waitForEntityState(ctx, io.Discard, fetch, publish, "sensor.home_test", "TEST-1", time.Millisecond)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if publishes != polls {
+				t.Errorf("Expected a publish before every poll, got %d publishes for %d polls", publishes, polls)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const (
+	versionOutputText = "text"
+	versionOutputJSON = "json"
+)
+
+func (c *CLI) runVersion(_ context.Context, cmd *cli.Command) error {
+	output := strings.ToLower(cmd.String("output"))
+	if output != versionOutputText && output != versionOutputJSON {
+		return newExitError(ExitConfigError, fmt.Errorf("--output '%s' must be one of: %s, %s",
+			output, versionOutputText, versionOutputJSON))
+	}
+	return writeVersion(os.Stdout, buildInfo(), output)
+}
+
+func buildInfo() *common.BuildInfo {
+	info := common.GetBuildInfo()
+	info.Drivers = scanner.AvailableDrivers()
+	info.HIDBackends = scanner.AvailableHIDBackends()
+	return info
+}
+
+// writeVersion prints the build information, as JSON for tooling that
+// verifies rollouts or as text for people.
+func writeVersion(out io.Writer, info *common.BuildInfo, output string) error {
+	if output == versionOutputJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	}
+
+	_, err := fmt.Fprintf(out, `%s %s
+  commit:       %s
+  branch:       %s
+  build date:   %s
+  go version:   %s
+  platform:     %s/%s
+  drivers:      %s
+  hid backends: %s
+`,
+		AppName, info.Version, info.Commit, info.Branch, info.BuildDate, info.GoVersion, info.OS, info.Arch,
+		strings.Join(info.Drivers, ", "), strings.Join(info.HIDBackends, ", "))
+	return err
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+)
+
+func TestWriteVersion(t *testing.T) {
+	info := &common.BuildInfo{
+		Version:     "1.2.3",
+		Commit:      "abc123",
+		Branch:      "main",
+		BuildDate:   "2026-01-02T03:04:05Z",
+		GoVersion:   "go1.26.0",
+		OS:          "linux",
+		Arch:        "arm64",
+		Drivers:     []string{"hid", "tcp"},
+		HIDBackends: []string{"hidapi"},
+	}
+
+	var jsonOut bytes.Buffer
+	if err := writeVersion(&jsonOut, info, versionOutputJSON); err != nil {
+		t.Fatalf("Expected JSON output, got error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	for key, expected := range map[string]string{"version": "1.2.3", "commit": "abc123", "build_date": "2026-01-02T03:04:05Z"} {
+		if decoded[key] != expected {
+			t.Errorf("Expected %s '%s', got %v", key, expected, decoded[key])
+		}
+	}
+	if drivers, ok := decoded["drivers"].([]any); !ok || len(drivers) != 2 {
+		t.Errorf("Expected 2 drivers, got %v", decoded["drivers"])
+	}
+
+	var textOut bytes.Buffer
+	if err := writeVersion(&textOut, info, versionOutputText); err != nil {
+		t.Fatalf("Expected text output, got error: %v", err)
+	}
+	for _, expected := range []string{AppName + " 1.2.3", "abc123", "linux/arm64", "hid, tcp"} {
+		if !strings.Contains(textOut.String(), expected) {
+			t.Errorf("Expected text output to contain '%s', got:\n%s", expected, textOut.String())
+		}
+	}
+}
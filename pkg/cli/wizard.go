@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+const (
+	defaultWizardBrokerURL     = "mqtt://homeassistant.local:1883"
+	terminationDetectTimeout   = 30 * time.Second
+	defaultTerminationFallback = "enter"
+)
+
+// configWizard asks for the scanners and MQTT settings on the console and
+// renders them as a configuration file.
+type configWizard struct {
+	in      *bufio.Reader
+	out     io.Writer
+	devices []hid.DeviceInfo
+
+	// idTemplate is written as scanner_id_template and names the selected
+	// scanners, expanding {instance} to instance.
+	idTemplate string
+	instance   string
+
+	// detectTermination test-scans a device and returns its termination_char.
+	detectTermination func(device *hid.DeviceInfo) (string, error)
+}
+
+type wizardScanner struct {
+	id              string
+	name            string
+	device          hid.DeviceInfo
+	multiInterface  bool
+	terminationChar string
+}
+
+type wizardMQTT struct {
+	brokerURL string
+	username  string
+	password  string
+}
+
+// runGenerateConfig walks through the interactive configuration wizard and
+// writes the result to the output file.
+func (c *CLI) runGenerateConfig(_ context.Context, cmd *cli.Command) error {
+	c.logger = c.setupLogger(cmd)
+
+	output := cmd.String("output")
+	if _, err := os.Stat(output); err == nil && !cmd.Bool("force") {
+		return newExitError(ExitConfigError, fmt.Errorf("%s already exists - use --force to overwrite it", output))
+	}
+	idTemplate, err := idTemplateFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	wizard := &configWizard{
+		in:         bufio.NewReader(os.Stdin),
+		out:        os.Stdout,
+		devices:    scanner.ListAllDevices(),
+		idTemplate: idTemplate,
+		instance:   defaultInstanceID(),
+		detectTermination: func(device *hid.DeviceInfo) (string, error) {
+			return detectTerminationChar(device, c.logger)
+		},
+	}
+
+	data, err := wizard.run()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	if _, err := config.LoadConfig(output); err != nil {
+		return newExitError(ExitConfigError, fmt.Errorf("generated %s is invalid: %w", output, err))
+	}
+
+	_, _ = fmt.Fprintf(wizard.out, "Wrote %s. Start the bridge with: %s --config %s\n", output, AppName, output)
+	return nil
+}
+
+func (w *configWizard) run() ([]byte, error) {
+	if len(w.devices) == 0 {
+		return nil, newExitError(ExitNoDevices, fmt.Errorf("no HID devices found - check permissions or udev rules"))
+	}
+
+	scanners, err := w.selectScanners()
+	if err != nil {
+		return nil, err
+	}
+
+	mqtt := wizardMQTT{}
+	if mqtt.brokerURL, err = w.ask("MQTT broker URL", defaultWizardBrokerURL); err != nil {
+		return nil, err
+	}
+	if mqtt.username, err = w.ask("MQTT username (empty for none)", ""); err != nil {
+		return nil, err
+	}
+	if mqtt.username != "" {
+		if mqtt.password, err = w.ask("MQTT password", ""); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range scanners {
+		scanners[i].terminationChar = w.testScan(&scanners[i])
+	}
+
+	return renderWizardConfig(&mqtt, scanners, w.idTemplate), nil
+}
+
+// selectScanners lists the HID devices and asks which ones to configure.
+// Devices that look like barcode scanners are preselected.
+func (w *configWizard) selectScanners() ([]wizardScanner, error) {
+	interfaceCounts := make(map[string]int)
+	for i := range w.devices {
+		interfaceCounts[deviceKey(&w.devices[i])]++
+	}
+
+	var suggested []string
+	_, _ = fmt.Fprintln(w.out, "HID devices (* likely barcode scanners):")
+	for i := range w.devices {
+		device := &w.devices[i]
+		marker := " "
+		if scanner.IsLikelyBarcodeScanner(device) {
+			marker = "*"
+			suggested = append(suggested, strconv.Itoa(i+1))
+		}
+		_, _ = fmt.Fprintf(w.out, "  %s %2d) %s (%04x:%04x interface %d)\n",
+			marker, i+1, scanner.DeviceDisplayName(device), device.VendorID, device.ProductID, device.Interface)
+	}
+
+	answer, err := w.ask("Scanners to configure (comma-separated numbers)", strings.Join(suggested, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []wizardScanner
+	usedIDs := make(map[string]bool)
+	generateID := scanner.NewIDGenerator(w.idTemplate, w.instance)
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		index, err := strconv.Atoi(field)
+		if err != nil || index < 1 || index > len(w.devices) {
+			return nil, fmt.Errorf("invalid device number '%s'", field)
+		}
+
+		device := w.devices[index-1]
+		name := scanner.DeviceDisplayName(&device)
+		baseID := generateID(name, &device)
+		id := baseID
+		for suffix := 2; usedIDs[id]; suffix++ {
+			id = fmt.Sprintf("%s_%d", baseID, suffix)
+		}
+		usedIDs[id] = true
+
+		selected = append(selected, wizardScanner{
+			id:             id,
+			name:           name,
+			device:         device,
+			multiInterface: interfaceCounts[deviceKey(&device)] > 1,
+		})
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no scanners selected")
+	}
+	return selected, nil
+}
+
+// testScan asks for a scan with the scanner to detect its termination
+// character, falling back to "enter" when detection fails.
+func (w *configWizard) testScan(ws *wizardScanner) string {
+	_, _ = fmt.Fprintf(w.out, "Scan any barcode with %s to detect its termination character (waiting %s)...\n",
+		ws.name, terminationDetectTimeout)
+
+	terminationChar, err := w.detectTermination(&ws.device)
+	if err != nil {
+		_, _ = fmt.Fprintf(w.out, "  %v, using termination_char %q\n", err, defaultTerminationFallback)
+		return defaultTerminationFallback
+	}
+
+	_, _ = fmt.Fprintf(w.out, "  detected termination_char %q\n", terminationChar)
+	return terminationChar
+}
+
+func (w *configWizard) ask(question, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		_, _ = fmt.Fprintf(w.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		_, _ = fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	line, err := w.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return defaultValue, nil
+}
+
+// detectTerminationChar waits for a scan on the device and returns the
+// termination character detected from its keyboard reports.
+func detectTerminationChar(device *hid.DeviceInfo, logger *logrus.Logger) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), terminationDetectTimeout)
+	defer cancel()
+
+	scan, err := scanner.ListenForScan(ctx, []hid.DeviceInfo{*device}, logger)
+	if err != nil {
+		return "", err
+	}
+	return scan.TerminationChar, nil
+}
+
+func renderWizardConfig(mqtt *wizardMQTT, scanners []wizardScanner, idTemplate string) []byte {
+	var buf bytes.Buffer
+
+	_, _ = fmt.Fprintf(&buf, "# Generated by %s generate-config\n\n", AppName)
+	if idTemplate != "" {
+		_, _ = fmt.Fprintf(&buf, "scanner_id_template: %q\n\n", idTemplate)
+	}
+	buf.WriteString("mqtt:\n")
+	_, _ = fmt.Fprintf(&buf, "  broker_url: %q\n", mqtt.brokerURL)
+	if mqtt.username != "" {
+		_, _ = fmt.Fprintf(&buf, "  username: %q\n", mqtt.username)
+		_, _ = fmt.Fprintf(&buf, "  password: %q\n", mqtt.password)
+	}
+
+	buf.WriteString("\nscanners:\n")
+	for i := range scanners {
+		ws := &scanners[i]
+		_, _ = fmt.Fprintf(&buf, "  %s:\n", ws.id)
+		_, _ = fmt.Fprintf(&buf, "    name: %q\n", ws.name)
+		buf.WriteString("    identification:\n")
+		_, _ = fmt.Fprintf(&buf, "      vendor_id: 0x%04x\n", ws.device.VendorID)
+		_, _ = fmt.Fprintf(&buf, "      product_id: 0x%04x\n", ws.device.ProductID)
+		if ws.device.Serial != "" {
+			_, _ = fmt.Fprintf(&buf, "      serial: %q\n", ws.device.Serial)
+		}
+		if ws.multiInterface {
+			_, _ = fmt.Fprintf(&buf, "      interface: %d\n", ws.device.Interface)
+		}
+		_, _ = fmt.Fprintf(&buf, "    termination_char: %q\n", ws.terminationChar)
+	}
+
+	buf.WriteString("\nhomeassistant:\n")
+	buf.WriteString("  discovery_prefix: \"homeassistant\"\n")
+
+	return buf.Bytes()
+}
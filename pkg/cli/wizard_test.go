@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestConfigWizard(t *testing.T) {
+	devices := []hid.DeviceInfo{
+		{VendorID: 0x046d, ProductID: 0xc52b, Product: "USB Receiver", Interface: 0},
+		{VendorID: 0x0c2e, ProductID: 0x0b61, Product: "Voyager", Serial: "ABC123", Interface: 0},
+		{VendorID: 0x0c2e, ProductID: 0x0b61, Product: "Voyager", Serial: "ABC123", Interface: 2},
+	}
+
+	detected := 0
+	wizard := &configWizard{
+		in:      bufio.NewReader(strings.NewReader("2\nmqtt://broker:1883\nbridge\nsecret\n")),
+		out:     io.Discard,
+		devices: devices,
+		detectTermination: func(device *hid.DeviceInfo) (string, error) {
+			detected++
+			return "tab", nil
+		},
+	}
+
+	data, err := wizard.run()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if detected != 1 {
+		t.Errorf("Expected one test scan, got %d", detected)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected generated config to load, got: %v\n%s", err, data)
+	}
+
+	if cfg.MQTT.BrokerURL != "mqtt://broker:1883" || cfg.MQTT.Username != "bridge" || cfg.MQTT.Password != "secret" {
+		t.Errorf("Expected MQTT settings from the answers, got %+v", cfg.MQTT)
+	}
+	if len(cfg.Scanners) != 1 {
+		t.Fatalf("Expected 1 scanner, got %d", len(cfg.Scanners))
+	}
+	for _, scannerConfig := range cfg.Scanners {
+		if scannerConfig.TerminationChar != "tab" {
+			t.Errorf("Expected detected termination char tab, got %q", scannerConfig.TerminationChar)
+		}
+		if scannerConfig.Identification.Serial != "ABC123" {
+			t.Errorf("Expected serial ABC123, got %q", scannerConfig.Identification.Serial)
+		}
+		if scannerConfig.Identification.Interface == nil || *scannerConfig.Identification.Interface != 0 {
+			t.Error("Expected interface 0 for a multi-interface device")
+		}
+	}
+}
+
+func TestConfigWizard_IDTemplate(t *testing.T) {
+	wizard := &configWizard{
+		in:  bufio.NewReader(strings.NewReader("1,2\n\n\n")),
+		out: io.Discard,
+		devices: []hid.DeviceInfo{
+			{VendorID: 0x0c2e, ProductID: 0x0b61, Product: "Voyager", Serial: "ABC123"},
+			{VendorID: 0x0c2e, ProductID: 0x0b61, Product: "Voyager", Serial: "ABC123", Interface: 1},
+		},
+		idTemplate: "{instance}-lane-{serial}",
+		instance:   "shop1",
+		detectTermination: func(device *hid.DeviceInfo) (string, error) {
+			return "enter", nil
+		},
+	}
+
+	data, err := wizard.run()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected generated config to load, got: %v\n%s", err, data)
+	}
+
+	if cfg.ScannerIDTemplate != "{instance}-lane-{serial}" {
+		t.Errorf("Expected the template to be written to the config, got %q", cfg.ScannerIDTemplate)
+	}
+	for _, id := range []string{"shop1-lane-abc123", "shop1-lane-abc123_2"} {
+		if _, exists := cfg.Scanners[id]; !exists {
+			t.Errorf("Expected scanner %s, got %v", id, cfg.Scanners)
+		}
+	}
+}
+
+func TestConfigWizard_InvalidSelection(t *testing.T) {
+	wizard := &configWizard{
+		in:      bufio.NewReader(strings.NewReader("5\n")),
+		out:     io.Discard,
+		devices: []hid.DeviceInfo{{VendorID: 0x0c2e, ProductID: 0x0b61}},
+	}
+
+	if _, err := wizard.run(); err == nil {
+		t.Error("Expected error for an out of range device number")
+	}
+}
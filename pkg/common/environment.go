@@ -0,0 +1,88 @@
+package common
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Environment describes the platform the bridge runs on. It is logged at
+// startup and published in the bridge diagnostics to speed up support.
+type Environment struct {
+	OS               string
+	Arch             string
+	Kernel           string
+	GoVersion        string
+	ContainerRuntime string
+	UdevAvailable    bool
+}
+
+func CollectEnvironment() *Environment {
+	return &Environment{
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		Kernel:           readKernelRelease(),
+		GoVersion:        runtime.Version(),
+		ContainerRuntime: detectContainerRuntime(),
+		UdevAvailable:    fileExists("/run/udev/control"),
+	}
+}
+
+func (e *Environment) Fields() map[string]any {
+	return map[string]any{
+		"version":           GetVersion(),
+		"commit":            COMMIT,
+		"os":                e.OS,
+		"arch":              e.Arch,
+		"kernel":            e.Kernel,
+		"go_version":        e.GoVersion,
+		"container_runtime": e.ContainerRuntime,
+		"udev_available":    e.UdevAvailable,
+	}
+}
+
+func readKernelRelease() string {
+	data, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func detectContainerRuntime() string {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	if fileExists("/.dockerenv") {
+		return "docker"
+	}
+	if fileExists("/run/.containerenv") {
+		return "podman"
+	}
+
+	cgroup, err := os.ReadFile("/proc/1/cgroup")
+	if err == nil {
+		return containerRuntimeFromCgroup(string(cgroup))
+	}
+	return "none"
+}
+
+func containerRuntimeFromCgroup(cgroup string) string {
+	for _, runtimeName := range []string{"kubepods", "docker", "containerd", "libpod", "lxc"} {
+		if strings.Contains(cgroup, runtimeName) {
+			if runtimeName == "kubepods" {
+				return "kubernetes"
+			}
+			if runtimeName == "libpod" {
+				return "podman"
+			}
+			return runtimeName
+		}
+	}
+	return "none"
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestContainerRuntimeFromCgroup(t *testing.T) {
+	tests := []struct {
+		cgroup   string
+		expected string
+	}{
+		{"0::/init.scope", "none"},
+		{"12:pids:/docker/3f1c2a", "docker"},
+		{"0::/kubepods/besteffort/pod1234", "kubernetes"},
+		{"0::/machine.slice/libpod-3f1c2a.scope", "podman"},
+		{"", "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := containerRuntimeFromCgroup(tt.cgroup); got != tt.expected {
+				t.Errorf("containerRuntimeFromCgroup(%q) = %s, expected %s", tt.cgroup, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCollectEnvironment(t *testing.T) {
+	env := CollectEnvironment()
+
+	if env.OS == "" || env.Arch == "" || env.GoVersion == "" {
+		t.Error("Expected OS, architecture and Go version to be set")
+	}
+
+	fields := env.Fields()
+	if fields["version"] != GetVersion() {
+		t.Errorf("Expected version field '%s', got %v", GetVersion(), fields["version"])
+	}
+}
@@ -0,0 +1,35 @@
+package common
+
+// LooksLikeGTIN reports whether barcode has the digits and length of a GTIN
+// (EAN-8, UPC-A, EAN-13 or ITF-14), regardless of its check digit.
+func LooksLikeGTIN(barcode string) bool {
+	switch len(barcode) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+	for i := range len(barcode) {
+		if barcode[i] < '0' || barcode[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsValidGTIN reports whether barcode is an 8, 12, 13 or 14 digit GTIN with
+// a correct check digit.
+func IsValidGTIN(barcode string) bool {
+	if !LooksLikeGTIN(barcode) {
+		return false
+	}
+
+	sum := 0
+	for i := len(barcode) - 1; i >= 0; i-- {
+		weight := 1
+		if (len(barcode)-1-i)%2 == 1 {
+			weight = 3
+		}
+		sum += int(barcode[i]-'0') * weight
+	}
+	return sum%10 == 0
+}
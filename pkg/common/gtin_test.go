@@ -0,0 +1,32 @@
+package common
+
+import "testing"
+
+func TestIsValidGTIN(t *testing.T) {
+	tests := []struct {
+		barcode   string
+		looksLike bool
+		valid     bool
+	}{
+		{"96385074", true, true},
+		{"036000291452", true, true},
+		{"4006381333931", true, true},
+		{"4006381333932", true, false},
+		{"10012345678902", true, true},
+		{"400638133393", true, false},
+		{"400638133393A", false, false},
+		{"12345", false, false},
+		{"", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			if got := LooksLikeGTIN(tt.barcode); got != tt.looksLike {
+				t.Errorf("Expected LooksLikeGTIN %v, got %v", tt.looksLike, got)
+			}
+			if got := IsValidGTIN(tt.barcode); got != tt.valid {
+				t.Errorf("Expected IsValidGTIN %v, got %v", tt.valid, got)
+			}
+		})
+	}
+}
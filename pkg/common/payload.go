@@ -0,0 +1,245 @@
+package common
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Structured payload formats recognized by ParsePayload, mostly found in QR
+// codes.
+const (
+	PayloadWiFi    = "wifi"
+	PayloadContact = "contact"
+	PayloadURL     = "url"
+	PayloadJSON    = "json"
+)
+
+// PayloadFormatAttribute is the scan attribute set to the format of a parsed
+// payload, next to the attributes holding its fields.
+const PayloadFormatAttribute = "payload_format"
+
+// contactFields maps MECARD and vCard properties to contact attributes.
+var contactFields = map[string]string{
+	"FN":    "contact_name",
+	"N":     "contact_name",
+	"TEL":   "contact_phone",
+	"EMAIL": "contact_email",
+	"ADR":   "contact_address",
+	"ORG":   "contact_organization",
+	"URL":   "contact_url",
+	"NOTE":  "contact_note",
+}
+
+// ParsePayload detects a Wi-Fi network (WIFI:), contact (MECARD: or vCard),
+// http(s) URL or JSON object payload and returns its format and fields as
+// scan attributes, prefixed by the format, e.g. wifi_ssid or json_id.
+func ParsePayload(data string) (format string, fields map[string]string, ok bool) {
+	trimmed := strings.TrimSpace(data)
+	upper := strings.ToUpper(trimmed)
+
+	switch {
+	case strings.HasPrefix(upper, "WIFI:"):
+		fields = parseWiFi(trimmed[len("WIFI:"):])
+		format = PayloadWiFi
+	case strings.HasPrefix(upper, "MECARD:"):
+		fields = parseMECARD(trimmed[len("MECARD:"):])
+		format = PayloadContact
+	case strings.HasPrefix(upper, "BEGIN:VCARD"):
+		fields = parseVCard(trimmed)
+		format = PayloadContact
+	case strings.HasPrefix(upper, "HTTP://"), strings.HasPrefix(upper, "HTTPS://"):
+		fields = parseURL(trimmed)
+		format = PayloadURL
+	case strings.HasPrefix(trimmed, "{"):
+		fields = parseJSONObject(trimmed)
+		format = PayloadJSON
+	}
+
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return format, fields, true
+}
+
+// PayloadFields returns the format and fields of a parsed payload from the
+// scan attributes, leaving out attributes added by other stages.
+func PayloadFields(attributes map[string]string) map[string]string {
+	format := attributes[PayloadFormatAttribute]
+	if format == "" {
+		return nil
+	}
+
+	fields := map[string]string{PayloadFormatAttribute: format}
+	for key, value := range attributes {
+		if strings.HasPrefix(key, format+"_") {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// splitEscaped splits s at unescaped separators, removing the backslash
+// escapes used by WIFI: and MECARD: payloads.
+func splitEscaped(s string, separator byte) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			current.WriteByte(s[i])
+		case s[i] == separator:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(s[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+// escapedProperties splits "K:value;K:value;;" into its properties, keeping
+// the escapes in values so they can be split further.
+func escapedProperties(s string) [][2]string {
+	var properties [][2]string
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] == '\\' {
+			i++
+			continue
+		}
+		if i < len(s) && s[i] != ';' {
+			continue
+		}
+
+		if key, value, found := strings.Cut(s[start:i], ":"); found {
+			properties = append(properties, [2]string{strings.ToUpper(key), value})
+		}
+		start = i + 1
+	}
+	return properties
+}
+
+func unescape(s string) string {
+	var unescaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		unescaped.WriteByte(s[i])
+	}
+	return unescaped.String()
+}
+
+func parseWiFi(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, property := range escapedProperties(s) {
+		value := unescape(property[1])
+		switch property[0] {
+		case "S":
+			fields["wifi_ssid"] = value
+		case "P":
+			fields["wifi_password"] = value
+		case "T":
+			fields["wifi_security"] = value
+		case "H":
+			fields["wifi_hidden"] = strings.ToLower(value)
+		}
+	}
+	if fields["wifi_ssid"] == "" {
+		return nil
+	}
+	return fields
+}
+
+func parseMECARD(s string) map[string]string {
+	fields := make(map[string]string)
+	for _, property := range escapedProperties(s) {
+		value := unescape(property[1])
+		if property[0] == "N" {
+			// "Last,First" reads better as "First Last"
+			names := splitEscaped(property[1], ',')
+			for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+				names[i], names[j] = names[j], names[i]
+			}
+			value = strings.TrimSpace(strings.Join(names, " "))
+		}
+		setContactField(fields, property[0], value)
+	}
+	return fields
+}
+
+func parseVCard(s string) map[string]string {
+	// Continuation lines start with whitespace
+	s = strings.NewReplacer("\r\n ", "", "\r\n\t", "", "\n ", "", "\n\t", "").Replace(s)
+
+	fields := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		name, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if !found {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		// Structured values (N, ADR) separate their components with ';'
+		components := strings.FieldsFunc(value, func(r rune) bool { return r == ';' })
+		if name == "N" && len(components) > 1 {
+			components[0], components[1] = components[1], components[0]
+		}
+		setContactField(fields, name, strings.Join(components, " "))
+	}
+	return fields
+}
+
+// setContactField keeps the first value of repeated properties, and FN over
+// N for the name.
+func setContactField(fields map[string]string, property, value string) {
+	key, exists := contactFields[property]
+	if !exists || value == "" {
+		return
+	}
+	if _, set := fields[key]; set && property != "FN" {
+		return
+	}
+	fields[key] = value
+}
+
+func parseURL(s string) map[string]string {
+	parsed, err := url.Parse(s)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+
+	fields := map[string]string{
+		"url_scheme": strings.ToLower(parsed.Scheme),
+		"url_host":   parsed.Hostname(),
+		"url_path":   parsed.Path,
+	}
+	for key, values := range parsed.Query() {
+		if len(values) > 0 {
+			fields["url_param_"+key] = values[0]
+		}
+	}
+	return fields
+}
+
+func parseJSONObject(s string) map[string]string {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &object); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]string, len(object))
+	for key, raw := range object {
+		var value string
+		switch {
+		case string(raw) == "null":
+		case json.Unmarshal(raw, &value) == nil:
+		default:
+			// Numbers, booleans and nested values keep their JSON text
+			value = string(raw)
+		}
+		fields["json_"+key] = value
+	}
+	return fields
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParsePayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		format   string
+		expected map[string]string
+	}{
+		{
+			"WiFi", `WIFI:T:WPA;S:Home\;Net;P:pa\:ss\\word;H:true;;`, PayloadWiFi,
+			map[string]string{"wifi_ssid": "Home;Net", "wifi_password": `pa:ss\word`, "wifi_security": "WPA", "wifi_hidden": "true"},
+		},
+		{
+			"Open WiFi", "wifi:S:Guest;;", PayloadWiFi,
+			map[string]string{"wifi_ssid": "Guest"},
+		},
+		{
+			"MECARD", "MECARD:N:Doe,John;TEL:+34600000000;TEL:+34911111111;EMAIL:john@example.com;;", PayloadContact,
+			map[string]string{"contact_name": "John Doe", "contact_phone": "+34600000000", "contact_email": "john@example.com"},
+		},
+		{
+			"vCard", "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Doe;Jane;;;\r\nFN:Dr. Jane Doe\r\nTEL;TYPE=work:+1 555 0100\r\nADR:;;1 Main St;Springfield;;12345;USA\r\nORG:Acme\r\nEND:VCARD", PayloadContact,
+			map[string]string{"contact_name": "Dr. Jane Doe", "contact_phone": "+1 555 0100", "contact_address": "1 Main St Springfield 12345 USA", "contact_organization": "Acme"},
+		},
+		{
+			"URL", "https://example.com/item/42?sku=ABC&lot=7", PayloadURL,
+			map[string]string{"url_scheme": "https", "url_host": "example.com", "url_path": "/item/42", "url_param_sku": "ABC", "url_param_lot": "7"},
+		},
+		{
+			"JSON", `{"id": "A1", "qty": 3, "fragile": true, "tags": ["x"], "note": null}`, PayloadJSON,
+			map[string]string{"json_id": "A1", "json_qty": "3", "json_fragile": "true", "json_tags": `["x"]`, "json_note": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, fields, ok := ParsePayload(tt.data)
+			if !ok {
+				t.Fatalf("Expected %s payload to be parsed", tt.format)
+			}
+			if format != tt.format {
+				t.Errorf("Expected format %s, got %s", tt.format, format)
+			}
+			if !maps.Equal(fields, tt.expected) {
+				t.Errorf("Expected fields %v, got %v", tt.expected, fields)
+			}
+		})
+	}
+
+	for _, data := range []string{"4006381333931", "WIFI:T:WPA;;", "http://", "{not json", "[1, 2]", "ftp://example.com"} {
+		if format, _, ok := ParsePayload(data); ok {
+			t.Errorf("Expected %q not to be parsed, got format %s", data, format)
+		}
+	}
+}
+
+func TestPayloadFields(t *testing.T) {
+	attributes := map[string]string{
+		PayloadFormatAttribute: PayloadJSON,
+		"json_id":              "A1",
+		"location":             "warehouse",
+		"url_host":             "example.com",
+	}
+	expected := map[string]string{PayloadFormatAttribute: PayloadJSON, "json_id": "A1"}
+	if fields := PayloadFields(attributes); !maps.Equal(fields, expected) {
+		t.Errorf("Expected fields %v, got %v", expected, fields)
+	}
+
+	if fields := PayloadFields(map[string]string{"location": "warehouse"}); fields != nil {
+		t.Errorf("Expected no fields without a parsed payload, got %v", fields)
+	}
+}
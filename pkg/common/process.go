@@ -0,0 +1,67 @@
+package common
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var processStart = time.Now()
+
+// ProcessStats is a snapshot of the bridge process resource usage, published
+// by the process stats sensors to diagnose long-running deployments.
+type ProcessStats struct {
+	Uptime       time.Duration
+	RSS          uint64 // Resident set size in bytes, the memory obtained from the OS where unavailable
+	Goroutines   int
+	HeapAlloc    uint64
+	HeapSys      uint64
+	NumGC        uint32
+	GCPauseTotal time.Duration
+	LastGC       time.Time // Zero before the first collection
+}
+
+func CollectProcessStats() *ProcessStats {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	stats := &ProcessStats{
+		Uptime:       time.Since(processStart),
+		RSS:          memStats.Sys,
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    memStats.HeapAlloc,
+		HeapSys:      memStats.HeapSys,
+		NumGC:        memStats.NumGC,
+		GCPauseTotal: time.Duration(memStats.PauseTotalNs), // #nosec G115 - total pause time fits in an int64
+	}
+	if memStats.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(memStats.LastGC)) // #nosec G115 - nanoseconds since 1970 fit in an int64
+	}
+	if rss, ok := readRSS(); ok {
+		stats.RSS = rss
+	}
+	return stats
+}
+
+// readRSS returns the resident set size from /proc, which only Linux has.
+func readRSS() (uint64, bool) {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, false
+	}
+	return rssFromStatm(string(data), os.Getpagesize())
+}
+
+func rssFromStatm(statm string, pageSize int) (uint64, bool) {
+	fields := strings.Fields(statm)
+	if len(fields) < 2 || pageSize <= 0 {
+		return 0, false
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return pages * uint64(pageSize), true
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestRSSFromStatm(t *testing.T) {
+	tests := []struct {
+		statm    string
+		expected uint64
+		ok       bool
+	}{
+		{"5340 1210 830 1 0 2104 0\n", 1210 * 4096, true},
+		{"5340", 0, false},
+		{"5340 abc", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		rss, ok := rssFromStatm(tt.statm, 4096)
+		if rss != tt.expected || ok != tt.ok {
+			t.Errorf("rssFromStatm(%q) = %d, %v, expected %d, %v", tt.statm, rss, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestCollectProcessStats(t *testing.T) {
+	stats := CollectProcessStats()
+	if stats.Uptime <= 0 {
+		t.Errorf("Expected positive uptime, got %s", stats.Uptime)
+	}
+	if stats.RSS == 0 {
+		t.Error("Expected RSS to be set")
+	}
+	if stats.Goroutines < 1 {
+		t.Errorf("Expected at least one goroutine, got %d", stats.Goroutines)
+	}
+	if stats.HeapAlloc == 0 || stats.HeapSys < stats.HeapAlloc {
+		t.Errorf("Unexpected heap stats: alloc %d, sys %d", stats.HeapAlloc, stats.HeapSys)
+	}
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OpenFoodFactsURL is the default Open Food Facts server products are
+// looked up on.
+const OpenFoodFactsURL = "https://world.openfoodfacts.org"
+
+// Product is the product information Open Food Facts has for a barcode.
+type Product struct {
+	Name     string `json:"product_name"`
+	Brand    string `json:"brands"`
+	ImageURL string `json:"image_url"`
+}
+
+// FetchProduct looks up barcode on the Open Food Facts server at baseURL.
+// It returns nil without an error for products the database doesn't know.
+func FetchProduct(ctx context.Context, client *http.Client, baseURL, barcode string) (*Product, error) {
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/api/v2/product/" + url.PathEscape(barcode) + "?fields=product_name,brands,image_url"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("User-Agent", "ha-barcode-bridge/"+GetVersion())
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	var result struct {
+		Status  int      `json:"status"`
+		Product *Product `json:"product"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid product: %w", err)
+	}
+	if result.Status != 1 || result.Product == nil {
+		return nil, nil
+	}
+	return result.Product, nil
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchProduct(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/product/3017620422003":
+			_, _ = w.Write([]byte(`{"code":"3017620422003","status":1,"product":{"product_name":"Nutella","brands":"Ferrero","image_url":"https://images.example/nutella.jpg"}}`))
+		case "/api/v2/product/4006381333931":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":0,"status_verbose":"product not found"}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	product, err := FetchProduct(context.Background(), server.Client(), server.URL+"/", "3017620422003")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if product == nil || product.Name != "Nutella" || product.Brand != "Ferrero" || product.ImageURL != "https://images.example/nutella.jpg" {
+		t.Errorf("Expected the Nutella product, got %+v", product)
+	}
+
+	product, err = FetchProduct(context.Background(), server.Client(), server.URL, "4006381333931")
+	if err != nil || product != nil {
+		t.Errorf("Expected no product and no error for an unknown barcode, got %+v (%v)", product, err)
+	}
+
+	if _, err := FetchProduct(context.Background(), server.Client(), server.URL, "0000000000000"); err == nil {
+		t.Error("Expected error for a failing server")
+	}
+}
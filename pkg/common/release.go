@@ -0,0 +1,100 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LatestReleaseURL is the GitHub API endpoint of the latest published release.
+const LatestReleaseURL = "https://api.github.com/repos/miguelangel-nubla/homeassistant-barcode-scanner/releases/latest"
+
+// Release is a published release of the bridge.
+type Release struct {
+	Version     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Notes       string    `json:"body"`
+	URL         string    `json:"html_url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// FetchLatestRelease asks the GitHub releases API at url for the latest
+// release, which excludes drafts and pre-releases.
+func FetchLatestRelease(ctx context.Context, client *http.Client, url string) (*Release, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("User-Agent", "ha-barcode-bridge/"+GetVersion())
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release: %w", err)
+	}
+	if release.Version == "" {
+		return nil, fmt.Errorf("release without a tag")
+	}
+	release.Version = strings.TrimPrefix(release.Version, "v")
+	return &release, nil
+}
+
+// IsNewerVersion reports whether latest is a later semantic version than
+// current. A release is newer than a pre-release or development build of the
+// same version; unparsable versions are never newer.
+func IsNewerVersion(current, latest string) bool {
+	currentParts, currentPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	latestParts, latestPre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+
+	for i := range currentParts {
+		if latestParts[i] != currentParts[i] {
+			return latestParts[i] > currentParts[i]
+		}
+	}
+	return currentPre && !latestPre
+}
+
+func parseVersion(version string) (parts [3]int, prerelease bool, ok bool) {
+	version = strings.TrimPrefix(version, "v")
+	if core, _, found := strings.Cut(version, "+"); found {
+		version = core
+	}
+	if core, _, found := strings.Cut(version, "-"); found {
+		version = core
+		prerelease = true
+	}
+
+	fields := strings.Split(version, ".")
+	if len(fields) != len(parts) {
+		return parts, false, false
+	}
+	for i, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 {
+			return parts, false, false
+		}
+		parts[i] = number
+	}
+	return parts, prerelease, true
+}
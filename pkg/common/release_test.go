@@ -0,0 +1,57 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		current  string
+		latest   string
+		expected bool
+	}{
+		{"1.2.0", "1.3.0", true},
+		{"1.2.0", "v1.2.1", true},
+		{"1.9.0", "1.10.0", true},
+		{"1.2.0", "2.0.0", true},
+		{"1.2.0", "1.2.0", false},
+		{"1.3.0", "1.2.9", false},
+		{"1.0.0-dev", "1.0.0", true},
+		{"1.0.0", "1.0.0-rc1", false},
+		{"1.2.0+build5", "1.2.0", false},
+		{"dev", "1.2.0", false},
+		{"1.2.0", "latest", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewerVersion(tt.current, tt.latest); got != tt.expected {
+			t.Errorf("IsNewerVersion(%q, %q) = %v, expected %v", tt.current, tt.latest, got, tt.expected)
+		}
+	}
+}
+
+func TestFetchLatestRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"tag_name":"v1.4.0","name":"Bridge 1.4.0","body":"- New sensors","html_url":"https://example.com/1.4.0","published_at":"2026-01-02T03:04:05Z"}`))
+	}))
+	defer server.Close()
+
+	release, err := FetchLatestRelease(context.Background(), server.Client(), server.URL+"/latest")
+	if err != nil {
+		t.Fatalf("Expected release, got error: %v", err)
+	}
+	if release.Version != "1.4.0" || release.Notes != "- New sensors" || release.URL != "https://example.com/1.4.0" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+
+	if _, err := FetchLatestRelease(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("Expected error for a missing release")
+	}
+}
@@ -0,0 +1,102 @@
+package common
+
+// Symbologies of scanned barcodes, as detected by DetectSymbology.
+const (
+	SymbologyUnknown    = "unknown"
+	SymbologyEAN8       = "ean_8"
+	SymbologyEAN13      = "ean_13"
+	SymbologyUPCA       = "upc_a"
+	SymbologyITF14      = "itf_14"
+	SymbologyITF        = "itf"
+	SymbologyCode39     = "code_39"
+	SymbologyCode93     = "code_93"
+	SymbologyCode128    = "code_128"
+	SymbologyCodabar    = "codabar"
+	SymbologyGS1DataBar = "gs1_databar"
+	SymbologyPDF417     = "pdf417"
+	SymbologyQRCode     = "qr_code"
+	SymbologyDataMatrix = "data_matrix"
+	SymbologyAztec      = "aztec"
+)
+
+// Scan attributes set by aim pipeline stages from the AIM identifier they
+// strip.
+const (
+	SymbologyAttribute     = "symbology"
+	AIMIdentifierAttribute = "aim_id"
+)
+
+// aimSymbologies maps the code character of AIM symbology identifiers
+// ("]Cm" prefixes sent by scanners configured to transmit them).
+var aimSymbologies = map[byte]string{
+	'A': SymbologyCode39,
+	'C': SymbologyCode128,
+	'F': SymbologyCodabar,
+	'G': SymbologyCode93,
+	'I': SymbologyITF,
+	'L': SymbologyPDF417,
+	'Q': SymbologyQRCode,
+	'd': SymbologyDataMatrix,
+	'e': SymbologyGS1DataBar,
+	'z': SymbologyAztec,
+}
+
+// ParseAIMIdentifier splits a barcode starting with an AIM symbology
+// identifier into the three character identifier, the symbology it denotes
+// and the data after it. Symbologies missing from the table are unknown.
+func ParseAIMIdentifier(barcode string) (identifier, symbology, data string, ok bool) {
+	if len(barcode) < 3 || barcode[0] != ']' || !isAIMCharacter(barcode[1]) || !isAIMCharacter(barcode[2]) {
+		return "", "", barcode, false
+	}
+
+	identifier = barcode[:3]
+	switch {
+	case barcode[1] == 'E' && barcode[2] == '4':
+		symbology = SymbologyEAN8
+	case barcode[1] == 'E':
+		symbology = SymbologyEAN13
+	default:
+		var exists bool
+		if symbology, exists = aimSymbologies[barcode[1]]; !exists {
+			symbology = SymbologyUnknown
+		}
+	}
+	return identifier, symbology, barcode[3:], true
+}
+
+func isAIMCharacter(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// DetectSymbology returns the symbology of a scanned barcode. An AIM
+// symbology identifier is authoritative; without one, only numeric GTINs
+// with a valid check digit can be told apart.
+func DetectSymbology(barcode string) string {
+	if _, symbology, _, ok := ParseAIMIdentifier(barcode); ok && symbology != SymbologyUnknown {
+		return symbology
+	}
+
+	if !IsValidGTIN(barcode) {
+		return SymbologyUnknown
+	}
+	switch len(barcode) {
+	case 8:
+		return SymbologyEAN8
+	case 12:
+		return SymbologyUPCA
+	case 13:
+		return SymbologyEAN13
+	default:
+		return SymbologyITF14
+	}
+}
+
+// IsMatrixSymbology reports whether the symbology is two-dimensional.
+func IsMatrixSymbology(symbology string) bool {
+	switch symbology {
+	case SymbologyQRCode, SymbologyDataMatrix, SymbologyAztec, SymbologyPDF417:
+		return true
+	default:
+		return false
+	}
+}
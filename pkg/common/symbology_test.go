@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDetectSymbology(t *testing.T) {
+	tests := []struct {
+		barcode  string
+		expected string
+	}{
+		{"4006381333931", SymbologyEAN13},
+		{"4006381333932", SymbologyUnknown},
+		{"036000291452", SymbologyUPCA},
+		{"96385074", SymbologyEAN8},
+		{"10012345678902", SymbologyITF14},
+		{"]Q1https://example.com", SymbologyQRCode},
+		{"]E04006381333931", SymbologyEAN13},
+		{"]E496385074", SymbologyEAN8},
+		{"]C1ABC-123", SymbologyCode128},
+		{"]d2010123", SymbologyDataMatrix},
+		{"ABC-123", SymbologyUnknown},
+		{"", SymbologyUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			if got := DetectSymbology(tt.barcode); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	if !IsMatrixSymbology(SymbologyQRCode) || IsMatrixSymbology(SymbologyEAN13) {
+		t.Error("Expected only 2D symbologies to be reported as matrix codes")
+	}
+}
+
+func TestParseAIMIdentifier(t *testing.T) {
+	tests := []struct {
+		barcode    string
+		identifier string
+		symbology  string
+		data       string
+		ok         bool
+	}{
+		{"]E04006381333931", "]E0", SymbologyEAN13, "4006381333931", true},
+		{"]C1ABC-123", "]C1", SymbologyCode128, "ABC-123", true},
+		{"]Q1", "]Q1", SymbologyQRCode, "", true},
+		{"]X0ABC", "]X0", SymbologyUnknown, "ABC", true},
+		{"]E", "", "", "]E", false},
+		{"]-1ABC", "", "", "]-1ABC", false},
+		{"4006381333931", "", "", "4006381333931", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			identifier, symbology, data, ok := ParseAIMIdentifier(tt.barcode)
+			if identifier != tt.identifier || symbology != tt.symbology || data != tt.data || ok != tt.ok {
+				t.Errorf("ParseAIMIdentifier(%q) = %q, %q, %q, %v, expected %q, %q, %q, %v",
+					tt.barcode, identifier, symbology, data, ok, tt.identifier, tt.symbology, tt.data, tt.ok)
+			}
+		})
+	}
+}
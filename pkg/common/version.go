@@ -1,11 +1,14 @@
 package common
 
+import "runtime"
+
 // These variables are injected at build time using -ldflags
 var (
 	SUMMARY = "development"
 	BRANCH  = "unknown"
 	VERSION = "dev"
 	COMMIT  = "unknown"
+	DATE    = "unknown" // RFC 3339 build time
 )
 
 func GetVersion() string {
@@ -14,3 +17,32 @@ func GetVersion() string {
 	}
 	return VERSION
 }
+
+// BuildInfo describes the running binary for tooling that verifies which
+// build is rolled out where. The input drivers and HID backends depend on
+// the platform and cgo, the CLI fills them in from the scanner package.
+type BuildInfo struct {
+	Version     string   `json:"version"`
+	Summary     string   `json:"summary"`
+	Commit      string   `json:"commit"`
+	Branch      string   `json:"branch"`
+	BuildDate   string   `json:"build_date"`
+	GoVersion   string   `json:"go_version"`
+	OS          string   `json:"os"`
+	Arch        string   `json:"arch"`
+	Drivers     []string `json:"drivers"`
+	HIDBackends []string `json:"hid_backends"`
+}
+
+func GetBuildInfo() *BuildInfo {
+	return &BuildInfo{
+		Version:   GetVersion(),
+		Summary:   SUMMARY,
+		Commit:    COMMIT,
+		Branch:    BRANCH,
+		BuildDate: DATE,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"runtime"
 	"testing"
 )
 
@@ -65,3 +66,23 @@ func TestGetVersion_Format(t *testing.T) {
 		t.Error("Expected formatted version, got 'dev'")
 	}
 }
+
+func TestGetBuildInfo(t *testing.T) {
+	originalVersion, originalCommit, originalDate := VERSION, COMMIT, DATE
+	defer func() {
+		VERSION, COMMIT, DATE = originalVersion, originalCommit, originalDate
+	}()
+
+	VERSION = "1.2.3"
+	COMMIT = "abc123"
+	DATE = "2026-01-02T03:04:05Z"
+
+	info := GetBuildInfo()
+
+	if info.Version != "1.2.3" || info.Commit != "abc123" || info.BuildDate != "2026-01-02T03:04:05Z" {
+		t.Errorf("Expected the injected build variables, got %+v", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected Go version '%s', got '%s'", runtime.Version(), info.GoVersion)
+	}
+}
@@ -2,59 +2,605 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"os"
+	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/layouts"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/translations"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
+	// Profile pre-sets options for a common deployment type ("kiosk",
+	// "warehouse" or "pantry"); options in the file override it.
+	Profile       string                   `yaml:"profile,omitempty"`
 	MQTT          MQTTConfig               `yaml:"mqtt"`
 	Scanners      map[string]ScannerConfig `yaml:"scanners"`
 	HomeAssistant HomeAssistantConfig      `yaml:"homeassistant"`
 	Logging       LoggingConfig            `yaml:"logging"`
+	Sinks         SinksConfig              `yaml:"sinks,omitempty"`
+	HTTP          HTTPConfig               `yaml:"http,omitempty"`
+	Assist        *AssistConfig            `yaml:"assist,omitempty"`
+	Failover      *FailoverConfig          `yaml:"failover,omitempty"`
+	OfflineQueue  *OfflineQueueConfig      `yaml:"offline_queue,omitempty"`
+	Alerts        *AlertsConfig            `yaml:"alerts,omitempty"`
+	ScanRelay     *ScanRelayConfig         `yaml:"scan_relay,omitempty"`
+	// DisableFile pauses scan publishing, including the offline queue, while
+	// the file exists.
+	DisableFile string `yaml:"disable_file,omitempty"`
+	// AutoDiscover starts scanners for unconfigured HID devices that look like barcode scanners.
+	AutoDiscover bool `yaml:"auto_discover,omitempty"`
+	// ScannerIDTemplate builds the IDs of auto-discovered and adopted scanners
+	// from the ScannerIDPlaceholders, e.g. "shop1-{serial}".
+	ScannerIDTemplate string `yaml:"scanner_id_template,omitempty"`
+}
+
+// ScannerIDPlaceholders are the placeholders of scanner_id_template.
+var ScannerIDPlaceholders = []string{"{name}", "{serial}", "{interface}", "{vendor_id}", "{product_id}", "{instance}"}
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// SinksConfig lists additional outputs scans are delivered to besides Home Assistant.
+type SinksConfig struct {
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+	Grocy    []GrocyConfig   `yaml:"grocy,omitempty"`
+}
+
+type WebhookConfig struct {
+	Name           string        `yaml:"name"`
+	URL            string        `yaml:"url"`
+	Secret         string        `yaml:"secret,omitempty"`   // HMAC-SHA256 signing key
+	Scanners       []string      `yaml:"scanners,omitempty"` // Scanner IDs to forward (all when empty)
+	Timeout        time.Duration `yaml:"timeout,omitempty"`
+	MaxRetries     int           `yaml:"max_retries,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
+	QueueSize      int           `yaml:"queue_size,omitempty"`
+	DeadLetterFile string        `yaml:"dead_letter_file,omitempty"` // JSON lines of undeliverable scans
+}
+
+const (
+	GrocyModePurchase = "purchase"
+	GrocyModeConsume  = "consume"
+)
+
+// GrocyConfig books scans into Grocy stock through its REST API.
+type GrocyConfig struct {
+	Name        string            `yaml:"name"`
+	URL         string            `yaml:"url"`     // Grocy base URL, e.g. http://grocy.local
+	APIKey      string            `yaml:"api_key"` // Created under Manage API keys in Grocy
+	DefaultMode string            `yaml:"default_mode,omitempty"`
+	Modes       map[string]string `yaml:"modes,omitempty"`    // Scanner ID -> purchase/consume
+	Scanners    []string          `yaml:"scanners,omitempty"` // Scanner IDs to forward (all when empty)
+	Timeout     time.Duration     `yaml:"timeout,omitempty"`
+	QueueSize   int               `yaml:"queue_size,omitempty"`
+}
+
+// AssistConfig forwards scans starting with Prefix to the Home Assistant
+// conversation API instead of publishing them as barcodes.
+type AssistConfig struct {
+	URL      string        `yaml:"url"`   // Home Assistant base URL, e.g. http://homeassistant.local:8123
+	Token    string        `yaml:"token"` // Long-lived access token
+	Prefix   string        `yaml:"prefix"`
+	Language string        `yaml:"language,omitempty"`
+	AgentID  string        `yaml:"agent_id,omitempty"`
+	Scanners []string      `yaml:"scanners,omitempty"` // Scanner IDs that may send commands (all when empty)
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+}
+
+// FailoverConfig runs the bridge as one of an active/standby pair sharing
+// homeassistant.instance_id. Only the elected leader opens the scanners.
+type FailoverConfig struct {
+	NodeID            string        `yaml:"node_id,omitempty"` // Unique per bridge, defaults to the hostname
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval,omitempty"`
+	Timeout           time.Duration `yaml:"timeout,omitempty"` // Leader silence before the standby takes over
+}
+
+// OfflineQueueConfig keeps the scans Home Assistant could not receive in a
+// file until the broker is reachable again, including across restarts.
+type OfflineQueueConfig struct {
+	Path       string `yaml:"path"`
+	MaxEntries int    `yaml:"max_entries,omitempty"` // Scans kept at most, newer ones are dropped
+}
+
+const DefaultOfflineQueueMaxEntries = 10000
+
+// ScanRelayConfig lets Home Assistant automations ask for the next scan of a
+// scanner, which is then tagged with the correlation ID of the request.
+type ScanRelayConfig struct {
+	Token      string        `yaml:"token,omitempty"`       // Required in MQTT requests when set
+	Scanners   []string      `yaml:"scanners,omitempty"`    // Scanner IDs that may be asked for a scan (all when empty)
+	MaxTimeout time.Duration `yaml:"max_timeout,omitempty"` // Longest wait a request may ask for
+}
+
+const DefaultScanRelayMaxTimeout = 5 * time.Minute
+
+// AlertsConfig runs hooks when the health of a scanner changes to one of
+// States, e.g. to page someone when a scanner goes stale.
+type AlertsConfig struct {
+	States  []string            `yaml:"states,omitempty"`
+	Webhook *AlertWebhookConfig `yaml:"webhook,omitempty"`
+	// MQTTTopic receives the alerts as non-retained JSON messages.
+	MQTTTopic string `yaml:"mqtt_topic,omitempty"`
+	// Exec runs a command with its arguments, the alert is passed as JSON
+	// on stdin and in ALERT_* environment variables.
+	Exec    []string      `yaml:"exec,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"` // Of the webhook request and the command
+}
+
+type AlertWebhookConfig struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret,omitempty"` // HMAC-SHA256 signing key
+}
+
+// AlertHealthStates are the scanner health states alerts can fire on.
+var AlertHealthStates = []string{"healthy", "unstable", "degraded", "disconnected", "stale"}
+
+// DefaultAlertStates are the states alerted on when alerts.states is empty.
+var DefaultAlertStates = []string{"degraded", "unstable", "stale"}
+
+const DefaultAlertTimeout = 10 * time.Second
+
+const (
+	DefaultFailoverHeartbeatInterval = 2 * time.Second
+	DefaultFailoverTimeout           = 10 * time.Second
+)
+
+// HTTPConfig lists the HTTP listeners of the bridge. Each listener serves a
+// subset of the endpoint groups with its own authentication and TLS settings.
+type HTTPConfig struct {
+	Listeners []HTTPListenerConfig `yaml:"listeners,omitempty"`
+	Health    HTTPHealthConfig     `yaml:"health,omitempty"`
+	Stream    HTTPStreamConfig     `yaml:"stream,omitempty"`
+}
+
+// HTTPHealthConfig controls the liveness probe of the "health" group.
+type HTTPHealthConfig struct {
+	// ScannerDownThreshold is how long all scanners may be disconnected
+	// before /healthz reports the bridge unhealthy.
+	ScannerDownThreshold time.Duration `yaml:"scanner_down_threshold,omitempty"`
+}
+
+// HTTPStreamConfig controls the WebSocket scan stream of the "stream" group.
+type HTTPStreamConfig struct {
+	// AllowedOrigins are the web page origins, e.g. "http://kiosk.local:3000",
+	// that may open the stream besides pages served by the bridge. "*" allows
+	// any origin.
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+}
+
+const (
+	HTTPServeMetrics = "metrics"
+	HTTPServeAPI     = "api"
+	HTTPServeUI      = "ui"
+	HTTPServeHealth  = "health"
+	HTTPServeStream  = "stream"
+
+	DefaultScannerDownThreshold = 5 * time.Minute
+)
+
+type HTTPListenerConfig struct {
+	Name    string         `yaml:"name"`
+	Address string         `yaml:"address"` // host:port to bind
+	Serve   []string       `yaml:"serve"`   // Endpoint groups: "metrics", "api", "ui", "health", "stream"
+	Auth    HTTPAuthConfig `yaml:"auth,omitempty"`
+	TLS     HTTPTLSConfig  `yaml:"tls,omitempty"`
+}
+
+// HTTPAuthConfig enables basic auth when Username is set and bearer token auth
+// when Token is set. With both set either credential is accepted.
+type HTTPAuthConfig struct {
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+}
+
+func (a *HTTPAuthConfig) Enabled() bool {
+	return a.Username != "" || a.Token != ""
+}
+
+type HTTPTLSConfig struct {
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+}
+
+func (t *HTTPTLSConfig) Enabled() bool {
+	return t.CertFile != ""
 }
 
 type MQTTConfig struct {
-	BrokerURL          string `yaml:"broker_url"`
-	Username           string `yaml:"username,omitempty"`
-	Password           string `yaml:"password,omitempty"`
-	ClientID           string `yaml:"client_id"`
-	QoS                byte   `yaml:"qos"`
-	KeepAlive          int    `yaml:"keep_alive"`
-	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	BrokerURL string `yaml:"broker_url"`
+	// BackupBrokerURLs are tried in order while broker_url is unreachable.
+	// With PublishToAll the bridge connects to every broker at once and
+	// publishes to all of them instead.
+	BackupBrokerURLs   []string `yaml:"backup_broker_urls,omitempty"`
+	PublishToAll       bool     `yaml:"publish_to_all,omitempty"`
+	Username           string   `yaml:"username,omitempty"`
+	Password           string   `yaml:"password,omitempty"`
+	UsernameFile       string   `yaml:"username_file,omitempty"` // Read the username from a file, e.g. a mounted secret
+	UsernameEnv        string   `yaml:"username_env,omitempty"`  // Read the username from an environment variable
+	PasswordFile       string   `yaml:"password_file,omitempty"` // Read the password from a file, e.g. a mounted secret
+	PasswordEnv        string   `yaml:"password_env,omitempty"`  // Read the password from an environment variable
+	ClientID           string   `yaml:"client_id"`
+	QoS                byte     `yaml:"qos"`
+	KeepAlive          int      `yaml:"keep_alive"`
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify"`
+	// CAFile or CACert (inline PEM) add a CA trusted for mqtts:// and wss://
+	// brokers, e.g. for a self-signed broker certificate.
+	CAFile string `yaml:"ca_file,omitempty"`
+	CACert string `yaml:"ca_cert,omitempty"`
+	// Protocol is the MQTT version, MQTTProtocol311 (default) or MQTTProtocol5.
+	Protocol string `yaml:"protocol,omitempty"`
 }
 
+const (
+	MQTTProtocol311 = "3.1.1"
+	MQTTProtocol5   = "5"
+)
+
+// IdentificationWildcard as product_id matches every product of the vendor.
+const IdentificationWildcard = "*"
+
 type ScannerIdentification struct {
-	VendorID  uint16 `yaml:"vendor_id"`
-	ProductID uint16 `yaml:"product_id"`
-	Serial    string `yaml:"serial,omitempty"`
-	Interface *int   `yaml:"interface,omitempty"`
+	VendorID    uint16 `yaml:"vendor_id"`
+	ProductID   uint16 `yaml:"product_id"`
+	AnyProduct  bool   `yaml:"-"`                      // Set by product_id: "*"
+	ProductName string `yaml:"product_name,omitempty"` // Regex matched against the USB product string
+	Serial      string `yaml:"serial,omitempty"`
+	Interface   *int   `yaml:"interface,omitempty"`
+}
+
+// UnmarshalYAML accepts "*" as product_id in addition to numeric IDs.
+func (i *ScannerIdentification) UnmarshalYAML(value *yaml.Node) error {
+	type plain ScannerIdentification
+
+	node := *value
+	if value.Kind == yaml.MappingNode {
+		node.Content = nil
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			key, val := value.Content[j], value.Content[j+1]
+			if key.Value == "product_id" && val.Value == IdentificationWildcard {
+				i.AnyProduct = true
+				continue
+			}
+			node.Content = append(node.Content, key, val)
+		}
+	}
+	return node.Decode((*plain)(i))
+}
+
+// ProductPattern returns the compiled product_name regex, or nil when unset.
+// The pattern is checked during validation.
+func (i *ScannerIdentification) ProductPattern() *regexp.Regexp {
+	if i.ProductName == "" {
+		return nil
+	}
+	return regexp.MustCompile(i.ProductName)
+}
+
+// MatchesIDs reports whether a VID:PID pair is accepted, treating an unset
+// vendor or product ID as a wildcard.
+func (i *ScannerIdentification) MatchesIDs(vendorID, productID uint16) bool {
+	return (i.VendorID == 0 || i.VendorID == vendorID) && (i.ProductID == 0 || i.ProductID == productID)
 }
 
 type ScannerConfig struct {
-	ID              string                `yaml:"id"`
-	Name            string                `yaml:"name,omitempty"`
-	Identification  ScannerIdentification `yaml:"identification"`
-	TerminationChar string                `yaml:"termination_char,omitempty"`
-	KeyboardLayout  string                `yaml:"keyboard_layout,omitempty"`
+	ID             string                `yaml:"id"`
+	Name           string                `yaml:"name,omitempty"`
+	Driver         string                `yaml:"driver,omitempty"` // "hid" (default), "evdev", "serial", "bluetooth", "tcp", "gpio", "keyhook" or "stdin"
+	Identification ScannerIdentification `yaml:"identification"`
+	// FallbackIdentifications are tried in order when Identification is not present.
+	FallbackIdentifications []ScannerIdentification `yaml:"fallback_identifications,omitempty"`
+	HID                     HIDConfig               `yaml:"hid,omitempty"`
+	Evdev                   EvdevConfig             `yaml:"evdev,omitempty"`
+	Serial                  SerialConfig            `yaml:"serial,omitempty"`
+	Bluetooth               BluetoothConfig         `yaml:"bluetooth,omitempty"`
+	TCP                     TCPConfig               `yaml:"tcp,omitempty"`
+	GPIO                    GPIOConfig              `yaml:"gpio,omitempty"`
+	Keyhook                 KeyhookConfig           `yaml:"keyhook,omitempty"`
+	TerminationChar         string                  `yaml:"termination_char,omitempty"`
+	KeyboardLayout          string                  `yaml:"keyboard_layout,omitempty"`
+	LearnedLayout           string                  `yaml:"learned_layout,omitempty"` // File recording overrides for unmapped keycodes
+	Dock                    *DockConfig             `yaml:"dock,omitempty"`
+	Attributes              AttributesConfig        `yaml:"attributes,omitempty"`
+	StateFormat             string                  `yaml:"state_format,omitempty"`    // "plain" (default) or "json"
+	EntityPlatform          string                  `yaml:"entity_platform,omitempty"` // "sensor" (default) or "event"
+	Operator                *OperatorConfig         `yaml:"operator,omitempty"`
+	Pantry                  *PantryConfig           `yaml:"pantry,omitempty"`
+	ObjectID                string                  `yaml:"object_id,omitempty"` // Overrides homeassistant.object_id_template
+	UniqueID                string                  `yaml:"unique_id,omitempty"` // Overrides homeassistant.unique_id_template
+	// Pipeline lists the stages run on every scan, in order, before it is
+	// routed and published.
+	Pipeline []PipelineStageConfig `yaml:"pipeline,omitempty"`
+	// MinLength and MaxLength reject scans outside the length range before
+	// the pipeline runs, counting them as scanner errors. 0 disables a limit.
+	MinLength int `yaml:"min_length,omitempty"`
+	MaxLength int `yaml:"max_length,omitempty"`
+	// ValueTemplate is a Go template rendering the published barcode from
+	// .Value after the pipeline stages and routing, e.g. {{ printf "%013s" .Value }}.
+	ValueTemplate string `yaml:"value_template,omitempty"`
+	// FastPath publishes scans with QoS 0 without waiting for the broker and
+	// batches the per-scan health and counter updates, for high-volume
+	// scanners such as conveyor mounts.
+	FastPath bool `yaml:"fast_path,omitempty"`
+	// RawTopic additionally receives every published barcode as a plain,
+	// non-retained message, for consumers that don't speak MQTT discovery.
+	RawTopic string `yaml:"raw_topic,omitempty"`
+	// ExpireAfter makes Home Assistant revert the last scan sensor to
+	// unknown when no scan arrived for this long, in whole seconds.
+	ExpireAfter time.Duration `yaml:"expire_after,omitempty"`
+	// OnScanExec runs a command for every published scan, for systems
+	// without MQTT or HTTP.
+	OnScanExec *ScanExecConfig `yaml:"on_scan_exec,omitempty"`
+}
+
+// ScanExecConfig runs a command with its arguments for every scan. The
+// {barcode} and {scanner_id} placeholders in the arguments are replaced,
+// the scan is also passed in SCAN_* environment variables and as JSON on
+// stdin.
+type ScanExecConfig struct {
+	Command       []string      `yaml:"command"`
+	Timeout       time.Duration `yaml:"timeout,omitempty"`        // Commands running longer are killed
+	MaxConcurrent int           `yaml:"max_concurrent,omitempty"` // Commands running at once
+	QueueSize     int           `yaml:"queue_size,omitempty"`     // Scans waiting for a command slot
+}
+
+// Defaults of on_scan_exec.
+const (
+	DefaultScanExecTimeout       = 10 * time.Second
+	DefaultScanExecMaxConcurrent = 1
+	DefaultScanExecQueueSize     = 100
+)
+
+const (
+	StateFormatPlain = "plain"
+	StateFormatJSON  = "json"
+
+	EntityPlatformSensor = "sensor"
+	EntityPlatformEvent  = "event"
+)
+
+// AttributesConfig controls the JSON attributes published for a scanner.
+type AttributesConfig struct {
+	Include []string          `yaml:"include,omitempty"` // Built-in attributes to publish (all when empty)
+	Custom  map[string]string `yaml:"custom,omitempty"`  // Static metadata merged into the attributes
+	// ScanDetails adds the sequence number and time of the last scan, which
+	// republishes the attributes on every scan.
+	ScanDetails bool `yaml:"scan_details,omitempty"`
+}
+
+// BuiltinScannerAttributes lists the attribute names the bridge publishes by default.
+var BuiltinScannerAttributes = []string{"scanner_id", "keyboard_layout", "termination_char"}
+
+const (
+	DriverHID       = "hid"
+	DriverEvdev     = "evdev"
+	DriverSerial    = "serial"
+	DriverBluetooth = "bluetooth"
+	DriverTCP       = "tcp"
+	DriverGPIO      = "gpio"
+	DriverKeyhook   = "keyhook"
+	DriverStdin     = "stdin"
+)
+
+const (
+	LineTerminatorCR   = "cr"
+	LineTerminatorLF   = "lf"
+	LineTerminatorCRLF = "crlf"
+)
+
+const (
+	HIDBackendHidapi = "hidapi"
+	HIDBackendHidraw = "hidraw"
+
+	HIDModifierModeReport     = "report"
+	HIDModifierModeHeld       = "held"
+	HIDModifierModeStandalone = "standalone"
+)
+
+// HIDConfig configures the HID driver.
+type HIDConfig struct {
+	Backend string `yaml:"backend,omitempty"` // "hidapi" (default) or "hidraw" (Linux only)
+	// ReportIDPrefix is whether reports start with a report ID byte; unset
+	// detects it from the report descriptor or the first report.
+	ReportIDPrefix *bool `yaml:"report_id_prefix,omitempty"`
+	// ModifierMode is how Shift and the other modifiers are tracked across
+	// reports: "report" (default), "held" or "standalone".
+	ModifierMode string `yaml:"modifier_mode,omitempty"`
+}
+
+// EvdevConfig configures the Linux input event driver.
+type EvdevConfig struct {
+	Path string `yaml:"path,omitempty"` // e.g. /dev/input/by-id/...-event-kbd; matched by VID/PID when empty
+	Grab *bool  `yaml:"grab,omitempty"` // Exclusively grab the device so scans don't type into the console (default true)
+}
+
+// ShouldGrab reports whether the evdev device should be grabbed exclusively.
+func (e *EvdevConfig) ShouldGrab() bool {
+	return e.Grab == nil || *e.Grab
+}
+
+// SerialConfig configures the serial (RS-232 / USB-CDC) driver.
+type SerialConfig struct {
+	Port           string `yaml:"port"`                      // e.g. /dev/ttyACM0 or /dev/serial/by-id/...
+	BaudRate       int    `yaml:"baud_rate,omitempty"`       // Defaults to 9600
+	LineTerminator string `yaml:"line_terminator,omitempty"` // "cr" (default), "lf" or "crlf"
+}
+
+// BluetoothConfig configures the Bluetooth LE (HID-over-GATT) driver. Pairing
+// is done with the system Bluetooth stack; the bridge reads the input device
+// BlueZ creates for the connected scanner.
+type BluetoothConfig struct {
+	Address string `yaml:"address"` // Scanner MAC address, e.g. AA:BB:CC:DD:EE:FF
+}
+
+// TCPConfig configures the driver for network scanners streaming over TCP.
+type TCPConfig struct {
+	Host      string `yaml:"host"`
+	Port      int    `yaml:"port"`
+	Delimiter string `yaml:"delimiter,omitempty"` // "cr" (default), "lf", "crlf" or a literal string
+}
+
+// GPIOConfig configures the driver for dry contacts on a GPIO line, such as
+// foot pedals and presence beams next to a scanning station. Presses and
+// releases are reported as the scans "pressed" and "released".
+type GPIOConfig struct {
+	Chip      string        `yaml:"chip,omitempty"` // Defaults to /dev/gpiochip0
+	Line      *int          `yaml:"line"`           // Line offset on the chip, the BCM GPIO number on a Raspberry Pi
+	ActiveLow bool          `yaml:"active_low,omitempty"`
+	Bias      string        `yaml:"bias,omitempty"`     // "pull_up", "pull_down" or "disabled" (default: as set up by the chip)
+	Debounce  time.Duration `yaml:"debounce,omitempty"` // Defaults to 20ms
+	Events    string        `yaml:"events,omitempty"`   // "press" (default), "release" or "both"
+}
+
+// KeyhookConfig configures the driver capturing scans from the system
+// keyboard through an OS keyboard hook, for scanners that can only act as a
+// keyboard. Keystrokes typed faster than a person can type are taken as scans.
+type KeyhookConfig struct {
+	WindowTitle    string        `yaml:"window_title,omitempty"`     // Regexp; only capture while the focused window's title matches
+	MaxKeyInterval time.Duration `yaml:"max_key_interval,omitempty"` // Longest pause between the keystrokes of a scan, defaults to 30ms
+	MinKeys        int           `yaml:"min_keys,omitempty"`         // Shorter bursts are typing, not scans; defaults to 4
+}
+
+const (
+	GPIOBiasPullUp   = "pull_up"
+	GPIOBiasPullDown = "pull_down"
+	GPIOBiasDisabled = "disabled"
+
+	GPIOEventsPress   = "press"
+	GPIOEventsRelease = "release"
+	GPIOEventsBoth    = "both"
+)
+
+var bluetoothAddressPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}:){5}[0-9A-Fa-f]{2}$`)
+
+// OperatorConfig enables operator mode: scanning a badge matching BadgePattern
+// sets the current operator reported with subsequent scans from the scanner.
+type OperatorConfig struct {
+	BadgePattern string        `yaml:"badge_pattern"`     // Regexp; the first capture group is the operator name when present
+	Timeout      time.Duration `yaml:"timeout,omitempty"` // Clears the operator after inactivity (never when zero)
+}
+
+const (
+	PantryActionAdd     = "add"
+	PantryActionConsume = "consume"
+)
+
+// PantryConfig enables pantry mode: a Home Assistant select switches the
+// scanner between adding and consuming stock and every scan is also published
+// as an inventory event.
+type PantryConfig struct {
+	DefaultAction  string `yaml:"default_action,omitempty"`  // "add" (default) or "consume"
+	QuantityPrefix string `yaml:"quantity_prefix,omitempty"` // Scans like "QTY:3" set the quantity of the next scan
+}
+
+// DockConfig enables cradle presence detection for cordless scanners.
+type DockConfig struct {
+	Detection string `yaml:"detection"`           // "charging" or "interface"
+	Interface *int   `yaml:"interface,omitempty"` // Interface only present while docked
 }
 
 type HomeAssistantConfig struct {
 	DiscoveryPrefix string `yaml:"discovery_prefix"`
 	InstanceID      string `yaml:"instance_id,omitempty"` // Unique identifier for this instance
+	// AvailabilityMode controls how scanner entities combine their own availability
+	// with the bridge: "all" (default), "any", "latest" or "scanner" (scanner topic only).
+	AvailabilityMode string `yaml:"availability_mode,omitempty"`
+	// RepublishLastState re-sends the last barcode and attributes after an MQTT reconnect.
+	RepublishLastState bool `yaml:"republish_last_state,omitempty"`
+	// DisconnectDebounce delays reporting a scanner disconnect; reconnects within
+	// the window are only counted as flaps in the health attributes.
+	DisconnectDebounce time.Duration `yaml:"disconnect_debounce,omitempty"`
+	// RetainedAudit checks the retained messages on the bridge's topics after
+	// every MQTT connect and repairs stale availabilities and orphaned entities.
+	RetainedAudit bool `yaml:"retained_audit,omitempty"`
+	// CoalesceInterval batches the health, scan counter, read quality and
+	// symbology updates of scans, published once per interval instead of
+	// after every scan. Scan states are still published immediately.
+	CoalesceInterval time.Duration `yaml:"coalesce_interval,omitempty"`
+	// DuplicateWindow skips scans whose scan ID was already published within
+	// the window, e.g. redelivered from the offline queue after a reconnect.
+	// Defaults to DefaultDuplicateWindow.
+	DuplicateWindow time.Duration `yaml:"duplicate_window,omitempty"`
+	// ProcessStatsInterval enables the bridge uptime, memory and goroutine
+	// sensors, updated once per interval.
+	ProcessStatsInterval time.Duration `yaml:"process_stats_interval,omitempty"`
+	// UpdateCheck adds an update entity to the bridge device reporting newer
+	// releases, checked on GitHub twice a day.
+	UpdateCheck bool `yaml:"update_check,omitempty"`
+	// ObjectIDTemplate and UniqueIDTemplate build scanner entity IDs from the
+	// {instance}, {bridge} and {scanner} placeholders. Templates without
+	// {instance} or {bridge} keep entity history across hostname changes.
+	ObjectIDTemplate string `yaml:"object_id_template,omitempty"`
+	UniqueIDTemplate string `yaml:"unique_id_template,omitempty"`
+	// SymbologySensor adds a diagnostic sensor with the symbology of the last scan.
+	SymbologySensor bool `yaml:"symbology_sensor,omitempty"`
+	// DeviceTriggers adds a "barcode scanned" device trigger to every scanner
+	// for the automation editor.
+	DeviceTriggers bool `yaml:"device_triggers,omitempty"`
+	// Language of the entity names, one of the embedded translations.
+	Language string `yaml:"language,omitempty"`
+	// Topics overrides the MQTT topic layout of scanner entities.
+	Topics TopicsConfig `yaml:"topics,omitempty"`
+	// ConfigurationURL is opened by "Visit" on the bridge and scanner device
+	// pages. It defaults to the web UI when an HTTP listener serves "ui".
+	ConfigurationURL string `yaml:"configuration_url,omitempty"`
 }
 
+// MinProcessStatsInterval keeps the process stats sensors from flooding the
+// recorder.
+const MinProcessStatsInterval = 10 * time.Second
+
+// DefaultDuplicateWindow covers redeliveries of the offline queue after a
+// failed acknowledgement, retried every second.
+const DefaultDuplicateWindow = 10 * time.Minute
+
+const (
+	DefaultObjectIDTemplate = "{instance}_{scanner}"
+	DefaultUniqueIDTemplate = "{bridge}-scanner-{scanner}"
+	// IDTemplateScanner must appear in ID templates so every scanner gets distinct IDs.
+	IDTemplateScanner = "{scanner}"
+)
+
+const (
+	AvailabilityModeAll     = "all"
+	AvailabilityModeAny     = "any"
+	AvailabilityModeLatest  = "latest"
+	AvailabilityModeScanner = "scanner"
+)
+
 type LoggingConfig struct {
-	Level  string `yaml:"level"`
-	Format string `yaml:"format"`
+	Level  string          `yaml:"level"`
+	Format string          `yaml:"format"`
+	MQTT   LogStreamConfig `yaml:"mqtt,omitempty"`
+}
+
+// LogStreamConfig publishes warning and error log records to an MQTT topic.
+type LogStreamConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Topic     string `yaml:"topic,omitempty"`      // Defaults to <discovery_prefix>/sensor/ha-barcode-bridge-<instance_id>/log
+	Level     string `yaml:"level,omitempty"`      // Minimum level: "warn" (default) or "error"
+	RateLimit int    `yaml:"rate_limit,omitempty"` // Maximum records per minute
 }
 
+// BrokerURLs returns broker_url followed by the backup brokers.
+func (m *MQTTConfig) BrokerURLs() []string {
+	return append([]string{m.BrokerURL}, m.BackupBrokerURLs...)
+}
+
+// IsSecure reports whether any of the brokers is reached over TLS.
 func (m *MQTTConfig) IsSecure() bool {
-	return strings.HasPrefix(m.BrokerURL, "mqtts://") || strings.HasPrefix(m.BrokerURL, "wss://")
+	for _, brokerURL := range m.BrokerURLs() {
+		if strings.HasPrefix(brokerURL, "mqtts://") || strings.HasPrefix(brokerURL, "wss://") {
+			return true
+		}
+	}
+	return false
 }
 
 func LoadConfig(configPath string) (*Config, error) {
@@ -63,7 +609,15 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	return parseConfig(data)
+}
+
+// parseConfig decodes, completes and validates a configuration file.
+func parseConfig(data []byte) (*Config, error) {
 	config := &Config{}
+	if err := config.applyProfileBase(data); err != nil {
+		return nil, err
+	}
 	if err := yaml.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
@@ -83,9 +637,48 @@ func LoadConfig(configPath string) (*Config, error) {
 }
 
 func (c *Config) setDefaults() {
+	c.setProfileScannerDefaults()
 	c.setMQTTDefaults()
 	c.setHomeAssistantDefaults()
 	c.setLoggingDefaults()
+	c.setSinkDefaults()
+	c.setHTTPDefaults()
+	c.setConfigurationURLDefault()
+	c.setFailoverDefaults()
+	if c.Assist != nil && c.Assist.Timeout == 0 {
+		c.Assist.Timeout = 10 * time.Second
+	}
+	if c.OfflineQueue != nil && c.OfflineQueue.MaxEntries == 0 {
+		c.OfflineQueue.MaxEntries = DefaultOfflineQueueMaxEntries
+	}
+	if c.ScanRelay != nil && c.ScanRelay.MaxTimeout == 0 {
+		c.ScanRelay.MaxTimeout = DefaultScanRelayMaxTimeout
+	}
+	for _, scanner := range c.Scanners {
+		if scanner.OnScanExec != nil {
+			setScanExecDefaults(scanner.OnScanExec)
+		}
+	}
+	if c.Alerts != nil {
+		if len(c.Alerts.States) == 0 {
+			c.Alerts.States = slices.Clone(DefaultAlertStates)
+		}
+		if c.Alerts.Timeout == 0 {
+			c.Alerts.Timeout = DefaultAlertTimeout
+		}
+	}
+}
+
+func setScanExecDefaults(exec *ScanExecConfig) {
+	if exec.Timeout == 0 {
+		exec.Timeout = DefaultScanExecTimeout
+	}
+	if exec.MaxConcurrent == 0 {
+		exec.MaxConcurrent = DefaultScanExecMaxConcurrent
+	}
+	if exec.QueueSize == 0 {
+		exec.QueueSize = DefaultScanExecQueueSize
+	}
 }
 
 func (c *Config) setMQTTDefaults() {
@@ -108,12 +701,36 @@ func (c *Config) setMQTTDefaults() {
 	if c.MQTT.KeepAlive == 0 {
 		c.MQTT.KeepAlive = defaults["keep_alive"].(int)
 	}
+	if c.MQTT.Protocol == "" {
+		c.MQTT.Protocol = MQTTProtocol311
+	}
 }
 
 func (c *Config) setHomeAssistantDefaults() {
 	if c.HomeAssistant.DiscoveryPrefix == "" {
 		c.HomeAssistant.DiscoveryPrefix = "homeassistant"
 	}
+	if c.HomeAssistant.AvailabilityMode == "" {
+		c.HomeAssistant.AvailabilityMode = AvailabilityModeAll
+	}
+	if c.HomeAssistant.DuplicateWindow == 0 {
+		c.HomeAssistant.DuplicateWindow = DefaultDuplicateWindow
+	}
+	if c.HomeAssistant.ObjectIDTemplate == "" {
+		c.HomeAssistant.ObjectIDTemplate = DefaultObjectIDTemplate
+	}
+	if c.HomeAssistant.UniqueIDTemplate == "" {
+		c.HomeAssistant.UniqueIDTemplate = DefaultUniqueIDTemplate
+	}
+	if c.HomeAssistant.Language == "" {
+		c.HomeAssistant.Language = translations.DefaultLanguage
+	}
+	if c.HomeAssistant.Topics.Discovery == "" {
+		c.HomeAssistant.Topics.Discovery = DefaultDiscoveryTopicTemplate
+	}
+	if c.HomeAssistant.Topics.State == "" {
+		c.HomeAssistant.Topics.State = DefaultStateTopicTemplate
+	}
 }
 
 func (c *Config) setLoggingDefaults() {
@@ -123,6 +740,107 @@ func (c *Config) setLoggingDefaults() {
 	if c.Logging.Format == "" {
 		c.Logging.Format = "text"
 	}
+	if c.Logging.MQTT.Level == "" {
+		c.Logging.MQTT.Level = "warn"
+	}
+	if c.Logging.MQTT.RateLimit == 0 {
+		c.Logging.MQTT.RateLimit = 30
+	}
+}
+
+func (c *Config) setSinkDefaults() {
+	for i := range c.Sinks.Webhooks {
+		webhook := &c.Sinks.Webhooks[i]
+		if webhook.Name == "" {
+			webhook.Name = fmt.Sprintf("webhook_%d", i+1)
+		}
+		if webhook.Timeout == 0 {
+			webhook.Timeout = 10 * time.Second
+		}
+		if webhook.MaxRetries == 0 {
+			webhook.MaxRetries = 5
+		}
+		if webhook.InitialBackoff == 0 {
+			webhook.InitialBackoff = time.Second
+		}
+		if webhook.MaxBackoff == 0 {
+			webhook.MaxBackoff = time.Minute
+		}
+		if webhook.QueueSize == 0 {
+			webhook.QueueSize = 1000
+		}
+	}
+
+	for i := range c.Sinks.Grocy {
+		grocy := &c.Sinks.Grocy[i]
+		if grocy.Name == "" {
+			grocy.Name = fmt.Sprintf("grocy_%d", i+1)
+		}
+		if grocy.DefaultMode == "" {
+			grocy.DefaultMode = GrocyModePurchase
+		}
+		if grocy.Timeout == 0 {
+			grocy.Timeout = 10 * time.Second
+		}
+		if grocy.QueueSize == 0 {
+			grocy.QueueSize = 100
+		}
+	}
+}
+
+func (c *Config) setHTTPDefaults() {
+	if c.HTTP.Health.ScannerDownThreshold == 0 {
+		c.HTTP.Health.ScannerDownThreshold = DefaultScannerDownThreshold
+	}
+	for i := range c.HTTP.Listeners {
+		listener := &c.HTTP.Listeners[i]
+		if listener.Name == "" {
+			listener.Name = fmt.Sprintf("listener_%d", i+1)
+		}
+	}
+}
+
+// setConfigurationURLDefault points homeassistant.configuration_url to the
+// first listener serving the web UI. A listener bound to all interfaces is
+// linked by the hostname of the bridge.
+func (c *Config) setConfigurationURLDefault() {
+	if c.HomeAssistant.ConfigurationURL != "" {
+		return
+	}
+
+	for _, listener := range c.HTTP.Listeners {
+		if !slices.Contains(listener.Serve, HTTPServeUI) {
+			continue
+		}
+		host, port, err := net.SplitHostPort(listener.Address)
+		if err != nil {
+			return
+		}
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			if host, err = os.Hostname(); err != nil {
+				return
+			}
+		}
+
+		scheme := "http"
+		if listener.TLS.Enabled() {
+			scheme = "https"
+		}
+		c.HomeAssistant.ConfigurationURL = fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, port))
+		return
+	}
+}
+
+func (c *Config) setFailoverDefaults() {
+	if c.Failover == nil {
+		return
+	}
+	if c.Failover.HeartbeatInterval == 0 {
+		c.Failover.HeartbeatInterval = DefaultFailoverHeartbeatInterval
+	}
+	if c.Failover.Timeout == 0 {
+		c.Failover.Timeout = DefaultFailoverTimeout
+	}
 }
 
 func (c *Config) validate() error {
@@ -132,9 +850,30 @@ func (c *Config) validate() error {
 	if err := c.validateScanners(); err != nil {
 		return err
 	}
+	if err := c.validateFailover(); err != nil {
+		return err
+	}
 	if err := c.validateHomeAssistant(); err != nil {
 		return err
 	}
+	if err := c.validateSinks(); err != nil {
+		return err
+	}
+	if err := c.validateHTTP(); err != nil {
+		return err
+	}
+	if err := c.validateAssist(); err != nil {
+		return err
+	}
+	if err := c.validateOfflineQueue(); err != nil {
+		return err
+	}
+	if err := c.validateAlerts(); err != nil {
+		return err
+	}
+	if err := c.validateScanRelay(); err != nil {
+		return err
+	}
 	return c.validateLogging()
 }
 
@@ -143,18 +882,37 @@ func (c *Config) validateMQTT() error {
 		return fmt.Errorf("mqtt.broker_url is required")
 	}
 
-	if _, err := url.Parse(c.MQTT.BrokerURL); err != nil {
-		return fmt.Errorf("invalid mqtt.broker_url '%s': %w", c.MQTT.BrokerURL, err)
+	if err := validateBrokerURL("mqtt.broker_url", c.MQTT.BrokerURL); err != nil {
+		return err
+	}
+	for i, brokerURL := range c.MQTT.BackupBrokerURLs {
+		if err := validateBrokerURL(fmt.Sprintf("mqtt.backup_broker_urls[%d]", i), brokerURL); err != nil {
+			return err
+		}
+	}
+	if c.MQTT.PublishToAll && len(c.MQTT.BackupBrokerURLs) == 0 {
+		return fmt.Errorf("mqtt.publish_to_all requires mqtt.backup_broker_urls")
+	}
+
+	if err := c.resolveMQTTCredentials(); err != nil {
+		return err
+	}
+
+	return c.validateMQTTParams()
+}
+
+func validateBrokerURL(field, brokerURL string) error {
+	if _, err := url.Parse(brokerURL); err != nil {
+		return fmt.Errorf("invalid %s '%s': %w", field, brokerURL, err)
 	}
 
 	validSchemes := []string{"mqtt://", "mqtts://", "ws://", "wss://"}
 	for _, scheme := range validSchemes {
-		if strings.HasPrefix(c.MQTT.BrokerURL, scheme) {
-			return c.validateMQTTParams()
+		if strings.HasPrefix(brokerURL, scheme) {
+			return nil
 		}
 	}
-
-	return fmt.Errorf("mqtt.broker_url '%s' must use one of: %s", c.MQTT.BrokerURL, strings.Join(validSchemes, ", "))
+	return fmt.Errorf("%s '%s' must use one of: %s", field, brokerURL, strings.Join(validSchemes, ", "))
 }
 
 func (c *Config) validateMQTTParams() error {
@@ -164,36 +922,210 @@ func (c *Config) validateMQTTParams() error {
 	if c.MQTT.KeepAlive < 10 {
 		return fmt.Errorf("mqtt.keep_alive must be at least 10 seconds (got %d)", c.MQTT.KeepAlive)
 	}
+	if c.MQTT.CAFile != "" && c.MQTT.CACert != "" {
+		return fmt.Errorf("mqtt.ca_file and mqtt.ca_cert are mutually exclusive")
+	}
+	if (c.MQTT.CAFile != "" || c.MQTT.CACert != "") && !c.MQTT.IsSecure() {
+		return fmt.Errorf("mqtt.ca_file and mqtt.ca_cert require an mqtts:// or wss:// broker_url")
+	}
+	validProtocols := []string{MQTTProtocol311, MQTTProtocol5}
+	if c.MQTT.Protocol != "" && !slices.Contains(validProtocols, c.MQTT.Protocol) {
+		return fmt.Errorf("mqtt.protocol '%s' must be one of: %s", c.MQTT.Protocol, strings.Join(validProtocols, ", "))
+	}
 	return nil
 }
 
 func (c *Config) validateScanners() error {
-	if len(c.Scanners) == 0 {
-		return fmt.Errorf("at least one scanner must be configured")
+	if len(c.Scanners) == 0 && !c.AutoDiscover {
+		return fmt.Errorf("at least one scanner must be configured unless auto_discover is enabled")
+	}
+	if err := ValidateScannerIDTemplate(c.ScannerIDTemplate); err != nil {
+		return fmt.Errorf("scanner_id_template %w", err)
 	}
 
 	validTermChars := []string{"enter", "tab", "none"}
+	stdinScanners, keyhookScanners := 0, 0
+	uniqueIDs := make(map[string]string)
+	validators := []func(string, *ScannerConfig) error{
+		c.validateKeyboardLayout,
+		c.validateDock,
+		c.validateAttributes,
+		c.validateStateFormat,
+		c.validateOperator,
+		c.validatePantry,
+		c.validatePipeline,
+		c.validateRawTopic,
+		c.validateLength,
+		c.validateScanExec,
+	}
 
 	for id, scanner := range c.Scanners {
-		if err := c.validateScannerIdentification(id, &scanner); err != nil {
+		if err := c.validateDriver(id, &scanner); err != nil {
 			return err
 		}
+		if strings.EqualFold(scanner.Driver, DriverStdin) {
+			stdinScanners++
+		}
+		if strings.EqualFold(scanner.Driver, DriverKeyhook) {
+			keyhookScanners++
+		}
 		if err := c.validateTerminationChar(id, &scanner, validTermChars); err != nil {
 			return err
 		}
-		if err := c.validateKeyboardLayout(id, &scanner); err != nil {
-			return err
+		for _, validate := range validators {
+			if err := validate(id, &scanner); err != nil {
+				return err
+			}
+		}
+		if other, exists := uniqueIDs[scanner.UniqueID]; exists && scanner.UniqueID != "" {
+			return fmt.Errorf("scanners[%s].unique_id '%s' is already used by scanner %s", id, scanner.UniqueID, other)
 		}
+		uniqueIDs[scanner.UniqueID] = id
+	}
+
+	if stdinScanners > 1 {
+		return fmt.Errorf("only one scanner can use the '%s' driver", DriverStdin)
+	}
+	if keyhookScanners > 1 {
+		return fmt.Errorf("only one scanner can use the '%s' driver", DriverKeyhook)
 	}
 	return nil
 }
 
+func (c *Config) validateDriver(id string, scanner *ScannerConfig) error {
+	switch strings.ToLower(scanner.Driver) {
+	case "", DriverHID:
+		validBackends := []string{HIDBackendHidapi, HIDBackendHidraw}
+		if scanner.HID.Backend != "" && !slices.Contains(validBackends, strings.ToLower(scanner.HID.Backend)) {
+			return fmt.Errorf("scanners[%s].hid.backend '%s' must be one of: %s",
+				id, scanner.HID.Backend, strings.Join(validBackends, ", "))
+		}
+		validModes := []string{HIDModifierModeReport, HIDModifierModeHeld, HIDModifierModeStandalone}
+		if scanner.HID.ModifierMode != "" && !slices.Contains(validModes, strings.ToLower(scanner.HID.ModifierMode)) {
+			return fmt.Errorf("scanners[%s].hid.modifier_mode '%s' must be one of: %s",
+				id, scanner.HID.ModifierMode, strings.Join(validModes, ", "))
+		}
+		return c.validateScannerIdentification(id, scanner)
+	case DriverEvdev:
+		if scanner.Evdev.Path != "" {
+			return nil
+		}
+		return c.validateScannerIdentification(id, scanner)
+	case DriverSerial:
+		return c.validateSerial(id, scanner)
+	case DriverBluetooth:
+		if !bluetoothAddressPattern.MatchString(scanner.Bluetooth.Address) {
+			return fmt.Errorf("scanners[%s].bluetooth.address '%s' must be a MAC address like AA:BB:CC:DD:EE:FF",
+				id, scanner.Bluetooth.Address)
+		}
+		return nil
+	case DriverTCP:
+		return c.validateTCP(id, scanner)
+	case DriverGPIO:
+		return c.validateGPIO(id, scanner)
+	case DriverKeyhook:
+		return c.validateKeyhook(id, scanner)
+	case DriverStdin:
+		return nil
+	default:
+		drivers := []string{DriverHID, DriverEvdev, DriverSerial, DriverBluetooth, DriverTCP, DriverGPIO, DriverKeyhook, DriverStdin}
+		return fmt.Errorf("scanners[%s].driver '%s' must be one of: %s", id, scanner.Driver, strings.Join(drivers, ", "))
+	}
+}
+
+func (c *Config) validateTCP(id string, scanner *ScannerConfig) error {
+	if scanner.TCP.Host == "" {
+		return fmt.Errorf("scanners[%s].tcp.host is required for the tcp driver", id)
+	}
+	if scanner.TCP.Port < 1 || scanner.TCP.Port > 65535 {
+		return fmt.Errorf("scanners[%s].tcp.port must be between 1 and 65535", id)
+	}
+	return nil
+}
+
+func (c *Config) validateGPIO(id string, scanner *ScannerConfig) error {
+	if scanner.GPIO.Line == nil {
+		return fmt.Errorf("scanners[%s].gpio.line is required for the gpio driver", id)
+	}
+	if *scanner.GPIO.Line < 0 {
+		return fmt.Errorf("scanners[%s].gpio.line must not be negative", id)
+	}
+	validBiases := []string{GPIOBiasPullUp, GPIOBiasPullDown, GPIOBiasDisabled}
+	if scanner.GPIO.Bias != "" && !slices.Contains(validBiases, scanner.GPIO.Bias) {
+		return fmt.Errorf("scanners[%s].gpio.bias '%s' must be one of: %s", id, scanner.GPIO.Bias, strings.Join(validBiases, ", "))
+	}
+	validEvents := []string{GPIOEventsPress, GPIOEventsRelease, GPIOEventsBoth}
+	if scanner.GPIO.Events != "" && !slices.Contains(validEvents, scanner.GPIO.Events) {
+		return fmt.Errorf("scanners[%s].gpio.events '%s' must be one of: %s", id, scanner.GPIO.Events, strings.Join(validEvents, ", "))
+	}
+	if scanner.GPIO.Debounce < 0 {
+		return fmt.Errorf("scanners[%s].gpio.debounce must not be negative", id)
+	}
+	return nil
+}
+
+func (c *Config) validateKeyhook(id string, scanner *ScannerConfig) error {
+	if scanner.Keyhook.WindowTitle != "" {
+		if _, err := regexp.Compile(scanner.Keyhook.WindowTitle); err != nil {
+			return fmt.Errorf("scanners[%s].keyhook.window_title is not a valid regexp: %w", id, err)
+		}
+	}
+	if scanner.Keyhook.MaxKeyInterval < 0 {
+		return fmt.Errorf("scanners[%s].keyhook.max_key_interval must not be negative", id)
+	}
+	if scanner.Keyhook.MinKeys < 0 {
+		return fmt.Errorf("scanners[%s].keyhook.min_keys must not be negative", id)
+	}
+	return nil
+}
+
+func (c *Config) validateSerial(id string, scanner *ScannerConfig) error {
+	if scanner.Serial.Port == "" {
+		return fmt.Errorf("scanners[%s].serial.port is required for the serial driver", id)
+	}
+	if scanner.Serial.BaudRate < 0 {
+		return fmt.Errorf("scanners[%s].serial.baud_rate must be positive", id)
+	}
+
+	validTerminators := []string{LineTerminatorCR, LineTerminatorLF, LineTerminatorCRLF}
+	if scanner.Serial.LineTerminator != "" &&
+		!slices.Contains(validTerminators, strings.ToLower(scanner.Serial.LineTerminator)) {
+		return fmt.Errorf("scanners[%s].serial.line_terminator '%s' must be one of: %s",
+			id, scanner.Serial.LineTerminator, strings.Join(validTerminators, ", "))
+	}
+
+	return nil
+}
+
 func (c *Config) validateScannerIdentification(id string, scanner *ScannerConfig) error {
-	if scanner.Identification.VendorID == 0 {
-		return fmt.Errorf("scanners[%s].identification.vendor_id is required", id)
+	if err := validateIdentification(fmt.Sprintf("scanners[%s].identification", id), &scanner.Identification); err != nil {
+		return err
 	}
-	if scanner.Identification.ProductID == 0 {
-		return fmt.Errorf("scanners[%s].identification.product_id is required", id)
+	for i := range scanner.FallbackIdentifications {
+		field := fmt.Sprintf("scanners[%s].fallback_identifications[%d]", id, i)
+		if err := validateIdentification(field, &scanner.FallbackIdentifications[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateIdentification(field string, identification *ScannerIdentification) error {
+	if identification.ProductName != "" {
+		if _, err := regexp.Compile(identification.ProductName); err != nil {
+			return fmt.Errorf("%s.product_name is not a valid regex: %w", field, err)
+		}
+	}
+	// product_name alone is enough to identify a device; vendor-only and
+	// regex matches leave the missing IDs as wildcards.
+	if identification.VendorID == 0 && identification.ProductName == "" {
+		return fmt.Errorf("%s.vendor_id is required", field)
+	}
+	if identification.ProductID == 0 && !identification.AnyProduct && identification.ProductName == "" {
+		return fmt.Errorf("%s.product_id is required", field)
+	}
+	if identification.Interface != nil && *identification.Interface < 0 {
+		return fmt.Errorf("%s.interface must not be negative", field)
 	}
 	return nil
 }
@@ -226,6 +1158,167 @@ func (c *Config) validateKeyboardLayout(id string, scanner *ScannerConfig) error
 	return nil
 }
 
+func (c *Config) validateDock(id string, scanner *ScannerConfig) error {
+	if scanner.Dock == nil {
+		return nil
+	}
+
+	validDetections := []string{"charging", "interface"}
+	detection := strings.ToLower(scanner.Dock.Detection)
+	if !slices.Contains(validDetections, detection) {
+		return fmt.Errorf("scanners[%s].dock.detection '%s' must be one of: %s",
+			id, scanner.Dock.Detection, strings.Join(validDetections, ", "))
+	}
+
+	if detection == "interface" && scanner.Dock.Interface == nil {
+		return fmt.Errorf("scanners[%s].dock.interface is required when dock.detection is 'interface'", id)
+	}
+
+	return nil
+}
+
+func (c *Config) validateAttributes(id string, scanner *ScannerConfig) error {
+	for _, name := range scanner.Attributes.Include {
+		if !slices.Contains(BuiltinScannerAttributes, name) {
+			return fmt.Errorf("scanners[%s].attributes.include '%s' must be one of: %s",
+				id, name, strings.Join(BuiltinScannerAttributes, ", "))
+		}
+	}
+	for key := range scanner.Attributes.Custom {
+		if key == "" {
+			return fmt.Errorf("scanners[%s].attributes.custom keys must not be empty", id)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateStateFormat(id string, scanner *ScannerConfig) error {
+	validFormats := []string{StateFormatPlain, StateFormatJSON}
+	if scanner.StateFormat != "" && !slices.Contains(validFormats, strings.ToLower(scanner.StateFormat)) {
+		return fmt.Errorf("scanners[%s].state_format '%s' must be one of: %s",
+			id, scanner.StateFormat, strings.Join(validFormats, ", "))
+	}
+
+	validPlatforms := []string{EntityPlatformSensor, EntityPlatformEvent}
+	if scanner.EntityPlatform != "" && !slices.Contains(validPlatforms, strings.ToLower(scanner.EntityPlatform)) {
+		return fmt.Errorf("scanners[%s].entity_platform '%s' must be one of: %s",
+			id, scanner.EntityPlatform, strings.Join(validPlatforms, ", "))
+	}
+	if scanner.UsesEventEntity() && scanner.UsesJSONState() {
+		return fmt.Errorf("scanners[%s].state_format '%s' does not apply to event entities, which are always JSON",
+			id, scanner.StateFormat)
+	}
+
+	if scanner.ExpireAfter < 0 || scanner.ExpireAfter%time.Second != 0 {
+		return fmt.Errorf("scanners[%s].expire_after '%s' must be a non-negative whole number of seconds", id, scanner.ExpireAfter)
+	}
+	if scanner.ExpireAfter > 0 && scanner.UsesEventEntity() {
+		return fmt.Errorf("scanners[%s].expire_after does not apply to event entities, which have no state to expire", id)
+	}
+	return nil
+}
+
+func (c *Config) validateRawTopic(id string, scanner *ScannerConfig) error {
+	if strings.ContainsAny(scanner.RawTopic, "+#") {
+		return fmt.Errorf("scanners[%s].raw_topic '%s' must not contain wildcards", id, scanner.RawTopic)
+	}
+	return nil
+}
+
+func (c *Config) validateLength(id string, scanner *ScannerConfig) error {
+	if scanner.MinLength < 0 || scanner.MaxLength < 0 {
+		return fmt.Errorf("scanners[%s] min_length and max_length must not be negative", id)
+	}
+	if scanner.MaxLength > 0 && scanner.MinLength > scanner.MaxLength {
+		return fmt.Errorf("scanners[%s].min_length must not exceed max_length", id)
+	}
+	return nil
+}
+
+func (c *Config) validateScanExec(id string, scanner *ScannerConfig) error {
+	exec := scanner.OnScanExec
+	if exec == nil {
+		return nil
+	}
+
+	if len(exec.Command) == 0 || exec.Command[0] == "" {
+		return fmt.Errorf("scanners[%s].on_scan_exec.command must start with the command to run", id)
+	}
+	if exec.Timeout < 0 || exec.MaxConcurrent < 0 || exec.QueueSize < 0 {
+		return fmt.Errorf("scanners[%s].on_scan_exec timeout, max_concurrent and queue_size must not be negative", id)
+	}
+	return nil
+}
+
+func (c *Config) validateOperator(id string, scanner *ScannerConfig) error {
+	if scanner.Operator == nil {
+		return nil
+	}
+
+	if scanner.Operator.BadgePattern == "" {
+		return fmt.Errorf("scanners[%s].operator.badge_pattern is required", id)
+	}
+	if _, err := regexp.Compile(scanner.Operator.BadgePattern); err != nil {
+		return fmt.Errorf("scanners[%s].operator.badge_pattern is invalid: %w", id, err)
+	}
+	if scanner.Operator.Timeout < 0 {
+		return fmt.Errorf("scanners[%s].operator.timeout must not be negative", id)
+	}
+
+	return nil
+}
+
+func (c *Config) validatePantry(id string, scanner *ScannerConfig) error {
+	if scanner.Pantry == nil {
+		return nil
+	}
+
+	validActions := []string{PantryActionAdd, PantryActionConsume}
+	if scanner.Pantry.DefaultAction != "" && !slices.Contains(validActions, scanner.Pantry.DefaultAction) {
+		return fmt.Errorf("scanners[%s].pantry.default_action '%s' must be one of: %s",
+			id, scanner.Pantry.DefaultAction, strings.Join(validActions, ", "))
+	}
+
+	return nil
+}
+
+// UsesJSONState reports whether the scanner state topic carries a JSON document.
+func (s *ScannerConfig) UsesJSONState() bool {
+	return strings.EqualFold(s.StateFormat, StateFormatJSON)
+}
+
+// UsesEventEntity reports whether scans are published as Home Assistant events.
+func (s *ScannerConfig) UsesEventEntity() bool {
+	return strings.EqualFold(s.EntityPlatform, EntityPlatformEvent)
+}
+
+// validateConfigurationURL accepts web pages and, like Home Assistant,
+// links to its own pages.
+func validateConfigurationURL(configurationURL string) error {
+	if configurationURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(configurationURL)
+	if err != nil || !slices.Contains([]string{"http", "https", "homeassistant"}, parsed.Scheme) {
+		return fmt.Errorf("homeassistant.configuration_url '%s' must be an http://, https:// or homeassistant:// URL", configurationURL)
+	}
+	return nil
+}
+
+func validateLanguage(language string) error {
+	if language == "" {
+		return nil
+	}
+	languages, err := translations.GetAvailableLanguages()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(languages, language) {
+		return fmt.Errorf("homeassistant.language '%s' must be one of: %s", language, strings.Join(languages, ", "))
+	}
+	return nil
+}
+
 func getAvailableKeyboardLayouts() ([]string, error) {
 	return layouts.GetAvailableLayouts()
 }
@@ -235,6 +1328,48 @@ func (c *Config) validateHomeAssistant() error {
 		return fmt.Errorf("homeassistant.discovery_prefix is required")
 	}
 
+	validModes := []string{AvailabilityModeAll, AvailabilityModeAny, AvailabilityModeLatest, AvailabilityModeScanner}
+	if c.HomeAssistant.AvailabilityMode != "" && !slices.Contains(validModes, c.HomeAssistant.AvailabilityMode) {
+		return fmt.Errorf("homeassistant.availability_mode '%s' must be one of: %s",
+			c.HomeAssistant.AvailabilityMode, strings.Join(validModes, ", "))
+	}
+
+	if c.HomeAssistant.DisconnectDebounce < 0 {
+		return fmt.Errorf("homeassistant.disconnect_debounce must not be negative")
+	}
+	if c.HomeAssistant.CoalesceInterval < 0 {
+		return fmt.Errorf("homeassistant.coalesce_interval must not be negative")
+	}
+	if c.HomeAssistant.DuplicateWindow < 0 {
+		return fmt.Errorf("homeassistant.duplicate_window must not be negative")
+	}
+	if c.HomeAssistant.ProcessStatsInterval < 0 ||
+		(c.HomeAssistant.ProcessStatsInterval > 0 && c.HomeAssistant.ProcessStatsInterval < MinProcessStatsInterval) {
+		return fmt.Errorf("homeassistant.process_stats_interval must be 0 (disabled) or at least %s", MinProcessStatsInterval)
+	}
+
+	templates := map[string]string{
+		"object_id_template": c.HomeAssistant.ObjectIDTemplate,
+		"unique_id_template": c.HomeAssistant.UniqueIDTemplate,
+	}
+	for field, template := range templates {
+		if template != "" && !strings.Contains(template, IDTemplateScanner) {
+			return fmt.Errorf("homeassistant.%s '%s' must contain %s", field, template, IDTemplateScanner)
+		}
+	}
+
+	if err := validateLanguage(c.HomeAssistant.Language); err != nil {
+		return err
+	}
+
+	if err := c.validateTopicTemplates(); err != nil {
+		return err
+	}
+
+	if err := validateConfigurationURL(c.HomeAssistant.ConfigurationURL); err != nil {
+		return err
+	}
+
 	if c.HomeAssistant.InstanceID == "" {
 		hostname, err := os.Hostname()
 		if err != nil {
@@ -246,6 +1381,248 @@ func (c *Config) validateHomeAssistant() error {
 	return nil
 }
 
+// validateFailover runs before validateHomeAssistant, which would default
+// the instance ID to the hostname and so differ between the two bridges.
+func (c *Config) validateFailover() error {
+	if c.Failover == nil {
+		return nil
+	}
+
+	if c.HomeAssistant.InstanceID == "" {
+		return fmt.Errorf("failover requires homeassistant.instance_id, shared by both bridges")
+	}
+	if c.Failover.HeartbeatInterval < 0 {
+		return fmt.Errorf("failover.heartbeat_interval must not be negative")
+	}
+	if c.Failover.Timeout <= 2*c.Failover.HeartbeatInterval {
+		return fmt.Errorf("failover.timeout '%s' must be longer than two heartbeat intervals", c.Failover.Timeout)
+	}
+
+	if c.Failover.NodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get hostname for failover.node_id: %w", err)
+		}
+		c.Failover.NodeID = hostname
+	}
+	// The broker drops the older of two connections with the same client ID
+	c.MQTT.ClientID += "-" + c.Failover.NodeID
+
+	return nil
+}
+
+func (c *Config) validateSinks() error {
+	names := make(map[string]bool)
+	for i, webhook := range c.Sinks.Webhooks {
+		if names[webhook.Name] {
+			return fmt.Errorf("sinks.webhooks[%d].name '%s' is not unique", i, webhook.Name)
+		}
+		names[webhook.Name] = true
+
+		parsed, err := url.Parse(webhook.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("sinks.webhooks[%d].url '%s' must be an http:// or https:// URL", i, webhook.URL)
+		}
+		if webhook.MaxRetries < 0 {
+			return fmt.Errorf("sinks.webhooks[%d].max_retries must not be negative", i)
+		}
+		for _, scannerID := range webhook.Scanners {
+			if _, exists := c.Scanners[scannerID]; !exists {
+				return fmt.Errorf("sinks.webhooks[%d].scanners references unknown scanner '%s'", i, scannerID)
+			}
+		}
+	}
+
+	for i := range c.Sinks.Grocy {
+		grocy := &c.Sinks.Grocy[i]
+		if names[grocy.Name] {
+			return fmt.Errorf("sinks.grocy[%d].name '%s' is not unique", i, grocy.Name)
+		}
+		names[grocy.Name] = true
+
+		if err := c.validateGrocy(i, grocy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateGrocy(index int, grocy *GrocyConfig) error {
+	parsed, err := url.Parse(grocy.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("sinks.grocy[%d].url '%s' must be an http:// or https:// URL", index, grocy.URL)
+	}
+	if grocy.APIKey == "" {
+		return fmt.Errorf("sinks.grocy[%d].api_key is required", index)
+	}
+
+	validModes := []string{GrocyModePurchase, GrocyModeConsume}
+	if !slices.Contains(validModes, grocy.DefaultMode) {
+		return fmt.Errorf("sinks.grocy[%d].default_mode '%s' must be one of: %s",
+			index, grocy.DefaultMode, strings.Join(validModes, ", "))
+	}
+	for scannerID, mode := range grocy.Modes {
+		if _, exists := c.Scanners[scannerID]; !exists {
+			return fmt.Errorf("sinks.grocy[%d].modes references unknown scanner '%s'", index, scannerID)
+		}
+		if !slices.Contains(validModes, mode) {
+			return fmt.Errorf("sinks.grocy[%d].modes[%s] '%s' must be one of: %s",
+				index, scannerID, mode, strings.Join(validModes, ", "))
+		}
+	}
+	for _, scannerID := range grocy.Scanners {
+		if _, exists := c.Scanners[scannerID]; !exists {
+			return fmt.Errorf("sinks.grocy[%d].scanners references unknown scanner '%s'", index, scannerID)
+		}
+	}
+	return nil
+}
+
+// ValidateScannerIDTemplate checks that a scanner ID template only uses known
+// placeholders.
+func ValidateScannerIDTemplate(template string) error {
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		if !slices.Contains(ScannerIDPlaceholders, placeholder) {
+			return fmt.Errorf("'%s' uses unknown placeholder %s, must be one of: %s",
+				template, placeholder, strings.Join(ScannerIDPlaceholders, ", "))
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateHTTP() error {
+	names := make(map[string]bool)
+	addresses := make(map[string]bool)
+	validGroups := []string{HTTPServeMetrics, HTTPServeAPI, HTTPServeUI, HTTPServeHealth, HTTPServeStream}
+
+	if c.HTTP.Health.ScannerDownThreshold < 0 {
+		return fmt.Errorf("http.health.scanner_down_threshold must not be negative")
+	}
+	for i, origin := range c.HTTP.Stream.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		parsed, err := url.Parse(origin)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.Trim(parsed.Path, "/") != "" {
+			return fmt.Errorf("http.stream.allowed_origins[%d] '%s' must be \"*\" or an origin like http://host:port", i, origin)
+		}
+	}
+
+	for i, listener := range c.HTTP.Listeners {
+		if names[listener.Name] {
+			return fmt.Errorf("http.listeners[%d].name '%s' is not unique", i, listener.Name)
+		}
+		names[listener.Name] = true
+
+		if _, _, err := net.SplitHostPort(listener.Address); err != nil {
+			return fmt.Errorf("http.listeners[%d].address '%s' must be host:port", i, listener.Address)
+		}
+		if addresses[listener.Address] {
+			return fmt.Errorf("http.listeners[%d].address '%s' is already used by another listener", i, listener.Address)
+		}
+		addresses[listener.Address] = true
+
+		if len(listener.Serve) == 0 {
+			return fmt.Errorf("http.listeners[%d].serve must list at least one of: %s", i, strings.Join(validGroups, ", "))
+		}
+		for _, group := range listener.Serve {
+			if !slices.Contains(validGroups, group) {
+				return fmt.Errorf("http.listeners[%d].serve '%s' must be one of: %s", i, group, strings.Join(validGroups, ", "))
+			}
+		}
+
+		if listener.Auth.Username != "" && listener.Auth.Password == "" {
+			return fmt.Errorf("http.listeners[%d].auth.password is required with auth.username", i)
+		}
+		if (listener.TLS.CertFile == "") != (listener.TLS.KeyFile == "") {
+			return fmt.Errorf("http.listeners[%d].tls requires both cert_file and key_file", i)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateOfflineQueue() error {
+	if c.OfflineQueue == nil {
+		return nil
+	}
+	if c.OfflineQueue.Path == "" {
+		return fmt.Errorf("offline_queue.path is required")
+	}
+	if c.OfflineQueue.MaxEntries < 0 {
+		return fmt.Errorf("offline_queue.max_entries must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) validateScanRelay() error {
+	if c.ScanRelay == nil {
+		return nil
+	}
+	if c.ScanRelay.MaxTimeout < 0 {
+		return fmt.Errorf("scan_relay.max_timeout must not be negative")
+	}
+	for _, scannerID := range c.ScanRelay.Scanners {
+		if _, exists := c.Scanners[scannerID]; !exists {
+			return fmt.Errorf("scan_relay.scanners references unknown scanner '%s'", scannerID)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateAlerts() error {
+	if c.Alerts == nil {
+		return nil
+	}
+
+	for _, state := range c.Alerts.States {
+		if !slices.Contains(AlertHealthStates, state) {
+			return fmt.Errorf("alerts.states '%s' must be one of: %s", state, strings.Join(AlertHealthStates, ", "))
+		}
+	}
+	if c.Alerts.Webhook == nil && c.Alerts.MQTTTopic == "" && len(c.Alerts.Exec) == 0 {
+		return fmt.Errorf("alerts requires at least one of webhook, mqtt_topic or exec")
+	}
+	if c.Alerts.Webhook != nil {
+		parsed, err := url.Parse(c.Alerts.Webhook.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("alerts.webhook.url '%s' must be an http:// or https:// URL", c.Alerts.Webhook.URL)
+		}
+	}
+	if strings.ContainsAny(c.Alerts.MQTTTopic, "+#") {
+		return fmt.Errorf("alerts.mqtt_topic '%s' must not contain wildcards", c.Alerts.MQTTTopic)
+	}
+	if len(c.Alerts.Exec) > 0 && c.Alerts.Exec[0] == "" {
+		return fmt.Errorf("alerts.exec must start with the command to run")
+	}
+	if c.Alerts.Timeout < 0 {
+		return fmt.Errorf("alerts.timeout must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) validateAssist() error {
+	if c.Assist == nil {
+		return nil
+	}
+
+	parsed, err := url.Parse(c.Assist.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("assist.url '%s' must be an http:// or https:// URL", c.Assist.URL)
+	}
+	if c.Assist.Token == "" {
+		return fmt.Errorf("assist.token is required")
+	}
+	if c.Assist.Prefix == "" {
+		return fmt.Errorf("assist.prefix is required so regular barcodes are not sent to Assist")
+	}
+	for _, scannerID := range c.Assist.Scanners {
+		if _, exists := c.Scanners[scannerID]; !exists {
+			return fmt.Errorf("assist.scanners references unknown scanner '%s'", scannerID)
+		}
+	}
+	return nil
+}
+
 func (c *Config) validateLogging() error {
 	validLogLevels := []string{"debug", "info", "warn", "warning", "error", "fatal", "panic"}
 	logLevel := strings.ToLower(c.Logging.Level)
@@ -254,6 +1631,15 @@ func (c *Config) validateLogging() error {
 			c.Logging.Level, strings.Join(validLogLevels, ", "))
 	}
 
+	validStreamLevels := []string{"warn", "warning", "error"}
+	if !slices.Contains(validStreamLevels, strings.ToLower(c.Logging.MQTT.Level)) {
+		return fmt.Errorf("logging.mqtt.level '%s' must be one of: %s",
+			c.Logging.MQTT.Level, strings.Join(validStreamLevels, ", "))
+	}
+	if c.Logging.MQTT.RateLimit < 0 {
+		return fmt.Errorf("logging.mqtt.rate_limit must not be negative")
+	}
+
 	validLogFormats := []string{"text", "json"}
 	logFormat := strings.ToLower(c.Logging.Format)
 	if !slices.Contains(validLogFormats, logFormat) {
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -142,6 +144,170 @@ func TestValidateScannerIdentification(t *testing.T) {
 	}
 }
 
+func TestValidateDock(t *testing.T) {
+	dockInterface := 2
+
+	tests := []struct {
+		name        string
+		dock        *DockConfig
+		expectError bool
+	}{
+		{"No dock", nil, false},
+		{"Charging detection", &DockConfig{Detection: "charging"}, false},
+		{"Interface detection", &DockConfig{Detection: "interface", Interface: &dockInterface}, false},
+		{"Interface detection without interface", &DockConfig{Detection: "interface"}, true},
+		{"Invalid detection", &DockConfig{Detection: "magnet"}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &ScannerConfig{Dock: tt.dock}
+			err := config.validateDock("test", scanner)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateDriver(t *testing.T) {
+	hidIdentification := ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7}
+	negativeInterface := -1
+	gpioLine := 17
+
+	tests := []struct {
+		name        string
+		scanner     ScannerConfig
+		expectError bool
+	}{
+		{"Default driver", ScannerConfig{Identification: hidIdentification}, false},
+		{"HID driver", ScannerConfig{Driver: "hid", Identification: hidIdentification}, false},
+		{"HID driver without identification", ScannerConfig{Driver: "hid"}, true},
+		{
+			"HID negative interface",
+			ScannerConfig{Identification: ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7, Interface: &negativeInterface}},
+			true,
+		},
+		{"HID hidraw backend", ScannerConfig{Identification: hidIdentification, HID: HIDConfig{Backend: "hidraw"}}, false},
+		{"HID unknown backend", ScannerConfig{Identification: hidIdentification, HID: HIDConfig{Backend: "winusb"}}, true},
+		{"HID held modifiers", ScannerConfig{Identification: hidIdentification, HID: HIDConfig{ModifierMode: "held"}}, false},
+		{"HID unknown modifier mode", ScannerConfig{Identification: hidIdentification, HID: HIDConfig{ModifierMode: "sticky"}}, true},
+		{"Evdev with path", ScannerConfig{Driver: "evdev", Evdev: EvdevConfig{Path: "/dev/input/event3"}}, false},
+		{"Evdev with identification", ScannerConfig{Driver: "evdev", Identification: hidIdentification}, false},
+		{"Evdev without path or identification", ScannerConfig{Driver: "evdev"}, true},
+		{"Serial with port", ScannerConfig{Driver: "serial", Serial: SerialConfig{Port: "/dev/ttyACM0"}}, false},
+		{"Serial without port", ScannerConfig{Driver: "serial", Identification: hidIdentification}, true},
+		{
+			"Serial with terminator",
+			ScannerConfig{Driver: "serial", Serial: SerialConfig{Port: "/dev/ttyACM0", LineTerminator: "crlf"}},
+			false,
+		},
+		{
+			"Serial with invalid terminator",
+			ScannerConfig{Driver: "serial", Serial: SerialConfig{Port: "/dev/ttyACM0", LineTerminator: "etx"}},
+			true,
+		},
+		{"Bluetooth", ScannerConfig{Driver: "bluetooth", Bluetooth: BluetoothConfig{Address: "aa:bb:cc:dd:ee:ff"}}, false},
+		{"Bluetooth without address", ScannerConfig{Driver: "bluetooth", Identification: hidIdentification}, true},
+		{"Bluetooth invalid address", ScannerConfig{Driver: "bluetooth", Bluetooth: BluetoothConfig{Address: "aa:bb:cc"}}, true},
+		{"TCP", ScannerConfig{Driver: "tcp", TCP: TCPConfig{Host: "10.0.0.5", Port: 2001}}, false},
+		{"TCP without host", ScannerConfig{Driver: "tcp", TCP: TCPConfig{Port: 2001}}, true},
+		{"TCP invalid port", ScannerConfig{Driver: "tcp", TCP: TCPConfig{Host: "10.0.0.5", Port: 70000}}, true},
+		{"GPIO", ScannerConfig{Driver: "gpio", GPIO: GPIOConfig{Line: &gpioLine, Bias: "pull_up", Events: "both"}}, false},
+		{"GPIO without line", ScannerConfig{Driver: "gpio"}, true},
+		{"GPIO negative line", ScannerConfig{Driver: "gpio", GPIO: GPIOConfig{Line: &negativeInterface}}, true},
+		{"GPIO invalid bias", ScannerConfig{Driver: "gpio", GPIO: GPIOConfig{Line: &gpioLine, Bias: "pull_sideways"}}, true},
+		{"GPIO invalid events", ScannerConfig{Driver: "gpio", GPIO: GPIOConfig{Line: &gpioLine, Events: "hold"}}, true},
+		{"Keyhook", ScannerConfig{Driver: "keyhook", Keyhook: KeyhookConfig{WindowTitle: "^Inventory", MinKeys: 6}}, false},
+		{"Keyhook invalid window title", ScannerConfig{Driver: "keyhook", Keyhook: KeyhookConfig{WindowTitle: "(["}}, true},
+		{"Keyhook negative interval", ScannerConfig{Driver: "keyhook", Keyhook: KeyhookConfig{MaxKeyInterval: -time.Millisecond}}, true},
+		{"Stdin", ScannerConfig{Driver: "stdin"}, false},
+		{"Unknown driver", ScannerConfig{Driver: "infrared", Identification: hidIdentification}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validateDriver("test", &tt.scanner)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateOperator(t *testing.T) {
+	tests := []struct {
+		name        string
+		operator    *OperatorConfig
+		expectError bool
+	}{
+		{"No operator mode", nil, false},
+		{"Badge pattern", &OperatorConfig{BadgePattern: "^BADGE-(.+)$"}, false},
+		{"Badge pattern with timeout", &OperatorConfig{BadgePattern: "^EMP\\d+$", Timeout: 8 * time.Hour}, false},
+		{"Missing badge pattern", &OperatorConfig{}, true},
+		{"Invalid badge pattern", &OperatorConfig{BadgePattern: "BADGE-(["}, true},
+		{"Negative timeout", &OperatorConfig{BadgePattern: "^BADGE-", Timeout: -time.Second}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &ScannerConfig{Operator: tt.operator}
+			err := config.validateOperator("test", scanner)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	tests := []struct {
+		name        string
+		attributes  AttributesConfig
+		expectError bool
+	}{
+		{"Defaults", AttributesConfig{}, false},
+		{"Known include", AttributesConfig{Include: []string{"scanner_id"}}, false},
+		{"Unknown include", AttributesConfig{Include: []string{"password"}}, true},
+		{"Custom metadata", AttributesConfig{Custom: map[string]string{"department": "Receiving"}}, false},
+		{"Empty custom key", AttributesConfig{Custom: map[string]string{"": "value"}}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &ScannerConfig{Attributes: tt.attributes}
+			err := config.validateAttributes("test", scanner)
+
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
 func TestMQTTConfig_IsSecure(t *testing.T) {
 	tests := []struct {
 		brokerURL string
@@ -175,6 +341,149 @@ func TestValidateMQTT_MissingBrokerURL(t *testing.T) {
 	}
 }
 
+func TestValidateMQTT_Protocol(t *testing.T) {
+	tests := []struct {
+		protocol    string
+		expectError bool
+	}{
+		{"", false},
+		{MQTTProtocol311, false},
+		{MQTTProtocol5, false},
+		{"3.1", true},
+		{"v5", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			config := &Config{
+				MQTT: MQTTConfig{BrokerURL: "mqtt://localhost:1883", KeepAlive: 60, Protocol: tt.protocol},
+			}
+
+			err := config.validateMQTT()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for protocol %q", tt.protocol)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for protocol %q, got: %v", tt.protocol, err)
+			}
+		})
+	}
+}
+
+func TestValidateMQTT_BackupBrokers(t *testing.T) {
+	tests := []struct {
+		name         string
+		backups      []string
+		publishToAll bool
+		expectError  bool
+	}{
+		{"No backups", nil, false, false},
+		{"Backup", []string{"mqtts://backup.local:8883"}, false, false},
+		{"Publish to all", []string{"mqtt://backup.local:1883"}, true, false},
+		{"Invalid scheme", []string{"http://backup.local:1883"}, false, true},
+		{"Publish to all without backups", nil, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				MQTT: MQTTConfig{
+					BrokerURL:        "mqtt://localhost:1883",
+					BackupBrokerURLs: tt.backups,
+					PublishToAll:     tt.publishToAll,
+					KeepAlive:        60,
+				},
+			}
+
+			err := config.validateMQTT()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateMQTT_CA(t *testing.T) {
+	tests := []struct {
+		name        string
+		brokerURL   string
+		caFile      string
+		caCert      string
+		expectError bool
+	}{
+		{"No CA", "mqtts://localhost:8883", "", "", false},
+		{"CA file", "mqtts://localhost:8883", "/etc/ssl/broker-ca.pem", "", false},
+		{"Inline CA", "wss://localhost:8884", "", "-----BEGIN CERTIFICATE-----", false},
+		{"Both", "mqtts://localhost:8883", "/etc/ssl/broker-ca.pem", "-----BEGIN CERTIFICATE-----", true},
+		{"Plain connection", "mqtt://localhost:1883", "/etc/ssl/broker-ca.pem", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				MQTT: MQTTConfig{BrokerURL: tt.brokerURL, KeepAlive: 60, CAFile: tt.caFile, CACert: tt.caCert},
+			}
+
+			err := config.validateMQTT()
+			if tt.expectError && err == nil {
+				t.Error("Expected error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveMQTTCredentials(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "mqtt_password")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	t.Setenv("TEST_MQTT_USERNAME", "scanner")
+
+	tests := []struct {
+		name             string
+		mqtt             MQTTConfig
+		expectedUsername string
+		expectedPassword string
+		expectError      bool
+	}{
+		{"Inline", MQTTConfig{Username: "user", Password: "pass"}, "user", "pass", false},
+		{"Env and file", MQTTConfig{UsernameEnv: "TEST_MQTT_USERNAME", PasswordFile: secretFile}, "scanner", "s3cret", false},
+		{"Unset env", MQTTConfig{UsernameEnv: "TEST_MQTT_UNSET"}, "", "", true},
+		{"Missing file", MQTTConfig{PasswordFile: secretFile + ".missing"}, "", "", true},
+		{"Inline and file", MQTTConfig{Password: "pass", PasswordFile: secretFile}, "", "", true},
+		{"File and env", MQTTConfig{UsernameFile: secretFile, UsernameEnv: "TEST_MQTT_USERNAME"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{MQTT: tt.mqtt}
+			config.MQTT.BrokerURL = "mqtt://localhost:1883"
+			config.MQTT.KeepAlive = 60
+
+			err := config.validateMQTT()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if config.MQTT.Username != tt.expectedUsername || config.MQTT.Password != tt.expectedPassword {
+				t.Errorf("Expected credentials %q/%q, got %q/%q",
+					tt.expectedUsername, tt.expectedPassword, config.MQTT.Username, config.MQTT.Password)
+			}
+		})
+	}
+}
+
 func TestValidateHomeAssistant_MissingDiscoveryPrefix(t *testing.T) {
 	config := &Config{
 		HomeAssistant: HomeAssistantConfig{},
@@ -186,15 +495,1050 @@ func TestValidateHomeAssistant_MissingDiscoveryPrefix(t *testing.T) {
 	}
 }
 
-func createTempConfig(t *testing.T, content string) string {
-	t.Helper()
+func TestValidateHomeAssistant_AvailabilityMode(t *testing.T) {
+	tests := []struct {
+		mode        string
+		expectError bool
+	}{
+		{"", false},
+		{AvailabilityModeAll, false},
+		{AvailabilityModeAny, false},
+		{AvailabilityModeLatest, false},
+		{AvailabilityModeScanner, false},
+		{"sometimes", true},
+	}
 
-	tempDir := t.TempDir()
-	tempFile := filepath.Join(tempDir, "config.yaml")
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			config := &Config{
+				HomeAssistant: HomeAssistantConfig{
+					DiscoveryPrefix:  "homeassistant",
+					InstanceID:       "test",
+					AvailabilityMode: tt.mode,
+				},
+			}
 
-	if err := os.WriteFile(tempFile, []byte(content), 0600); err != nil {
-		t.Fatalf("Failed to create temp config file: %v", err)
+			err := config.validateHomeAssistant()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for availability mode '%s'", tt.mode)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for availability mode '%s', got: %v", tt.mode, err)
+			}
+		})
 	}
+}
 
-	return tempFile
+func TestValidateHomeAssistant_Language(t *testing.T) {
+	tests := []struct {
+		language    string
+		expectError bool
+	}{
+		{"", false},
+		{"en", false},
+		{"es", false},
+		{"klingon", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			config := &Config{
+				HomeAssistant: HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", Language: tt.language},
+			}
+
+			err := config.validateHomeAssistant()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for language %q", tt.language)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for language %q, got: %v", tt.language, err)
+			}
+		})
+	}
+}
+
+func TestValidateHomeAssistant_Topics(t *testing.T) {
+	tests := []struct {
+		name        string
+		topics      TopicsConfig
+		expectError bool
+	}{
+		{"defaults", TopicsConfig{Discovery: DefaultDiscoveryTopicTemplate, State: DefaultStateTopicTemplate}, false},
+		{"unset", TopicsConfig{}, false},
+		{"node id", TopicsConfig{Discovery: "{{ .Prefix }}/{{ .Component }}/{{ .BridgeID }}/{{ .ScannerID }}{{ .Entity }}"}, false},
+		{"custom state", TopicsConfig{State: "barcode/{{ .ObjectID }}"}, false},
+		{"syntax error", TopicsConfig{State: "barcode/{{ .ObjectID"}, true},
+		{"unknown field", TopicsConfig{State: "barcode/{{ .Scanner }}"}, true},
+		{"discovery outside prefix", TopicsConfig{Discovery: "barcode/{{ .Component }}/{{ .ObjectID }}"}, true},
+		{"discovery too deep", TopicsConfig{Discovery: "{{ .Prefix }}/{{ .Component }}/a/b/{{ .ObjectID }}"}, true},
+		{"discovery invalid characters", TopicsConfig{Discovery: "{{ .Prefix }}/{{ .Component }}/{{ .ObjectID }}.x"}, true},
+		{"state not unique", TopicsConfig{State: "barcode/{{ .ScannerID }}"}, true},
+		{"state empty level", TopicsConfig{State: "barcode/{{ .BridgeID }}/{{ .ScannerID }}/{{ .Component }}/{{ .Entity }}"}, true},
+		{"state wildcard", TopicsConfig{State: "barcode/+/{{ .ObjectID }}"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				HomeAssistant: HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", Topics: tt.topics},
+			}
+
+			err := config.validateHomeAssistant()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for topics %+v", tt.topics)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error for topics %+v, got: %v", tt.topics, err)
+			}
+		})
+	}
+}
+
+func TestValidateHomeAssistant_DisconnectDebounce(t *testing.T) {
+	config := &Config{
+		HomeAssistant: HomeAssistantConfig{
+			DiscoveryPrefix:    "homeassistant",
+			InstanceID:         "test",
+			DisconnectDebounce: 5 * time.Second,
+		},
+	}
+	if err := config.validateHomeAssistant(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.HomeAssistant.DisconnectDebounce = -time.Second
+	if err := config.validateHomeAssistant(); err == nil {
+		t.Error("Expected error for negative disconnect debounce")
+	}
+}
+
+func TestValidateHomeAssistant_CoalesceInterval(t *testing.T) {
+	config := &Config{
+		HomeAssistant: HomeAssistantConfig{
+			DiscoveryPrefix:  "homeassistant",
+			InstanceID:       "test",
+			CoalesceInterval: 2 * time.Second,
+		},
+	}
+	if err := config.validateHomeAssistant(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+
+	config.HomeAssistant.CoalesceInterval = -time.Second
+	if err := config.validateHomeAssistant(); err == nil {
+		t.Error("Expected error for negative coalesce interval")
+	}
+
+	config.HomeAssistant.CoalesceInterval = 0
+	config.HomeAssistant.DuplicateWindow = -time.Minute
+	if err := config.validateHomeAssistant(); err == nil {
+		t.Error("Expected error for negative duplicate window")
+	}
+}
+
+func TestValidateHomeAssistant_ProcessStatsInterval(t *testing.T) {
+	tests := []struct {
+		interval    time.Duration
+		expectError bool
+	}{
+		{0, false},
+		{time.Minute, false},
+		{MinProcessStatsInterval, false},
+		{time.Second, true},
+		{-time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval.String(), func(t *testing.T) {
+			config := &Config{
+				HomeAssistant: HomeAssistantConfig{
+					DiscoveryPrefix:      "homeassistant",
+					InstanceID:           "test",
+					ProcessStatsInterval: tt.interval,
+				},
+			}
+			err := config.validateHomeAssistant()
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error for process_stats_interval %s", tt.interval)
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateSinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		webhook     WebhookConfig
+		expectError bool
+	}{
+		{"Valid webhook", WebhookConfig{Name: "erp", URL: "https://erp.local/scans"}, false},
+		{"Scanner filter", WebhookConfig{Name: "erp", URL: "http://erp.local", Scanners: []string{"test_scanner"}}, false},
+		{"Unknown scanner", WebhookConfig{Name: "erp", URL: "http://erp.local", Scanners: []string{"missing"}}, true},
+		{"Invalid scheme", WebhookConfig{Name: "erp", URL: "ftp://erp.local"}, true},
+		{"Missing URL", WebhookConfig{Name: "erp"}, true},
+		{"Negative retries", WebhookConfig{Name: "erp", URL: "http://erp.local", MaxRetries: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Scanners: map[string]ScannerConfig{"test_scanner": {}},
+				Sinks:    SinksConfig{Webhooks: []WebhookConfig{tt.webhook}},
+			}
+
+			err := config.validateSinks()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePantry(t *testing.T) {
+	tests := []struct {
+		name        string
+		pantry      *PantryConfig
+		expectError bool
+	}{
+		{"Disabled", nil, false},
+		{"Default action", &PantryConfig{}, false},
+		{"Consume", &PantryConfig{DefaultAction: "consume", QuantityPrefix: "QTY:"}, false},
+		{"Invalid action", &PantryConfig{DefaultAction: "open"}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validatePantry("test", &ScannerConfig{Pantry: tt.pantry})
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateHTTP(t *testing.T) {
+	metrics := []string{HTTPServeMetrics}
+
+	tests := []struct {
+		name        string
+		listeners   []HTTPListenerConfig
+		expectError bool
+	}{
+		{"No listeners", nil, false},
+		{"Metrics listener", []HTTPListenerConfig{{Name: "lan", Address: ":9100", Serve: metrics}}, false},
+		{
+			"Separate metrics and API listeners",
+			[]HTTPListenerConfig{
+				{Name: "lan", Address: ":9100", Serve: metrics},
+				{Name: "api", Address: "127.0.0.1:8080", Serve: []string{HTTPServeAPI}, Auth: HTTPAuthConfig{Token: "secret"}},
+			},
+			false,
+		},
+		{"Missing port", []HTTPListenerConfig{{Name: "lan", Address: "localhost", Serve: metrics}}, true},
+		{"Nothing served", []HTTPListenerConfig{{Name: "lan", Address: ":9100"}}, true},
+		{"Unknown group", []HTTPListenerConfig{{Name: "lan", Address: ":9100", Serve: []string{"admin"}}}, true},
+		{"Health listener", []HTTPListenerConfig{{Name: "probes", Address: ":8081", Serve: []string{HTTPServeHealth}}}, false},
+		{
+			"Duplicate address",
+			[]HTTPListenerConfig{{Name: "a", Address: ":9100", Serve: metrics}, {Name: "b", Address: ":9100", Serve: metrics}},
+			true,
+		},
+		{
+			"Duplicate name",
+			[]HTTPListenerConfig{{Name: "a", Address: ":9100", Serve: metrics}, {Name: "a", Address: ":9101", Serve: metrics}},
+			true,
+		},
+		{
+			"Username without password",
+			[]HTTPListenerConfig{{Name: "a", Address: ":9100", Serve: metrics, Auth: HTTPAuthConfig{Username: "admin"}}},
+			true,
+		},
+		{
+			"Certificate without key",
+			[]HTTPListenerConfig{{Name: "a", Address: ":9100", Serve: metrics, TLS: HTTPTLSConfig{CertFile: "cert.pem"}}},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{HTTP: HTTPConfig{Listeners: tt.listeners}}
+
+			err := config.validateHTTP()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateScannerIDTemplate(t *testing.T) {
+	tests := []struct {
+		template    string
+		expectError bool
+	}{
+		{"", false},
+		{"{instance}-lane-{serial}", false},
+		{"{vendor_id}_{product_id}_{interface}_{name}", false},
+		{"{site}-{serial}", true},
+		{"{Serial}", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateScannerIDTemplate(tt.template)
+		if tt.expectError && err == nil {
+			t.Errorf("Expected error for template %q", tt.template)
+		}
+		if !tt.expectError && err != nil {
+			t.Errorf("Expected no error for template %q, got: %v", tt.template, err)
+		}
+	}
+}
+
+func TestHTTPHealthDefaults(t *testing.T) {
+	config := &Config{}
+	config.setHTTPDefaults()
+	if config.HTTP.Health.ScannerDownThreshold != DefaultScannerDownThreshold {
+		t.Errorf("Expected default scanner down threshold %v, got %v", DefaultScannerDownThreshold, config.HTTP.Health.ScannerDownThreshold)
+	}
+
+	config.HTTP.Health.ScannerDownThreshold = -time.Minute
+	if err := config.validateHTTP(); err == nil {
+		t.Error("Expected error for negative scanner down threshold")
+	}
+}
+
+func TestValidateHTTPStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		expectError bool
+	}{
+		{"Same origin only", nil, false},
+		{"Any origin", []string{"*"}, false},
+		{"Listed origins", []string{"http://kiosk.local:3000", "https://dashboard.example.com/"}, false},
+		{"Missing scheme", []string{"kiosk.local:3000"}, true},
+		{"Path", []string{"http://kiosk.local/app"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{HTTP: HTTPConfig{
+				Listeners: []HTTPListenerConfig{{Name: "kiosk", Address: ":8082", Serve: []string{HTTPServeStream}}},
+				Stream:    HTTPStreamConfig{AllowedOrigins: tt.origins},
+			}}
+			err := config.validateHTTP()
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no validation error but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		instanceID  string
+		failover    FailoverConfig
+		expectError bool
+	}{
+		{"Defaults", "kitchen", FailoverConfig{NodeID: "pi-a"}, false},
+		{"Missing instance ID", "", FailoverConfig{NodeID: "pi-a"}, true},
+		{"Timeout too short", "kitchen", FailoverConfig{NodeID: "pi-a", HeartbeatInterval: 5 * time.Second}, true},
+		{"Negative interval", "kitchen", FailoverConfig{NodeID: "pi-a", HeartbeatInterval: -time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failover := tt.failover
+			config := &Config{
+				MQTT:          MQTTConfig{ClientID: "ha-barcode-bridge"},
+				HomeAssistant: HomeAssistantConfig{InstanceID: tt.instanceID},
+				Failover:      &failover,
+			}
+			config.setFailoverDefaults()
+
+			err := config.validateFailover()
+			if tt.expectError && err == nil {
+				t.Error("Expected validation error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no validation error but got: %v", err)
+			}
+			if err == nil && config.MQTT.ClientID != "ha-barcode-bridge-pi-a" {
+				t.Errorf("Expected client ID with node suffix, got %s", config.MQTT.ClientID)
+			}
+		})
+	}
+}
+
+func TestValidateScanRelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		relay       *ScanRelayConfig
+		expectError bool
+	}{
+		{"Disabled", nil, false},
+		{"Valid", &ScanRelayConfig{Token: "token", MaxTimeout: time.Minute}, false},
+		{"Scanner filter", &ScanRelayConfig{Scanners: []string{"test_scanner"}}, false},
+		{"Unknown scanner", &ScanRelayConfig{Scanners: []string{"missing"}}, true},
+		{"Negative max timeout", &ScanRelayConfig{MaxTimeout: -time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Scanners:  map[string]ScannerConfig{"test_scanner": {}},
+				ScanRelay: tt.relay,
+			}
+
+			err := config.validateScanRelay()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAssist(t *testing.T) {
+	tests := []struct {
+		name        string
+		assist      *AssistConfig
+		expectError bool
+	}{
+		{"Disabled", nil, false},
+		{"Valid", &AssistConfig{URL: "http://homeassistant.local:8123", Token: "token", Prefix: "ASSIST:"}, false},
+		{
+			"Scanner filter",
+			&AssistConfig{URL: "http://ha.local:8123", Token: "token", Prefix: "ASSIST:", Scanners: []string{"test_scanner"}},
+			false,
+		},
+		{
+			"Unknown scanner",
+			&AssistConfig{URL: "http://ha.local:8123", Token: "token", Prefix: "ASSIST:", Scanners: []string{"missing"}},
+			true,
+		},
+		{"Invalid URL", &AssistConfig{URL: "homeassistant.local", Token: "token", Prefix: "ASSIST:"}, true},
+		{"Missing token", &AssistConfig{URL: "http://ha.local:8123", Prefix: "ASSIST:"}, true},
+		{"Missing prefix", &AssistConfig{URL: "http://ha.local:8123", Token: "token"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				Scanners: map[string]ScannerConfig{"test_scanner": {}},
+				Assist:   tt.assist,
+			}
+
+			err := config.validateAssist()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateOfflineQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		queue       *OfflineQueueConfig
+		expectError bool
+	}{
+		{"Disabled", nil, false},
+		{"Valid", &OfflineQueueConfig{Path: "/var/lib/barcode/queue.jsonl", MaxEntries: 100}, false},
+		{"Default max entries", &OfflineQueueConfig{Path: "/var/lib/barcode/queue.jsonl"}, false},
+		{"Missing path", &OfflineQueueConfig{MaxEntries: 100}, true},
+		{"Negative max entries", &OfflineQueueConfig{Path: "/var/lib/barcode/queue.jsonl", MaxEntries: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{OfflineQueue: tt.queue}
+
+			err := config.validateOfflineQueue()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateAlerts(t *testing.T) {
+	tests := []struct {
+		name        string
+		alerts      *AlertsConfig
+		expectError bool
+	}{
+		{"Disabled", nil, false},
+		{"Webhook", &AlertsConfig{Webhook: &AlertWebhookConfig{URL: "https://example.com/alert"}}, false},
+		{"MQTT topic", &AlertsConfig{States: []string{"stale"}, MQTTTopic: "barcode/alerts"}, false},
+		{"Exec", &AlertsConfig{Exec: []string{"/usr/local/bin/notify", "--urgent"}}, false},
+		{"No hook", &AlertsConfig{States: []string{"stale"}}, true},
+		{"Unknown state", &AlertsConfig{States: []string{"broken"}, MQTTTopic: "barcode/alerts"}, true},
+		{"Invalid webhook URL", &AlertsConfig{Webhook: &AlertWebhookConfig{URL: "example.com"}}, true},
+		{"Wildcard topic", &AlertsConfig{MQTTTopic: "barcode/#"}, true},
+		{"Empty command", &AlertsConfig{Exec: []string{""}}, true},
+		{"Negative timeout", &AlertsConfig{MQTTTopic: "barcode/alerts", Timeout: -time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Alerts: tt.alerts}
+
+			err := config.validateAlerts()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetDefaults_Alerts(t *testing.T) {
+	config := &Config{Alerts: &AlertsConfig{MQTTTopic: "barcode/alerts"}}
+	config.setDefaults()
+
+	if !slices.Equal(config.Alerts.States, DefaultAlertStates) {
+		t.Errorf("Expected default states %v, got %v", DefaultAlertStates, config.Alerts.States)
+	}
+	if config.Alerts.Timeout != DefaultAlertTimeout {
+		t.Errorf("Expected default timeout %v, got %v", DefaultAlertTimeout, config.Alerts.Timeout)
+	}
+}
+
+func TestSetDefaults_DuplicateWindow(t *testing.T) {
+	config := &Config{}
+	config.setDefaults()
+	if config.HomeAssistant.DuplicateWindow != DefaultDuplicateWindow {
+		t.Errorf("Expected default duplicate window %v, got %v", DefaultDuplicateWindow, config.HomeAssistant.DuplicateWindow)
+	}
+
+	config = &Config{HomeAssistant: HomeAssistantConfig{DuplicateWindow: time.Hour}}
+	config.setDefaults()
+	if config.HomeAssistant.DuplicateWindow != time.Hour {
+		t.Errorf("Expected duplicate window to be kept, got %v", config.HomeAssistant.DuplicateWindow)
+	}
+}
+
+func createTempConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	tempFile := filepath.Join(tempDir, "config.yaml")
+
+	if err := os.WriteFile(tempFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to create temp config file: %v", err)
+	}
+
+	return tempFile
+}
+
+func TestValidateScanners_AutoDiscover(t *testing.T) {
+	config := &Config{Scanners: map[string]ScannerConfig{}}
+	if err := config.validateScanners(); err == nil {
+		t.Error("Expected error for no scanners without auto_discover")
+	}
+
+	config.AutoDiscover = true
+	if err := config.validateScanners(); err != nil {
+		t.Errorf("Expected no error with auto_discover enabled, got %v", err)
+	}
+}
+
+func TestValidateGrocy(t *testing.T) {
+	tests := []struct {
+		name        string
+		grocy       GrocyConfig
+		expectError bool
+	}{
+		{"valid", GrocyConfig{URL: "http://grocy.local", APIKey: "key", DefaultMode: GrocyModePurchase}, false},
+		{"scanner mode", GrocyConfig{
+			URL: "http://grocy.local", APIKey: "key", DefaultMode: GrocyModePurchase,
+			Modes: map[string]string{"test": GrocyModeConsume},
+		}, false},
+		{"missing api key", GrocyConfig{URL: "http://grocy.local", DefaultMode: GrocyModePurchase}, true},
+		{"invalid url", GrocyConfig{URL: "grocy.local", APIKey: "key", DefaultMode: GrocyModePurchase}, true},
+		{"invalid mode", GrocyConfig{URL: "http://grocy.local", APIKey: "key", DefaultMode: "open"}, true},
+		{"unknown scanner", GrocyConfig{
+			URL: "http://grocy.local", APIKey: "key", DefaultMode: GrocyModePurchase,
+			Modes: map[string]string{"other": GrocyModeConsume},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		config := &Config{Scanners: map[string]ScannerConfig{"test": {ID: "test"}}}
+		err := config.validateGrocy(0, &tt.grocy)
+		if tt.expectError && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+		if !tt.expectError && err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestScannerIdentification_Wildcard(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		anyProduct  bool
+		expectError bool
+	}{
+		{"Exact IDs", "vendor_id: 0x60e\nproduct_id: 0x16c7", false, false},
+		{"Product wildcard", "vendor_id: 0x60e\nproduct_id: \"*\"", true, false},
+		{"Product name only", "product_name: \"^Voyager\"", false, false},
+		{"Vendor without product", "vendor_id: 0x60e", false, true},
+		{"Wildcard without vendor", "product_id: \"*\"", true, true},
+		{"Invalid product name", "vendor_id: 0x60e\nproduct_name: \"[\"", false, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &ScannerConfig{}
+			if err := yaml.Unmarshal([]byte(tt.yaml), &scanner.Identification); err != nil {
+				t.Fatalf("Expected identification to parse, got: %v", err)
+			}
+			if scanner.Identification.AnyProduct != tt.anyProduct {
+				t.Errorf("Expected AnyProduct %v, got %v", tt.anyProduct, scanner.Identification.AnyProduct)
+			}
+
+			err := config.validateScannerIdentification("test", scanner)
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestScannerIdentification_MatchesIDs(t *testing.T) {
+	identification := ScannerIdentification{VendorID: 0x60e, AnyProduct: true}
+	if !identification.MatchesIDs(0x60e, 0x1234) {
+		t.Error("Expected vendor wildcard to match any product")
+	}
+	if identification.MatchesIDs(0x5e0, 0x1234) {
+		t.Error("Expected vendor wildcard to reject other vendors")
+	}
+}
+
+func TestValidateHomeAssistant_IDTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		objectID    string
+		uniqueID    string
+		expectError bool
+	}{
+		{"defaults", DefaultObjectIDTemplate, DefaultUniqueIDTemplate, false},
+		{"stable templates", "barcode_{scanner}", "barcode-{scanner}", false},
+		{"object template without scanner", "barcode", DefaultUniqueIDTemplate, true},
+		{"unique template without scanner", DefaultObjectIDTemplate, "{instance}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{HomeAssistant: HomeAssistantConfig{
+				DiscoveryPrefix:  "homeassistant",
+				InstanceID:       "test",
+				ObjectIDTemplate: tt.objectID,
+				UniqueIDTemplate: tt.uniqueID,
+			}}
+
+			err := config.validateHomeAssistant()
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateScanners_DuplicateUniqueID(t *testing.T) {
+	scanner := func(id string) ScannerConfig {
+		return ScannerConfig{
+			ID:              id,
+			Identification:  ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7},
+			TerminationChar: "enter",
+			UniqueID:        "front-desk",
+		}
+	}
+
+	config := &Config{Scanners: map[string]ScannerConfig{"one": scanner("one"), "two": scanner("two")}}
+	if err := config.validateScanners(); err == nil {
+		t.Error("Expected error for duplicate unique_id")
+	}
+}
+
+func TestValidateScannerIdentification_Fallbacks(t *testing.T) {
+	config := &Config{}
+	scanner := &ScannerConfig{
+		Identification: ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7},
+		FallbackIdentifications: []ScannerIdentification{
+			{VendorID: 0x60e, ProductID: 0x16c8},
+		},
+	}
+	if err := config.validateScannerIdentification("test", scanner); err != nil {
+		t.Errorf("Expected no error, but got: %v", err)
+	}
+
+	scanner.FallbackIdentifications = append(scanner.FallbackIdentifications, ScannerIdentification{VendorID: 0x60e})
+	if err := config.validateScannerIdentification("test", scanner); err == nil {
+		t.Error("Expected error for fallback without product_id")
+	}
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	configContent := `
+profile: warehouse
+auto_discover: false
+homeassistant:
+  disconnect_debounce: 2s
+scanners:
+  dock_scanner:
+    termination_char: enter
+    identification:
+      vendor_id: 0x60e
+      product_id: 0x16c7
+  plain_scanner:
+    termination_char: enter
+    state_format: plain
+    identification:
+      vendor_id: 0x60e
+      product_id: 0x16c8
+`
+
+	config, err := LoadConfig(createTempConfig(t, configContent))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !config.HomeAssistant.SymbologySensor {
+		t.Error("Expected profile to enable the symbology sensor")
+	}
+	if config.AutoDiscover {
+		t.Error("Expected auto_discover set in the file to override the profile")
+	}
+	if config.HomeAssistant.DisconnectDebounce != 2*time.Second {
+		t.Errorf("Expected disconnect_debounce 2s from the file, got %v", config.HomeAssistant.DisconnectDebounce)
+	}
+	if config.HomeAssistant.DiscoveryPrefix != "homeassistant" {
+		t.Errorf("Expected regular defaults to still apply, got discovery prefix %q", config.HomeAssistant.DiscoveryPrefix)
+	}
+	if got := config.Scanners["dock_scanner"].StateFormat; got != StateFormatJSON {
+		t.Errorf("Expected profile state format json, got %q", got)
+	}
+	if got := config.Scanners["plain_scanner"].StateFormat; got != StateFormatPlain {
+		t.Errorf("Expected scanner state format plain to override the profile, got %q", got)
+	}
+
+	if _, err := LoadConfig(createTempConfig(t, "profile: office\n")); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestValidatePipeline(t *testing.T) {
+	tests := []struct {
+		name        string
+		stage       PipelineStageConfig
+		expectError bool
+	}{
+		{"Validate", PipelineStageConfig{Type: StageValidate, Pattern: "^[0-9]+$", MinLength: 8, MaxLength: 14}, false},
+		{"Invalid pattern", PipelineStageConfig{Type: StageValidate, Pattern: "("}, true},
+		{"Inverted lengths", PipelineStageConfig{Type: StageValidate, MinLength: 10, MaxLength: 5}, true},
+		{"Flag invalid", PipelineStageConfig{Type: StageValidate, CheckDigit: true, OnInvalid: InvalidActionFlag}, false},
+		{"Unknown invalid action", PipelineStageConfig{Type: StageValidate, CheckDigit: true, OnInvalid: "warn"}, true},
+		{"Transform", PipelineStageConfig{Type: StageTransform, TrimPrefix: "]E0", Case: TransformCaseUpper}, false},
+		{"Invalid case", PipelineStageConfig{Type: StageTransform, Case: "title"}, true},
+		{"Transform replace", PipelineStageConfig{Type: StageTransform, ReplacePattern: "^0+", SubstringStart: 1, Template: "{{ .Value }}"}, false},
+		{"Invalid replace pattern", PipelineStageConfig{Type: StageTransform, ReplacePattern: "[a-"}, true},
+		{"Replacement without pattern", PipelineStageConfig{Type: StageTransform, ReplaceWith: "x"}, true},
+		{"Negative substring", PipelineStageConfig{Type: StageTransform, SubstringStart: -1}, true},
+		{"Invalid template", PipelineStageConfig{Type: StageTransform, Template: "{{ .Value"}, true},
+		{"Dedupe", PipelineStageConfig{Type: StageDedupe, Window: time.Second}, false},
+		{"Dedupe without window", PipelineStageConfig{Type: StageDedupe}, true},
+		{"Enrich", PipelineStageConfig{Type: StageEnrich, Attributes: map[string]string{"site": "a"}}, false},
+		{"Enrich without attributes", PipelineStageConfig{Type: StageEnrich}, true},
+		{"AIM", PipelineStageConfig{Type: StageAIM}, false},
+		{"Rate limit", PipelineStageConfig{Type: StageRateLimit, MaxPerSecond: 0.5, OnLimit: LimitActionQueue, QueueSize: 5}, false},
+		{"Rate limit without rate", PipelineStageConfig{Type: StageRateLimit}, true},
+		{"Invalid limit action", PipelineStageConfig{Type: StageRateLimit, MaxPerSecond: 2, OnLimit: "block"}, true},
+		{"Negative queue size", PipelineStageConfig{Type: StageRateLimit, MaxPerSecond: 2, QueueSize: -1}, true},
+		{"Parse", PipelineStageConfig{Type: StageParse}, false},
+		{"Open Food Facts", PipelineStageConfig{Type: StageProduct}, false},
+		{"Open Food Facts mirror", PipelineStageConfig{Type: StageProduct, URL: "http://off.lan:8080", Timeout: time.Second, CacheTTL: time.Hour}, false},
+		{"Invalid Open Food Facts URL", PipelineStageConfig{Type: StageProduct, URL: "off.lan"}, true},
+		{"Negative cache TTL", PipelineStageConfig{Type: StageProduct, CacheTTL: -time.Hour}, true},
+		{"Lookup", PipelineStageConfig{Type: StageLookup, File: "/config/products.CSV"}, false},
+		{"Lookup without file", PipelineStageConfig{Type: StageLookup}, true},
+		{"Lookup of unknown format", PipelineStageConfig{Type: StageLookup, File: "products.json"}, true},
+		{"Unknown type", PipelineStageConfig{Type: "publish"}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validatePipeline("test", &ScannerConfig{Pipeline: []PipelineStageConfig{tt.stage}})
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+
+	if err := config.validatePipeline("test", &ScannerConfig{ValueTemplate: `{{ printf "%013s" .Value }}`}); err != nil {
+		t.Errorf("Expected valid value_template, but got: %v", err)
+	}
+	if err := config.validatePipeline("test", &ScannerConfig{ValueTemplate: "{{ .Value"}); err == nil {
+		t.Error("Expected error for an invalid value_template")
+	}
+}
+
+func TestRateLimitQueueSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []PipelineStageConfig
+		expected int
+	}{
+		{"No stages", nil, 0},
+		{"Dropping", []PipelineStageConfig{{Type: StageRateLimit, MaxPerSecond: 2}}, 0},
+		{"Default queue size", []PipelineStageConfig{{Type: StageDedupe}, {Type: StageRateLimit, MaxPerSecond: 2, OnLimit: LimitActionQueue}}, DefaultRateLimitQueueSize},
+		{"Queue size", []PipelineStageConfig{{Type: StageRateLimit, MaxPerSecond: 2, OnLimit: LimitActionQueue, QueueSize: 3}}, 3},
+	}
+	for _, tt := range tests {
+		scanner := &ScannerConfig{Pipeline: tt.pipeline}
+		if size := scanner.RateLimitQueueSize(); size != tt.expected {
+			t.Errorf("%s: expected queue size %d, got %d", tt.name, tt.expected, size)
+		}
+	}
+}
+
+func TestParsedFieldSensors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []PipelineStageConfig
+		expected bool
+	}{
+		{"No stages", nil, false},
+		{"Parse without sensors", []PipelineStageConfig{{Type: StageParse}}, false},
+		{"Parse with sensors", []PipelineStageConfig{{Type: StageDedupe}, {Type: StageParse, Sensors: true}}, true},
+		{"Sensors on another stage", []PipelineStageConfig{{Type: StageEnrich, Sensors: true}}, false},
+	}
+	for _, tt := range tests {
+		scanner := &ScannerConfig{Pipeline: tt.pipeline}
+		if sensors := scanner.ParsedFieldSensors(); sensors != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, sensors)
+		}
+	}
+}
+
+func TestSetConfigurationURLDefault(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("No hostname: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		configURL string
+		listeners []HTTPListenerConfig
+		expected  string
+	}{
+		{"No listeners", "", nil, ""},
+		{"No UI listener", "", []HTTPListenerConfig{{Address: "127.0.0.1:9100", Serve: []string{HTTPServeMetrics}}}, ""},
+		{"Bound host", "", []HTTPListenerConfig{{Address: "192.168.1.20:8080", Serve: []string{HTTPServeUI}}}, "http://192.168.1.20:8080/"},
+		{"All interfaces", "", []HTTPListenerConfig{{Address: ":8080", Serve: []string{HTTPServeUI}}}, "http://" + hostname + ":8080/"},
+		{"Unspecified IP", "", []HTTPListenerConfig{{Address: "0.0.0.0:8080", Serve: []string{HTTPServeUI}}}, "http://" + hostname + ":8080/"},
+		{
+			"TLS",
+			"",
+			[]HTTPListenerConfig{{Address: "bridge.lan:8443", Serve: []string{HTTPServeUI}, TLS: HTTPTLSConfig{CertFile: "c", KeyFile: "k"}}},
+			"https://bridge.lan:8443/",
+		},
+		{
+			"Configured",
+			"https://bridge.example.com/",
+			[]HTTPListenerConfig{{Address: ":8080", Serve: []string{HTTPServeUI}}},
+			"https://bridge.example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{
+				HomeAssistant: HomeAssistantConfig{ConfigurationURL: tt.configURL},
+				HTTP:          HTTPConfig{Listeners: tt.listeners},
+			}
+			config.setConfigurationURLDefault()
+			if config.HomeAssistant.ConfigurationURL != tt.expected {
+				t.Errorf("Expected configuration URL '%s', got '%s'", tt.expected, config.HomeAssistant.ConfigurationURL)
+			}
+		})
+	}
+}
+
+func TestValidateConfigurationURL(t *testing.T) {
+	for _, valid := range []string{"", "http://bridge.lan:8080/", "homeassistant://config/integrations"} {
+		if err := validateConfigurationURL(valid); err != nil {
+			t.Errorf("Expected '%s' to be valid, got: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"bridge.lan:8080", "ftp://bridge.lan/"} {
+		if err := validateConfigurationURL(invalid); err == nil {
+			t.Errorf("Expected '%s' to be invalid", invalid)
+		}
+	}
+}
+
+func TestValidateRawTopic(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawTopic    string
+		expectError bool
+	}{
+		{"Disabled", "", false},
+		{"Topic", "warehouse/dock1/scan", false},
+		{"Single-level wildcard", "warehouse/+/scan", true},
+		{"Multi-level wildcard", "warehouse/#", true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validateRawTopic("test", &ScannerConfig{RawTopic: tt.rawTopic})
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		minLength   int
+		maxLength   int
+		expectError bool
+	}{
+		{"Disabled", 0, 0, false},
+		{"Range", 8, 14, false},
+		{"Minimum only", 4, 0, false},
+		{"Equal", 13, 13, false},
+		{"Inverted", 14, 8, true},
+		{"Negative", -1, 0, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validateLength("test", &ScannerConfig{MinLength: tt.minLength, MaxLength: tt.maxLength})
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateScanExec(t *testing.T) {
+	tests := []struct {
+		name        string
+		exec        *ScanExecConfig
+		expectError bool
+	}{
+		{"Disabled", nil, false},
+		{"Command", &ScanExecConfig{Command: []string{"/usr/local/bin/on-scan", "{barcode}"}, Timeout: time.Second, MaxConcurrent: 2}, false},
+		{"Without command", &ScanExecConfig{}, true},
+		{"Empty command", &ScanExecConfig{Command: []string{"", "{barcode}"}}, true},
+		{"Negative concurrency", &ScanExecConfig{Command: []string{"true"}, MaxConcurrent: -1}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validateScanExec("test", &ScannerConfig{OnScanExec: tt.exec})
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateEntityPlatform(t *testing.T) {
+	tests := []struct {
+		name        string
+		scanner     ScannerConfig
+		expectError bool
+	}{
+		{"Default", ScannerConfig{}, false},
+		{"Sensor", ScannerConfig{EntityPlatform: "sensor", StateFormat: "json"}, false},
+		{"Event", ScannerConfig{EntityPlatform: "event"}, false},
+		{"Event with plain state", ScannerConfig{EntityPlatform: "event", StateFormat: "plain"}, false},
+		{"Event with JSON state", ScannerConfig{EntityPlatform: "event", StateFormat: "json"}, true},
+		{"Unknown platform", ScannerConfig{EntityPlatform: "button"}, true},
+		{"Sensor expiring", ScannerConfig{ExpireAfter: 30 * time.Second}, false},
+		{"Negative expiry", ScannerConfig{ExpireAfter: -time.Second}, true},
+		{"Fractional expiry", ScannerConfig{ExpireAfter: 1500 * time.Millisecond}, true},
+		{"Event expiring", ScannerConfig{EntityPlatform: "event", ExpireAfter: 30 * time.Second}, true},
+	}
+
+	config := &Config{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.validateStateFormat("test", &tt.scanner)
+			if tt.expectError && err == nil {
+				t.Error("Expected error, but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Expected no error, but got: %v", err)
+			}
+		})
+	}
 }
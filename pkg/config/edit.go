@@ -0,0 +1,155 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddScannerToFile adds a scanner block to the scanners section of the
+// configuration file. The file is edited as a YAML document so the other
+// sections and comments are kept, and it is only replaced once the result
+// validates.
+func AddScannerToFile(path, id string, scanner *ScannerConfig) error {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	updated, err := addScannerToDocument(data, id, scanner)
+	if err != nil {
+		return err
+	}
+	if _, err := parseConfig(updated); err != nil {
+		return fmt.Errorf("configuration with scanner '%s' is invalid: %w", id, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat config file: %w", err)
+	}
+
+	// Replace the file atomically so the config watcher never reads it half written
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(updated); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
+
+func addScannerToDocument(data []byte, id string, scanner *ScannerConfig) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file is not a YAML mapping")
+	}
+	root := document.Content[0]
+
+	var scanners *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "scanners" {
+			scanners = root.Content[i+1]
+			break
+		}
+	}
+	if scanners == nil {
+		scanners = &yaml.Node{}
+		root.Content = append(root.Content, scalarNode("scanners"), scanners)
+	}
+	if scanners.Kind != yaml.MappingNode {
+		if scanners.Tag != "" && scanners.Tag != "!!null" {
+			return nil, fmt.Errorf("scanners is not a YAML mapping")
+		}
+		*scanners = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	for i := 0; i < len(scanners.Content); i += 2 {
+		if scanners.Content[i].Value == id {
+			return nil, fmt.Errorf("scanner '%s' is already configured", id)
+		}
+	}
+	scanners.Content = append(scanners.Content, scalarNode(id), scannerNode(scanner))
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return nil, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scannerNode renders the identification and input settings of a scanner,
+// with USB IDs in hex like in the examples.
+func scannerNode(scanner *ScannerConfig) *yaml.Node {
+	identification := mappingNode(
+		scalarNode("vendor_id"), hexNode(scanner.Identification.VendorID),
+		scalarNode("product_id"), hexNode(scanner.Identification.ProductID),
+	)
+	if scanner.Identification.Serial != "" {
+		identification.Content = append(identification.Content,
+			scalarNode("serial"), quotedNode(scanner.Identification.Serial))
+	}
+	if scanner.Identification.Interface != nil {
+		identification.Content = append(identification.Content,
+			scalarNode("interface"), intNode(strconv.Itoa(*scanner.Identification.Interface)))
+	}
+
+	node := mappingNode()
+	if scanner.Name != "" {
+		node.Content = append(node.Content, scalarNode("name"), quotedNode(scanner.Name))
+	}
+	node.Content = append(node.Content, scalarNode("identification"), identification)
+	if scanner.TerminationChar != "" {
+		node.Content = append(node.Content, scalarNode("termination_char"), quotedNode(scanner.TerminationChar))
+	}
+	if scanner.KeyboardLayout != "" {
+		node.Content = append(node.Content, scalarNode("keyboard_layout"), quotedNode(scanner.KeyboardLayout))
+	}
+	return node
+}
+
+// mappingNode builds a mapping from alternating key and value nodes.
+func mappingNode(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: content}
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func quotedNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}
+}
+
+func intNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value}
+}
+
+func hexNode(value uint16) *yaml.Node {
+	return intNode(fmt.Sprintf("0x%04x", value))
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAddScannerToFile(t *testing.T) {
+	configContent := `# Bridge configuration
+mqtt:
+  broker_url: "mqtt://localhost:1883" # local broker
+
+scanners:
+  front_desk:
+    termination_char: enter
+    identification:
+      vendor_id: 0x60e
+      product_id: 0x16c7
+`
+	path := createTempConfig(t, configContent)
+
+	iface := 1
+	scanner := ScannerConfig{
+		Name:            "Kitchen Scanner",
+		TerminationChar: "tab",
+		Identification:  ScannerIdentification{VendorID: 0x1eab, ProductID: 0x1a03, Serial: "0042", Interface: &iface},
+	}
+	if err := AddScannerToFile(path, "kitchen", &scanner); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	for _, expected := range []string{"# Bridge configuration", "# local broker", "vendor_id: 0x1eab", "serial: \"0042\""} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected config to contain %q, got:\n%s", expected, data)
+		}
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected updated config to load, got: %v", err)
+	}
+	kitchen, ok := config.Scanners["kitchen"]
+	if !ok {
+		t.Fatalf("Expected scanner 'kitchen' in %v", config.Scanners)
+	}
+	if kitchen.Identification.ProductID != 0x1a03 || kitchen.Identification.Serial != "0042" || kitchen.TerminationChar != "tab" {
+		t.Errorf("Expected adopted scanner settings, got %+v", kitchen)
+	}
+	if _, ok := config.Scanners["front_desk"]; !ok {
+		t.Error("Expected existing scanner to be kept")
+	}
+
+	if err := AddScannerToFile(path, "kitchen", &scanner); err == nil {
+		t.Error("Expected error for an already configured scanner")
+	}
+}
+
+func TestAddScannerToDocument(t *testing.T) {
+	scanner := ScannerConfig{TerminationChar: "enter", Identification: ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7}}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"no scanners section", "mqtt:\n  broker_url: mqtt://localhost:1883\n", false},
+		{"empty scanners section", "mqtt:\n  broker_url: mqtt://localhost:1883\nscanners:\n", false},
+		{"scanners not a mapping", "scanners:\n  - a\n", true},
+		{"not a mapping", "- a\n", true},
+	}
+
+	for _, tt := range tests {
+		data, err := addScannerToDocument([]byte(tt.content), "new_scanner", &scanner)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, got: %v", tt.name, err)
+			continue
+		}
+		config, err := parseConfig(data)
+		if err != nil {
+			t.Errorf("%s: expected valid config, got: %v", tt.name, err)
+			continue
+		}
+		if _, ok := config.Scanners["new_scanner"]; !ok {
+			t.Errorf("%s: expected scanner 'new_scanner', got:\n%s", tt.name, data)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Pipeline stage types run on a scan before it is routed and published.
+const (
+	StageValidate  = "validate"
+	StageTransform = "transform"
+	StageDedupe    = "dedupe"
+	StageEnrich    = "enrich"
+	StageAIM       = "aim"
+	StageRateLimit = "rate_limit"
+	StageParse     = "parse"
+	StageProduct   = "openfoodfacts"
+	StageLookup    = "lookup"
+)
+
+// What a validate stage does with scans failing its checks.
+const (
+	InvalidActionDrop = "drop"
+	InvalidActionFlag = "flag"
+)
+
+// What a rate_limit stage does with scans over the limit.
+const (
+	LimitActionDrop  = "drop"
+	LimitActionQueue = "queue"
+)
+
+// DefaultRateLimitQueueSize is how many scans a queueing rate_limit stage
+// holds back before dropping further ones.
+const DefaultRateLimitQueueSize = 10
+
+// Defaults of openfoodfacts stages.
+const (
+	DefaultProductLookupTimeout = 5 * time.Second
+	DefaultProductCacheTTL      = 24 * time.Hour
+)
+
+const (
+	TransformCaseUpper = "upper"
+	TransformCaseLower = "lower"
+)
+
+// PipelineStageConfig configures one stage of a scanner pipeline. Only the
+// options of the selected type apply.
+type PipelineStageConfig struct {
+	Type string `yaml:"type"` // "validate", "transform", "dedupe", "enrich", "aim", "rate_limit", "parse", "openfoodfacts" or "lookup"
+
+	// validate: drop scans not matching the pattern or length limits, or
+	// GTINs with a wrong check digit, or flag them with valid: false.
+	Pattern    string `yaml:"pattern,omitempty"`
+	MinLength  int    `yaml:"min_length,omitempty"`
+	MaxLength  int    `yaml:"max_length,omitempty"`
+	CheckDigit bool   `yaml:"check_digit,omitempty"` // 8, 12, 13 and 14 digit barcodes must have a valid GTIN check digit
+	OnInvalid  string `yaml:"on_invalid,omitempty"`  // "drop" (default) or "flag"
+
+	// transform: rewrite the barcode, applying the options in the order
+	// listed here.
+	Trim            bool   `yaml:"trim,omitempty"` // Remove leading and trailing whitespace
+	TrimPrefix      string `yaml:"trim_prefix,omitempty"`
+	TrimSuffix      string `yaml:"trim_suffix,omitempty"`
+	SubstringStart  int    `yaml:"substring_start,omitempty"`  // Characters to skip
+	SubstringLength int    `yaml:"substring_length,omitempty"` // Characters to keep, 0 for the rest
+	ReplacePattern  string `yaml:"replace_pattern,omitempty"`  // Regex whose matches are replaced
+	ReplaceWith     string `yaml:"replace_with,omitempty"`     // Replacement, may reference groups as ${1}
+	Case            string `yaml:"case,omitempty"`             // "upper" or "lower"
+	Template        string `yaml:"template,omitempty"`         // Go template like value_template
+
+	// dedupe: drop repeats of the previous barcode within the window.
+	Window time.Duration `yaml:"window,omitempty"`
+
+	// rate_limit: let at most max_per_second scans through, dropping the
+	// excess or delaying it until the rate allows.
+	MaxPerSecond float64 `yaml:"max_per_second,omitempty"`
+	OnLimit      string  `yaml:"on_limit,omitempty"`   // "drop" (default) or "queue"
+	QueueSize    int     `yaml:"queue_size,omitempty"` // Scans held back when queueing, defaults to 10
+
+	// aim: strip the AIM symbology identifier scanners can be configured to
+	// prefix, publishing the symbology as an attribute instead. No options.
+
+	// parse: add the fields of Wi-Fi, contact, URL and JSON payloads as
+	// attributes.
+	Sensors bool `yaml:"sensors,omitempty"` // Also publish each field as a sensor of the scanner device
+
+	// openfoodfacts: look GTINs up on Open Food Facts, adding the product
+	// name, brand and image URL as attributes. Lookups are cached.
+	URL      string        `yaml:"url,omitempty"`       // Defaults to https://world.openfoodfacts.org
+	Timeout  time.Duration `yaml:"timeout,omitempty"`   // Defaults to 5s
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"` // Defaults to 24h
+
+	// lookup: merge the fields of the barcode's row in a CSV or YAML file
+	// into the attributes. The file is reloaded when it changes.
+	File string `yaml:"file,omitempty"`
+
+	// enrich: static attributes added to the scan, published with it and
+	// delivered to sinks.
+	Attributes map[string]string `yaml:"attributes,omitempty"`
+}
+
+// RateLimitQueueSize returns how many scans the queueing rate_limit stage of
+// the scanner's pipeline holds back, or 0 without one.
+func (s *ScannerConfig) RateLimitQueueSize() int {
+	for _, stage := range s.Pipeline {
+		if stage.Type == StageRateLimit && stage.OnLimit == LimitActionQueue {
+			if stage.QueueSize == 0 {
+				return DefaultRateLimitQueueSize
+			}
+			return stage.QueueSize
+		}
+	}
+	return 0
+}
+
+// ParsedFieldSensors reports whether a parse stage of the scanner's pipeline
+// publishes the parsed fields as sensors.
+func (s *ScannerConfig) ParsedFieldSensors() bool {
+	for _, stage := range s.Pipeline {
+		if stage.Type == StageParse && stage.Sensors {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) validatePipeline(id string, scanner *ScannerConfig) error {
+	validTypes := []string{StageValidate, StageTransform, StageDedupe, StageEnrich, StageAIM, StageRateLimit, StageParse, StageProduct, StageLookup}
+
+	for i := range scanner.Pipeline {
+		stage := &scanner.Pipeline[i]
+		field := fmt.Sprintf("scanners[%s].pipeline[%d]", id, i)
+
+		if !slices.Contains(validTypes, stage.Type) {
+			return fmt.Errorf("%s.type '%s' must be one of: %s", field, stage.Type, strings.Join(validTypes, ", "))
+		}
+		if err := validatePipelineStage(field, stage); err != nil {
+			return err
+		}
+	}
+
+	if scanner.ValueTemplate != "" {
+		if _, err := template.New("value_template").Parse(scanner.ValueTemplate); err != nil {
+			return fmt.Errorf("scanners[%s].value_template is invalid: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func validatePipelineStage(field string, stage *PipelineStageConfig) error {
+	switch stage.Type {
+	case StageValidate:
+		if _, err := regexp.Compile(stage.Pattern); err != nil {
+			return fmt.Errorf("%s.pattern is invalid: %w", field, err)
+		}
+		if stage.MinLength < 0 || stage.MaxLength < 0 {
+			return fmt.Errorf("%s lengths must not be negative", field)
+		}
+		if stage.MaxLength > 0 && stage.MinLength > stage.MaxLength {
+			return fmt.Errorf("%s.min_length must not exceed max_length", field)
+		}
+		validActions := []string{InvalidActionDrop, InvalidActionFlag}
+		if stage.OnInvalid != "" && !slices.Contains(validActions, stage.OnInvalid) {
+			return fmt.Errorf("%s.on_invalid '%s' must be one of: %s", field, stage.OnInvalid, strings.Join(validActions, ", "))
+		}
+	case StageTransform:
+		validCases := []string{TransformCaseUpper, TransformCaseLower}
+		if stage.Case != "" && !slices.Contains(validCases, stage.Case) {
+			return fmt.Errorf("%s.case '%s' must be one of: %s", field, stage.Case, strings.Join(validCases, ", "))
+		}
+		if stage.SubstringStart < 0 || stage.SubstringLength < 0 {
+			return fmt.Errorf("%s substring bounds must not be negative", field)
+		}
+		if _, err := regexp.Compile(stage.ReplacePattern); err != nil {
+			return fmt.Errorf("%s.replace_pattern is invalid: %w", field, err)
+		}
+		if stage.ReplaceWith != "" && stage.ReplacePattern == "" {
+			return fmt.Errorf("%s.replace_with requires replace_pattern", field)
+		}
+		if _, err := template.New("template").Parse(stage.Template); err != nil {
+			return fmt.Errorf("%s.template is invalid: %w", field, err)
+		}
+	case StageDedupe:
+		if stage.Window <= 0 {
+			return fmt.Errorf("%s.window must be positive", field)
+		}
+	case StageRateLimit:
+		if stage.MaxPerSecond <= 0 {
+			return fmt.Errorf("%s.max_per_second must be positive", field)
+		}
+		validActions := []string{LimitActionDrop, LimitActionQueue}
+		if stage.OnLimit != "" && !slices.Contains(validActions, stage.OnLimit) {
+			return fmt.Errorf("%s.on_limit '%s' must be one of: %s", field, stage.OnLimit, strings.Join(validActions, ", "))
+		}
+		if stage.QueueSize < 0 {
+			return fmt.Errorf("%s.queue_size must not be negative", field)
+		}
+	case StageProduct:
+		if stage.URL != "" {
+			if parsed, err := url.Parse(stage.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("%s.url '%s' must be an http or https URL", field, stage.URL)
+			}
+		}
+		if stage.Timeout < 0 || stage.CacheTTL < 0 {
+			return fmt.Errorf("%s timeout and cache_ttl must not be negative", field)
+		}
+	case StageLookup:
+		if stage.File == "" {
+			return fmt.Errorf("%s.file is required", field)
+		}
+		if !slices.Contains([]string{".csv", ".yaml", ".yml"}, strings.ToLower(filepath.Ext(stage.File))) {
+			return fmt.Errorf("%s.file '%s' must be a .csv, .yaml or .yml file", field, stage.File)
+		}
+	case StageEnrich:
+		if len(stage.Attributes) == 0 {
+			return fmt.Errorf("%s.attributes must not be empty", field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ProfileKiosk     = "kiosk"
+	ProfileWarehouse = "warehouse"
+	ProfilePantry    = "pantry"
+)
+
+// profile is a named bundle of options for a common deployment type. The base
+// document is decoded before the configuration file, so every option set in
+// the file overrides it.
+type profile struct {
+	base string
+	// scanner fills per-scanner options left unset in the configuration file.
+	scanner func(scanner *ScannerConfig)
+}
+
+var profiles = map[string]profile{
+	// A single public-facing scanner: short USB resets and bridge MQTT blips
+	// must not flap the entity shown on the dashboard.
+	ProfileKiosk: {
+		base: `
+homeassistant:
+  disconnect_debounce: 5s
+  availability_mode: latest
+`,
+	},
+	// Many scanners shared by several operators: pick up new scanners
+	// automatically and publish structured states for automations.
+	ProfileWarehouse: {
+		base: `
+auto_discover: true
+homeassistant:
+  disconnect_debounce: 10s
+  symbology_sensor: true
+`,
+		scanner: func(scanner *ScannerConfig) {
+			if scanner.StateFormat == "" && !scanner.UsesEventEntity() {
+				scanner.StateFormat = StateFormatJSON
+			}
+		},
+	},
+	// Kitchen scanners booking stock in and out.
+	ProfilePantry: {
+		scanner: func(scanner *ScannerConfig) {
+			if scanner.Pantry == nil {
+				scanner.Pantry = &PantryConfig{}
+			}
+		},
+	},
+}
+
+// ProfileNames returns the built-in profile names in sorted order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyProfileBase decodes the base options of the profile selected in data
+// into c, before the configuration file itself is decoded on top.
+func (c *Config) applyProfileBase(data []byte) error {
+	var selector struct {
+		Profile string `yaml:"profile"`
+	}
+	if err := yaml.Unmarshal(data, &selector); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if selector.Profile == "" {
+		return nil
+	}
+
+	selected, exists := profiles[selector.Profile]
+	if !exists {
+		return fmt.Errorf("profile '%s' must be one of: %s", selector.Profile, strings.Join(ProfileNames(), ", "))
+	}
+	if err := yaml.Unmarshal([]byte(selected.base), c); err != nil {
+		return fmt.Errorf("invalid built-in profile '%s': %w", selector.Profile, err)
+	}
+	return nil
+}
+
+func (c *Config) setProfileScannerDefaults() {
+	selected, exists := profiles[c.Profile]
+	if !exists || selected.scanner == nil {
+		return
+	}
+
+	for id, scanner := range c.Scanners {
+		selected.scanner(&scanner)
+		c.Scanners[id] = scanner
+	}
+}
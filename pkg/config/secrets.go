@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// credentialSource is a value that can be set inline, read from a file such
+// as a mounted Docker or Kubernetes secret, or taken from an environment
+// variable.
+type credentialSource struct {
+	field string
+	value *string
+	file  string
+	env   string
+}
+
+// resolveMQTTCredentials fills the MQTT username and password from their
+// _file and _env options, so the rest of the bridge only sees the values.
+func (c *Config) resolveMQTTCredentials() error {
+	sources := []credentialSource{
+		{"mqtt.username", &c.MQTT.Username, c.MQTT.UsernameFile, c.MQTT.UsernameEnv},
+		{"mqtt.password", &c.MQTT.Password, c.MQTT.PasswordFile, c.MQTT.PasswordEnv},
+	}
+	for i := range sources {
+		if err := sources[i].resolve(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *credentialSource) resolve() error {
+	set := 0
+	for _, option := range []string{*s.value, s.file, s.env} {
+		if option != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("only one of %s, %s_file and %s_env can be set", s.field, s.field, s.field)
+	}
+
+	switch {
+	case s.file != "":
+		data, err := os.ReadFile(s.file) // #nosec G304 - path from the configuration
+		if err != nil {
+			return fmt.Errorf("failed to read %s_file: %w", s.field, err)
+		}
+		// Secret files usually end with a newline added by the editor or echo
+		*s.value = strings.TrimRight(string(data), "\r\n")
+	case s.env != "":
+		value, exists := os.LookupEnv(s.env)
+		if !exists {
+			return fmt.Errorf("%s_env: environment variable %s is not set", s.field, s.env)
+		}
+		*s.value = value
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultDiscoveryTopicTemplate and DefaultStateTopicTemplate keep every
+	// entity of a scanner under its discovery topic, e.g.
+	// homeassistant/sensor/ha-barcode-bridge-host-scanner-front-health.
+	DefaultDiscoveryTopicTemplate = "{{ .Prefix }}/{{ .Component }}/{{ .ObjectID }}"
+	DefaultStateTopicTemplate     = "{{ .Prefix }}/{{ .Component }}/{{ .ObjectID }}"
+)
+
+// TopicsConfig overrides the MQTT topic layout of scanner entities. Both are
+// Go templates over TopicTemplateData: Discovery renders the topic the
+// discovery config is published under, without the trailing "/config", and
+// State the base of the state, availability, attributes and command topics.
+type TopicsConfig struct {
+	Discovery string `yaml:"discovery,omitempty"`
+	State     string `yaml:"state,omitempty"`
+}
+
+// TopicTemplateData is what the topic templates are rendered with, once per
+// scanner entity.
+type TopicTemplateData struct {
+	Prefix    string // Discovery prefix
+	Component string // Home Assistant component, e.g. "sensor" or "select"
+	BridgeID  string // ha-barcode-bridge-<instance_id>
+	ScannerID string
+	Entity    string // Empty for the barcode sensor, e.g. "health" or "battery" otherwise
+	ObjectID  string // Default object ID, <bridge_id>-scanner-<scanner_id>[-<entity>]
+}
+
+var topicLevelPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// NewTopicTemplateData fills in the default object ID of a scanner entity.
+func NewTopicTemplateData(prefix, component, bridgeID, scannerID, entity string) *TopicTemplateData {
+	objectID := bridgeID + "-scanner-" + scannerID
+	if entity != "" {
+		objectID += "-" + entity
+	}
+	return &TopicTemplateData{
+		Prefix:    prefix,
+		Component: component,
+		BridgeID:  bridgeID,
+		ScannerID: scannerID,
+		Entity:    entity,
+		ObjectID:  objectID,
+	}
+}
+
+// ParseTopicTemplate parses a topic template. Referencing a field that
+// TopicTemplateData doesn't have fails when rendering, not here.
+func ParseTopicTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Parse(text)
+}
+
+// RenderTopic renders a topic template for one scanner entity.
+func RenderTopic(tmpl *template.Template, data *TopicTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// validateTopicTemplates renders both templates for a few sample entities, so
+// mistakes show up at startup instead of as entities Home Assistant ignores.
+func (c *Config) validateTopicTemplates() error {
+	prefix := c.HomeAssistant.DiscoveryPrefix
+	samples := []*TopicTemplateData{
+		NewTopicTemplateData(prefix, "sensor", "ha-barcode-bridge-a", "front", ""),
+		NewTopicTemplateData(prefix, "sensor", "ha-barcode-bridge-a", "front", "health"),
+		NewTopicTemplateData(prefix, "select", "ha-barcode-bridge-a", "front", "pantry_mode"),
+		NewTopicTemplateData(prefix, "sensor", "ha-barcode-bridge-a", "back", ""),
+		NewTopicTemplateData(prefix, "sensor", "ha-barcode-bridge-b", "front", ""),
+	}
+
+	fields := []struct {
+		name     string
+		text     string
+		validate func(topic string, data *TopicTemplateData) error
+	}{
+		{"discovery", c.HomeAssistant.Topics.Discovery, validateDiscoveryTopic},
+		{"state", c.HomeAssistant.Topics.State, validateStateTopic},
+	}
+	for _, field := range fields {
+		if field.text == "" {
+			continue
+		}
+		tmpl, err := ParseTopicTemplate(field.name, field.text)
+		if err != nil {
+			return fmt.Errorf("homeassistant.topics.%s is invalid: %w", field.name, err)
+		}
+
+		rendered := make(map[string]bool, len(samples))
+		for _, data := range samples {
+			topic, err := RenderTopic(tmpl, data)
+			if err != nil {
+				return fmt.Errorf("homeassistant.topics.%s is invalid: %w", field.name, err)
+			}
+			if err := field.validate(topic, data); err != nil {
+				return fmt.Errorf("homeassistant.topics.%s %w", field.name, err)
+			}
+			if rendered[topic] {
+				return fmt.Errorf("homeassistant.topics.%s renders '%s' for several entities, "+
+					"it must include .ObjectID or .BridgeID, .ScannerID and .Entity", field.name, topic)
+			}
+			rendered[topic] = true
+		}
+	}
+	return nil
+}
+
+// validateDiscoveryTopic checks the layout Home Assistant subscribes to:
+// <prefix>/<component>/[<node_id>/]<object_id>.
+func validateDiscoveryTopic(topic string, data *TopicTemplateData) error {
+	rest, found := strings.CutPrefix(topic, data.Prefix+"/"+data.Component+"/")
+	levels := strings.Split(rest, "/")
+	if !found || len(levels) > 2 {
+		return fmt.Errorf("'%s' must be {{ .Prefix }}/{{ .Component }}/[<node_id>/]<object_id>", topic)
+	}
+	for _, level := range levels {
+		if !topicLevelPattern.MatchString(level) {
+			return fmt.Errorf("'%s' must only use letters, digits, '_' and '-' after the component", topic)
+		}
+	}
+	return nil
+}
+
+func validateStateTopic(topic string, _ *TopicTemplateData) error {
+	if strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("'%s' must not contain MQTT wildcards", topic)
+	}
+	if slices.Contains(strings.Split(topic, "/"), "") {
+		return fmt.Errorf("'%s' must not have empty topic levels", topic)
+	}
+	return nil
+}
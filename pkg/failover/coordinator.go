@@ -0,0 +1,191 @@
+package failover
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+const clientIDSuffix = "-failover"
+
+// Coordinator takes part in the leader election over its own MQTT
+// connection, so it keeps running while the application is stopped.
+type Coordinator struct {
+	client            mqtt.Client
+	elector           *Elector
+	topic             string
+	availabilityTopic string
+	interval          time.Duration
+	logger            *logrus.Logger
+
+	promoted     chan struct{}
+	demoted      chan struct{}
+	stopCh       chan struct{}
+	promotedOnce sync.Once
+	demotedOnce  sync.Once
+	wg           sync.WaitGroup
+}
+
+func NewCoordinator(cfg *config.Config, logger *logrus.Logger) (*Coordinator, error) {
+	mqttConfig := cfg.MQTT
+	mqttConfig.ClientID += clientIDSuffix
+
+	client, err := mqtt.NewClient(&mqttConfig, "", logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Coordinator{
+		client:            client,
+		elector:           NewElector(cfg.Failover.NodeID, cfg.Failover.Timeout, time.Now()),
+		topic:             homeassistant.GenerateFailoverTopic(&cfg.HomeAssistant),
+		availabilityTopic: homeassistant.GenerateBridgeAvailabilityTopic(&cfg.HomeAssistant),
+		interval:          cfg.Failover.HeartbeatInterval,
+		logger:            logger,
+		promoted:          make(chan struct{}),
+		demoted:           make(chan struct{}),
+		stopCh:            make(chan struct{}),
+	}, nil
+}
+
+// Promoted is closed once this bridge becomes the leader.
+func (c *Coordinator) Promoted() <-chan struct{} {
+	return c.promoted
+}
+
+// Demoted is closed when another bridge took over the leadership.
+func (c *Coordinator) Demoted() <-chan struct{} {
+	return c.demoted
+}
+
+func (c *Coordinator) Start() error {
+	c.client.SetOnConnectCallback(c.subscribe)
+	if err := c.client.Connect(); err != nil {
+		return err
+	}
+
+	c.wg.Add(1)
+	go c.run()
+	return nil
+}
+
+// Resign stops acting as leader: no more heartbeats or availability
+// corrections. Call it before stopping the application, whose offline
+// messages must not be corrected; Stop then hands over to the standby.
+func (c *Coordinator) Resign() {
+	c.elector.Resign()
+}
+
+// Stop resigns, tells the standby to take over right away if this bridge
+// was leader, and disconnects.
+func (c *Coordinator) Stop() error {
+	close(c.stopCh)
+	c.wg.Wait()
+
+	c.elector.Resign()
+	if c.promotedBefore() && !c.demotedBefore() {
+		c.publishHeartbeat()
+	}
+	c.client.Disconnect()
+	return nil
+}
+
+func (c *Coordinator) subscribe() {
+	if err := c.client.Subscribe(c.topic, c.handleHeartbeat); err != nil {
+		c.logger.WithError(err).Error("Failed to subscribe to the failover topic")
+	}
+	if err := c.client.Subscribe(c.availabilityTopic, c.handleAvailability); err != nil {
+		c.logger.WithError(err).Error("Failed to subscribe to the bridge availability")
+	}
+}
+
+func (c *Coordinator) run() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			if !c.client.IsConnected() {
+				// Without the broker this node can't hear the leader
+				continue
+			}
+			if c.elector.Tick(time.Now()) {
+				heartbeat := c.elector.Heartbeat()
+				c.logger.WithField("term", heartbeat.Term).Warn("No failover leader heard, taking over as leader")
+				c.publishHeartbeat()
+				c.promotedOnce.Do(func() { close(c.promoted) })
+				continue
+			}
+			if c.elector.IsLeader() {
+				c.publishHeartbeat()
+			}
+		}
+	}
+}
+
+func (c *Coordinator) publishHeartbeat() {
+	payload, err := json.Marshal(c.elector.Heartbeat())
+	if err != nil {
+		return
+	}
+	if err := c.client.Publish(c.topic, string(payload), true); err != nil {
+		c.logger.WithError(err).Error("Failed to publish failover heartbeat")
+	}
+}
+
+func (c *Coordinator) handleHeartbeat(_ string, payload []byte) {
+	var heartbeat Heartbeat
+	if err := json.Unmarshal(payload, &heartbeat); err != nil {
+		c.logger.WithError(err).Warn("Ignoring invalid failover heartbeat")
+		return
+	}
+
+	if c.elector.Observe(heartbeat, time.Now()) {
+		c.logger.WithFields(map[string]any{
+			"leader": heartbeat.NodeID,
+			"term":   heartbeat.Term,
+		}).Warn("Another bridge is failover leader, stepping down")
+		c.demotedOnce.Do(func() { close(c.demoted) })
+	}
+}
+
+// handleAvailability republishes the bridge as online when the former
+// leader's will marks it offline after this bridge took over.
+func (c *Coordinator) handleAvailability(_ string, payload []byte) {
+	if string(payload) != homeassistant.StatusOffline || !c.elector.IsLeader() {
+		return
+	}
+	c.logger.Info("Former failover leader marked the bridge offline, republishing online")
+	if err := c.client.Publish(c.availabilityTopic, "online", true); err != nil {
+		c.logger.WithError(err).Error("Failed to republish bridge availability")
+	}
+}
+
+func (c *Coordinator) promotedBefore() bool {
+	select {
+	case <-c.promoted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Coordinator) demotedBefore() bool {
+	select {
+	case <-c.demoted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,135 @@
+// Package failover runs a bridge as one of an active/standby pair. The
+// bridges elect a leader over MQTT heartbeats and only the leader opens the
+// scanners and publishes.
+package failover
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	StateLeader   = "leader"
+	StateResigned = "resigned"
+)
+
+// Heartbeat is published by the leader on the failover topic, and once with
+// StateResigned when it shuts down so the standby takes over right away.
+type Heartbeat struct {
+	NodeID string `json:"node_id"`
+	Term   int64  `json:"term"`
+	State  string `json:"state"`
+}
+
+// Elector decides the role of this node from the heartbeats it observes. A
+// standby promotes itself once no leader has been heard for the timeout,
+// with a term above every term it has seen. When two nodes are leaders at
+// once, e.g. after a network partition healed, the higher term wins and the
+// lower node ID breaks ties.
+type Elector struct {
+	nodeID  string
+	timeout time.Duration
+
+	mutex     sync.Mutex
+	term      int64
+	leader    bool
+	leaderID  string // Leader heard from last, empty when none
+	lastHeard time.Time
+}
+
+// NewElector returns a standby elector. It waits a full timeout from now
+// before promoting itself, to hear an existing leader first.
+func NewElector(nodeID string, timeout time.Duration, now time.Time) *Elector {
+	return &Elector{nodeID: nodeID, timeout: timeout, lastHeard: now}
+}
+
+// Observe processes a heartbeat and reports whether this node lost the
+// leadership because of it.
+func (e *Elector) Observe(heartbeat Heartbeat, now time.Time) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if heartbeat.NodeID == e.nodeID {
+		// Retained heartbeat from a previous run of this node
+		e.term = max(e.term, heartbeat.Term)
+		return false
+	}
+
+	switch heartbeat.State {
+	case StateResigned:
+		e.term = max(e.term, heartbeat.Term)
+		if !e.leader && (e.leaderID == "" || e.leaderID == heartbeat.NodeID) {
+			e.leaderID = ""
+			e.lastHeard = time.Time{}
+		}
+		return false
+	case StateLeader:
+		wasLeader := e.leader
+		if e.leader {
+			if !outranks(heartbeat, e.term, e.nodeID) {
+				return false
+			}
+			e.leader = false
+		}
+		e.term = max(e.term, heartbeat.Term)
+		e.leaderID = heartbeat.NodeID
+		e.lastHeard = now
+		return wasLeader
+	default:
+		return false
+	}
+}
+
+// Tick promotes a standby that has not heard a leader within the timeout,
+// and reports whether it did.
+func (e *Elector) Tick(now time.Time) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.leader || now.Sub(e.lastHeard) < e.timeout {
+		return false
+	}
+	e.term++
+	e.leader = true
+	e.leaderID = e.nodeID
+	return true
+}
+
+// Resign gives up the leadership and reports whether this node was leader.
+func (e *Elector) Resign() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	wasLeader := e.leader
+	e.leader = false
+	return wasLeader
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leader
+}
+
+// Heartbeat returns the heartbeat of this node in its current role.
+func (e *Elector) Heartbeat() Heartbeat {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	state := StateResigned
+	if e.leader {
+		state = StateLeader
+	}
+	return Heartbeat{NodeID: e.nodeID, Term: e.term, State: state}
+}
+
+// Leader returns the node ID of the current leader, empty when unknown.
+func (e *Elector) Leader() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leaderID
+}
+
+func outranks(heartbeat Heartbeat, term int64, nodeID string) bool {
+	return heartbeat.Term > term || (heartbeat.Term == term && heartbeat.NodeID < nodeID)
+}
@@ -0,0 +1,98 @@
+package failover
+
+import (
+	"testing"
+	"time"
+)
+
+const testTimeout = 10 * time.Second
+
+func TestElector_PromotesAfterTimeout(t *testing.T) {
+	start := time.Now()
+	elector := NewElector("b", testTimeout, start)
+
+	elector.Observe(Heartbeat{NodeID: "a", Term: 3, State: StateLeader}, start.Add(5*time.Second))
+	if elector.Tick(start.Add(12 * time.Second)) {
+		t.Error("Expected no promotion while the leader is heard")
+	}
+	if elector.Leader() != "a" {
+		t.Errorf("Expected leader a, got %s", elector.Leader())
+	}
+
+	if !elector.Tick(start.Add(16 * time.Second)) {
+		t.Fatal("Expected promotion after the leader timed out")
+	}
+	if heartbeat := elector.Heartbeat(); heartbeat.Term != 4 || heartbeat.State != StateLeader {
+		t.Errorf("Expected leader heartbeat with term 4, got %+v", heartbeat)
+	}
+	if elector.Tick(start.Add(30 * time.Second)) {
+		t.Error("Expected no second promotion")
+	}
+}
+
+func TestElector_ResignHandsOver(t *testing.T) {
+	start := time.Now()
+	elector := NewElector("b", testTimeout, start)
+
+	elector.Observe(Heartbeat{NodeID: "a", Term: 1, State: StateLeader}, start)
+	elector.Observe(Heartbeat{NodeID: "a", Term: 1, State: StateResigned}, start.Add(time.Second))
+	if !elector.Tick(start.Add(2 * time.Second)) {
+		t.Error("Expected immediate promotion after the leader resigned")
+	}
+	if !elector.Resign() || elector.IsLeader() {
+		t.Error("Expected the leader to resign")
+	}
+	if elector.Heartbeat().State != StateResigned {
+		t.Error("Expected a resigned heartbeat")
+	}
+}
+
+func TestElector_SplitBrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		heartbeat Heartbeat
+		demoted   bool
+	}{
+		{"Higher term wins", Heartbeat{NodeID: "c", Term: 2, State: StateLeader}, true},
+		{"Lower term loses", Heartbeat{NodeID: "a", Term: 0, State: StateLeader}, false},
+		{"Lower node ID breaks tie", Heartbeat{NodeID: "a", Term: 1, State: StateLeader}, true},
+		{"Higher node ID loses tie", Heartbeat{NodeID: "c", Term: 1, State: StateLeader}, false},
+		{"Resigned heartbeat", Heartbeat{NodeID: "a", Term: 5, State: StateResigned}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			elector := NewElector("b", testTimeout, start)
+			if !elector.Tick(start.Add(testTimeout)) {
+				t.Fatal("Expected promotion")
+			}
+
+			if demoted := elector.Observe(tt.heartbeat, start.Add(testTimeout)); demoted != tt.demoted {
+				t.Errorf("Expected demoted %v, got %v", tt.demoted, demoted)
+			}
+			if elector.IsLeader() == tt.demoted {
+				t.Errorf("Expected leader %v", !tt.demoted)
+			}
+		})
+	}
+}
+
+func TestElector_OwnRetainedHeartbeat(t *testing.T) {
+	start := time.Now()
+	elector := NewElector("b", testTimeout, start)
+
+	// Left retained by a previous run of this node
+	if elector.Observe(Heartbeat{NodeID: "b", Term: 7, State: StateLeader}, start) {
+		t.Error("Expected own heartbeat not to demote")
+	}
+	if elector.Tick(start.Add(time.Second)) {
+		t.Error("Expected own heartbeat not to count as a leader")
+	}
+	if !elector.Tick(start.Add(testTimeout)) {
+		t.Fatal("Expected promotion after the timeout")
+	}
+	if term := elector.Heartbeat().Term; term != 8 {
+		t.Errorf("Expected term 8 above the retained term, got %d", term)
+	}
+}
@@ -0,0 +1,179 @@
+package homeassistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	assistConversationPath = "/api/conversation/process"
+	assistQueueSize        = 16
+	assistResponseError    = "error"
+)
+
+type assistCommand struct {
+	scannerID string
+	text      string
+}
+
+type assistRequest struct {
+	Text           string `json:"text"`
+	Language       string `json:"language,omitempty"`
+	AgentID        string `json:"agent_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+}
+
+type assistResponse struct {
+	ConversationID string `json:"conversation_id"`
+	Response       struct {
+		ResponseType string `json:"response_type"`
+		Speech       struct {
+			Plain struct {
+				Speech string `json:"speech"`
+			} `json:"plain"`
+		} `json:"speech"`
+	} `json:"response"`
+}
+
+// AssistForwarder sends scans carrying the configured prefix to the Home
+// Assistant conversation API, so barcode cards can trigger Assist intents
+// from a kiosk without a microphone.
+type AssistForwarder struct {
+	config *config.AssistConfig
+	client *http.Client
+	logger *logrus.Logger
+
+	queue  chan assistCommand
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// Conversation IDs per scanner so follow-up cards keep the Assist context.
+	// Only accessed from the worker goroutine.
+	conversations map[string]string
+}
+
+func NewAssistForwarder(cfg *config.AssistConfig, logger *logrus.Logger) *AssistForwarder {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AssistForwarder{
+		config:        cfg,
+		client:        &http.Client{Timeout: cfg.Timeout},
+		logger:        logger,
+		queue:         make(chan assistCommand, assistQueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		conversations: make(map[string]string),
+	}
+}
+
+func (a *AssistForwarder) Start() error {
+	a.wg.Add(1)
+	go a.worker()
+	return nil
+}
+
+func (a *AssistForwarder) Stop() error {
+	a.cancel()
+	a.wg.Wait()
+	return nil
+}
+
+// HandleScan queues the command when the barcode carries the Assist prefix
+// and reports whether the scan was consumed.
+func (a *AssistForwarder) HandleScan(scannerID, barcode string) bool {
+	if len(a.config.Scanners) > 0 && !slices.Contains(a.config.Scanners, scannerID) {
+		return false
+	}
+
+	text, ok := strings.CutPrefix(barcode, a.config.Prefix)
+	if !ok {
+		return false
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		a.logger.WithField("scanner_id", scannerID).Warn("Ignoring empty Assist command")
+		return true
+	}
+
+	select {
+	case a.queue <- assistCommand{scannerID: scannerID, text: text}:
+	default:
+		a.logger.WithFields(logrus.Fields{"scanner_id": scannerID, "command": text}).Error("Assist queue full, command dropped")
+	}
+	return true
+}
+
+func (a *AssistForwarder) worker() {
+	defer a.wg.Done()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case command := <-a.queue:
+			logger := a.logger.WithFields(logrus.Fields{"scanner_id": command.scannerID, "command": command.text})
+
+			response, err := a.process(command)
+			if err != nil {
+				logger.WithError(err).Error("Failed to forward command to Assist")
+				continue
+			}
+
+			a.conversations[command.scannerID] = response.ConversationID
+			logger = logger.WithField("speech", response.Response.Speech.Plain.Speech)
+			if response.Response.ResponseType == assistResponseError {
+				logger.Warn("Assist could not handle command")
+			} else {
+				logger.Info("Assist command processed")
+			}
+		}
+	}
+}
+
+func (a *AssistForwarder) process(command assistCommand) (*assistResponse, error) {
+	body, err := json.Marshal(assistRequest{
+		Text:           command.text,
+		Language:       a.config.Language,
+		AgentID:        a.config.AgentID,
+		ConversationID: a.conversations[command.scannerID],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Assist request: %w", err)
+	}
+
+	url := strings.TrimRight(a.config.URL, "/") + assistConversationPath
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.config.Token)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var response assistResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode Assist response: %w", err)
+	}
+	return &response, nil
+}
@@ -0,0 +1,114 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestAssistForwarder_HandleScan(t *testing.T) {
+	received := make(chan assistRequest, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != assistConversationPath {
+			t.Errorf("Expected path %s, got %s", assistConversationPath, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("Expected bearer token, got '%s'", auth)
+		}
+
+		var request assistRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Expected JSON request body, got error: %v", err)
+		}
+		received <- request
+
+		_, _ = w.Write([]byte(`{"conversation_id":"conv-1","response":{"response_type":"action_done",` +
+			`"speech":{"plain":{"speech":"Turned on the lights"}}}}`))
+	}))
+	defer server.Close()
+
+	forwarder := NewAssistForwarder(&config.AssistConfig{
+		URL:      server.URL + "/",
+		Token:    "token",
+		Prefix:   "ASSIST:",
+		Language: "en",
+		Scanners: []string{"kiosk"},
+		Timeout:  time.Second,
+	}, logrus.New())
+	if err := forwarder.Start(); err != nil {
+		t.Fatalf("Expected no error starting forwarder, got: %v", err)
+	}
+	defer func() { _ = forwarder.Stop() }()
+
+	tests := []struct {
+		name      string
+		scannerID string
+		barcode   string
+		consumed  bool
+	}{
+		{"Regular barcode", "kiosk", "8412345678905", false},
+		{"Scanner not allowed", "warehouse", "ASSIST:turn on the lights", false},
+		{"Assist command", "kiosk", "ASSIST: turn on the lights", true},
+	}
+
+	for _, tt := range tests {
+		if consumed := forwarder.HandleScan(tt.scannerID, tt.barcode); consumed != tt.consumed {
+			t.Errorf("%s: expected consumed %v, got %v", tt.name, tt.consumed, consumed)
+		}
+	}
+
+	select {
+	case request := <-received:
+		if request.Text != "turn on the lights" {
+			t.Errorf("Expected prefix to be stripped, got '%s'", request.Text)
+		}
+		if request.Language != "en" {
+			t.Errorf("Expected language 'en', got '%s'", request.Language)
+		}
+		if request.ConversationID != "" {
+			t.Errorf("Expected no conversation ID on first command, got '%s'", request.ConversationID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Assist request, got none")
+	}
+
+	forwarder.HandleScan("kiosk", "ASSIST:and the fan")
+	select {
+	case request := <-received:
+		if request.ConversationID != "conv-1" {
+			t.Errorf("Expected follow-up to reuse conversation 'conv-1', got '%s'", request.ConversationID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected follow-up Assist request, got none")
+	}
+}
+
+func TestFetchEntityState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("Expected bearer token, got '%s'", auth)
+		}
+		if r.URL.Path != statesAPIPath+"sensor.home_test" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"entity_id":"sensor.home_test","state":"TEST-1"}`))
+	}))
+	defer server.Close()
+
+	state, err := FetchEntityState(t.Context(), server.Client(), server.URL+"/", "token", "sensor.home_test")
+	if err != nil || state != "TEST-1" {
+		t.Errorf("Expected state TEST-1, got %q (error: %v)", state, err)
+	}
+
+	if _, err := FetchEntityState(t.Context(), server.Client(), server.URL, "token", "sensor.missing"); !errors.Is(err, ErrEntityNotFound) {
+		t.Errorf("Expected ErrEntityNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package homeassistant
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// clockCheckInterval is how often health states are re-evaluated and the
+	// wall clock is compared against the monotonic clock.
+	clockCheckInterval = 30 * time.Second
+
+	// clockJumpThreshold is the wall-clock jump reported in the diagnostics,
+	// well above the slewing NTP does on a running system.
+	clockJumpThreshold = time.Minute
+
+	// scannerStaleAfter is how long a disconnected scanner is reported as
+	// "disconnected" before it turns "stale".
+	scannerStaleAfter = 5 * time.Minute
+)
+
+// clockMonitor detects wall-clock jumps, e.g. the first NTP sync of a
+// Raspberry Pi without an RTC, which starts with the time of its last
+// shutdown. Health staleness is measured on the monotonic clock and is not
+// affected, but the wall-clock timestamps in the attributes published
+// before the jump are off by its size.
+type clockMonitor struct {
+	mutex    sync.Mutex
+	lastWall time.Time // Wall clock only
+	lastMono time.Time // With monotonic reading
+	jumps    int
+	lastJump time.Duration
+	jumpedAt time.Time
+}
+
+func newClockMonitor(now time.Time) *clockMonitor {
+	return &clockMonitor{lastWall: now.Round(0), lastMono: now}
+}
+
+// check compares the wall and monotonic time elapsed since the previous
+// check and returns the wall-clock jump, if any.
+func (m *clockMonitor) check(now time.Time) (time.Duration, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	jump := now.Round(0).Sub(m.lastWall) - now.Sub(m.lastMono)
+	m.lastWall = now.Round(0)
+	m.lastMono = now
+
+	if jump.Abs() < clockJumpThreshold {
+		return 0, false
+	}
+	m.jumps++
+	m.lastJump = jump
+	m.jumpedAt = now
+	return jump, true
+}
+
+func (m *clockMonitor) addAttributes(attributes map[string]any) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.jumps == 0 {
+		return
+	}
+	attributes["clock_jumps"] = m.jumps
+	attributes["last_clock_jump"] = m.lastJump.Round(time.Second).String()
+	attributes["last_clock_jump_at"] = m.jumpedAt.Format(time.RFC3339)
+	attributes["clock_warning"] = "System clock jumped, timestamps published before " +
+		m.jumpedAt.Format(time.RFC3339) + " are off by " + m.lastJump.Round(time.Second).String()
+}
+
+// runClockChecks republishes health states that changed with time alone,
+// like a disconnected scanner turning stale, and reports wall-clock jumps.
+func (integration *Integration) runClockChecks() {
+	ticker := time.NewTicker(clockCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-integration.stopCh:
+			return
+		case <-ticker.C:
+			integration.checkClock(time.Now())
+		}
+	}
+}
+
+func (integration *Integration) checkClock(now time.Time) {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+	jump, jumped := integration.clock.check(now)
+	if jumped {
+		integration.logger.WithField("jump", jump.Round(time.Second).String()).
+			Warn("System clock jumped, published timestamps before now are off; health staleness is not affected")
+	}
+
+	// Alerts don't depend on MQTT, a scanner going stale during a broker
+	// outage is still reported
+	for scannerID := range integration.scanners {
+		integration.checkHealthTransition(scannerID)
+	}
+
+	if !integration.mqtt.IsConnected() {
+		return
+	}
+	for scannerID, scanner := range integration.scanners {
+		if scanner.Health == nil || integration.getScannerHealthStatus(scannerID) == scanner.Health.reportedStatus {
+			continue
+		}
+		if err := integration.publishScannerHealthState(scannerID); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish health state")
+		}
+	}
+	if jumped {
+		integration.bridgeEntities.publishAllStates()
+	}
+}
@@ -0,0 +1,53 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const connectivitySuffix = "connectivity"
+
+// The connectivity binary sensor reports whether a scanner is connected as a
+// state of its own, for automations and history graphs. Unlike the scanner
+// entities it only depends on the bridge availability, so a disconnected
+// scanner shows as off instead of unavailable.
+func (integration *Integration) publishScannerConnectivityDiscoveryConfig(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	sensorConfig := SensorConfig{
+		Name:       integration.scannerEntityName(scanner, integration.names.Connected),
+		ObjectID:   integration.scannerObjectID(scannerID, connectivitySuffix),
+		UniqueID:   integration.scannerUniqueID(scannerID, connectivitySuffix),
+		TildeTopic: scanner.ConnectivityTopics.BaseTopic,
+		StateTopic: "~/state",
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:         scanner.DeviceInfo,
+		DeviceClass:    "connectivity",
+		EntityCategory: "diagnostic",
+		PayloadOn:      PayloadOn,
+		PayloadOff:     PayloadOff,
+	}
+
+	configJSON, err := json.Marshal(sensorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal connectivity discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(scanner.ConnectivityTopics.ConfigTopic, string(configJSON), true)
+}
+
+func (integration *Integration) publishScannerConnectivityState(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists {
+		return fmt.Errorf("scanner %s not found", scannerID)
+	}
+
+	return integration.mqtt.Publish(scanner.ConnectivityTopics.StateTopic, boolPayload(scanner.Connected), true)
+}
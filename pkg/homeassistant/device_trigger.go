@@ -0,0 +1,78 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const deviceTriggerSuffix = "scanned"
+
+// Type and subtype of the scan device trigger, shown in the automation
+// editor as "barcode" scanned.
+const (
+	ScanTriggerType    = "scanned"
+	ScanTriggerSubtype = "barcode"
+)
+
+// DeviceTriggerConfig is the discovery config of an MQTT device trigger.
+// Home Assistant fires the trigger on every message on Topic, with the
+// barcode as trigger.payload.
+type DeviceTriggerConfig struct {
+	AutomationType string      `json:"automation_type"`
+	Topic          string      `json:"topic"`
+	Type           string      `json:"type"`
+	Subtype        string      `json:"subtype"`
+	Device         *DeviceInfo `json:"device"`
+}
+
+func (integration *Integration) generateScanTriggerTopics(scannerID string) *ScannerTopics {
+	return integration.generateScannerComponentTopics("device_automation", scannerID, deviceTriggerSuffix)
+}
+
+func (integration *Integration) scanTriggerConfig(scanner *ScannerDevice) *DeviceTriggerConfig {
+	return &DeviceTriggerConfig{
+		AutomationType: "trigger",
+		Topic:          integration.generateScanTriggerTopics(scanner.ID).StateTopic,
+		Type:           ScanTriggerType,
+		Subtype:        ScanTriggerSubtype,
+		Device:         scanner.DeviceInfo,
+	}
+}
+
+func (integration *Integration) publishScannerTriggerDiscoveryConfig(scannerID string) error {
+	if !integration.config.DeviceTriggers {
+		return nil
+	}
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	configJSON, err := json.Marshal(integration.scanTriggerConfig(scanner))
+	if err != nil {
+		return fmt.Errorf("failed to marshal device trigger discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(integration.generateScanTriggerTopics(scannerID).ConfigTopic, string(configJSON), true)
+}
+
+// publishScanTrigger fires the scan device trigger. Unlike the barcode
+// sensor, it fires for repeated scans of the same barcode too. A failure is
+// only logged, the scan already reached Home Assistant.
+func (integration *Integration) publishScanTrigger(scannerID, barcode string) {
+	if !integration.config.DeviceTriggers {
+		return
+	}
+
+	topic := integration.generateScanTriggerTopics(scannerID).StateTopic
+	var err error
+	if integration.isFastPath(scannerID) {
+		err = integration.mqtt.PublishFast(topic, barcode)
+	} else {
+		err = integration.mqtt.Publish(topic, barcode, false)
+	}
+	if err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to fire scan device trigger")
+	}
+}
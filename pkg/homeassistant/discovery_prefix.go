@@ -0,0 +1,110 @@
+package homeassistant
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ChangeDiscoveryPrefix moves all entities to a new discovery prefix. The
+// retained discovery configs under the old prefix are cleared first, so Home
+// Assistant never sees two configs with the same unique_id, and the MQTT
+// client reconnects with the bridge availability under the new prefix as its
+// will, which republishes everything from handleConnect. Unique IDs don't
+// include the prefix, so the recreated entities keep their entity IDs and
+// history.
+func (integration *Integration) ChangeDiscoveryPrefix(prefix string) error {
+	oldPrefix := integration.config.DiscoveryPrefix
+	if prefix == oldPrefix {
+		return nil
+	}
+
+	var clearErr error
+	if integration.mqtt.IsConnected() {
+		integration.scannersMutex.RLock()
+		clearErr = integration.ClearDiscoveryConfigs()
+		integration.scannersMutex.RUnlock()
+	}
+
+	integration.connectionMutex.Lock()
+	integration.scannersMutex.Lock()
+	integration.config.DiscoveryPrefix = prefix
+	for _, scanner := range integration.scanners {
+		integration.assignScannerTopics(scanner)
+	}
+	integration.scannersMutex.Unlock()
+	integration.connectionMutex.Unlock()
+
+	integration.logger.WithField("old_prefix", oldPrefix).Infof("Moving Home Assistant discovery to prefix '%s'", prefix)
+	integration.mqtt.SetWillTopic(integration.GenerateBridgeAvailabilityTopic())
+
+	if clearErr != nil {
+		return fmt.Errorf("failed to clear discovery configs under '%s': %w", oldPrefix, clearErr)
+	}
+	return nil
+}
+
+// ClearDiscoveryConfigs removes the retained discovery configs of the bridge
+// and all configured scanners, and the bridge availability, under the
+// current discovery prefix, so Home Assistant deletes the entities.
+func (integration *Integration) ClearDiscoveryConfigs() error {
+	for scannerID := range integration.scannerConfigs {
+		if err := integration.UnpublishScanner(scannerID); err != nil {
+			return err
+		}
+	}
+
+	topics := make([]string, 0, len(integration.bridgeEntities.entities)+2)
+	for i := range integration.bridgeEntities.entities {
+		entityTopics, _ := integration.generateBridgeEntityTopics(integration.bridgeEntities.entities[i].EntityType)
+		topics = append(topics, entityTopics.ConfigTopic)
+	}
+	if integration.pauseControl != nil {
+		topics = append(topics, integration.generatePauseSwitchTopic()+"/config")
+	}
+	if integration.restartScanners != nil {
+		topics = append(topics, integration.generateRestartButtonTopic()+"/config")
+	}
+	if integration.config.UpdateCheck {
+		topics = append(topics, integration.generateUpdateEntityTopic()+"/config")
+	}
+	topics = append(topics, integration.GenerateBridgeAvailabilityTopic())
+
+	for _, topic := range topics {
+		if err := integration.mqtt.Publish(topic, "", true); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// restoreRetainedScannerStates republishes the retained scanner states from
+// memory, which are missing after a discovery prefix change or a restart of
+// a broker without persistence.
+func (integration *Integration) restoreRetainedScannerStates(scannerID string) {
+	scanner := integration.scanners[scannerID]
+	logger := integration.logger.WithField("scanner_id", scannerID)
+
+	availability := StatusOffline
+	if scanner.Connected {
+		availability = "online"
+	}
+	if err := integration.publishScannerAvailability(scannerID, availability); err != nil {
+		logger.WithError(err).Error("Failed to restore availability")
+	}
+	if err := integration.publishScannerHealthState(scannerID); err != nil {
+		logger.WithError(err).Error("Failed to restore health state")
+	}
+	if err := integration.publishScannerConnectivityState(scannerID); err != nil {
+		logger.WithError(err).Error("Failed to restore connectivity")
+	}
+	if scanner.BatteryLevel != nil {
+		if err := integration.mqtt.Publish(scanner.BatteryTopics.StateTopic, strconv.Itoa(*scanner.BatteryLevel), true); err != nil {
+			logger.WithError(err).Error("Failed to restore battery level")
+		}
+	}
+	if scanner.Docked != nil {
+		if err := integration.mqtt.Publish(scanner.DockTopics.StateTopic, boolPayload(*scanner.Docked), true); err != nil {
+			logger.WithError(err).Error("Failed to restore dock state")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	PayloadOn  = "ON"
+	PayloadOff = "OFF"
+)
+
+func (integration *Integration) SetScannerDocked(scannerID string, docked bool) error {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists {
+		return fmt.Errorf("scanner %s not found", scannerID)
+	}
+
+	firstReport := scanner.Docked == nil
+	scanner.Docked = &docked
+
+	if !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	if firstReport {
+		if err := integration.publishScannerDockDiscoveryConfig(scannerID); err != nil {
+			return err
+		}
+	}
+
+	return integration.mqtt.Publish(scanner.DockTopics.StateTopic, boolPayload(docked), true)
+}
+
+func (integration *Integration) publishScannerDockDiscoveryConfig(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	sensorConfig := SensorConfig{
+		Name:        integration.scannerEntityName(scanner, integration.names.Docked),
+		ObjectID:    integration.scannerObjectID(scannerID, "docked"),
+		UniqueID:    integration.scannerUniqueID(scannerID, "dock"),
+		TildeTopic:  scanner.DockTopics.BaseTopic,
+		StateTopic:  "~/state",
+		Device:      scanner.DeviceInfo,
+		Icon:        "mdi:battery-charging-wireless",
+		DeviceClass: "plug",
+		PayloadOn:   PayloadOn,
+		PayloadOff:  PayloadOff,
+	}
+
+	sensorConfig.Availability, sensorConfig.AvailabilityMode = integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	configJSON, err := json.Marshal(sensorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dock discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(scanner.DockTopics.ConfigTopic, string(configJSON), true)
+}
+
+func boolPayload(value bool) string {
+	if value {
+		return PayloadOn
+	}
+	return PayloadOff
+}
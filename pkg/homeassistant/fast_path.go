@@ -0,0 +1,111 @@
+package homeassistant
+
+import (
+	"cmp"
+	"time"
+)
+
+// fastPathFlushInterval is how often the health state and scan counters of
+// fast path scanners are published, instead of after every scan, unless
+// coalesce_interval is set.
+const fastPathFlushInterval = 10 * time.Second
+
+func (integration *Integration) isFastPath(scannerID string) bool {
+	scannerConfig := integration.scannerConfigs[scannerID]
+	return scannerConfig != nil && scannerConfig.FastPath
+}
+
+// isCoalesced reports whether the updates following a scan are batched until
+// the next flush: for all scanners with coalesce_interval, otherwise only on
+// the fast path.
+func (integration *Integration) isCoalesced(scannerID string) bool {
+	return integration.config.CoalesceInterval > 0 || integration.isFastPath(scannerID)
+}
+
+func (integration *Integration) flushInterval() time.Duration {
+	return cmp.Or(integration.config.CoalesceInterval, fastPathFlushInterval)
+}
+
+// markPending batches the updates of a scanner until the next flush.
+func (integration *Integration) markPending(scannerID string) {
+	integration.fastPathMutex.Lock()
+	integration.fastPathPending[scannerID] = true
+	integration.fastPathMutex.Unlock()
+}
+
+// deferScanUpdates counts a scan without publishing the counters, they
+// follow with the health state in the next flush.
+func (integration *Integration) deferScanUpdates(scannerID string, now time.Time) {
+	if counter, exists := integration.scanCounters[scannerID]; exists {
+		counter.record(now)
+	}
+	integration.markPending(scannerID)
+}
+
+// publishBarcodeFast publishes only the scan state, with QoS 0 and without
+// waiting for the broker. Health, scan counters and symbology follow in the
+// next flush.
+func (integration *Integration) publishBarcodeFast(scannerID, barcode string, metadata ScanMetadata) error {
+	scanner := integration.scanners[scannerID]
+	payload, err := integration.formatScannerState(scannerID, barcode, metadata)
+	if err != nil {
+		return err
+	}
+	return integration.mqtt.PublishFast(scanner.Topics.StateTopic, payload)
+}
+
+// takeFastPathPending returns and clears the scanners with batched updates
+// since the last flush.
+func (integration *Integration) takeFastPathPending() []string {
+	integration.fastPathMutex.Lock()
+	defer integration.fastPathMutex.Unlock()
+
+	scannerIDs := make([]string, 0, len(integration.fastPathPending))
+	for scannerID := range integration.fastPathPending {
+		scannerIDs = append(scannerIDs, scannerID)
+	}
+	clear(integration.fastPathPending)
+	return scannerIDs
+}
+
+func (integration *Integration) flushFastPath() {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+	now := time.Now()
+	for _, scannerID := range integration.takeFastPathPending() {
+		scanner, exists := integration.scanners[scannerID]
+		if !exists {
+			continue
+		}
+		if counter, exists := integration.scanCounters[scannerID]; exists {
+			total, windowCount := counter.snapshot(now)
+			integration.publishScanCounts(scannerID, total, windowCount)
+		}
+		if quality, exists := integration.readQualities[scannerID]; exists {
+			accepted, rejected := quality.snapshot(now)
+			integration.publishReadQuality(scannerID, accepted, rejected)
+		}
+		integration.publishSymbology(scannerID, scanSymbology(scanner.LastBarcode, scanner.LastScan))
+		if err := integration.publishScannerHealthState(scannerID); err != nil {
+			integration.logger.WithError(err).Errorf("Failed to update batched health state for scanner %s", scannerID)
+		}
+	}
+}
+
+// runFastPathFlushes publishes the batched updates of fast path scanners,
+// and of all scanners with coalesce_interval.
+func (integration *Integration) runFastPathFlushes() {
+	ticker := time.NewTicker(integration.flushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-integration.stopCh:
+			return
+		case <-ticker.C:
+			if integration.mqtt.IsConnected() {
+				integration.flushFastPath()
+			}
+		}
+	}
+}
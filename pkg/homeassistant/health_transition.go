@@ -0,0 +1,45 @@
+package homeassistant
+
+import "time"
+
+// HealthTransition is a change of the health state of a scanner.
+type HealthTransition struct {
+	ScannerID string         `json:"scanner_id"`
+	Name      string         `json:"name"`
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Time      time.Time      `json:"time"`
+	Health    map[string]any `json:"health"` // Attributes of the health sensor
+}
+
+// SetOnHealthChangeCallback registers a callback for health state changes of
+// the scanners. It runs synchronously, so it must not block.
+func (integration *Integration) SetOnHealthChangeCallback(callback func(transition *HealthTransition)) {
+	integration.onHealthChange = callback
+}
+
+// checkHealthTransition passes a change of the health state of the scanner
+// since the previous check to the health change callback. The first state
+// of a scanner is not a transition.
+func (integration *Integration) checkHealthTransition(scannerID string) {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.Health == nil {
+		return
+	}
+
+	status := integration.getScannerHealthStatus(scannerID)
+	previous := scanner.Health.alertedStatus
+	scanner.Health.alertedStatus = status
+	if previous == "" || previous == status || integration.onHealthChange == nil {
+		return
+	}
+
+	integration.onHealthChange(&HealthTransition{
+		ScannerID: scannerID,
+		Name:      scanner.Name,
+		From:      previous,
+		To:        status,
+		Time:      time.Now(),
+		Health:    integration.getScannerHealthAttributes(scannerID),
+	})
+}
@@ -2,15 +2,20 @@ package homeassistant
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/karalabe/hid"
 	"github.com/sirupsen/logrus"
 
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/translations"
 )
 
 const (
@@ -18,13 +23,32 @@ const (
 	StatusUnknown = "unknown"
 )
 
+// Health states of the scanner health sensor.
+const (
+	HealthHealthy      = "healthy"
+	HealthUnstable     = "unstable"
+	HealthDegraded     = "degraded"
+	HealthDisconnected = "disconnected"
+	HealthStale        = "stale"
+)
+
+// ErrScannerNotFound is returned by PublishBarcode for scanners that are not
+// configured, e.g. removed by a configuration reload.
+var ErrScannerNotFound = errors.New("scanner not found")
+
+// ErrScannerNotRegistered is returned by PublishBarcode for configured
+// scanners whose hardware didn't connect yet, so their Home Assistant
+// device doesn't exist.
+var ErrScannerNotRegistered = errors.New("scanner not registered")
+
 type DeviceInfo struct {
-	Identifiers  []string `json:"identifiers"`
-	Name         string   `json:"name"`
-	Model        string   `json:"model,omitempty"`
-	Manufacturer string   `json:"manufacturer,omitempty"`
-	SWVersion    string   `json:"sw_version,omitempty"`
-	ViaDevice    string   `json:"via_device,omitempty"`
+	Identifiers      []string `json:"identifiers"`
+	Name             string   `json:"name"`
+	Model            string   `json:"model,omitempty"`
+	Manufacturer     string   `json:"manufacturer,omitempty"`
+	SWVersion        string   `json:"sw_version,omitempty"`
+	ViaDevice        string   `json:"via_device,omitempty"`
+	ConfigurationURL string   `json:"configuration_url,omitempty"`
 }
 
 type AvailabilityConfig struct {
@@ -32,23 +56,38 @@ type AvailabilityConfig struct {
 }
 
 type SensorConfig struct {
-	Name              string               `json:"name"`
-	ObjectID          string               `json:"object_id,omitempty"`
-	UniqueID          string               `json:"unique_id"`
-	TildeTopic        string               `json:"~,omitempty"`
-	StateTopic        string               `json:"state_topic"`
-	AttributesTopic   string               `json:"json_attributes_topic,omitempty"`
-	AvailabilityTopic string               `json:"availability_topic,omitempty"`
-	Availability      []AvailabilityConfig `json:"availability,omitempty"`
-	AvailabilityMode  string               `json:"availability_mode,omitempty"`
-	Device            *DeviceInfo          `json:"device,omitempty"`
-	Icon              string               `json:"icon,omitempty"`
-	ForceUpdate       bool                 `json:"force_update,omitempty"`
-	EntityCategory    string               `json:"entity_category,omitempty"`
+	Name               string               `json:"name"`
+	ObjectID           string               `json:"object_id,omitempty"`
+	UniqueID           string               `json:"unique_id"`
+	TildeTopic         string               `json:"~,omitempty"`
+	StateTopic         string               `json:"state_topic"`
+	CommandTopic       string               `json:"command_topic,omitempty"`
+	Options            []string             `json:"options,omitempty"`
+	AttributesTopic    string               `json:"json_attributes_topic,omitempty"`
+	AvailabilityTopic  string               `json:"availability_topic,omitempty"`
+	Availability       []AvailabilityConfig `json:"availability,omitempty"`
+	AvailabilityMode   string               `json:"availability_mode,omitempty"`
+	Device             *DeviceInfo          `json:"device,omitempty"`
+	Icon               string               `json:"icon,omitempty"`
+	ForceUpdate        bool                 `json:"force_update,omitempty"`
+	EntityCategory     string               `json:"entity_category,omitempty"`
+	DeviceClass        string               `json:"device_class,omitempty"`
+	UnitOfMeasurement  string               `json:"unit_of_measurement,omitempty"`
+	StateClass         string               `json:"state_class,omitempty"`
+	PayloadOn          string               `json:"payload_on,omitempty"`
+	PayloadOff         string               `json:"payload_off,omitempty"`
+	ValueTemplate      string               `json:"value_template,omitempty"`
+	AttributesTemplate string               `json:"json_attributes_template,omitempty"`
+	EventTypes         []string             `json:"event_types,omitempty"`
+	ExpireAfter        int                  `json:"expire_after,omitempty"`
+	Min                float64              `json:"min,omitempty"`
+	Max                float64              `json:"max,omitempty"`
+	Step               float64              `json:"step,omitempty"`
+	Mode               string               `json:"mode,omitempty"`
 }
 
 type Integration struct {
-	mqtt             *mqtt.Client
+	mqtt             mqtt.Client
 	config           *config.HomeAssistantConfig
 	logger           *logrus.Logger
 	version          string
@@ -56,16 +95,55 @@ type Integration struct {
 	scannerConfigs   map[string]*config.ScannerConfig
 	bridgeDeviceInfo *DeviceInfo
 	bridgeEntities   *BridgeEntityManager
+	environment      map[string]any
+	sinkHealth       func() any
+	badgePatterns    map[string]*regexp.Regexp
+	operators        map[string]*operatorSession
+	pantry           map[string]*pantryState
+	scanCounters     map[string]*scanCounter
+	readQualities    map[string]*readQuality
+	published        *scanDeduplicator
+	relay            *scanRelay
+	stopCh           chan struct{}
+	connectionMutex  sync.Mutex
+	scannersMutex    sync.RWMutex // Guards the per-scanner maps and ScannerDevice fields; writers take Lock
+	operatorsMutex   sync.Mutex   // Operators change on badge scans of any scanner
+	pauseControl     *PauseControl
+	enableControl    *ScannerEnableControl
+	timeoutControl   *ScanTimeoutControl
+	layoutControl    *KeyboardLayoutControl
+	restartScanners  func() error
+	latestRelease    *common.Release // Result of the last update check
+	updateMutex      sync.Mutex
+	onHealthChange   func(transition *HealthTransition)
+	autoDiscover     bool
+	retainedAudit    retainedAudit
+	fastPathPending  map[string]bool // Fast path scanners scanned since the last flush
+	fastPathMutex    sync.Mutex
+	createdAt        time.Time
+	clock            *clockMonitor
+	names            *translations.EntityNames
+	topics           *topicTemplates
 }
 
+// ScannerHealthMetrics timestamps come from time.Now, so durations between
+// them use the monotonic clock and survive wall-clock jumps. Don't round or
+// serialize them before comparing, which strips the monotonic reading.
 type ScannerHealthMetrics struct {
 	LastSeen       time.Time
 	ConnectedAt    *time.Time
 	DisconnectedAt *time.Time
 	ReconnectCount int
+	FlapCount      int // Disconnects shorter than the debounce window, not reported
 	ErrorCount     int
+	OpenFailures   int // Device found but could not be opened
+	ReadTimeouts   int
+	IOErrors       int
+	BadLengths     int // Scans rejected by the scanner's min_length or max_length
 	TotalScans     int
 	LastScanTime   *time.Time
+	reportedStatus string // Health state last published
+	alertedStatus  string // Health state last passed to the health change callback
 }
 
 type ScannerDevice struct {
@@ -76,9 +154,28 @@ type ScannerDevice struct {
 	Topics       *ScannerTopics
 	HealthTopics *ScannerTopics
 	Health       *ScannerHealthMetrics
+	LastBarcode  string
+	LastScan     ScanMetadata
+	ScanSequence uint64
+
+	BatteryTopics *ScannerTopics
+	BatteryLevel  *int
+
+	DockTopics *ScannerTopics
+	Docked     *bool
+
+	ConnectivityTopics *ScannerTopics
+
+	ScanCountTopics *ScannerTopics
+	ScanRateTopics  *ScannerTopics
+
+	ParsedFields map[string]bool // Parsed fields with a discovered sensor
+
+	pendingDisconnect *time.Timer
 }
 
 type ScannerTopics struct {
+	BaseTopic         string // "~" of the discovery config, the other topics below are under it
 	ConfigTopic       string
 	StateTopic        string
 	AvailabilityTopic string
@@ -86,13 +183,17 @@ type ScannerTopics struct {
 }
 
 type BridgeEntity struct {
-	EntityType       string
-	Name             string
-	Icon             string
-	Retain           bool
-	GetStatus        func(*Integration) string
-	GetAttributes    func(*Integration) map[string]any
-	GetShutdownState func(*Integration) string
+	EntityType        string
+	Name              string
+	Icon              string
+	DeviceClass       string
+	UnitOfMeasurement string
+	StateClass        string
+	Retain            bool
+	Periodic          bool // Republished on a timer, its state changes without events
+	GetStatus         func(*Integration) string
+	GetAttributes     func(*Integration) map[string]any // Optional
+	GetShutdownState  func(*Integration) string         // Optional, the state is left as is on shutdown without it
 }
 
 type BridgeEntityManager struct {
@@ -101,27 +202,40 @@ type BridgeEntityManager struct {
 }
 
 func NewIntegration(
-	mqttClient *mqtt.Client,
+	mqttClient mqtt.Client,
 	haConfig *config.HomeAssistantConfig,
 	version string,
 	logger *logrus.Logger,
 ) *Integration {
 	integration := &Integration{
-		mqtt:           mqttClient,
-		config:         haConfig,
-		logger:         logger,
-		version:        version,
-		scanners:       make(map[string]*ScannerDevice),
-		scannerConfigs: make(map[string]*config.ScannerConfig),
+		mqtt:            mqttClient,
+		config:          haConfig,
+		logger:          logger,
+		version:         version,
+		scanners:        make(map[string]*ScannerDevice),
+		scannerConfigs:  make(map[string]*config.ScannerConfig),
+		badgePatterns:   make(map[string]*regexp.Regexp),
+		operators:       make(map[string]*operatorSession),
+		pantry:          make(map[string]*pantryState),
+		scanCounters:    make(map[string]*scanCounter),
+		readQualities:   make(map[string]*readQuality),
+		fastPathPending: make(map[string]bool),
+		published:       newScanDeduplicator(haConfig.DuplicateWindow),
+		stopCh:          make(chan struct{}),
+		createdAt:       time.Now(),
 	}
+	integration.clock = newClockMonitor(integration.createdAt)
+	integration.names = loadEntityNames(haConfig.Language, logger)
+	integration.topics = loadTopicTemplates(&haConfig.Topics, logger)
 
 	bridgeID := generateBridgeDeviceID(integration.config)
 	integration.bridgeDeviceInfo = &DeviceInfo{
-		Identifiers:  []string{bridgeID},
-		Name:         fmt.Sprintf("HA Barcode Bridge - %s", integration.config.InstanceID),
-		Model:        "https://github.com/miguelangel-nubla/homeassistant-barcode-scanner",
-		Manufacturer: "Miguel Angel Nubla",
-		SWVersion:    version,
+		Identifiers:      []string{bridgeID},
+		Name:             fmt.Sprintf("HA Barcode Bridge - %s", integration.config.InstanceID),
+		Model:            "https://github.com/miguelangel-nubla/homeassistant-barcode-scanner",
+		Manufacturer:     "Miguel Angel Nubla",
+		SWVersion:        version,
+		ConfigurationURL: haConfig.ConfigurationURL,
 	}
 
 	integration.bridgeEntities = &BridgeEntityManager{
@@ -129,21 +243,36 @@ func NewIntegration(
 		entities: []BridgeEntity{
 			{
 				EntityType: "diagnostics",
-				Name:       "Diagnostics",
+				Name:       integration.names.Diagnostics,
 				Icon:       "mdi:stethoscope",
 				Retain:     true,
 				GetStatus:  (*Integration).getScannerSummaryStatus,
 				GetAttributes: func(i *Integration) map[string]any {
-					return map[string]any{
+					attributes := map[string]any{
 						"connected_scanners": i.getConnectedScannerCount(),
 						"total_scanners":     len(i.scanners),
 						"scanner_list":       i.getScannerList(),
 					}
+					i.addMQTTDisconnectAttributes(attributes)
+					i.clock.addAttributes(attributes)
+					i.addRetainedAuditAttributes(attributes)
+					if i.environment != nil {
+						attributes["environment"] = i.environment
+					}
+					if i.sinkHealth != nil {
+						if health := i.sinkHealth(); health != nil {
+							attributes["sinks"] = health
+						}
+					}
+					return attributes
 				},
 				GetShutdownState: func(i *Integration) string { return StatusOffline },
 			},
 		},
 	}
+	if haConfig.ProcessStatsInterval > 0 {
+		integration.bridgeEntities.entities = append(integration.bridgeEntities.entities, integration.processStatsEntities()...)
+	}
 
 	return integration
 }
@@ -151,7 +280,7 @@ func NewIntegration(
 func (bem *BridgeEntityManager) publishAllDiscoveryConfigs() error {
 	for i := range bem.entities {
 		entity := &bem.entities[i]
-		if err := bem.integration.publishBridgeEntityDiscoveryConfig(entity.EntityType, entity.Name, entity.Icon); err != nil {
+		if err := bem.integration.publishBridgeEntityDiscoveryConfig(entity); err != nil {
 			bem.integration.logger.WithError(err).Errorf("Failed to publish %s discovery config", entity.Name)
 			return err
 		}
@@ -175,6 +304,10 @@ func (bem *BridgeEntityManager) publishEntityState(entity *BridgeEntity) error {
 		return err
 	}
 
+	if entity.GetAttributes == nil {
+		return nil
+	}
+
 	attributes := entity.GetAttributes(bem.integration)
 	attributesJSON, err := json.Marshal(attributes)
 	if err != nil {
@@ -187,6 +320,10 @@ func (bem *BridgeEntityManager) publishEntityState(entity *BridgeEntity) error {
 func (bem *BridgeEntityManager) publishOfflineStates() {
 	for i := range bem.entities {
 		entity := &bem.entities[i]
+		if entity.GetShutdownState == nil {
+			continue
+		}
+
 		topics, _ := bem.integration.generateBridgeEntityTopics(entity.EntityType)
 		shutdownState := entity.GetShutdownState(bem.integration)
 		if err := bem.integration.mqtt.Publish(topics.StateTopic, shutdownState, entity.Retain); err != nil {
@@ -205,21 +342,36 @@ func (integration *Integration) Start() error {
 		integration.handleConnect()
 	}
 
+	go integration.runScanRateUpdates()
+	go integration.runFastPathFlushes()
+	go integration.runClockChecks()
+	if integration.config.ProcessStatsInterval > 0 {
+		go integration.runProcessStatsUpdates()
+	}
+	if integration.config.UpdateCheck {
+		go integration.runUpdateChecks()
+	}
+
 	return nil
 }
 
 func (integration *Integration) Stop() error {
 	integration.logger.Info("Stopping Home Assistant integration")
 
+	close(integration.stopCh)
+
 	if integration.mqtt.IsConnected() {
+		integration.flushFastPath()
+		integration.scannersMutex.RLock()
 		for scannerID := range integration.scanners {
 			if err := integration.publishScannerAvailability(scannerID, "offline"); err != nil {
 				integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish offline status")
 			}
-			if err := integration.publishScannerState(scannerID, StatusUnknown); err != nil {
+			if err := integration.publishScannerState(scannerID, StatusUnknown, ScanMetadata{}); err != nil {
 				integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish unknown state")
 			}
 		}
+		integration.scannersMutex.RUnlock()
 
 		if err := integration.publishBridgeAvailability("offline"); err != nil {
 			integration.logger.WithError(err).Error("Failed to publish bridge offline status")
@@ -231,16 +383,56 @@ func (integration *Integration) Stop() error {
 	return nil
 }
 
+// SetEnvironment stores the platform report published with the bridge diagnostics.
+func (integration *Integration) SetEnvironment(environment map[string]any) {
+	integration.environment = environment
+}
+
+// SetSinkHealth sets the source of the sinks attribute of the bridge
+// diagnostics. The attribute is left out while health returns nil.
+func (integration *Integration) SetSinkHealth(health func() any) {
+	integration.sinkHealth = health
+}
+
+// AddScanner registers a scanner configuration. Reloads add and remove
+// scanners while the read loops of the others keep scanning, so the per-scanner
+// maps are written under scannersMutex, and the entry points of the scan path
+// and of the background loops hold its read lock. Helpers they call must not
+// take it again.
 func (integration *Integration) AddScanner(scannerID, scannerName string, scannerConfig *config.ScannerConfig) {
 	integration.logger.Debugf("Registering scanner configuration: %s", scannerID)
 
+	integration.scannersMutex.Lock()
 	integration.scannerConfigs[scannerID] = scannerConfig
+	integration.registerOperatorMode(scannerID)
+	integration.registerPantryMode(scannerID)
+	if _, exists := integration.scanCounters[scannerID]; !exists {
+		integration.scanCounters[scannerID] = &scanCounter{}
+	}
+	if _, exists := integration.readQualities[scannerID]; !exists {
+		integration.readQualities[scannerID] = &readQuality{}
+	}
+	integration.scannersMutex.Unlock()
+
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+	// Scanners added after the MQTT connection, e.g. by a config reload,
+	// miss the subscriptions made in handleConnect.
+	if _, enabled := integration.pantry[scannerID]; enabled && integration.mqtt.IsConnected() {
+		integration.subscribePantryMode(scannerID)
+	}
+	if integration.mqtt.IsConnected() {
+		integration.subscribeScannerSwitch(scannerID)
+		integration.subscribeScanTimeout(scannerID)
+		integration.subscribeKeyboardLayout(scannerID)
+	}
 	integration.logger.Debugf("Stored config for scanner %s, will create HA device when hardware connects", scannerID)
 }
 
 func (integration *Integration) RemoveScanner(scannerID string) {
 	integration.logger.Debugf("Removing scanner from Home Assistant integration: %s", scannerID)
 
+	integration.scannersMutex.RLock()
 	if integration.mqtt.IsConnected() {
 		scanner := integration.scanners[scannerID]
 		if scanner != nil {
@@ -249,15 +441,85 @@ func (integration *Integration) RemoveScanner(scannerID string) {
 			}
 		}
 	}
+	integration.scannersMutex.RUnlock()
 
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+	integration.operatorsMutex.Lock()
+	delete(integration.operators, scannerID)
+	integration.operatorsMutex.Unlock()
 	delete(integration.scanners, scannerID)
 	delete(integration.scannerConfigs, scannerID)
+	delete(integration.badgePatterns, scannerID)
+	delete(integration.pantry, scannerID)
+	delete(integration.scanCounters, scannerID)
+	delete(integration.readQualities, scannerID)
+}
+
+// UnpublishScanner clears the retained discovery configs of every entity a
+// scanner may have created, so Home Assistant deletes them. Used when a
+// scanner is removed from the configuration.
+func (integration *Integration) UnpublishScanner(scannerID string) error {
+	if !integration.mqtt.IsConnected() {
+		return fmt.Errorf("MQTT not connected")
+	}
+
+	topics := []*ScannerTopics{
+		integration.generateScannerComponentTopics(config.EntityPlatformSensor, scannerID, ""),
+		integration.generateScannerComponentTopics(config.EntityPlatformEvent, scannerID, ""),
+		integration.generateScannerComponentTopics("binary_sensor", scannerID, "dock"),
+		integration.generateScannerComponentTopics("binary_sensor", scannerID, connectivitySuffix),
+		integration.generateScannerComponentTopics("select", scannerID, pantryModeSuffix),
+		integration.generateScanTriggerTopics(scannerID),
+		integration.generateScannerSwitchTopics(scannerID),
+		integration.generateScanTimeoutTopics(scannerID),
+		integration.generateKeyboardLayoutTopics(scannerID),
+	}
+	for _, suffix := range []string{"health", "battery", "scans", "scan_rate", readQualitySuffix, symbologySuffix} {
+		topics = append(topics, integration.generateScannerSubEntityTopics(scannerID, suffix))
+	}
+	if scanner := integration.scanners[scannerID]; scanner != nil {
+		for field := range scanner.ParsedFields {
+			topics = append(topics, integration.generateScannerSubEntityTopics(scannerID, parsedFieldEntitySuffix(field)))
+		}
+	}
+
+	for _, entityTopics := range topics {
+		if err := integration.mqtt.Publish(entityTopics.ConfigTopic, "", true); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", entityTopics.ConfigTopic, err)
+		}
+	}
+	return nil
 }
 
 func (integration *Integration) SetScannerDeviceInfo(scannerID string, deviceInfo *hid.DeviceInfo) {
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+
+	if !integration.storeScannerDevice(scannerID, deviceInfo) {
+		return
+	}
+
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+	integration.publishScannerDevice(scannerID)
+}
+
+// storeScannerDevice registers the Home Assistant device of a scanner whose
+// hardware connected. It reports whether the device has to be published.
+func (integration *Integration) storeScannerDevice(scannerID string, deviceInfo *hid.DeviceInfo) bool {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
 	if _, exists := integration.scannerConfigs[scannerID]; !exists {
 		integration.logger.Errorf("Scanner config %s not found, cannot create HA device", scannerID)
-		return
+		return false
+	}
+
+	existing := integration.scanners[scannerID]
+	if existing != nil && existing.pendingDisconnect != nil {
+		// Reconnected within the debounce window: keep the device as published.
+		return false
 	}
 
 	displayName := strings.TrimSpace(deviceInfo.Manufacturer)
@@ -270,7 +532,7 @@ func (integration *Integration) SetScannerDeviceInfo(scannerID string, deviceInf
 	}
 
 	if displayName == "" {
-		displayName = fmt.Sprintf("Scanner %s", scannerID)
+		displayName = fmt.Sprintf("%s %s", integration.names.Scanner, scannerID)
 	}
 
 	bridgeID := generateBridgeDeviceID(integration.config)
@@ -278,17 +540,16 @@ func (integration *Integration) SetScannerDeviceInfo(scannerID string, deviceInf
 
 	now := time.Now()
 	scanner := &ScannerDevice{
-		ID:           scannerID,
-		Name:         displayName,
-		Connected:    false,
-		Topics:       integration.generateScannerTopics(scannerID),
-		HealthTopics: integration.generateScannerHealthTopics(scannerID),
+		ID:        scannerID,
+		Name:      displayName,
+		Connected: false,
 		DeviceInfo: &DeviceInfo{
-			Identifiers:  []string{scannerDeviceID},
-			Name:         displayName,
-			Model:        strings.TrimSpace(deviceInfo.Product),
-			Manufacturer: strings.TrimSpace(deviceInfo.Manufacturer),
-			ViaDevice:    bridgeID,
+			Identifiers:      []string{scannerDeviceID},
+			Name:             displayName,
+			Model:            strings.TrimSpace(deviceInfo.Product),
+			Manufacturer:     strings.TrimSpace(deviceInfo.Manufacturer),
+			ViaDevice:        bridgeID,
+			ConfigurationURL: integration.config.ConfigurationURL,
 		},
 		Health: &ScannerHealthMetrics{
 			LastSeen:       now,
@@ -297,12 +558,20 @@ func (integration *Integration) SetScannerDeviceInfo(scannerID string, deviceInf
 			TotalScans:     0,
 		},
 	}
+	if existing != nil {
+		scanner.Health = existing.Health
+		scanner.ParsedFields = existing.ParsedFields
+	}
+	integration.assignScannerTopics(scanner)
 
 	integration.scanners[scannerID] = scanner
 
 	integration.logger.Infof("Created HA device for scanner %s: %s %s (VID:PID %04x:%04x)",
 		scannerID, deviceInfo.Manufacturer, deviceInfo.Product, deviceInfo.VendorID, deviceInfo.ProductID)
+	return true
+}
 
+func (integration *Integration) publishScannerDevice(scannerID string) {
 	if integration.mqtt.IsConnected() {
 		if err := integration.publishScannerAvailability(scannerID, "offline"); err != nil {
 			integration.logger.Errorf("Failed to publish initial availability for scanner %s: %v", scannerID, err)
@@ -314,6 +583,29 @@ func (integration *Integration) SetScannerDeviceInfo(scannerID string, deviceInf
 		if err := integration.publishScannerHealthDiscoveryConfig(scannerID); err != nil {
 			integration.logger.Errorf("Failed to publish health discovery config for scanner %s: %v", scannerID, err)
 		}
+		if err := integration.publishScannerConnectivityDiscoveryConfig(scannerID); err != nil {
+			integration.logger.Errorf("Failed to publish connectivity discovery config for scanner %s: %v", scannerID, err)
+		}
+		if err := integration.publishScannerConnectivityState(scannerID); err != nil {
+			integration.logger.Errorf("Failed to publish initial connectivity for scanner %s: %v", scannerID, err)
+		}
+		if err := integration.publishScannerCounterDiscoveryConfigs(scannerID); err != nil {
+			integration.logger.Errorf("Failed to publish scan counter discovery configs for scanner %s: %v", scannerID, err)
+		}
+		if err := integration.publishScannerSymbologyDiscoveryConfig(scannerID); err != nil {
+			integration.logger.Errorf("Failed to publish symbology discovery config for scanner %s: %v", scannerID, err)
+		}
+		if err := integration.publishScannerTriggerDiscoveryConfig(scannerID); err != nil {
+			integration.logger.Errorf("Failed to publish device trigger discovery config for scanner %s: %v", scannerID, err)
+		}
+		if err := integration.publishScannerControlDiscoveryConfigs(scannerID); err != nil {
+			integration.logger.Errorf("Failed to publish control discovery configs for scanner %s: %v", scannerID, err)
+		}
+		if _, enabled := integration.pantry[scannerID]; enabled {
+			if err := integration.publishPantryModeDiscoveryConfig(scannerID); err != nil {
+				integration.logger.Errorf("Failed to publish pantry mode discovery config for scanner %s: %v", scannerID, err)
+			}
+		}
 		// Publish static attributes once during initialization to avoid duplicate HA state changes on each scan
 		if err := integration.publishScannerAttributes(scannerID); err != nil {
 			integration.logger.Errorf("Failed to publish initial attributes for scanner %s: %v", scannerID, err)
@@ -322,11 +614,57 @@ func (integration *Integration) SetScannerDeviceInfo(scannerID string, deviceInf
 }
 
 func (integration *Integration) SetScannerConnected(scannerID string, connected bool) error {
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
 	scanner, exists := integration.scanners[scannerID]
 	if !exists {
 		return fmt.Errorf("scanner %s not found", scannerID)
 	}
 
+	debounce := integration.config.DisconnectDebounce
+	if connected && scanner.pendingDisconnect != nil {
+		scanner.pendingDisconnect.Stop()
+		scanner.pendingDisconnect = nil
+		scanner.Health.FlapCount++
+		integration.logger.WithField("scanner_id", scannerID).Debugf("Scanner reconnected within %s, not reporting disconnect", debounce)
+		return integration.publishScannerHealthState(scannerID)
+	}
+	if !connected && scanner.Connected && debounce > 0 {
+		if scanner.pendingDisconnect == nil {
+			scanner.pendingDisconnect = time.AfterFunc(debounce, func() {
+				integration.reportDebouncedDisconnect(scannerID)
+			})
+		}
+		return nil
+	}
+
+	return integration.applyScannerConnected(scanner, connected)
+}
+
+// reportDebouncedDisconnect publishes a disconnect that outlasted the debounce window.
+func (integration *Integration) reportDebouncedDisconnect(scannerID string) {
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.pendingDisconnect == nil {
+		return
+	}
+	scanner.pendingDisconnect = nil
+
+	if err := integration.applyScannerConnected(scanner, false); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to report scanner disconnect")
+	}
+}
+
+func (integration *Integration) applyScannerConnected(scanner *ScannerDevice, connected bool) error {
+	scannerID := scanner.ID
+
 	now := time.Now()
 	scanner.Health.LastSeen = now
 	if connected && !scanner.Connected {
@@ -342,7 +680,7 @@ func (integration *Integration) SetScannerConnected(scannerID string, connected
 
 	scanner.Connected = connected
 
-	if err := integration.publishScannerState(scannerID, StatusUnknown); err != nil {
+	if err := integration.publishScannerState(scannerID, StatusUnknown, ScanMetadata{}); err != nil {
 		return err
 	}
 	if err := integration.publishScannerAttributes(scannerID); err != nil {
@@ -359,6 +697,9 @@ func (integration *Integration) SetScannerConnected(scannerID string, connected
 	if err := integration.publishScannerAvailability(scannerID, availabilityStatus); err != nil {
 		return err
 	}
+	if err := integration.publishScannerConnectivityState(scannerID); err != nil {
+		return err
+	}
 
 	if err := integration.publishScannerHealthState(scannerID); err != nil {
 		integration.logger.WithError(err).Errorf("Failed to publish health state for scanner %s", scannerID)
@@ -368,10 +709,29 @@ func (integration *Integration) SetScannerConnected(scannerID string, connected
 	return nil
 }
 
-func (integration *Integration) PublishBarcode(scannerID, barcode string) error {
+// ScanMetadata identifies a published scan.
+type ScanMetadata struct {
+	ID            string    // Unique per scan
+	CorrelationID string    // Of the expect_scan request the scan answered
+	Sequence      uint64    // Per scanner since the bridge started, set by PublishBarcode
+	Time          time.Time // When the scan was read, set to the publish time by PublishBarcode when zero
+	// Attributes of the scan added by pipeline stages, e.g. valid: false
+	// from a validate stage flagging invalid scans
+	Attributes map[string]string
+}
+
+// PublishBarcode publishes a scan to Home Assistant. A scan ID published
+// within the duplicate_window is skipped; an empty one is always published.
+func (integration *Integration) PublishBarcode(scannerID, barcode string, metadata ScanMetadata) error {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
 	scanner, exists := integration.scanners[scannerID]
 	if !exists {
-		return fmt.Errorf("scanner %s not found", scannerID)
+		if _, configured := integration.scannerConfigs[scannerID]; configured {
+			return fmt.Errorf("%w: %s", ErrScannerNotRegistered, scannerID)
+		}
+		return fmt.Errorf("%w: %s", ErrScannerNotFound, scannerID)
 	}
 
 	if !integration.mqtt.IsConnected() {
@@ -379,16 +739,63 @@ func (integration *Integration) PublishBarcode(scannerID, barcode string) error
 	}
 
 	now := time.Now()
-	scanner.Health.LastSeen = now
-	scanner.Health.LastScanTime = &now
-	scanner.Health.TotalScans++
+	if !integration.published.claim(metadata.ID, now) {
+		integration.logger.WithFields(map[string]any{
+			"scanner_id": scannerID,
+			"scan_id":    metadata.ID,
+		}).Info("Skipping scan already published")
+		return nil
+	}
+
+	metadata.Sequence = scanner.ScanSequence + 1
+	if metadata.Time.IsZero() {
+		metadata.Time = now
+	}
+	// Plain sensors carry the correlation ID and scan attributes in their
+	// attributes, which are published again to add them or to clear them
+	// after such a scan.
+	correlationChanged := metadata.CorrelationID != "" || scanner.LastScan.CorrelationID != ""
+	attributesChanged := len(metadata.Attributes) > 0 || len(scanner.LastScan.Attributes) > 0
+
+	if integration.touchOperator(scannerID, now) || correlationChanged || attributesChanged || integration.hasScanDetails(scannerID) {
+		if err := integration.publishScanAttributes(scanner, metadata); err != nil {
+			integration.logger.WithError(err).Errorf("Failed to update attributes after scan for scanner %s", scannerID)
+		}
+	}
 
 	// Only publish state on barcode scan to prevent duplicate Home Assistant state change events.
 	// Attributes are published once during scanner initialization, not on every scan.
-	if err := integration.publishScannerState(scannerID, barcode); err != nil {
+	var err error
+	if integration.isFastPath(scannerID) {
+		err = integration.publishBarcodeFast(scannerID, barcode, metadata)
+	} else {
+		err = integration.publishScannerState(scannerID, barcode, metadata)
+	}
+	if err != nil {
+		integration.published.release(metadata.ID)
 		return err
 	}
 
+	// The scan is counted only once published, a failed one is queued and
+	// published again with the same sequence number.
+	scanner.Health.LastSeen = now
+	scanner.Health.LastScanTime = &metadata.Time
+	scanner.Health.TotalScans++
+	scanner.ScanSequence = metadata.Sequence
+	scanner.LastBarcode = barcode
+	scanner.LastScan = metadata
+	integration.publishRawBarcode(scannerID, barcode)
+	integration.publishScanTrigger(scannerID, barcode)
+	integration.publishParsedFields(scanner, metadata)
+
+	if integration.isCoalesced(scannerID) {
+		integration.deferScanUpdates(scannerID, now)
+		return nil
+	}
+
+	integration.recordScan(scannerID, now)
+	integration.publishSymbology(scannerID, scanSymbology(barcode, metadata))
+
 	if err := integration.publishScannerHealthState(scannerID); err != nil {
 		integration.logger.WithError(err).Errorf("Failed to update health state after scan for scanner %s", scannerID)
 	}
@@ -396,6 +803,31 @@ func (integration *Integration) PublishBarcode(scannerID, barcode string) error
 	return nil
 }
 
+func (integration *Integration) SetScannerBatteryLevel(scannerID string, level int) error {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists {
+		return fmt.Errorf("scanner %s not found", scannerID)
+	}
+
+	firstReport := scanner.BatteryLevel == nil
+	scanner.BatteryLevel = &level
+
+	if !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	if firstReport {
+		if err := integration.publishScannerBatteryDiscoveryConfig(scannerID); err != nil {
+			return err
+		}
+	}
+
+	return integration.mqtt.Publish(scanner.BatteryTopics.StateTopic, fmt.Sprintf("%d", level), true)
+}
+
 func (integration *Integration) GenerateBridgeAvailabilityTopic() string {
 	bridgeID := generateBridgeDeviceID(integration.config)
 	return fmt.Sprintf("%s/sensor/%s/availability", integration.config.DiscoveryPrefix, bridgeID)
@@ -406,36 +838,121 @@ func GenerateBridgeAvailabilityTopic(haConfig *config.HomeAssistantConfig) strin
 	return fmt.Sprintf("%s/sensor/%s/availability", haConfig.DiscoveryPrefix, bridgeID)
 }
 
+func GenerateBridgeLogTopic(haConfig *config.HomeAssistantConfig) string {
+	bridgeID := generateBridgeDeviceID(haConfig)
+	return fmt.Sprintf("%s/sensor/%s/log", haConfig.DiscoveryPrefix, bridgeID)
+}
+
+// GenerateFailoverTopic returns the topic the bridges of a failover pair
+// exchange heartbeats on.
+func GenerateFailoverTopic(haConfig *config.HomeAssistantConfig) string {
+	bridgeID := generateBridgeDeviceID(haConfig)
+	return fmt.Sprintf("%s/sensor/%s/failover", haConfig.DiscoveryPrefix, bridgeID)
+}
+
 func generateBridgeDeviceID(haConfig *config.HomeAssistantConfig) string {
 	return fmt.Sprintf("ha-barcode-bridge-%s", haConfig.InstanceID)
 }
 
+// scannerObjectID returns the object_id of a scanner entity. The suffix
+// distinguishes the additional entities of the scanner and is empty for the
+// main barcode sensor.
+func (integration *Integration) scannerObjectID(scannerID, suffix string) string {
+	objectID := integration.expandIDTemplate(integration.config.ObjectIDTemplate, config.DefaultObjectIDTemplate, scannerID)
+	if scannerCfg, exists := integration.scannerConfigs[scannerID]; exists && scannerCfg.ObjectID != "" {
+		objectID = scannerCfg.ObjectID
+	}
+	if suffix != "" {
+		objectID += "_" + suffix
+	}
+	return objectID
+}
+
+// ScannerEntityID returns the entity ID Home Assistant derives from the
+// object_id of the main barcode sensor of a scanner.
+func (integration *Integration) ScannerEntityID(scannerID string) string {
+	return integration.scannerPlatform(scannerID) + "." + slugify(integration.scannerObjectID(scannerID, ""))
+}
+
+// ScannerTopics returns the topics of the main barcode entity of a scanner.
+func (integration *Integration) ScannerTopics(scannerID string) *ScannerTopics {
+	return integration.generateScannerTopics(scannerID)
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify approximates the Home Assistant slugify used for entity IDs.
+func slugify(text string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(text), "_"), "_")
+}
+
+// scannerUniqueID returns the unique_id of a scanner entity, see scannerObjectID.
+func (integration *Integration) scannerUniqueID(scannerID, suffix string) string {
+	uniqueID := integration.expandIDTemplate(integration.config.UniqueIDTemplate, config.DefaultUniqueIDTemplate, scannerID)
+	if scannerCfg, exists := integration.scannerConfigs[scannerID]; exists && scannerCfg.UniqueID != "" {
+		uniqueID = scannerCfg.UniqueID
+	}
+	if suffix != "" {
+		uniqueID += "-" + suffix
+	}
+	return uniqueID
+}
+
+func (integration *Integration) expandIDTemplate(template, fallback, scannerID string) string {
+	if template == "" {
+		template = fallback
+	}
+	return strings.NewReplacer(
+		"{instance}", integration.config.InstanceID,
+		"{bridge}", generateBridgeDeviceID(integration.config),
+		config.IDTemplateScanner, scannerID,
+	).Replace(template)
+}
+
 func (integration *Integration) generateScannerDeviceID(scannerID string) string {
 	bridgeID := generateBridgeDeviceID(integration.config)
 	return fmt.Sprintf("%s-scanner-%s", bridgeID, scannerID)
 }
 
 func (integration *Integration) generateScannerTopics(scannerID string) *ScannerTopics {
-	bridgeID := generateBridgeDeviceID(integration.config)
-	entityID := fmt.Sprintf("%s-scanner-%s", bridgeID, scannerID)
+	return integration.generateScannerComponentTopics(integration.scannerPlatform(scannerID), scannerID, "")
+}
 
-	return &ScannerTopics{
-		ConfigTopic:       fmt.Sprintf("%s/sensor/%s/config", integration.config.DiscoveryPrefix, entityID),
-		StateTopic:        fmt.Sprintf("%s/sensor/%s/state", integration.config.DiscoveryPrefix, entityID),
-		AvailabilityTopic: fmt.Sprintf("%s/sensor/%s/availability", integration.config.DiscoveryPrefix, entityID),
-		AttributesTopic:   fmt.Sprintf("%s/sensor/%s/attributes", integration.config.DiscoveryPrefix, entityID),
-	}
+// assignScannerTopics stores the topics of every entity of the scanner
+// under the current discovery prefix.
+func (integration *Integration) assignScannerTopics(scanner *ScannerDevice) {
+	scannerID := scanner.ID
+	scanner.Topics = integration.generateScannerTopics(scannerID)
+	scanner.HealthTopics = integration.generateScannerHealthTopics(scannerID)
+	scanner.BatteryTopics = integration.generateScannerSubEntityTopics(scannerID, "battery")
+	scanner.DockTopics = integration.generateScannerComponentTopics("binary_sensor", scannerID, "dock")
+	scanner.ConnectivityTopics = integration.generateScannerComponentTopics("binary_sensor", scannerID, connectivitySuffix)
+	scanner.ScanCountTopics = integration.generateScannerSubEntityTopics(scannerID, "scans")
+	scanner.ScanRateTopics = integration.generateScannerSubEntityTopics(scannerID, "scan_rate")
 }
 
 func (integration *Integration) generateScannerHealthTopics(scannerID string) *ScannerTopics {
+	return integration.generateScannerSubEntityTopics(scannerID, "health")
+}
+
+func (integration *Integration) generateScannerSubEntityTopics(scannerID, suffix string) *ScannerTopics {
+	return integration.generateScannerComponentTopics("sensor", scannerID, suffix)
+}
+
+// generateScannerComponentTopics renders the topics of a scanner entity from
+// the topic templates. suffix names the entity and is empty for the barcode
+// sensor.
+func (integration *Integration) generateScannerComponentTopics(component, scannerID, suffix string) *ScannerTopics {
 	bridgeID := generateBridgeDeviceID(integration.config)
-	entityID := fmt.Sprintf("%s-scanner-%s-health", bridgeID, scannerID)
+	data := config.NewTopicTemplateData(integration.config.DiscoveryPrefix, component, bridgeID, scannerID, suffix)
+	baseTopic := integration.topics.renderState(data, integration.logger)
 
 	return &ScannerTopics{
-		ConfigTopic:       fmt.Sprintf("%s/sensor/%s/config", integration.config.DiscoveryPrefix, entityID),
-		StateTopic:        fmt.Sprintf("%s/sensor/%s/state", integration.config.DiscoveryPrefix, entityID),
-		AvailabilityTopic: fmt.Sprintf("%s/sensor/%s/availability", integration.config.DiscoveryPrefix, entityID),
-		AttributesTopic:   fmt.Sprintf("%s/sensor/%s/attributes", integration.config.DiscoveryPrefix, entityID),
+		BaseTopic:         baseTopic,
+		ConfigTopic:       integration.topics.renderDiscovery(data, integration.logger) + "/config",
+		StateTopic:        baseTopic + "/state",
+		AvailabilityTopic: baseTopic + "/availability",
+		AttributesTopic:   baseTopic + "/attributes",
 	}
 }
 
@@ -455,22 +972,141 @@ func (integration *Integration) getScannerSummaryStatus() string {
 func (integration *Integration) handleConnect() {
 	integration.logger.Info("MQTT connected, publishing bridge availability and discovery configs")
 
+	// Runs on the MQTT client's goroutine, while scanners keep connecting
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
 	if err := integration.bridgeEntities.publishAllDiscoveryConfigs(); err != nil {
 		integration.logger.WithError(err).Error("Failed to publish bridge entity discovery configs")
 	}
 
-	for scannerID := range integration.scanners {
+	for scannerID, scanner := range integration.scanners {
 		if err := integration.publishScannerDiscoveryConfig(scannerID); err != nil {
 			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish discovery config")
 		}
 		if err := integration.publishScannerHealthDiscoveryConfig(scannerID); err != nil {
 			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish health discovery config")
 		}
+		if err := integration.publishScannerConnectivityDiscoveryConfig(scannerID); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish connectivity discovery config")
+		}
+		if scanner.BatteryLevel != nil {
+			if err := integration.publishScannerBatteryDiscoveryConfig(scannerID); err != nil {
+				integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish battery discovery config")
+			}
+		}
+		if scanner.Docked != nil {
+			if err := integration.publishScannerDockDiscoveryConfig(scannerID); err != nil {
+				integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish dock discovery config")
+			}
+		}
+		if err := integration.publishScannerCounterDiscoveryConfigs(scannerID); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish scan counter discovery configs")
+		}
+		if err := integration.publishScannerSymbologyDiscoveryConfig(scannerID); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish symbology discovery config")
+		}
+		for field := range scanner.ParsedFields {
+			if err := integration.publishParsedFieldDiscoveryConfig(scanner, field); err != nil {
+				integration.logger.WithField("scanner_id", scannerID).WithError(err).Errorf("Failed to publish discovery config of parsed field %s", field)
+			}
+		}
+		if err := integration.publishScannerTriggerDiscoveryConfig(scannerID); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish device trigger discovery config")
+		}
+		if err := integration.publishScannerControlDiscoveryConfigs(scannerID); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish scanner control discovery configs")
+		}
+		if _, enabled := integration.pantry[scannerID]; enabled {
+			if err := integration.publishPantryModeDiscoveryConfig(scannerID); err != nil {
+				integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish pantry mode discovery config")
+			}
+		}
+		integration.restoreRetainedScannerStates(scannerID)
 	}
 
+	integration.subscribeScanCountResets()
+	integration.subscribePantryModes()
+	integration.subscribeScannerSwitches()
+	integration.subscribeScanTimeouts()
+	integration.subscribeKeyboardLayouts()
+	integration.setupPauseSwitch()
+	integration.setupRestartButton()
+	integration.setupUpdateEntity()
+	integration.setupScanRelay()
+	integration.publishAllScanCounts()
+	integration.publishAllReadQualities()
+
 	if err := integration.publishBridgeAvailability("online"); err != nil {
 		integration.logger.WithError(err).Error("Failed to publish bridge availability")
 	}
+
+	if integration.config.RepublishLastState {
+		integration.republishLastStates()
+	}
+
+	integration.bridgeEntities.publishAllStates()
+	integration.startRetainedAudit()
+}
+
+// republishLastStates restores the non-retained scanner state and attributes
+// from memory so entities don't show unknown after every broker restart. Only
+// the state is published, not the raw barcode or the device trigger, and the
+// sensor is then discovered without force_update, so Home Assistant doesn't
+// record the last barcode as a new scan. Called by handleConnect with
+// scannersMutex held.
+func (integration *Integration) republishLastStates() {
+	for scannerID, scanner := range integration.scanners {
+		logger := integration.logger.WithField("scanner_id", scannerID)
+
+		if err := integration.publishScannerAttributes(scannerID); err != nil {
+			logger.WithError(err).Error("Failed to republish attributes")
+		}
+
+		// Republishing would fire the last scan event again
+		if scanner.LastBarcode == "" || integration.usesEventEntity(scannerID) {
+			continue
+		}
+		// Nor bring back a scan Home Assistant already expired
+		if integration.lastScanExpired(scannerID, time.Now()) {
+			continue
+		}
+		if err := integration.publishScannerState(scannerID, scanner.LastBarcode, scanner.LastScan); err != nil {
+			logger.WithError(err).Error("Failed to republish last barcode")
+		} else {
+			logger.Debug("Republished last barcode after MQTT reconnect")
+		}
+	}
+}
+
+// lastScanExpired reports whether the last scan is older than the
+// expire_after of the scanner.
+func (integration *Integration) lastScanExpired(scannerID string, now time.Time) bool {
+	scannerCfg, exists := integration.scannerConfigs[scannerID]
+	lastScan := integration.scanners[scannerID].Health.LastScanTime
+	if !exists || scannerCfg.ExpireAfter <= 0 || lastScan == nil {
+		return false
+	}
+	return now.Sub(*lastScan) >= scannerCfg.ExpireAfter
+}
+
+// loadEntityNames falls back to English for a language the config
+// validation would have rejected.
+func loadEntityNames(language string, logger *logrus.Logger) *translations.EntityNames {
+	names, err := translations.LoadEntityNames(language)
+	if err != nil {
+		logger.WithError(err).Warn("Using English entity names")
+		names, _ = translations.LoadEntityNames(translations.DefaultLanguage)
+	}
+	return names
+}
+
+// scannerEntityName prefixes a translated entity name with the scanner
+// device name, so entities stay distinguishable across scanners.
+func (integration *Integration) scannerEntityName(scanner *ScannerDevice, name string) string {
+	return fmt.Sprintf("%s %s", scanner.Name, name)
 }
 
 func (integration *Integration) handleDisconnect() {
@@ -483,34 +1119,36 @@ func (integration *Integration) publishScannerDiscoveryConfig(scannerID string)
 		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
 	}
 
-	bridgeID := generateBridgeDeviceID(integration.config)
-
-	sensorName := scanner.Name
-	if sensorName == "" {
-		sensorName = scannerID
+	if err := integration.clearOtherScannerPlatform(scannerID); err != nil {
+		return err
+	}
+	if integration.usesEventEntity(scannerID) {
+		return integration.publishScannerEventDiscoveryConfig(scanner)
 	}
-
-	baseTopic := fmt.Sprintf("%s/sensor/%s-scanner-%s", integration.config.DiscoveryPrefix, bridgeID, scannerID)
 
 	sensorConfig := SensorConfig{
-		Name:            sensorName,
-		ObjectID:        fmt.Sprintf("%s_%s", integration.config.InstanceID, scannerID),
-		UniqueID:        fmt.Sprintf("%s-scanner-%s", bridgeID, scannerID),
-		TildeTopic:      baseTopic,
+		Name:            integration.scannerEntityName(scanner, integration.names.LastScan),
+		ObjectID:        integration.scannerObjectID(scannerID, ""),
+		UniqueID:        integration.scannerUniqueID(scannerID, ""),
+		TildeTopic:      scanner.Topics.BaseTopic,
 		StateTopic:      "~/state",
 		AttributesTopic: "~/attributes",
-		Availability: []AvailabilityConfig{
-			{
-				Topic: "~/availability",
-			},
-			{
-				Topic: integration.GenerateBridgeAvailabilityTopic(),
-			},
-		},
-		AvailabilityMode: "all",
-		Device:           scanner.DeviceInfo,
-		Icon:             "mdi:barcode-scan",
-		ForceUpdate:      true,
+		Device:          scanner.DeviceInfo,
+		Icon:            "mdi:barcode-scan",
+		// Records repeated scans of the same barcode, unless the last
+		// barcode is republished after reconnects
+		ForceUpdate: !integration.config.RepublishLastState,
+	}
+
+	sensorConfig.Availability, sensorConfig.AvailabilityMode = integration.scannerAvailability("~/availability")
+
+	if scannerCfg, exists := integration.scannerConfigs[scannerID]; exists {
+		if scannerCfg.UsesJSONState() {
+			sensorConfig.ValueTemplate = JSONStateValueTemplate
+			sensorConfig.AttributesTopic = "~/state"
+			sensorConfig.AttributesTemplate = JSONStateAttributesTemplate
+		}
+		sensorConfig.ExpireAfter = int(scannerCfg.ExpireAfter / time.Second)
 	}
 
 	configJSON, err := json.Marshal(sensorConfig)
@@ -527,15 +1165,11 @@ func (integration *Integration) publishScannerHealthDiscoveryConfig(scannerID st
 		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
 	}
 
-	bridgeID := generateBridgeDeviceID(integration.config)
-	healthName := fmt.Sprintf("%s Health", scanner.Name)
-	baseTopic := fmt.Sprintf("%s/sensor/%s-scanner-%s-health", integration.config.DiscoveryPrefix, bridgeID, scannerID)
-
 	sensorConfig := SensorConfig{
-		Name:            healthName,
-		ObjectID:        fmt.Sprintf("%s_%s_health", integration.config.InstanceID, scannerID),
-		UniqueID:        fmt.Sprintf("%s-scanner-%s-health", bridgeID, scannerID),
-		TildeTopic:      baseTopic,
+		Name:            integration.scannerEntityName(scanner, integration.names.Health),
+		ObjectID:        integration.scannerObjectID(scannerID, "health"),
+		UniqueID:        integration.scannerUniqueID(scannerID, "health"),
+		TildeTopic:      scanner.HealthTopics.BaseTopic,
 		StateTopic:      "~/state",
 		AttributesTopic: "~/attributes",
 		Availability: []AvailabilityConfig{
@@ -557,6 +1191,52 @@ func (integration *Integration) publishScannerHealthDiscoveryConfig(scannerID st
 	return integration.mqtt.Publish(scanner.HealthTopics.ConfigTopic, string(configJSON), true)
 }
 
+func (integration *Integration) publishScannerBatteryDiscoveryConfig(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	sensorConfig := SensorConfig{
+		Name:              integration.scannerEntityName(scanner, integration.names.Battery),
+		ObjectID:          integration.scannerObjectID(scannerID, "battery"),
+		UniqueID:          integration.scannerUniqueID(scannerID, "battery"),
+		TildeTopic:        scanner.BatteryTopics.BaseTopic,
+		StateTopic:        "~/state",
+		Device:            scanner.DeviceInfo,
+		EntityCategory:    "diagnostic",
+		DeviceClass:       "battery",
+		UnitOfMeasurement: "%",
+		StateClass:        "measurement",
+	}
+
+	sensorConfig.Availability, sensorConfig.AvailabilityMode = integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	configJSON, err := json.Marshal(sensorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal battery discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(scanner.BatteryTopics.ConfigTopic, string(configJSON), true)
+}
+
+// scannerAvailability returns the availability list and mode for scanner
+// entities according to the configured coupling with the bridge availability.
+func (integration *Integration) scannerAvailability(scannerTopic string) (availability []AvailabilityConfig, mode string) {
+	availability = []AvailabilityConfig{{Topic: scannerTopic}}
+
+	mode = integration.config.AvailabilityMode
+	if mode == config.AvailabilityModeScanner {
+		return availability, ""
+	}
+	if mode == "" {
+		mode = config.AvailabilityModeAll
+	}
+
+	availability = append(availability, AvailabilityConfig{Topic: integration.GenerateBridgeAvailabilityTopic()})
+	return availability, mode
+}
+
 func (integration *Integration) publishBridgeAvailability(status string) error {
 	topic := integration.GenerateBridgeAvailabilityTopic()
 	return integration.mqtt.Publish(topic, status, true)
@@ -571,13 +1251,23 @@ func (integration *Integration) publishScannerAvailability(scannerID, status str
 	return integration.mqtt.Publish(scanner.Topics.AvailabilityTopic, status, true)
 }
 
-func (integration *Integration) publishScannerState(scannerID, state string) error {
+func (integration *Integration) publishScannerState(scannerID, state string, metadata ScanMetadata) error {
 	scanner, exists := integration.scanners[scannerID]
 	if !exists {
 		return fmt.Errorf("scanner %s not found", scannerID)
 	}
 
-	return integration.mqtt.Publish(scanner.Topics.StateTopic, state, false)
+	if state == StatusUnknown && integration.usesEventEntity(scannerID) {
+		// Events have no state to reset
+		return nil
+	}
+
+	payload, err := integration.formatScannerState(scannerID, state, metadata)
+	if err != nil {
+		return err
+	}
+
+	return integration.mqtt.Publish(scanner.Topics.StateTopic, payload, false)
 }
 
 func (integration *Integration) publishScannerAttributes(scannerID string) error {
@@ -586,21 +1276,72 @@ func (integration *Integration) publishScannerAttributes(scannerID string) error
 		return fmt.Errorf("scanner %s not found", scannerID)
 	}
 
-	attributes := map[string]any{
+	return integration.publishScanAttributes(scanner, scanner.LastScan)
+}
+
+// publishScanAttributes publishes the attributes of a plain sensor for the
+// given last scan, before the scan itself is published.
+func (integration *Integration) publishScanAttributes(scanner *ScannerDevice, lastScan ScanMetadata) error {
+	attributesJSON, err := json.Marshal(integration.scannerAttributes(scanner, lastScan))
+	if err != nil {
+		return fmt.Errorf("failed to marshal attributes: %w", err)
+	}
+
+	return integration.mqtt.Publish(scanner.Topics.AttributesTopic, string(attributesJSON), false)
+}
+
+// scannerAttributes returns the attributes published on the attributes topic
+// of a plain sensor.
+func (integration *Integration) scannerAttributes(scanner *ScannerDevice, lastScan ScanMetadata) map[string]any {
+	scannerID := scanner.ID
+	attributes := buildScannerAttributes(scannerID, integration.scannerConfigs[scannerID])
+	integration.addOperatorAttributes(scannerID, attributes)
+	addScanAttributes(attributes, lastScan.Attributes)
+	if lastScan.CorrelationID != "" {
+		attributes[CorrelationIDAttribute] = lastScan.CorrelationID
+	}
+	if integration.hasScanDetails(scannerID) && lastScan.Sequence != 0 {
+		attributes[SequenceAttribute] = lastScan.Sequence
+		attributes["timestamp"] = lastScan.Time.Format(time.RFC3339)
+	}
+	return attributes
+}
+
+// hasScanDetails reports whether the attributes of a plain sensor carry the
+// sequence number and time of the last scan. JSON states always carry them.
+func (integration *Integration) hasScanDetails(scannerID string) bool {
+	scannerCfg, exists := integration.scannerConfigs[scannerID]
+	return exists && scannerCfg.Attributes.ScanDetails && !scannerCfg.UsesJSONState() && !scannerCfg.UsesEventEntity()
+}
+
+// buildScannerAttributes applies the per-scanner include list and custom
+// metadata to the built-in attributes.
+func buildScannerAttributes(scannerID string, scannerCfg *config.ScannerConfig) map[string]any {
+	builtins := map[string]any{
 		"scanner_id": scannerID,
 	}
+	if scannerCfg == nil {
+		return builtins
+	}
 
-	if scannerCfg, exists := integration.scannerConfigs[scannerID]; exists {
-		attributes["keyboard_layout"] = scannerCfg.KeyboardLayout
-		attributes["termination_char"] = scannerCfg.TerminationChar
+	builtins["keyboard_layout"] = scannerCfg.KeyboardLayout
+	builtins["termination_char"] = scannerCfg.TerminationChar
+
+	attributes := builtins
+	if len(scannerCfg.Attributes.Include) > 0 {
+		attributes = make(map[string]any, len(scannerCfg.Attributes.Include))
+		for _, name := range scannerCfg.Attributes.Include {
+			if value, exists := builtins[name]; exists {
+				attributes[name] = value
+			}
+		}
 	}
 
-	attributesJSON, err := json.Marshal(attributes)
-	if err != nil {
-		return fmt.Errorf("failed to marshal attributes: %w", err)
+	for key, value := range scannerCfg.Attributes.Custom {
+		attributes[key] = value
 	}
 
-	return integration.mqtt.Publish(scanner.Topics.AttributesTopic, string(attributesJSON), false)
+	return attributes
 }
 
 func (integration *Integration) publishScannerHealthState(scannerID string) error {
@@ -609,10 +1350,15 @@ func (integration *Integration) publishScannerHealthState(scannerID string) erro
 		return fmt.Errorf("scanner %s not found", scannerID)
 	}
 
+	integration.checkHealthTransition(scannerID)
+
 	healthStatus := integration.getScannerHealthStatus(scannerID)
 	if err := integration.mqtt.Publish(scanner.HealthTopics.StateTopic, healthStatus, true); err != nil {
 		return err
 	}
+	if scanner.Health != nil {
+		scanner.Health.reportedStatus = healthStatus
+	}
 
 	attributes := integration.getScannerHealthAttributes(scannerID)
 	attributesJSON, err := json.Marshal(attributes)
@@ -638,31 +1384,36 @@ func (integration *Integration) generateBridgeEntityTopics(entityType string) (t
 	return
 }
 
-func (integration *Integration) publishBridgeEntityDiscoveryConfig(entityType, name, icon string) error {
-	topics, baseTopic := integration.generateBridgeEntityTopics(entityType)
+func (integration *Integration) publishBridgeEntityDiscoveryConfig(entity *BridgeEntity) error {
+	topics, baseTopic := integration.generateBridgeEntityTopics(entity.EntityType)
 	bridgeID := generateBridgeDeviceID(integration.config)
-	entityID := fmt.Sprintf("%s-%s", bridgeID, entityType)
+	entityID := fmt.Sprintf("%s-%s", bridgeID, entity.EntityType)
 
 	sensorConfig := SensorConfig{
-		Name:            name,
-		UniqueID:        entityID,
-		TildeTopic:      baseTopic,
-		StateTopic:      "~/state",
-		AttributesTopic: "~/attributes",
+		Name:       entity.Name,
+		UniqueID:   entityID,
+		TildeTopic: baseTopic,
+		StateTopic: "~/state",
 		Availability: []AvailabilityConfig{
 			{
 				Topic: integration.GenerateBridgeAvailabilityTopic(),
 			},
 		},
-		Device:         integration.bridgeDeviceInfo,
-		Icon:           icon,
-		ForceUpdate:    false,
-		EntityCategory: "diagnostic",
+		Device:            integration.bridgeDeviceInfo,
+		Icon:              entity.Icon,
+		ForceUpdate:       false,
+		EntityCategory:    "diagnostic",
+		DeviceClass:       entity.DeviceClass,
+		UnitOfMeasurement: entity.UnitOfMeasurement,
+		StateClass:        entity.StateClass,
+	}
+	if entity.GetAttributes != nil {
+		sensorConfig.AttributesTopic = "~/attributes"
 	}
 
 	configJSON, err := json.Marshal(sensorConfig)
 	if err != nil {
-		return fmt.Errorf("failed to marshal %s discovery config: %w", entityType, err)
+		return fmt.Errorf("failed to marshal %s discovery config: %w", entity.EntityType, err)
 	}
 
 	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
@@ -683,21 +1434,21 @@ func (integration *Integration) getScannerHealthStatus(scannerID string) string
 	}
 
 	if !scanner.Connected {
-		if time.Since(scanner.Health.LastSeen) > 5*time.Minute {
-			return "stale"
+		if time.Since(scanner.Health.LastSeen) > scannerStaleAfter {
+			return HealthStale
 		}
-		return "disconnected"
+		return HealthDisconnected
 	}
 
 	if scanner.Health.ErrorCount > 10 {
-		return "degraded"
+		return HealthDegraded
 	}
 
 	if scanner.Health.ReconnectCount > 5 {
-		return "unstable"
+		return HealthUnstable
 	}
 
-	return "healthy"
+	return HealthHealthy
 }
 
 func (integration *Integration) getScannerHealthAttributes(scannerID string) map[string]any {
@@ -709,10 +1460,20 @@ func (integration *Integration) getScannerHealthAttributes(scannerID string) map
 	attributes := map[string]any{
 		"last_seen":       scanner.Health.LastSeen.Format(time.RFC3339),
 		"reconnect_count": scanner.Health.ReconnectCount,
+		"flap_count":      scanner.Health.FlapCount,
 		"error_count":     scanner.Health.ErrorCount,
+		"open_failures":   scanner.Health.OpenFailures,
+		"read_timeouts":   scanner.Health.ReadTimeouts,
+		"io_errors":       scanner.Health.IOErrors,
+		"bad_lengths":     scanner.Health.BadLengths,
 		"total_scans":     scanner.Health.TotalScans,
 	}
 
+	if integration.isFastPath(scannerID) {
+		attributes["fast_path"] = true
+		attributes["pending_publishes"] = integration.mqtt.PendingFastPublishes()
+	}
+
 	if scanner.Health.ConnectedAt != nil {
 		attributes["connected_at"] = scanner.Health.ConnectedAt.Format(time.RFC3339)
 	}
@@ -724,6 +1485,22 @@ func (integration *Integration) getScannerHealthAttributes(scannerID string) map
 	return attributes
 }
 
+func (integration *Integration) addMQTTDisconnectAttributes(attributes map[string]any) {
+	lastDisconnect := integration.mqtt.LastDisconnect()
+	if lastDisconnect == nil {
+		return
+	}
+
+	attributes["last_mqtt_disconnect_reason"] = lastDisconnect.Reason
+	attributes["last_mqtt_disconnect_at"] = lastDisconnect.Time.Format(time.RFC3339)
+	if lastDisconnect.Error != "" {
+		attributes["last_mqtt_disconnect_error"] = lastDisconnect.Error
+	}
+	if lastDisconnect.ConnackCode != 0 {
+		attributes["last_mqtt_connack_code"] = lastDisconnect.ConnackCode
+	}
+}
+
 func (integration *Integration) getConnectedScannerCount() int {
 	count := 0
 	for _, scanner := range integration.scanners {
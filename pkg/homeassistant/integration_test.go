@@ -1,9 +1,23 @@
 package homeassistant
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
 )
 
 func TestGenerateBridgeAvailabilityTopic(t *testing.T) {
@@ -164,3 +178,1733 @@ func TestScannerDevice_Structure(t *testing.T) {
 		t.Error("Expected topics to match")
 	}
 }
+
+func TestBuildScannerAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *config.ScannerConfig
+		expected map[string]any
+	}{
+		{
+			name:     "No config",
+			config:   nil,
+			expected: map[string]any{"scanner_id": "test"},
+		},
+		{
+			name:   "Defaults",
+			config: &config.ScannerConfig{KeyboardLayout: "us", TerminationChar: "enter"},
+			expected: map[string]any{
+				"scanner_id":       "test",
+				"keyboard_layout":  "us",
+				"termination_char": "enter",
+			},
+		},
+		{
+			name: "Include and custom",
+			config: &config.ScannerConfig{
+				KeyboardLayout:  "us",
+				TerminationChar: "enter",
+				Attributes: config.AttributesConfig{
+					Include: []string{"scanner_id"},
+					Custom:  map[string]string{"department": "Receiving"},
+				},
+			},
+			expected: map[string]any{
+				"scanner_id": "test",
+				"department": "Receiving",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes := buildScannerAttributes("test", tt.config)
+
+			if len(attributes) != len(tt.expected) {
+				t.Fatalf("Expected %d attributes, got %d: %v", len(tt.expected), len(attributes), attributes)
+			}
+			for key, value := range tt.expected {
+				if attributes[key] != value {
+					t.Errorf("Expected attribute %s=%v, got %v", key, value, attributes[key])
+				}
+			}
+		})
+	}
+}
+
+func TestScanDetailsAttributes(t *testing.T) {
+	scanTime := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	integration := &Integration{
+		scannerConfigs: map[string]*config.ScannerConfig{
+			"details": {ID: "details", Attributes: config.AttributesConfig{ScanDetails: true}},
+			"json":    {ID: "json", StateFormat: "json", Attributes: config.AttributesConfig{ScanDetails: true}},
+			"plain":   {ID: "plain"},
+		},
+	}
+
+	if !integration.hasScanDetails("details") || integration.hasScanDetails("json") || integration.hasScanDetails("plain") {
+		t.Error("Expected scan details only on plain sensors with attributes.scan_details")
+	}
+
+	scanner := &ScannerDevice{ID: "details"}
+	if _, exists := integration.scannerAttributes(scanner, scanner.LastScan)[SequenceAttribute]; exists {
+		t.Error("Expected no sequence before the first scan")
+	}
+
+	scanner.LastScan = ScanMetadata{Sequence: 3, Time: scanTime}
+	attributes := integration.scannerAttributes(scanner, scanner.LastScan)
+	if attributes[SequenceAttribute] != uint64(3) || attributes["timestamp"] != "2026-01-01T12:00:00Z" {
+		t.Errorf("Expected sequence 3 and timestamp of the last scan, got %v", attributes)
+	}
+
+	scanner = &ScannerDevice{ID: "plain", LastScan: ScanMetadata{Sequence: 3, Time: scanTime}}
+	if _, exists := integration.scannerAttributes(scanner, scanner.LastScan)[SequenceAttribute]; exists {
+		t.Error("Expected no sequence without attributes.scan_details")
+	}
+
+	scanner.LastScan.Attributes = map[string]string{"valid": "false"}
+	if valid := integration.scannerAttributes(scanner, scanner.LastScan)["valid"]; valid != "false" {
+		t.Errorf("Expected the scan attributes of the last scan, got %v", valid)
+	}
+}
+
+func TestFormatScannerState(t *testing.T) {
+	integration := &Integration{
+		scannerConfigs: map[string]*config.ScannerConfig{
+			"plain": {ID: "plain"},
+			"json":  {ID: "json", StateFormat: config.StateFormatJSON},
+		},
+	}
+
+	payload, err := integration.formatScannerState("plain", "12345", ScanMetadata{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payload != "12345" {
+		t.Errorf("Expected plain payload '12345', got %s", payload)
+	}
+
+	payload, err = integration.formatScannerState("json", "12345", ScanMetadata{ID: "scan-1", CorrelationID: "pick-1", Sequence: 7})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var state StatePayload
+	if err := json.Unmarshal([]byte(payload), &state); err != nil {
+		t.Fatalf("Expected JSON payload, got %s: %v", payload, err)
+	}
+	if state.Value == nil || *state.Value != "12345" {
+		t.Errorf("Expected value '12345', got %v", state.Value)
+	}
+	if state.Timestamp == "" || state.Attributes["timestamp"] != state.Timestamp {
+		t.Error("Expected timestamp to be set in payload and attributes")
+	}
+	if state.ScanID != "scan-1" || state.Attributes["scan_id"] != "scan-1" {
+		t.Errorf("Expected scan ID 'scan-1' in payload and attributes, got %+v", state)
+	}
+	if state.Sequence != 7 || state.Attributes[SequenceAttribute] != float64(7) {
+		t.Errorf("Expected sequence 7 in payload and attributes, got %+v", state)
+	}
+	if state.Attributes[CorrelationIDAttribute] != "pick-1" {
+		t.Errorf("Expected correlation ID 'pick-1' in attributes, got %v", state.Attributes)
+	}
+
+	payload, err = integration.formatScannerState("json", StatusUnknown, ScanMetadata{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	state = StatePayload{}
+	if err := json.Unmarshal([]byte(payload), &state); err != nil {
+		t.Fatalf("Expected JSON payload, got %s: %v", payload, err)
+	}
+	if state.Value != nil {
+		t.Errorf("Expected null value for unknown state, got %s", *state.Value)
+	}
+}
+
+func TestFormatScanEvent(t *testing.T) {
+	integration := &Integration{
+		config:         &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"},
+		scannerConfigs: map[string]*config.ScannerConfig{"event": {ID: "event", EntityPlatform: config.EntityPlatformEvent}},
+		topics:         loadTopicTemplates(&config.TopicsConfig{}, logrus.New()),
+	}
+
+	metadata := ScanMetadata{ID: "scan-1", CorrelationID: "pick-1", Attributes: map[string]string{"valid": "false"}}
+	payload, err := integration.formatScannerState("event", "12345", metadata)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var event ScanEventPayload
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("Expected JSON payload, got %s: %v", payload, err)
+	}
+	if event.EventType != ScanEventType || event.Barcode != "12345" || event.ScannerID != "event" || event.ScanID != "scan-1" || event.CorrelationID != "pick-1" || event.Timestamp == "" {
+		t.Errorf("Expected scan event of 12345, got %+v", event)
+	}
+	if event.Attributes["valid"] != "false" {
+		t.Errorf("Expected scan attributes in the event, got %v", event.Attributes)
+	}
+
+	topics := integration.generateScannerTopics("event")
+	if !strings.HasPrefix(topics.ConfigTopic, "homeassistant/event/") {
+		t.Errorf("Expected the event component in the discovery topic, got %s", topics.ConfigTopic)
+	}
+}
+
+func TestLastScanExpired(t *testing.T) {
+	now := time.Now()
+	lastScan := now.Add(-time.Minute)
+	integration := &Integration{
+		scannerConfigs: map[string]*config.ScannerConfig{
+			"expiring": {ID: "expiring", ExpireAfter: 30 * time.Second},
+			"slow":     {ID: "slow", ExpireAfter: 2 * time.Minute},
+			"plain":    {ID: "plain"},
+		},
+		scanners: map[string]*ScannerDevice{
+			"expiring": {ID: "expiring", Health: &ScannerHealthMetrics{LastScanTime: &lastScan}},
+			"slow":     {ID: "slow", Health: &ScannerHealthMetrics{LastScanTime: &lastScan}},
+			"plain":    {ID: "plain", Health: &ScannerHealthMetrics{LastScanTime: &lastScan}},
+		},
+	}
+
+	if !integration.lastScanExpired("expiring", now) {
+		t.Error("Expected a scan older than expire_after to be expired")
+	}
+	if integration.lastScanExpired("slow", now) {
+		t.Error("Expected a scan within expire_after not to be expired")
+	}
+	if integration.lastScanExpired("plain", now) {
+		t.Error("Expected scans never to expire without expire_after")
+	}
+}
+
+// recordingClient is a connected MQTT client recording the published
+// payloads by topic.
+type recordingClient struct {
+	mqtt.Client
+	mutex     sync.Mutex
+	published map[string][]string
+	failTopic string // Publishing to it fails
+}
+
+func newRecordingClient() *recordingClient {
+	return &recordingClient{published: make(map[string][]string)}
+}
+
+func (c *recordingClient) IsConnected() bool { return true }
+
+func (c *recordingClient) Publish(topic, payload string, _ bool) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if topic == c.failTopic {
+		return errors.New("publish failed")
+	}
+	c.published[topic] = append(c.published[topic], payload)
+	return nil
+}
+
+func (c *recordingClient) Subscribe(string, func(string, []byte)) error { return nil }
+
+func (c *recordingClient) LastDisconnect() *mqtt.DisconnectInfo { return nil }
+
+func TestRepublishLastStates(t *testing.T) {
+	client := newRecordingClient()
+	logger := logrus.New()
+	haConfig := &config.HomeAssistantConfig{
+		DiscoveryPrefix:    "homeassistant",
+		InstanceID:         "test",
+		RepublishLastState: true,
+		DeviceTriggers:     true,
+	}
+	integration := NewIntegration(client, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk", RawTopic: "barcode/desk/raw"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+	topics := integration.ScannerTopics("desk")
+	discovery := client.published[topics.ConfigTopic]
+	if len(discovery) != 1 || strings.Contains(discovery[0], "force_update") {
+		t.Errorf("Expected the sensor to be discovered without force_update, got %v", discovery)
+	}
+
+	if err := integration.PublishBarcode("desk", "4006381333931", ScanMetadata{CorrelationID: "order-1"}); err != nil {
+		t.Fatalf("Expected scan to be published, got: %v", err)
+	}
+	if raw := client.published["barcode/desk/raw"]; len(raw) != 1 {
+		t.Fatalf("Expected the scan on the raw topic, got %v", raw)
+	}
+	triggerTopic := integration.generateScanTriggerTopics("desk").StateTopic
+	clear(client.published)
+
+	integration.scannersMutex.RLock()
+	integration.republishLastStates()
+	integration.scannersMutex.RUnlock()
+
+	attributes := client.published[topics.AttributesTopic]
+	if len(attributes) != 1 || !strings.Contains(attributes[0], `"correlation_id":"order-1"`) {
+		t.Errorf("Expected the attributes of the last scan to be republished, got %v", attributes)
+	}
+	if states := client.published[topics.StateTopic]; len(states) != 1 || states[0] != "4006381333931" {
+		t.Errorf("Expected the last barcode to be republished, got %v", states)
+	}
+	if triggers := client.published[triggerTopic]; len(triggers) != 0 {
+		t.Errorf("Expected no scan trigger on reconnect, got %v", triggers)
+	}
+	if raw := client.published["barcode/desk/raw"]; len(raw) != 0 {
+		t.Errorf("Expected no raw barcode on reconnect, got %v", raw)
+	}
+}
+
+func TestFailedScanNotCounted(t *testing.T) {
+	client := newRecordingClient()
+	logger := logrus.New()
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(client, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+	scanner := integration.scanners["desk"]
+
+	client.failTopic = integration.ScannerTopics("desk").StateTopic
+	if err := integration.PublishBarcode("desk", "123", ScanMetadata{ID: "scan-1"}); err == nil {
+		t.Fatal("Expected error publishing the scan state")
+	}
+	if scanner.Health.TotalScans != 0 || scanner.ScanSequence != 0 || scanner.LastBarcode != "" || scanner.LastScan.ID != "" {
+		t.Errorf("Expected a failed scan not to be counted, got %d scans, sequence %d, last scan %+v",
+			scanner.Health.TotalScans, scanner.ScanSequence, scanner.LastScan)
+	}
+
+	client.failTopic = ""
+	if err := integration.PublishBarcode("desk", "123", ScanMetadata{ID: "scan-1"}); err != nil {
+		t.Fatalf("Expected retried scan to be published, got: %v", err)
+	}
+	if scanner.Health.TotalScans != 1 || scanner.ScanSequence != 1 || scanner.LastScan.Sequence != 1 {
+		t.Errorf("Expected the retried scan counted once with sequence 1, got %d scans, sequence %d",
+			scanner.Health.TotalScans, scanner.LastScan.Sequence)
+	}
+
+	scannedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := integration.PublishBarcode("desk", "456", ScanMetadata{ID: "scan-2", Time: scannedAt}); err != nil {
+		t.Fatalf("Expected queued scan to be published, got: %v", err)
+	}
+	if !scanner.LastScan.Time.Equal(scannedAt) || !scanner.Health.LastScanTime.Equal(scannedAt) {
+		t.Errorf("Expected a queued scan to keep its scan time %v, got %v", scannedAt, scanner.LastScan.Time)
+	}
+
+	integration.AddScanner("shelf", "Shelf", &config.ScannerConfig{ID: "shelf"})
+	if err := integration.PublishBarcode("shelf", "123", ScanMetadata{}); !errors.Is(err, ErrScannerNotRegistered) {
+		t.Errorf("Expected ErrScannerNotRegistered before the scanner connected, got %v", err)
+	}
+	if err := integration.PublishBarcode("missing", "123", ScanMetadata{}); !errors.Is(err, ErrScannerNotFound) {
+		t.Errorf("Expected ErrScannerNotFound for an unconfigured scanner, got %v", err)
+	}
+}
+
+func TestConcurrentScanUpdates(t *testing.T) {
+	client := newRecordingClient()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", DuplicateWindow: time.Minute}
+	integration := NewIntegration(client, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+
+	// The same scans delivered twice at once, e.g. by the offline queue and
+	// the pipeline, while the device reports its state
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_ = integration.PublishBarcode("desk", "123", ScanMetadata{ID: fmt.Sprintf("scan-%d", i%10)})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = integration.SetScannerConnected("desk", i%2 == 0)
+			_ = integration.SetScannerBatteryLevel("desk", i)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = integration.RecordScannerError("desk", scanner.ErrorCategoryIOError)
+		}()
+	}
+	wg.Wait()
+
+	device := integration.scanners["desk"]
+	if device.Health.TotalScans != 10 || device.ScanSequence != 10 {
+		t.Errorf("Expected each scan published once, got %d scans and sequence %d", device.Health.TotalScans, device.ScanSequence)
+	}
+	if device.Health.IOErrors != 20 {
+		t.Errorf("Expected 20 I/O errors, got %d", device.Health.IOErrors)
+	}
+}
+
+func TestParsedFieldSensors(t *testing.T) {
+	client := newRecordingClient()
+	logger := logrus.New()
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(client, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{
+		ID:       "desk",
+		Pipeline: []config.PipelineStageConfig{{Type: config.StageParse, Sensors: true}},
+	})
+	integration.AddScanner("shelf", "Shelf", &config.ScannerConfig{ID: "shelf", Pipeline: []config.PipelineStageConfig{{Type: config.StageParse}}})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+	integration.SetScannerDeviceInfo("shelf", &hid.DeviceInfo{Product: "Shelf"})
+
+	attributes := map[string]string{
+		common.PayloadFormatAttribute: common.PayloadURL,
+		"url_host":                    "example.com",
+		"url_param_lot no":            "7",
+		"location":                    "warehouse",
+	}
+	for range 2 {
+		if err := integration.PublishBarcode("desk", "https://example.com/?lot+no=7", ScanMetadata{Attributes: attributes}); err != nil {
+			t.Fatalf("Expected scan to be published, got: %v", err)
+		}
+	}
+
+	hostTopics := integration.generateScannerSubEntityTopics("desk", "field_url_host")
+	if configs := client.published[hostTopics.ConfigTopic]; len(configs) != 1 || !strings.Contains(configs[0], `"name":"Desk url_host"`) {
+		t.Errorf("Expected the url_host sensor to be discovered once, got %v", configs)
+	}
+	if states := client.published[hostTopics.StateTopic]; len(states) != 2 || states[1] != "example.com" {
+		t.Errorf("Expected the url_host state on every scan, got %v", states)
+	}
+	paramTopics := integration.generateScannerSubEntityTopics("desk", "field_url_param_lot_no")
+	if states := client.published[paramTopics.StateTopic]; len(states) != 2 || states[0] != "7" {
+		t.Errorf("Expected the query parameter on a sanitized topic, got %v", states)
+	}
+	formatTopics := integration.generateScannerSubEntityTopics("desk", "field_payload_format")
+	if states := client.published[formatTopics.StateTopic]; len(states) != 2 || states[0] != common.PayloadURL {
+		t.Errorf("Expected the payload format sensor, got %v", states)
+	}
+	if configs := client.published[integration.generateScannerSubEntityTopics("desk", "field_location").ConfigTopic]; len(configs) != 0 {
+		t.Errorf("Expected no sensor for attributes of other stages, got %v", configs)
+	}
+
+	if err := integration.PublishBarcode("shelf", "https://example.com/", ScanMetadata{Attributes: attributes}); err != nil {
+		t.Fatalf("Expected scan to be published, got: %v", err)
+	}
+	if configs := client.published[integration.generateScannerSubEntityTopics("shelf", "field_url_host").ConfigTopic]; len(configs) != 0 {
+		t.Errorf("Expected no field sensors without sensors: true, got %v", configs)
+	}
+
+	clear(client.published)
+	if err := integration.UnpublishScanner("desk"); err != nil {
+		t.Fatalf("Expected scanner to be unpublished, got: %v", err)
+	}
+	if configs := client.published[hostTopics.ConfigTopic]; len(configs) != 1 || configs[0] != "" {
+		t.Errorf("Expected the field sensor discovery to be cleared, got %v", configs)
+	}
+}
+
+func TestScannerAvailability(t *testing.T) {
+	tests := []struct {
+		mode          string
+		expectedMode  string
+		expectedCount int
+	}{
+		{"", config.AvailabilityModeAll, 2},
+		{config.AvailabilityModeAll, config.AvailabilityModeAll, 2},
+		{config.AvailabilityModeLatest, config.AvailabilityModeLatest, 2},
+		{config.AvailabilityModeAny, config.AvailabilityModeAny, 2},
+		{config.AvailabilityModeScanner, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			integration := &Integration{
+				config: &config.HomeAssistantConfig{
+					DiscoveryPrefix:  "homeassistant",
+					InstanceID:       "test",
+					AvailabilityMode: tt.mode,
+				},
+			}
+
+			availability, mode := integration.scannerAvailability("~/availability")
+
+			if mode != tt.expectedMode {
+				t.Errorf("Expected mode '%s', got '%s'", tt.expectedMode, mode)
+			}
+			if len(availability) != tt.expectedCount {
+				t.Fatalf("Expected %d availability topics, got %d", tt.expectedCount, len(availability))
+			}
+			if availability[0].Topic != "~/availability" {
+				t.Errorf("Expected scanner topic first, got %s", availability[0].Topic)
+			}
+		})
+	}
+}
+
+func TestScannerEntityIDs(t *testing.T) {
+	tests := []struct {
+		name           string
+		haConfig       config.HomeAssistantConfig
+		scannerConfig  config.ScannerConfig
+		suffix         string
+		expectedObject string
+		expectedUnique string
+	}{
+		{
+			"default scheme", config.HomeAssistantConfig{InstanceID: "host"}, config.ScannerConfig{}, "",
+			"host_office", "ha-barcode-bridge-host-scanner-office",
+		},
+		{
+			"default scheme with suffix", config.HomeAssistantConfig{InstanceID: "host"}, config.ScannerConfig{}, "health",
+			"host_office_health", "ha-barcode-bridge-host-scanner-office-health",
+		},
+		{
+			"templates", config.HomeAssistantConfig{
+				InstanceID: "host", ObjectIDTemplate: "barcode_{scanner}", UniqueIDTemplate: "barcode-{scanner}",
+			}, config.ScannerConfig{}, "battery",
+			"barcode_office_battery", "barcode-office-battery",
+		},
+		{
+			"scanner override", config.HomeAssistantConfig{InstanceID: "host", UniqueIDTemplate: "barcode-{scanner}"},
+			config.ScannerConfig{ObjectID: "front_desk", UniqueID: "front-desk"}, "dock",
+			"front_desk_dock", "front-desk-dock",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			integration := &Integration{
+				config:         &tt.haConfig,
+				scannerConfigs: map[string]*config.ScannerConfig{"office": &tt.scannerConfig},
+			}
+
+			if objectID := integration.scannerObjectID("office", tt.suffix); objectID != tt.expectedObject {
+				t.Errorf("Expected object_id '%s', got '%s'", tt.expectedObject, objectID)
+			}
+			if uniqueID := integration.scannerUniqueID("office", tt.suffix); uniqueID != tt.expectedUnique {
+				t.Errorf("Expected unique_id '%s', got '%s'", tt.expectedUnique, uniqueID)
+			}
+		})
+	}
+}
+
+func TestParseOperatorBadge(t *testing.T) {
+	tests := []struct {
+		name          string
+		pattern       string
+		barcode       string
+		expectedName  string
+		expectedBadge bool
+	}{
+		{"Capture group", "^BADGE-(.+)$", "BADGE-alice", "alice", true},
+		{"Whole barcode", "^EMP\\d{4}$", "EMP0042", "EMP0042", true},
+		{"Regular barcode", "^BADGE-(.+)$", "8412345678905", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, isBadge := parseOperatorBadge(regexp.MustCompile(tt.pattern), tt.barcode)
+
+			if isBadge != tt.expectedBadge {
+				t.Errorf("Expected badge %v, got %v", tt.expectedBadge, isBadge)
+			}
+			if name != tt.expectedName {
+				t.Errorf("Expected operator '%s', got '%s'", tt.expectedName, name)
+			}
+		})
+	}
+}
+
+func TestOperatorTimeout(t *testing.T) {
+	integration := &Integration{
+		logger: logrus.New(),
+		scannerConfigs: map[string]*config.ScannerConfig{
+			"test": {ID: "test", Operator: &config.OperatorConfig{BadgePattern: "^BADGE-(.+)$", Timeout: time.Minute}},
+		},
+		badgePatterns: make(map[string]*regexp.Regexp),
+		operators:     make(map[string]*operatorSession),
+	}
+	integration.registerOperatorMode("test")
+
+	start := time.Now()
+	integration.operators["test"] = &operatorSession{Name: "alice", Since: start, LastScan: start}
+
+	if integration.touchOperator("test", start.Add(30*time.Second)) {
+		t.Error("Expected operator to stay assigned within the timeout")
+	}
+
+	attributes := map[string]any{}
+	integration.addOperatorAttributes("test", attributes)
+	if attributes[CurrentOperatorAttribute] != "alice" {
+		t.Errorf("Expected current operator 'alice', got %v", attributes[CurrentOperatorAttribute])
+	}
+
+	if !integration.touchOperator("test", start.Add(2*time.Minute)) {
+		t.Error("Expected operator to be cleared after the timeout")
+	}
+	if integration.operators["test"] != nil {
+		t.Error("Expected operator session to be removed")
+	}
+}
+
+func TestScanCounter(t *testing.T) {
+	counter := &scanCounter{}
+	start := time.Now()
+
+	counter.record(start)
+	counter.record(start.Add(30 * time.Minute))
+	total, windowCount := counter.record(start.Add(45 * time.Minute))
+	if total != 3 || windowCount != 3 {
+		t.Errorf("Expected 3 total and 3 in window, got %d and %d", total, windowCount)
+	}
+
+	total, windowCount = counter.snapshot(start.Add(ScanRateWindow + time.Minute))
+	if total != 3 {
+		t.Errorf("Expected total to stay monotonic at 3, got %d", total)
+	}
+	if windowCount != 2 {
+		t.Errorf("Expected 2 scans in window, got %d", windowCount)
+	}
+
+	counter.reset()
+	total, windowCount = counter.snapshot(start)
+	if total != 0 || windowCount != 0 {
+		t.Errorf("Expected counters to be reset, got %d and %d", total, windowCount)
+	}
+}
+
+func TestScanDeduplicator(t *testing.T) {
+	published := newScanDeduplicator(time.Minute)
+	start := time.Now()
+
+	if !published.claim("scan-1", start) {
+		t.Error("Expected an unpublished scan to be claimed")
+	}
+	if published.claim("scan-1", start.Add(30*time.Second)) {
+		t.Error("Expected a scan published within the window to be a duplicate")
+	}
+	if !published.claim("scan-1", start.Add(time.Minute)) {
+		t.Error("Expected a scan published before the window not to be a duplicate")
+	}
+
+	published.release("scan-1")
+	if !published.claim("scan-1", start.Add(time.Minute)) {
+		t.Error("Expected a released scan to be claimed again")
+	}
+
+	if !published.claim("", start) || !published.claim("", start) {
+		t.Error("Expected scans without an ID never to be duplicates")
+	}
+
+	published.claim("scan-2", start.Add(3*time.Minute))
+	if _, exists := published.published["scan-1"]; exists {
+		t.Error("Expected scans outside the window to be forgotten")
+	}
+
+	disabled := newScanDeduplicator(0)
+	if !disabled.claim("scan-1", start) || !disabled.claim("scan-1", start) {
+		t.Error("Expected no duplicates without a window")
+	}
+}
+
+func TestReadQuality(t *testing.T) {
+	quality := &readQuality{}
+	start := time.Now()
+
+	if accepted, rejected := quality.snapshot(start); formatReadQuality(accepted, rejected) != readQualityUnknown {
+		t.Errorf("Expected unknown read quality without scans, got %d accepted and %d rejected", accepted, rejected)
+	}
+
+	quality.record(start, true)
+	quality.record(start.Add(30*time.Minute), false)
+	quality.record(start.Add(40*time.Minute), false)
+	accepted, rejected := quality.record(start.Add(45*time.Minute), false)
+	if accepted != 3 || rejected != 1 {
+		t.Errorf("Expected 3 accepted and 1 rejected, got %d and %d", accepted, rejected)
+	}
+	if value := formatReadQuality(accepted, rejected); value != "75.0" {
+		t.Errorf("Expected read quality '75.0', got '%s'", value)
+	}
+
+	accepted, rejected = quality.snapshot(start.Add(ScanRateWindow + time.Minute))
+	if accepted != 3 || rejected != 0 {
+		t.Errorf("Expected the rejected scan to leave the window, got %d accepted and %d rejected", accepted, rejected)
+	}
+	if value := formatReadQuality(accepted, rejected); value != "100.0" {
+		t.Errorf("Expected read quality '100.0', got '%s'", value)
+	}
+}
+
+func TestGenerateScanCountResetTopic(t *testing.T) {
+	integration := &Integration{
+		config: &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"},
+	}
+
+	expected := "homeassistant/sensor/ha-barcode-bridge-test-scanner-office-scans/reset"
+	if topic := integration.GenerateScanCountResetTopic("office"); topic != expected {
+		t.Errorf("Expected topic '%s', got '%s'", expected, topic)
+	}
+}
+
+func TestScannerTopicTemplates(t *testing.T) {
+	tests := []struct {
+		name              string
+		topics            config.TopicsConfig
+		component         string
+		suffix            string
+		expectedConfig    string
+		expectedState     string
+		expectedInventory string
+	}{
+		{
+			name:              "default layout",
+			component:         "sensor",
+			expectedConfig:    "homeassistant/sensor/ha-barcode-bridge-test-scanner-office/config",
+			expectedState:     "homeassistant/sensor/ha-barcode-bridge-test-scanner-office/state",
+			expectedInventory: "homeassistant/sensor/ha-barcode-bridge-test-scanner-office/inventory",
+		},
+		{
+			name:           "default layout sub-entity",
+			component:      "select",
+			suffix:         "pantry_mode",
+			expectedConfig: "homeassistant/select/ha-barcode-bridge-test-scanner-office-pantry_mode/config",
+			expectedState:  "homeassistant/select/ha-barcode-bridge-test-scanner-office-pantry_mode/state",
+		},
+		{
+			name: "custom layout",
+			topics: config.TopicsConfig{
+				Discovery: "{{ .Prefix }}/{{ .Component }}/{{ .BridgeID }}/{{ .ScannerID }}{{ with .Entity }}_{{ . }}{{ end }}",
+				State:     "barcode/{{ .ScannerID }}{{ with .Entity }}/{{ . }}{{ end }}",
+			},
+			component:         "sensor",
+			expectedConfig:    "homeassistant/sensor/ha-barcode-bridge-test/office/config",
+			expectedState:     "barcode/office/state",
+			expectedInventory: "barcode/office/inventory",
+		},
+		{
+			name: "custom layout sub-entity",
+			topics: config.TopicsConfig{
+				Discovery: "{{ .Prefix }}/{{ .Component }}/{{ .BridgeID }}/{{ .ScannerID }}{{ with .Entity }}_{{ . }}{{ end }}",
+				State:     "barcode/{{ .ScannerID }}{{ with .Entity }}/{{ . }}{{ end }}",
+			},
+			component:      "sensor",
+			suffix:         "health",
+			expectedConfig: "homeassistant/sensor/ha-barcode-bridge-test/office_health/config",
+			expectedState:  "barcode/office/health/state",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			integration := &Integration{
+				config: &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", Topics: tt.topics},
+				logger: logrus.New(),
+			}
+			integration.topics = loadTopicTemplates(&tt.topics, integration.logger)
+
+			topics := integration.generateScannerComponentTopics(tt.component, "office", tt.suffix)
+			if topics.ConfigTopic != tt.expectedConfig {
+				t.Errorf("Expected config topic '%s', got '%s'", tt.expectedConfig, topics.ConfigTopic)
+			}
+			if topics.StateTopic != tt.expectedState {
+				t.Errorf("Expected state topic '%s', got '%s'", tt.expectedState, topics.StateTopic)
+			}
+			if tt.expectedInventory != "" {
+				if topic := integration.GenerateInventoryTopic("office"); topic != tt.expectedInventory {
+					t.Errorf("Expected inventory topic '%s', got '%s'", tt.expectedInventory, topic)
+				}
+			}
+		})
+	}
+}
+
+func TestSetScannerConnected_DisconnectDebounce(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	integration := &Integration{
+		mqtt:   mqttClient,
+		logger: logger,
+		config: &config.HomeAssistantConfig{
+			DiscoveryPrefix:    "homeassistant",
+			InstanceID:         "test",
+			DisconnectDebounce: time.Minute,
+		},
+		scanners: map[string]*ScannerDevice{
+			"test": {ID: "test", Connected: true, Health: &ScannerHealthMetrics{}},
+		},
+	}
+	scanner := integration.scanners["test"]
+	scanner.HealthTopics = integration.generateScannerHealthTopics("test")
+
+	if err := integration.SetScannerConnected("test", false); err != nil {
+		t.Fatalf("Expected debounced disconnect to succeed, got: %v", err)
+	}
+	if scanner.pendingDisconnect == nil {
+		t.Fatal("Expected disconnect to be pending")
+	}
+	if !scanner.Connected {
+		t.Error("Expected scanner to stay connected during the debounce window")
+	}
+
+	_ = integration.SetScannerConnected("test", true)
+	if scanner.pendingDisconnect != nil {
+		t.Error("Expected pending disconnect to be cancelled")
+	}
+	if scanner.Health.FlapCount != 1 {
+		t.Errorf("Expected flap count 1, got %d", scanner.Health.FlapCount)
+	}
+}
+
+func TestParsePantryQuantity(t *testing.T) {
+	tests := []struct {
+		name             string
+		prefix           string
+		barcode          string
+		expectedQuantity int
+		expectedOK       bool
+	}{
+		{"Quantity barcode", "QTY:", "QTY:3", 3, true},
+		{"Product barcode", "QTY:", "8412345678905", 0, false},
+		{"Not a number", "QTY:", "QTY:many", 0, false},
+		{"Zero quantity", "QTY:", "QTY:0", 0, false},
+		{"No prefix configured", "", "3", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity, ok := parsePantryQuantity(tt.prefix, tt.barcode)
+			if ok != tt.expectedOK || quantity != tt.expectedQuantity {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tt.expectedQuantity, tt.expectedOK, quantity, ok)
+			}
+		})
+	}
+}
+
+func TestPantryMode(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	integration := &Integration{
+		mqtt:   mqttClient,
+		logger: logger,
+		config: &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"},
+		scannerConfigs: map[string]*config.ScannerConfig{
+			"pantry":  {ID: "pantry", Pantry: &config.PantryConfig{QuantityPrefix: "QTY:"}},
+			"regular": {ID: "regular"},
+		},
+		pantry: make(map[string]*pantryState),
+	}
+	integration.registerPantryMode("pantry")
+	integration.registerPantryMode("regular")
+
+	if integration.HandlePantryQuantity("regular", "QTY:2") {
+		t.Error("Expected quantity scan to be ignored without pantry mode")
+	}
+	if !integration.HandlePantryQuantity("pantry", "QTY:2") {
+		t.Error("Expected quantity scan to be consumed in pantry mode")
+	}
+
+	integration.createPantryModeHandler("pantry")("", []byte("consume"))
+	integration.createPantryModeHandler("pantry")("", []byte("open"))
+
+	action, quantity := integration.pantry["pantry"].takeItem()
+	if action != config.PantryActionConsume {
+		t.Errorf("Expected action '%s', got '%s'", config.PantryActionConsume, action)
+	}
+	if quantity != 2 {
+		t.Errorf("Expected quantity 2, got %d", quantity)
+	}
+
+	_, quantity = integration.pantry["pantry"].takeItem()
+	if quantity != 1 {
+		t.Errorf("Expected quantity to reset to 1 after an item, got %d", quantity)
+	}
+
+	event, _ := integration.PublishInventoryEvent("regular", "8412345678905")
+	if event != nil {
+		t.Error("Expected no inventory event without pantry mode")
+	}
+}
+
+func TestRecordScannerError(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	integration := &Integration{
+		mqtt:   mqttClient,
+		logger: logger,
+		config: &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"},
+		scanners: map[string]*ScannerDevice{
+			"test": {ID: "test", Connected: true, Health: &ScannerHealthMetrics{}},
+		},
+	}
+
+	for _, category := range []string{"open_failure", "read_timeout", "read_timeout", "io_error", "bad_length"} {
+		if err := integration.RecordScannerError("test", category); err != nil {
+			t.Fatalf("Expected %s to be recorded, got: %v", category, err)
+		}
+	}
+	if err := integration.RecordScannerError("test", "bogus"); err == nil {
+		t.Error("Expected error for unknown category")
+	}
+	if err := integration.RecordScannerError("missing", "io_error"); !errors.Is(err, ErrScannerNotRegistered) {
+		t.Errorf("Expected ErrScannerNotRegistered for a scanner that never connected, got %v", err)
+	}
+
+	// Open failures don't count towards the degraded threshold
+	attributes := integration.getScannerHealthAttributes("test")
+	expected := map[string]int{"open_failures": 1, "read_timeouts": 2, "io_errors": 1, "bad_lengths": 1, "error_count": 4}
+	for key, value := range expected {
+		if attributes[key] != value {
+			t.Errorf("Expected %s %d, got %v", key, value, attributes[key])
+		}
+	}
+}
+
+// TestReloadDuringScans adds and removes a scanner, as a configuration
+// reload does, while another one keeps scanning. Run with -race.
+func TestReloadDuringScans(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_ = integration.PublishBarcode("desk", "123", ScanMetadata{})
+			integration.RecordRead("desk", false)
+			_ = integration.RecordScannerError("desk", "read_timeout")
+			_, _ = integration.HandleOperatorBadge("desk", "123")
+			_ = integration.HandlePantryQuantity("desk", "123")
+		}
+	}()
+
+	for i := range 200 {
+		scannerID := "reloaded" + strconv.Itoa(i%3)
+		integration.AddScanner(scannerID, "Reloaded", &config.ScannerConfig{
+			ID:       scannerID,
+			Operator: &config.OperatorConfig{BadgePattern: "^OP-(.+)$"},
+			Pantry:   &config.PantryConfig{},
+		})
+		integration.SetScannerDeviceInfo(scannerID, &hid.DeviceInfo{Product: "Reloaded"})
+		integration.RemoveScanner(scannerID)
+	}
+	close(done)
+	wg.Wait()
+
+	if _, exists := integration.ScannerStatsByID("desk"); !exists {
+		t.Error("Expected the scanning scanner to survive the reloads")
+	}
+}
+
+func TestScanSymbology(t *testing.T) {
+	if symbology := scanSymbology("4006381333931", ScanMetadata{}); symbology != common.SymbologyEAN13 {
+		t.Errorf("Expected the symbology detected from the barcode, got %s", symbology)
+	}
+
+	metadata := ScanMetadata{Attributes: map[string]string{common.SymbologyAttribute: common.SymbologyCode128}}
+	if symbology := scanSymbology("4006381333931", metadata); symbology != common.SymbologyCode128 {
+		t.Errorf("Expected the symbology of the stripped AIM identifier, got %s", symbology)
+	}
+}
+
+func TestChangeDiscoveryPrefix(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	integration := &Integration{
+		mqtt:   mqttClient,
+		logger: logger,
+		config: &config.HomeAssistantConfig{
+			DiscoveryPrefix: "homeassistant",
+			InstanceID:      "test",
+		},
+		scanners: map[string]*ScannerDevice{
+			"test": {ID: "test", Health: &ScannerHealthMetrics{}},
+		},
+	}
+	integration.assignScannerTopics(integration.scanners["test"])
+
+	if err := integration.ChangeDiscoveryPrefix("ha"); err != nil {
+		t.Fatalf("Expected prefix change to succeed, got: %v", err)
+	}
+
+	scanner := integration.scanners["test"]
+	expectedTopics := map[string]string{
+		scanner.Topics.ConfigTopic:                    "ha/sensor/ha-barcode-bridge-test-scanner-test/config",
+		scanner.HealthTopics.StateTopic:               "ha/sensor/ha-barcode-bridge-test-scanner-test-health/state",
+		scanner.DockTopics.StateTopic:                 "ha/binary_sensor/ha-barcode-bridge-test-scanner-test-dock/state",
+		scanner.ConnectivityTopics.StateTopic:         "ha/binary_sensor/ha-barcode-bridge-test-scanner-test-connectivity/state",
+		scanner.ScanCountTopics.StateTopic:            "ha/sensor/ha-barcode-bridge-test-scanner-test-scans/state",
+		integration.GenerateBridgeAvailabilityTopic(): "ha/sensor/ha-barcode-bridge-test/availability",
+	}
+	for actual, expected := range expectedTopics {
+		if actual != expected {
+			t.Errorf("Expected topic %s, got %s", expected, actual)
+		}
+	}
+}
+
+func TestParseBridgeTopic(t *testing.T) {
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "home"}
+
+	tests := []struct {
+		topic          string
+		expectedObject string
+		expectedOwned  bool
+	}{
+		{"homeassistant/sensor/ha-barcode-bridge-home/availability", "", true},
+		{"homeassistant/switch/ha-barcode-bridge-home-pause/state", "", true},
+		{"homeassistant/button/ha-barcode-bridge-home-restart/config", "", true},
+		{"homeassistant/sensor/ha-barcode-bridge-home-diagnostics/config", "", true},
+		{"homeassistant/sensor/ha-barcode-bridge-home-scanner-office-health/state", "office-health", true},
+		{"homeassistant/sensor/ha-barcode-bridge-home2/availability", "", false},
+		{"homeassistant/sensor/ha-barcode-bridge-home-scanner-/state", "", false},
+		{"homeassistant/light/kitchen/config", "", false},
+		{"zigbee2mqtt/sensor/ha-barcode-bridge-home/availability", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			object, owned := ParseBridgeTopic(haConfig, tt.topic)
+			if object != tt.expectedObject || owned != tt.expectedOwned {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expectedObject, tt.expectedOwned, object, owned)
+			}
+		})
+	}
+}
+
+func TestScannerEntityID(t *testing.T) {
+	tests := []struct {
+		name       string
+		instanceID string
+		objectID   string
+		platform   string
+		expected   string
+	}{
+		{"Default template", "home", "", "", "sensor.home_office"},
+		{"Hyphenated instance", "home-test", "", "", "sensor.home_test_office"},
+		{"Custom object ID", "home", "Front Door", "", "sensor.front_door"},
+		{"Event entity", "home", "", config.EntityPlatformEvent, "event.home_office"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			integration := &Integration{
+				config:         &config.HomeAssistantConfig{InstanceID: tt.instanceID},
+				scannerConfigs: map[string]*config.ScannerConfig{"office": {ObjectID: tt.objectID, EntityPlatform: tt.platform}},
+			}
+			if entityID := integration.ScannerEntityID("office"); entityID != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, entityID)
+			}
+		})
+	}
+}
+
+func TestFastPath(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	integration.AddScanner("conveyor", "Conveyor", &config.ScannerConfig{ID: "conveyor", FastPath: true})
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk"})
+	integration.SetScannerDeviceInfo("conveyor", &hid.DeviceInfo{Product: "Conveyor"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+
+	if !integration.isFastPath("conveyor") || integration.isFastPath("desk") || integration.isFastPath("missing") {
+		t.Error("Expected only the conveyor scanner on the fast path")
+	}
+	if attributes := integration.getScannerHealthAttributes("conveyor"); attributes["fast_path"] != true {
+		t.Errorf("Expected fast_path health attribute, got %v", attributes)
+	}
+	if attributes := integration.getScannerHealthAttributes("desk"); attributes["fast_path"] != nil {
+		t.Errorf("Expected no fast_path health attribute for a regular scanner, got %v", attributes)
+	}
+
+	if err := integration.publishBarcodeFast("conveyor", "123", ScanMetadata{}); err == nil {
+		t.Error("Expected error publishing while not connected")
+	}
+	if pending := integration.takeFastPathPending(); len(pending) != 0 {
+		t.Errorf("Expected a failed publish not to be batched, got %v", pending)
+	}
+
+	integration.fastPathPending["conveyor"] = true
+	if pending := integration.takeFastPathPending(); len(pending) != 1 || pending[0] != "conveyor" {
+		t.Errorf("Expected conveyor pending, got %v", pending)
+	}
+	if pending := integration.takeFastPathPending(); len(pending) != 0 {
+		t.Errorf("Expected pending scanners to be cleared, got %v", pending)
+	}
+	if integration.isCoalesced("desk") || integration.flushInterval() != fastPathFlushInterval {
+		t.Error("Expected only fast path scanners to be batched without coalesce_interval")
+	}
+
+	haConfig.CoalesceInterval = 2 * time.Second
+	if !integration.isCoalesced("desk") || integration.flushInterval() != 2*time.Second {
+		t.Error("Expected all scanners to be batched every coalesce_interval")
+	}
+	integration.deferScanUpdates("desk", time.Now())
+	if pending := integration.takeFastPathPending(); len(pending) != 1 || pending[0] != "desk" {
+		t.Errorf("Expected desk pending, got %v", pending)
+	}
+	if total, _ := integration.scanCounters["desk"].snapshot(time.Now()); total != 1 {
+		t.Errorf("Expected the deferred scan to be counted, got %d", total)
+	}
+}
+
+func TestAllScannersDownSince(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	if _, down := integration.AllScannersDownSince(); down {
+		t.Error("Expected no scanners not to count as down")
+	}
+
+	integration.AddScanner("front", "Front", &config.ScannerConfig{ID: "front"})
+	integration.AddScanner("back", "Back", &config.ScannerConfig{ID: "back"})
+	since, down := integration.AllScannersDownSince()
+	if !down || !since.Equal(integration.createdAt) {
+		t.Errorf("Expected never connected scanners down since creation, got %v %v", since, down)
+	}
+
+	integration.SetScannerDeviceInfo("front", &hid.DeviceInfo{Product: "Front"})
+	integration.scanners["front"].Connected = true
+	if _, down := integration.AllScannersDownSince(); down {
+		t.Error("Expected a connected scanner to keep the bridge up")
+	}
+
+	disconnectedAt := integration.createdAt.Add(time.Minute)
+	integration.scanners["front"].Connected = false
+	integration.scanners["front"].Health.DisconnectedAt = &disconnectedAt
+	if since, down := integration.AllScannersDownSince(); !down || !since.Equal(disconnectedAt) {
+		t.Errorf("Expected down since the last disconnect %v, got %v %v", disconnectedAt, since, down)
+	}
+}
+
+func TestScannerStatsByID(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	integration.AddScanner("front", "Front", &config.ScannerConfig{ID: "front"})
+	integration.AddScanner("back", "Back", &config.ScannerConfig{ID: "back"})
+	integration.SetScannerDeviceInfo("front", &hid.DeviceInfo{Product: "Front Scanner"})
+
+	scanTime := time.Now()
+	integration.scanners["front"].Health.LastScanTime = &scanTime
+
+	stats, exists := integration.ScannerStatsByID("front")
+	if !exists || stats.Name != "Front Scanner" || stats.LastScanTime == nil || !stats.LastScanTime.Equal(scanTime) {
+		t.Errorf("Expected stats of the found scanner, got %+v %v", stats, exists)
+	}
+	if _, exists := integration.ScannerStatsByID("back"); exists {
+		t.Error("Expected no stats for a scanner whose device was not found")
+	}
+}
+
+func TestEntityNameLanguage(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", Language: "es"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	integration.AddScanner("front", "Front", &config.ScannerConfig{ID: "front"})
+	integration.SetScannerDeviceInfo("front", &hid.DeviceInfo{})
+
+	scanner := integration.scanners["front"]
+	if scanner.Name != "Escáner front" {
+		t.Errorf("Expected translated fallback device name, got %q", scanner.Name)
+	}
+	if name := integration.scannerEntityName(scanner, integration.names.Health); name != "Escáner front Estado" {
+		t.Errorf("Expected translated health entity name, got %q", name)
+	}
+	if name := integration.bridgeEntities.entities[0].Name; name != "Diagnóstico" {
+		t.Errorf("Expected translated diagnostics entity name, got %q", name)
+	}
+}
+
+func TestProcessStatsEntities(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	if entities := NewIntegration(mqttClient, haConfig, "1.0.0", logger).bridgeEntities.entities; len(entities) != 1 {
+		t.Errorf("Expected only the diagnostics entity without process_stats_interval, got %d entities", len(entities))
+	}
+
+	haConfig.ProcessStatsInterval = time.Minute
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	entities := integration.bridgeEntities.entities
+	if len(entities) != 4 {
+		t.Fatalf("Expected diagnostics and 3 process stats entities, got %d", len(entities))
+	}
+
+	for _, entity := range entities[1:] {
+		if !entity.Periodic || entity.GetShutdownState != nil {
+			t.Errorf("Expected %s to be periodic without a shutdown state", entity.EntityType)
+		}
+		status := entity.GetStatus(integration)
+		if value, err := strconv.ParseFloat(status, 64); err != nil || value < 0 {
+			t.Errorf("Expected numeric %s state, got %q", entity.EntityType, status)
+		}
+	}
+
+	attributes := entities[2].GetAttributes(integration)
+	for _, key := range []string{"heap_alloc_mib", "heap_sys_mib", "gc_runs", "gc_pause_total_ms"} {
+		if _, exists := attributes[key]; !exists {
+			t.Errorf("Expected memory attribute %s, got %v", key, attributes)
+		}
+	}
+}
+
+func TestBuildUpdateState(t *testing.T) {
+	release := &common.Release{
+		Version: "1.4.0",
+		Notes:   strings.Repeat("n", 300),
+		URL:     "https://example.com/1.4.0",
+	}
+
+	state := buildUpdateState("1.3.0", release)
+	if state.InstalledVersion != "1.3.0" || state.LatestVersion != "1.4.0" || state.ReleaseURL != release.URL {
+		t.Errorf("Expected update from 1.3.0 to 1.4.0, got %+v", state)
+	}
+	if length := len([]rune(state.ReleaseSummary)); length != releaseSummaryMaxLength {
+		t.Errorf("Expected release summary truncated to %d characters, got %d", releaseSummaryMaxLength, length)
+	}
+
+	for _, installed := range []string{"1.4.0", "1.5.0-dev"} {
+		state = buildUpdateState(installed, release)
+		if state.LatestVersion != installed || state.ReleaseSummary != "" {
+			t.Errorf("Expected %s to be up to date, got %+v", installed, state)
+		}
+	}
+}
+
+func TestClockMonitor(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name      string
+		wallShift time.Duration // Wall clock change between the checks besides the elapsed time
+		expected  bool
+	}{
+		{"No jump", 0, false},
+		{"NTP slew", 2 * time.Second, false},
+		{"Forward jump", 3 * time.Hour, true},
+		{"Backward jump", -10 * time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := newClockMonitor(start)
+			monitor.lastWall = monitor.lastWall.Add(-tt.wallShift)
+
+			jump, jumped := monitor.check(start.Add(clockCheckInterval))
+			if jumped != tt.expected {
+				t.Fatalf("Expected jump detected %v, got %v (%s)", tt.expected, jumped, jump)
+			}
+			attributes := map[string]any{}
+			monitor.addAttributes(attributes)
+			if !tt.expected {
+				if len(attributes) != 0 {
+					t.Errorf("Expected no clock attributes, got %v", attributes)
+				}
+				return
+			}
+			if jump != tt.wallShift {
+				t.Errorf("Expected jump %s, got %s", tt.wallShift, jump)
+			}
+			if attributes["clock_jumps"] != 1 || attributes["clock_warning"] == nil {
+				t.Errorf("Expected clock warning attributes, got %v", attributes)
+			}
+
+			// The next check measures from the jumped wall clock
+			if _, jumped := monitor.check(start.Add(2 * clockCheckInterval)); jumped {
+				t.Error("Expected a single jump to be reported once")
+			}
+		})
+	}
+}
+
+func TestHealthTransition(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	integration := &Integration{
+		mqtt:   mqttClient,
+		logger: logger,
+		config: &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"},
+		scanners: map[string]*ScannerDevice{
+			"test": {ID: "test", Name: "Test", Connected: true, Health: &ScannerHealthMetrics{LastSeen: time.Now()}},
+		},
+		clock: newClockMonitor(time.Now()),
+	}
+
+	var transitions []*HealthTransition
+	integration.SetOnHealthChangeCallback(func(transition *HealthTransition) {
+		transitions = append(transitions, transition)
+	})
+
+	integration.checkHealthTransition("test")
+	if len(transitions) != 0 {
+		t.Fatalf("Expected the first state not to be a transition, got %v", transitions)
+	}
+
+	scanner := integration.scanners["test"]
+	scanner.Health.ErrorCount = 11
+	integration.checkHealthTransition("test")
+	integration.checkHealthTransition("test")
+	if len(transitions) != 1 {
+		t.Fatalf("Expected 1 transition, got %d", len(transitions))
+	}
+	if transitions[0].From != HealthHealthy || transitions[0].To != HealthDegraded || transitions[0].Name != "Test" {
+		t.Errorf("Expected healthy -> degraded of Test, got %+v", transitions[0])
+	}
+	if transitions[0].Health["error_count"] != 11 {
+		t.Errorf("Expected health attributes, got %v", transitions[0].Health)
+	}
+
+	// Staleness is detected by the clock checks while MQTT is down
+	scanner.Connected = false
+	scanner.Health.LastSeen = time.Now().Add(-2 * scannerStaleAfter)
+	integration.checkClock(time.Now())
+	if len(transitions) != 2 || transitions[1].To != HealthStale {
+		t.Errorf("Expected degraded -> stale, got %v", transitions)
+	}
+}
+
+func TestRetainedAudit(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", RetainedAudit: true}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk"})
+	integration.AddScanner("dock", "Dock", &config.ScannerConfig{ID: "dock"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+	integration.scanners["desk"].Connected = true
+
+	expected := integration.expectedAvailabilities()
+	tests := []struct {
+		topic        string
+		availability string
+	}{
+		{"homeassistant/sensor/ha-barcode-bridge-test/availability", "online"},
+		{"homeassistant/sensor/ha-barcode-bridge-test-scanner-desk/availability", "online"},
+		{"homeassistant/sensor/ha-barcode-bridge-test-scanner-dock/availability", "offline"},
+	}
+	for _, tt := range tests {
+		if expected[tt.topic] != tt.availability {
+			t.Errorf("Expected availability '%s' for %s, got '%s'", tt.availability, tt.topic, expected[tt.topic])
+		}
+	}
+
+	orphans := map[string]bool{
+		"homeassistant/sensor/ha-barcode-bridge-test-scanner-desk/config":        false,
+		"homeassistant/sensor/ha-barcode-bridge-test-scanner-desk-health/config": false,
+		"homeassistant/sensor/ha-barcode-bridge-test-scanner-old/config":         true,
+		"homeassistant/sensor/ha-barcode-bridge-test-scanner-old-battery/state":  true,
+		"homeassistant/sensor/ha-barcode-bridge-test/availability":               false,
+		"homeassistant/sensor/ha-barcode-bridge-other-scanner-old/config":        false,
+	}
+	for topic, orphaned := range orphans {
+		if integration.isOrphanedTopic(topic) != orphaned {
+			t.Errorf("Expected orphaned %v for %s", orphaned, topic)
+		}
+	}
+
+	integration.retainedAudit.messages = make(map[string]string)
+	messages := []mqtt.Message{
+		{Topic: "homeassistant/sensor/ha-barcode-bridge-test-scanner-old/config", Payload: []byte("{}"), Retained: true},
+		{Topic: "homeassistant/sensor/ha-barcode-bridge-test-scanner-desk/config", Payload: []byte(""), Retained: true},
+		{Topic: "homeassistant/sensor/ha-barcode-bridge-test/availability", Payload: []byte("online")},
+		{Topic: "homeassistant/sensor/other/config", Payload: []byte("{}"), Retained: true},
+	}
+	for _, message := range messages {
+		integration.recordRetainedMessage(message)
+	}
+	if recorded := integration.retainedAudit.messages; len(recorded) != 1 {
+		t.Errorf("Expected only the retained message on the bridge's topics to be recorded, got %v", recorded)
+	}
+}
+
+func TestScanTriggerConfig(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test", DeviceTriggers: true}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+	integration.AddScanner("desk", "Desk", &config.ScannerConfig{ID: "desk"})
+	integration.SetScannerDeviceInfo("desk", &hid.DeviceInfo{Product: "Desk"})
+
+	scanner := integration.scanners["desk"]
+	data, err := json.Marshal(integration.scanTriggerConfig(scanner))
+	if err != nil {
+		t.Fatalf("Expected device trigger config to marshal, got: %v", err)
+	}
+
+	var triggerConfig map[string]any
+	if err := json.Unmarshal(data, &triggerConfig); err != nil {
+		t.Fatalf("Expected JSON device trigger config, got: %v", err)
+	}
+	expected := map[string]string{
+		"automation_type": "trigger",
+		"topic":           "homeassistant/device_automation/ha-barcode-bridge-test-scanner-desk-scanned/state",
+		"type":            ScanTriggerType,
+		"subtype":         ScanTriggerSubtype,
+	}
+	for key, value := range expected {
+		if triggerConfig[key] != value {
+			t.Errorf("Expected %s '%s', got %v", key, value, triggerConfig[key])
+		}
+	}
+	if _, exists := triggerConfig["device"]; !exists {
+		t.Error("Expected the trigger to be attached to the scanner device")
+	}
+
+	topics := integration.generateScanTriggerTopics("desk")
+	if topics.ConfigTopic != "homeassistant/device_automation/ha-barcode-bridge-test-scanner-desk-scanned/config" {
+		t.Errorf("Expected device_automation discovery topic, got %s", topics.ConfigTopic)
+	}
+}
+
+func TestRestartButton(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+
+	restarts := 0
+	integration.SetRestartControl(func() error {
+		restarts++
+		return nil
+	})
+
+	if topic := integration.generateRestartButtonTopic(); topic != "homeassistant/button/ha-barcode-bridge-test-restart" {
+		t.Errorf("Expected restart button topic, got %s", topic)
+	}
+
+	integration.handleRestartCommand("", []byte("ON"))
+	if restarts != 0 {
+		t.Error("Expected unknown commands to be ignored")
+	}
+	integration.handleRestartCommand("", []byte("press\n"))
+	if restarts != 1 {
+		t.Errorf("Expected one restart, got %d", restarts)
+	}
+}
+
+func TestScannerSwitch(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+
+	enabled := map[string]bool{"desk": true}
+	integration.SetScannerEnableControl(&ScannerEnableControl{
+		IsEnabled:  func(scannerID string) bool { return enabled[scannerID] },
+		SetEnabled: func(scannerID string, value bool) { enabled[scannerID] = value },
+	})
+
+	topics := integration.generateScannerSwitchTopics("desk")
+	if topics.ConfigTopic != "homeassistant/switch/ha-barcode-bridge-test-scanner-desk-enabled/config" {
+		t.Errorf("Expected scanner switch discovery topic, got %s", topics.ConfigTopic)
+	}
+
+	handler := integration.createScannerSwitchHandler("desk")
+	handler("", []byte("toggle"))
+	if !enabled["desk"] {
+		t.Error("Expected unknown commands to be ignored")
+	}
+	handler("", []byte("off"))
+	if enabled["desk"] {
+		t.Error("Expected desk to be disabled")
+	}
+	handler("", []byte("ON"))
+	if !enabled["desk"] {
+		t.Error("Expected desk to be enabled again")
+	}
+}
+
+func TestPauseSwitch(t *testing.T) {
+	client := newRecordingClient()
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(client, haConfig, "1.0.0", logger)
+
+	if err := integration.PublishPauseState(); err != nil || len(client.published) != 0 {
+		t.Errorf("Expected no pause state without pause control, got %v (%v)", client.published, err)
+	}
+
+	paused := false
+	var setErr error
+	integration.SetPauseControl(&PauseControl{
+		IsPaused: func() bool { return paused },
+		SetPaused: func(value bool) error {
+			if setErr != nil {
+				return setErr
+			}
+			paused = value
+			return nil
+		},
+	})
+	stateTopic := integration.generatePauseSwitchTopic() + "/state"
+	if stateTopic != "homeassistant/switch/ha-barcode-bridge-test-pause/state" {
+		t.Errorf("Expected pause switch state topic, got %s", stateTopic)
+	}
+
+	integration.setupPauseSwitch()
+	if states := client.published[stateTopic]; len(states) != 1 || states[0] != PayloadOff {
+		t.Errorf("Expected the initial pause state, got %v", states)
+	}
+
+	tests := []struct {
+		command  string
+		setErr   error
+		expected string
+	}{
+		{"on", nil, PayloadOn},
+		{"toggle", nil, ""},
+		{" OFF\n", nil, PayloadOff},
+		{"ON", errors.New("read-only file system"), PayloadOff},
+	}
+	for _, tt := range tests {
+		clear(client.published)
+		setErr = tt.setErr
+		integration.handlePauseCommand("", []byte(tt.command))
+
+		states := client.published[stateTopic]
+		if tt.expected == "" {
+			if len(states) != 0 {
+				t.Errorf("%q: expected unknown commands to be ignored, got %v", tt.command, states)
+			}
+			continue
+		}
+		if len(states) != 1 || states[0] != tt.expected {
+			t.Errorf("%q: expected state %s, got %v", tt.command, tt.expected, states)
+		}
+	}
+}
+
+func TestParseScanTimeout(t *testing.T) {
+	tests := []struct {
+		payload  string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"150", 150 * time.Millisecond, false},
+		{"150.0", 150 * time.Millisecond, false},
+		{" 2000\n", 2 * time.Second, false},
+		{"20", 20 * time.Millisecond, false},
+		{"10", 0, true},
+		{"2010", 0, true},
+		{"1e30", 0, true},
+		{"NaN", 0, true},
+		{"slow", 0, true},
+	}
+
+	for _, tt := range tests {
+		timeout, err := parseScanTimeout(tt.payload)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseScanTimeout(%q): expected error %v, got %v", tt.payload, tt.wantErr, err)
+			continue
+		}
+		if timeout != tt.expected {
+			t.Errorf("parseScanTimeout(%q): expected %s, got %s", tt.payload, tt.expected, timeout)
+		}
+	}
+}
+
+func TestScanTimeoutNumber(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+
+	timeouts := map[string]time.Duration{"desk": 100 * time.Millisecond}
+	integration.SetScanTimeoutControl(&ScanTimeoutControl{
+		Get: func(scannerID string) (time.Duration, bool) {
+			timeout, ok := timeouts[scannerID]
+			return timeout, ok
+		},
+		Set: func(scannerID string, timeout time.Duration) error {
+			timeouts[scannerID] = timeout
+			return nil
+		},
+	})
+
+	topics := integration.generateScanTimeoutTopics("desk")
+	if topics.ConfigTopic != "homeassistant/number/ha-barcode-bridge-test-scanner-desk-scan_timeout/config" {
+		t.Errorf("Expected scan timeout discovery topic, got %s", topics.ConfigTopic)
+	}
+	if !integration.hasScanTimeout("desk") || integration.hasScanTimeout("stdin") {
+		t.Error("Expected a scan timeout number only for scanners with a completion timeout")
+	}
+
+	handler := integration.createScanTimeoutHandler("desk")
+	handler("", []byte("5"))
+	if timeouts["desk"] != 100*time.Millisecond {
+		t.Errorf("Expected out of range commands to be ignored, got %s", timeouts["desk"])
+	}
+	handler("", []byte("250.0"))
+	if timeouts["desk"] != 250*time.Millisecond {
+		t.Errorf("Expected scan timeout 250ms, got %s", timeouts["desk"])
+	}
+}
+
+func TestKeyboardLayoutSelect(t *testing.T) {
+	logger := logrus.New()
+	mqttClient, err := mqtt.NewClient(&config.MQTTConfig{BrokerURL: "mqtt://localhost:1883", ClientID: "test"}, "", logger)
+	if err != nil {
+		t.Fatalf("Expected MQTT client, got error: %v", err)
+	}
+
+	haConfig := &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"}
+	integration := NewIntegration(mqttClient, haConfig, "1.0.0", logger)
+
+	layouts := map[string]string{"desk": "us"}
+	integration.SetKeyboardLayoutControl(&KeyboardLayoutControl{
+		Layouts: []string{"es", "us"},
+		Get: func(scannerID string) (string, bool) {
+			layout, ok := layouts[scannerID]
+			return layout, ok
+		},
+		Set: func(scannerID, layout string) error {
+			layouts[scannerID] = layout
+			return nil
+		},
+	})
+
+	topics := integration.generateKeyboardLayoutTopics("desk")
+	if topics.ConfigTopic != "homeassistant/select/ha-barcode-bridge-test-scanner-desk-keyboard_layout/config" {
+		t.Errorf("Expected keyboard layout discovery topic, got %s", topics.ConfigTopic)
+	}
+	if !integration.hasKeyboardLayout("desk") || integration.hasKeyboardLayout("stdin") {
+		t.Error("Expected a keyboard layout select only for keyboard scanners")
+	}
+
+	handler := integration.createKeyboardLayoutHandler("desk")
+	handler("", []byte("klingon"))
+	if layouts["desk"] != "us" {
+		t.Errorf("Expected unknown layouts to be ignored, got %s", layouts["desk"])
+	}
+	handler("", []byte("es"))
+	if layouts["desk"] != "es" {
+		t.Errorf("Expected keyboard layout 'es', got %s", layouts["desk"])
+	}
+}
+
+func TestScanRelay(t *testing.T) {
+	integration := &Integration{
+		logger: logrus.New(),
+		config: &config.HomeAssistantConfig{DiscoveryPrefix: "homeassistant", InstanceID: "test"},
+		scannerConfigs: map[string]*config.ScannerConfig{
+			"desk":    {ID: "desk"},
+			"kitchen": {ID: "kitchen"},
+		},
+	}
+
+	if err := integration.ExpectScan("desk", "pick-1", time.Minute); !errors.Is(err, ErrScanRelayDisabled) {
+		t.Errorf("Expected ErrScanRelayDisabled, got %v", err)
+	}
+
+	integration.SetScanRelay(&config.ScanRelayConfig{Token: "secret", Scanners: []string{"desk"}, MaxTimeout: time.Minute})
+	if topic := GenerateScanRelayTopic(integration.config); topic != "homeassistant/sensor/ha-barcode-bridge-test/expect_scan" {
+		t.Errorf("Expected expect_scan topic, got %s", topic)
+	}
+
+	if err := integration.ExpectScan("missing", "pick-1", time.Minute); !errors.Is(err, ErrScannerNotFound) {
+		t.Errorf("Expected ErrScannerNotFound, got %v", err)
+	}
+	if err := integration.ExpectScan("kitchen", "pick-1", time.Minute); !errors.Is(err, ErrScanRelayForbidden) {
+		t.Errorf("Expected ErrScanRelayForbidden, got %v", err)
+	}
+	if err := integration.ExpectScan("desk", "pick-1", time.Hour); err == nil {
+		t.Error("Expected error for a timeout above max_timeout")
+	}
+
+	integration.handleExpectScanCommand("", []byte(`{"scanner_id": "desk", "correlation_id": "pick-1", "timeout": 30, "token": "wrong"}`))
+	if id := integration.TakeExpectedScan("desk", time.Now()); id != "" {
+		t.Errorf("Expected requests with a wrong token to be ignored, got %s", id)
+	}
+
+	integration.handleExpectScanCommand("", []byte(`{"scanner_id": "desk", "correlation_id": "pick-1", "timeout": 30, "token": "secret"}`))
+	if id := integration.TakeExpectedScan("desk", time.Now()); id != "pick-1" {
+		t.Errorf("Expected the next scan to be tagged 'pick-1', got '%s'", id)
+	}
+	if id := integration.TakeExpectedScan("desk", time.Now()); id != "" {
+		t.Errorf("Expected only the next scan to be tagged, got '%s'", id)
+	}
+
+	if err := integration.ExpectScan("desk", "pick-2", time.Second); err != nil {
+		t.Fatalf("Expected scan to be expected, got error: %v", err)
+	}
+	if id := integration.TakeExpectedScan("desk", time.Now().Add(2*time.Second)); id != "" {
+		t.Errorf("Expected an expired request not to tag the scan, got '%s'", id)
+	}
+}
@@ -0,0 +1,127 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const keyboardLayoutSuffix = "keyboard_layout"
+
+// KeyboardLayoutControl connects the per-scanner "Keyboard Layout" selects to
+// the scanner manager. Get returns false for scanners whose driver doesn't
+// decode keyboard reports, which get no select.
+type KeyboardLayoutControl struct {
+	Layouts []string
+	Get     func(scannerID string) (string, bool)
+	Set     func(scannerID, layout string) error
+}
+
+// SetKeyboardLayoutControl enables the "Keyboard Layout" select on the devices
+// of keyboard scanners, to switch their layout without restarting the bridge.
+func (integration *Integration) SetKeyboardLayoutControl(control *KeyboardLayoutControl) {
+	integration.layoutControl = control
+}
+
+func (integration *Integration) generateKeyboardLayoutTopics(scannerID string) *ScannerTopics {
+	return integration.generateScannerComponentTopics("select", scannerID, keyboardLayoutSuffix)
+}
+
+func (integration *Integration) hasKeyboardLayout(scannerID string) bool {
+	if integration.layoutControl == nil {
+		return false
+	}
+	_, supported := integration.layoutControl.Get(scannerID)
+	return supported
+}
+
+func (integration *Integration) publishKeyboardLayoutDiscoveryConfig(scannerID string) error {
+	if !integration.hasKeyboardLayout(scannerID) {
+		return nil
+	}
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	topics := integration.generateKeyboardLayoutTopics(scannerID)
+	selectConfig := SensorConfig{
+		Name:         integration.scannerEntityName(scanner, integration.names.KeyboardLayout),
+		ObjectID:     integration.scannerObjectID(scannerID, keyboardLayoutSuffix),
+		UniqueID:     integration.scannerUniqueID(scannerID, keyboardLayoutSuffix),
+		TildeTopic:   topics.BaseTopic,
+		StateTopic:   "~/state",
+		CommandTopic: "~/set",
+		Options:      integration.layoutControl.Layouts,
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:         scanner.DeviceInfo,
+		Icon:           "mdi:keyboard-outline",
+		EntityCategory: "config",
+	}
+
+	configJSON, err := json.Marshal(selectConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal keyboard layout discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
+
+func (integration *Integration) publishKeyboardLayout(scannerID string) error {
+	if integration.layoutControl == nil || !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	layout, supported := integration.layoutControl.Get(scannerID)
+	if !supported {
+		return nil
+	}
+	return integration.mqtt.Publish(integration.generateKeyboardLayoutTopics(scannerID).StateTopic, layout, true)
+}
+
+func (integration *Integration) subscribeKeyboardLayouts() {
+	for scannerID := range integration.scannerConfigs {
+		integration.subscribeKeyboardLayout(scannerID)
+	}
+}
+
+// subscribeKeyboardLayout subscribes for every configured scanner, even those
+// not running yet, so the select works once the scanner is started.
+func (integration *Integration) subscribeKeyboardLayout(scannerID string) {
+	if integration.layoutControl == nil {
+		return
+	}
+
+	commandTopic := integration.generateKeyboardLayoutTopics(scannerID).BaseTopic + "/set"
+	if err := integration.mqtt.Subscribe(commandTopic, integration.createKeyboardLayoutHandler(scannerID)); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to subscribe to keyboard layout command topic")
+	}
+
+	if err := integration.publishKeyboardLayout(scannerID); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish keyboard layout")
+	}
+}
+
+func (integration *Integration) createKeyboardLayoutHandler(scannerID string) func(string, []byte) {
+	return func(_ string, payload []byte) {
+		logger := integration.logger.WithField("scanner_id", scannerID)
+
+		layout := strings.TrimSpace(string(payload))
+		if !slices.Contains(integration.layoutControl.Layouts, layout) {
+			logger.Warnf("Ignoring unknown keyboard layout '%s'", layout)
+		} else if err := integration.layoutControl.Set(scannerID, layout); err != nil {
+			logger.WithError(err).Error("Failed to set keyboard layout")
+		}
+
+		// Publish even when nothing changed so the select shows the layout in use.
+		if err := integration.publishKeyboardLayout(scannerID); err != nil {
+			logger.WithError(err).Error("Failed to publish keyboard layout")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package homeassistant
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const CurrentOperatorAttribute = "current_operator"
+
+// operatorSession tracks the operator that badged in on a scanner. It lives
+// outside ScannerDevice so a reconnect does not log the operator out.
+type operatorSession struct {
+	Name     string
+	Since    time.Time
+	LastScan time.Time
+}
+
+// parseOperatorBadge returns the operator name encoded in a badge barcode:
+// the first capture group of the pattern, or the whole barcode without one.
+func parseOperatorBadge(pattern *regexp.Regexp, barcode string) (string, bool) {
+	match := pattern.FindStringSubmatch(barcode)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 && match[1] != "" {
+		return match[1], true
+	}
+	return barcode, true
+}
+
+func (integration *Integration) registerOperatorMode(scannerID string) {
+	scannerCfg := integration.scannerConfigs[scannerID]
+	if scannerCfg == nil || scannerCfg.Operator == nil {
+		delete(integration.badgePatterns, scannerID)
+		return
+	}
+
+	pattern, err := regexp.Compile(scannerCfg.Operator.BadgePattern)
+	if err != nil {
+		integration.logger.WithError(err).Errorf("Invalid operator badge pattern for scanner %s", scannerID)
+		return
+	}
+	integration.badgePatterns[scannerID] = pattern
+}
+
+// HandleOperatorBadge checks whether a scan is an operator badge. Badge scans
+// switch the current operator and are not published as barcodes.
+func (integration *Integration) HandleOperatorBadge(scannerID, barcode string) (bool, error) {
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	pattern, enabled := integration.badgePatterns[scannerID]
+	if !enabled {
+		return false, nil
+	}
+
+	name, isBadge := parseOperatorBadge(pattern, barcode)
+	if !isBadge {
+		return false, nil
+	}
+
+	now := time.Now()
+	integration.operatorsMutex.Lock()
+	integration.operators[scannerID] = &operatorSession{Name: name, Since: now, LastScan: now}
+	integration.operatorsMutex.Unlock()
+	integration.logger.WithField("scanner_id", scannerID).Infof("Operator %s assigned to scanner", name)
+
+	if _, exists := integration.scanners[scannerID]; !exists || !integration.mqtt.IsConnected() {
+		return true, nil
+	}
+	if err := integration.publishScannerAttributes(scannerID); err != nil {
+		return true, fmt.Errorf("failed to publish operator attributes: %w", err)
+	}
+	return true, nil
+}
+
+// currentOperator returns the active operator session for a scanner, ending
+// it once the configured inactivity timeout has passed.
+func (integration *Integration) currentOperator(scannerID string, now time.Time) *operatorSession {
+	integration.operatorsMutex.Lock()
+	defer integration.operatorsMutex.Unlock()
+
+	session := integration.operators[scannerID]
+	if session == nil {
+		return nil
+	}
+
+	scannerCfg := integration.scannerConfigs[scannerID]
+	if scannerCfg != nil && scannerCfg.Operator != nil && scannerCfg.Operator.Timeout > 0 &&
+		now.Sub(session.LastScan) > scannerCfg.Operator.Timeout {
+		integration.logger.WithField("scanner_id", scannerID).Infof("Operator %s timed out", session.Name)
+		delete(integration.operators, scannerID)
+		return nil
+	}
+
+	return session
+}
+
+// touchOperator refreshes the operator inactivity timer on a scan. It reports
+// whether the operator changed because the previous session expired.
+func (integration *Integration) touchOperator(scannerID string, now time.Time) bool {
+	if _, enabled := integration.badgePatterns[scannerID]; !enabled {
+		return false
+	}
+
+	integration.operatorsMutex.Lock()
+	hadOperator := integration.operators[scannerID] != nil
+	integration.operatorsMutex.Unlock()
+	session := integration.currentOperator(scannerID, now)
+	if session == nil {
+		return hadOperator
+	}
+
+	session.LastScan = now
+	return false
+}
+
+func (integration *Integration) addOperatorAttributes(scannerID string, attributes map[string]any) {
+	if _, enabled := integration.badgePatterns[scannerID]; !enabled {
+		return
+	}
+
+	session := integration.currentOperator(scannerID, time.Now())
+	if session == nil {
+		attributes[CurrentOperatorAttribute] = nil
+		return
+	}
+
+	attributes[CurrentOperatorAttribute] = session.Name
+	attributes["operator_since"] = session.Since.Format(time.RFC3339)
+}
@@ -0,0 +1,238 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const pantryModeSuffix = "pantry_mode"
+
+var pantryActions = []string{config.PantryActionAdd, config.PantryActionConsume}
+
+// InventoryEvent is published for every scan of a scanner in pantry mode so
+// pantry-tracking integrations get the intended stock change, not just a code.
+type InventoryEvent struct {
+	Action    string `json:"action"`
+	Barcode   string `json:"barcode"`
+	Quantity  int    `json:"qty"`
+	ScannerID string `json:"scanner_id"`
+	Timestamp string `json:"timestamp"`
+}
+
+// pantryState holds the selected action and the quantity scanned for the next
+// item. It lives outside ScannerDevice so a reconnect keeps the mode.
+type pantryState struct {
+	mutex        sync.Mutex
+	action       string
+	nextQuantity int
+}
+
+func (p *pantryState) setAction(action string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.action = action
+}
+
+func (p *pantryState) currentAction() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.action
+}
+
+func (p *pantryState) setNextQuantity(quantity int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.nextQuantity = quantity
+}
+
+// takeItem returns the action and quantity for a scanned item and resets the
+// quantity to one.
+func (p *pantryState) takeItem() (action string, quantity int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	quantity = max(p.nextQuantity, 1)
+	p.nextQuantity = 0
+	return p.action, quantity
+}
+
+// parsePantryQuantity returns the quantity encoded in a quantity barcode.
+func parsePantryQuantity(prefix, barcode string) (quantity int, ok bool) {
+	if prefix == "" {
+		return 0, false
+	}
+
+	value, ok := strings.CutPrefix(barcode, prefix)
+	if !ok {
+		return 0, false
+	}
+
+	quantity, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || quantity < 1 {
+		return 0, false
+	}
+	return quantity, true
+}
+
+func (integration *Integration) registerPantryMode(scannerID string) {
+	scannerCfg := integration.scannerConfigs[scannerID]
+	if scannerCfg == nil || scannerCfg.Pantry == nil {
+		delete(integration.pantry, scannerID)
+		return
+	}
+
+	action := scannerCfg.Pantry.DefaultAction
+	if action == "" {
+		action = config.PantryActionAdd
+	}
+	if _, exists := integration.pantry[scannerID]; !exists {
+		integration.pantry[scannerID] = &pantryState{action: action}
+	}
+}
+
+// GenerateInventoryTopic returns the topic inventory events of a scanner are
+// published on.
+func (integration *Integration) GenerateInventoryTopic(scannerID string) string {
+	return integration.generateScannerTopics(scannerID).BaseTopic + "/inventory"
+}
+
+// HandlePantryQuantity checks whether a scan is a quantity barcode. Quantity
+// scans set the quantity of the next item and are not published as barcodes.
+func (integration *Integration) HandlePantryQuantity(scannerID, barcode string) bool {
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	state, enabled := integration.pantry[scannerID]
+	if !enabled {
+		return false
+	}
+
+	quantity, ok := parsePantryQuantity(integration.scannerConfigs[scannerID].Pantry.QuantityPrefix, barcode)
+	if !ok {
+		return false
+	}
+
+	state.setNextQuantity(quantity)
+	integration.logger.WithField("scanner_id", scannerID).Infof("Quantity for next item set to %d", quantity)
+	return true
+}
+
+// PublishInventoryEvent publishes the inventory event for a scanned item. It
+// returns nil when the scanner is not in pantry mode.
+func (integration *Integration) PublishInventoryEvent(scannerID, barcode string) (*InventoryEvent, error) {
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	state, enabled := integration.pantry[scannerID]
+	if !enabled {
+		return nil, nil
+	}
+
+	action, quantity := state.takeItem()
+	event := &InventoryEvent{
+		Action:    action,
+		Barcode:   barcode,
+		Quantity:  quantity,
+		ScannerID: scannerID,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return event, fmt.Errorf("failed to marshal inventory event: %w", err)
+	}
+
+	if !integration.mqtt.IsConnected() {
+		return event, fmt.Errorf("MQTT not connected")
+	}
+	return event, integration.mqtt.Publish(integration.GenerateInventoryTopic(scannerID), string(payload), false)
+}
+
+func (integration *Integration) publishPantryModeDiscoveryConfig(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	topics := integration.generateScannerComponentTopics("select", scannerID, pantryModeSuffix)
+
+	selectConfig := SensorConfig{
+		Name:           integration.scannerEntityName(scanner, integration.names.PantryMode),
+		ObjectID:       integration.scannerObjectID(scannerID, pantryModeSuffix),
+		UniqueID:       integration.scannerUniqueID(scannerID, pantryModeSuffix),
+		TildeTopic:     topics.BaseTopic,
+		StateTopic:     "~/state",
+		CommandTopic:   "~/set",
+		Options:        pantryActions,
+		Device:         scanner.DeviceInfo,
+		Icon:           "mdi:fridge-outline",
+		EntityCategory: "config",
+	}
+
+	selectConfig.Availability, selectConfig.AvailabilityMode = integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	configJSON, err := json.Marshal(selectConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pantry mode discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
+
+func (integration *Integration) publishPantryMode(scannerID string) error {
+	state, enabled := integration.pantry[scannerID]
+	if !enabled || !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	topics := integration.generateScannerComponentTopics("select", scannerID, pantryModeSuffix)
+	return integration.mqtt.Publish(topics.StateTopic, state.currentAction(), true)
+}
+
+func (integration *Integration) subscribePantryModes() {
+	for scannerID := range integration.pantry {
+		integration.subscribePantryMode(scannerID)
+	}
+}
+
+func (integration *Integration) subscribePantryMode(scannerID string) {
+	topics := integration.generateScannerComponentTopics("select", scannerID, pantryModeSuffix)
+	commandTopic := topics.BaseTopic + "/set"
+	if err := integration.mqtt.Subscribe(commandTopic, integration.createPantryModeHandler(scannerID)); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to subscribe to pantry mode command topic")
+	}
+
+	if err := integration.publishPantryMode(scannerID); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish pantry mode")
+	}
+}
+
+func (integration *Integration) createPantryModeHandler(scannerID string) func(string, []byte) {
+	return func(_ string, payload []byte) {
+		logger := integration.logger.WithField("scanner_id", scannerID)
+
+		state, enabled := integration.pantry[scannerID]
+		if !enabled {
+			return
+		}
+
+		action := strings.ToLower(strings.TrimSpace(string(payload)))
+		if !slices.Contains(pantryActions, action) {
+			logger.Warnf("Ignoring unknown pantry mode '%s'", action)
+		} else {
+			state.setAction(action)
+			logger.Infof("Pantry mode set to %s", action)
+		}
+
+		if err := integration.publishPantryMode(scannerID); err != nil {
+			logger.WithError(err).Error("Failed to publish pantry mode")
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+)
+
+const (
+	parsedFieldSuffix = "field"
+
+	// Home Assistant rejects longer sensor states
+	parsedFieldMaxLength = 255
+)
+
+// parsedFieldEntitySuffix names the sensor of a parsed field. Fields like
+// url_param_<name> and json_<key> come from the barcode, so anything that is
+// not safe in a topic or entity ID is replaced.
+func parsedFieldEntitySuffix(field string) string {
+	suffix := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(field))
+	return parsedFieldSuffix + "_" + suffix
+}
+
+// publishParsedFields publishes the fields a parse stage with sensors added
+// to the scan, each on its own sensor. A field's sensor is discovered the
+// first time the field is scanned and keeps the value of the last scan that
+// had it. Failures are only logged, the scan already reached Home Assistant.
+func (integration *Integration) publishParsedFields(scanner *ScannerDevice, metadata ScanMetadata) {
+	scannerConfig := integration.scannerConfigs[scanner.ID]
+	if scannerConfig == nil || !scannerConfig.ParsedFieldSensors() {
+		return
+	}
+
+	logger := integration.logger.WithField("scanner_id", scanner.ID)
+	for field, value := range common.PayloadFields(metadata.Attributes) {
+		if !scanner.ParsedFields[field] {
+			if err := integration.publishParsedFieldDiscoveryConfig(scanner, field); err != nil {
+				logger.WithError(err).Errorf("Failed to publish discovery config of parsed field %s", field)
+				continue
+			}
+			if scanner.ParsedFields == nil {
+				scanner.ParsedFields = make(map[string]bool)
+			}
+			scanner.ParsedFields[field] = true
+		}
+
+		if runes := []rune(value); len(runes) > parsedFieldMaxLength {
+			value = string(runes[:parsedFieldMaxLength-1]) + "…"
+		}
+		topics := integration.generateScannerSubEntityTopics(scanner.ID, parsedFieldEntitySuffix(field))
+		if err := integration.mqtt.Publish(topics.StateTopic, value, true); err != nil {
+			logger.WithError(err).Errorf("Failed to publish parsed field %s", field)
+		}
+	}
+}
+
+func (integration *Integration) publishParsedFieldDiscoveryConfig(scanner *ScannerDevice, field string) error {
+	if scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s device info not set", scanner.ID)
+	}
+
+	suffix := parsedFieldEntitySuffix(field)
+	topics := integration.generateScannerSubEntityTopics(scanner.ID, suffix)
+	sensorConfig := SensorConfig{
+		Name:       integration.scannerEntityName(scanner, field),
+		ObjectID:   integration.scannerObjectID(scanner.ID, suffix),
+		UniqueID:   integration.scannerUniqueID(scanner.ID, suffix),
+		TildeTopic: topics.BaseTopic,
+		StateTopic: "~/state",
+		Device:     scanner.DeviceInfo,
+		Icon:       "mdi:form-textbox",
+	}
+
+	sensorConfig.Availability, sensorConfig.AvailabilityMode = integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	configJSON, err := json.Marshal(sensorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parsed field discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
@@ -0,0 +1,99 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PauseControl connects the bridge pause switch to the instance kill switch.
+type PauseControl struct {
+	IsPaused  func() bool
+	SetPaused func(paused bool) error
+}
+
+// SetPauseControl enables the "Pause Publishing" switch on the bridge device.
+func (integration *Integration) SetPauseControl(control *PauseControl) {
+	integration.pauseControl = control
+}
+
+func (integration *Integration) generatePauseSwitchTopic() string {
+	bridgeID := generateBridgeDeviceID(integration.config)
+	return fmt.Sprintf("%s/switch/%s-pause", integration.config.DiscoveryPrefix, bridgeID)
+}
+
+func (integration *Integration) publishPauseSwitchDiscoveryConfig() error {
+	baseTopic := integration.generatePauseSwitchTopic()
+	bridgeID := generateBridgeDeviceID(integration.config)
+
+	switchConfig := SensorConfig{
+		Name:         integration.names.PausePublishing,
+		UniqueID:     fmt.Sprintf("%s-pause", bridgeID),
+		TildeTopic:   baseTopic,
+		StateTopic:   "~/state",
+		CommandTopic: "~/set",
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:         integration.bridgeDeviceInfo,
+		Icon:           "mdi:pause-octagon",
+		EntityCategory: "config",
+		PayloadOn:      PayloadOn,
+		PayloadOff:     PayloadOff,
+	}
+
+	configJSON, err := json.Marshal(switchConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pause switch discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(baseTopic+"/config", string(configJSON), true)
+}
+
+// PublishPauseState reports the current pause state on the switch.
+func (integration *Integration) PublishPauseState() error {
+	if integration.pauseControl == nil || !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	state := boolPayload(integration.pauseControl.IsPaused())
+	return integration.mqtt.Publish(integration.generatePauseSwitchTopic()+"/state", state, true)
+}
+
+func (integration *Integration) setupPauseSwitch() {
+	if integration.pauseControl == nil {
+		return
+	}
+
+	if err := integration.publishPauseSwitchDiscoveryConfig(); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish pause switch discovery config")
+	}
+
+	commandTopic := integration.generatePauseSwitchTopic() + "/set"
+	if err := integration.mqtt.Subscribe(commandTopic, integration.handlePauseCommand); err != nil {
+		integration.logger.WithError(err).Error("Failed to subscribe to pause switch command topic")
+	}
+
+	if err := integration.PublishPauseState(); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish pause state")
+	}
+}
+
+func (integration *Integration) handlePauseCommand(_ string, payload []byte) {
+	command := strings.ToUpper(strings.TrimSpace(string(payload)))
+	if command != PayloadOn && command != PayloadOff {
+		integration.logger.Warnf("Ignoring unknown pause switch command '%s'", command)
+		return
+	}
+
+	if err := integration.pauseControl.SetPaused(command == PayloadOn); err != nil {
+		integration.logger.WithError(err).Error("Failed to change pause state")
+	}
+
+	// Publish even when nothing changed so the switch does not stay optimistic.
+	if err := integration.PublishPauseState(); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish pause state")
+	}
+}
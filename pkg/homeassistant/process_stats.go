@@ -0,0 +1,101 @@
+package homeassistant
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+)
+
+const bytesPerMiB = 1024 * 1024
+
+// processStatsEntities are the bridge sensors reporting the process resource
+// usage, published with process_stats_interval.
+func (integration *Integration) processStatsEntities() []BridgeEntity {
+	return []BridgeEntity{
+		{
+			EntityType:        "uptime",
+			Name:              integration.names.Uptime,
+			Icon:              "mdi:timer-outline",
+			DeviceClass:       "duration",
+			UnitOfMeasurement: "s",
+			Retain:            true,
+			Periodic:          true,
+			GetStatus: func(*Integration) string {
+				return strconv.FormatInt(int64(common.CollectProcessStats().Uptime.Seconds()), 10)
+			},
+		},
+		{
+			EntityType:        "memory",
+			Name:              integration.names.Memory,
+			Icon:              "mdi:memory",
+			DeviceClass:       "data_size",
+			UnitOfMeasurement: "MiB",
+			StateClass:        "measurement",
+			Retain:            true,
+			Periodic:          true,
+			GetStatus: func(*Integration) string {
+				return fmt.Sprintf("%.1f", float64(common.CollectProcessStats().RSS)/bytesPerMiB)
+			},
+			GetAttributes: func(*Integration) map[string]any {
+				stats := common.CollectProcessStats()
+				attributes := map[string]any{
+					"heap_alloc_mib":    roundMiB(stats.HeapAlloc),
+					"heap_sys_mib":      roundMiB(stats.HeapSys),
+					"gc_runs":           stats.NumGC,
+					"gc_pause_total_ms": stats.GCPauseTotal.Milliseconds(),
+				}
+				if !stats.LastGC.IsZero() {
+					attributes["last_gc"] = stats.LastGC.Format(time.RFC3339)
+				}
+				return attributes
+			},
+		},
+		{
+			EntityType: "goroutines",
+			Name:       integration.names.Goroutines,
+			Icon:       "mdi:format-list-numbered",
+			StateClass: "measurement",
+			Retain:     true,
+			Periodic:   true,
+			GetStatus: func(*Integration) string {
+				return strconv.Itoa(common.CollectProcessStats().Goroutines)
+			},
+		},
+	}
+}
+
+func roundMiB(bytes uint64) float64 {
+	return float64(bytes*10/bytesPerMiB) / 10
+}
+
+// runProcessStatsUpdates republishes the process stats sensors, which change
+// without any event of their own.
+func (integration *Integration) runProcessStatsUpdates() {
+	ticker := time.NewTicker(integration.config.ProcessStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-integration.stopCh:
+			return
+		case <-ticker.C:
+			if integration.mqtt.IsConnected() {
+				integration.publishProcessStats()
+			}
+		}
+	}
+}
+
+func (integration *Integration) publishProcessStats() {
+	for i := range integration.bridgeEntities.entities {
+		entity := &integration.bridgeEntities.entities[i]
+		if !entity.Periodic {
+			continue
+		}
+		if err := integration.bridgeEntities.publishEntityState(entity); err != nil {
+			integration.logger.WithError(err).Errorf("Failed to update %s", entity.Name)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package homeassistant
+
+// publishRawBarcode publishes the barcode as is to the raw_topic of the
+// scanner. A failure is only logged, the scan already reached Home
+// Assistant.
+func (integration *Integration) publishRawBarcode(scannerID, barcode string) {
+	scannerConfig := integration.scannerConfigs[scannerID]
+	if scannerConfig == nil || scannerConfig.RawTopic == "" {
+		return
+	}
+
+	var err error
+	if scannerConfig.FastPath {
+		err = integration.mqtt.PublishFast(scannerConfig.RawTopic, barcode)
+	} else {
+		err = integration.mqtt.Publish(scannerConfig.RawTopic, barcode, false)
+	}
+	if err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish barcode to raw topic")
+	}
+}
@@ -0,0 +1,151 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	readQualitySuffix = "read_quality"
+
+	// readQualityUnknown is the payload Home Assistant shows as unknown,
+	// published while no scans are in the window.
+	readQualityUnknown = "None"
+)
+
+// readQuality tracks how many scans of a scanner passed validation over the
+// same rolling window as the scan rate sensor. A rising share of rejected
+// scans hints at a dirty window or a failing laser.
+type readQuality struct {
+	mutex sync.Mutex
+	reads []qualityRead
+}
+
+type qualityRead struct {
+	at       time.Time
+	rejected bool
+}
+
+func (q *readQuality) record(now time.Time, rejected bool) (accepted, rejectedCount int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.reads = append(q.reads, qualityRead{at: now, rejected: rejected})
+	return q.countLocked(now)
+}
+
+func (q *readQuality) snapshot(now time.Time) (accepted, rejected int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.countLocked(now)
+}
+
+func (q *readQuality) countLocked(now time.Time) (accepted, rejected int) {
+	cutoff := now.Add(-ScanRateWindow)
+	expired := 0
+	for expired < len(q.reads) && !q.reads[expired].at.After(cutoff) {
+		expired++
+	}
+	q.reads = q.reads[expired:]
+
+	for _, read := range q.reads {
+		if read.rejected {
+			rejected++
+		} else {
+			accepted++
+		}
+	}
+	return accepted, rejected
+}
+
+// formatReadQuality returns the percentage of accepted scans with one
+// decimal, or unknown without scans.
+func formatReadQuality(accepted, rejected int) string {
+	total := accepted + rejected
+	if total == 0 {
+		return readQualityUnknown
+	}
+	return strconv.FormatFloat(100*float64(accepted)/float64(total), 'f', 1, 64)
+}
+
+// RecordRead counts a scan for the read quality sensor; rejected scans are
+// the ones a validate stage dropped as misreads.
+func (integration *Integration) RecordRead(scannerID string, rejected bool) {
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	quality, exists := integration.readQualities[scannerID]
+	if !exists {
+		return
+	}
+
+	accepted, rejectedCount := quality.record(time.Now(), rejected)
+	if integration.isCoalesced(scannerID) {
+		integration.markPending(scannerID)
+		return
+	}
+	integration.publishReadQuality(scannerID, accepted, rejectedCount)
+}
+
+func (integration *Integration) publishReadQuality(scannerID string, accepted, rejected int) {
+	if _, exists := integration.scanners[scannerID]; !exists {
+		return
+	}
+	logger := integration.logger.WithField("scanner_id", scannerID)
+	topics := integration.generateScannerSubEntityTopics(scannerID, readQualitySuffix)
+
+	if err := integration.mqtt.Publish(topics.StateTopic, formatReadQuality(accepted, rejected), true); err != nil {
+		logger.WithError(err).Error("Failed to publish read quality")
+	}
+
+	attributes, err := json.Marshal(map[string]any{"accepted": accepted, "rejected": rejected})
+	if err != nil {
+		return
+	}
+	if err := integration.mqtt.Publish(topics.AttributesTopic, string(attributes), true); err != nil {
+		logger.WithError(err).Error("Failed to publish read quality attributes")
+	}
+}
+
+func (integration *Integration) publishAllReadQualities() {
+	now := time.Now()
+	for scannerID, quality := range integration.readQualities {
+		accepted, rejected := quality.snapshot(now)
+		integration.publishReadQuality(scannerID, accepted, rejected)
+	}
+}
+
+func (integration *Integration) publishScannerReadQualityDiscoveryConfig(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	topics := integration.generateScannerSubEntityTopics(scannerID, readQualitySuffix)
+	sensorConfig := SensorConfig{
+		Name:              integration.scannerEntityName(scanner, integration.names.ReadQuality),
+		ObjectID:          integration.scannerObjectID(scannerID, readQualitySuffix),
+		UniqueID:          integration.scannerUniqueID(scannerID, readQualitySuffix),
+		TildeTopic:        topics.BaseTopic,
+		StateTopic:        "~/state",
+		AttributesTopic:   "~/attributes",
+		Device:            scanner.DeviceInfo,
+		Icon:              "mdi:barcode-scan",
+		EntityCategory:    "diagnostic",
+		UnitOfMeasurement: "%",
+		StateClass:        "measurement",
+	}
+
+	sensorConfig.Availability, sensorConfig.AvailabilityMode = integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	configJSON, err := json.Marshal(sensorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal read quality discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
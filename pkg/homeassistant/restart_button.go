@@ -0,0 +1,76 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PayloadPress is the command Home Assistant sends when a button is pressed.
+const PayloadPress = "PRESS"
+
+// SetRestartControl enables the "Restart Scanners" button on the bridge
+// device, which calls restart when pressed.
+func (integration *Integration) SetRestartControl(restart func() error) {
+	integration.restartScanners = restart
+}
+
+func (integration *Integration) generateRestartButtonTopic() string {
+	bridgeID := generateBridgeDeviceID(integration.config)
+	return fmt.Sprintf("%s/button/%s-restart", integration.config.DiscoveryPrefix, bridgeID)
+}
+
+func (integration *Integration) publishRestartButtonDiscoveryConfig() error {
+	baseTopic := integration.generateRestartButtonTopic()
+	bridgeID := generateBridgeDeviceID(integration.config)
+
+	buttonConfig := SensorConfig{
+		Name:         integration.names.RestartScanners,
+		UniqueID:     fmt.Sprintf("%s-restart", bridgeID),
+		TildeTopic:   baseTopic,
+		CommandTopic: "~/press",
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:         integration.bridgeDeviceInfo,
+		DeviceClass:    "restart",
+		EntityCategory: "config",
+	}
+
+	configJSON, err := json.Marshal(buttonConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal restart button discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(baseTopic+"/config", string(configJSON), true)
+}
+
+func (integration *Integration) setupRestartButton() {
+	if integration.restartScanners == nil {
+		return
+	}
+
+	if err := integration.publishRestartButtonDiscoveryConfig(); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish restart button discovery config")
+	}
+
+	commandTopic := integration.generateRestartButtonTopic() + "/press"
+	if err := integration.mqtt.Subscribe(commandTopic, integration.handleRestartCommand); err != nil {
+		integration.logger.WithError(err).Error("Failed to subscribe to restart button command topic")
+	}
+}
+
+func (integration *Integration) handleRestartCommand(_ string, payload []byte) {
+	command := strings.ToUpper(strings.TrimSpace(string(payload)))
+	if command != PayloadPress {
+		integration.logger.Warnf("Ignoring unknown restart button command '%s'", command)
+		return
+	}
+
+	integration.logger.Info("Restart button pressed, restarting scanners")
+	if err := integration.restartScanners(); err != nil {
+		integration.logger.WithError(err).Error("Failed to restart scanners")
+	}
+}
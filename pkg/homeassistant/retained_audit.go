@@ -0,0 +1,223 @@
+package homeassistant
+
+import (
+	"cmp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+// retainedAuditWindow is how long the audit collects retained messages. The
+// broker sends them right after the subscription, so this only has to cover
+// the round trip of a large retained store.
+const retainedAuditWindow = 3 * time.Second
+
+// RetainedAuditSummary is the result of the last retained message audit,
+// published as the retained_audit attribute of the bridge diagnostics.
+type RetainedAuditSummary struct {
+	Time                 time.Time `json:"time"`
+	RetainedTopics       int       `json:"retained_topics"`
+	OrphanedTopics       int       `json:"orphaned_topics"`
+	OrphansCleared       int       `json:"orphans_cleared"`
+	AvailabilityRepaired int       `json:"availability_repaired"`
+}
+
+// retainedAudit collects the retained messages on the bridge's topics after
+// a connect, to repair what a broker restored from a backup brought back.
+type retainedAudit struct {
+	mutex    sync.Mutex
+	running  bool
+	messages map[string]string
+	summary  *RetainedAuditSummary
+}
+
+// SetAutoDiscover tells the retained audit that scanners may appear that are
+// not in the configuration, so their retained topics are not orphans.
+func (integration *Integration) SetAutoDiscover(enabled bool) {
+	integration.autoDiscover = enabled
+}
+
+// startRetainedAudit runs the audit in the background, the connect callback
+// must not wait for the audit window. A reconnect while an audit is still
+// collecting doesn't start another one.
+func (integration *Integration) startRetainedAudit() {
+	if !integration.config.RetainedAudit {
+		return
+	}
+
+	audit := &integration.retainedAudit
+	audit.mutex.Lock()
+	if audit.running {
+		audit.mutex.Unlock()
+		return
+	}
+	audit.running = true
+	audit.messages = make(map[string]string)
+	audit.mutex.Unlock()
+
+	go integration.runRetainedAudit()
+}
+
+// runRetainedAudit subscribes to the discovery prefix right after the
+// current state was published, so the retained messages that differ from it
+// were left over from before, e.g. restored with a broker backup.
+func (integration *Integration) runRetainedAudit() {
+	audit := &integration.retainedAudit
+	defer func() {
+		audit.mutex.Lock()
+		audit.running = false
+		audit.messages = nil
+		audit.mutex.Unlock()
+	}()
+
+	filter := integration.config.DiscoveryPrefix + "/#"
+	if err := integration.mqtt.SubscribeMessages(filter, integration.recordRetainedMessage); err != nil {
+		integration.logger.WithError(err).Error("Failed to subscribe for the retained message audit")
+		return
+	}
+
+	select {
+	case <-integration.stopCh:
+	case <-time.After(retainedAuditWindow):
+	}
+
+	if err := integration.mqtt.Unsubscribe(filter); err != nil {
+		integration.logger.WithError(err).Warn("Failed to unsubscribe after the retained message audit")
+	}
+
+	audit.mutex.Lock()
+	messages := audit.messages
+	audit.messages = nil
+	audit.mutex.Unlock()
+
+	summary := integration.repairRetainedMessages(messages)
+
+	audit.mutex.Lock()
+	audit.summary = summary
+	audit.mutex.Unlock()
+
+	integration.logger.WithFields(map[string]any{
+		"retained_topics":       summary.RetainedTopics,
+		"orphaned_topics":       summary.OrphanedTopics,
+		"orphans_cleared":       summary.OrphansCleared,
+		"availability_repaired": summary.AvailabilityRepaired,
+	}).Info("Retained message audit completed")
+
+	integration.bridgeEntities.publishAllStates()
+}
+
+// recordRetainedMessage keeps the retained messages on the bridge's topics.
+// Live messages, including the bridge's own publishes, are ignored.
+func (integration *Integration) recordRetainedMessage(message mqtt.Message) {
+	if !message.Retained || len(message.Payload) == 0 {
+		return
+	}
+	if _, owned := ParseBridgeTopic(integration.config, message.Topic); !owned {
+		return
+	}
+
+	audit := &integration.retainedAudit
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+	if audit.messages != nil {
+		audit.messages[message.Topic] = string(message.Payload)
+	}
+}
+
+// repairRetainedMessages clears the retained topics of scanners that are no
+// longer configured and republishes availabilities that don't match the
+// current scanner state.
+func (integration *Integration) repairRetainedMessages(messages map[string]string) *RetainedAuditSummary {
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	summary := &RetainedAuditSummary{Time: time.Now(), RetainedTopics: len(messages)}
+	expected := integration.expectedAvailabilities()
+
+	for topic, payload := range messages {
+		logger := integration.logger.WithField("topic", topic)
+
+		if availability, audited := expected[topic]; audited {
+			if payload == availability {
+				continue
+			}
+			if err := integration.mqtt.Publish(topic, availability, true); err != nil {
+				logger.WithError(err).Error("Failed to repair retained availability")
+				continue
+			}
+			logger.Infof("Repaired retained availability '%s', now '%s'", payload, availability)
+			summary.AvailabilityRepaired++
+			continue
+		}
+
+		if !integration.isOrphanedTopic(topic) {
+			continue
+		}
+		summary.OrphanedTopics++
+		if integration.autoDiscover {
+			logger.Debug("Keeping retained topic of an unconfigured scanner, it may be auto-discovered")
+			continue
+		}
+		if err := integration.mqtt.Publish(topic, "", true); err != nil {
+			logger.WithError(err).Error("Failed to clear orphaned retained topic")
+			continue
+		}
+		logger.Info("Cleared orphaned retained topic of an unconfigured scanner")
+		summary.OrphansCleared++
+	}
+
+	return summary
+}
+
+// expectedAvailabilities maps the availability topics of the bridge and the
+// configured scanners to their current availability. Scanners that haven't
+// been connected since the start are offline.
+func (integration *Integration) expectedAvailabilities() map[string]string {
+	expected := map[string]string{integration.GenerateBridgeAvailabilityTopic(): "online"}
+	for scannerID := range integration.scannerConfigs {
+		availability := StatusOffline
+		if scanner, exists := integration.scanners[scannerID]; exists && scanner.Connected {
+			availability = "online"
+		}
+		expected[integration.generateScannerTopics(scannerID).AvailabilityTopic] = availability
+	}
+	return expected
+}
+
+// isOrphanedTopic reports whether a topic belongs to a scanner entity of
+// this bridge whose scanner is not configured. ParseBridgeTopic only knows
+// the default topic layout, other layouts have no orphans.
+func (integration *Integration) isOrphanedTopic(topic string) bool {
+	topics := &integration.config.Topics
+	if cmp.Or(topics.Discovery, config.DefaultDiscoveryTopicTemplate) != config.DefaultDiscoveryTopicTemplate ||
+		cmp.Or(topics.State, config.DefaultStateTopicTemplate) != config.DefaultStateTopicTemplate {
+		return false
+	}
+
+	scannerObject, owned := ParseBridgeTopic(integration.config, topic)
+	if !owned || scannerObject == "" {
+		return false
+	}
+	for scannerID := range integration.scannerConfigs {
+		if scannerObject == scannerID || strings.HasPrefix(scannerObject, scannerID+"-") {
+			return false
+		}
+	}
+	return true
+}
+
+// addRetainedAuditAttributes adds the summary of the last retained message
+// audit to the bridge diagnostics attributes.
+func (integration *Integration) addRetainedAuditAttributes(attributes map[string]any) {
+	audit := &integration.retainedAudit
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+	if audit.summary != nil {
+		attributes["retained_audit"] = audit.summary
+	}
+}
@@ -0,0 +1,193 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ScanRateWindow is the rolling window of the "scans last hour" sensor.
+	ScanRateWindow = time.Hour
+
+	scanRateUpdateInterval = time.Minute
+	scanCountUnit          = "scans"
+)
+
+// scanCounter keeps the scan statistics published for a scanner. It survives
+// device reconnects; a bridge restart starts over from zero, which the
+// total_increasing state class treats as a meter reset.
+type scanCounter struct {
+	mutex  sync.Mutex
+	total  int64
+	recent []time.Time
+}
+
+func (c *scanCounter) record(now time.Time) (total int64, windowCount int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.total++
+	c.recent = append(c.recent, now)
+	return c.total, c.pruneLocked(now)
+}
+
+func (c *scanCounter) snapshot(now time.Time) (total int64, windowCount int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.total, c.pruneLocked(now)
+}
+
+func (c *scanCounter) reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.total = 0
+	c.recent = nil
+}
+
+func (c *scanCounter) pruneLocked(now time.Time) int {
+	cutoff := now.Add(-ScanRateWindow)
+	expired := 0
+	for expired < len(c.recent) && !c.recent[expired].After(cutoff) {
+		expired++
+	}
+	c.recent = c.recent[expired:]
+	return len(c.recent)
+}
+
+// GenerateScanCountResetTopic returns the topic that resets the scan counters
+// of a scanner when any payload is published to it.
+func (integration *Integration) GenerateScanCountResetTopic(scannerID string) string {
+	return integration.generateScannerSubEntityTopics(scannerID, "scans").BaseTopic + "/reset"
+}
+
+func (integration *Integration) recordScan(scannerID string, now time.Time) {
+	counter, exists := integration.scanCounters[scannerID]
+	if !exists {
+		return
+	}
+
+	total, windowCount := counter.record(now)
+	integration.publishScanCounts(scannerID, total, windowCount)
+}
+
+func (integration *Integration) publishScanCounts(scannerID string, total int64, windowCount int) {
+	logger := integration.logger.WithField("scanner_id", scannerID)
+
+	scansTopics := integration.generateScannerSubEntityTopics(scannerID, "scans")
+	if err := integration.mqtt.Publish(scansTopics.StateTopic, strconv.FormatInt(total, 10), true); err != nil {
+		logger.WithError(err).Error("Failed to publish scan count")
+	}
+
+	rateTopics := integration.generateScannerSubEntityTopics(scannerID, "scan_rate")
+	if err := integration.mqtt.Publish(rateTopics.StateTopic, strconv.Itoa(windowCount), true); err != nil {
+		logger.WithError(err).Error("Failed to publish scan rate")
+	}
+}
+
+func (integration *Integration) publishAllScanCounts() {
+	now := time.Now()
+	for scannerID, counter := range integration.scanCounters {
+		total, windowCount := counter.snapshot(now)
+		integration.publishScanCounts(scannerID, total, windowCount)
+	}
+}
+
+// runScanRateUpdates republishes the rolling windows so the rate and read
+// quality sensors decay while a scanner is idle.
+func (integration *Integration) runScanRateUpdates() {
+	ticker := time.NewTicker(scanRateUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-integration.stopCh:
+			return
+		case <-ticker.C:
+			if integration.mqtt.IsConnected() {
+				integration.scannersMutex.RLock()
+				integration.publishAllScanCounts()
+				integration.publishAllReadQualities()
+				integration.scannersMutex.RUnlock()
+				// Sink deliveries don't trigger a diagnostics update of their own
+				if integration.sinkHealth != nil && integration.sinkHealth() != nil {
+					integration.bridgeEntities.publishAllStates()
+				}
+			}
+		}
+	}
+}
+
+func (integration *Integration) subscribeScanCountResets() {
+	for scannerID := range integration.scanCounters {
+		topic := integration.GenerateScanCountResetTopic(scannerID)
+		if err := integration.mqtt.Subscribe(topic, integration.createScanCountResetHandler(scannerID)); err != nil {
+			integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to subscribe to scan count reset topic")
+		}
+	}
+}
+
+func (integration *Integration) createScanCountResetHandler(scannerID string) func(string, []byte) {
+	return func(_ string, _ []byte) {
+		counter, exists := integration.scanCounters[scannerID]
+		if !exists {
+			return
+		}
+
+		counter.reset()
+		integration.logger.WithField("scanner_id", scannerID).Info("Scan counters reset")
+		integration.publishScanCounts(scannerID, 0, 0)
+	}
+}
+
+func (integration *Integration) publishScannerCounterDiscoveryConfigs(scannerID string) error {
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	availability, availabilityMode := integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	sensors := []struct {
+		suffix     string
+		name       string
+		icon       string
+		stateClass string
+		topics     *ScannerTopics
+	}{
+		{"scans", integration.names.Scans, "mdi:counter", "total_increasing", scanner.ScanCountTopics},
+		{"scan_rate", integration.names.ScansLastHour, "mdi:chart-line", "measurement", scanner.ScanRateTopics},
+	}
+
+	for _, sensor := range sensors {
+		sensorConfig := SensorConfig{
+			Name:              integration.scannerEntityName(scanner, sensor.name),
+			ObjectID:          integration.scannerObjectID(scannerID, sensor.suffix),
+			UniqueID:          integration.scannerUniqueID(scannerID, sensor.suffix),
+			TildeTopic:        sensor.topics.BaseTopic,
+			StateTopic:        "~/state",
+			Availability:      availability,
+			AvailabilityMode:  availabilityMode,
+			Device:            scanner.DeviceInfo,
+			Icon:              sensor.icon,
+			UnitOfMeasurement: scanCountUnit,
+			StateClass:        sensor.stateClass,
+			EntityCategory:    "diagnostic",
+		}
+
+		configJSON, err := json.Marshal(sensorConfig)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s discovery config: %w", sensor.suffix, err)
+		}
+
+		if err := integration.mqtt.Publish(sensor.topics.ConfigTopic, string(configJSON), true); err != nil {
+			return err
+		}
+	}
+
+	return integration.publishScannerReadQualityDiscoveryConfig(scannerID)
+}
@@ -0,0 +1,57 @@
+package homeassistant
+
+import (
+	"sync"
+	"time"
+)
+
+// scanDeduplicator remembers the IDs of the scans published within the
+// duplicate_window. A scan published again, e.g. redelivered from the
+// offline queue after its acknowledgement was lost, is skipped so Home
+// Assistant automations don't fire twice.
+type scanDeduplicator struct {
+	window    time.Duration
+	mutex     sync.Mutex
+	published map[string]time.Time
+}
+
+func newScanDeduplicator(window time.Duration) *scanDeduplicator {
+	return &scanDeduplicator{
+		window:    window,
+		published: make(map[string]time.Time),
+	}
+}
+
+// claim reports whether the scan may be published and, if so, remembers it
+// in the same step, so a scan delivered twice at once is published only
+// once. Scans without an ID and a zero window are always claimed.
+func (d *scanDeduplicator) claim(scanID string, now time.Time) bool {
+	if scanID == "" || d.window <= 0 {
+		return true
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for id, publishedAt := range d.published {
+		if now.Sub(publishedAt) >= d.window {
+			delete(d.published, id)
+		}
+	}
+	if _, exists := d.published[scanID]; exists {
+		return false
+	}
+	d.published[scanID] = now
+	return true
+}
+
+// release forgets a claimed scan whose publish failed, so it can be
+// published again.
+func (d *scanDeduplicator) release(scanID string) {
+	if scanID == "" || d.window <= 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.published, scanID)
+}
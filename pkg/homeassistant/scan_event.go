@@ -0,0 +1,91 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// ScanEventType is the event type of scans published to event entities.
+const ScanEventType = "scan"
+
+// ScanEventPayload is published on the state topic of scanners using the
+// event platform. Home Assistant adds the fields besides event_type to the
+// attributes of the event.
+type ScanEventPayload struct {
+	EventType     string            `json:"event_type"`
+	Barcode       string            `json:"barcode"`
+	ScannerID     string            `json:"scanner_id"`
+	ScanID        string            `json:"scan_id,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Sequence      uint64            `json:"sequence,omitempty"`
+	Timestamp     string            `json:"timestamp"`
+	Attributes    map[string]string `json:"attributes,omitempty"` // Added by pipeline stages
+}
+
+func (integration *Integration) usesEventEntity(scannerID string) bool {
+	scannerCfg, exists := integration.scannerConfigs[scannerID]
+	return exists && scannerCfg.UsesEventEntity()
+}
+
+// scannerPlatform returns the Home Assistant component scans of the scanner
+// are published as.
+func (integration *Integration) scannerPlatform(scannerID string) string {
+	if integration.usesEventEntity(scannerID) {
+		return config.EntityPlatformEvent
+	}
+	return config.EntityPlatformSensor
+}
+
+func formatScanEvent(scannerID, barcode string, metadata ScanMetadata) (string, error) {
+	payloadJSON, err := json.Marshal(ScanEventPayload{
+		EventType:     ScanEventType,
+		Barcode:       barcode,
+		ScannerID:     scannerID,
+		ScanID:        metadata.ID,
+		CorrelationID: metadata.CorrelationID,
+		Sequence:      metadata.Sequence,
+		Timestamp:     scanTime(metadata).Format(time.RFC3339),
+		Attributes:    metadata.Attributes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal scan event: %w", err)
+	}
+	return string(payloadJSON), nil
+}
+
+// clearOtherScannerPlatform removes the scan entity of the platform the
+// scanner doesn't use, left behind after changing entity_platform.
+func (integration *Integration) clearOtherScannerPlatform(scannerID string) error {
+	other := config.EntityPlatformEvent
+	if integration.usesEventEntity(scannerID) {
+		other = config.EntityPlatformSensor
+	}
+	topics := integration.generateScannerComponentTopics(other, scannerID, "")
+	return integration.mqtt.Publish(topics.ConfigTopic, "", true)
+}
+
+func (integration *Integration) publishScannerEventDiscoveryConfig(scanner *ScannerDevice) error {
+	eventConfig := SensorConfig{
+		Name:            integration.scannerEntityName(scanner, integration.names.LastScan),
+		ObjectID:        integration.scannerObjectID(scanner.ID, ""),
+		UniqueID:        integration.scannerUniqueID(scanner.ID, ""),
+		TildeTopic:      scanner.Topics.BaseTopic,
+		StateTopic:      "~/state",
+		AttributesTopic: "~/attributes",
+		Device:          scanner.DeviceInfo,
+		Icon:            "mdi:barcode-scan",
+		EventTypes:      []string{ScanEventType},
+	}
+
+	eventConfig.Availability, eventConfig.AvailabilityMode = integration.scannerAvailability("~/availability")
+
+	configJSON, err := json.Marshal(eventConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(scanner.Topics.ConfigTopic, string(configJSON), true)
+}
@@ -0,0 +1,150 @@
+package homeassistant
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// CorrelationIDAttribute is the attribute of a scan answering an expect_scan
+// request, holding the correlation ID of the request.
+const CorrelationIDAttribute = "correlation_id"
+
+var (
+	// ErrScanRelayDisabled is returned by ExpectScan without scan_relay.
+	ErrScanRelayDisabled = errors.New("scan relay is not enabled")
+	// ErrScanRelayForbidden is returned by ExpectScan for scanners not
+	// listed in scan_relay.scanners.
+	ErrScanRelayForbidden = errors.New("scanner is not enabled for the scan relay")
+)
+
+// ExpectScanRequest asks for the next scan of a scanner, within Timeout
+// seconds, to be tagged with CorrelationID. Requests sent over MQTT must
+// carry the scan_relay token when one is configured.
+type ExpectScanRequest struct {
+	ScannerID     string  `json:"scanner_id"`
+	CorrelationID string  `json:"correlation_id"`
+	Timeout       float64 `json:"timeout"`
+	Token         string  `json:"token,omitempty"`
+}
+
+type scanExpectation struct {
+	correlationID string
+	until         time.Time
+}
+
+// scanRelay holds the pending expect_scan requests, one per scanner; a newer
+// request replaces the pending one.
+type scanRelay struct {
+	config       *config.ScanRelayConfig
+	mutex        sync.Mutex
+	expectations map[string]scanExpectation
+}
+
+// SetScanRelay lets Home Assistant automations ask for the next scan of a
+// scanner through the expect_scan topic and ExpectScan.
+func (integration *Integration) SetScanRelay(cfg *config.ScanRelayConfig) {
+	integration.relay = &scanRelay{
+		config:       cfg,
+		expectations: make(map[string]scanExpectation),
+	}
+}
+
+// GenerateScanRelayTopic returns the topic expect_scan requests are sent to.
+func GenerateScanRelayTopic(haConfig *config.HomeAssistantConfig) string {
+	bridgeID := generateBridgeDeviceID(haConfig)
+	return fmt.Sprintf("%s/sensor/%s/expect_scan", haConfig.DiscoveryPrefix, bridgeID)
+}
+
+// ExpectScan tags the next scan of the scanner within timeout with the
+// correlation ID.
+func (integration *Integration) ExpectScan(scannerID, correlationID string, timeout time.Duration) error {
+	relay := integration.relay
+	if relay == nil {
+		return ErrScanRelayDisabled
+	}
+	integration.scannersMutex.RLock()
+	_, exists := integration.scannerConfigs[scannerID]
+	integration.scannersMutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrScannerNotFound, scannerID)
+	}
+	if len(relay.config.Scanners) > 0 && !slices.Contains(relay.config.Scanners, scannerID) {
+		return fmt.Errorf("%w: %s", ErrScanRelayForbidden, scannerID)
+	}
+	if correlationID == "" {
+		return fmt.Errorf("correlation_id is required")
+	}
+	if timeout <= 0 || timeout > relay.config.MaxTimeout {
+		return fmt.Errorf("timeout %s must be positive and at most %s", timeout, relay.config.MaxTimeout)
+	}
+
+	relay.mutex.Lock()
+	relay.expectations[scannerID] = scanExpectation{correlationID: correlationID, until: time.Now().Add(timeout)}
+	relay.mutex.Unlock()
+
+	integration.logger.WithFields(map[string]any{
+		"scanner_id":     scannerID,
+		"correlation_id": correlationID,
+		"timeout":        timeout,
+	}).Info("Expecting a scan")
+	return nil
+}
+
+// TakeExpectedScan returns the correlation ID of a pending expect_scan
+// request of the scanner and clears it, or "" when none is pending.
+func (integration *Integration) TakeExpectedScan(scannerID string, now time.Time) string {
+	relay := integration.relay
+	if relay == nil {
+		return ""
+	}
+
+	relay.mutex.Lock()
+	defer relay.mutex.Unlock()
+	expectation, exists := relay.expectations[scannerID]
+	if !exists {
+		return ""
+	}
+	delete(relay.expectations, scannerID)
+	if now.After(expectation.until) {
+		return ""
+	}
+	return expectation.correlationID
+}
+
+func (integration *Integration) setupScanRelay() {
+	if integration.relay == nil {
+		return
+	}
+
+	topic := GenerateScanRelayTopic(integration.config)
+	if err := integration.mqtt.Subscribe(topic, integration.handleExpectScanCommand); err != nil {
+		integration.logger.WithError(err).Error("Failed to subscribe to expect_scan topic")
+	}
+}
+
+func (integration *Integration) handleExpectScanCommand(_ string, payload []byte) {
+	var request ExpectScanRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		integration.logger.WithError(err).Warn("Ignoring invalid expect_scan request")
+		return
+	}
+
+	logger := integration.logger.WithField("scanner_id", request.ScannerID)
+	if token := integration.relay.config.Token; token != "" &&
+		subtle.ConstantTimeCompare([]byte(request.Token), []byte(token)) != 1 {
+		logger.Warn("Ignoring expect_scan request with an invalid token")
+		return
+	}
+
+	timeout := time.Duration(request.Timeout * float64(time.Second))
+	if err := integration.ExpectScan(request.ScannerID, request.CorrelationID, timeout); err != nil {
+		logger.WithError(err).Warn("Ignoring expect_scan request")
+	}
+}
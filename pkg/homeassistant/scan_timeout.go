@@ -0,0 +1,155 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const scanTimeoutSuffix = "scan_timeout"
+
+// The scan timeout is checked every 10ms by the read loop, shorter timeouts
+// would not be honored.
+const (
+	minScanTimeout = 20 * time.Millisecond
+	maxScanTimeout = 2 * time.Second
+)
+
+// ScanTimeoutControl connects the per-scanner "Scan Timeout" numbers to the
+// scanner manager. Get returns false for scanners whose driver doesn't
+// complete scans after a pause, which get no number.
+type ScanTimeoutControl struct {
+	Get func(scannerID string) (time.Duration, bool)
+	Set func(scannerID string, timeout time.Duration) error
+}
+
+// SetScanTimeoutControl enables the "Scan Timeout" number on the devices of
+// keyboard scanners, to tune how long a scan may pause before it is complete.
+func (integration *Integration) SetScanTimeoutControl(control *ScanTimeoutControl) {
+	integration.timeoutControl = control
+}
+
+func (integration *Integration) generateScanTimeoutTopics(scannerID string) *ScannerTopics {
+	return integration.generateScannerComponentTopics("number", scannerID, scanTimeoutSuffix)
+}
+
+func (integration *Integration) hasScanTimeout(scannerID string) bool {
+	if integration.timeoutControl == nil {
+		return false
+	}
+	_, supported := integration.timeoutControl.Get(scannerID)
+	return supported
+}
+
+func (integration *Integration) publishScanTimeoutDiscoveryConfig(scannerID string) error {
+	if !integration.hasScanTimeout(scannerID) {
+		return nil
+	}
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	topics := integration.generateScanTimeoutTopics(scannerID)
+	numberConfig := SensorConfig{
+		Name:         integration.scannerEntityName(scanner, integration.names.ScanTimeout),
+		ObjectID:     integration.scannerObjectID(scannerID, scanTimeoutSuffix),
+		UniqueID:     integration.scannerUniqueID(scannerID, scanTimeoutSuffix),
+		TildeTopic:   topics.BaseTopic,
+		StateTopic:   "~/state",
+		CommandTopic: "~/set",
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:            scanner.DeviceInfo,
+		Icon:              "mdi:timer-outline",
+		EntityCategory:    "config",
+		UnitOfMeasurement: "ms",
+		Min:               float64(minScanTimeout.Milliseconds()),
+		Max:               float64(maxScanTimeout.Milliseconds()),
+		Step:              10,
+		Mode:              "box",
+	}
+
+	configJSON, err := json.Marshal(numberConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan timeout discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
+
+func (integration *Integration) publishScanTimeout(scannerID string) error {
+	if integration.timeoutControl == nil || !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	timeout, supported := integration.timeoutControl.Get(scannerID)
+	if !supported {
+		return nil
+	}
+	state := strconv.FormatInt(timeout.Milliseconds(), 10)
+	return integration.mqtt.Publish(integration.generateScanTimeoutTopics(scannerID).StateTopic, state, true)
+}
+
+func (integration *Integration) subscribeScanTimeouts() {
+	for scannerID := range integration.scannerConfigs {
+		integration.subscribeScanTimeout(scannerID)
+	}
+}
+
+// subscribeScanTimeout subscribes for every configured scanner, even those
+// not running yet, so the number works once the scanner is started.
+func (integration *Integration) subscribeScanTimeout(scannerID string) {
+	if integration.timeoutControl == nil {
+		return
+	}
+
+	commandTopic := integration.generateScanTimeoutTopics(scannerID).BaseTopic + "/set"
+	if err := integration.mqtt.Subscribe(commandTopic, integration.createScanTimeoutHandler(scannerID)); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to subscribe to scan timeout command topic")
+	}
+
+	if err := integration.publishScanTimeout(scannerID); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish scan timeout")
+	}
+}
+
+func (integration *Integration) createScanTimeoutHandler(scannerID string) func(string, []byte) {
+	return func(_ string, payload []byte) {
+		logger := integration.logger.WithField("scanner_id", scannerID)
+
+		timeout, err := parseScanTimeout(string(payload))
+		if err != nil {
+			logger.WithError(err).Warn("Ignoring scan timeout command")
+		} else if err := integration.timeoutControl.Set(scannerID, timeout); err != nil {
+			logger.WithError(err).Error("Failed to set scan timeout")
+		}
+
+		// Publish even when nothing changed so the number shows the timeout in use.
+		if err := integration.publishScanTimeout(scannerID); err != nil {
+			logger.WithError(err).Error("Failed to publish scan timeout")
+		}
+	}
+}
+
+// parseScanTimeout parses a number command in milliseconds. Home Assistant
+// sends numbers as floats, e.g. "150.0".
+func parseScanTimeout(payload string) (time.Duration, error) {
+	milliseconds, err := strconv.ParseFloat(strings.TrimSpace(payload), 64)
+	if err != nil || math.IsNaN(milliseconds) {
+		return 0, fmt.Errorf("invalid scan timeout '%s'", payload)
+	}
+
+	milliseconds = math.Round(milliseconds)
+	if milliseconds < float64(minScanTimeout.Milliseconds()) || milliseconds > float64(maxScanTimeout.Milliseconds()) {
+		return 0, fmt.Errorf("scan timeout %gms must be between %s and %s", milliseconds, minScanTimeout, maxScanTimeout)
+	}
+	return time.Duration(milliseconds) * time.Millisecond, nil
+}
@@ -0,0 +1,43 @@
+package homeassistant
+
+import (
+	"fmt"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/scanner"
+)
+
+// RecordScannerError counts a low-level device error or a scan of bad length
+// in the scanner health, per category and in the overall error count. Open
+// failures happen while the device is disconnected and are left out of the
+// error count, so an unplugged scanner doesn't turn degraded. Errors of
+// scanners that never connected return ErrScannerNotRegistered.
+func (integration *Integration) RecordScannerError(scannerID, category string) error {
+	integration.scannersMutex.Lock()
+	defer integration.scannersMutex.Unlock()
+
+	device, exists := integration.scanners[scannerID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrScannerNotRegistered, scannerID)
+	}
+
+	switch category {
+	case scanner.ErrorCategoryOpenFailure:
+		device.Health.OpenFailures++
+	case scanner.ErrorCategoryReadTimeout:
+		device.Health.ReadTimeouts++
+	case scanner.ErrorCategoryIOError:
+		device.Health.IOErrors++
+	case scanner.ErrorCategoryBadLength:
+		device.Health.BadLengths++
+	default:
+		return fmt.Errorf("unknown error category '%s'", category)
+	}
+	if category != scanner.ErrorCategoryOpenFailure {
+		device.Health.ErrorCount++
+	}
+
+	if !integration.mqtt.IsConnected() {
+		return nil
+	}
+	return integration.publishScannerHealthState(scannerID)
+}
@@ -0,0 +1,135 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const scannerEnabledSuffix = "enabled"
+
+// ScannerEnableControl connects the per-scanner "Enabled" switches to the
+// scanner manager, which drops the scans of disabled scanners while keeping
+// their devices open.
+type ScannerEnableControl struct {
+	IsEnabled  func(scannerID string) bool
+	SetEnabled func(scannerID string, enabled bool)
+}
+
+// SetScannerEnableControl enables the "Enabled" switch on every scanner device.
+func (integration *Integration) SetScannerEnableControl(control *ScannerEnableControl) {
+	integration.enableControl = control
+}
+
+func (integration *Integration) generateScannerSwitchTopics(scannerID string) *ScannerTopics {
+	return integration.generateScannerComponentTopics("switch", scannerID, scannerEnabledSuffix)
+}
+
+func (integration *Integration) publishScannerSwitchDiscoveryConfig(scannerID string) error {
+	if integration.enableControl == nil {
+		return nil
+	}
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	topics := integration.generateScannerSwitchTopics(scannerID)
+	switchConfig := SensorConfig{
+		Name:         integration.scannerEntityName(scanner, integration.names.ScannerEnabled),
+		ObjectID:     integration.scannerObjectID(scannerID, scannerEnabledSuffix),
+		UniqueID:     integration.scannerUniqueID(scannerID, scannerEnabledSuffix),
+		TildeTopic:   topics.BaseTopic,
+		StateTopic:   "~/state",
+		CommandTopic: "~/set",
+		// Only the bridge availability: the switch stays usable while the
+		// scanner is unplugged, to disable it before it is plugged back in.
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:         scanner.DeviceInfo,
+		Icon:           "mdi:barcode-off",
+		EntityCategory: "config",
+		PayloadOn:      PayloadOn,
+		PayloadOff:     PayloadOff,
+	}
+
+	configJSON, err := json.Marshal(switchConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scanner switch discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
+
+// publishScannerControlDiscoveryConfigs publishes the discovery configs of the
+// configuration entities of a scanner device.
+func (integration *Integration) publishScannerControlDiscoveryConfigs(scannerID string) error {
+	if err := integration.publishScannerSwitchDiscoveryConfig(scannerID); err != nil {
+		return err
+	}
+	if err := integration.publishScanTimeoutDiscoveryConfig(scannerID); err != nil {
+		return err
+	}
+	if err := integration.publishKeyboardLayoutDiscoveryConfig(scannerID); err != nil {
+		return err
+	}
+	// The scan timeout and keyboard layout are only known once the scanner is
+	// running, which it may not have been when the command topics were
+	// subscribed.
+	if err := integration.publishScanTimeout(scannerID); err != nil {
+		return err
+	}
+	return integration.publishKeyboardLayout(scannerID)
+}
+
+func (integration *Integration) publishScannerEnabled(scannerID string) error {
+	if integration.enableControl == nil || !integration.mqtt.IsConnected() {
+		return nil
+	}
+
+	state := boolPayload(integration.enableControl.IsEnabled(scannerID))
+	return integration.mqtt.Publish(integration.generateScannerSwitchTopics(scannerID).StateTopic, state, true)
+}
+
+func (integration *Integration) subscribeScannerSwitches() {
+	for scannerID := range integration.scannerConfigs {
+		integration.subscribeScannerSwitch(scannerID)
+	}
+}
+
+func (integration *Integration) subscribeScannerSwitch(scannerID string) {
+	if integration.enableControl == nil {
+		return
+	}
+
+	commandTopic := integration.generateScannerSwitchTopics(scannerID).BaseTopic + "/set"
+	if err := integration.mqtt.Subscribe(commandTopic, integration.createScannerSwitchHandler(scannerID)); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to subscribe to scanner switch command topic")
+	}
+
+	if err := integration.publishScannerEnabled(scannerID); err != nil {
+		integration.logger.WithField("scanner_id", scannerID).WithError(err).Error("Failed to publish scanner switch state")
+	}
+}
+
+func (integration *Integration) createScannerSwitchHandler(scannerID string) func(string, []byte) {
+	return func(_ string, payload []byte) {
+		logger := integration.logger.WithField("scanner_id", scannerID)
+
+		command := strings.ToUpper(strings.TrimSpace(string(payload)))
+		if command != PayloadOn && command != PayloadOff {
+			logger.Warnf("Ignoring unknown scanner switch command '%s'", command)
+		} else {
+			integration.enableControl.SetEnabled(scannerID, command == PayloadOn)
+		}
+
+		// Publish even when nothing changed so the switch does not stay optimistic.
+		if err := integration.publishScannerEnabled(scannerID); err != nil {
+			logger.WithError(err).Error("Failed to publish scanner switch state")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	JSONStateValueTemplate      = "{{ value_json.value }}"
+	JSONStateAttributesTemplate = "{{ value_json.attributes | tojson }}"
+
+	// SequenceAttribute counts the scans of a scanner since the bridge
+	// started, so automations can notice missed or repeated scans.
+	SequenceAttribute = "sequence"
+)
+
+// StatePayload is published on the state topic of scanners using the JSON
+// state format. Home Assistant extracts the value and attributes with the
+// templates included in the discovery config.
+type StatePayload struct {
+	Value      *string        `json:"value"`
+	Timestamp  string         `json:"timestamp"`
+	ScannerID  string         `json:"scanner_id"`
+	ScanID     string         `json:"scan_id,omitempty"`
+	Sequence   uint64         `json:"sequence,omitempty"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+// addScanAttributes adds the attributes of a scan to the scanner attributes.
+func addScanAttributes(attributes map[string]any, scanAttributes map[string]string) {
+	for key, value := range scanAttributes {
+		attributes[key] = value
+	}
+}
+
+// scanTime returns when a scan was read, now for states that aren't scans.
+func scanTime(metadata ScanMetadata) time.Time {
+	if metadata.Time.IsZero() {
+		return time.Now()
+	}
+	return metadata.Time
+}
+
+func (integration *Integration) formatScannerState(scannerID, state string, metadata ScanMetadata) (string, error) {
+	scannerCfg, exists := integration.scannerConfigs[scannerID]
+	if exists && scannerCfg.UsesEventEntity() {
+		return formatScanEvent(scannerID, state, metadata)
+	}
+	if !exists || !scannerCfg.UsesJSONState() {
+		return state, nil
+	}
+
+	timestamp := scanTime(metadata).Format(time.RFC3339)
+	attributes := buildScannerAttributes(scannerID, scannerCfg)
+	integration.addOperatorAttributes(scannerID, attributes)
+	addScanAttributes(attributes, metadata.Attributes)
+	attributes["timestamp"] = timestamp
+	if metadata.ID != "" {
+		// Lets automations skip a scan delivered twice
+		attributes["scan_id"] = metadata.ID
+	}
+	if metadata.CorrelationID != "" {
+		attributes[CorrelationIDAttribute] = metadata.CorrelationID
+	}
+	if metadata.Sequence != 0 {
+		attributes[SequenceAttribute] = metadata.Sequence
+	}
+
+	payload := StatePayload{
+		Timestamp:  timestamp,
+		ScannerID:  scannerID,
+		ScanID:     metadata.ID,
+		Sequence:   metadata.Sequence,
+		Attributes: attributes,
+	}
+	if state != StatusUnknown {
+		payload.Value = &state
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal state payload: %w", err)
+	}
+
+	return string(payloadJSON), nil
+}
@@ -0,0 +1,49 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const statesAPIPath = "/api/states/"
+
+// ErrEntityNotFound is returned by FetchEntityState for unknown entities.
+var ErrEntityNotFound = errors.New("entity not found")
+
+type entityState struct {
+	State string `json:"state"`
+}
+
+// FetchEntityState returns the current state of an entity from the Home
+// Assistant REST API, authenticating with a long-lived access token.
+func FetchEntityState(ctx context.Context, client *http.Client, baseURL, token, entityID string) (string, error) {
+	url := strings.TrimRight(baseURL, "/") + statesAPIPath + entityID
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrEntityNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var state entityState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return "", fmt.Errorf("failed to decode state of %s: %w", entityID, err)
+	}
+	return state.State, nil
+}
@@ -0,0 +1,104 @@
+package homeassistant
+
+import (
+	"slices"
+	"time"
+)
+
+// ScannerStats is a point-in-time view of a scanner for consumers outside MQTT
+// such as the HTTP metrics endpoint.
+type ScannerStats struct {
+	ID             string
+	Name           string
+	Connected      bool
+	TotalScans     int64
+	RecentScans    int // Scans within ScanRateWindow
+	ReconnectCount int
+	FlapCount      int
+	ErrorCount     int
+	BatteryLevel   *int
+	LastScanTime   *time.Time
+}
+
+// ScannerStats returns the statistics of all scanners ordered by ID.
+func (integration *Integration) ScannerStats() []ScannerStats {
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	now := time.Now()
+	stats := make([]ScannerStats, 0, len(integration.scanners))
+	for scannerID, scanner := range integration.scanners {
+		entry := ScannerStats{
+			ID:           scannerID,
+			Name:         scanner.Name,
+			Connected:    scanner.Connected,
+			BatteryLevel: scanner.BatteryLevel,
+		}
+		if scanner.Health != nil {
+			entry.ReconnectCount = scanner.Health.ReconnectCount
+			entry.FlapCount = scanner.Health.FlapCount
+			entry.ErrorCount = scanner.Health.ErrorCount
+			entry.LastScanTime = scanner.Health.LastScanTime
+		}
+		if counter, exists := integration.scanCounters[scannerID]; exists {
+			entry.TotalScans, entry.RecentScans = counter.snapshot(now)
+		}
+		stats = append(stats, entry)
+	}
+
+	slices.SortFunc(stats, func(a, b ScannerStats) int {
+		if a.ID < b.ID {
+			return -1
+		}
+		if a.ID > b.ID {
+			return 1
+		}
+		return 0
+	})
+	return stats
+}
+
+// ScannerStatsByID returns the statistics of one scanner, and false when no
+// device has been found for it.
+func (integration *Integration) ScannerStatsByID(scannerID string) (ScannerStats, bool) {
+	for _, stats := range integration.ScannerStats() {
+		if stats.ID == scannerID {
+			return stats, true
+		}
+	}
+	return ScannerStats{}, false
+}
+
+// AllScannersDownSince reports whether every configured scanner is
+// disconnected and since when the last of them has been. Scanners that never
+// connected count as down since the integration was created. It returns
+// false when no scanners are configured.
+func (integration *Integration) AllScannersDownSince() (time.Time, bool) {
+	integration.connectionMutex.Lock()
+	defer integration.connectionMutex.Unlock()
+	integration.scannersMutex.RLock()
+	defer integration.scannersMutex.RUnlock()
+
+	if len(integration.scannerConfigs) == 0 {
+		return time.Time{}, false
+	}
+
+	var since time.Time
+	for scannerID := range integration.scannerConfigs {
+		scannerSince := integration.createdAt
+		if scanner, exists := integration.scanners[scannerID]; exists {
+			if scanner.Connected {
+				return time.Time{}, false
+			}
+			if scanner.Health != nil && scanner.Health.DisconnectedAt != nil {
+				scannerSince = *scanner.Health.DisconnectedAt
+			}
+		}
+		if scannerSince.After(since) {
+			since = scannerSince
+		}
+	}
+	return since, true
+}
@@ -0,0 +1,73 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+)
+
+const symbologySuffix = "symbology"
+
+// scanSymbology returns the symbology of a scan, taken from the identifier an
+// aim stage stripped or detected from the barcode.
+func scanSymbology(barcode string, metadata ScanMetadata) string {
+	if symbology := metadata.Attributes[common.SymbologyAttribute]; symbology != "" {
+		return symbology
+	}
+	return common.DetectSymbology(barcode)
+}
+
+func (integration *Integration) publishSymbology(scannerID, symbology string) {
+	if !integration.config.SymbologySensor {
+		return
+	}
+
+	topics := integration.generateScannerSubEntityTopics(scannerID, symbologySuffix)
+	logger := integration.logger.WithField("scanner_id", scannerID)
+
+	if err := integration.mqtt.Publish(topics.StateTopic, symbology, true); err != nil {
+		logger.WithError(err).Error("Failed to publish symbology")
+	}
+
+	attributes, err := json.Marshal(map[string]any{"matrix": common.IsMatrixSymbology(symbology)})
+	if err != nil {
+		return
+	}
+	if err := integration.mqtt.Publish(topics.AttributesTopic, string(attributes), true); err != nil {
+		logger.WithError(err).Error("Failed to publish symbology attributes")
+	}
+}
+
+func (integration *Integration) publishScannerSymbologyDiscoveryConfig(scannerID string) error {
+	if !integration.config.SymbologySensor {
+		return nil
+	}
+
+	scanner, exists := integration.scanners[scannerID]
+	if !exists || scanner.DeviceInfo == nil {
+		return fmt.Errorf("scanner %s not found or device info not set", scannerID)
+	}
+
+	topics := integration.generateScannerSubEntityTopics(scannerID, symbologySuffix)
+	sensorConfig := SensorConfig{
+		Name:            integration.scannerEntityName(scanner, integration.names.Symbology),
+		ObjectID:        integration.scannerObjectID(scannerID, symbologySuffix),
+		UniqueID:        integration.scannerUniqueID(scannerID, symbologySuffix),
+		TildeTopic:      topics.BaseTopic,
+		StateTopic:      "~/state",
+		AttributesTopic: "~/attributes",
+		Device:          scanner.DeviceInfo,
+		Icon:            "mdi:barcode",
+		EntityCategory:  "diagnostic",
+	}
+
+	sensorConfig.Availability, sensorConfig.AvailabilityMode = integration.scannerAvailability(scanner.Topics.AvailabilityTopic)
+
+	configJSON, err := json.Marshal(sensorConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal symbology discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(topics.ConfigTopic, string(configJSON), true)
+}
@@ -0,0 +1,90 @@
+package homeassistant
+
+import (
+	"cmp"
+	"strings"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// topicTemplates render the topics of scanner entities, see
+// config.TopicsConfig. A nil template renders the default layout.
+type topicTemplates struct {
+	discovery *template.Template
+	state     *template.Template
+}
+
+// loadTopicTemplates parses the configured topic templates. They were
+// validated with the configuration, so a template that still fails falls back
+// to the default layout.
+func loadTopicTemplates(topics *config.TopicsConfig, logger *logrus.Logger) *topicTemplates {
+	parse := func(name, text, fallback string) *template.Template {
+		tmpl, err := config.ParseTopicTemplate(name, cmp.Or(text, fallback))
+		if err != nil {
+			logger.WithError(err).Warnf("Using the default %s topic template", name)
+			tmpl, _ = config.ParseTopicTemplate(name, fallback)
+		}
+		return tmpl
+	}
+	return &topicTemplates{
+		discovery: parse("discovery", topics.Discovery, config.DefaultDiscoveryTopicTemplate),
+		state:     parse("state", topics.State, config.DefaultStateTopicTemplate),
+	}
+}
+
+// renderDiscovery returns the discovery topic of an entity, without "/config".
+func (t *topicTemplates) renderDiscovery(data *config.TopicTemplateData, logger *logrus.Logger) string {
+	if t == nil {
+		return renderTopic(nil, data, logger)
+	}
+	return renderTopic(t.discovery, data, logger)
+}
+
+// renderState returns the base of the state, availability and attributes
+// topics of an entity.
+func (t *topicTemplates) renderState(data *config.TopicTemplateData, logger *logrus.Logger) string {
+	if t == nil {
+		return renderTopic(nil, data, logger)
+	}
+	return renderTopic(t.state, data, logger)
+}
+
+func renderTopic(tmpl *template.Template, data *config.TopicTemplateData, logger *logrus.Logger) string {
+	defaultTopic := data.Prefix + "/" + data.Component + "/" + data.ObjectID
+	if tmpl == nil {
+		return defaultTopic
+	}
+	topic, err := config.RenderTopic(tmpl, data)
+	if err != nil || topic == "" {
+		logger.WithError(err).WithField("template", tmpl.Name()).Warnf("Using the default topic %s", defaultTopic)
+		return defaultTopic
+	}
+	return topic
+}
+
+// ParseBridgeTopic reports whether a topic under the discovery prefix belongs
+// to this bridge instance. For scanner entities it also returns the object
+// part after "-scanner-", i.e. the scanner ID followed by any entity suffix.
+// Only the default topic layout is recognized.
+func ParseBridgeTopic(haConfig *config.HomeAssistantConfig, topic string) (scannerObject string, owned bool) {
+	levels := strings.Split(topic, "/")
+	if len(levels) < 3 || levels[0] != haConfig.DiscoveryPrefix {
+		return "", false
+	}
+
+	bridgeID := generateBridgeDeviceID(haConfig)
+	object := levels[2]
+	switch object {
+	case bridgeID, bridgeID + "-pause", bridgeID + "-restart", bridgeID + "-diagnostics":
+		return "", true
+	}
+
+	scannerObject, owned = strings.CutPrefix(object, bridgeID+"-scanner-")
+	if !owned || scannerObject == "" {
+		return "", false
+	}
+	return scannerObject, true
+}
@@ -0,0 +1,177 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+)
+
+const (
+	updateCheckInterval = 12 * time.Hour
+	updateCheckTimeout  = 30 * time.Second
+	updateTitle         = "HA Barcode Bridge"
+
+	// Home Assistant rejects longer release summaries
+	releaseSummaryMaxLength = 255
+)
+
+// updateState is the JSON state of the update entity.
+type updateState struct {
+	InstalledVersion string `json:"installed_version"`
+	LatestVersion    string `json:"latest_version"`
+	Title            string `json:"title"`
+	ReleaseSummary   string `json:"release_summary,omitempty"`
+	ReleaseURL       string `json:"release_url,omitempty"`
+}
+
+func (integration *Integration) generateUpdateEntityTopic() string {
+	bridgeID := generateBridgeDeviceID(integration.config)
+	return fmt.Sprintf("%s/update/%s-update", integration.config.DiscoveryPrefix, bridgeID)
+}
+
+func (integration *Integration) publishUpdateDiscoveryConfig() error {
+	baseTopic := integration.generateUpdateEntityTopic()
+	bridgeID := generateBridgeDeviceID(integration.config)
+
+	updateConfig := SensorConfig{
+		Name:            integration.names.Update,
+		UniqueID:        fmt.Sprintf("%s-update", bridgeID),
+		TildeTopic:      baseTopic,
+		StateTopic:      "~/state",
+		AttributesTopic: "~/attributes",
+		Availability: []AvailabilityConfig{
+			{
+				Topic: integration.GenerateBridgeAvailabilityTopic(),
+			},
+		},
+		Device:         integration.bridgeDeviceInfo,
+		Icon:           "mdi:package-up",
+		EntityCategory: "config",
+	}
+
+	configJSON, err := json.Marshal(updateConfig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal update discovery config: %w", err)
+	}
+
+	return integration.mqtt.Publish(baseTopic+"/config", string(configJSON), true)
+}
+
+func (integration *Integration) setupUpdateEntity() {
+	if !integration.config.UpdateCheck {
+		return
+	}
+
+	if err := integration.publishUpdateDiscoveryConfig(); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish update discovery config")
+	}
+	integration.publishUpdateState()
+}
+
+// buildUpdateState reports the release as the latest version only when it is
+// newer than the running one, so development builds show as up to date.
+func buildUpdateState(installedVersion string, release *common.Release) updateState {
+	state := updateState{
+		InstalledVersion: installedVersion,
+		LatestVersion:    installedVersion,
+		Title:            updateTitle,
+	}
+	if release == nil || !common.IsNewerVersion(installedVersion, release.Version) {
+		return state
+	}
+
+	state.LatestVersion = release.Version
+	state.ReleaseURL = release.URL
+	state.ReleaseSummary = release.Notes
+	if runes := []rune(release.Notes); len(runes) > releaseSummaryMaxLength {
+		state.ReleaseSummary = string(runes[:releaseSummaryMaxLength-1]) + "…"
+	}
+	return state
+}
+
+func buildUpdateAttributes(release *common.Release) map[string]any {
+	return map[string]any{
+		"release_name":  release.Name,
+		"release_notes": release.Notes,
+		"published_at":  release.PublishedAt.Format(time.RFC3339),
+	}
+}
+
+// publishUpdateState publishes the result of the last update check, nothing
+// before the first one succeeded.
+func (integration *Integration) publishUpdateState() {
+	integration.updateMutex.Lock()
+	release := integration.latestRelease
+	integration.updateMutex.Unlock()
+	if release == nil {
+		return
+	}
+
+	baseTopic := integration.generateUpdateEntityTopic()
+	stateJSON, err := json.Marshal(buildUpdateState(integration.version, release))
+	if err != nil {
+		integration.logger.WithError(err).Error("Failed to marshal update state")
+		return
+	}
+	if err := integration.mqtt.Publish(baseTopic+"/state", string(stateJSON), true); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish update state")
+	}
+
+	attributesJSON, err := json.Marshal(buildUpdateAttributes(release))
+	if err != nil {
+		integration.logger.WithError(err).Error("Failed to marshal update attributes")
+		return
+	}
+	if err := integration.mqtt.Publish(baseTopic+"/attributes", string(attributesJSON), true); err != nil {
+		integration.logger.WithError(err).Error("Failed to publish update attributes")
+	}
+}
+
+// runUpdateChecks asks GitHub for the latest release at startup and then
+// every updateCheckInterval.
+func (integration *Integration) runUpdateChecks() {
+	client := &http.Client{Timeout: updateCheckTimeout}
+	integration.checkForUpdate(client)
+
+	ticker := time.NewTicker(updateCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-integration.stopCh:
+			return
+		case <-ticker.C:
+			integration.checkForUpdate(client)
+		}
+	}
+}
+
+func (integration *Integration) checkForUpdate(client *http.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+
+	release, err := common.FetchLatestRelease(ctx, client, common.LatestReleaseURL)
+	if err != nil {
+		integration.logger.WithError(err).Warn("Failed to check for bridge updates")
+		return
+	}
+
+	if common.IsNewerVersion(integration.version, release.Version) {
+		integration.logger.WithFields(map[string]any{
+			"installed_version": integration.version,
+			"latest_version":    release.Version,
+		}).Info("A newer bridge version is available")
+	}
+
+	integration.updateMutex.Lock()
+	integration.latestRelease = release
+	integration.updateMutex.Unlock()
+
+	if integration.mqtt.IsConnected() {
+		integration.publishUpdateState()
+	}
+}
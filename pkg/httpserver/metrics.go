@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Metric is one metric family in the Prometheus text exposition format.
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string // "gauge" or "counter"
+	Samples []Sample
+}
+
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// MetricsHandler serves the metrics returned by collect on every request.
+func MetricsHandler(collect func() []Metric) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, collect())
+	})
+}
+
+func writeMetrics(w io.Writer, metrics []Metric) {
+	for _, metric := range metrics {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n", metric.Name, metric.Help)
+		_, _ = fmt.Fprintf(w, "# TYPE %s %s\n", metric.Name, metric.Type)
+		for _, sample := range metric.Samples {
+			_, _ = fmt.Fprintf(w, "%s%s %s\n", metric.Name, formatLabels(sample.Labels),
+				strconv.FormatFloat(sample.Value, 'g', -1, 64))
+		}
+	}
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, strconv.Quote(labels[name])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
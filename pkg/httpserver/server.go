@@ -0,0 +1,177 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 5 * time.Second
+)
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// Server runs the configured HTTP listeners. Endpoints are registered per
+// group and every listener only mounts the groups listed in its serve option,
+// so metrics can be open on the LAN while the control API needs credentials.
+type Server struct {
+	listeners []config.HTTPListenerConfig
+	routes    map[string][]route
+	servers   []*http.Server
+	onStop    []func()
+	logger    *logrus.Logger
+	wg        sync.WaitGroup
+}
+
+func NewServer(cfg *config.HTTPConfig, logger *logrus.Logger) *Server {
+	return &Server{
+		listeners: cfg.Listeners,
+		routes:    make(map[string][]route),
+		logger:    logger,
+	}
+}
+
+// Handle registers an endpoint in a group. It must be called before Start.
+func (s *Server) Handle(group, pattern string, handler http.Handler) {
+	s.routes[group] = append(s.routes[group], route{pattern: pattern, handler: handler})
+}
+
+// OnStop registers a function run when the server stops, to close the
+// hijacked connections http.Server.Shutdown leaves open.
+func (s *Server) OnStop(stop func()) {
+	s.onStop = append(s.onStop, stop)
+}
+
+// Start binds every listener before serving so address conflicts are
+// reported as startup errors rather than logged from a goroutine.
+func (s *Server) Start() error {
+	for i := range s.listeners {
+		listenerCfg := &s.listeners[i]
+
+		listener, err := net.Listen("tcp", listenerCfg.Address)
+		if err != nil {
+			_ = s.Stop()
+			return fmt.Errorf("failed to bind HTTP listener %s on %s: %w", listenerCfg.Name, listenerCfg.Address, err)
+		}
+
+		server := &http.Server{
+			Handler:           s.buildHandler(listenerCfg),
+			ReadHeaderTimeout: readHeaderTimeout,
+		}
+		if listenerCfg.TLS.Enabled() {
+			server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		s.servers = append(s.servers, server)
+
+		s.wg.Add(1)
+		go s.serve(listenerCfg, server, listener)
+	}
+	return nil
+}
+
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	for _, stop := range s.onStop {
+		stop()
+	}
+
+	var errs []error
+	for _, server := range s.servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.servers = nil
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *Server) serve(listenerCfg *config.HTTPListenerConfig, server *http.Server, listener net.Listener) {
+	defer s.wg.Done()
+
+	logger := s.logger.WithFields(logrus.Fields{
+		"listener": listenerCfg.Name,
+		"address":  listener.Addr().String(),
+		"serve":    strings.Join(listenerCfg.Serve, ","),
+		"tls":      listenerCfg.TLS.Enabled(),
+		"auth":     listenerCfg.Auth.Enabled(),
+	})
+	logger.Info("HTTP listener started")
+
+	var err error
+	if listenerCfg.TLS.Enabled() {
+		err = server.ServeTLS(listener, listenerCfg.TLS.CertFile, listenerCfg.TLS.KeyFile)
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.WithError(err).Error("HTTP listener failed")
+	}
+}
+
+func (s *Server) buildHandler(listenerCfg *config.HTTPListenerConfig) http.Handler {
+	mux := http.NewServeMux()
+	for _, group := range listenerCfg.Serve {
+		for _, r := range s.routes[group] {
+			mux.Handle(r.pattern, r.handler)
+		}
+	}
+	return authenticate(listenerCfg.Auth, mux)
+}
+
+func authenticate(auth config.HTTPAuthConfig, next http.Handler) http.Handler {
+	if !auth.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized(&auth, r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if auth.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="homeassistant-barcode-scanner"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func authorized(auth *config.HTTPAuthConfig, r *http.Request) bool {
+	if auth.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && secureEqual(token, auth.Token) {
+			return true
+		}
+	}
+
+	if auth.Username != "" {
+		username, password, ok := r.BasicAuth()
+		if ok && secureEqual(username, auth.Username) && secureEqual(password, auth.Password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,99 @@
+package httpserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func newTestServer() *Server {
+	server := NewServer(&config.HTTPConfig{}, logrus.New())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+	server.Handle(config.HTTPServeMetrics, "/metrics", ok)
+	server.Handle(config.HTTPServeAPI, "/api/pause", ok)
+	return server
+}
+
+func TestListenerHandler(t *testing.T) {
+	server := newTestServer()
+
+	open := server.buildHandler(&config.HTTPListenerConfig{Serve: []string{config.HTTPServeMetrics}})
+	protected := server.buildHandler(&config.HTTPListenerConfig{
+		Serve: []string{config.HTTPServeAPI},
+		Auth:  config.HTTPAuthConfig{Username: "admin", Password: "hunter2", Token: "secret"},
+	})
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		path     string
+		setup    func(*http.Request)
+		expected int
+	}{
+		{"Open metrics", open, "/metrics", nil, http.StatusOK},
+		{"API not mounted on metrics listener", open, "/api/pause", nil, http.StatusNotFound},
+		{"Metrics not mounted on API listener", protected, "/metrics", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer secret")
+		}, http.StatusNotFound},
+		{"Missing credentials", protected, "/api/pause", nil, http.StatusUnauthorized},
+		{"Valid token", protected, "/api/pause", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer secret")
+		}, http.StatusOK},
+		{"Invalid token", protected, "/api/pause", func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer wrong")
+		}, http.StatusUnauthorized},
+		{"Valid basic auth", protected, "/api/pause", func(r *http.Request) {
+			r.SetBasicAuth("admin", "hunter2")
+		}, http.StatusOK},
+		{"Invalid basic auth", protected, "/api/pause", func(r *http.Request) {
+			r.SetBasicAuth("admin", "wrong")
+		}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			if tt.setup != nil {
+				tt.setup(request)
+			}
+			recorder := httptest.NewRecorder()
+			tt.handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	handler := MetricsHandler(func() []Metric {
+		return []Metric{{
+			Name: "barcode_scanner_connected",
+			Help: "Whether the scanner is connected.",
+			Type: "gauge",
+			Samples: []Sample{
+				{Labels: map[string]string{"scanner": "office"}, Value: 1},
+			},
+		}}
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", http.NoBody))
+
+	expected := `barcode_scanner_connected{scanner="office"} 1`
+	if !strings.Contains(recorder.Body.String(), expected) {
+		t.Errorf("Expected body to contain %q, got:\n%s", expected, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), "# TYPE barcode_scanner_connected gauge") {
+		t.Error("Expected TYPE line in metrics output")
+	}
+}
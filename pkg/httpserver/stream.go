@@ -0,0 +1,187 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// streamClientBuffer is how many events a slow client may fall behind
+	// before further events are dropped for it.
+	streamClientBuffer = 64
+	streamWriteTimeout = 10 * time.Second
+	streamPingInterval = 30 * time.Second
+)
+
+type streamEvent struct {
+	key     string
+	payload any
+}
+
+type streamClient struct {
+	events chan streamEvent
+	filter string // Only events with this key, all when empty
+}
+
+// EventStream pushes JSON events to WebSocket clients. Clients can pass the
+// filter query parameter to only receive the events of one key.
+type EventStream struct {
+	filterParam string
+	upgrader    websocket.Upgrader
+	logger      *logrus.Logger
+
+	mutex   sync.Mutex
+	clients map[*streamClient]struct{}
+	closed  bool
+}
+
+// NewEventStream accepts browser connections from the same origin and from
+// allowedOrigins, where "*" allows any origin. Clients that send no Origin
+// header, i.e. anything but a browser, are always accepted.
+func NewEventStream(filterParam string, allowedOrigins []string, logger *logrus.Logger) *EventStream {
+	stream := &EventStream{
+		filterParam: filterParam,
+		logger:      logger,
+		clients:     make(map[*streamClient]struct{}),
+	}
+	stream.upgrader.CheckOrigin = func(r *http.Request) bool {
+		return originAllowed(r, allowedOrigins)
+	}
+	return stream
+}
+
+// Publish sends an event to the connected clients without blocking; clients
+// that fall behind miss events.
+func (s *EventStream) Publish(key string, payload any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for client := range s.clients {
+		if client.filter != "" && client.filter != key {
+			continue
+		}
+		select {
+		case client.events <- streamEvent{key: key, payload: payload}:
+		default:
+		}
+	}
+}
+
+// Clients returns the number of connected clients.
+func (s *EventStream) Clients() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.clients)
+}
+
+// Close disconnects all clients. WebSocket connections are hijacked, so
+// http.Server.Shutdown doesn't close them.
+func (s *EventStream) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	for client := range s.clients {
+		close(client.events)
+		delete(s.clients, client)
+	}
+}
+
+func (s *EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already replied with an error status
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	client := &streamClient{events: make(chan streamEvent, streamClientBuffer), filter: r.URL.Query().Get(s.filterParam)}
+	if !s.subscribe(client) {
+		return
+	}
+	defer s.unsubscribe(client)
+
+	logger := s.logger.WithField("remote", r.RemoteAddr)
+	logger.Debug("Event stream client connected")
+	defer logger.Debug("Event stream client disconnected")
+
+	// Reading is only needed to process close and pong frames
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-client.events:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(streamWriteTimeout))
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(event.payload); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *EventStream) subscribe(client *streamClient) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.clients[client] = struct{}{}
+	return true
+}
+
+func (s *EventStream) unsubscribe(client *streamClient) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.clients[client]; exists {
+		close(client.events)
+		delete(s.clients, client)
+	}
+}
+
+func originAllowed(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(allowedOrigins, "*") {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	return slices.ContainsFunc(allowedOrigins, func(allowed string) bool {
+		return strings.EqualFold(strings.TrimRight(allowed, "/"), origin)
+	})
+}
@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+type testEvent struct {
+	Scanner string `json:"scanner"`
+	Barcode string `json:"barcode"`
+}
+
+func dialStream(t *testing.T, server *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Expected WebSocket connection, got error: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func waitForClients(t *testing.T, stream *EventStream, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for stream.Clients() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d stream clients, got %d", expected, stream.Clients())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	stream := NewEventStream("scanner", nil, logrus.New())
+	mux := http.NewServeMux()
+	mux.Handle("/ws", stream)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	all := dialStream(t, server, "")
+	filtered := dialStream(t, server, "?scanner=desk")
+	waitForClients(t, stream, 2)
+
+	stream.Publish("conveyor", testEvent{Scanner: "conveyor", Barcode: "111"})
+	stream.Publish("desk", testEvent{Scanner: "desk", Barcode: "222"})
+
+	var event testEvent
+	for _, expected := range []string{"111", "222"} {
+		if err := all.ReadJSON(&event); err != nil {
+			t.Fatalf("Expected event, got error: %v", err)
+		}
+		if event.Barcode != expected {
+			t.Errorf("Expected barcode %s, got %s", expected, event.Barcode)
+		}
+	}
+	if err := filtered.ReadJSON(&event); err != nil {
+		t.Fatalf("Expected event, got error: %v", err)
+	}
+	if event.Scanner != "desk" {
+		t.Errorf("Expected only desk events on the filtered stream, got %+v", event)
+	}
+
+	stream.Close()
+	_, _, err := all.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected going away close, got %v", err)
+	}
+	waitForClients(t, stream, 0)
+}
+
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		origin   string
+		allowed  []string
+		expected bool
+	}{
+		{"No origin", "", nil, true},
+		{"Same origin", "http://bridge.local:8080", nil, true},
+		{"Other origin", "http://kiosk.local:3000", nil, false},
+		{"Listed origin", "http://kiosk.local:3000", []string{"http://kiosk.local:3000/"}, true},
+		{"Any origin", "http://kiosk.local:3000", []string{"*"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "http://bridge.local:8080/ws", http.NoBody)
+			if tt.origin != "" {
+				request.Header.Set("Origin", tt.origin)
+			}
+			if allowed := originAllowed(request, tt.allowed); allowed != tt.expected {
+				t.Errorf("Expected allowed %v, got %v", tt.expected, allowed)
+			}
+		})
+	}
+}
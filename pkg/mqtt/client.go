@@ -1,12 +1,12 @@
 package mqtt
 
 import (
-	"crypto/tls"
 	"fmt"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/sirupsen/logrus"
 
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
@@ -18,12 +18,83 @@ const (
 	DefaultConnectTimeout       = 10 * time.Second
 	DefaultPingTimeout          = 5 * time.Second
 	DefaultWriteTimeout         = 5 * time.Second
+	DefaultPublishTimeout       = 10 * time.Second // Publish and subscribe round trips of the MQTT 5 client
 	DefaultWaitForConnTimeout   = 100 * time.Millisecond
 	DefaultDisconnectTimeout    = 250 // milliseconds
 )
 
-type Client struct {
-	client       mqtt.Client
+// Client is the MQTT connection of the bridge. mqtt.protocol selects the
+// implementation: paho.mqtt.golang for MQTT 3.1.1 or paho.golang for MQTT 5.
+// Both reconnect on their own and start every connection with a clean
+// session, so subscriptions are made from the connect callback.
+type Client interface {
+	Start() error
+	Stop() error
+	Connect() error
+	// ConnectWithRetry gives up after maxRetries failed attempts, or right
+	// away when the broker refuses the credentials or the client ID.
+	ConnectWithRetry(maxRetries int, retryDelay time.Duration) error
+	Disconnect()
+	IsConnected() bool
+	WaitForConnection(timeout time.Duration) error
+
+	SetOnConnectCallback(callback func())
+	SetOnDisconnectCallback(callback func())
+	// SetWillTopic changes the topic of the last will message. The will is
+	// sent to the broker when connecting, so an active connection is
+	// replaced by a new one, which runs the connect callback again.
+	SetWillTopic(topic string)
+
+	Publish(topic, payload string, retain bool) error
+	// PublishFast publishes a non-retained message with QoS 0 without
+	// waiting for it to be written, so a burst of scans doesn't serialize
+	// on the broker round trip. It returns ErrPublishBackpressure when the
+	// connection doesn't catch up with the pending publishes.
+	PublishFast(topic, payload string) error
+	// Subscribe registers a handler for a topic. Handlers run in their own
+	// goroutine so they can publish without blocking the message router.
+	Subscribe(topic string, handler func(topic string, payload []byte)) error
+	// SubscribeMessages is like Subscribe, but passes the whole message to
+	// the handler, e.g. to tell retained data from live publishes.
+	SubscribeMessages(topic string, handler func(message Message)) error
+	// Unsubscribe removes the subscription and handler of a topic.
+	Unsubscribe(topic string) error
+
+	// LastDisconnect returns details about the most recent connection loss
+	// or failed connection attempt, or nil if none happened yet.
+	LastDisconnect() *DisconnectInfo
+	// PendingFastPublishes returns the number of fast publishes in flight.
+	PendingFastPublishes() int
+}
+
+// Message is a received MQTT message, including whether the broker
+// delivered it from its retained store.
+type Message struct {
+	Topic    string
+	Payload  []byte
+	Retained bool
+}
+
+// NewClient connects to broker_url, failing over to the backup brokers in
+// order, or to all of them at once with publish_to_all.
+func NewClient(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) (Client, error) {
+	if cfg.PublishToAll && len(cfg.BackupBrokerURLs) > 0 {
+		return newMirrorClient(cfg, willTopic, logger)
+	}
+	return newBrokerClient(cfg, willTopic, logger)
+}
+
+func newBrokerClient(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) (Client, error) {
+	if cfg.Protocol == config.MQTTProtocol5 {
+		return newPahoV5Client(cfg, willTopic, logger)
+	}
+	return newPahoV3Client(cfg, willTopic, logger)
+}
+
+// connection is the state both implementations track the same way: the
+// connection status and callbacks, the disconnect telemetry and the fast
+// publish slots.
+type connection struct {
 	config       *config.MQTTConfig
 	logger       *logrus.Logger
 	connected    bool
@@ -31,98 +102,74 @@ type Client struct {
 	willTopic    string
 	onConnect    func()
 	onDisconnect func()
+
+	connectedAt    time.Time
+	lastDisconnect *DisconnectInfo
+	broker         string // Broker of the latest connection attempt
+
+	fastSlots chan struct{}
 }
 
-func NewClient(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) (*Client, error) {
-	c := &Client{
+func newConnection(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) connection {
+	return connection{
 		config:    cfg,
 		logger:    logger,
 		willTopic: willTopic,
+		fastSlots: make(chan struct{}, MaxPendingFastPublishes),
 	}
-
-	opts := c.buildClientOptions()
-	c.client = mqtt.NewClient(opts)
-
-	return c, nil
-}
-
-func (c *Client) buildClientOptions() *mqtt.ClientOptions {
-	opts := mqtt.NewClientOptions().
-		AddBroker(c.config.BrokerURL).
-		SetClientID(c.config.ClientID).
-		SetKeepAlive(time.Duration(c.config.KeepAlive) * time.Second).
-		SetCleanSession(true).
-		SetAutoReconnect(true).
-		SetMaxReconnectInterval(DefaultMaxReconnectInterval).
-		SetConnectRetryInterval(DefaultConnectRetryInterval).
-		SetConnectRetry(true).
-		SetConnectTimeout(DefaultConnectTimeout).
-		SetPingTimeout(DefaultPingTimeout).
-		SetWriteTimeout(DefaultWriteTimeout).
-		SetOnConnectHandler(c.handleConnect).
-		SetConnectionLostHandler(c.handleDisconnect)
-
-	if c.config.Username != "" {
-		opts.SetUsername(c.config.Username)
-		if c.config.Password != "" {
-			opts.SetPassword(c.config.Password)
-		}
-	}
-
-	if c.config.IsSecure() {
-		opts.SetTLSConfig(&tls.Config{
-			InsecureSkipVerify: c.config.InsecureSkipVerify, // #nosec G402 - configurable for dev environments
-		})
-	}
-
-	if c.willTopic != "" {
-		opts.SetWill(c.willTopic, "offline", c.config.QoS, true)
-	}
-
-	return opts
 }
 
-func (c *Client) SetOnConnectCallback(callback func()) {
+func (c *connection) SetOnConnectCallback(callback func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.onConnect = callback
 }
 
-func (c *Client) SetOnDisconnectCallback(callback func()) {
+func (c *connection) SetOnDisconnectCallback(callback func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.onDisconnect = callback
 }
 
-func (c *Client) Start() error {
-	return c.Connect()
-}
-
-func (c *Client) Connect() error {
-	return c.ConnectWithRetry(3, 2*time.Second)
-}
-
-func (c *Client) ConnectWithRetry(maxRetries int, retryDelay time.Duration) error {
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			c.logger.WithField("attempt", attempt+1).Warn("Retrying MQTT connection...")
+// connectWithRetry runs connection attempts. attempt reports whether the
+// client connected within the connect timeout, or the error the attempt
+// failed with.
+func (c *connection) connectWithRetry(maxRetries int, retryDelay time.Duration, attempt func() (bool, error)) error {
+	for i := 0; i <= maxRetries; i++ {
+		if i > 0 {
+			c.logger.WithField("attempt", i+1).Warn("Retrying MQTT connection...")
 			time.Sleep(retryDelay)
 			retryDelay *= 2 // exponential backoff
 		}
 
-		c.logger.Infof("Connecting to MQTT broker: %s (attempt %d/%d)", c.config.BrokerURL, attempt+1, maxRetries+1)
-
-		token := c.client.Connect()
+		brokers := strings.Join(c.config.BrokerURLs(), ", ")
+		c.logger.Infof("Connecting to MQTT broker: %s (attempt %d/%d)", brokers, i+1, maxRetries+1)
 
-		// Use WaitTimeout instead of Wait to prevent hanging
-		if !token.WaitTimeout(DefaultConnectTimeout) {
-			c.logger.Warn("MQTT connection attempt timed out")
-			if attempt == maxRetries {
-				return fmt.Errorf("MQTT connection timed out after %d attempts", maxRetries+1)
+		connected, err := attempt()
+		if err != nil {
+			info := c.recordDisconnect(err)
+			c.logger.WithError(err).Warn("MQTT connection failed")
+			if err := c.refusalError(info); err != nil {
+				return err
+			}
+			if i == maxRetries {
+				return c.connectFailedError(maxRetries+1, info)
 			}
 			continue
 		}
 
-		if token.Error() != nil {
-			c.logger.WithError(token.Error()).Warn("MQTT connection failed")
-			if attempt == maxRetries {
-				return fmt.Errorf("failed to connect to MQTT broker after %d attempts: %w", maxRetries+1, token.Error())
+		if !connected {
+			// Failed attempts of a client retrying in the background are
+			// picked up from its connection notifications instead
+			lastFailure := c.LastDisconnect()
+			if lastFailure != nil {
+				if err := c.refusalError(lastFailure); err != nil {
+					return err
+				}
+			}
+			c.logger.Warn("MQTT connection attempt timed out")
+			if i == maxRetries {
+				return c.connectFailedError(maxRetries+1, lastFailure)
 			}
 			continue
 		}
@@ -134,109 +181,142 @@ func (c *Client) ConnectWithRetry(maxRetries int, retryDelay time.Duration) erro
 	return fmt.Errorf("failed to connect to MQTT broker after %d attempts", maxRetries+1)
 }
 
-func (c *Client) Stop() error {
-	c.Disconnect()
-	return nil
+func (c *connection) connectFailedError(attempts int, lastFailure *DisconnectInfo) error {
+	if lastFailure == nil {
+		return fmt.Errorf("MQTT connection timed out after %d attempts", attempts)
+	}
+	return fmt.Errorf("failed to connect to MQTT broker after %d attempts (%s): %s",
+		attempts, lastFailure.Reason, lastFailure.Error)
 }
 
-func (c *Client) Disconnect() {
-	c.logger.Debug("Disconnecting from MQTT broker")
+// markConnected marks the client connected and returns the will topic to
+// announce the bridge online on with announceConnected.
+func (c *connection) markConnected() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.connected = true
+	c.connectedAt = time.Now()
 
-	c.client.Disconnect(DefaultDisconnectTimeout)
-	c.setConnected(false)
+	if c.onBackupBrokerLocked() {
+		c.logger.WithField("broker", c.broker).Warn("Connected to backup MQTT broker")
+	} else {
+		c.logger.Debug("MQTT client connected")
+	}
+	return c.willTopic
 }
 
-func (c *Client) Publish(topic, payload string, retain bool) error {
-	if !c.IsConnected() {
-		return fmt.Errorf("MQTT client is not connected")
-	}
+// setBroker records the broker a connection attempt is made to, so the
+// connection can tell a backup broker apart.
+func (c *connection) setBroker(brokerURL *url.URL) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.broker = brokerURL.String()
+}
 
-	token := c.client.Publish(topic, c.config.QoS, retain, payload)
-	token.Wait()
-	if err := token.Error(); err != nil {
-		c.logger.WithFields(map[string]any{
-			"topic":  topic,
-			"retain": retain,
-		}).WithError(err).Error("MQTT publish failed")
-		return err
+func (c *connection) onBackupBrokerLocked() bool {
+	if c.broker == "" || len(c.config.BackupBrokerURLs) == 0 {
+		return false
 	}
-
-	return nil
+	primary, err := url.Parse(c.config.BrokerURL)
+	return err == nil && c.broker != primary.String()
 }
 
-func (c *Client) PublishWithRetry(topic, payload string, maxRetries int, retryDelay time.Duration) error {
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if err := c.attemptPublish(topic, payload, attempt, maxRetries); err == nil {
-			return nil
-		}
-
-		if attempt < maxRetries {
-			time.Sleep(retryDelay)
+// announceConnected publishes the bridge online on the will topic and runs
+// the connect callback.
+func (c *connection) announceConnected(willTopic string, publish func(topic, payload string, retain bool) error) {
+	if willTopic != "" {
+		if err := publish(willTopic, "online", true); err != nil {
+			c.logger.Errorf("Failed to publish online status: %v", err)
 		}
 	}
 
-	return fmt.Errorf("failed to publish to %s after %d attempts", topic, maxRetries+1)
-}
+	c.mutex.RLock()
+	onConnect := c.onConnect
+	c.mutex.RUnlock()
 
-func (c *Client) attemptPublish(topic, payload string, attempt, maxRetries int) error {
-	if !c.IsConnected() {
-		return fmt.Errorf("not connected")
+	if onConnect != nil {
+		onConnect()
 	}
+}
 
-	if err := c.Publish(topic, payload, false); err != nil {
-		if attempt == maxRetries {
-			c.logger.WithFields(map[string]any{
-				"topic":    topic,
-				"attempts": maxRetries + 1,
-			}).WithError(err).Error("MQTT publish failed after all retries")
-		}
-		return err
+func (c *connection) handleConnectionLost(err error) {
+	info := c.recordDisconnect(err)
+	c.logger.WithFields(map[string]any{
+		"reason": info.Reason,
+		"uptime": info.Uptime.Round(time.Second).String(),
+	}).Errorf("MQTT connection lost: %v", err)
+	if info.Reason == DisconnectReasonSessionTakeover {
+		c.logger.Warnf("Broker closed the session shortly after connecting - check that client_id '%s' is not used by another client",
+			c.config.ClientID)
 	}
+	c.logger.Info("MQTT client will attempt automatic reconnection...")
+	c.setConnected(false)
+
+	c.mutex.RLock()
+	onDisconnect := c.onDisconnect
+	c.mutex.RUnlock()
 
-	return nil
+	if onDisconnect != nil {
+		onDisconnect()
+	}
 }
 
-func (c *Client) IsConnected() bool {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	return c.connected && c.client.IsConnected()
+// handleConnectFailed records a failed background connection attempt, so
+// the last CONNACK refusal shows up in diagnostics.
+func (c *connection) handleConnectFailed(err error) {
+	info := c.recordDisconnect(err)
+	logger := c.logger.WithField("reason", info.Reason)
+	if err := c.refusalError(info); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+	logger.WithError(err).Debug("MQTT connection attempt failed")
 }
 
-func (c *Client) setConnected(connected bool) {
+func (c *connection) setConnected(connected bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.connected = connected
 }
 
-func (c *Client) handleConnect(client mqtt.Client) {
-	c.logger.Debug("MQTT client connected")
-	c.setConnected(true)
-
-	if c.willTopic != "" {
-		if err := c.Publish(c.willTopic, "online", true); err != nil {
-			c.logger.Errorf("Failed to publish online status: %v", err)
-		}
-	}
-
-	if c.onConnect != nil {
-		c.onConnect()
+func (c *connection) LastDisconnect() *DisconnectInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.lastDisconnect == nil {
+		return nil
 	}
+	info := *c.lastDisconnect
+	return &info
 }
 
-func (c *Client) handleDisconnect(client mqtt.Client, err error) {
-	c.logger.Errorf("MQTT connection lost: %v", err)
-	c.logger.Info("MQTT client will attempt automatic reconnection...")
-	c.setConnected(false)
+func (c *connection) recordDisconnect(err error) *DisconnectInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	if c.onDisconnect != nil {
-		c.onDisconnect()
+	var uptime time.Duration
+	if !c.connectedAt.IsZero() {
+		uptime = time.Since(c.connectedAt)
 	}
+
+	info := &DisconnectInfo{
+		Reason:      ClassifyDisconnect(err, uptime),
+		ConnackCode: connackCode(err),
+		Time:        time.Now(),
+		Uptime:      uptime,
+	}
+	if err != nil {
+		info.Error = err.Error()
+	}
+
+	c.lastDisconnect = info
+	c.connectedAt = time.Time{}
+	return info
 }
 
-func (c *Client) WaitForConnection(timeout time.Duration) error {
+func waitForConnection(client Client, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		if c.IsConnected() {
+		if client.IsConnected() {
 			return nil
 		}
 		time.Sleep(DefaultWaitForConnTimeout)
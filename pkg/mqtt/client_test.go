@@ -1,9 +1,13 @@
 package mqtt
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.mqtt.golang/packets"
 	"github.com/sirupsen/logrus"
 
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
@@ -29,11 +33,16 @@ func TestNewClient_ValidConfig(t *testing.T) {
 		t.Fatal("Expected client to be created")
 	}
 
-	if client.config != cfg {
+	v3Client, ok := client.(*pahoV3Client)
+	if !ok {
+		t.Fatalf("Expected the paho v3 client by default, got %T", client)
+	}
+
+	if v3Client.config != cfg {
 		t.Error("Expected config to be stored")
 	}
 
-	if client.logger != logger {
+	if v3Client.logger != logger {
 		t.Error("Expected logger to be stored")
 	}
 }
@@ -83,7 +92,7 @@ func TestClient_SetCallbacks(t *testing.T) {
 	}
 
 	logger := logrus.New()
-	client, err := NewClient(cfg, "test/will", logger)
+	client, err := newPahoV3Client(cfg, "test/will", logger)
 	if err != nil {
 		t.Fatalf("Expected no error creating client, got: %v", err)
 	}
@@ -233,3 +242,153 @@ func TestClient_Disconnect_Safe(t *testing.T) {
 	client.Disconnect()
 	client.Disconnect()
 }
+
+func TestClient_RefusalError(t *testing.T) {
+	cfg := &config.MQTTConfig{
+		BrokerURL: "mqtt://localhost:1883",
+		ClientID:  "test-client",
+		Username:  "scanner",
+	}
+
+	client, err := newPahoV3Client(cfg, "test/will", logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+		contains string
+	}{
+		{"Bad credentials", packets.ErrorRefusedBadUsernameOrPassword, ErrAuthRejected, "user 'scanner'"},
+		{"Not authorized", packets.ErrorRefusedNotAuthorised, ErrAuthRejected, "broker ACLs"},
+		{"Client ID rejected", packets.ErrorRefusedIDRejected, ErrClientIDRejected, "client_id 'test-client'"},
+		{"Protocol version", packets.ErrorRefusedBadProtocolVersion, ErrProtocolRejected, "CONNACK 1"},
+		{"Broker unavailable", packets.ErrorRefusedServerUnavailable, nil, ""},
+		{"Network error", errors.New("connection refused"), nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := client.recordDisconnect(tt.err)
+			got := client.refusalError(info)
+
+			if tt.expected == nil {
+				if got != nil {
+					t.Errorf("Expected no refusal error, got: %v", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.expected) {
+				t.Errorf("Expected error wrapping %v, got: %v", tt.expected, got)
+			}
+			if got != nil && !strings.Contains(got.Error(), tt.contains) {
+				t.Errorf("Expected error to contain %q, got: %v", tt.contains, got)
+			}
+		})
+	}
+}
+
+func TestNewClient_MQTT5(t *testing.T) {
+	cfg := &config.MQTTConfig{
+		BrokerURL: "mqtt://localhost:1883",
+		ClientID:  "test-client",
+		Protocol:  config.MQTTProtocol5,
+	}
+
+	client, err := NewClient(cfg, "test/will", logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+	if _, ok := client.(*pahoV5Client); !ok {
+		t.Fatalf("Expected the MQTT 5 client, got %T", client)
+	}
+
+	if client.IsConnected() {
+		t.Error("Expected new client to not be connected")
+	}
+	if err := client.Publish("test/topic", "test message", false); err == nil {
+		t.Error("Expected error when publishing while not connected")
+	}
+	if err := client.PublishFast("test/topic", "test message"); err == nil {
+		t.Error("Expected error when fast publishing while not connected")
+	}
+	if err := client.Subscribe("test/topic", func(string, []byte) {}); err == nil {
+		t.Error("Expected error when subscribing while not connected")
+	}
+	if err := client.Unsubscribe("test/topic"); err == nil {
+		t.Error("Expected error when unsubscribing while not connected")
+	}
+
+	// Safe before the first connection and repeatedly
+	client.SetWillTopic("test/other")
+	client.Disconnect()
+	client.Disconnect()
+}
+
+func TestClient_RefusalError_MQTT5(t *testing.T) {
+	cfg := &config.MQTTConfig{
+		BrokerURL: "mqtt://localhost:1883",
+		ClientID:  "test-client",
+		Username:  "scanner",
+		Protocol:  config.MQTTProtocol5,
+	}
+
+	client, err := newPahoV5Client(cfg, "test/will", logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		reasonCode byte
+		expected   error
+		contains   string
+	}{
+		{"Bad credentials", 0x86, ErrAuthRejected, "user 'scanner'"},
+		{"Not authorized", 0x87, ErrAuthRejected, "broker ACLs"},
+		{"Client ID rejected", 0x85, ErrClientIDRejected, "client_id 'test-client'"},
+		{"Protocol version", 0x84, ErrProtocolRejected, "does not support MQTT 5"},
+		{"Broker unavailable", 0x88, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := client.recordDisconnect(&autopaho.ConnackError{ReasonCode: tt.reasonCode})
+			got := client.refusalError(info)
+
+			if tt.expected == nil {
+				if got != nil {
+					t.Errorf("Expected no refusal error, got: %v", got)
+				}
+				return
+			}
+			if !errors.Is(got, tt.expected) {
+				t.Errorf("Expected error wrapping %v, got: %v", tt.expected, got)
+			}
+			if got != nil && !strings.Contains(got.Error(), tt.contains) {
+				t.Errorf("Expected error to contain %q, got: %v", tt.contains, got)
+			}
+		})
+	}
+}
+
+func TestClient_PublishFast_NotConnected(t *testing.T) {
+	cfg := &config.MQTTConfig{
+		BrokerURL: "mqtt://localhost:1883",
+		ClientID:  "test-client",
+	}
+
+	client, err := NewClient(cfg, "test/will", logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+
+	if err := client.PublishFast("test/topic", "test message"); err == nil {
+		t.Error("Expected error when publishing while not connected")
+	}
+	if pending := client.PendingFastPublishes(); pending != 0 {
+		t.Errorf("Expected no pending fast publishes, got %d", pending)
+	}
+}
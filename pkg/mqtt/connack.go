@@ -0,0 +1,102 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// Reasons for connections refused by the broker in its CONNACK.
+const (
+	DisconnectReasonProtocolVersion   = "unsupported_protocol"
+	DisconnectReasonClientIDRejected  = "client_id_rejected"
+	DisconnectReasonBrokerUnavailable = "broker_unavailable"
+	DisconnectReasonBadCredentials    = "bad_credentials"
+	DisconnectReasonNotAuthorized     = "not_authorized"
+)
+
+var (
+	// ErrAuthRejected is returned when the broker refuses the configured credentials.
+	// Retrying cannot succeed, so the connection attempt stops immediately.
+	ErrAuthRejected = errors.New("MQTT broker rejected the credentials")
+	// ErrClientIDRejected is returned when the broker refuses the client_id,
+	// e.g. because it is malformed or banned.
+	ErrClientIDRejected = errors.New("MQTT broker rejected the client ID")
+	// ErrProtocolRejected is returned when the broker doesn't support the configured protocol version.
+	ErrProtocolRejected = errors.New("MQTT broker rejected the protocol version")
+)
+
+// connackReasons holds the MQTT 3.1.1 return codes (1-5) and the MQTT 5
+// reason codes (0x80 and up) that refuse a connection.
+var connackReasons = map[byte]string{
+	packets.ErrRefusedBadProtocolVersion:    DisconnectReasonProtocolVersion,
+	packets.ErrRefusedIDRejected:            DisconnectReasonClientIDRejected,
+	packets.ErrRefusedServerUnavailable:     DisconnectReasonBrokerUnavailable,
+	packets.ErrRefusedBadUsernameOrPassword: DisconnectReasonBadCredentials,
+	packets.ErrRefusedNotAuthorised:         DisconnectReasonNotAuthorized,
+
+	0x84: DisconnectReasonProtocolVersion,   // Unsupported Protocol Version
+	0x85: DisconnectReasonClientIDRejected,  // Client Identifier not valid
+	0x86: DisconnectReasonBadCredentials,    // Bad User Name or Password
+	0x87: DisconnectReasonNotAuthorized,     // Not authorized
+	0x88: DisconnectReasonBrokerUnavailable, // Server unavailable
+	0x89: DisconnectReasonBrokerUnavailable, // Server busy
+	0x8A: DisconnectReasonClientIDRejected,  // Banned
+}
+
+// connackCode returns the CONNACK return or reason code behind a connection
+// error of either paho library, or 0 when the broker did not refuse the
+// connection.
+func connackCode(err error) byte {
+	var connackErr *autopaho.ConnackError
+	if errors.As(err, &connackErr) {
+		if _, known := connackReasons[connackErr.ReasonCode]; known {
+			return connackErr.ReasonCode
+		}
+		return 0
+	}
+
+	for code := range connackReasons {
+		if code >= 0x80 {
+			continue
+		}
+		if errors.Is(err, packets.ConnErrors[code]) {
+			return code
+		}
+	}
+	return 0
+}
+
+// refusalError turns a connection refusal that retrying cannot fix into a
+// user-readable error naming the configuration to check. It returns nil for
+// every other failure.
+func (c *connection) refusalError(info *DisconnectInfo) error {
+	user := fmt.Sprintf("user '%s'", c.config.Username)
+	if c.config.Username == "" {
+		user = "anonymous clients"
+	}
+
+	switch info.Reason {
+	case DisconnectReasonBadCredentials:
+		return fmt.Errorf("%w: bad username or password for %s (CONNACK %d) - check mqtt.username and mqtt.password",
+			ErrAuthRejected, user, info.ConnackCode)
+	case DisconnectReasonNotAuthorized:
+		return fmt.Errorf("%w: %s not authorized to connect (CONNACK %d) - check the broker ACLs",
+			ErrAuthRejected, user, info.ConnackCode)
+	case DisconnectReasonClientIDRejected:
+		return fmt.Errorf("%w: client_id '%s' is invalid or banned (CONNACK %d) - check mqtt.client_id",
+			ErrClientIDRejected, c.config.ClientID, info.ConnackCode)
+	case DisconnectReasonProtocolVersion:
+		if c.config.Protocol == config.MQTTProtocol5 {
+			return fmt.Errorf("%w: broker does not support MQTT 5 (CONNACK %d) - set mqtt.protocol to \"%s\"",
+				ErrProtocolRejected, info.ConnackCode, config.MQTTProtocol311)
+		}
+		return fmt.Errorf("%w: broker supports neither MQTT 3.1.1 nor 3.1 (CONNACK %d)",
+			ErrProtocolRejected, info.ConnackCode)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package mqtt
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	DisconnectReasonDNS             = "dns_failure"
+	DisconnectReasonSessionTakeover = "session_takeover"
+	DisconnectReasonKeepalive       = "keepalive_timeout"
+	DisconnectReasonBrokerClosed    = "broker_closed"
+	DisconnectReasonNetwork         = "network_error"
+	DisconnectReasonUnknown         = "unknown"
+)
+
+// sessionTakeoverWindow is how long a connection must survive before an
+// unexplained broker-side close is no longer blamed on a duplicate client_id.
+const sessionTakeoverWindow = 30 * time.Second
+
+type DisconnectInfo struct {
+	Reason      string
+	Error       string
+	ConnackCode byte // Non-zero when the broker refused the connection
+	Time        time.Time
+	Uptime      time.Duration
+}
+
+// ClassifyDisconnect maps a paho connection error to a coarse reason so users
+// can tell a duplicate client_id from a network issue.
+func ClassifyDisconnect(err error, uptime time.Duration) string {
+	if err == nil {
+		return DisconnectReasonUnknown
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return DisconnectReasonDNS
+	}
+
+	if code := connackCode(err); code != 0 {
+		return connackReasons[code]
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "pingresp not received"):
+		return DisconnectReasonKeepalive
+	case strings.Contains(message, "not authorized"):
+		return DisconnectReasonNotAuthorized
+	case strings.Contains(message, "bad user name or password"):
+		return DisconnectReasonBadCredentials
+	}
+
+	if errors.Is(err, io.EOF) || strings.Contains(message, "connection reset by peer") {
+		if uptime > 0 && uptime < sessionTakeoverWindow {
+			return DisconnectReasonSessionTakeover
+		}
+		return DisconnectReasonBrokerClosed
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return DisconnectReasonNetwork
+	}
+
+	return DisconnectReasonUnknown
+}
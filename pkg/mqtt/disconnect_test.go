@@ -0,0 +1,43 @@
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+)
+
+func TestClassifyDisconnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		uptime   time.Duration
+		expected string
+	}{
+		{"Nil error", nil, 0, DisconnectReasonUnknown},
+		{"DNS failure", &net.DNSError{Err: "no such host", Name: "broker"}, 0, DisconnectReasonDNS},
+		{"Bad credentials", packets.ErrorRefusedBadUsernameOrPassword, 0, DisconnectReasonBadCredentials},
+		{"Not authorized wrapped", fmt.Errorf("connect: %w", packets.ErrorRefusedNotAuthorised), 0, DisconnectReasonNotAuthorized},
+		{"Client ID rejected", packets.ErrorRefusedIDRejected, 0, DisconnectReasonClientIDRejected},
+		{"Broker unavailable", packets.ErrorRefusedServerUnavailable, 0, DisconnectReasonBrokerUnavailable},
+		{"Protocol version", packets.ErrorRefusedBadProtocolVersion, 0, DisconnectReasonProtocolVersion},
+		{"Not authorized text", errors.New("Not Authorized"), time.Hour, DisconnectReasonNotAuthorized},
+		{"Keepalive", errors.New("pingresp not received, disconnecting"), time.Hour, DisconnectReasonKeepalive},
+		{"EOF shortly after connect", io.EOF, 2 * time.Second, DisconnectReasonSessionTakeover},
+		{"EOF after long uptime", io.EOF, time.Hour, DisconnectReasonBrokerClosed},
+		{"Network error", &net.OpError{Op: "read", Err: errors.New("i/o timeout")}, time.Hour, DisconnectReasonNetwork},
+		{"Other", errors.New("something odd"), time.Hour, DisconnectReasonUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyDisconnect(tt.err, tt.uptime); got != tt.expected {
+				t.Errorf("ClassifyDisconnect() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package mqtt
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxPendingFastPublishes bounds the fast publishes handed to the MQTT
+// library but not yet written to the connection.
+const MaxPendingFastPublishes = 64
+
+// ErrPublishBackpressure is returned by PublishFast when the connection has
+// not caught up with the pending publishes within the write timeout.
+var ErrPublishBackpressure = errors.New("MQTT publish queue full")
+
+// acquireFastSlot takes one of the MaxPendingFastPublishes slots before a
+// fast publish is handed over; releaseFastSlot frees it once the message
+// was written. With all slots taken it blocks until one is freed, which
+// slows the caller down to what the connection sustains instead of queueing
+// without bound.
+func (c *connection) acquireFastSlot() error {
+	timer := time.NewTimer(DefaultWriteTimeout)
+	defer timer.Stop()
+	select {
+	case c.fastSlots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrPublishBackpressure
+	}
+}
+
+func (c *connection) releaseFastSlot(topic string, err error) {
+	<-c.fastSlots
+	if err != nil {
+		c.logger.WithField("topic", topic).WithError(err).Error("MQTT fast publish failed")
+	}
+}
+
+func (c *connection) PendingFastPublishes() int {
+	return len(c.fastSlots)
+}
@@ -0,0 +1,177 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const logHookQueueSize = 100
+
+var secretPattern = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api_key)\s*[=:]\s*)\S+`)
+
+type logRecord struct {
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Time    string         `json:"time"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// LogHook is a logrus hook streaming warning and error records to an MQTT
+// topic. Records are rate limited per minute and scrubbed of known secrets.
+type LogHook struct {
+	client  Client
+	topic   string
+	levels  []logrus.Level
+	limit   int
+	secrets []string
+
+	queue  chan logRecord
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	windowCount int
+	suppressed  int
+}
+
+func NewLogHook(client Client, topic string, minLevel logrus.Level, ratePerMinute int, secrets []string) *LogHook {
+	var levels []logrus.Level
+	for _, level := range logrus.AllLevels {
+		if level <= minLevel {
+			levels = append(levels, level)
+		}
+	}
+
+	var nonEmptySecrets []string
+	for _, secret := range secrets {
+		if secret != "" {
+			nonEmptySecrets = append(nonEmptySecrets, secret)
+		}
+	}
+
+	return &LogHook{
+		client:  client,
+		topic:   topic,
+		levels:  levels,
+		limit:   ratePerMinute,
+		secrets: nonEmptySecrets,
+		queue:   make(chan logRecord, logHookQueueSize),
+		stopCh:  make(chan struct{}),
+	}
+}
+
+func (h *LogHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *LogHook) Fire(entry *logrus.Entry) error {
+	// Failures publishing our own records must not feed back into the stream
+	if topic, ok := entry.Data["topic"]; ok && topic == h.topic {
+		return nil
+	}
+
+	if !h.allow(entry.Time) {
+		return nil
+	}
+
+	fields := make(map[string]any, len(entry.Data))
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		if str, ok := value.(string); ok {
+			value = h.redact(str)
+		}
+		fields[key] = value
+	}
+
+	record := logRecord{
+		Level:   entry.Level.String(),
+		Message: h.redact(entry.Message),
+		Time:    entry.Time.Format(time.RFC3339),
+		Fields:  fields,
+	}
+
+	select {
+	case h.queue <- record:
+	default:
+	}
+	return nil
+}
+
+func (h *LogHook) Start() error {
+	h.wg.Add(1)
+	go h.publisher()
+	return nil
+}
+
+func (h *LogHook) Stop() error {
+	close(h.stopCh)
+	h.wg.Wait()
+	return nil
+}
+
+func (h *LogHook) publisher() {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case <-h.stopCh:
+			return
+		case record := <-h.queue:
+			if !h.client.IsConnected() {
+				continue
+			}
+			payload, err := json.Marshal(record)
+			if err != nil {
+				continue
+			}
+			_ = h.client.Publish(h.topic, string(payload), false)
+		}
+	}
+}
+
+// allow implements a fixed one-minute window. When a new window opens after
+// records were dropped, a summary record is queued first.
+func (h *LogHook) allow(now time.Time) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if now.Sub(h.windowStart) >= time.Minute {
+		if h.suppressed > 0 {
+			select {
+			case h.queue <- logRecord{
+				Level:   logrus.WarnLevel.String(),
+				Message: fmt.Sprintf("%d log records suppressed by rate limit", h.suppressed),
+				Time:    now.Format(time.RFC3339),
+			}:
+			default:
+			}
+		}
+		h.windowStart = now
+		h.windowCount = 0
+		h.suppressed = 0
+	}
+
+	if h.limit > 0 && h.windowCount >= h.limit {
+		h.suppressed++
+		return false
+	}
+
+	h.windowCount++
+	return true
+}
+
+func (h *LogHook) redact(text string) string {
+	for _, secret := range h.secrets {
+		text = strings.ReplaceAll(text, secret, "***")
+	}
+	return secretPattern.ReplaceAllString(text, "${1}***")
+}
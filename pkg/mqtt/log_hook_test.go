@@ -0,0 +1,112 @@
+package mqtt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func newTestLogHook(t *testing.T, ratePerMinute int) *LogHook {
+	t.Helper()
+
+	cfg := &config.MQTTConfig{
+		BrokerURL: "mqtt://localhost:1883",
+		ClientID:  "test-client",
+	}
+	client, err := NewClient(cfg, "test/will", logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+
+	return NewLogHook(client, "test/log", logrus.WarnLevel, ratePerMinute, []string{"hunter2", ""})
+}
+
+func TestLogHook_Levels(t *testing.T) {
+	hook := newTestLogHook(t, 10)
+
+	for _, level := range hook.Levels() {
+		if level > logrus.WarnLevel {
+			t.Errorf("Expected only warn and more severe levels, got %s", level)
+		}
+	}
+	if len(hook.Levels()) != 4 {
+		t.Errorf("Expected 4 levels (panic, fatal, error, warn), got %d", len(hook.Levels()))
+	}
+}
+
+func TestLogHook_Redact(t *testing.T) {
+	hook := newTestLogHook(t, 10)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"auth failed with hunter2", "auth failed with ***"},
+		{"password=abc123 rejected", "password=*** rejected"},
+		{"token: xyz", "token: ***"},
+		{"nothing secret here", "nothing secret here"},
+	}
+
+	for _, tt := range tests {
+		if got := hook.redact(tt.input); got != tt.expected {
+			t.Errorf("redact(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestLogHook_RateLimit(t *testing.T) {
+	hook := newTestLogHook(t, 2)
+	now := time.Now()
+
+	if !hook.allow(now) || !hook.allow(now) {
+		t.Fatal("Expected first two records to be allowed")
+	}
+	if hook.allow(now) {
+		t.Error("Expected third record in the same minute to be suppressed")
+	}
+
+	if !hook.allow(now.Add(time.Minute)) {
+		t.Error("Expected record in the next window to be allowed")
+	}
+
+	select {
+	case record := <-hook.queue:
+		if record.Message != "1 log records suppressed by rate limit" {
+			t.Errorf("Expected suppression summary, got %q", record.Message)
+		}
+	default:
+		t.Error("Expected suppression summary to be queued")
+	}
+}
+
+func TestLogHook_FireSkipsOwnTopic(t *testing.T) {
+	hook := newTestLogHook(t, 10)
+	logger := logrus.New()
+
+	entry := logger.WithField("topic", "test/log").WithError(errors.New("publish failed"))
+	entry.Level = logrus.ErrorLevel
+	entry.Time = time.Now()
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(hook.queue) != 0 {
+		t.Error("Expected records about the log topic itself to be skipped")
+	}
+
+	entry = logger.WithError(errors.New("password=abc"))
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "Something failed"
+	entry.Time = time.Now()
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	record := <-hook.queue
+	if record.Fields["error"] != "password=***" {
+		t.Errorf("Expected error field to be redacted, got %v", record.Fields["error"])
+	}
+}
@@ -0,0 +1,219 @@
+package mqtt
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// mirrorClient keeps a connection to every configured broker and publishes
+// to all connected ones, for mqtt.publish_to_all. It counts as connected
+// while any broker is, so the bridge keeps working with one broker down;
+// a broker connecting runs the connect callback, which republishes the
+// discovery configs and subscriptions to all of them.
+type mirrorClient struct {
+	clients []Client
+
+	mutex        sync.RWMutex
+	onConnect    func()
+	onDisconnect func()
+}
+
+func newMirrorClient(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) (*mirrorClient, error) {
+	m := &mirrorClient{}
+	for _, brokerURL := range cfg.BrokerURLs() {
+		brokerConfig := *cfg
+		brokerConfig.BrokerURL = brokerURL
+		brokerConfig.BackupBrokerURLs = nil
+		brokerConfig.PublishToAll = false
+
+		client, err := newBrokerClient(&brokerConfig, willTopic, logger)
+		if err != nil {
+			return nil, err
+		}
+		client.SetOnConnectCallback(m.handleConnect)
+		client.SetOnDisconnectCallback(m.handleDisconnect)
+		m.clients = append(m.clients, client)
+	}
+	return m, nil
+}
+
+func (m *mirrorClient) Start() error {
+	return m.Connect()
+}
+
+func (m *mirrorClient) Stop() error {
+	m.Disconnect()
+	return nil
+}
+
+func (m *mirrorClient) Connect() error {
+	return m.ConnectWithRetry(3, 2*time.Second)
+}
+
+// ConnectWithRetry connects to all brokers at once and succeeds when any of
+// them connected. The others keep connecting in the background.
+func (m *mirrorClient) ConnectWithRetry(maxRetries int, retryDelay time.Duration) error {
+	errs := make([]error, len(m.clients))
+	var wg sync.WaitGroup
+	for i, client := range m.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.ConnectWithRetry(maxRetries, retryDelay)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+func (m *mirrorClient) Disconnect() {
+	for _, client := range m.clients {
+		client.Disconnect()
+	}
+}
+
+func (m *mirrorClient) IsConnected() bool {
+	for _, client := range m.clients {
+		if client.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mirrorClient) WaitForConnection(timeout time.Duration) error {
+	return waitForConnection(m, timeout)
+}
+
+func (m *mirrorClient) SetOnConnectCallback(callback func()) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.onConnect = callback
+}
+
+func (m *mirrorClient) SetOnDisconnectCallback(callback func()) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.onDisconnect = callback
+}
+
+func (m *mirrorClient) handleConnect() {
+	m.mutex.RLock()
+	onConnect := m.onConnect
+	m.mutex.RUnlock()
+
+	if onConnect != nil {
+		onConnect()
+	}
+}
+
+// handleDisconnect runs the disconnect callback once the last broker is
+// gone.
+func (m *mirrorClient) handleDisconnect() {
+	m.mutex.RLock()
+	onDisconnect := m.onDisconnect
+	m.mutex.RUnlock()
+
+	if onDisconnect != nil && !m.IsConnected() {
+		onDisconnect()
+	}
+}
+
+func (m *mirrorClient) SetWillTopic(topic string) {
+	for _, client := range m.clients {
+		client.SetWillTopic(topic)
+	}
+}
+
+func (m *mirrorClient) Publish(topic, payload string, retain bool) error {
+	return m.each(func(client Client) error {
+		return client.Publish(topic, payload, retain)
+	})
+}
+
+func (m *mirrorClient) PublishFast(topic, payload string) error {
+	return m.each(func(client Client) error {
+		return client.PublishFast(topic, payload)
+	})
+}
+
+func (m *mirrorClient) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	return m.each(func(client Client) error {
+		return client.Subscribe(topic, handler)
+	})
+}
+
+func (m *mirrorClient) SubscribeMessages(topic string, handler func(message Message)) error {
+	return m.each(func(client Client) error {
+		return client.SubscribeMessages(topic, handler)
+	})
+}
+
+func (m *mirrorClient) Unsubscribe(topic string) error {
+	return m.each(func(client Client) error {
+		return client.Unsubscribe(topic)
+	})
+}
+
+// each runs fn on the connected brokers in parallel, so a slow broker
+// doesn't hold up the others. It fails only when no broker is connected or
+// fn failed on all of them; the clients log failures of single brokers.
+func (m *mirrorClient) each(fn func(client Client) error) error {
+	var connected []Client
+	for _, client := range m.clients {
+		if client.IsConnected() {
+			connected = append(connected, client)
+		}
+	}
+	if len(connected) == 0 {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	errs := make([]error, len(connected))
+	var wg sync.WaitGroup
+	for i, client := range connected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(client)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+// LastDisconnect returns the most recent disconnect of any broker.
+func (m *mirrorClient) LastDisconnect() *DisconnectInfo {
+	var latest *DisconnectInfo
+	for _, client := range m.clients {
+		if info := client.LastDisconnect(); info != nil && (latest == nil || info.Time.After(latest.Time)) {
+			latest = info
+		}
+	}
+	return latest
+}
+
+func (m *mirrorClient) PendingFastPublishes() int {
+	pending := 0
+	for _, client := range m.clients {
+		pending = max(pending, client.PendingFastPublishes())
+	}
+	return pending
+}
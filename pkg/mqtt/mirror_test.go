@@ -0,0 +1,158 @@
+package mqtt
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// fakeClient is a broker connection of a mirrorClient under test.
+type fakeClient struct {
+	Client
+	connected  bool
+	publishErr error
+	published  []string
+	lastError  *DisconnectInfo
+}
+
+func (f *fakeClient) IsConnected() bool { return f.connected }
+
+func (f *fakeClient) Publish(topic, _ string, _ bool) error {
+	if f.publishErr != nil {
+		return f.publishErr
+	}
+	f.published = append(f.published, topic)
+	return nil
+}
+
+func (f *fakeClient) LastDisconnect() *DisconnectInfo { return f.lastError }
+
+func TestNewClient_BackupBrokers(t *testing.T) {
+	for _, protocol := range []string{config.MQTTProtocol311, config.MQTTProtocol5} {
+		t.Run(protocol, func(t *testing.T) {
+			cfg := &config.MQTTConfig{
+				BrokerURL:        "mqtt://primary:1883",
+				BackupBrokerURLs: []string{"mqtt://backup:1883"},
+				ClientID:         "test-client",
+				KeepAlive:        60,
+				Protocol:         protocol,
+			}
+
+			client, err := NewClient(cfg, "test/will", logrus.New())
+			if err != nil {
+				t.Fatalf("Expected no error creating client, got: %v", err)
+			}
+
+			switch c := client.(type) {
+			case *pahoV3Client:
+				options := c.pahoClient().OptionsReader()
+				if servers := options.Servers(); len(servers) != 2 || servers[1].Host != "backup:1883" {
+					t.Errorf("Expected primary and backup brokers, got %v", servers)
+				}
+			case *pahoV5Client:
+				if len(c.brokerURLs) != 2 || c.brokerURLs[1].Host != "backup:1883" {
+					t.Errorf("Expected primary and backup brokers, got %v", c.brokerURLs)
+				}
+			default:
+				t.Errorf("Expected a single failover client, got %T", client)
+			}
+		})
+	}
+}
+
+func TestNewClient_PublishToAll(t *testing.T) {
+	cfg := &config.MQTTConfig{
+		BrokerURL:        "mqtt://primary:1883",
+		BackupBrokerURLs: []string{"mqtt://backup:1883"},
+		PublishToAll:     true,
+		ClientID:         "test-client",
+		KeepAlive:        60,
+	}
+
+	client, err := NewClient(cfg, "test/will", logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error creating client, got: %v", err)
+	}
+	mirror, ok := client.(*mirrorClient)
+	if !ok {
+		t.Fatalf("Expected a mirror client, got %T", client)
+	}
+	if len(mirror.clients) != 2 {
+		t.Fatalf("Expected a client per broker, got %d", len(mirror.clients))
+	}
+	if backup := mirror.clients[1].(*pahoV3Client).config; backup.BrokerURL != "mqtt://backup:1883" || len(backup.BackupBrokerURLs) != 0 {
+		t.Errorf("Expected the second client to connect only to the backup, got %+v", backup)
+	}
+	if err := client.Publish("test/topic", "payload", false); err == nil {
+		t.Error("Expected publish to fail while no broker is connected")
+	}
+}
+
+func TestMirrorClient_Publish(t *testing.T) {
+	primary := &fakeClient{connected: true, publishErr: errors.New("timeout")}
+	backup := &fakeClient{connected: true}
+	offline := &fakeClient{}
+	mirror := &mirrorClient{clients: []Client{primary, backup, offline}}
+
+	if err := mirror.Publish("test/topic", "payload", true); err != nil {
+		t.Errorf("Expected publish to succeed on the backup, got: %v", err)
+	}
+	if len(backup.published) != 1 || len(offline.published) != 0 {
+		t.Errorf("Expected only the connected brokers to be published to, got %v and %v", backup.published, offline.published)
+	}
+
+	backup.publishErr = errors.New("not authorized")
+	if err := mirror.Publish("test/topic", "payload", true); err == nil {
+		t.Error("Expected publish to fail when it failed on every broker")
+	}
+}
+
+func TestMirrorClient_Disconnect(t *testing.T) {
+	primary := &fakeClient{connected: true}
+	backup := &fakeClient{connected: true}
+	mirror := &mirrorClient{clients: []Client{primary, backup}}
+
+	disconnects := 0
+	mirror.SetOnDisconnectCallback(func() { disconnects++ })
+
+	primary.connected = false
+	mirror.handleDisconnect()
+	if disconnects != 0 || !mirror.IsConnected() {
+		t.Error("Expected the mirror to stay connected while a broker is")
+	}
+
+	backup.connected = false
+	mirror.handleDisconnect()
+	if disconnects != 1 || mirror.IsConnected() {
+		t.Error("Expected the disconnect callback once the last broker is gone")
+	}
+
+	now := time.Now()
+	primary.lastError = &DisconnectInfo{Reason: "network", Time: now.Add(-time.Minute)}
+	backup.lastError = &DisconnectInfo{Reason: "keepalive", Time: now}
+	if info := mirror.LastDisconnect(); info == nil || info.Reason != "keepalive" {
+		t.Errorf("Expected the latest disconnect, got %+v", info)
+	}
+}
+
+func TestConnection_OnBackupBroker(t *testing.T) {
+	cfg := &config.MQTTConfig{BrokerURL: "mqtt://primary:1883", BackupBrokerURLs: []string{"mqtt://backup:1883"}}
+	c := newConnection(cfg, "", logrus.New())
+
+	primary, _ := url.Parse("mqtt://primary:1883")
+	c.setBroker(primary)
+	if c.onBackupBrokerLocked() {
+		t.Error("Expected the primary broker not to count as backup")
+	}
+
+	backup, _ := url.Parse("mqtt://backup:1883")
+	c.setBroker(backup)
+	if !c.onBackupBrokerLocked() {
+		t.Error("Expected the backup broker to be recognized")
+	}
+}
@@ -0,0 +1,239 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// pahoV3Client speaks MQTT 3.1.1 through paho.mqtt.golang.
+type pahoV3Client struct {
+	connection
+	client    mqtt.Client
+	tlsConfig *tls.Config
+}
+
+func newPahoV3Client(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) (*pahoV3Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &pahoV3Client{connection: newConnection(cfg, willTopic, logger), tlsConfig: tlsConfig}
+	c.client = mqtt.NewClient(c.buildClientOptions())
+	return c, nil
+}
+
+func (c *pahoV3Client) buildClientOptions() *mqtt.ClientOptions {
+	opts := mqtt.NewClientOptions()
+	// paho tries the brokers in order on every connection attempt, so it
+	// returns to broker_url once the backup connection drops
+	for _, brokerURL := range c.config.BrokerURLs() {
+		opts.AddBroker(brokerURL)
+	}
+	opts.SetClientID(c.config.ClientID).
+		SetKeepAlive(time.Duration(c.config.KeepAlive) * time.Second).
+		SetCleanSession(true).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(DefaultMaxReconnectInterval).
+		SetConnectRetryInterval(DefaultConnectRetryInterval).
+		SetConnectRetry(true).
+		SetConnectTimeout(DefaultConnectTimeout).
+		SetPingTimeout(DefaultPingTimeout).
+		SetWriteTimeout(DefaultWriteTimeout).
+		SetOnConnectHandler(c.handleConnect).
+		SetConnectionAttemptHandler(c.handleConnectionAttempt).
+		SetConnectionNotificationHandler(c.handleConnectionNotification).
+		SetConnectionLostHandler(c.handleDisconnect)
+
+	if c.config.Username != "" {
+		opts.SetUsername(c.config.Username)
+		if c.config.Password != "" {
+			opts.SetPassword(c.config.Password)
+		}
+	}
+
+	if c.tlsConfig != nil {
+		opts.SetTLSConfig(c.tlsConfig)
+	}
+
+	if c.willTopic != "" {
+		opts.SetWill(c.willTopic, "offline", c.config.QoS, true)
+	}
+
+	return opts
+}
+
+func (c *pahoV3Client) Start() error {
+	return c.Connect()
+}
+
+func (c *pahoV3Client) Connect() error {
+	return c.ConnectWithRetry(3, 2*time.Second)
+}
+
+func (c *pahoV3Client) ConnectWithRetry(maxRetries int, retryDelay time.Duration) error {
+	return c.connectWithRetry(maxRetries, retryDelay, func() (bool, error) {
+		// Use WaitTimeout instead of Wait to prevent hanging. With connect retry
+		// enabled the token only completes once connected.
+		token := c.pahoClient().Connect()
+		if !token.WaitTimeout(DefaultConnectTimeout) {
+			return false, nil
+		}
+		return token.Error() == nil, token.Error()
+	})
+}
+
+func (c *pahoV3Client) handleConnectionAttempt(broker *url.URL, tlsConfig *tls.Config) *tls.Config {
+	c.setBroker(broker)
+	return tlsConfig
+}
+
+// handleConnectionNotification records failed connection attempts, including
+// background reconnects.
+func (c *pahoV3Client) handleConnectionNotification(_ mqtt.Client, notification mqtt.ConnectionNotification) {
+	if failed, ok := notification.(mqtt.ConnectionNotificationFailed); ok {
+		c.handleConnectFailed(failed.Reason)
+	}
+}
+
+func (c *pahoV3Client) Stop() error {
+	c.Disconnect()
+	return nil
+}
+
+func (c *pahoV3Client) Disconnect() {
+	c.logger.Debug("Disconnecting from MQTT broker")
+
+	c.pahoClient().Disconnect(DefaultDisconnectTimeout)
+	c.setConnected(false)
+}
+
+func (c *pahoV3Client) SetWillTopic(topic string) {
+	c.mutex.Lock()
+	previous := c.client
+	active := previous.IsConnected()
+	c.willTopic = topic
+	c.client = mqtt.NewClient(c.buildClientOptions())
+	c.connected = false
+	c.mutex.Unlock()
+
+	if !active {
+		return
+	}
+
+	c.logger.WithField("will_topic", topic).Info("Reconnecting to MQTT broker to change the last will topic")
+	previous.Disconnect(DefaultDisconnectTimeout)
+	// With connect retry enabled the new client keeps trying in the
+	// background; failures are logged from the connection notifications.
+	c.pahoClient().Connect()
+}
+
+func (c *pahoV3Client) pahoClient() mqtt.Client {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.client
+}
+
+func (c *pahoV3Client) Publish(topic, payload string, retain bool) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	token := c.pahoClient().Publish(topic, c.config.QoS, retain, payload)
+	token.Wait()
+	if err := token.Error(); err != nil {
+		c.logger.WithFields(map[string]any{
+			"topic":  topic,
+			"retain": retain,
+		}).WithError(err).Error("MQTT publish failed")
+		return err
+	}
+
+	return nil
+}
+
+func (c *pahoV3Client) PublishFast(topic, payload string) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+	if err := c.acquireFastSlot(); err != nil {
+		return err
+	}
+
+	token := c.pahoClient().Publish(topic, 0, false, payload)
+	go func() {
+		token.Wait()
+		c.releaseFastSlot(topic, token.Error())
+	}()
+
+	return nil
+}
+
+func (c *pahoV3Client) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	return c.SubscribeMessages(topic, func(message Message) {
+		handler(message.Topic, message.Payload)
+	})
+}
+
+func (c *pahoV3Client) SubscribeMessages(topic string, handler func(message Message)) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	token := c.pahoClient().Subscribe(topic, c.config.QoS, func(_ mqtt.Client, message mqtt.Message) {
+		go handler(Message{Topic: message.Topic(), Payload: message.Payload(), Retained: message.Retained()})
+	})
+	token.Wait()
+	if err := token.Error(); err != nil {
+		c.logger.WithField("topic", topic).WithError(err).Error("MQTT subscribe failed")
+		return err
+	}
+
+	return nil
+}
+
+func (c *pahoV3Client) Unsubscribe(topic string) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	token := c.pahoClient().Unsubscribe(topic)
+	token.Wait()
+	if err := token.Error(); err != nil {
+		c.logger.WithField("topic", topic).WithError(err).Error("MQTT unsubscribe failed")
+		return err
+	}
+
+	return nil
+}
+
+func (c *pahoV3Client) IsConnected() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.connected && c.client.IsConnected()
+}
+
+func (c *pahoV3Client) WaitForConnection(timeout time.Duration) error {
+	return waitForConnection(c, timeout)
+}
+
+func (c *pahoV3Client) handleConnect(client mqtt.Client) {
+	if client != c.pahoClient() {
+		// A client replaced by SetWillTopic connected late
+		client.Disconnect(DefaultDisconnectTimeout)
+		return
+	}
+
+	c.announceConnected(c.markConnected(), c.Publish)
+}
+
+func (c *pahoV3Client) handleDisconnect(_ mqtt.Client, err error) {
+	c.handleConnectionLost(err)
+}
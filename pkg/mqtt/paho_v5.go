@@ -0,0 +1,323 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// pahoV5Client speaks MQTT 5 through paho.golang, whose autopaho connection
+// manager handles the reconnects. It is the base for MQTT 5 features paho
+// v3 lacks, like manual acknowledgements and enhanced authentication.
+type pahoV5Client struct {
+	connection
+	brokerURLs []*url.URL
+	tlsConfig  *tls.Config
+	router     *paho.StandardRouter
+
+	// manager is replaced by SetWillTopic; callbacks of a replaced manager
+	// are ignored.
+	manager   *autopaho.ConnectionManager
+	lastError error // Error that ended the current connection
+}
+
+func newPahoV5Client(cfg *config.MQTTConfig, willTopic string, logger *logrus.Logger) (*pahoV5Client, error) {
+	var brokerURLs []*url.URL
+	for _, rawURL := range cfg.BrokerURLs() {
+		brokerURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MQTT broker URL '%s': %w", rawURL, err)
+		}
+		brokerURLs = append(brokerURLs, brokerURL)
+	}
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pahoV5Client{
+		connection: newConnection(cfg, willTopic, logger),
+		brokerURLs: brokerURLs,
+		tlsConfig:  tlsConfig,
+		router:     paho.NewStandardRouter(),
+	}, nil
+}
+
+func (c *pahoV5Client) buildClientConfig() autopaho.ClientConfig {
+	clientConfig := autopaho.ClientConfig{
+		// autopaho tries the brokers in order on every connection attempt,
+		// so it returns to broker_url once the backup connection drops
+		ServerUrls:                    c.brokerURLs,
+		KeepAlive:                     uint16(min(c.config.KeepAlive, math.MaxUint16)), // #nosec G115 - bounded
+		CleanStartOnInitialConnection: true,
+		ReconnectBackoff: autopaho.NewExponentialBackoff(
+			DefaultConnectRetryInterval, DefaultMaxReconnectInterval, DefaultConnectRetryInterval, 2),
+		ConnectTimeout:  DefaultConnectTimeout,
+		ConnectUsername: c.config.Username,
+		ConnectPassword: []byte(c.config.Password),
+		ConnectPacketBuilder: func(connect *paho.Connect, broker *url.URL) (*paho.Connect, error) {
+			c.setBroker(broker)
+			return connect, nil
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID:          c.config.ClientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){c.route},
+			OnClientError:     c.setLastError,
+			OnServerDisconnect: func(disconnect *paho.Disconnect) {
+				c.setLastError(fmt.Errorf("broker closed the connection (reason %d)", disconnect.ReasonCode))
+			},
+		},
+	}
+
+	if c.tlsConfig != nil {
+		clientConfig.TlsCfg = c.tlsConfig
+	}
+
+	if c.willTopic != "" {
+		clientConfig.WillMessage = &paho.WillMessage{Topic: c.willTopic, Payload: []byte("offline"), QoS: c.config.QoS, Retain: true}
+	}
+
+	return clientConfig
+}
+
+// start creates a connection manager, which keeps connecting in the
+// background until it is disconnected.
+func (c *pahoV5Client) start() (*autopaho.ConnectionManager, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.manager != nil {
+		return c.manager, nil
+	}
+
+	var manager *autopaho.ConnectionManager
+	clientConfig := c.buildClientConfig()
+	clientConfig.OnConnectionUp = func(_ *autopaho.ConnectionManager, _ *paho.Connack) {
+		if c.currentManager() != manager {
+			return
+		}
+		willTopic := c.markConnected()
+		// Callbacks must not block the connection manager
+		go c.announceConnected(willTopic, c.Publish)
+	}
+	clientConfig.OnConnectionDown = func() bool {
+		if c.currentManager() != manager {
+			return false
+		}
+		c.handleConnectionLost(c.takeLastError())
+		return true
+	}
+	clientConfig.OnConnectError = c.handleConnectFailed
+
+	manager, err := autopaho.NewConnection(context.Background(), clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MQTT 5 connection: %w", err)
+	}
+	c.manager = manager
+	return manager, nil
+}
+
+func (c *pahoV5Client) currentManager() *autopaho.ConnectionManager {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.manager
+}
+
+func (c *pahoV5Client) setLastError(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lastError = err
+}
+
+func (c *pahoV5Client) takeLastError() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	err := c.lastError
+	c.lastError = nil
+	return err
+}
+
+func (c *pahoV5Client) Start() error {
+	return c.Connect()
+}
+
+func (c *pahoV5Client) Connect() error {
+	return c.ConnectWithRetry(3, 2*time.Second)
+}
+
+func (c *pahoV5Client) ConnectWithRetry(maxRetries int, retryDelay time.Duration) error {
+	manager, err := c.start()
+	if err != nil {
+		return err
+	}
+
+	return c.connectWithRetry(maxRetries, retryDelay, func() (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultConnectTimeout)
+		defer cancel()
+		if err := manager.AwaitConnection(ctx); err != nil {
+			return false, nil
+		}
+		// The connection is up before OnConnectionUp marked it connected
+		return c.WaitForConnection(DefaultConnectTimeout) == nil, nil
+	})
+}
+
+func (c *pahoV5Client) Stop() error {
+	c.Disconnect()
+	return nil
+}
+
+func (c *pahoV5Client) Disconnect() {
+	c.logger.Debug("Disconnecting from MQTT broker")
+
+	c.mutex.Lock()
+	manager := c.manager
+	c.manager = nil
+	c.connected = false
+	c.mutex.Unlock()
+
+	if manager != nil {
+		disconnectManager(manager)
+	}
+}
+
+func (c *pahoV5Client) SetWillTopic(topic string) {
+	c.mutex.Lock()
+	previous := c.manager
+	active := c.connected
+	c.willTopic = topic
+	c.manager = nil
+	c.connected = false
+	c.mutex.Unlock()
+
+	if previous == nil {
+		return
+	}
+
+	if active {
+		c.logger.WithField("will_topic", topic).Info("Reconnecting to MQTT broker to change the last will topic")
+	}
+	disconnectManager(previous)
+	// The new manager keeps trying in the background; failures are logged
+	// from OnConnectError.
+	if _, err := c.start(); err != nil {
+		c.logger.WithError(err).Error("Failed to reconnect to MQTT broker")
+	}
+}
+
+func disconnectManager(manager *autopaho.ConnectionManager) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultDisconnectTimeout*time.Millisecond)
+	defer cancel()
+	_ = manager.Disconnect(ctx)
+}
+
+func (c *pahoV5Client) Publish(topic, payload string, retain bool) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultPublishTimeout)
+	defer cancel()
+	_, err := c.currentManager().Publish(ctx, &paho.Publish{Topic: topic, QoS: c.config.QoS, Retain: retain, Payload: []byte(payload)})
+	if err != nil {
+		c.logger.WithFields(map[string]any{
+			"topic":  topic,
+			"retain": retain,
+		}).WithError(err).Error("MQTT publish failed")
+		return err
+	}
+
+	return nil
+}
+
+func (c *pahoV5Client) PublishFast(topic, payload string) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+	if err := c.acquireFastSlot(); err != nil {
+		return err
+	}
+
+	manager := c.currentManager()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultWriteTimeout)
+		defer cancel()
+		_, err := manager.Publish(ctx, &paho.Publish{Topic: topic, QoS: 0, Payload: []byte(payload)})
+		c.releaseFastSlot(topic, err)
+	}()
+
+	return nil
+}
+
+func (c *pahoV5Client) Subscribe(topic string, handler func(topic string, payload []byte)) error {
+	return c.SubscribeMessages(topic, func(message Message) {
+		handler(message.Topic, message.Payload)
+	})
+}
+
+func (c *pahoV5Client) SubscribeMessages(topic string, handler func(message Message)) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	// Like paho v3, subscribing again replaces the handler of the topic
+	c.router.UnregisterHandler(topic)
+	c.router.RegisterHandler(topic, func(publish *paho.Publish) {
+		go handler(Message{Topic: publish.Topic, Payload: publish.Payload, Retained: publish.Retain})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultPublishTimeout)
+	defer cancel()
+	suback, err := c.currentManager().Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: topic, QoS: c.config.QoS}},
+	})
+	if err == nil && len(suback.Reasons) > 0 && suback.Reasons[0] >= 0x80 {
+		err = fmt.Errorf("broker refused the subscription (reason %d)", suback.Reasons[0])
+	}
+	if err != nil {
+		c.logger.WithField("topic", topic).WithError(err).Error("MQTT subscribe failed")
+		return err
+	}
+
+	return nil
+}
+
+func (c *pahoV5Client) Unsubscribe(topic string) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("MQTT client is not connected")
+	}
+
+	c.router.UnregisterHandler(topic)
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultPublishTimeout)
+	defer cancel()
+	if _, err := c.currentManager().Unsubscribe(ctx, &paho.Unsubscribe{Topics: []string{topic}}); err != nil {
+		c.logger.WithField("topic", topic).WithError(err).Error("MQTT unsubscribe failed")
+		return err
+	}
+
+	return nil
+}
+
+func (c *pahoV5Client) route(received paho.PublishReceived) (bool, error) {
+	c.router.Route(received.Packet.Packet())
+	return true, nil
+}
+
+func (c *pahoV5Client) IsConnected() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.connected && c.manager != nil
+}
+
+func (c *pahoV5Client) WaitForConnection(timeout time.Duration) error {
+	return waitForConnection(c, timeout)
+}
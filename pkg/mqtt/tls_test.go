@@ -0,0 +1,97 @@
+package mqtt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func selfSignedCA(t *testing.T) (*x509.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test broker CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	cert, caPEM := selfSignedCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte(caPEM), 0o600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		cfg         config.MQTTConfig
+		expectTLS   bool
+		expectCA    bool
+		expectError bool
+	}{
+		{"Plain connection", config.MQTTConfig{BrokerURL: "mqtt://localhost:1883"}, false, false, false},
+		{"System roots", config.MQTTConfig{BrokerURL: "mqtts://localhost:8883"}, true, false, false},
+		{"Inline CA", config.MQTTConfig{BrokerURL: "mqtts://localhost:8883", CACert: caPEM}, true, true, false},
+		{"CA file", config.MQTTConfig{BrokerURL: "wss://localhost:8884", CAFile: caFile}, true, true, false},
+		{"Missing CA file", config.MQTTConfig{BrokerURL: "mqtts://localhost:8883", CAFile: caFile + ".missing"}, false, false, true},
+		{"Invalid PEM", config.MQTTConfig{BrokerURL: "mqtts://localhost:8883", CACert: "not a certificate"}, false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(&tt.cfg)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if (tlsConfig != nil) != tt.expectTLS {
+				t.Fatalf("Expected TLS config %v, got %v", tt.expectTLS, tlsConfig)
+			}
+			if !tt.expectTLS {
+				return
+			}
+
+			if (tlsConfig.RootCAs != nil) != tt.expectCA {
+				t.Errorf("Expected custom roots %v, got %v", tt.expectCA, tlsConfig.RootCAs != nil)
+			}
+			if tt.expectCA {
+				if _, err := cert.Verify(x509.VerifyOptions{Roots: tlsConfig.RootCAs}); err != nil {
+					t.Errorf("Expected the configured CA to be trusted, got: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// lookupPollInterval is how often a lookup stage checks its file for
+// changes, at most once per scan.
+const lookupPollInterval = 2 * time.Second
+
+type lookupProcessor struct {
+	path   string
+	logger *logrus.Logger
+
+	mutex     sync.Mutex
+	table     map[string]map[string]string
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+	failed    bool // The file changed since the last load but failed to load
+}
+
+func newLookupProcessor(stage *config.PipelineStageConfig, logger *logrus.Logger) (Processor, error) {
+	processor := &lookupProcessor{path: stage.File, logger: logger}
+	if err := processor.reload(); err != nil {
+		return nil, err
+	}
+	return processor, nil
+}
+
+// Process merges the fields of the barcode's row into the scan attributes.
+// The file is reloaded when it changed; a file that fails to load keeps the
+// previous table and is reported once until it loads again.
+func (p *lookupProcessor) Process(scan *Scan) error {
+	p.mutex.Lock()
+	if time.Since(p.checkedAt) >= lookupPollInterval {
+		if err := p.reload(); err != nil {
+			if !p.failed {
+				p.logger.WithError(err).WithField("file", p.path).Error("Failed to reload lookup file, keeping the previous table")
+			}
+			p.failed = true
+		} else if p.failed {
+			p.logger.WithField("file", p.path).Info("Lookup file reloaded")
+			p.failed = false
+		}
+	}
+	fields := p.table[scan.Barcode]
+	p.mutex.Unlock()
+
+	if len(fields) == 0 {
+		return nil
+	}
+	if scan.Attributes == nil {
+		scan.Attributes = make(map[string]string, len(fields))
+	}
+	maps.Copy(scan.Attributes, fields)
+	return nil
+}
+
+// reload loads the file when its modification time or size changed since
+// the last load.
+func (p *lookupProcessor) reload() error {
+	p.checkedAt = time.Now()
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("lookup file: %w", err)
+	}
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size && p.table != nil {
+		return nil
+	}
+
+	table, err := loadLookupTable(p.path)
+	if err != nil {
+		return err
+	}
+	p.table, p.modTime, p.size = table, info.ModTime(), info.Size()
+	return nil
+}
+
+// loadLookupTable reads a CSV file whose header names the fields, with the
+// barcode in the "barcode" column or else the first one, or a YAML file
+// mapping barcodes to their fields.
+func loadLookupTable(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("lookup file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readLookupCSV(file)
+	case ".yaml", ".yml":
+		table := make(map[string]map[string]string)
+		if err := yaml.NewDecoder(file).Decode(&table); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid lookup file %s: %w", path, err)
+		}
+		return table, nil
+	default:
+		return nil, fmt.Errorf("lookup file %s must be a .csv, .yaml or .yml file", path)
+	}
+}
+
+func readLookupCSV(reader io.Reader) (map[string]map[string]string, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return map[string]map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid lookup file header: %w", err)
+	}
+
+	barcodeColumn := 0
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if strings.EqualFold(header[i], "barcode") {
+			barcodeColumn = i
+		}
+	}
+
+	table := make(map[string]map[string]string)
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return table, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid lookup file: %w", err)
+		}
+
+		fields := make(map[string]string, len(record)-1)
+		for i, value := range record {
+			if i != barcodeColumn && value != "" {
+				fields[header[i]] = value
+			}
+		}
+		table[record[barcodeColumn]] = fields
+	}
+}
@@ -0,0 +1,66 @@
+// Package pipeline runs scans through an ordered chain of processors before
+// they are routed and published.
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDropped is returned by processors to stop the pipeline and discard the
+// scan. It is an expected outcome, e.g. a duplicate or invalid barcode.
+var ErrDropped = errors.New("scan dropped")
+
+// ErrRejected marks scans dropped as misreads, e.g. by a validate stage,
+// rather than as duplicates or by choice. It is returned wrapped together
+// with ErrDropped.
+var ErrRejected = errors.New("invalid barcode")
+
+// Scan is the barcode travelling through a pipeline. Processors may rewrite
+// it in place.
+type Scan struct {
+	ID         string // Unique per scan, lets Home Assistant and the bridge spot redeliveries
+	ScannerID  string
+	Barcode    string
+	Timestamp  time.Time
+	Attributes map[string]string // Added by stages, published with the scan and delivered to sinks
+}
+
+// Processor is a single pipeline stage.
+type Processor interface {
+	Process(scan *Scan) error
+}
+
+// ProcessorFunc adapts a function to the Processor interface.
+type ProcessorFunc func(scan *Scan) error
+
+func (f ProcessorFunc) Process(scan *Scan) error {
+	return f(scan)
+}
+
+// Stage is a named processor; the name identifies it in errors.
+type Stage struct {
+	Name      string
+	Processor Processor
+}
+
+// Pipeline runs its stages in order until one fails or drops the scan.
+type Pipeline struct {
+	stages []Stage
+}
+
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Run passes the scan through every stage. A dropped scan returns an error
+// wrapping ErrDropped.
+func (p *Pipeline) Run(scan *Scan) error {
+	for _, stage := range p.stages {
+		if err := stage.Processor.Process(scan); err != nil {
+			return fmt.Errorf("%s: %w", stage.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,603 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	var order []string
+	record := func(name string, err error) Stage {
+		return Stage{Name: name, Processor: ProcessorFunc(func(*Scan) error {
+			order = append(order, name)
+			return err
+		})}
+	}
+
+	err := New(record("first", nil), record("second", ErrDropped), record("third", nil)).Run(&Scan{})
+	if !errors.Is(err, ErrDropped) {
+		t.Errorf("Expected ErrDropped, got: %v", err)
+	}
+	if len(order) != 2 || order[1] != "second" {
+		t.Errorf("Expected the pipeline to stop after the second stage, ran %v", order)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	stages, err := Build([]config.PipelineStageConfig{
+		{Type: config.StageTransform, TrimPrefix: "]E0", Case: config.TransformCaseUpper},
+		{Type: config.StageValidate, Pattern: "^[0-9A-Z]+$", MinLength: 4, CheckDigit: true},
+		{Type: config.StageDedupe, Window: time.Second},
+		{Type: config.StageEnrich, Attributes: map[string]string{"location": "kitchen"}},
+	}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	chain := New(stages...)
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		barcode  string
+		at       time.Time
+		expected string
+		dropped  bool
+		rejected bool
+	}{
+		{"Transformed", "]E0abc123", now, "ABC123", false, false},
+		{"Duplicate", "ABC123", now.Add(500 * time.Millisecond), "", true, false},
+		{"After window", "abc123", now.Add(2 * time.Second), "ABC123", false, false},
+		{"Too short", "AB1", now, "", true, true},
+		{"Invalid characters", "AB-123", now, "", true, true},
+		{"Valid GTIN", "4006381333931", now, "4006381333931", false, false},
+		{"Wrong check digit", "4006381333932", now, "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := &Scan{ScannerID: "test", Barcode: tt.barcode, Timestamp: tt.at}
+			err := chain.Run(scan)
+
+			if tt.dropped {
+				if !errors.Is(err, ErrDropped) {
+					t.Errorf("Expected scan to be dropped, got: %v", err)
+				}
+				if errors.Is(err, ErrRejected) != tt.rejected {
+					t.Errorf("Expected rejected %v, got: %v", tt.rejected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if scan.Barcode != tt.expected {
+				t.Errorf("Expected barcode %s, got %s", tt.expected, scan.Barcode)
+			}
+			if scan.Attributes["location"] != "kitchen" {
+				t.Errorf("Expected enriched location attribute, got %v", scan.Attributes)
+			}
+		})
+	}
+}
+
+func TestValidateFlag(t *testing.T) {
+	stages, err := Build([]config.PipelineStageConfig{
+		{Type: config.StageValidate, CheckDigit: true, OnInvalid: config.InvalidActionFlag},
+		{Type: config.StageValidate, MinLength: 4, OnInvalid: config.InvalidActionFlag},
+	}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	chain := New(stages...)
+
+	tests := []struct {
+		barcode  string
+		expected string
+	}{
+		{"4006381333931", "true"},
+		{"4006381333932", "false"},
+		{"036000291452", "true"},
+		{"036000291453", "false"},
+		{"ABC", "false"},
+		{"ABC123", "true"},
+	}
+
+	for _, tt := range tests {
+		scan := &Scan{ScannerID: "test", Barcode: tt.barcode}
+		if err := chain.Run(scan); err != nil {
+			t.Fatalf("Expected flagged scans to pass, got: %v", err)
+		}
+		if scan.Attributes[ValidAttribute] != tt.expected {
+			t.Errorf("Expected %s to be flagged valid=%s, got %v", tt.barcode, tt.expected, scan.Attributes)
+		}
+	}
+}
+
+func TestCheckLength(t *testing.T) {
+	tests := []struct {
+		barcode   string
+		minLength int
+		maxLength int
+		valid     bool
+	}{
+		{"ABC", 4, 0, false},
+		{"ABCD", 4, 0, true},
+		{"ABCDE", 0, 4, false},
+		{"ÄÖÜ€", 4, 4, true}, // 4 characters in 9 bytes
+		{"ÄÖÜ€", 0, 3, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckLength(tt.barcode, tt.minLength, tt.maxLength)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected %q within %d-%d to be valid=%v, got: %v", tt.barcode, tt.minLength, tt.maxLength, tt.valid, err)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		stage    config.PipelineStageConfig
+		barcode  string
+		expected string
+		dropped  bool
+	}{
+		{"Trim", config.PipelineStageConfig{Trim: true}, "  ABC123\t", "ABC123", false},
+		{"Prefix and case", config.PipelineStageConfig{TrimPrefix: "]E0", Case: config.TransformCaseLower}, "]E0ABC", "abc", false},
+		{"Substring", config.PipelineStageConfig{SubstringStart: 2, SubstringLength: 5}, "0104006381333931", "04006", false},
+		{"Substring to end", config.PipelineStageConfig{SubstringStart: 3}, "LOCshelf-7", "shelf-7", false},
+		{"Substring past end", config.PipelineStageConfig{SubstringStart: 10}, "ABC", "", true},
+		{"Replace", config.PipelineStageConfig{ReplacePattern: "^0+"}, "000123", "123", false},
+		{"Replace groups", config.PipelineStageConfig{ReplacePattern: `^(\d{3})(\d+)$`, ReplaceWith: "${1}-${2}"}, "123456", "123-456", false},
+		{"Template", config.PipelineStageConfig{Case: config.TransformCaseUpper, Template: "{{ .ScannerID }}/{{ .Value }}"}, "abc", "test/ABC", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.stage.Type = config.StageTransform
+			stages, err := Build([]config.PipelineStageConfig{tt.stage}, logrus.New())
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			scan := &Scan{ScannerID: "test", Barcode: tt.barcode}
+			err = New(stages...).Run(scan)
+			if tt.dropped {
+				if !errors.Is(err, ErrDropped) {
+					t.Errorf("Expected scan to be dropped, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if scan.Barcode != tt.expected {
+				t.Errorf("Expected barcode %s, got %s", tt.expected, scan.Barcode)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Now()
+	at := func(ms int) time.Time { return now.Add(time.Duration(ms) * time.Millisecond) }
+
+	drop, err := newRateLimitProcessor(&config.PipelineStageConfig{MaxPerSecond: 2}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	dropTests := []struct {
+		at      time.Time
+		dropped bool
+	}{
+		{at(0), false},
+		{at(100), true},
+		{at(499), true},
+		{at(500), false},
+		{at(2000), false},
+		{at(2200), true},
+	}
+	for i, tt := range dropTests {
+		err := drop.Process(&Scan{Barcode: "ABC", Timestamp: tt.at})
+		if errors.Is(err, ErrDropped) != tt.dropped {
+			t.Errorf("Scan %d: expected dropped %v, got: %v", i, tt.dropped, err)
+		}
+	}
+
+	processor, err := newRateLimitProcessor(&config.PipelineStageConfig{MaxPerSecond: 10, OnLimit: config.LimitActionQueue}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	queue := processor.(*rateLimitProcessor)
+	clock := now
+	var waits []time.Duration
+	queue.now = func() time.Time { return clock }
+	queue.sleep = func(wait time.Duration) {
+		waits = append(waits, wait)
+		clock = clock.Add(wait)
+	}
+
+	// Scans are run when the worker gets to them, not when they were read
+	for _, ms := range []int{0, 20, 50, 500} {
+		clock = maxTime(clock, at(ms))
+		if err := queue.Process(&Scan{Barcode: "ABC", Timestamp: at(ms)}); err != nil {
+			t.Errorf("Expected queued scan at %dms to pass, got: %v", ms, err)
+		}
+	}
+	expected := []time.Duration{80 * time.Millisecond, 100 * time.Millisecond}
+	if len(waits) != len(expected) || waits[0] != expected[0] || waits[1] != expected[1] {
+		t.Errorf("Expected waits %v, got %v", expected, waits)
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func TestWorker(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	worker := NewWorker(2, func(scan *Scan) {
+		<-release
+		handled <- scan.Barcode
+	})
+
+	// The first scan is taken by the worker and blocks it, queue_size more wait
+	if !worker.Push(&Scan{Barcode: "1"}) {
+		t.Fatal("Expected the first scan to be queued")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(worker.queue) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the worker to take the first scan")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	pushed := 0
+	for _, barcode := range []string{"2", "3", "4"} {
+		if worker.Push(&Scan{Barcode: barcode}) {
+			pushed++
+		}
+	}
+	if pushed != 2 {
+		t.Errorf("Expected queue_size scans to wait and the rest to be rejected, %d queued", pushed)
+	}
+
+	worker.Close()
+	if worker.Push(&Scan{Barcode: "6"}) {
+		t.Error("Expected a closed worker to reject scans")
+	}
+	close(release)
+	for _, expected := range []string{"1", "2", "3"} {
+		select {
+		case barcode := <-handled:
+			if barcode != expected {
+				t.Errorf("Expected scan %s, got %s", expected, barcode)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected queued scan %s to be handled after closing", expected)
+		}
+	}
+}
+
+func TestAIMStage(t *testing.T) {
+	stages, err := Build([]config.PipelineStageConfig{{Type: config.StageAIM}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	chain := New(stages...)
+
+	tests := []struct {
+		barcode    string
+		expected   string
+		symbology  string
+		identifier string
+		dropped    bool
+	}{
+		{"]E04006381333931", "4006381333931", common.SymbologyEAN13, "]E0", false},
+		{"]C1ABC-123", "ABC-123", common.SymbologyCode128, "]C1", false},
+		{"]Q1https://example.com", "https://example.com", common.SymbologyQRCode, "]Q1", false},
+		{"4006381333931", "4006381333931", common.SymbologyEAN13, "", false},
+		{"ABC-123", "ABC-123", common.SymbologyUnknown, "", false},
+		{"]Q1", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			scan := &Scan{ScannerID: "test", Barcode: tt.barcode}
+			err := chain.Run(scan)
+			if tt.dropped {
+				if !errors.Is(err, ErrDropped) {
+					t.Errorf("Expected scan to be dropped, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if scan.Barcode != tt.expected {
+				t.Errorf("Expected barcode %s, got %s", tt.expected, scan.Barcode)
+			}
+			if scan.Attributes[common.SymbologyAttribute] != tt.symbology || scan.Attributes[common.AIMIdentifierAttribute] != tt.identifier {
+				t.Errorf("Expected symbology %s and identifier %q, got %v", tt.symbology, tt.identifier, scan.Attributes)
+			}
+		})
+	}
+}
+
+func TestParseStage(t *testing.T) {
+	stages, err := Build([]config.PipelineStageConfig{{Type: config.StageParse}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	chain := New(stages...)
+
+	scan := &Scan{ScannerID: "test", Barcode: "WIFI:T:WPA;S:Guest;P:secret;;", Attributes: map[string]string{"site": "lobby"}}
+	if err := chain.Run(scan); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if scan.Barcode != "WIFI:T:WPA;S:Guest;P:secret;;" {
+		t.Errorf("Expected the barcode to be unchanged, got %s", scan.Barcode)
+	}
+	expected := map[string]string{"site": "lobby", "payload_format": "wifi", "wifi_ssid": "Guest", "wifi_password": "secret", "wifi_security": "WPA"}
+	for key, value := range expected {
+		if scan.Attributes[key] != value {
+			t.Errorf("Expected attribute %s=%s, got %v", key, value, scan.Attributes)
+		}
+	}
+
+	plain := &Scan{ScannerID: "test", Barcode: "4006381333931"}
+	if err := chain.Run(plain); err != nil || plain.Attributes != nil {
+		t.Errorf("Expected plain barcodes to pass without attributes, got %v (%v)", plain.Attributes, err)
+	}
+}
+
+func TestProductLookup(t *testing.T) {
+	requests := 0
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case failing:
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/api/v2/product/3017620422003":
+			_, _ = w.Write([]byte(`{"status":1,"product":{"product_name":"Nutella","brands":"Ferrero","image_url":"https://images.example/nutella.jpg"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	stages, err := Build([]config.PipelineStageConfig{{Type: config.StageProduct, URL: server.URL}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	chain := New(stages...)
+
+	for range 2 {
+		scan := &Scan{ScannerID: "test", Barcode: "3017620422003"}
+		if err := chain.Run(scan); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		expected := map[string]string{
+			ProductFoundAttribute:    "true",
+			ProductNameAttribute:     "Nutella",
+			ProductBrandAttribute:    "Ferrero",
+			ProductImageURLAttribute: "https://images.example/nutella.jpg",
+		}
+		for key, value := range expected {
+			if scan.Attributes[key] != value {
+				t.Errorf("Expected attribute %s=%s, got %v", key, value, scan.Attributes)
+			}
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the repeated scan to be served from the cache, got %d requests", requests)
+	}
+
+	unknown := &Scan{ScannerID: "test", Barcode: "4006381333931"}
+	if err := chain.Run(unknown); err != nil || unknown.Attributes[ProductFoundAttribute] != "false" {
+		t.Errorf("Expected unknown product to be flagged not found, got %v (%v)", unknown.Attributes, err)
+	}
+
+	notGTIN := &Scan{ScannerID: "test", Barcode: "ABC-123"}
+	if err := chain.Run(notGTIN); err != nil || notGTIN.Attributes != nil || requests != 2 {
+		t.Errorf("Expected other barcodes not to be looked up, got %v after %d requests (%v)", notGTIN.Attributes, requests, err)
+	}
+
+	failing = true
+	failed := &Scan{ScannerID: "test", Barcode: "036000291452"}
+	if err := chain.Run(failed); err != nil || failed.Attributes != nil {
+		t.Errorf("Expected a failed lookup to pass the scan without attributes, got %v (%v)", failed.Attributes, err)
+	}
+	if requests != 3 {
+		t.Fatalf("Expected the failed lookup to be requested, got %d requests", requests)
+	}
+
+	skipped := &Scan{ScannerID: "test", Barcode: "5901234123457"}
+	if err := chain.Run(skipped); err != nil || skipped.Attributes != nil || requests != 3 {
+		t.Errorf("Expected lookups to be skipped after a failure, got %v after %d requests (%v)", skipped.Attributes, requests, err)
+	}
+	cached := &Scan{ScannerID: "test", Barcode: "3017620422003"}
+	if err := chain.Run(cached); err != nil || cached.Attributes[ProductNameAttribute] != "Nutella" {
+		t.Errorf("Expected cached products while lookups are skipped, got %v (%v)", cached.Attributes, err)
+	}
+
+	failing = false
+	stages[0].Processor.(*productProcessor).retryAfter = time.Time{}
+	retried := &Scan{ScannerID: "test", Barcode: "5901234123457"}
+	if err := chain.Run(retried); err != nil || retried.Attributes[ProductFoundAttribute] != "false" || requests != 4 {
+		t.Errorf("Expected lookups to resume after the retry delay, got %v after %d requests (%v)", retried.Attributes, requests, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv")
+	writeFile := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write lookup file: %v", err)
+		}
+	}
+	writeFile("name,barcode,category\nNutella,3017620422003,spreads\nFlour,8410000000000,\n")
+
+	processor, err := newLookupProcessor(&config.PipelineStageConfig{File: path}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	scan := &Scan{Barcode: "3017620422003", Attributes: map[string]string{"site": "kitchen"}}
+	if err := processor.Process(scan); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := map[string]string{"site": "kitchen", "name": "Nutella", "category": "spreads"}
+	if !maps.Equal(scan.Attributes, expected) {
+		t.Errorf("Expected attributes %v, got %v", expected, scan.Attributes)
+	}
+
+	flour := &Scan{Barcode: "8410000000000"}
+	if err := processor.Process(flour); err != nil || !maps.Equal(flour.Attributes, map[string]string{"name": "Flour"}) {
+		t.Errorf("Expected empty cells to be skipped, got %v (%v)", flour.Attributes, err)
+	}
+
+	unknown := &Scan{Barcode: "4006381333931"}
+	if err := processor.Process(unknown); err != nil || unknown.Attributes != nil {
+		t.Errorf("Expected unknown barcodes to pass without attributes, got %v (%v)", unknown.Attributes, err)
+	}
+
+	// A changed file is picked up on the next check, a broken one ignored
+	lookup := processor.(*lookupProcessor)
+	writeFile("barcode,name\n4006381333931,Pencil\n")
+	lookup.checkedAt = time.Time{}
+	if err := processor.Process(unknown); err != nil || unknown.Attributes["name"] != "Pencil" {
+		t.Errorf("Expected the reloaded table to be used, got %v (%v)", unknown.Attributes, err)
+	}
+
+	var logs bytes.Buffer
+	lookup.logger.SetOutput(&logs)
+	writeFile("barcode,name\n\"unterminated\n")
+	for range 2 {
+		lookup.checkedAt = time.Time{}
+		pencil := &Scan{Barcode: "4006381333931"}
+		if err := processor.Process(pencil); err != nil || pencil.Attributes["name"] != "Pencil" {
+			t.Errorf("Expected a broken file to keep the previous table, got %v (%v)", pencil.Attributes, err)
+		}
+	}
+	if count := strings.Count(logs.String(), "Failed to reload lookup file"); count != 1 {
+		t.Errorf("Expected the broken file to be reported once, got %d reports: %s", count, logs.String())
+	}
+
+	writeFile("barcode,name\n4006381333931,Pen\n")
+	lookup.checkedAt = time.Time{}
+	pen := &Scan{Barcode: "4006381333931"}
+	if err := processor.Process(pen); err != nil || pen.Attributes["name"] != "Pen" || lookup.failed {
+		t.Errorf("Expected the fixed file to be loaded, got %v (%v)", pen.Attributes, err)
+	}
+
+	yamlPath := filepath.Join(t.TempDir(), "products.yaml")
+	if err := os.WriteFile(yamlPath, []byte("\"3017620422003\":\n  name: Nutella\n  brand: Ferrero\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write lookup file: %v", err)
+	}
+	processor, err = newLookupProcessor(&config.PipelineStageConfig{File: yamlPath}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	scan = &Scan{Barcode: "3017620422003"}
+	if err := processor.Process(scan); err != nil || !maps.Equal(scan.Attributes, map[string]string{"name": "Nutella", "brand": "Ferrero"}) {
+		t.Errorf("Expected YAML fields, got %v (%v)", scan.Attributes, err)
+	}
+
+	if _, err := newLookupProcessor(&config.PipelineStageConfig{File: filepath.Join(t.TempDir(), "missing.csv")}, logrus.New()); err == nil {
+		t.Error("Expected error for a missing lookup file")
+	}
+}
+
+func TestValueTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		barcode  string
+		expected string
+		dropped  bool
+		fails    bool
+	}{
+		{"Zero padded EAN", `{{ printf "%013s" .Value }}`, "12345678", "0000012345678", false, false},
+		{"Scanner and attributes", `{{ .ScannerID }}:{{ .Attributes.site }}:{{ .Value }}`, "42", "front:kitchen:42", false, false},
+		{"Conditional", `{{ if eq (len .Value) 12 }}0{{ end }}{{ .Value }}`, "012345678905", "0012345678905", false, false},
+		{"Empty result", `{{ if false }}{{ .Value }}{{ end }}`, "42", "", true, false},
+		{"Execution error", `{{ index .Value 10 }}`, "42", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := NewValueTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			scan := &Scan{ScannerID: "front", Barcode: tt.barcode, Attributes: map[string]string{"site": "kitchen"}}
+			err = processor.Process(scan)
+			switch {
+			case tt.dropped:
+				if !errors.Is(err, ErrDropped) {
+					t.Errorf("Expected scan to be dropped, got: %v", err)
+				}
+			case tt.fails:
+				if err == nil || errors.Is(err, ErrDropped) {
+					t.Errorf("Expected a render error, got: %v", err)
+				}
+			case err != nil:
+				t.Errorf("Expected no error, got: %v", err)
+			case scan.Barcode != tt.expected:
+				t.Errorf("Expected barcode %s, got %s", tt.expected, scan.Barcode)
+			}
+		})
+	}
+
+	if _, err := NewValueTemplate("{{ .Value"); err == nil {
+		t.Error("Expected error for an unterminated template")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("reverse", func(*config.PipelineStageConfig, *logrus.Logger) (Processor, error) {
+		return ProcessorFunc(func(scan *Scan) error {
+			runes := []rune(scan.Barcode)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			scan.Barcode = string(runes)
+			return nil
+		}), nil
+	})
+
+	stages, err := Build([]config.PipelineStageConfig{{Type: "reverse"}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected registered stage to build, got: %v", err)
+	}
+
+	scan := &Scan{Barcode: "abc"}
+	if err := New(stages...).Run(scan); err != nil || scan.Barcode != "cba" {
+		t.Errorf("Expected reversed barcode, got %q (%v)", scan.Barcode, err)
+	}
+
+	if _, err := Build([]config.PipelineStageConfig{{Type: "missing"}}, logrus.New()); err == nil {
+		t.Error("Expected error for unknown stage type")
+	}
+}
@@ -0,0 +1,368 @@
+package pipeline
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// ValidAttribute is set to "true" or "false" on scans checked by a validate
+// stage flagging invalid scans instead of dropping them.
+const ValidAttribute = "valid"
+
+type validateProcessor struct {
+	pattern    *regexp.Regexp
+	minLength  int
+	maxLength  int
+	checkDigit bool
+	flag       bool
+}
+
+func newValidateProcessor(stage *config.PipelineStageConfig, _ *logrus.Logger) (Processor, error) {
+	processor := &validateProcessor{
+		minLength:  stage.MinLength,
+		maxLength:  stage.MaxLength,
+		checkDigit: stage.CheckDigit,
+		flag:       stage.OnInvalid == config.InvalidActionFlag,
+	}
+	if stage.Pattern != "" {
+		pattern, err := regexp.Compile(stage.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		processor.pattern = pattern
+	}
+	return processor, nil
+}
+
+func (p *validateProcessor) Process(scan *Scan) error {
+	err := p.validate(scan.Barcode)
+	if !p.flag {
+		return err
+	}
+
+	if scan.Attributes == nil {
+		scan.Attributes = make(map[string]string)
+	}
+	// A scan flagged by an earlier stage stays invalid
+	if err != nil || scan.Attributes[ValidAttribute] == "" {
+		scan.Attributes[ValidAttribute] = strconv.FormatBool(err == nil)
+	}
+	return nil
+}
+
+func (p *validateProcessor) validate(barcode string) error {
+	if err := CheckLength(barcode, p.minLength, p.maxLength); err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrDropped, ErrRejected, err)
+	}
+	if p.pattern != nil && !p.pattern.MatchString(barcode) {
+		return fmt.Errorf("%w: %w: does not match %s", ErrDropped, ErrRejected, p.pattern)
+	}
+	if p.checkDigit && common.LooksLikeGTIN(barcode) && !common.IsValidGTIN(barcode) {
+		return fmt.Errorf("%w: %w: wrong GTIN check digit", ErrDropped, ErrRejected)
+	}
+	return nil
+}
+
+// CheckLength reports a barcode shorter than minLength or longer than a
+// non-zero maxLength, counted in characters.
+func CheckLength(barcode string, minLength, maxLength int) error {
+	length := utf8.RuneCountInString(barcode)
+	if length < minLength || (maxLength > 0 && length > maxLength) {
+		return fmt.Errorf("%d characters outside %d-%d", length, minLength, maxLength)
+	}
+	return nil
+}
+
+type transformProcessor struct {
+	trim            bool
+	trimPrefix      string
+	trimSuffix      string
+	substringStart  int
+	substringLength int
+	replacePattern  *regexp.Regexp
+	replaceWith     string
+	letterCase      string
+	template        *template.Template
+}
+
+func newTransformProcessor(stage *config.PipelineStageConfig, _ *logrus.Logger) (Processor, error) {
+	processor := &transformProcessor{
+		trim:            stage.Trim,
+		trimPrefix:      stage.TrimPrefix,
+		trimSuffix:      stage.TrimSuffix,
+		substringStart:  stage.SubstringStart,
+		substringLength: stage.SubstringLength,
+		replaceWith:     stage.ReplaceWith,
+		letterCase:      stage.Case,
+	}
+	if stage.ReplacePattern != "" {
+		pattern, err := regexp.Compile(stage.ReplacePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replace_pattern: %w", err)
+		}
+		processor.replacePattern = pattern
+	}
+	if stage.Template != "" {
+		tmpl, err := template.New("template").Parse(stage.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		processor.template = tmpl
+	}
+	return processor, nil
+}
+
+func (p *transformProcessor) Process(scan *Scan) error {
+	barcode := scan.Barcode
+	if p.trim {
+		barcode = strings.TrimSpace(barcode)
+	}
+	barcode = strings.TrimSuffix(strings.TrimPrefix(barcode, p.trimPrefix), p.trimSuffix)
+	barcode = substring(barcode, p.substringStart, p.substringLength)
+	if p.replacePattern != nil {
+		barcode = p.replacePattern.ReplaceAllString(barcode, p.replaceWith)
+	}
+
+	switch p.letterCase {
+	case config.TransformCaseUpper:
+		barcode = strings.ToUpper(barcode)
+	case config.TransformCaseLower:
+		barcode = strings.ToLower(barcode)
+	}
+
+	if p.template != nil {
+		rendered, err := renderTemplate(p.template, scan, barcode)
+		if err != nil {
+			return fmt.Errorf("failed to render template: %w", err)
+		}
+		barcode = rendered
+	}
+
+	if barcode == "" {
+		return fmt.Errorf("%w: empty after transform", ErrDropped)
+	}
+	scan.Barcode = barcode
+	return nil
+}
+
+// substring keeps length characters of s from start, or the rest when
+// length is 0. Bounds past the end are clamped.
+func substring(s string, start, length int) string {
+	if start == 0 && length == 0 {
+		return s
+	}
+
+	runes := []rune(s)
+	start = min(start, len(runes))
+	end := len(runes)
+	if length > 0 {
+		end = min(start+length, end)
+	}
+	return string(runes[start:end])
+}
+
+type dedupeProcessor struct {
+	window time.Duration
+
+	mutex    sync.Mutex
+	last     string
+	lastTime time.Time
+}
+
+func newDedupeProcessor(stage *config.PipelineStageConfig, _ *logrus.Logger) (Processor, error) {
+	return &dedupeProcessor{window: stage.Window}, nil
+}
+
+// Process drops a barcode repeated within the window of its previous scan.
+// Repeats extend the window, so holding the trigger never lets one through.
+func (p *dedupeProcessor) Process(scan *Scan) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	duplicate := scan.Barcode == p.last && scan.Timestamp.Sub(p.lastTime) < p.window
+	p.last = scan.Barcode
+	p.lastTime = scan.Timestamp
+
+	if duplicate {
+		return fmt.Errorf("%w: duplicate within %s", ErrDropped, p.window)
+	}
+	return nil
+}
+
+type rateLimitProcessor struct {
+	interval time.Duration
+	queue    bool
+	now      func() time.Time
+	sleep    func(time.Duration)
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+func newRateLimitProcessor(stage *config.PipelineStageConfig, _ *logrus.Logger) (Processor, error) {
+	return &rateLimitProcessor{
+		interval: time.Duration(float64(time.Second) / stage.MaxPerSecond),
+		queue:    stage.OnLimit == config.LimitActionQueue,
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}, nil
+}
+
+// Process spaces scans at least one interval apart. Scans arriving early
+// are dropped, or held back until their turn when queueing. Scanners with a
+// queueing stage run their pipeline on a Worker, whose queue_size bounds
+// the scans held back, so waiting doesn't block the scanner's reads.
+func (p *rateLimitProcessor) Process(scan *Scan) error {
+	p.mutex.Lock()
+	if !p.queue {
+		defer p.mutex.Unlock()
+		if p.next.After(scan.Timestamp) {
+			return fmt.Errorf("%w: over %s rate limit", ErrDropped, p.rate())
+		}
+		p.next = scan.Timestamp.Add(p.interval)
+		return nil
+	}
+
+	// Queued scans may have waited for the worker already, so their turn is
+	// measured from now rather than from when they were read
+	now := p.now()
+	slot := now
+	if p.next.After(slot) {
+		slot = p.next
+	}
+	p.next = slot.Add(p.interval)
+	p.mutex.Unlock()
+
+	if wait := slot.Sub(now); wait > 0 {
+		p.sleep(wait)
+	}
+	return nil
+}
+
+func (p *rateLimitProcessor) rate() string {
+	return strconv.FormatFloat(float64(time.Second)/float64(p.interval), 'g', 3, 64) + "/s"
+}
+
+type enrichProcessor struct {
+	attributes map[string]string
+}
+
+func newEnrichProcessor(stage *config.PipelineStageConfig, _ *logrus.Logger) (Processor, error) {
+	return &enrichProcessor{attributes: maps.Clone(stage.Attributes)}, nil
+}
+
+func (p *enrichProcessor) Process(scan *Scan) error {
+	if scan.Attributes == nil {
+		scan.Attributes = make(map[string]string, len(p.attributes))
+	}
+	maps.Copy(scan.Attributes, p.attributes)
+	return nil
+}
+
+type aimProcessor struct{}
+
+func newAIMProcessor(*config.PipelineStageConfig, *logrus.Logger) (Processor, error) {
+	return aimProcessor{}, nil
+}
+
+// Process strips the AIM identifier and adds the symbology and identifier
+// attributes. Scans without one get the symbology detected from the
+// barcode, so routing can rely on the attribute.
+func (aimProcessor) Process(scan *Scan) error {
+	if scan.Attributes == nil {
+		scan.Attributes = make(map[string]string)
+	}
+
+	identifier, symbology, data, ok := common.ParseAIMIdentifier(scan.Barcode)
+	if !ok {
+		scan.Attributes[common.SymbologyAttribute] = common.DetectSymbology(scan.Barcode)
+		return nil
+	}
+	if data == "" {
+		return fmt.Errorf("%w: empty after the AIM identifier", ErrDropped)
+	}
+
+	scan.Barcode = data
+	scan.Attributes[common.SymbologyAttribute] = symbology
+	scan.Attributes[common.AIMIdentifierAttribute] = identifier
+	return nil
+}
+
+type parseProcessor struct{}
+
+func newParseProcessor(*config.PipelineStageConfig, *logrus.Logger) (Processor, error) {
+	return parseProcessor{}, nil
+}
+
+// Process adds the format and fields of a structured payload to the scan
+// attributes. Other barcodes pass unchanged.
+func (parseProcessor) Process(scan *Scan) error {
+	format, fields, ok := common.ParsePayload(scan.Barcode)
+	if !ok {
+		return nil
+	}
+
+	if scan.Attributes == nil {
+		scan.Attributes = make(map[string]string, len(fields)+1)
+	}
+	maps.Copy(scan.Attributes, fields)
+	scan.Attributes[common.PayloadFormatAttribute] = format
+	return nil
+}
+
+// ValueTemplateData is the data a scanner's value_template renders.
+type ValueTemplateData struct {
+	Value      string
+	ScannerID  string
+	Timestamp  time.Time
+	Attributes map[string]string
+}
+
+type valueTemplateProcessor struct {
+	template *template.Template
+}
+
+// NewValueTemplate creates the processor rendering a scanner's
+// value_template into the barcode. It runs after the configured stages, so
+// the template sees their result.
+func NewValueTemplate(text string) (Processor, error) {
+	tmpl, err := template.New("value_template").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value_template: %w", err)
+	}
+	return &valueTemplateProcessor{template: tmpl}, nil
+}
+
+func (p *valueTemplateProcessor) Process(scan *Scan) error {
+	value, err := renderTemplate(p.template, scan, scan.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to render value_template: %w", err)
+	}
+
+	if value == "" {
+		return fmt.Errorf("%w: empty after value_template", ErrDropped)
+	}
+	scan.Barcode = value
+	return nil
+}
+
+func renderTemplate(tmpl *template.Template, scan *Scan, value string) (string, error) {
+	var rendered strings.Builder
+	data := ValueTemplateData{Value: value, ScannerID: scan.ScannerID, Timestamp: scan.Timestamp, Attributes: scan.Attributes}
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,149 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/common"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// Attributes set by openfoodfacts stages. ProductFoundAttribute is "true" or
+// "false" once a lookup succeeded, and missing when it failed.
+const (
+	ProductFoundAttribute    = "product_found"
+	ProductNameAttribute     = "product_name"
+	ProductBrandAttribute    = "product_brand"
+	ProductImageURLAttribute = "product_image_url"
+)
+
+// maxProductCacheEntries bounds the lookup cache of a stage.
+const maxProductCacheEntries = 1000
+
+// productRetryDelay is how long a stage skips lookups after one failed, so
+// scans aren't held back by the lookup timeout while the database is
+// unreachable.
+const productRetryDelay = time.Minute
+
+// errProductLookupSkipped is returned by lookups within productRetryDelay of
+// a failed one.
+var errProductLookupSkipped = errors.New("product lookup skipped after a recent failure")
+
+type productCacheEntry struct {
+	product *common.Product // nil for products the database doesn't know
+	expires time.Time
+}
+
+type productProcessor struct {
+	url      string
+	client   *http.Client
+	cacheTTL time.Duration
+	logger   *logrus.Logger
+
+	mutex      sync.Mutex
+	cache      map[string]productCacheEntry
+	retryAfter time.Time // Lookups are skipped until then after a failure
+}
+
+func newProductProcessor(stage *config.PipelineStageConfig, logger *logrus.Logger) (Processor, error) {
+	processor := &productProcessor{
+		url:      stage.URL,
+		client:   &http.Client{Timeout: stage.Timeout},
+		cacheTTL: stage.CacheTTL,
+		logger:   logger,
+		cache:    make(map[string]productCacheEntry),
+	}
+	if processor.url == "" {
+		processor.url = common.OpenFoodFactsURL
+	}
+	if processor.client.Timeout == 0 {
+		processor.client.Timeout = config.DefaultProductLookupTimeout
+	}
+	if processor.cacheTTL == 0 {
+		processor.cacheTTL = config.DefaultProductCacheTTL
+	}
+	return processor, nil
+}
+
+// Process adds the product information of GTIN barcodes. A failed lookup
+// doesn't hold the scan back, the scan is published without product
+// attributes and lookups of uncached barcodes are skipped for
+// productRetryDelay.
+func (p *productProcessor) Process(scan *Scan) error {
+	if !common.IsValidGTIN(scan.Barcode) {
+		return nil
+	}
+
+	product, err := p.lookup(scan.Barcode)
+	if errors.Is(err, errProductLookupSkipped) {
+		p.logger.WithField("barcode", scan.Barcode).Debug("Skipping product lookup after a recent failure")
+		return nil
+	}
+	if err != nil {
+		p.logger.WithError(err).WithField("barcode", scan.Barcode).Warnf("Product lookup failed, retrying in %s", productRetryDelay)
+		return nil
+	}
+
+	if scan.Attributes == nil {
+		scan.Attributes = make(map[string]string)
+	}
+	scan.Attributes[ProductFoundAttribute] = strconv.FormatBool(product != nil)
+	if product == nil {
+		return nil
+	}
+	for key, value := range map[string]string{
+		ProductNameAttribute:     product.Name,
+		ProductBrandAttribute:    product.Brand,
+		ProductImageURLAttribute: product.ImageURL,
+	} {
+		if value != "" {
+			scan.Attributes[key] = value
+		}
+	}
+	return nil
+}
+
+func (p *productProcessor) lookup(barcode string) (*common.Product, error) {
+	now := time.Now()
+	p.mutex.Lock()
+	entry, cached := p.cache[barcode]
+	retryAfter := p.retryAfter
+	p.mutex.Unlock()
+	if cached && now.Before(entry.expires) {
+		return entry.product, nil
+	}
+	if now.Before(retryAfter) {
+		return nil, errProductLookupSkipped
+	}
+
+	product, err := common.FetchProduct(context.Background(), p.client, p.url, barcode)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if err != nil {
+		p.retryAfter = time.Now().Add(productRetryDelay)
+		return nil, err
+	}
+	if len(p.cache) >= maxProductCacheEntries {
+		p.evictExpired(now)
+	}
+	if len(p.cache) >= maxProductCacheEntries {
+		clear(p.cache)
+	}
+	p.cache[barcode] = productCacheEntry{product: product, expires: now.Add(p.cacheTTL)}
+	return product, nil
+}
+
+func (p *productProcessor) evictExpired(now time.Time) {
+	for barcode, entry := range p.cache {
+		if !now.Before(entry.expires) {
+			delete(p.cache, barcode)
+		}
+	}
+}
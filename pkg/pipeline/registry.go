@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// Factory creates a processor from its stage configuration. Every scanner
+// gets its own instances, so processors may keep per-scanner state. Failures
+// that don't reject the scan are reported on the logger.
+type Factory func(stage *config.PipelineStageConfig, logger *logrus.Logger) (Processor, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{
+		config.StageValidate:  newValidateProcessor,
+		config.StageTransform: newTransformProcessor,
+		config.StageDedupe:    newDedupeProcessor,
+		config.StageEnrich:    newEnrichProcessor,
+		config.StageAIM:       newAIMProcessor,
+		config.StageRateLimit: newRateLimitProcessor,
+		config.StageParse:     newParseProcessor,
+		config.StageProduct:   newProductProcessor,
+		config.StageLookup:    newLookupProcessor,
+	}
+)
+
+// Register adds or replaces the factory for a stage type.
+func Register(stageType string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+	factories[stageType] = factory
+}
+
+// Build creates the processors for the configured stages.
+func Build(stages []config.PipelineStageConfig, logger *logrus.Logger) ([]Stage, error) {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	built := make([]Stage, 0, len(stages))
+	for i := range stages {
+		factory, exists := factories[stages[i].Type]
+		if !exists {
+			return nil, fmt.Errorf("unknown pipeline stage type '%s'", stages[i].Type)
+		}
+
+		processor, err := factory(&stages[i], logger)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline stage %d (%s): %w", i, stages[i].Type, err)
+		}
+		built = append(built, Stage{Name: stages[i].Type, Processor: processor})
+	}
+	return built, nil
+}
@@ -0,0 +1,50 @@
+package pipeline
+
+import "sync"
+
+// Worker runs the scans pushed to it one at a time on a goroutine of its
+// own, so a stage holding scans back, like a queueing rate_limit, doesn't
+// block the scanner's read loop. Up to size scans wait for their turn.
+type Worker struct {
+	mutex  sync.Mutex
+	queue  chan *Scan
+	closed bool
+}
+
+func NewWorker(size int, handle func(scan *Scan)) *Worker {
+	worker := &Worker{queue: make(chan *Scan, size)}
+	go func() {
+		for scan := range worker.queue {
+			handle(scan)
+		}
+	}()
+	return worker
+}
+
+// Push queues a scan. It returns false when the queue is full or the worker
+// is closed.
+func (w *Worker) Push(scan *Scan) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return false
+	}
+	select {
+	case w.queue <- scan:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops accepting scans. Those already queued are still handled.
+func (w *Worker) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+}
@@ -0,0 +1,269 @@
+// Package queue persists scans on disk until they are delivered.
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrFull is returned by Push when the queue holds its maximum number of
+// entries.
+var ErrFull = errors.New("queue full")
+
+const (
+	opAdd = "add"
+	opAck = "ack"
+)
+
+// Entry is a queued scan. Seq increases with every pushed entry and
+// identifies it when acknowledging.
+type Entry struct {
+	Seq           uint64            `json:"seq"`
+	ScannerID     string            `json:"scanner_id,omitempty"`
+	Barcode       string            `json:"barcode,omitempty"`
+	ScanID        string            `json:"scan_id,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"` // Set on scans answering an expect_scan request
+	Timestamp     time.Time         `json:"timestamp,omitzero"`
+	Attributes    map[string]string `json:"attributes,omitempty"` // Added by pipeline stages
+}
+
+// record is a line of the queue file: an added entry, or the
+// acknowledgement of the entry with that sequence number.
+type record struct {
+	Op string `json:"op"`
+	Entry
+}
+
+// FileQueue is a FIFO of scans kept in an append-only file of JSON lines.
+// Every push and acknowledgement is synced to disk before it returns, so
+// the queue survives a crash or power loss, and the file is rewritten with
+// only the pending entries when opened and truncated once drained.
+type FileQueue struct {
+	path       string
+	maxEntries int
+
+	mutex   sync.Mutex
+	file    *os.File
+	pending []Entry
+	nextSeq uint64
+}
+
+// Open loads the pending entries of a queue file, creating it if needed.
+// maxEntries limits the pending entries, zero means no limit.
+func Open(path string, maxEntries int) (*FileQueue, error) {
+	q := &FileQueue{path: path, maxEntries: maxEntries, nextSeq: 1}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	if err := q.rewrite(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// load replays the queue file. A crash while appending can leave a partial
+// last line, which is skipped.
+func (q *FileQueue) load() error {
+	file, err := os.Open(q.path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open queue %s: %w", q.path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read queue %s: %w", q.path, err)
+	}
+
+	for i, line := range lines {
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("queue %s line %d is corrupt: %w", q.path, i+1, err)
+		}
+		q.replay(&rec)
+	}
+	return nil
+}
+
+func (q *FileQueue) replay(rec *record) {
+	switch rec.Op {
+	case opAdd:
+		q.pending = append(q.pending, rec.Entry)
+		q.nextSeq = max(q.nextSeq, rec.Seq+1)
+	case opAck:
+		for i := range q.pending {
+			if q.pending[i].Seq == rec.Seq {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// rewrite replaces the queue file with one holding only the pending
+// entries and keeps it open for appending.
+func (q *FileQueue) rewrite() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o750); err != nil {
+		return fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), "."+filepath.Base(q.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to rewrite queue %s: %w", q.path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	writer := bufio.NewWriter(tmp)
+	for i := range q.pending {
+		if err := writeRecord(writer, &record{Op: opAdd, Entry: q.pending[i]}); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("failed to rewrite queue %s: %w", q.path, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to rewrite queue %s: %w", q.path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to rewrite queue %s: %w", q.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite queue %s: %w", q.path, err)
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("failed to replace queue %s: %w", q.path, err)
+	}
+
+	file, err := os.OpenFile(q.path, os.O_APPEND|os.O_WRONLY, 0o600) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to open queue %s: %w", q.path, err)
+	}
+	q.file = file
+	return nil
+}
+
+// Push appends a scan to the queue and returns it with its sequence number.
+func (q *FileQueue) Push(entry Entry) (Entry, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.maxEntries > 0 && len(q.pending) >= q.maxEntries {
+		return Entry{}, ErrFull
+	}
+
+	entry.Seq = q.nextSeq
+	if err := q.append(&record{Op: opAdd, Entry: entry}); err != nil {
+		return Entry{}, err
+	}
+	q.nextSeq++
+	q.pending = append(q.pending, entry)
+	return entry, nil
+}
+
+// Peek returns the oldest pending entry.
+func (q *FileQueue) Peek() (Entry, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.pending) == 0 {
+		return Entry{}, false
+	}
+	return q.pending[0], true
+}
+
+// Ack removes a delivered entry. The file is truncated once no entries are
+// pending, so it doesn't grow across outages.
+func (q *FileQueue) Ack(seq uint64) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	index := -1
+	for i := range q.pending {
+		if q.pending[i].Seq == seq {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil
+	}
+
+	if len(q.pending) == 1 {
+		if err := q.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate queue %s: %w", q.path, err)
+		}
+		if err := q.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync queue %s: %w", q.path, err)
+		}
+	} else if err := q.append(&record{Op: opAck, Entry: Entry{Seq: seq}}); err != nil {
+		return err
+	}
+	q.pending = append(q.pending[:index], q.pending[index+1:]...)
+	return nil
+}
+
+// Len returns the number of pending entries.
+func (q *FileQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.pending)
+}
+
+// Close closes the queue file. Pending entries are loaded again by Open.
+func (q *FileQueue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.file == nil {
+		return nil
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+func (q *FileQueue) append(rec *record) error {
+	if q.file == nil {
+		return fmt.Errorf("queue %s is closed", q.path)
+	}
+	writer := bufio.NewWriter(q.file)
+	if err := writeRecord(writer, rec); err != nil {
+		return fmt.Errorf("failed to write queue %s: %w", q.path, err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write queue %s: %w", q.path, err)
+	}
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync queue %s: %w", q.path, err)
+	}
+	return nil
+}
+
+func writeRecord(writer *bufio.Writer, rec *record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
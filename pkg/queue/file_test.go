@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestQueue(t *testing.T, path string, maxEntries int) *FileQueue {
+	t.Helper()
+
+	q, err := Open(path, maxEntries)
+	if err != nil {
+		t.Fatalf("Failed to open queue: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+	return q
+}
+
+func TestFileQueue_PushPeekAck(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "queue.jsonl"), 0)
+
+	if _, exists := q.Peek(); exists {
+		t.Error("Expected empty queue")
+	}
+
+	first, err := q.Push(Entry{ScannerID: "front", Barcode: "123", Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if _, err := q.Push(Entry{ScannerID: "back", Barcode: "456", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+
+	entry, exists := q.Peek()
+	if !exists || entry.Seq != first.Seq || entry.Barcode != "123" {
+		t.Errorf("Expected first entry, got %+v", entry)
+	}
+
+	if err := q.Ack(first.Seq); err != nil {
+		t.Fatalf("Failed to ack: %v", err)
+	}
+	entry, exists = q.Peek()
+	if !exists || entry.ScannerID != "back" || entry.Barcode != "456" {
+		t.Errorf("Expected second entry, got %+v", entry)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Expected 1 pending entry, got %d", q.Len())
+	}
+}
+
+func TestFileQueue_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	q, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Failed to open queue: %v", err)
+	}
+	first, _ := q.Push(Entry{ScannerID: "front", Barcode: "123", Timestamp: timestamp})
+	second, _ := q.Push(Entry{ScannerID: "front", Barcode: "456", ScanID: "scan-2", Timestamp: timestamp})
+	if err := q.Ack(first.Seq); err != nil {
+		t.Fatalf("Failed to ack: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	q = openTestQueue(t, path, 0)
+	entry, exists := q.Peek()
+	if !exists || entry.Seq != second.Seq || entry.Barcode != "456" || entry.ScanID != "scan-2" || !entry.Timestamp.Equal(timestamp) {
+		t.Errorf("Expected second entry after reopening, got %+v", entry)
+	}
+
+	third, err := q.Push(Entry{ScannerID: "front", Barcode: "789", Timestamp: timestamp})
+	if err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if third.Seq <= second.Seq {
+		t.Errorf("Expected sequence after %d, got %d", second.Seq, third.Seq)
+	}
+}
+
+func TestFileQueue_PartialLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	content := `{"op":"add","seq":1,"scanner_id":"front","barcode":"123"}` + "\n" + `{"op":"add","seq":2,"scan`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write queue: %v", err)
+	}
+
+	q := openTestQueue(t, path, 0)
+	if q.Len() != 1 {
+		t.Errorf("Expected 1 pending entry, got %d", q.Len())
+	}
+}
+
+func TestFileQueue_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	content := "not json\n" + `{"op":"add","seq":1,"scanner_id":"front","barcode":"123"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write queue: %v", err)
+	}
+
+	if _, err := Open(path, 0); err == nil {
+		t.Error("Expected error for corrupt queue")
+	}
+}
+
+func TestFileQueue_Full(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "queue.jsonl"), 1)
+
+	if _, err := q.Push(Entry{ScannerID: "front", Barcode: "123", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if _, err := q.Push(Entry{ScannerID: "front", Barcode: "456", Timestamp: time.Now()}); !errors.Is(err, ErrFull) {
+		t.Errorf("Expected ErrFull, got %v", err)
+	}
+}
+
+func TestFileQueue_TruncatedWhenDrained(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	q := openTestQueue(t, path, 0)
+
+	first, _ := q.Push(Entry{ScannerID: "front", Barcode: "123", Timestamp: time.Now()})
+	second, _ := q.Push(Entry{ScannerID: "front", Barcode: "456", Timestamp: time.Now()})
+	for _, seq := range []uint64{first.Seq, second.Seq} {
+		if err := q.Ack(seq); err != nil {
+			t.Fatalf("Failed to ack: %v", err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat queue: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected empty queue file, got %d bytes", info.Size())
+	}
+
+	if _, err := q.Push(Entry{ScannerID: "front", Barcode: "789", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("Failed to push after truncating: %v", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Expected 1 pending entry, got %d", q.Len())
+	}
+}
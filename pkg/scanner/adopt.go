@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// ErrNoScanDetected is returned by ListenForScan when none of the devices
+// produced a scan before the context ended.
+var ErrNoScanDetected = errors.New("no scan received")
+
+// DetectedScan is the first scan ListenForScan received, with the device
+// that produced it and the termination character it sent.
+type DetectedScan struct {
+	Device          hid.DeviceInfo
+	Barcode         string
+	TerminationChar string
+}
+
+// UnclaimedDevices returns the HID devices that none of the configured
+// scanners would open, i.e. the candidates for a new scanner.
+func UnclaimedDevices(configs map[string]config.ScannerConfig, devices []hid.DeviceInfo) []hid.DeviceInfo {
+	var unclaimed []hid.DeviceInfo
+	for i := range devices {
+		claimed := false
+		for id := range configs {
+			scannerConfig := configs[id]
+			if claimsDevice(&scannerConfig, &devices[i]) {
+				claimed = true
+				break
+			}
+		}
+		if !claimed {
+			unclaimed = append(unclaimed, devices[i])
+		}
+	}
+	return unclaimed
+}
+
+// ListenForScan opens the devices without a termination character and
+// returns the first scan any of them produces, detecting its termination
+// character from the raw keyboard reports. All devices are closed again
+// before it returns.
+func ListenForScan(ctx context.Context, devices []hid.DeviceInfo, logger *logrus.Logger) (*DetectedScan, error) {
+	detected := make(chan *DetectedScan, 1)
+	scanners := make([]*BarcodeScanner, 0, len(devices))
+	defer func() {
+		for _, barcodeScanner := range scanners {
+			_ = barcodeScanner.Stop()
+		}
+	}()
+
+	for i := range devices {
+		device := devices[i]
+		iface := device.Interface
+		barcodeScanner := NewBarcodeScannerWithInterface(
+			device.VendorID, device.ProductID, device.Serial, &iface, "none", "us", logger)
+
+		var mutex sync.Mutex
+		var reports [][]byte
+		barcodeScanner.SetOnRawReportCallback(func(data []byte) {
+			mutex.Lock()
+			reports = append(reports, data)
+			mutex.Unlock()
+		})
+		barcodeScanner.SetOnScanCallback(func(barcode string) {
+			mutex.Lock()
+			scan := &DetectedScan{Device: device, Barcode: barcode, TerminationChar: DetectTerminationChar(reports)}
+			mutex.Unlock()
+
+			select {
+			case detected <- scan:
+			default:
+			}
+		})
+
+		if err := barcodeScanner.Start(); err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", DeviceDisplayName(&device), err)
+		}
+		scanners = append(scanners, barcodeScanner)
+	}
+
+	select {
+	case scan := <-detected:
+		return scan, nil
+	case <-ctx.Done():
+		return nil, ErrNoScanDetected
+	}
+}
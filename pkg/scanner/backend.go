@@ -0,0 +1,38 @@
+package scanner
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/karalabe/hid"
+)
+
+const hidModulePath = "github.com/karalabe/hid"
+
+// HIDBackendInfo returns the hidapi backend in use, the version of the Go
+// binding and whether HID access is compiled in at all.
+func HIDBackendInfo() map[string]any {
+	backend := "hidapi/libusb"
+	switch runtime.GOOS {
+	case "windows":
+		backend = "hidapi/windows"
+	case "darwin":
+		backend = "hidapi/mac"
+	}
+
+	version := "unknown"
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range buildInfo.Deps {
+			if dep.Path == hidModulePath {
+				version = dep.Version
+				break
+			}
+		}
+	}
+
+	return map[string]any{
+		"hid_backend":         backend,
+		"hid_library_version": version,
+		"hid_supported":       hid.Supported(),
+	}
+}
@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+)
+
+// baseScanner holds the callback and connection bookkeeping shared by the
+// drivers that are not backed by hidapi.
+type baseScanner struct {
+	logger         *logrus.Logger
+	reconnectDelay time.Duration
+	connected      int32
+	deviceInfo     *hid.DeviceInfo
+	openFailed     atomic.Bool // An open failure was reported since the last connect
+
+	onScan             func(string)
+	onConnectionChange func(bool)
+	onError            func(string, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.RWMutex
+}
+
+func newBaseScanner(logger *logrus.Logger) baseScanner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return baseScanner{
+		logger:         logger,
+		reconnectDelay: time.Second,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func (b *baseScanner) SetOnScanCallback(callback func(string)) {
+	b.mutex.Lock()
+	b.onScan = callback
+	b.mutex.Unlock()
+}
+
+func (b *baseScanner) SetOnConnectionChangeCallback(callback func(bool)) {
+	b.mutex.Lock()
+	b.onConnectionChange = callback
+	b.mutex.Unlock()
+}
+
+func (b *baseScanner) SetOnErrorCallback(callback func(string, error)) {
+	b.mutex.Lock()
+	b.onError = callback
+	b.mutex.Unlock()
+}
+
+func (b *baseScanner) SetReconnectDelay(delay time.Duration) {
+	b.reconnectDelay = delay
+}
+
+func (b *baseScanner) IsConnected() bool {
+	return atomic.LoadInt32(&b.connected) == 1
+}
+
+func (b *baseScanner) GetConnectedDeviceInfo() *hid.DeviceInfo {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.deviceInfo
+}
+
+func (b *baseScanner) emitScan(barcode string) {
+	b.mutex.RLock()
+	callback := b.onScan
+	b.mutex.RUnlock()
+
+	if callback != nil {
+		callback(barcode)
+	}
+}
+
+func (b *baseScanner) reportError(category string, err error) {
+	b.mutex.RLock()
+	callback := b.onError
+	b.mutex.RUnlock()
+
+	if callback != nil && category != "" {
+		callback(category, err)
+	}
+}
+
+// reportOpenFailure reports the first failure to open the device per
+// disconnected period, not every reconnect attempt.
+func (b *baseScanner) reportOpenFailure(err error) {
+	if !b.openFailed.Swap(true) {
+		b.reportError(ErrorCategoryOpenFailure, err)
+	}
+}
+
+func (b *baseScanner) setConnected(deviceInfo *hid.DeviceInfo) {
+	b.openFailed.Store(false)
+	b.mutex.Lock()
+	b.deviceInfo = deviceInfo
+	callback := b.onConnectionChange
+	b.mutex.Unlock()
+
+	atomic.StoreInt32(&b.connected, 1)
+	if callback != nil {
+		callback(true)
+	}
+}
+
+func (b *baseScanner) setDisconnected() {
+	if atomic.SwapInt32(&b.connected, 0) == 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	b.deviceInfo = nil
+	callback := b.onConnectionChange
+	b.mutex.Unlock()
+
+	if callback != nil {
+		callback(false)
+	}
+}
+
+// runConnectionLoop calls session until the scanner is stopped, waiting
+// reconnectDelay between attempts. A session blocks while the device is in use.
+func (b *baseScanner) runConnectionLoop(session func() error) {
+	for {
+		if err := session(); err != nil && b.ctx.Err() == nil {
+			b.logger.Debugf("Scanner session ended: %v", err)
+		}
+		b.setDisconnected()
+
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(b.reconnectDelay):
+		}
+	}
+}
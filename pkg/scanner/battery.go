@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultBatteryPollInterval = 60 * time.Second
+
+const (
+	DockDetectionCharging  = "charging"
+	DockDetectionInterface = "interface"
+)
+
+type PowerSupplyInfo struct {
+	Capacity int
+	Status   string // Charging, Discharging, Full, Not charging, Unknown
+}
+
+// IsCharging reports whether the battery is being fed from a cradle or cable.
+func (p *PowerSupplyInfo) IsCharging() bool {
+	return p.Status == "Charging" || p.Status == "Full"
+}
+
+// parseHIDUevent extracts the bus-independent identification of a HID device
+// from the contents of its sysfs uevent file, e.g. HID_ID=0003:0000060E:000016C7.
+func parseHIDUevent(data string) (vendorID, productID uint16, uniq string, ok bool) {
+	for line := range strings.SplitSeq(data, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "HID_ID":
+			parts := strings.Split(value, ":")
+			if len(parts) != 3 {
+				return 0, 0, "", false
+			}
+			vid, err := strconv.ParseUint(parts[1], 16, 32)
+			if err != nil {
+				return 0, 0, "", false
+			}
+			pid, err := strconv.ParseUint(parts[2], 16, 32)
+			if err != nil {
+				return 0, 0, "", false
+			}
+			vendorID, productID, ok = uint16(vid), uint16(pid), true // #nosec G115 - HID IDs are 16 bit
+		case "HID_UNIQ":
+			uniq = value
+		}
+	}
+	return vendorID, productID, uniq, ok
+}
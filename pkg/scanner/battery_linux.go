@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const powerSupplySysfsPath = "/sys/class/power_supply"
+
+// ReadPowerSupply returns the battery state the kernel HID driver reports for
+// the given device through the power_supply class, if any.
+func ReadPowerSupply(vendorID, productID uint16, serial string) (*PowerSupplyInfo, error) {
+	entries, err := os.ReadDir(powerSupplySysfsPath)
+	if err != nil {
+		return nil, ErrBatteryUnavailable
+	}
+
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "hid-") {
+			continue
+		}
+
+		supplyPath := filepath.Join(powerSupplySysfsPath, entry.Name())
+		uevent, err := os.ReadFile(filepath.Join(supplyPath, "device", "uevent")) // #nosec G304
+		if err != nil {
+			continue
+		}
+
+		vid, pid, uniq, ok := parseHIDUevent(string(uevent))
+		if !ok || vid != vendorID || pid != productID {
+			continue
+		}
+		if serial != "" && uniq != "" && !strings.EqualFold(uniq, serial) {
+			continue
+		}
+
+		capacity, err := os.ReadFile(filepath.Join(supplyPath, "capacity")) // #nosec G304
+		if err != nil {
+			continue
+		}
+
+		level, err := strconv.Atoi(strings.TrimSpace(string(capacity)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid battery capacity in %s: %w", supplyPath, err)
+		}
+
+		info := &PowerSupplyInfo{Capacity: level}
+		if status, err := os.ReadFile(filepath.Join(supplyPath, "status")); err == nil { // #nosec G304
+			info.Status = strings.TrimSpace(string(status))
+		}
+		return info, nil
+	}
+
+	return nil, ErrBatteryUnavailable
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package scanner
+
+// ReadPowerSupply is only implemented on Linux, where the kernel exposes HID
+// battery reports through the power_supply class.
+func ReadPowerSupply(vendorID, productID uint16, serial string) (*PowerSupplyInfo, error) {
+	return nil, ErrBatteryUnavailable
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"testing"
+)
+
+func TestParseHIDUevent(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		expectOK   bool
+		expectVID  uint16
+		expectPID  uint16
+		expectUniq string
+	}{
+		{
+			name:       "USB device with serial",
+			data:       "DRIVER=hid-generic\nHID_ID=0003:0000060E:000016C7\nHID_NAME=Scanner\nHID_UNIQ=ABC123\n",
+			expectOK:   true,
+			expectVID:  0x060e,
+			expectPID:  0x16c7,
+			expectUniq: "ABC123",
+		},
+		{
+			name:      "Bluetooth device without uniq",
+			data:      "HID_ID=0005:00000A12:00000001\n",
+			expectOK:  true,
+			expectVID: 0x0a12,
+			expectPID: 0x0001,
+		},
+		{"Missing HID_ID", "DRIVER=hid-generic\nHID_UNIQ=ABC123\n", false, 0, 0, "ABC123"},
+		{"Malformed HID_ID", "HID_ID=0003:zz\n", false, 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vid, pid, uniq, ok := parseHIDUevent(tt.data)
+
+			if ok != tt.expectOK {
+				t.Fatalf("Expected ok=%v, got %v", tt.expectOK, ok)
+			}
+			if vid != tt.expectVID || pid != tt.expectPID {
+				t.Errorf("Expected %04x:%04x, got %04x:%04x", tt.expectVID, tt.expectPID, vid, pid)
+			}
+			if uniq != tt.expectUniq {
+				t.Errorf("Expected uniq '%s', got '%s'", tt.expectUniq, uniq)
+			}
+		})
+	}
+}
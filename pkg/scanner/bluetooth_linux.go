@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const bluezStoragePath = "/var/lib/bluetooth"
+
+// BluetoothScanner reads a BLE (HID-over-GATT) scanner through the input
+// device BlueZ creates while the scanner is connected. Pairing itself is left
+// to the system Bluetooth stack; reconnects follow the evdev connection loop.
+type BluetoothScanner struct {
+	*EvdevScanner
+	address        string
+	onBatteryLevel func(int)
+	batteryLevel   int
+}
+
+func NewBluetoothScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	evdev, err := NewEvdevScanner(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BluetoothScanner{
+		EvdevScanner: evdev.(*EvdevScanner),
+		address:      strings.ToUpper(cfg.Bluetooth.Address),
+		batteryLevel: -1,
+	}
+	s.matchDevice = func(info *hid.DeviceInfo) bool {
+		return strings.EqualFold(info.Serial, s.address)
+	}
+	return s, nil
+}
+
+func (s *BluetoothScanner) SetOnBatteryLevelCallback(callback func(int)) {
+	s.mutex.Lock()
+	s.onBatteryLevel = callback
+	s.mutex.Unlock()
+}
+
+func (s *BluetoothScanner) Start() error {
+	if paired, err := isBluetoothPaired(s.address); err == nil && !paired {
+		s.logger.Warnf("Bluetooth scanner %s is not paired - pair and trust it with bluetoothctl", s.address)
+	}
+
+	go s.pollBattery()
+	return s.EvdevScanner.Start()
+}
+
+func (s *BluetoothScanner) TryInitialConnect() error {
+	err := s.EvdevScanner.TryInitialConnect()
+	if err == nil {
+		return nil
+	}
+
+	if paired, pairErr := isBluetoothPaired(s.address); pairErr == nil && !paired {
+		return fmt.Errorf("bluetooth scanner %s is not paired: %w", s.address, err)
+	}
+	return fmt.Errorf("bluetooth scanner %s is not connected: %w", s.address, err)
+}
+
+// pollBattery reports the level from the power_supply entry the kernel creates
+// for HID-over-GATT devices exposing the battery service.
+func (s *BluetoothScanner) pollBattery() {
+	ticker := time.NewTicker(DefaultBatteryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if info := s.GetConnectedDeviceInfo(); info != nil {
+			if supply, err := ReadPowerSupply(info.VendorID, info.ProductID, s.address); err == nil {
+				s.updateBatteryLevel(supply.Capacity)
+			}
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BluetoothScanner) updateBatteryLevel(level int) {
+	s.mutex.Lock()
+	changed := s.batteryLevel != level
+	s.batteryLevel = level
+	callback := s.onBatteryLevel
+	s.mutex.Unlock()
+
+	if changed && callback != nil {
+		callback(level)
+	}
+}
+
+// isBluetoothPaired checks the BlueZ storage for a pairing with the address on
+// any adapter. Reading it requires root, so callers ignore errors.
+func isBluetoothPaired(address string) (bool, error) {
+	adapters, err := os.ReadDir(bluezStoragePath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, adapter := range adapters {
+		info := filepath.Join(bluezStoragePath, adapter.Name(), strings.ToUpper(address), "info")
+		if _, err := os.Stat(info); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
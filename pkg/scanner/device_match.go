@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// deviceMatch is one way of identifying a scanner's HID device. Zero vendor
+// or product IDs act as wildcards.
+type deviceMatch struct {
+	vendorID       uint16
+	productID      uint16
+	serial         string
+	iface          *int
+	productPattern *regexp.Regexp
+}
+
+func newDeviceMatch(identification *config.ScannerIdentification) deviceMatch {
+	return deviceMatch{
+		vendorID:       identification.VendorID,
+		productID:      identification.ProductID,
+		serial:         identification.Serial,
+		iface:          identification.Interface,
+		productPattern: identification.ProductPattern(),
+	}
+}
+
+func (m *deviceMatch) matches(deviceInfo *hid.DeviceInfo) bool {
+	if (m.vendorID != 0 && deviceInfo.VendorID != m.vendorID) || (m.productID != 0 && deviceInfo.ProductID != m.productID) {
+		return false
+	}
+
+	if m.productPattern != nil && !m.productPattern.MatchString(deviceInfo.Product) {
+		return false
+	}
+
+	if m.serial != "" && deviceInfo.Serial != m.serial {
+		return false
+	}
+
+	if m.iface != nil && deviceInfo.Interface != *m.iface {
+		return false
+	}
+
+	return true
+}
+
+// String formats the match for error messages, showing wildcards as "*".
+func (m *deviceMatch) String() string {
+	vendor, product := "*", "*"
+	if m.vendorID != 0 {
+		vendor = fmt.Sprintf("%04x", m.vendorID)
+	}
+	if m.productID != 0 {
+		product = fmt.Sprintf("%04x", m.productID)
+	}
+
+	description := fmt.Sprintf("device %s:%s", vendor, product)
+	if m.productPattern != nil {
+		description += fmt.Sprintf(" product '%s'", m.productPattern)
+	}
+	if m.serial != "" {
+		description += fmt.Sprintf(" serial '%s'", m.serial)
+	}
+	if m.iface != nil {
+		description += fmt.Sprintf(" interface %d", *m.iface)
+	}
+	return description
+}
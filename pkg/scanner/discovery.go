@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	usagePageGenericDesktop = 0x01
+	usageKeyboard           = 0x06
+	usagePageBarcodeScanner = 0x8c
+
+	// Defaults for scanners found by auto-discovery.
+	discoveredTerminationChar = "enter"
+	discoveredKeyboardLayout  = "us"
+)
+
+var (
+	scannerIDPattern    = regexp.MustCompile(`[^a-z0-9]+`)
+	scannerNameKeywords = []string{"barcode", "scanner", "scan", "imager"}
+)
+
+// DeviceDisplayName returns a friendly name for a device from its USB strings.
+func DeviceDisplayName(device *hid.DeviceInfo) string {
+	name := device.Product
+	if name == "" {
+		name = "Unknown Device"
+	}
+	if device.Manufacturer != "" && device.Manufacturer != name {
+		name = fmt.Sprintf("%s %s", device.Manufacturer, name)
+	}
+	return name
+}
+
+// GenerateScannerID creates a valid YAML key from device info
+func GenerateScannerID(name string, device *hid.DeviceInfo) string {
+	// Convert to lowercase and replace spaces/special chars with underscores
+	id := strings.ToLower(name)
+	// Replace any non-alphanumeric characters with underscores
+	id = scannerIDPattern.ReplaceAllString(id, "_")
+	// Remove leading/trailing underscores
+	id = strings.Trim(id, "_")
+
+	// If empty or starts with number, prepend "scanner"
+	if id == "" || (id != "" && id[0] >= '0' && id[0] <= '9') {
+		id = fmt.Sprintf("scanner_%s", id)
+	}
+	// If still empty, use fallback
+	if id == "" || id == "scanner_" {
+		id = "scanner"
+	}
+
+	// Add interface index for same VID:PID devices (only if > 0)
+	if device.Interface > 0 {
+		id = fmt.Sprintf("%s_%d", id, device.Interface)
+	}
+
+	// Add serial suffix if available (for additional uniqueness)
+	if device.Serial != "" {
+		serialSuffix := scannerIDPattern.ReplaceAllString(strings.ToLower(device.Serial), "_")
+		serialSuffix = strings.Trim(serialSuffix, "_")
+		if serialSuffix != "" {
+			id = fmt.Sprintf("%s_%s", id, serialSuffix)
+		}
+	}
+
+	return id
+}
+
+// IsLikelyBarcodeScanner is the auto-discovery heuristic. Devices on the
+// barcode scanner usage page always match; keyboard-emulating devices match
+// when their name says they are a scanner, so regular keyboards are left
+// alone. Backends that do not report a usage page rely on the name only.
+func IsLikelyBarcodeScanner(device *hid.DeviceInfo) bool {
+	if device.UsagePage == usagePageBarcodeScanner {
+		return true
+	}
+
+	keyboard := device.UsagePage == usagePageGenericDesktop && device.Usage == usageKeyboard
+	if device.UsagePage != 0 && !keyboard {
+		return false
+	}
+
+	name := strings.ToLower(device.Manufacturer + " " + device.Product)
+	for _, keyword := range scannerNameKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoveredScannerConfig builds the configuration of an auto-discovered
+// scanner, pinned to the device serial and interface.
+func discoveredScannerConfig(id string, device *hid.DeviceInfo) config.ScannerConfig {
+	iface := device.Interface
+	return config.ScannerConfig{
+		ID:   id,
+		Name: DeviceDisplayName(device),
+		Identification: config.ScannerIdentification{
+			VendorID:  device.VendorID,
+			ProductID: device.ProductID,
+			Serial:    device.Serial,
+			Interface: &iface,
+		},
+		TerminationChar: discoveredTerminationChar,
+		KeyboardLayout:  discoveredKeyboardLayout,
+	}
+}
+
+// claimsDevice reports whether a configured scanner would open the device,
+// through its identification or one of its fallbacks.
+func claimsDevice(cfg *config.ScannerConfig, device *hid.DeviceInfo) bool {
+	if cfg.Driver != "" && !strings.EqualFold(cfg.Driver, config.DriverHID) {
+		return false
+	}
+
+	identifications := append([]config.ScannerIdentification{cfg.Identification}, cfg.FallbackIdentifications...)
+	for i := range identifications {
+		match := newDeviceMatch(&identifications[i])
+		if match.matches(device) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimedDevice returns the first of the devices a configured scanner would
+// open.
+func ClaimedDevice(cfg *config.ScannerConfig, devices []hid.DeviceInfo) (*hid.DeviceInfo, bool) {
+	for i := range devices {
+		if claimsDevice(cfg, &devices[i]) {
+			return &devices[i], true
+		}
+	}
+	return nil, false
+}
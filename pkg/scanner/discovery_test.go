@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestGenerateScannerID(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   hid.DeviceInfo
+		expected string
+	}{
+		{"Honeywell Scanner", hid.DeviceInfo{}, "honeywell_scanner"},
+		{"Honeywell Scanner", hid.DeviceInfo{Interface: 1}, "honeywell_scanner_1"},
+		{"Honeywell Scanner", hid.DeviceInfo{Serial: "AB-12"}, "honeywell_scanner_ab_12"},
+		{"3D Imager", hid.DeviceInfo{}, "scanner_3d_imager"},
+		{"---", hid.DeviceInfo{}, "scanner"},
+	}
+
+	for _, tt := range tests {
+		if id := GenerateScannerID(tt.name, &tt.device); id != tt.expected {
+			t.Errorf("Expected ID %q for %q, got %q", tt.expected, tt.name, id)
+		}
+	}
+}
+
+func TestIsLikelyBarcodeScanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		device   hid.DeviceInfo
+		expected bool
+	}{
+		{"barcode usage page", hid.DeviceInfo{UsagePage: usagePageBarcodeScanner, Product: "Device"}, true},
+		{"scanner keyboard", hid.DeviceInfo{UsagePage: 0x01, Usage: 0x06, Product: "Barcode Scanner"}, true},
+		{"regular keyboard", hid.DeviceInfo{UsagePage: 0x01, Usage: 0x06, Product: "USB Keyboard"}, false},
+		{"scanner mouse interface", hid.DeviceInfo{UsagePage: 0x01, Usage: 0x02, Product: "Barcode Scanner"}, false},
+		{"unknown usage page", hid.DeviceInfo{Manufacturer: "Zebra", Product: "Imager"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsLikelyBarcodeScanner(&tt.device); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestClaimsDevice(t *testing.T) {
+	iface := 1
+	device := hid.DeviceInfo{VendorID: 0x60e, ProductID: 0x16c7, Serial: "123", Interface: 1}
+
+	tests := []struct {
+		name     string
+		cfg      config.ScannerConfig
+		expected bool
+	}{
+		{"vid pid", config.ScannerConfig{Identification: config.ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7}}, true},
+		{"other product", config.ScannerConfig{Identification: config.ScannerIdentification{VendorID: 0x60e, ProductID: 0x1}}, false},
+		{"other serial", config.ScannerConfig{Identification: config.ScannerIdentification{
+			VendorID: 0x60e, ProductID: 0x16c7, Serial: "456",
+		}}, false},
+		{"matching interface", config.ScannerConfig{Identification: config.ScannerIdentification{
+			VendorID: 0x60e, ProductID: 0x16c7, Interface: &iface,
+		}}, true},
+		{"other driver", config.ScannerConfig{Driver: config.DriverSerial, Identification: config.ScannerIdentification{
+			VendorID: 0x60e, ProductID: 0x16c7,
+		}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := claimsDevice(&tt.cfg, &device); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestDiscoverScanners(t *testing.T) {
+	devices := []hid.DeviceInfo{
+		{VendorID: 0x60e, ProductID: 0x16c7, Product: "Barcode Scanner", UsagePage: usagePageBarcodeScanner},
+		{VendorID: 0x1, ProductID: 0x2, Product: "Barcode Scanner", UsagePage: usagePageBarcodeScanner},
+		{VendorID: 0x3, ProductID: 0x4, Product: "USB Keyboard", UsagePage: 0x01, Usage: 0x06},
+	}
+
+	configured := []config.ScannerConfig{{
+		ID:             "barcode_scanner",
+		Identification: config.ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7},
+	}}
+
+	manager := NewScannerManager(configured, logrus.New())
+	manager.enumerate = func(uint16, uint16) []hid.DeviceInfo { return devices }
+
+	var discovered []string
+	manager.SetOnScannerDiscoveredCallback(func(cfg *config.ScannerConfig) {
+		discovered = append(discovered, cfg.ID)
+	})
+
+	manager.discoverScanners()
+	manager.discoverScanners()
+	defer manager.Stop() //nolint:errcheck
+
+	if len(discovered) != 1 {
+		t.Fatalf("Expected 1 discovered scanner, got %v", discovered)
+	}
+	if discovered[0] != "barcode_scanner_2" {
+		t.Errorf("Expected ID 'barcode_scanner_2', got %s", discovered[0])
+	}
+}
+
+func TestUnclaimedDevices(t *testing.T) {
+	devices := []hid.DeviceInfo{
+		{VendorID: 0x60e, ProductID: 0x16c7, Path: "/dev/hidraw0"},
+		{VendorID: 0x1eab, ProductID: 0x1a03, Path: "/dev/hidraw1"},
+	}
+	configs := map[string]config.ScannerConfig{
+		"front_desk": {Identification: config.ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7}},
+	}
+
+	unclaimed := UnclaimedDevices(configs, devices)
+	if len(unclaimed) != 1 || unclaimed[0].Path != "/dev/hidraw1" {
+		t.Errorf("Expected only /dev/hidraw1 to be unclaimed, got %v", unclaimed)
+	}
+	if got := UnclaimedDevices(nil, devices); len(got) != 2 {
+		t.Errorf("Expected all devices without configured scanners, got %v", got)
+	}
+}
@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// Scanner is implemented by every input driver. Device details are reported
+// as hid.DeviceInfo regardless of the transport so Home Assistant device
+// registration works the same for all drivers.
+type Scanner interface {
+	Start() error
+	Stop() error
+	TryInitialConnect() error
+	IsConnected() bool
+	GetConnectedDeviceInfo() *hid.DeviceInfo
+	SetOnScanCallback(callback func(string))
+	SetOnConnectionChangeCallback(callback func(bool))
+	SetReconnectDelay(delay time.Duration)
+}
+
+// BatteryReporter is implemented by drivers that can report the scanner
+// battery level.
+type BatteryReporter interface {
+	SetOnBatteryLevelCallback(callback func(int))
+}
+
+// ErrorReporter is implemented by drivers that report low-level device
+// errors, categorized by the ErrorCategory constants.
+type ErrorReporter interface {
+	SetOnErrorCallback(callback func(category string, err error))
+}
+
+// KeyboardDecoder is implemented by drivers that decode keyboard reports and
+// can therefore learn overrides for unmapped keycodes.
+type KeyboardDecoder interface {
+	SetLearnedLayout(learned *LearnedLayout)
+}
+
+// RawReportMonitor is implemented by drivers that decode keyboard reports and
+// can expose them before decoding, e.g. to debug layout or termination issues.
+type RawReportMonitor interface {
+	SetOnRawReportCallback(callback func([]byte))
+}
+
+// CompletionTimeoutTuner is implemented by drivers that complete scans
+// without a termination key after a pause, which slow scanners may need
+// longer.
+type CompletionTimeoutTuner interface {
+	SetCompletionTimeout(timeout time.Duration)
+	CompletionTimeout() time.Duration
+}
+
+// KeyboardLayoutSwitcher is implemented by drivers that decode keyboard
+// reports, whose layout can be switched while they are reading.
+type KeyboardLayoutSwitcher interface {
+	SetKeyboardLayout(layout string)
+	KeyboardLayout() string
+}
+
+// NewScanner creates the input driver selected in the scanner configuration.
+func NewScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	scanner, err := newDriver(cfg, logger)
+	if err != nil || cfg.LearnedLayout == "" {
+		return scanner, err
+	}
+
+	decoder, ok := scanner.(KeyboardDecoder)
+	if !ok {
+		logger.Warnf("Scanner %s: learned_layout is ignored by the %s driver", cfg.ID, cfg.Driver)
+		return scanner, nil
+	}
+
+	learned, err := LoadLearnedLayout(cfg.LearnedLayout)
+	if err != nil {
+		return nil, err
+	}
+	decoder.SetLearnedLayout(learned)
+	return scanner, nil
+}
+
+// AvailableDrivers returns the input drivers of this build. The hid driver
+// needs a HID backend, the device drivers other than tcp and stdin need Linux,
+// and the keyhook driver needs Windows.
+func AvailableDrivers() []string {
+	var drivers []string
+	if len(AvailableHIDBackends()) > 0 {
+		drivers = append(drivers, config.DriverHID)
+	}
+	if runtime.GOOS == "linux" {
+		drivers = append(drivers, config.DriverEvdev, config.DriverSerial, config.DriverBluetooth, config.DriverGPIO)
+	}
+	if runtime.GOOS == "windows" {
+		drivers = append(drivers, config.DriverKeyhook)
+	}
+	return append(drivers, config.DriverTCP, config.DriverStdin)
+}
+
+func newDriver(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", config.DriverHID:
+		scanner := NewBarcodeScannerWithInterface(
+			cfg.Identification.VendorID,
+			cfg.Identification.ProductID,
+			cfg.Identification.Serial,
+			cfg.Identification.Interface,
+			cfg.TerminationChar,
+			cfg.KeyboardLayout,
+			logger,
+		)
+		scanner.SetProductPattern(cfg.Identification.ProductPattern())
+		scanner.SetFallbackIdentifications(cfg.FallbackIdentifications)
+		scanner.SetReportIDPrefix(cfg.HID.ReportIDPrefix)
+		scanner.SetModifierMode(cfg.HID.ModifierMode)
+		backend, err := NewHIDBackend(cfg.HID.Backend)
+		if err != nil {
+			logger.Warnf("Scanner %s: %v, falling back to the %s backend", cfg.ID, err, config.HIDBackendHidapi)
+			return scanner, nil
+		}
+		scanner.SetHIDBackend(backend)
+		return scanner, nil
+	case config.DriverEvdev:
+		return NewEvdevScanner(cfg, logger)
+	case config.DriverSerial:
+		return NewSerialScanner(cfg, logger)
+	case config.DriverBluetooth:
+		return NewBluetoothScanner(cfg, logger)
+	case config.DriverTCP:
+		return NewTCPScanner(cfg, logger)
+	case config.DriverGPIO:
+		return NewGPIOScanner(cfg, logger)
+	case config.DriverKeyhook:
+		return NewKeyhookScanner(cfg, logger)
+	case config.DriverStdin:
+		return NewStdinScanner(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown scanner driver '%s'", cfg.Driver)
+	}
+}
@@ -1,9 +1,45 @@
 package scanner
 
-import "errors"
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+)
 
 var (
 	ErrDeviceOpenFailed       = errors.New("failed to open device")
 	ErrReconnectionInProgress = errors.New("reconnection already in progress")
 	ErrScannerStopped         = errors.New("scanner stopped")
+	ErrBatteryUnavailable     = errors.New("battery level not available")
+	ErrDeviceNotFound         = errors.New("device not found")
+	ErrPermissionDenied       = errors.New("permission denied opening scanner devices")
+	ErrNoScannersAvailable    = errors.New("no configured scanner is available")
+)
+
+// Low-level device error categories reported through ErrorReporter. They are
+// tracked separately so cable or hub problems (timeouts, I/O errors) can be
+// told apart from configuration problems (open failures).
+const (
+	ErrorCategoryOpenFailure = "open_failure"
+	ErrorCategoryReadTimeout = "read_timeout"
+	ErrorCategoryIOError     = "io_error"
+	// ErrorCategoryBadLength counts scans outside the configured length
+	// range, usually partial reads or keyboard noise. It is reported by the
+	// bridge, not the drivers.
+	ErrorCategoryBadLength = "bad_length"
 )
+
+// classifyReadError returns the error category of a failed device read, or
+// an empty string when the device was simply unplugged.
+func classifyReadError(err error) string {
+	switch {
+	case errors.Is(err, syscall.ENODEV), errors.Is(err, os.ErrClosed):
+		return ""
+	case errors.Is(err, syscall.ETIMEDOUT), errors.Is(err, os.ErrDeadlineExceeded),
+		strings.Contains(strings.ToLower(err.Error()), "timeout"):
+		return ErrorCategoryReadTimeout
+	default:
+		return ErrorCategoryIOError
+	}
+}
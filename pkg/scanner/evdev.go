@@ -0,0 +1,67 @@
+package scanner
+
+// Linux input event key codes (linux/input-event-codes.h) mapped to the HID
+// keyboard usage IDs the keyboard layouts are written in.
+var evdevKeyToHIDUsage = map[uint16]byte{
+	2: 0x1e, 3: 0x1f, 4: 0x20, 5: 0x21, 6: 0x22, 7: 0x23, 8: 0x24, 9: 0x25, 10: 0x26, 11: 0x27,
+	12: 0x2d, 13: 0x2e, 14: 0x2a, 15: 0x2b,
+	16: 0x14, 17: 0x1a, 18: 0x08, 19: 0x15, 20: 0x17, 21: 0x1c, 22: 0x18, 23: 0x0c, 24: 0x12, 25: 0x13,
+	26: 0x2f, 27: 0x30, 28: 0x28,
+	30: 0x04, 31: 0x16, 32: 0x07, 33: 0x09, 34: 0x0a, 35: 0x0b, 36: 0x0d, 37: 0x0e, 38: 0x0f,
+	39: 0x33, 40: 0x34, 41: 0x35, 43: 0x31,
+	44: 0x1d, 45: 0x1b, 46: 0x06, 47: 0x19, 48: 0x05, 49: 0x11, 50: 0x10,
+	51: 0x36, 52: 0x37, 53: 0x38, 55: 0x55, 57: 0x2c,
+	71: 0x5f, 72: 0x60, 73: 0x61, 74: 0x56, 75: 0x5c, 76: 0x5d, 77: 0x5e, 78: 0x57,
+	79: 0x59, 80: 0x5a, 81: 0x5b, 82: 0x62, 83: 0x63, 86: 0x64, 96: 0x58, 98: 0x54,
+}
+
+// Linux modifier key codes mapped to HID boot protocol modifier bits.
+var evdevModifierBits = map[uint16]byte{
+	29:  0x01, // KEY_LEFTCTRL
+	42:  0x02, // KEY_LEFTSHIFT
+	56:  0x04, // KEY_LEFTALT
+	125: 0x08, // KEY_LEFTMETA
+	97:  0x10, // KEY_RIGHTCTRL
+	54:  0x20, // KEY_RIGHTSHIFT
+	100: 0x40, // KEY_RIGHTALT
+	126: 0x80, // KEY_RIGHTMETA
+}
+
+const (
+	evdevTypeKey     = 0x01
+	evdevKeyRelease  = 0
+	evdevKeyPress    = 1
+	evdevKeyCodeKeyA = 30
+)
+
+// evdevTranslator turns key events into HID boot keyboard reports so evdev
+// input goes through the same HIDProcessor and keyboard layouts as hidapi.
+type evdevTranslator struct {
+	modifiers byte
+}
+
+func (t *evdevTranslator) translate(eventType, code uint16, value int32) []byte {
+	if eventType != evdevTypeKey {
+		return nil
+	}
+
+	if bit, isModifier := evdevModifierBits[code]; isModifier {
+		if value == evdevKeyRelease {
+			t.modifiers &^= bit
+		} else {
+			t.modifiers |= bit
+		}
+		return nil
+	}
+
+	if value != evdevKeyPress {
+		return nil
+	}
+
+	usage, exists := evdevKeyToHIDUsage[code]
+	if !exists {
+		return nil
+	}
+
+	return []byte{t.modifiers, 0, usage, 0, 0, 0, 0, 0}
+}
@@ -0,0 +1,294 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	evdevIocRead  = 2
+	evdevIocWrite = 1
+	evdevNameLen  = 256
+)
+
+type evdevInputID struct {
+	Bustype uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+type evdevInputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// EvdevScanner reads scans from a Linux input event device. This works for
+// scanners already claimed by the kernel keyboard driver and, when grabbed,
+// keeps scans from also typing into the console.
+type EvdevScanner struct {
+	baseScanner
+	config       *config.ScannerConfig
+	hidProcessor *HIDProcessor
+	file         *os.File
+
+	// matches holds the configured identification and its fallbacks.
+	matches []deviceMatch
+
+	// matchDevice selects the event device when no explicit path is configured.
+	matchDevice func(*hid.DeviceInfo) bool
+}
+
+func NewEvdevScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	s := &EvdevScanner{
+		baseScanner:  newBaseScanner(logger),
+		config:       cfg,
+		hidProcessor: NewHIDProcessor(cfg.TerminationChar, cfg.KeyboardLayout, logger),
+	}
+	for _, identification := range append([]config.ScannerIdentification{cfg.Identification}, cfg.FallbackIdentifications...) {
+		match := newDeviceMatch(&identification)
+		match.iface = nil // Event devices do not expose the USB interface
+		s.matches = append(s.matches, match)
+	}
+	s.hidProcessor.SetOnScanCallback(s.emitScan)
+	s.matchDevice = s.matchIdentification
+	return s, nil
+}
+
+func (s *EvdevScanner) SetLearnedLayout(learned *LearnedLayout) {
+	s.hidProcessor.SetLearnedLayout(learned)
+}
+
+func (s *EvdevScanner) SetOnRawReportCallback(callback func([]byte)) {
+	s.hidProcessor.SetOnRawReportCallback(callback)
+}
+
+func (s *EvdevScanner) SetCompletionTimeout(timeout time.Duration) {
+	s.hidProcessor.SetCompletionTimeout(timeout)
+}
+
+func (s *EvdevScanner) CompletionTimeout() time.Duration {
+	return s.hidProcessor.CompletionTimeout()
+}
+
+func (s *EvdevScanner) SetKeyboardLayout(layout string) {
+	s.hidProcessor.SetKeyboardLayout(layout)
+}
+
+func (s *EvdevScanner) KeyboardLayout() string {
+	return s.hidProcessor.KeyboardLayout()
+}
+
+func (s *EvdevScanner) matchIdentification(info *hid.DeviceInfo) bool {
+	for i := range s.matches {
+		if s.matches[i].matches(info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *EvdevScanner) Start() error {
+	go s.runConnectionLoop(s.session)
+	s.logger.Debug("Evdev scanner started successfully")
+	return nil
+}
+
+func (s *EvdevScanner) Stop() error {
+	s.cancel()
+
+	s.mutex.Lock()
+	file := s.file
+	s.file = nil
+	s.mutex.Unlock()
+
+	if file != nil {
+		_ = file.Close()
+	}
+
+	s.logger.Debug("Evdev scanner stopped")
+	return nil
+}
+
+func (s *EvdevScanner) TryInitialConnect() error {
+	path, _, err := s.findDevice()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path) // #nosec G304 - input device path from config
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+func (s *EvdevScanner) session() error {
+	path, deviceInfo, err := s.findDevice()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path) // #nosec G304 - input device path from config
+	if err != nil {
+		s.reportOpenFailure(err)
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if s.config.Evdev.ShouldGrab() {
+		if err := unix.IoctlSetPointerInt(int(file.Fd()), evdevIoctl(evdevIocWrite, 0x90, 4), 1); err != nil {
+			return fmt.Errorf("failed to grab %s: %w", path, err)
+		}
+	}
+
+	s.mutex.Lock()
+	s.file = file
+	s.mutex.Unlock()
+
+	s.setConnected(deviceInfo)
+	s.logger.Debugf("Connected to input device %s (%s)", path, deviceInfo.Product)
+
+	return s.readLoop(file)
+}
+
+func (s *EvdevScanner) readLoop(file *os.File) error {
+	pacer := newReadPacer()
+	defer pacer.Stop()
+
+	reportChan := make(chan []byte, 64)
+	errorChan := make(chan error, 1)
+
+	go func() {
+		translator := &evdevTranslator{}
+		event := evdevInputEvent{}
+		buffer := make([]byte, unsafe.Sizeof(event))
+
+		for {
+			if _, err := file.Read(buffer); err != nil {
+				errorChan <- err
+				return
+			}
+
+			eventType := binary.NativeEndian.Uint16(buffer[len(buffer)-8:])
+			code := binary.NativeEndian.Uint16(buffer[len(buffer)-6:])
+			value := int32(binary.NativeEndian.Uint32(buffer[len(buffer)-4:])) // #nosec G115 - kernel value is s32
+
+			if report := translator.translate(eventType, code, value); report != nil {
+				reportChan <- report
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-pacer.C():
+			s.hidProcessor.CheckTimeout()
+			pacer.Checked(s.hidProcessor.Pending())
+		case report := <-reportChan:
+			s.hidProcessor.ProcessData(report)
+			pacer.Activity()
+		case err := <-errorChan:
+			s.reportError(classifyReadError(err), err)
+			return fmt.Errorf("input device read error: %w", err)
+		}
+	}
+}
+
+// findDevice resolves the configured path, or scans /dev/input for a keyboard
+// capable event device accepted by matchDevice.
+func (s *EvdevScanner) findDevice() (string, *hid.DeviceInfo, error) {
+	if s.config.Evdev.Path != "" {
+		info, err := readEvdevInfo(s.config.Evdev.Path)
+		if err != nil {
+			return "", nil, err
+		}
+		return s.config.Evdev.Path, info, nil
+	}
+
+	var permissionErr error
+	paths, _ := filepath.Glob("/dev/input/event*")
+	for _, path := range paths {
+		info, err := readEvdevInfo(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) {
+				permissionErr = err
+			}
+			continue
+		}
+		if s.matchDevice(info) {
+			return path, info, nil
+		}
+	}
+
+	if permissionErr != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, permissionErr)
+	}
+	return "", nil, ErrDeviceNotFound
+}
+
+func readEvdevInfo(path string) (*hid.DeviceInfo, error) {
+	file, err := os.Open(path) // #nosec G304 - input device path
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	fd := file.Fd()
+
+	var id evdevInputID
+	if err := evdevIoctlPtr(fd, evdevIoctl(evdevIocRead, 0x02, unsafe.Sizeof(id)), unsafe.Pointer(&id)); err != nil {
+		return nil, fmt.Errorf("%s is not an input device: %w", path, err)
+	}
+
+	var keyBits [96]byte
+	if err := evdevIoctlPtr(fd, evdevIoctl(evdevIocRead, 0x20+evdevTypeKey, uintptr(len(keyBits))), unsafe.Pointer(&keyBits)); err != nil {
+		return nil, err
+	}
+	if keyBits[evdevKeyCodeKeyA/8]&(1<<(evdevKeyCodeKeyA%8)) == 0 {
+		return nil, fmt.Errorf("%s does not report keyboard keys", path)
+	}
+
+	return &hid.DeviceInfo{
+		Path:      path,
+		VendorID:  id.Vendor,
+		ProductID: id.Product,
+		Product:   evdevString(fd, 0x06),
+		Serial:    evdevString(fd, 0x08),
+	}, nil
+}
+
+func evdevString(fd uintptr, nr uintptr) string {
+	buffer := make([]byte, evdevNameLen)
+	if err := evdevIoctlPtr(fd, evdevIoctl(evdevIocRead, nr, evdevNameLen), unsafe.Pointer(&buffer[0])); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(buffer), "\x00"))
+}
+
+func evdevIoctl(direction, nr, size uintptr) uint {
+	return uint(direction<<30 | size<<16 | 'E'<<8 | nr)
+}
+
+func evdevIoctlPtr(fd uintptr, request uint, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, uintptr(request), uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
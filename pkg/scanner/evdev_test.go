@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEvdevTranslator(t *testing.T) {
+	const (
+		keyA         = 30
+		keyEnter     = 28
+		keyLeftShift = 42
+		keyRepeat    = 2
+		eventSync    = 0
+	)
+
+	type event struct {
+		eventType uint16
+		code      uint16
+		value     int32
+	}
+
+	tests := []struct {
+		name     string
+		events   []event
+		expected []byte
+	}{
+		{"Key press", []event{{evdevTypeKey, keyA, evdevKeyPress}}, []byte{0, 0, 0x04, 0, 0, 0, 0, 0}},
+		{"Enter", []event{{evdevTypeKey, keyEnter, evdevKeyPress}}, []byte{0, 0, 0x28, 0, 0, 0, 0, 0}},
+		{"Key release", []event{{evdevTypeKey, keyA, evdevKeyRelease}}, nil},
+		{"Key repeat", []event{{evdevTypeKey, keyA, keyRepeat}}, nil},
+		{"Sync event", []event{{eventSync, 0, 0}}, nil},
+		{"Unknown key", []event{{evdevTypeKey, 240, evdevKeyPress}}, nil},
+		{
+			"Shifted key",
+			[]event{{evdevTypeKey, keyLeftShift, evdevKeyPress}, {evdevTypeKey, keyA, evdevKeyPress}},
+			[]byte{0x02, 0, 0x04, 0, 0, 0, 0, 0},
+		},
+		{
+			"Shift released",
+			[]event{
+				{evdevTypeKey, keyLeftShift, evdevKeyPress},
+				{evdevTypeKey, keyLeftShift, evdevKeyRelease},
+				{evdevTypeKey, keyA, evdevKeyPress},
+			},
+			[]byte{0, 0, 0x04, 0, 0, 0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translator := &evdevTranslator{}
+
+			var report []byte
+			for _, e := range tt.events {
+				report = translator.translate(e.eventType, e.code, e.value)
+			}
+
+			if !bytes.Equal(report, tt.expected) {
+				t.Errorf("Expected report %v, got %v", tt.expected, report)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	DefaultGPIOChip     = "/dev/gpiochip0"
+	DefaultGPIODebounce = 20 * time.Millisecond
+
+	// GPIOPressed and GPIOReleased are the scans the gpio driver reports
+	// when the line turns active or inactive.
+	GPIOPressed  = "pressed"
+	GPIOReleased = "released"
+)
+
+// Line flags and event IDs of the Linux GPIO character device, v2 uAPI.
+const (
+	gpioV2LineFlagActiveLow    = 1 << 1
+	gpioV2LineFlagInput        = 1 << 2
+	gpioV2LineFlagEdgeRising   = 1 << 4
+	gpioV2LineFlagEdgeFalling  = 1 << 5
+	gpioV2LineFlagBiasPullUp   = 1 << 8
+	gpioV2LineFlagBiasPullDown = 1 << 9
+	gpioV2LineFlagBiasDisabled = 1 << 10
+	gpioV2LineEventFallingEdge = 2
+)
+
+// gpioLineFlags returns the flags requesting the line as an input reporting
+// the configured events. The kernel applies active_low before edge
+// detection, so a press is always a rising edge.
+func gpioLineFlags(cfg *config.GPIOConfig) uint64 {
+	flags := uint64(gpioV2LineFlagInput)
+	if cfg.ActiveLow {
+		flags |= gpioV2LineFlagActiveLow
+	}
+
+	switch cfg.Bias {
+	case config.GPIOBiasPullUp:
+		flags |= gpioV2LineFlagBiasPullUp
+	case config.GPIOBiasPullDown:
+		flags |= gpioV2LineFlagBiasPullDown
+	case config.GPIOBiasDisabled:
+		flags |= gpioV2LineFlagBiasDisabled
+	}
+
+	switch cfg.Events {
+	case config.GPIOEventsRelease:
+		flags |= gpioV2LineFlagEdgeFalling
+	case config.GPIOEventsBoth:
+		flags |= gpioV2LineFlagEdgeRising | gpioV2LineFlagEdgeFalling
+	default:
+		flags |= gpioV2LineFlagEdgeRising
+	}
+	return flags
+}
+
+// gpioEventScan returns the scan reported for a line event.
+func gpioEventScan(eventID uint32) string {
+	if eventID == gpioV2LineEventFallingEdge {
+		return GPIOReleased
+	}
+	return GPIOPressed
+}
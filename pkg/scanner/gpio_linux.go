@@ -0,0 +1,196 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unsafe"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	gpioIoctlType            = 0xB4
+	gpioMaxNameSize          = 32
+	gpioV2LinesMax           = 64
+	gpioV2LineNumAttrsMax    = 10
+	gpioV2LineAttrIDDebounce = 3
+	gpioV2LineEventSize      = 48
+)
+
+// The structs below mirror linux/gpio.h.
+
+type gpioChipInfo struct {
+	Name  [gpioMaxNameSize]byte
+	Label [gpioMaxNameSize]byte
+	Lines uint32
+}
+
+type gpioV2LineAttribute struct {
+	ID      uint32
+	Padding uint32
+	Value   [8]byte // Flags, output values or debounce period, depending on ID
+}
+
+type gpioV2LineConfigAttribute struct {
+	Attr gpioV2LineAttribute
+	Mask uint64
+}
+
+type gpioV2LineConfig struct {
+	Flags    uint64
+	NumAttrs uint32
+	Padding  [5]uint32
+	Attrs    [gpioV2LineNumAttrsMax]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	Offsets         [gpioV2LinesMax]uint32
+	Consumer        [gpioMaxNameSize]byte
+	Config          gpioV2LineConfig
+	NumLines        uint32
+	EventBufferSize uint32
+	Padding         [5]uint32
+	Fd              int32
+}
+
+// GPIOScanner reports edges of a GPIO line, e.g. a foot pedal or a presence
+// beam wired as a dry contact, as scans. It uses the GPIO character device,
+// so it works on any Linux board without the deprecated sysfs interface.
+type GPIOScanner struct {
+	baseScanner
+	config *config.ScannerConfig
+	chip   string
+	file   *os.File
+}
+
+func NewGPIOScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	chip := cfg.GPIO.Chip
+	if chip == "" {
+		chip = DefaultGPIOChip
+	}
+
+	return &GPIOScanner{
+		baseScanner: newBaseScanner(logger),
+		config:      cfg,
+		chip:        chip,
+	}, nil
+}
+
+func (s *GPIOScanner) Start() error {
+	go s.runConnectionLoop(s.session)
+	s.logger.Debug("GPIO scanner started successfully")
+	return nil
+}
+
+func (s *GPIOScanner) Stop() error {
+	s.cancel()
+
+	s.mutex.Lock()
+	file := s.file
+	s.file = nil
+	s.mutex.Unlock()
+
+	if file != nil {
+		_ = file.Close()
+	}
+
+	s.logger.Debug("GPIO scanner stopped")
+	return nil
+}
+
+func (s *GPIOScanner) TryInitialConnect() error {
+	file, _, err := s.requestLine()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func (s *GPIOScanner) session() error {
+	file, info, err := s.requestLine()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	s.mutex.Lock()
+	s.file = file
+	s.mutex.Unlock()
+
+	s.setConnected(info)
+	s.logger.Debugf("Requested GPIO line %d on %s", *s.config.GPIO.Line, s.chip)
+
+	event := make([]byte, gpioV2LineEventSize)
+	for {
+		if _, err := io.ReadFull(file, event); err != nil {
+			return fmt.Errorf("GPIO read error: %w", err)
+		}
+		// struct gpio_v2_line_event starts with the 64-bit timestamp
+		s.emitScan(gpioEventScan(binary.NativeEndian.Uint32(event[8:12])))
+	}
+}
+
+// requestLine requests the line as an input with edge detection and
+// returns the file events are read from.
+func (s *GPIOScanner) requestLine() (*os.File, *hid.DeviceInfo, error) {
+	line := *s.config.GPIO.Line
+
+	chip, err := os.Open(s.chip) // #nosec G304 - GPIO chip path from config
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", s.chip, err)
+	}
+	defer func() { _ = chip.Close() }()
+
+	var chipInfo gpioChipInfo
+	if err := evdevIoctlPtr(chip.Fd(), gpioIoctl(evdevIocRead, 0x01, unsafe.Sizeof(chipInfo)), unsafe.Pointer(&chipInfo)); err != nil {
+		return nil, nil, fmt.Errorf("%s is not a GPIO chip: %w", s.chip, err)
+	}
+	if line >= int(chipInfo.Lines) {
+		return nil, nil, fmt.Errorf("%s has no line %d, it has %d lines", s.chip, line, chipInfo.Lines)
+	}
+
+	debounce := s.config.GPIO.Debounce
+	if debounce == 0 {
+		debounce = DefaultGPIODebounce
+	}
+
+	request := gpioV2LineRequest{NumLines: 1}
+	request.Offsets[0] = uint32(line) // #nosec G115 - checked against the line count
+	copy(request.Consumer[:gpioMaxNameSize-1], "barcode-bridge:"+s.config.ID)
+	request.Config.Flags = gpioLineFlags(&s.config.GPIO)
+	request.Config.NumAttrs = 1
+	request.Config.Attrs[0].Attr.ID = gpioV2LineAttrIDDebounce
+	// The mask selects the lines the attribute applies to, by request index
+	request.Config.Attrs[0].Mask = 1
+	debounceMicros := uint32(debounce.Microseconds()) // #nosec G115 - debounce periods are milliseconds
+	binary.NativeEndian.PutUint32(request.Config.Attrs[0].Attr.Value[:4], debounceMicros)
+
+	ioctl := gpioIoctl(evdevIocRead|evdevIocWrite, 0x07, unsafe.Sizeof(request))
+	if err := evdevIoctlPtr(chip.Fd(), ioctl, unsafe.Pointer(&request)); err != nil {
+		return nil, nil, fmt.Errorf("failed to request line %d of %s: %w", line, s.chip, err)
+	}
+
+	// Non-blocking so Stop can interrupt a pending read by closing the file
+	if err := unix.SetNonblock(int(request.Fd), true); err != nil {
+		_ = unix.Close(int(request.Fd))
+		return nil, nil, err
+	}
+	file := os.NewFile(uintptr(request.Fd), fmt.Sprintf("%s line %d", s.chip, line))
+
+	return file, &hid.DeviceInfo{
+		Path:         fmt.Sprintf("%s:%d", s.chip, line),
+		Manufacturer: strings.TrimRight(string(chipInfo.Label[:]), "\x00"),
+		Product:      "GPIO Trigger",
+	}, nil
+}
+
+func gpioIoctl(direction, nr, size uintptr) uint {
+	return uint(direction<<30 | size<<16 | gpioIoctlType<<8 | nr)
+}
@@ -0,0 +1,42 @@
+package scanner
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// The ioctls encode the struct sizes, so they must match linux/gpio.h.
+func TestGPIOStructSizes(t *testing.T) {
+	sizes := map[string][2]uintptr{
+		"gpiochip_info":        {unsafe.Sizeof(gpioChipInfo{}), 68},
+		"gpio_v2_line_config":  {unsafe.Sizeof(gpioV2LineConfig{}), 272},
+		"gpio_v2_line_request": {unsafe.Sizeof(gpioV2LineRequest{}), 592},
+	}
+	for name, size := range sizes {
+		if size[0] != size[1] {
+			t.Errorf("Expected struct %s to be %d bytes, got %d", name, size[1], size[0])
+		}
+	}
+
+	if request := gpioIoctl(evdevIocRead|evdevIocWrite, 0x07, unsafe.Sizeof(gpioV2LineRequest{})); request != 0xC250B407 {
+		t.Errorf("Expected GPIO_V2_GET_LINE_IOCTL 0xC250B407, got %#x", request)
+	}
+}
+
+func TestGPIOScanner_MissingChip(t *testing.T) {
+	line := 17
+	s, err := NewGPIOScanner(&config.ScannerConfig{ID: "pedal", Driver: "gpio", GPIO: config.GPIOConfig{
+		Chip: "/nonexistent/gpiochip9",
+		Line: &line,
+	}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected scanner, got error: %v", err)
+	}
+	if err := s.TryInitialConnect(); err == nil {
+		t.Error("Expected error for a missing GPIO chip")
+	}
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestGPIOLineFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.GPIOConfig
+		expected uint64
+	}{
+		{"Default", config.GPIOConfig{}, gpioV2LineFlagInput | gpioV2LineFlagEdgeRising},
+		{
+			"Pedal to ground",
+			config.GPIOConfig{ActiveLow: true, Bias: config.GPIOBiasPullUp},
+			gpioV2LineFlagInput | gpioV2LineFlagActiveLow | gpioV2LineFlagBiasPullUp | gpioV2LineFlagEdgeRising,
+		},
+		{
+			"Release",
+			config.GPIOConfig{Bias: config.GPIOBiasPullDown, Events: config.GPIOEventsRelease},
+			gpioV2LineFlagInput | gpioV2LineFlagBiasPullDown | gpioV2LineFlagEdgeFalling,
+		},
+		{
+			"Both",
+			config.GPIOConfig{Bias: config.GPIOBiasDisabled, Events: config.GPIOEventsBoth},
+			gpioV2LineFlagInput | gpioV2LineFlagBiasDisabled | gpioV2LineFlagEdgeRising | gpioV2LineFlagEdgeFalling,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if flags := gpioLineFlags(&tt.config); flags != tt.expected {
+				t.Errorf("Expected flags %#x, got %#x", tt.expected, flags)
+			}
+		})
+	}
+}
+
+func TestGPIOEventScan(t *testing.T) {
+	if scan := gpioEventScan(1); scan != GPIOPressed {
+		t.Errorf("Expected rising edge to be %s, got %s", GPIOPressed, scan)
+	}
+	if scan := gpioEventScan(gpioV2LineEventFallingEdge); scan != GPIOReleased {
+		t.Errorf("Expected falling edge to be %s, got %s", GPIOReleased, scan)
+	}
+}
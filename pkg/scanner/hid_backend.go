@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// HIDDevice is an open HID device. Read returns one input report per call.
+type HIDDevice interface {
+	Read(b []byte) (int, error)
+	Close() error
+}
+
+// HIDBackend enumerates and opens HID devices. Device details use
+// hid.DeviceInfo on every backend so matching and Home Assistant device
+// registration do not depend on the backend.
+type HIDBackend interface {
+	Name() string
+	Enumerate(vendorID, productID uint16) []hid.DeviceInfo
+	Open(info *hid.DeviceInfo) (HIDDevice, error)
+}
+
+// NewHIDBackend returns the backend selected in the scanner configuration.
+func NewHIDBackend(name string) (HIDBackend, error) {
+	switch strings.ToLower(name) {
+	case "", config.HIDBackendHidapi:
+		return hidapiBackend{}, nil
+	case config.HIDBackendHidraw:
+		return newHidrawBackend()
+	default:
+		return nil, fmt.Errorf("unknown HID backend '%s'", name)
+	}
+}
+
+// AvailableHIDBackends returns the HID backends of this build: hidapi needs
+// cgo and hidraw needs Linux.
+func AvailableHIDBackends() []string {
+	var backends []string
+	if hid.Supported() {
+		backends = append(backends, config.HIDBackendHidapi)
+	}
+	if runtime.GOOS == "linux" {
+		backends = append(backends, config.HIDBackendHidraw)
+	}
+	return backends
+}
+
+// hidapiBackend uses the hidapi library bundled with karalabe/hid.
+type hidapiBackend struct{}
+
+func (hidapiBackend) Name() string {
+	return config.HIDBackendHidapi
+}
+
+func (hidapiBackend) Enumerate(vendorID, productID uint16) []hid.DeviceInfo {
+	return hid.Enumerate(vendorID, productID)
+}
+
+func (hidapiBackend) Open(info *hid.DeviceInfo) (HIDDevice, error) {
+	return info.Open()
+}
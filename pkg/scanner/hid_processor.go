@@ -3,6 +3,7 @@ package scanner
 import (
 	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -14,6 +15,10 @@ const (
 	hidModifierShift = 0x22
 )
 
+// DefaultCompletionTimeout is how long a scan without a termination key may
+// pause before the buffered characters are published as a scan.
+const DefaultCompletionTimeout = 100 * time.Millisecond
+
 type KeyboardLayout struct {
 	Letters map[byte][2]byte
 	Numbers map[byte][2]byte
@@ -22,34 +27,88 @@ type KeyboardLayout struct {
 
 type HIDProcessor struct {
 	terminationChar string
-	keyboardLayout  string
 	buffer          []byte
 	bufferLen       int
 	onScan          func(string)
+	onRawReport     func([]byte)
 	logger          *logrus.Logger
 	lastActivity    time.Time
+	learnedLayout   *LearnedLayout
+	// reportIDPrefix is whether reports start with a report ID byte; nil
+	// detects it from the first report.
+	reportIDPrefix *bool
+	modifiers      modifierTracker
+	// completionTimeout and keyboardLayout are read by the read loop and
+	// set from Home Assistant, so they are atomic.
+	completionTimeout atomic.Int64
+	keyboardLayout    atomic.Pointer[string]
 }
 
 func NewHIDProcessor(terminationChar, keyboardLayout string, logger *logrus.Logger) *HIDProcessor {
-	return &HIDProcessor{
+	p := &HIDProcessor{
 		terminationChar: terminationChar,
-		keyboardLayout:  keyboardLayout,
 		logger:          logger,
 		buffer:          make([]byte, 256),
 		lastActivity:    time.Now(),
 	}
+	p.keyboardLayout.Store(&keyboardLayout)
+	p.completionTimeout.Store(int64(DefaultCompletionTimeout))
+	return p
 }
 
 func (p *HIDProcessor) SetOnScanCallback(callback func(string)) {
 	p.onScan = callback
 }
 
+// SetOnRawReportCallback receives every keyboard report before it is decoded.
+func (p *HIDProcessor) SetOnRawReportCallback(callback func([]byte)) {
+	p.onRawReport = callback
+}
+
+// SetLearnedLayout enables per-scanner overrides and recording of unmapped keycodes.
+func (p *HIDProcessor) SetLearnedLayout(learned *LearnedLayout) {
+	p.learnedLayout = learned
+}
+
+// SetReportIDPrefix sets whether reports start with a report ID byte that
+// shifts the modifier and key offsets. nil detects it from the first report.
+func (p *HIDProcessor) SetReportIDPrefix(prefix *bool) {
+	p.reportIDPrefix = prefix
+}
+
+// stripReportID removes the report ID byte, so the raw report callback and
+// the decoding always see the boot keyboard layout.
+func (p *HIDProcessor) stripReportID(data []byte) []byte {
+	if p.reportIDPrefix == nil {
+		detected := looksReportIDPrefixed(data)
+		p.reportIDPrefix = &detected
+		if detected {
+			p.logger.WithField("report_id", data[0]).Info("Scanner reports carry a report ID, skipping it when decoding")
+		}
+	}
+	if *p.reportIDPrefix && len(data) > 0 {
+		return data[1:]
+	}
+	return data
+}
+
+// SetModifierMode sets how modifiers are tracked across reports: "report"
+// (default), "held" or "standalone".
+func (p *HIDProcessor) SetModifierMode(mode string) {
+	p.modifiers.mode = strings.ToLower(mode)
+}
+
 func (p *HIDProcessor) ProcessData(data []byte) {
+	data = p.stripReportID(data)
+	if p.onRawReport != nil {
+		p.onRawReport(data)
+	}
+
 	if len(data) < 3 {
 		return
 	}
 
-	modifier := data[0]
+	modifier := p.modifiers.modifiers(data)
 
 	for i := 2; i < min(len(data), 8); i++ {
 		keyCode := data[i]
@@ -70,15 +129,40 @@ func (p *HIDProcessor) ProcessData(data []byte) {
 	}
 }
 
+// SetCompletionTimeout sets how long a scan may pause before it is complete.
+// It is safe to call while the scanner is reading.
+func (p *HIDProcessor) SetCompletionTimeout(timeout time.Duration) {
+	p.completionTimeout.Store(int64(timeout))
+}
+
+func (p *HIDProcessor) CompletionTimeout() time.Duration {
+	return time.Duration(p.completionTimeout.Load())
+}
+
+// SetKeyboardLayout switches the layout keycodes are decoded with. It is safe
+// to call while the scanner is reading.
+func (p *HIDProcessor) SetKeyboardLayout(layout string) {
+	p.keyboardLayout.Store(&layout)
+}
+
+func (p *HIDProcessor) KeyboardLayout() string {
+	return *p.keyboardLayout.Load()
+}
+
+// Pending reports whether characters of an incomplete scan are buffered.
+func (p *HIDProcessor) Pending() bool {
+	return p.bufferLen > 0
+}
+
 func (p *HIDProcessor) CheckTimeout() {
-	const timeout = 100 * time.Millisecond
-	if p.bufferLen > 0 && time.Since(p.lastActivity) > timeout {
+	if p.bufferLen > 0 && time.Since(p.lastActivity) > p.CompletionTimeout() {
 		p.finalizeInput()
 	}
 }
 
 func (p *HIDProcessor) Reset() {
 	p.bufferLen = 0
+	p.modifiers.reset()
 }
 
 func (p *HIDProcessor) finalizeInput() {
@@ -110,9 +194,10 @@ func (p *HIDProcessor) isTerminationKey(keyCode byte) bool {
 }
 
 func (p *HIDProcessor) keyCodeToChar(keyCode, modifier byte) byte {
-	layout, err := GetKeyboardLayout(p.keyboardLayout)
+	layoutName := p.KeyboardLayout()
+	layout, err := GetKeyboardLayout(layoutName)
 	if err != nil {
-		p.logger.WithError(err).Warnf("Failed to load keyboard layout '%s', using US fallback", p.keyboardLayout)
+		p.logger.WithError(err).Warnf("Failed to load keyboard layout '%s', using US fallback", layoutName)
 		layout, _ = GetKeyboardLayout("us")
 	}
 
@@ -122,6 +207,12 @@ func (p *HIDProcessor) keyCodeToChar(keyCode, modifier byte) byte {
 		return 0
 	}
 
+	if p.learnedLayout != nil {
+		if char, exists := p.learnedLayout.Lookup(keyCode, shifted); exists {
+			return char
+		}
+	}
+
 	if chars, exists := layout.Letters[keyCode]; exists {
 		if shifted {
 			return chars[1]
@@ -146,5 +237,36 @@ func (p *HIDProcessor) keyCodeToChar(keyCode, modifier byte) byte {
 		return chars[0]
 	}
 
+	p.recordUnmapped(keyCode, shifted)
 	return 0
 }
+
+func (p *HIDProcessor) recordUnmapped(keyCode byte, shifted bool) {
+	if p.learnedLayout == nil {
+		return
+	}
+
+	sample := string(p.buffer[:p.bufferLen])
+	if err := p.learnedLayout.RecordUnmapped(keyCode, shifted, sample); err != nil {
+		p.logger.WithError(err).Error("Failed to record unmapped keycode")
+		return
+	}
+	p.logger.WithField("keycode", keyCode).Debug("Unmapped keycode recorded for confirmation")
+}
+
+// DetectTerminationChar returns the termination_char matching the keyboard
+// reports of a test scan decoded with termination "none": "enter" or "tab"
+// when the scanner sent that key, otherwise "none".
+func DetectTerminationChar(reports [][]byte) string {
+	for _, report := range reports {
+		for i := 2; i < min(len(report), 8); i++ {
+			switch report[i] {
+			case hidKeyEnter:
+				return "enter"
+			case hidKeyTab:
+				return "tab"
+			}
+		}
+	}
+	return "none"
+}
@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -19,8 +20,8 @@ func TestNewHIDProcessor(t *testing.T) {
 		t.Errorf("Expected termination char 'enter', got %s", processor.terminationChar)
 	}
 
-	if processor.keyboardLayout != "us" {
-		t.Errorf("Expected keyboard layout 'us', got %s", processor.keyboardLayout)
+	if processor.KeyboardLayout() != "us" {
+		t.Errorf("Expected keyboard layout 'us', got %s", processor.KeyboardLayout())
 	}
 
 	if processor.logger != logger {
@@ -34,8 +35,8 @@ func TestHIDProcessor_EmptyKeyboardLayout(t *testing.T) {
 	// Test with empty keyboard layout - should store as empty (defaulting happens in config)
 	processor := NewHIDProcessor("tab", "", logger)
 
-	if processor.keyboardLayout != "" {
-		t.Errorf("Expected empty keyboard layout to be stored as empty, got %s", processor.keyboardLayout)
+	if processor.KeyboardLayout() != "" {
+		t.Errorf("Expected empty keyboard layout to be stored as empty, got %s", processor.KeyboardLayout())
 	}
 }
 
@@ -89,8 +90,87 @@ func TestHIDProcessor_KeyboardLayoutOptions(t *testing.T) {
 	for _, layout := range layouts {
 		processor := NewHIDProcessor("enter", layout, logger)
 
-		if processor.keyboardLayout != layout {
-			t.Errorf("Expected keyboard layout '%s', got '%s'", layout, processor.keyboardLayout)
+		if processor.KeyboardLayout() != layout {
+			t.Errorf("Expected keyboard layout '%s', got '%s'", layout, processor.KeyboardLayout())
 		}
 	}
 }
+
+func TestHIDProcessor_SetKeyboardLayout(t *testing.T) {
+	processor := NewHIDProcessor("enter", "us", logrus.New())
+
+	var scans []string
+	processor.SetOnScanCallback(func(barcode string) { scans = append(scans, barcode) })
+
+	slash := []byte{0x00, 0x00, 0x38, 0x00, 0x00, 0x00, 0x00, 0x00}
+	enter := []byte{0x00, 0x00, 0x28, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	processor.ProcessData(slash)
+	processor.ProcessData(enter)
+	processor.SetKeyboardLayout("es")
+	processor.ProcessData(slash)
+	processor.ProcessData(enter)
+
+	if processor.KeyboardLayout() != "es" {
+		t.Errorf("Expected keyboard layout 'es', got '%s'", processor.KeyboardLayout())
+	}
+	if len(scans) != 2 || scans[0] != "/" || scans[1] != "-" {
+		t.Errorf("Expected the switched layout to apply to the next scan, got %v", scans)
+	}
+}
+
+func TestHIDProcessor_CompletionTimeout(t *testing.T) {
+	processor := NewHIDProcessor("none", "us", logrus.New())
+
+	var scans []string
+	processor.SetOnScanCallback(func(barcode string) { scans = append(scans, barcode) })
+
+	if processor.CompletionTimeout() != DefaultCompletionTimeout {
+		t.Errorf("Expected default completion timeout %s, got %s", DefaultCompletionTimeout, processor.CompletionTimeout())
+	}
+
+	processor.SetCompletionTimeout(500 * time.Millisecond)
+	processor.ProcessData([]byte{0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00})
+	if !processor.Pending() {
+		t.Error("Expected the incomplete scan to be pending")
+	}
+
+	processor.lastActivity = time.Now().Add(-200 * time.Millisecond)
+	processor.CheckTimeout()
+	if len(scans) != 0 {
+		t.Errorf("Expected no scan before the completion timeout, got %v", scans)
+	}
+
+	processor.lastActivity = time.Now().Add(-600 * time.Millisecond)
+	processor.CheckTimeout()
+	if len(scans) != 1 || scans[0] != "a" {
+		t.Errorf("Expected scan 'a' after the completion timeout, got %v", scans)
+	}
+	if processor.Pending() {
+		t.Error("Expected nothing pending after the scan completed")
+	}
+}
+
+func TestDetectTerminationChar(t *testing.T) {
+	keyA := []byte{0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}
+	release := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	tests := []struct {
+		name     string
+		reports  [][]byte
+		expected string
+	}{
+		{"Enter", [][]byte{keyA, release, {0x00, 0x00, hidKeyEnter}, release}, "enter"},
+		{"Tab", [][]byte{keyA, release, {0x00, 0x00, hidKeyTab}, release}, "tab"},
+		{"None", [][]byte{keyA, release}, "none"},
+		{"Short report", [][]byte{{0x00}}, "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectTerminationChar(tt.reports); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
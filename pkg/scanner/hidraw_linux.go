@@ -0,0 +1,157 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/karalabe/hid"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const hidrawClassPath = "/sys/class/hidraw"
+
+// hidrawBackend talks to the kernel hidraw interface directly, without libusb.
+// The kernel keeps its HID driver bound, so unlike hidapi/libusb it also sees
+// Bluetooth and I2C HID devices.
+type hidrawBackend struct {
+	classPath string
+}
+
+func newHidrawBackend() (HIDBackend, error) {
+	if _, err := os.Stat(hidrawClassPath); err != nil {
+		return nil, fmt.Errorf("hidraw is not available (is the hidraw kernel module loaded?): %w", err)
+	}
+	return &hidrawBackend{classPath: hidrawClassPath}, nil
+}
+
+func (b *hidrawBackend) Name() string {
+	return config.HIDBackendHidraw
+}
+
+func (b *hidrawBackend) Enumerate(vendorID, productID uint16) []hid.DeviceInfo {
+	entries, err := os.ReadDir(b.classPath)
+	if err != nil {
+		return nil
+	}
+
+	var devices []hid.DeviceInfo
+	for _, entry := range entries {
+		info, err := b.readDeviceInfo(entry.Name())
+		if err != nil {
+			continue
+		}
+		if (vendorID != 0 && info.VendorID != vendorID) || (productID != 0 && info.ProductID != productID) {
+			continue
+		}
+		devices = append(devices, *info)
+	}
+	return devices
+}
+
+func (b *hidrawBackend) Open(info *hid.DeviceInfo) (HIDDevice, error) {
+	// Character devices are registered with the runtime poller, so Close
+	// unblocks a pending Read.
+	file, err := os.Open(info.Path) // #nosec G304 - hidraw node from sysfs enumeration
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", info.Path, err)
+	}
+	return file, nil
+}
+
+// ReportDescriptor reads the HID report descriptor of a hidraw node.
+func (b *hidrawBackend) ReportDescriptor(info *hid.DeviceInfo) ([]byte, error) {
+	name := filepath.Base(info.Path)
+	return os.ReadFile(filepath.Join(b.classPath, name, "device", "report_descriptor")) // #nosec G304 - sysfs attribute
+}
+
+// readDeviceInfo describes a hidraw node from sysfs. USB details come from
+// the interface and device directories above the HID device, matching what
+// hidapi reports.
+func (b *hidrawBackend) readDeviceInfo(name string) (*hid.DeviceInfo, error) {
+	hidDevice, err := filepath.EvalSymlinks(filepath.Join(b.classPath, name, "device"))
+	if err != nil {
+		return nil, err
+	}
+
+	uevent, err := readUevent(filepath.Join(hidDevice, "uevent"))
+	if err != nil {
+		return nil, err
+	}
+
+	vendorID, productID, err := parseHIDID(uevent["HID_ID"])
+	if err != nil {
+		return nil, err
+	}
+
+	info := &hid.DeviceInfo{
+		Path:      filepath.Join("/dev", name),
+		VendorID:  vendorID,
+		ProductID: productID,
+		Product:   uevent["HID_NAME"],
+		Serial:    uevent["HID_UNIQ"],
+		Interface: -1,
+	}
+
+	usbInterface := filepath.Dir(hidDevice)
+	interfaceNumber, err := strconv.ParseUint(readSysfsString(filepath.Join(usbInterface, "bInterfaceNumber")), 16, 8)
+	if err != nil {
+		return info, nil // Not a USB device
+	}
+	info.Interface = int(interfaceNumber)
+
+	usbDevice := filepath.Dir(usbInterface)
+	info.Manufacturer = readSysfsString(filepath.Join(usbDevice, "manufacturer"))
+	if product := readSysfsString(filepath.Join(usbDevice, "product")); product != "" {
+		info.Product = product
+	}
+	if serial := readSysfsString(filepath.Join(usbDevice, "serial")); serial != "" {
+		info.Serial = serial
+	}
+	if release, err := readSysfsHex(filepath.Join(usbDevice, "bcdDevice")); err == nil {
+		info.Release = release
+	}
+
+	return info, nil
+}
+
+func readUevent(path string) (map[string]string, error) {
+	file, err := os.Open(path) // #nosec G304 - sysfs attribute
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			values[key] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+// parseHIDID parses the HID_ID uevent value "<bus>:<vendor>:<product>", e.g.
+// "0003:0000060E:000016C7".
+func parseHIDID(value string) (vendorID, productID uint16, err error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, 0, fmt.Errorf("invalid HID_ID '%s'", value)
+	}
+
+	vendor, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid HID_ID '%s': %w", value, err)
+	}
+	product, err := strconv.ParseUint(parts[2], 16, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid HID_ID '%s': %w", value, err)
+	}
+
+	return uint16(vendor), uint16(product), nil // #nosec G115 - USB IDs are 16 bit
+}
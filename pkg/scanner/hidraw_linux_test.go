@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/karalabe/hid"
+)
+
+func writeSysfsFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestHidrawEnumerate(t *testing.T) {
+	root := t.TempDir()
+	usbDevice := filepath.Join(root, "devices", "usb1", "1-1")
+	usbInterface := filepath.Join(usbDevice, "1-1:1.0")
+	hidDevice := filepath.Join(usbInterface, "0003:060E:16C7.0001")
+	bluetoothDevice := filepath.Join(root, "devices", "virtual", "0005:05AC:0250.0002")
+
+	writeSysfsFile(t, filepath.Join(usbDevice, "manufacturer"), "Acme\n")
+	writeSysfsFile(t, filepath.Join(usbDevice, "product"), "Barcode Scanner\n")
+	writeSysfsFile(t, filepath.Join(usbDevice, "serial"), "ABC123\n")
+	writeSysfsFile(t, filepath.Join(usbDevice, "bcdDevice"), "0102\n")
+	writeSysfsFile(t, filepath.Join(usbInterface, "bInterfaceNumber"), "01\n")
+	writeSysfsFile(t, filepath.Join(hidDevice, "uevent"), "HID_ID=0003:0000060E:000016C7\nHID_NAME=Acme Scanner\n")
+	writeSysfsFile(t, filepath.Join(bluetoothDevice, "uevent"),
+		"HID_ID=0005:000005AC:00000250\nHID_NAME=BT Scanner\nHID_UNIQ=aa:bb:cc:dd:ee:ff\n")
+
+	classPath := filepath.Join(root, "class", "hidraw")
+	for name, target := range map[string]string{"hidraw0": hidDevice, "hidraw1": bluetoothDevice} {
+		if err := os.MkdirAll(filepath.Join(classPath, name), 0o750); err != nil {
+			t.Fatalf("Failed to create class dir: %v", err)
+		}
+		if err := os.Symlink(target, filepath.Join(classPath, name, "device")); err != nil {
+			t.Fatalf("Failed to create device symlink: %v", err)
+		}
+	}
+
+	backend := &hidrawBackend{classPath: classPath}
+
+	devices := backend.Enumerate(0x60e, 0x16c7)
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 USB device, got %d", len(devices))
+	}
+
+	usb := devices[0]
+	if usb.Path != "/dev/hidraw0" {
+		t.Errorf("Expected path /dev/hidraw0, got %s", usb.Path)
+	}
+	if usb.Interface != 1 {
+		t.Errorf("Expected interface 1, got %d", usb.Interface)
+	}
+	if usb.Manufacturer != "Acme" || usb.Product != "Barcode Scanner" || usb.Serial != "ABC123" {
+		t.Errorf("Expected USB strings from the device directory, got %q %q %q", usb.Manufacturer, usb.Product, usb.Serial)
+	}
+	if usb.Release != 0x0102 {
+		t.Errorf("Expected release 0x0102, got 0x%04x", usb.Release)
+	}
+
+	devices = backend.Enumerate(0, 0)
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices without filter, got %d", len(devices))
+	}
+
+	bluetooth := devices[1]
+	if bluetooth.Interface != -1 {
+		t.Errorf("Expected interface -1 for non-USB device, got %d", bluetooth.Interface)
+	}
+	if bluetooth.Product != "BT Scanner" || bluetooth.Serial != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("Expected uevent name and uniq, got %q %q", bluetooth.Product, bluetooth.Serial)
+	}
+}
+
+func TestHidrawReportDescriptor(t *testing.T) {
+	classPath := t.TempDir()
+	writeSysfsFile(t, filepath.Join(classPath, "hidraw0", "device", "report_descriptor"), "\x05\x01\x85\x01")
+
+	backend := &hidrawBackend{classPath: classPath}
+	descriptor, err := backend.ReportDescriptor(&hid.DeviceInfo{Path: "/dev/hidraw0"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !descriptorUsesReportIDs(descriptor) {
+		t.Errorf("Expected the descriptor to declare report IDs, got % x", descriptor)
+	}
+
+	if _, err := backend.ReportDescriptor(&hid.DeviceInfo{Path: "/dev/hidraw1"}); err == nil {
+		t.Error("Expected error for a missing hidraw node")
+	}
+}
+
+func TestParseHIDID(t *testing.T) {
+	tests := []struct {
+		value       string
+		vendorID    uint16
+		productID   uint16
+		expectError bool
+	}{
+		{"0003:0000060E:000016C7", 0x060e, 0x16c7, false},
+		{"0005:000005AC:00000250", 0x05ac, 0x0250, false},
+		{"0003:0000060E", 0, 0, true},
+		{"0003:XYZ:000016C7", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		vendorID, productID, err := parseHIDID(test.value)
+		if test.expectError {
+			if err == nil {
+				t.Errorf("Expected error for %q", test.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected no error for %q, got: %v", test.value, err)
+			continue
+		}
+		if vendorID != test.vendorID || productID != test.productID {
+			t.Errorf("Expected %04x:%04x for %q, got %04x:%04x", test.vendorID, test.productID, test.value, vendorID, productID)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package scanner
+
+import "errors"
+
+func newHidrawBackend() (HIDBackend, error) {
+	return nil, errors.New("the hidraw backend is only available on Linux")
+}
@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/karalabe/hid"
+)
+
+// IDGenerator returns the ID of a new scanner from its display name and
+// device, before it is made unique among the configured scanners.
+type IDGenerator func(name string, device *hid.DeviceInfo) string
+
+var templateIDPattern = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// NewIDGenerator returns GenerateScannerID for an empty template, and
+// otherwise expands the config.ScannerIDPlaceholders of the template. The
+// result is lowercased, other characters than letters, digits, "_" and "-"
+// become "_", and separators left by empty placeholders are trimmed.
+func NewIDGenerator(template, instance string) IDGenerator {
+	if template == "" {
+		return GenerateScannerID
+	}
+
+	return func(name string, device *hid.DeviceInfo) string {
+		id := strings.NewReplacer(
+			"{name}", idComponent(name),
+			"{serial}", idComponent(device.Serial),
+			"{interface}", strconv.Itoa(device.Interface),
+			"{vendor_id}", fmt.Sprintf("%04x", device.VendorID),
+			"{product_id}", fmt.Sprintf("%04x", device.ProductID),
+			"{instance}", idComponent(instance),
+		).Replace(template)
+
+		id = strings.Trim(templateIDPattern.ReplaceAllString(strings.ToLower(id), "_"), "_-")
+		if id == "" {
+			return GenerateScannerID(name, device)
+		}
+		return id
+	}
+}
+
+func idComponent(value string) string {
+	return strings.Trim(scannerIDPattern.ReplaceAllString(strings.ToLower(value), "_"), "_")
+}
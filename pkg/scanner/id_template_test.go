@@ -0,0 +1,31 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/karalabe/hid"
+)
+
+func TestNewIDGenerator(t *testing.T) {
+	device := hid.DeviceInfo{VendorID: 0x0c2e, ProductID: 0x0b61, Serial: "AB-12", Interface: 2}
+
+	tests := []struct {
+		template string
+		device   hid.DeviceInfo
+		expected string
+	}{
+		{"", device, "honeywell_voyager_2_ab_12"},
+		{"{instance}-lane-{serial}", device, "shop_1-lane-ab_12"},
+		{"{vendor_id}_{product_id}_{interface}", device, "0c2e_0b61_2"},
+		{"Dock {name}", device, "dock_honeywell_voyager"},
+		{"lane-{serial}", hid.DeviceInfo{}, "lane"},
+		{"{serial}", hid.DeviceInfo{}, "honeywell_voyager"},
+	}
+
+	for _, tt := range tests {
+		generateID := NewIDGenerator(tt.template, "Shop 1")
+		if id := generateID("Honeywell Voyager", &tt.device); id != tt.expected {
+			t.Errorf("Expected ID %q for template %q, got %q", tt.expected, tt.template, id)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package scanner
+
+import "time"
+
+const (
+	DefaultKeyhookMaxKeyInterval = 30 * time.Millisecond
+	DefaultKeyhookMinKeys        = 4
+)
+
+// PC scan codes (set 1) of the keys the keyboard hook reports with the
+// extended flag, mapped to Linux input event key codes. Codes without the
+// flag are the same in both.
+var keyhookExtendedScanCodes = map[uint32]uint16{
+	0x1c: 96,  // KEY_KPENTER
+	0x1d: 97,  // KEY_RIGHTCTRL
+	0x35: 98,  // KEY_KPSLASH
+	0x38: 100, // KEY_RIGHTALT
+	0x5b: 125, // KEY_LEFTMETA
+	0x5c: 126, // KEY_RIGHTMETA
+}
+
+// keyhookKeyCode returns the Linux input event key code of a key reported by
+// the keyboard hook, so hook events go through the evdev translator.
+func keyhookKeyCode(scanCode uint32, extended bool) uint16 {
+	if extended {
+		return keyhookExtendedScanCodes[scanCode]
+	}
+	if scanCode > 0xff {
+		return 0
+	}
+	return uint16(scanCode)
+}
+
+// keyBurstFilter tells scans apart from typing on a shared system keyboard.
+// Key reports are held until minKeys of them arrived at most maxInterval
+// apart; the rest of such a burst is passed on as it arrives. Slower or
+// shorter input is dropped.
+type keyBurstFilter struct {
+	maxInterval time.Duration
+	minKeys     int
+
+	pending [][]byte
+	passing bool
+	last    time.Time
+}
+
+func newKeyBurstFilter(maxInterval time.Duration, minKeys int) *keyBurstFilter {
+	if maxInterval <= 0 {
+		maxInterval = DefaultKeyhookMaxKeyInterval
+	}
+	if minKeys <= 0 {
+		minKeys = DefaultKeyhookMinKeys
+	}
+	return &keyBurstFilter{maxInterval: maxInterval, minKeys: minKeys}
+}
+
+// add returns the reports to decode once the key report arrived at the given
+// time, which are none while a burst is not yet known to be a scan.
+func (f *keyBurstFilter) add(report []byte, at time.Time) [][]byte {
+	if !f.last.IsZero() && at.Sub(f.last) > f.maxInterval {
+		f.reset()
+	}
+	f.last = at
+
+	if f.passing {
+		return [][]byte{report}
+	}
+
+	f.pending = append(f.pending, report)
+	if len(f.pending) < f.minKeys {
+		return nil
+	}
+
+	reports := f.pending
+	f.pending = nil
+	f.passing = true
+	return reports
+}
+
+// reset drops the burst in progress, e.g. when the focus moved to a window
+// scans are not captured from.
+func (f *keyBurstFilter) reset() {
+	f.pending = nil
+	f.passing = false
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func NewKeyhookScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	return nil, fmt.Errorf("scanner %s: the keyhook driver is only available on Windows", cfg.ID)
+}
@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyhookKeyCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		scanCode uint32
+		extended bool
+		expected uint16
+	}{
+		{"Letter A", 0x1e, false, evdevKeyCodeKeyA},
+		{"Enter", 0x1c, false, 28},
+		{"Keypad enter", 0x1c, true, 96},
+		{"Right alt", 0x38, true, 100},
+		{"Unknown extended key", 0x47, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := keyhookKeyCode(tt.scanCode, tt.extended); code != tt.expected {
+				t.Errorf("Expected key code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestKeyBurstFilter(t *testing.T) {
+	filter := newKeyBurstFilter(0, 3)
+	start := time.Now()
+	key := func(usage byte) []byte { return []byte{0, 0, usage, 0, 0, 0, 0, 0} }
+
+	// Typing: keys further apart than the interval are dropped
+	for i := range 5 {
+		if reports := filter.add(key(0x04), start.Add(time.Duration(i)*200*time.Millisecond)); reports != nil {
+			t.Fatalf("Expected typed keys to be dropped, got %v", reports)
+		}
+	}
+
+	// A scan: held until min_keys arrived, then passed on as it arrives
+	scanStart := start.Add(2 * time.Second)
+	if reports := filter.add(key(0x1e), scanStart); reports != nil {
+		t.Errorf("Expected the first key of a burst to be held, got %v", reports)
+	}
+	filter.add(key(0x1f), scanStart.Add(5*time.Millisecond))
+	if reports := filter.add(key(0x20), scanStart.Add(10*time.Millisecond)); len(reports) != 3 || reports[0][2] != 0x1e {
+		t.Errorf("Expected the held keys once the burst is a scan, got %v", reports)
+	}
+	if reports := filter.add(key(0x28), scanStart.Add(15*time.Millisecond)); len(reports) != 1 || reports[0][2] != 0x28 {
+		t.Errorf("Expected the rest of the scan to pass, got %v", reports)
+	}
+
+	// A pause ends the scan
+	if reports := filter.add(key(0x04), scanStart.Add(time.Second)); reports != nil {
+		t.Errorf("Expected a key after a pause to be held, got %v", reports)
+	}
+
+	filter.reset()
+	filter.add(key(0x1e), scanStart.Add(time.Second+5*time.Millisecond))
+	if reports := filter.add(key(0x1f), scanStart.Add(time.Second+10*time.Millisecond)); reports != nil {
+		t.Errorf("Expected reset to drop the burst in progress, got %v", reports)
+	}
+}
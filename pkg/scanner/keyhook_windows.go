@@ -0,0 +1,266 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	whKeyboardLL  = 13
+	wmQuit        = 0x0012
+	wmKeyUp       = 0x0101
+	wmSysKeyUp    = 0x0105
+	llkhfExtended = 0x01
+	llkhfInjected = 0x10
+)
+
+var (
+	user32                  = windows.NewLazySystemDLL("user32.dll")
+	procSetWindowsHookExW   = user32.NewProc("SetWindowsHookExW")
+	procCallNextHookEx      = user32.NewProc("CallNextHookEx")
+	procUnhookWindowsHookEx = user32.NewProc("UnhookWindowsHookEx")
+	procGetMessageW         = user32.NewProc("GetMessageW")
+	procPostThreadMessageW  = user32.NewProc("PostThreadMessageW")
+	procGetWindowTextW      = user32.NewProc("GetWindowTextW")
+)
+
+// kbdllHookStruct mirrors KBDLLHOOKSTRUCT.
+type kbdllHookStruct struct {
+	VkCode    uint32
+	ScanCode  uint32
+	Flags     uint32
+	Time      uint32
+	ExtraInfo uintptr
+}
+
+// windowsMsg mirrors MSG.
+type windowsMsg struct {
+	Hwnd     uintptr
+	Message  uint32
+	WParam   uintptr
+	LParam   uintptr
+	Time     uint32
+	X, Y     int32
+	LPrivate uint32
+}
+
+type keyhookEvent struct {
+	scanCode uint32
+	extended bool
+	down     bool
+	at       time.Time
+}
+
+var (
+	// The hook procedure is process-wide, so it hands key events to the
+	// single running keyhook scanner.
+	keyhookEvents atomic.Pointer[chan keyhookEvent]
+	keyhookProc   = windows.NewCallback(keyhookCallback)
+)
+
+func keyhookCallback(code, wParam, lParam uintptr) uintptr {
+	if int32(code) >= 0 { // #nosec G115 - nCode is a C int
+		if events := keyhookEvents.Load(); events != nil {
+			info := *(**kbdllHookStruct)(unsafe.Pointer(&lParam))
+			// Keystrokes sent by software, e.g. remote desktop tools, are not scans
+			if info.Flags&llkhfInjected == 0 {
+				event := keyhookEvent{
+					scanCode: info.ScanCode,
+					extended: info.Flags&llkhfExtended != 0,
+					down:     wParam != wmKeyUp && wParam != wmSysKeyUp,
+					at:       time.Now(),
+				}
+				select {
+				case *events <- event:
+				default:
+				}
+			}
+		}
+	}
+	ret, _, _ := procCallNextHookEx.Call(0, code, wParam, lParam)
+	return ret
+}
+
+// KeyhookScanner captures scans typed by a scanner acting as the system
+// keyboard through a low-level keyboard hook, for setups without access to
+// the scanner's HID interface. Keystrokes still reach the focused window.
+type KeyhookScanner struct {
+	baseScanner
+	config       *config.ScannerConfig
+	hidProcessor *HIDProcessor
+	windowTitle  *regexp.Regexp
+}
+
+func NewKeyhookScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	s := &KeyhookScanner{
+		baseScanner:  newBaseScanner(logger),
+		config:       cfg,
+		hidProcessor: NewHIDProcessor(cfg.TerminationChar, cfg.KeyboardLayout, logger),
+	}
+	if cfg.Keyhook.WindowTitle != "" {
+		pattern, err := regexp.Compile(cfg.Keyhook.WindowTitle)
+		if err != nil {
+			return nil, fmt.Errorf("scanner %s: invalid keyhook.window_title: %w", cfg.ID, err)
+		}
+		s.windowTitle = pattern
+	}
+	s.hidProcessor.SetOnScanCallback(s.emitScan)
+	return s, nil
+}
+
+func (s *KeyhookScanner) SetLearnedLayout(learned *LearnedLayout) {
+	s.hidProcessor.SetLearnedLayout(learned)
+}
+
+func (s *KeyhookScanner) SetOnRawReportCallback(callback func([]byte)) {
+	s.hidProcessor.SetOnRawReportCallback(callback)
+}
+
+func (s *KeyhookScanner) SetCompletionTimeout(timeout time.Duration) {
+	s.hidProcessor.SetCompletionTimeout(timeout)
+}
+
+func (s *KeyhookScanner) CompletionTimeout() time.Duration {
+	return s.hidProcessor.CompletionTimeout()
+}
+
+func (s *KeyhookScanner) SetKeyboardLayout(layout string) {
+	s.hidProcessor.SetKeyboardLayout(layout)
+}
+
+func (s *KeyhookScanner) KeyboardLayout() string {
+	return s.hidProcessor.KeyboardLayout()
+}
+
+func (s *KeyhookScanner) Start() error {
+	go s.runConnectionLoop(s.session)
+	s.logger.Debug("Keyhook scanner started successfully")
+	return nil
+}
+
+func (s *KeyhookScanner) Stop() error {
+	s.cancel()
+	s.logger.Debug("Keyhook scanner stopped")
+	return nil
+}
+
+func (s *KeyhookScanner) TryInitialConnect() error {
+	return procSetWindowsHookExW.Find()
+}
+
+func (s *KeyhookScanner) session() error {
+	events := make(chan keyhookEvent, 256)
+	if !keyhookEvents.CompareAndSwap(nil, &events) {
+		return errors.New("another keyboard hook is already running")
+	}
+	defer keyhookEvents.Store(nil)
+
+	started := make(chan uint32, 1)
+	done := make(chan error, 1)
+	go runKeyboardHook(started, done)
+
+	var threadID uint32
+	select {
+	case threadID = <-started:
+	case err := <-done:
+		s.reportOpenFailure(err)
+		return err
+	}
+	defer func() { _, _, _ = procPostThreadMessageW.Call(uintptr(threadID), wmQuit, 0, 0) }()
+
+	s.setConnected(&hid.DeviceInfo{Path: config.DriverKeyhook, Product: "Keyboard Hook"})
+	s.logger.Debug("Installed the keyboard hook")
+
+	return s.readLoop(events, done)
+}
+
+func (s *KeyhookScanner) readLoop(events <-chan keyhookEvent, done <-chan error) error {
+	pacer := newReadPacer()
+	defer pacer.Stop()
+
+	translator := &evdevTranslator{}
+	bursts := newKeyBurstFilter(s.config.Keyhook.MaxKeyInterval, s.config.Keyhook.MinKeys)
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-pacer.C():
+			s.hidProcessor.CheckTimeout()
+			pacer.Checked(s.hidProcessor.Pending())
+		case event := <-events:
+			value := int32(evdevKeyRelease)
+			if event.down {
+				value = evdevKeyPress
+			}
+			// Modifiers are tracked in every window so Shift held while the
+			// focus changes is not lost.
+			report := translator.translate(evdevTypeKey, keyhookKeyCode(event.scanCode, event.extended), value)
+			if report == nil {
+				continue
+			}
+			if s.windowTitle != nil && !s.windowTitle.MatchString(foregroundWindowTitle()) {
+				bursts.reset()
+				continue
+			}
+			for _, report := range bursts.add(report, event.at) {
+				s.hidProcessor.ProcessData(report)
+			}
+			pacer.Activity()
+		case err := <-done:
+			return fmt.Errorf("keyboard hook ended: %w", err)
+		}
+	}
+}
+
+// runKeyboardHook installs the hook and runs the message loop its callbacks
+// are delivered through, on a thread of its own, until WM_QUIT is posted to
+// the thread ID sent on started.
+func runKeyboardHook(started chan<- uint32, done chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hook, _, err := procSetWindowsHookExW.Call(whKeyboardLL, keyhookProc, 0, 0)
+	if hook == 0 {
+		done <- fmt.Errorf("failed to install keyboard hook: %w", err)
+		return
+	}
+	defer func() { _, _, _ = procUnhookWindowsHookEx.Call(hook) }()
+
+	started <- windows.GetCurrentThreadId()
+
+	var msg windowsMsg
+	for {
+		ret, _, err := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		switch int32(ret) { // #nosec G115 - GetMessage returns a BOOL
+		case -1:
+			done <- fmt.Errorf("keyboard hook message loop failed: %w", err)
+			return
+		case 0:
+			done <- nil
+			return
+		}
+	}
+}
+
+func foregroundWindowTitle() string {
+	hwnd := windows.GetForegroundWindow()
+	if hwnd == 0 {
+		return ""
+	}
+
+	buffer := make([]uint16, 256)
+	length, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)))
+	return windows.UTF16ToString(buffer[:length])
+}
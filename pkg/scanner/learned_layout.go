@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LearnedLayoutFile is the on-disk format of a per-scanner learned overrides
+// file. Keys uses the same [normal, shifted] pairs as the layout YAML files.
+type LearnedLayoutFile struct {
+	Keys    map[uint8][2]string `yaml:"keys,omitempty"`
+	Pending []PendingKey        `yaml:"pending,omitempty"`
+}
+
+// PendingKey is an unmapped keycode waiting for the user to confirm the
+// intended character.
+type PendingKey struct {
+	KeyCode uint8  `yaml:"keycode"`
+	Shifted bool   `yaml:"shifted"`
+	Sample  string `yaml:"sample,omitempty"` // Text decoded before the key in the first scan that hit it
+	Seen    int    `yaml:"seen"`
+}
+
+// LearnedLayout overrides the keyboard layout of one scanner with characters
+// confirmed by the user and records unmapped keycodes for confirmation.
+type LearnedLayout struct {
+	path  string
+	file  LearnedLayoutFile
+	mutex sync.Mutex
+}
+
+// LoadLearnedLayout reads a learned overrides file. A missing file is an empty
+// layout; it is created when the first unmapped keycode is recorded.
+func LoadLearnedLayout(path string) (*LearnedLayout, error) {
+	learned := &LearnedLayout{path: path}
+
+	data, err := os.ReadFile(path) // #nosec G304 - path from config
+	if errors.Is(err, os.ErrNotExist) {
+		return learned, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read learned layout %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, &learned.file); err != nil {
+		return nil, fmt.Errorf("failed to parse learned layout %s: %w", path, err)
+	}
+	return learned, nil
+}
+
+// Lookup returns the confirmed character for a keycode, if any.
+func (l *LearnedLayout) Lookup(keyCode byte, shifted bool) (byte, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	chars, exists := l.file.Keys[keyCode]
+	if !exists {
+		return 0, false
+	}
+
+	char := chars[0]
+	if shifted {
+		char = chars[1]
+	}
+	if char == "" {
+		return 0, false
+	}
+	return char[0], true
+}
+
+// RecordUnmapped adds a keycode to the pending list. The file is only written
+// when a new keycode shows up, not on every repeated occurrence.
+func (l *LearnedLayout) RecordUnmapped(keyCode byte, shifted bool, sample string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	index := slices.IndexFunc(l.file.Pending, func(key PendingKey) bool {
+		return key.KeyCode == keyCode && key.Shifted == shifted
+	})
+	if index >= 0 {
+		l.file.Pending[index].Seen++
+		return nil
+	}
+
+	l.file.Pending = append(l.file.Pending, PendingKey{KeyCode: keyCode, Shifted: shifted, Sample: sample, Seen: 1})
+	return l.saveLocked()
+}
+
+// Pending returns the keycodes awaiting confirmation.
+func (l *LearnedLayout) Pending() []PendingKey {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return slices.Clone(l.file.Pending)
+}
+
+// Confirm stores the intended character for a pending keycode and removes it
+// from the pending list.
+func (l *LearnedLayout) Confirm(keyCode byte, shifted bool, char string) error {
+	if len(char) != 1 {
+		return fmt.Errorf("expected a single character, got %q", char)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file.Keys == nil {
+		l.file.Keys = make(map[uint8][2]string)
+	}
+	chars := l.file.Keys[keyCode]
+	if shifted {
+		chars[1] = char
+	} else {
+		chars[0] = char
+	}
+	l.file.Keys[keyCode] = chars
+
+	l.file.Pending = slices.DeleteFunc(l.file.Pending, func(key PendingKey) bool {
+		return key.KeyCode == keyCode && key.Shifted == shifted
+	})
+	return l.saveLocked()
+}
+
+// saveLocked writes the file through a temporary file so readers never see a
+// partial write.
+func (l *LearnedLayout) saveLocked() error {
+	data, err := yaml.Marshal(&l.file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal learned layout: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".learned-layout-*")
+	if err != nil {
+		return fmt.Errorf("failed to write learned layout %s: %w", l.path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write learned layout %s: %w", l.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write learned layout %s: %w", l.path, err)
+	}
+
+	return os.Rename(tmp.Name(), l.path)
+}
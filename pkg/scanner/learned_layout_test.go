@@ -0,0 +1,68 @@
+package scanner
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLearnedLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "learned.yaml")
+
+	learned, err := LoadLearnedLayout(path)
+	if err != nil {
+		t.Fatalf("Expected missing file to load as empty layout, got: %v", err)
+	}
+
+	if err := learned.RecordUnmapped(0x64, false, "ABC"); err != nil {
+		t.Fatalf("Expected no error recording keycode, got: %v", err)
+	}
+	if err := learned.RecordUnmapped(0x64, false, "XYZ"); err != nil {
+		t.Fatalf("Expected no error recording repeated keycode, got: %v", err)
+	}
+
+	pending := learned.Pending()
+	if len(pending) != 1 {
+		t.Fatalf("Expected 1 pending key, got %d", len(pending))
+	}
+	if pending[0].Sample != "ABC" {
+		t.Errorf("Expected sample from first scan 'ABC', got '%s'", pending[0].Sample)
+	}
+
+	if _, ok := learned.Lookup(0x64, false); ok {
+		t.Error("Expected pending keycode not to be mapped before confirmation")
+	}
+
+	if err := learned.Confirm(0x64, false, "<"); err != nil {
+		t.Fatalf("Expected no error confirming keycode, got: %v", err)
+	}
+	if err := learned.Confirm(0x64, true, "too long"); err == nil {
+		t.Error("Expected error confirming more than one character")
+	}
+
+	reloaded, err := LoadLearnedLayout(path)
+	if err != nil {
+		t.Fatalf("Expected no error reloading learned layout, got: %v", err)
+	}
+	if len(reloaded.Pending()) != 0 {
+		t.Errorf("Expected no pending keys after confirmation, got %d", len(reloaded.Pending()))
+	}
+
+	tests := []struct {
+		keyCode  byte
+		shifted  bool
+		expected byte
+		ok       bool
+	}{
+		{0x64, false, '<', true},
+		{0x64, true, 0, false},
+		{0x65, false, 0, false},
+	}
+
+	for _, test := range tests {
+		char, ok := reloaded.Lookup(test.keyCode, test.shifted)
+		if ok != test.ok || char != test.expected {
+			t.Errorf("Lookup(0x%02x, %v): expected (%q, %v), got (%q, %v)",
+				test.keyCode, test.shifted, test.expected, test.ok, char, ok)
+		}
+	}
+}
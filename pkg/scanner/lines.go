@@ -0,0 +1,39 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// lineTerminatorBytes resolves a configured terminator name ("cr", "lf",
+// "crlf") to its bytes. Any other non-empty value is used literally.
+func lineTerminatorBytes(lineTerminator string) []byte {
+	switch strings.ToLower(lineTerminator) {
+	case "", config.LineTerminatorCR:
+		return []byte{'\r'}
+	case config.LineTerminatorLF:
+		return []byte{'\n'}
+	case config.LineTerminatorCRLF:
+		return []byte{'\r', '\n'}
+	default:
+		return []byte(lineTerminator)
+	}
+}
+
+// splitLines returns a bufio.SplitFunc that yields one barcode per
+// terminator. Stray CR/LF bytes around a barcode are dropped so a scanner
+// sending CRLF still works with a CR terminator.
+func splitLines(terminator []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if index := bytes.Index(data, terminator); index >= 0 {
+			return index + len(terminator), bytes.Trim(data[:index], "\r\n"), nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), bytes.Trim(data, "\r\n"), nil
+		}
+		return 0, nil, nil
+	}
+}
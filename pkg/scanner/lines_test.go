@@ -0,0 +1,41 @@
+package scanner
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		terminator string
+		input      string
+		expected   []string
+	}{
+		{"CR", "cr", "123\r456\r", []string{"123", "456"}},
+		{"LF", "lf", "123\n456\n", []string{"123", "456"}},
+		{"CRLF", "crlf", "123\r\n456\r\n", []string{"123", "456"}},
+		{"CRLF with CR terminator", "", "123\r\n456\r\n", []string{"123", "456"}},
+		{"Trailing data", "cr", "123\r456", []string{"123", "456"}},
+		{"Literal delimiter", "\x03", "123\x03456\x03", []string{"123", "456"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := bufio.NewScanner(strings.NewReader(tt.input))
+			lines.Split(splitLines(lineTerminatorBytes(tt.terminator)))
+
+			var barcodes []string
+			for lines.Scan() {
+				if lines.Text() != "" {
+					barcodes = append(barcodes, lines.Text())
+				}
+			}
+
+			if strings.Join(barcodes, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected barcodes %v, got %v", tt.expected, barcodes)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package scanner
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"slices"
 	"sync"
 	"time"
 
@@ -11,22 +14,39 @@ import (
 	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
 )
 
+const autoDiscoveryInterval = 5 * time.Second
+
 type ScannerManager struct {
-	scanners             map[string]*BarcodeScanner
+	scanners             map[string]Scanner
 	configs              []config.ScannerConfig
 	logger               *logrus.Logger
 	onScanCallback       func(scannerID, barcode string)
 	onConnectionCallback func(scannerID string, connected bool)
+	onBatteryCallback    func(scannerID string, level int)
+	onDockCallback       func(scannerID string, docked bool)
+	onErrorCallback      func(scannerID, category string, err error)
+	onDiscoveredCallback func(cfg *config.ScannerConfig)
+	autoDiscover         bool
+	generateID           IDGenerator
+	enumerate            func(vendorID, productID uint16) []hid.DeviceInfo
+	disabled             map[string]bool          // Scanners whose scans are dropped, kept across restarts of the scanner
+	completionTimeouts   map[string]time.Duration // Tuned at runtime, kept across restarts of the scanner
+	keyboardLayouts      map[string]string        // Switched at runtime, kept across restarts of the scanner
 	mutex                sync.RWMutex
 	stopCh               chan struct{}
 }
 
 func NewScannerManager(configs []config.ScannerConfig, logger *logrus.Logger) *ScannerManager {
 	return &ScannerManager{
-		scanners: make(map[string]*BarcodeScanner),
-		configs:  configs,
-		logger:   logger,
-		stopCh:   make(chan struct{}),
+		scanners:           make(map[string]Scanner),
+		disabled:           make(map[string]bool),
+		completionTimeouts: make(map[string]time.Duration),
+		keyboardLayouts:    make(map[string]string),
+		configs:            configs,
+		logger:             logger,
+		generateID:         GenerateScannerID,
+		enumerate:          hid.Enumerate,
+		stopCh:             make(chan struct{}),
 	}
 }
 
@@ -46,6 +66,36 @@ func (sm *ScannerManager) SetOnConnectionChangeCallback(callback func(scannerID
 	sm.onConnectionCallback = callback
 }
 
+func (sm *ScannerManager) SetOnBatteryLevelCallback(callback func(scannerID string, level int)) {
+	sm.onBatteryCallback = callback
+}
+
+func (sm *ScannerManager) SetOnErrorCallback(callback func(scannerID, category string, err error)) {
+	sm.onErrorCallback = callback
+}
+
+func (sm *ScannerManager) SetOnDockChangeCallback(callback func(scannerID string, docked bool)) {
+	sm.onDockCallback = callback
+}
+
+// SetAutoDiscover enables starting scanners for unconfigured devices that
+// look like barcode scanners. It must be called before Start.
+func (sm *ScannerManager) SetAutoDiscover(enabled bool) {
+	sm.autoDiscover = enabled
+}
+
+// SetIDGenerator sets how auto-discovered scanners are named. It must be
+// called before Start.
+func (sm *ScannerManager) SetIDGenerator(generateID IDGenerator) {
+	sm.generateID = generateID
+}
+
+// SetOnScannerDiscoveredCallback is called with the generated configuration
+// of an auto-discovered scanner before the scanner is started.
+func (sm *ScannerManager) SetOnScannerDiscoveredCallback(callback func(cfg *config.ScannerConfig)) {
+	sm.onDiscoveredCallback = callback
+}
+
 func (sm *ScannerManager) Start() error {
 	sm.logger.Info("Starting scanner manager...")
 
@@ -59,10 +109,104 @@ func (sm *ScannerManager) Start() error {
 		}
 	}
 
+	if sm.autoDiscover {
+		go sm.runAutoDiscovery()
+	}
+
 	sm.logger.Infof("Scanner manager started with %d active scanners", len(sm.scanners))
 	return nil
 }
 
+func (sm *ScannerManager) runAutoDiscovery() {
+	sm.logger.Info("Auto-discovery of unconfigured scanners enabled")
+
+	ticker := time.NewTicker(autoDiscoveryInterval)
+	defer ticker.Stop()
+
+	sm.discoverScanners()
+	for {
+		select {
+		case <-sm.stopCh:
+			return
+		case <-ticker.C:
+			sm.discoverScanners()
+		}
+	}
+}
+
+// discoverScanners starts a scanner for every device that matches the
+// heuristic and is not claimed by a configured or previously discovered
+// scanner. Discovered scanners are pinned to the device serial and interface,
+// so a replugged device reconnects to the same scanner ID.
+func (sm *ScannerManager) discoverScanners() {
+	for _, device := range sm.enumerate(0, 0) {
+		if !IsLikelyBarcodeScanner(&device) {
+			continue
+		}
+
+		cfg, claimed := sm.claimDiscoveredDevice(&device)
+		if !claimed {
+			continue
+		}
+
+		sm.logger.WithFields(logrus.Fields{
+			"scanner_id": cfg.ID,
+			"vendor_id":  fmt.Sprintf("%04x", device.VendorID),
+			"product_id": fmt.Sprintf("%04x", device.ProductID),
+			"interface":  device.Interface,
+			"serial":     device.Serial,
+		}).Info("Discovered unconfigured scanner")
+
+		if sm.onDiscoveredCallback != nil {
+			sm.onDiscoveredCallback(&cfg)
+		}
+		if err := sm.startScanner(&cfg); err != nil {
+			sm.logger.Errorf("Failed to start discovered scanner %s: %v", cfg.ID, err)
+		}
+	}
+}
+
+// claimDiscoveredDevice registers a configuration for a device no scanner
+// claims yet, and reports false when the device is already claimed.
+func (sm *ScannerManager) claimDiscoveredDevice(device *hid.DeviceInfo) (config.ScannerConfig, bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if sm.isDeviceClaimed(device) {
+		return config.ScannerConfig{}, false
+	}
+
+	cfg := discoveredScannerConfig(sm.uniqueScannerID(sm.generateID(DeviceDisplayName(device), device)), device)
+	sm.configs = append(sm.configs, cfg)
+	return cfg, true
+}
+
+func (sm *ScannerManager) isDeviceClaimed(device *hid.DeviceInfo) bool {
+	for i := range sm.configs {
+		if claimsDevice(&sm.configs[i], device) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sm *ScannerManager) uniqueScannerID(id string) string {
+	taken := func(candidate string) bool {
+		for _, cfg := range sm.configs {
+			if cfg.ID == candidate {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := id
+	for suffix := 2; taken(candidate); suffix++ {
+		candidate = fmt.Sprintf("%s_%d", id, suffix)
+	}
+	return candidate
+}
+
 func (sm *ScannerManager) Stop() error {
 	close(sm.stopCh)
 
@@ -76,7 +220,7 @@ func (sm *ScannerManager) Stop() error {
 		}
 	}
 
-	sm.scanners = make(map[string]*BarcodeScanner)
+	sm.scanners = make(map[string]Scanner)
 	sm.logger.Info("All scanners stopped")
 	return nil
 }
@@ -96,28 +240,138 @@ func (sm *ScannerManager) GetConnectedScanners() map[string]*hid.DeviceInfo {
 	return connected
 }
 
-func (sm *ScannerManager) GetScanner(id string) *BarcodeScanner {
+// AddScanner starts a scanner that was not part of the initial configuration.
+func (sm *ScannerManager) AddScanner(cfg config.ScannerConfig) error {
+	sm.mutex.Lock()
+	if _, exists := sm.scanners[cfg.ID]; exists {
+		sm.mutex.Unlock()
+		return fmt.Errorf("scanner %s is already running", cfg.ID)
+	}
+	sm.configs = append(sm.configs, cfg)
+	sm.mutex.Unlock()
+
+	return sm.startScanner(&cfg)
+}
+
+// RemoveScanner stops a scanner and forgets its configuration.
+func (sm *ScannerManager) RemoveScanner(id string) {
+	sm.mutex.Lock()
+	scanner := sm.scanners[id]
+	delete(sm.scanners, id)
+	sm.configs = slices.DeleteFunc(sm.configs, func(cfg config.ScannerConfig) bool {
+		return cfg.ID == id
+	})
+	sm.mutex.Unlock()
+
+	if scanner != nil {
+		if err := scanner.Stop(); err != nil {
+			sm.logger.Errorf("Error stopping scanner %s: %v", id, err)
+		}
+	}
+}
+
+func (sm *ScannerManager) GetScanner(id string) Scanner {
 	sm.mutex.RLock()
 	defer sm.mutex.RUnlock()
 	return sm.scanners[id]
 }
 
+// SetScannerEnabled stops or resumes forwarding the scans of a scanner. A
+// disabled scanner stays open, so it is enabled again without reconnecting.
+func (sm *ScannerManager) SetScannerEnabled(id string, enabled bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if enabled {
+		delete(sm.disabled, id)
+		sm.logger.WithField("scanner_id", id).Info("Scanner enabled")
+	} else {
+		sm.disabled[id] = true
+		sm.logger.WithField("scanner_id", id).Info("Scanner disabled, scans are dropped")
+	}
+}
+
+func (sm *ScannerManager) IsScannerEnabled(id string) bool {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return !sm.disabled[id]
+}
+
+// SetCompletionTimeout sets how long a scan of the scanner may pause before
+// it is complete. The timeout is applied again when the scanner restarts.
+func (sm *ScannerManager) SetCompletionTimeout(id string, timeout time.Duration) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	tuner, ok := sm.scanners[id].(CompletionTimeoutTuner)
+	if !ok {
+		return fmt.Errorf("scanner %s has no completion timeout", id)
+	}
+	tuner.SetCompletionTimeout(timeout)
+	sm.completionTimeouts[id] = timeout
+	sm.logger.WithField("scanner_id", id).Infof("Scan completion timeout set to %s", timeout)
+	return nil
+}
+
+// CompletionTimeout returns the completion timeout of a running scanner, or
+// false when its driver doesn't complete scans after a pause.
+func (sm *ScannerManager) CompletionTimeout(id string) (time.Duration, bool) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	tuner, ok := sm.scanners[id].(CompletionTimeoutTuner)
+	if !ok {
+		return 0, false
+	}
+	return tuner.CompletionTimeout(), true
+}
+
+// SetKeyboardLayout switches the keyboard layout of the scanner. The layout
+// is applied again when the scanner restarts.
+func (sm *ScannerManager) SetKeyboardLayout(id, layout string) error {
+	if !IsLayoutAvailable(layout) {
+		return fmt.Errorf("keyboard layout '%s' is not available", layout)
+	}
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	switcher, ok := sm.scanners[id].(KeyboardLayoutSwitcher)
+	if !ok {
+		return fmt.Errorf("scanner %s has no keyboard layout", id)
+	}
+	switcher.SetKeyboardLayout(layout)
+	sm.keyboardLayouts[id] = layout
+	sm.logger.WithField("scanner_id", id).Infof("Keyboard layout set to %s", layout)
+	return nil
+}
+
+// KeyboardLayout returns the keyboard layout of a running scanner, or false
+// when its driver doesn't decode keyboard reports.
+func (sm *ScannerManager) KeyboardLayout(id string) (string, bool) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	switcher, ok := sm.scanners[id].(KeyboardLayoutSwitcher)
+	if !ok {
+		return "", false
+	}
+	return switcher.KeyboardLayout(), true
+}
+
 func (sm *ScannerManager) startScanner(cfg *config.ScannerConfig) error {
 	sm.logger.Debugf("Starting scanner: %s", cfg.ID)
 
-	keyboardLayout := cfg.KeyboardLayout
-
-	scanner := NewBarcodeScannerWithInterface(
-		cfg.Identification.VendorID,
-		cfg.Identification.ProductID,
-		cfg.Identification.Serial,
-		cfg.Identification.Interface,
-		cfg.TerminationChar,
-		keyboardLayout,
-		sm.logger,
-	)
+	scanner, err := NewScanner(cfg, sm.logger)
+	if err != nil {
+		return err
+	}
 
 	scanner.SetOnScanCallback(func(barcode string) {
+		if !sm.IsScannerEnabled(cfg.ID) {
+			sm.logger.WithField("scanner_id", cfg.ID).Debug("Scanner disabled, dropping scan")
+			return
+		}
 		if sm.onScanCallback != nil {
 			sm.onScanCallback(cfg.ID, barcode)
 		}
@@ -129,7 +383,15 @@ func (sm *ScannerManager) startScanner(cfg *config.ScannerConfig) error {
 		}
 	})
 
+	sm.attachPowerCallbacks(cfg, scanner)
+
 	sm.mutex.Lock()
+	if tuner, ok := scanner.(CompletionTimeoutTuner); ok && sm.completionTimeouts[cfg.ID] > 0 {
+		tuner.SetCompletionTimeout(sm.completionTimeouts[cfg.ID])
+	}
+	if switcher, ok := scanner.(KeyboardLayoutSwitcher); ok && sm.keyboardLayouts[cfg.ID] != "" {
+		switcher.SetKeyboardLayout(sm.keyboardLayouts[cfg.ID])
+	}
 	sm.scanners[cfg.ID] = scanner
 	sm.mutex.Unlock()
 	sm.logger.Debugf("Stored scanner %s in manager before starting", cfg.ID)
@@ -145,6 +407,40 @@ func (sm *ScannerManager) startScanner(cfg *config.ScannerConfig) error {
 	return nil
 }
 
+// attachPowerCallbacks wires error and battery reporting for drivers that
+// support it.
+// Dock detection relies on the hidraw power_supply and interface layout, so it
+// is only available with the HID driver.
+func (sm *ScannerManager) attachPowerCallbacks(cfg *config.ScannerConfig, scanner Scanner) {
+	if reporter, ok := scanner.(ErrorReporter); ok {
+		reporter.SetOnErrorCallback(func(category string, err error) {
+			if sm.onErrorCallback != nil {
+				sm.onErrorCallback(cfg.ID, category, err)
+			}
+		})
+	}
+
+	if reporter, ok := scanner.(BatteryReporter); ok {
+		reporter.SetOnBatteryLevelCallback(func(level int) {
+			if sm.onBatteryCallback != nil {
+				sm.onBatteryCallback(cfg.ID, level)
+			}
+		})
+	}
+
+	hidScanner, ok := scanner.(*BarcodeScanner)
+	if !ok || cfg.Dock == nil {
+		return
+	}
+
+	hidScanner.SetDockDetection(cfg.Dock.Detection, cfg.Dock.Interface)
+	hidScanner.SetOnDockChangeCallback(func(docked bool) {
+		if sm.onDockCallback != nil {
+			sm.onDockCallback(cfg.ID, docked)
+		}
+	})
+}
+
 func (sm *ScannerManager) SetReconnectDelay(delay time.Duration) {
 	sm.mutex.RLock()
 	defer sm.mutex.RUnlock()
@@ -159,31 +455,36 @@ func (sm *ScannerManager) checkInitialConnections() error {
 
 	connected := 0
 	disconnected := 0
+	permissionDenied := false
 
 	for _, cfg := range sm.configs {
-		scanner := NewBarcodeScannerWithInterface(
-			cfg.Identification.VendorID,
-			cfg.Identification.ProductID,
-			cfg.Identification.Serial,
-			cfg.Identification.Interface,
-			cfg.TerminationChar,
-			cfg.KeyboardLayout,
-			sm.logger,
-		)
+		scanner, err := NewScanner(&cfg, sm.logger)
+		if err != nil {
+			sm.logger.Warnf("Scanner '%s' (%s) cannot be created: %v", cfg.ID, cfg.Name, err)
+			disconnected++
+			continue
+		}
 
 		if err := scanner.TryInitialConnect(); err != nil {
 			sm.logger.Warnf("Scanner '%s' (%s) not connected at startup: %v", cfg.ID, cfg.Name, err)
 			disconnected++
+			if errors.Is(err, ErrDeviceOpenFailed) || errors.Is(err, fs.ErrPermission) {
+				permissionDenied = true
+			}
 		} else {
 			sm.logger.Infof("Scanner '%s' (%s) available at startup", cfg.ID, cfg.Name)
 			connected++
 		}
 	}
 
-	if connected == 0 && len(sm.configs) > 0 {
-		return fmt.Errorf("FATAL: None of the %d configured scanners could connect - "+
+	if connected == 0 && len(sm.configs) > 0 && !sm.autoDiscover {
+		if permissionDenied {
+			return fmt.Errorf("%w: none of the %d configured scanners could be opened - "+
+				"privileged mode or udev rules are required for device access", ErrPermissionDenied, len(sm.configs))
+		}
+		return fmt.Errorf("%w: none of the %d configured scanners could connect - "+
 			"this usually indicates insufficient privileges (privileged mode required for HID device access)",
-			len(sm.configs))
+			ErrNoScannersAvailable, len(sm.configs))
 	}
 
 	if disconnected > 0 {
@@ -108,6 +108,79 @@ func TestScannerManager_GetScanner_NotFound(t *testing.T) {
 	}
 }
 
+func TestScannerManager_SetScannerEnabled(t *testing.T) {
+	manager := NewScannerManager([]config.ScannerConfig{}, logrus.New())
+
+	if !manager.IsScannerEnabled("desk") {
+		t.Error("Expected scanners to be enabled by default")
+	}
+
+	manager.SetScannerEnabled("desk", false)
+	if manager.IsScannerEnabled("desk") {
+		t.Error("Expected desk to be disabled")
+	}
+	if !manager.IsScannerEnabled("dock") {
+		t.Error("Expected other scanners to stay enabled")
+	}
+
+	manager.SetScannerEnabled("desk", true)
+	if !manager.IsScannerEnabled("desk") {
+		t.Error("Expected desk to be enabled again")
+	}
+}
+
+func TestScannerManager_CompletionTimeout(t *testing.T) {
+	manager := NewScannerManager([]config.ScannerConfig{}, logrus.New())
+	manager.scanners["desk"] = NewBarcodeScanner(0x1234, 0x5678, "enter", "us", logrus.New())
+	manager.scanners["stdin"] = NewStdinScanner(logrus.New())
+
+	if timeout, ok := manager.CompletionTimeout("desk"); !ok || timeout != DefaultCompletionTimeout {
+		t.Errorf("Expected default completion timeout, got %s (%v)", timeout, ok)
+	}
+	if err := manager.SetCompletionTimeout("desk", 250*time.Millisecond); err != nil {
+		t.Fatalf("Expected completion timeout to be set, got error: %v", err)
+	}
+	if timeout, _ := manager.CompletionTimeout("desk"); timeout != 250*time.Millisecond {
+		t.Errorf("Expected completion timeout 250ms, got %s", timeout)
+	}
+
+	if _, ok := manager.CompletionTimeout("stdin"); ok {
+		t.Error("Expected no completion timeout for a line-based driver")
+	}
+	if err := manager.SetCompletionTimeout("stdin", time.Second); err == nil {
+		t.Error("Expected error for a line-based driver")
+	}
+	if err := manager.SetCompletionTimeout("missing", time.Second); err == nil {
+		t.Error("Expected error for an unknown scanner")
+	}
+}
+
+func TestScannerManager_KeyboardLayout(t *testing.T) {
+	manager := NewScannerManager([]config.ScannerConfig{}, logrus.New())
+	manager.scanners["desk"] = NewBarcodeScanner(0x1234, 0x5678, "enter", "us", logrus.New())
+	manager.scanners["stdin"] = NewStdinScanner(logrus.New())
+
+	if layout, ok := manager.KeyboardLayout("desk"); !ok || layout != "us" {
+		t.Errorf("Expected keyboard layout 'us', got '%s' (%v)", layout, ok)
+	}
+	if err := manager.SetKeyboardLayout("desk", "es"); err != nil {
+		t.Fatalf("Expected keyboard layout to be set, got error: %v", err)
+	}
+	if layout, _ := manager.KeyboardLayout("desk"); layout != "es" {
+		t.Errorf("Expected keyboard layout 'es', got '%s'", layout)
+	}
+	if err := manager.SetKeyboardLayout("desk", "klingon"); err == nil {
+		t.Error("Expected error for an unknown layout")
+	}
+
+	if _, ok := manager.KeyboardLayout("stdin"); ok {
+		t.Error("Expected no keyboard layout for a line-based driver")
+	}
+	if err := manager.SetKeyboardLayout("stdin", "es"); err == nil {
+		t.Error("Expected error for a line-based driver")
+	}
+}
+
 func TestScannerManager_Start_NoConfigs(t *testing.T) {
 	configs := []config.ScannerConfig{}
 	logger := logrus.New()
@@ -185,3 +258,33 @@ func TestScannerConfigWithDefaults(t *testing.T) {
 		t.Errorf("Expected empty serial for minimal config, got %s", minimalConfig.Identification.Serial)
 	}
 }
+
+func TestScannerManager_AddRemoveScanner(t *testing.T) {
+	manager := NewScannerManager([]config.ScannerConfig{}, logrus.New())
+	defer func() { _ = manager.Stop() }()
+
+	cfg := config.ScannerConfig{
+		ID:              "added",
+		Identification:  config.ScannerIdentification{VendorID: 0x60e, ProductID: 0x16c7},
+		KeyboardLayout:  "us",
+		TerminationChar: "enter",
+	}
+
+	if err := manager.AddScanner(cfg); err != nil {
+		t.Fatalf("Expected no error adding scanner, got: %v", err)
+	}
+	if manager.GetScanner("added") == nil {
+		t.Error("Expected added scanner to be running")
+	}
+	if err := manager.AddScanner(cfg); err == nil {
+		t.Error("Expected error adding a running scanner twice")
+	}
+
+	manager.RemoveScanner("added")
+	if manager.GetScanner("added") != nil {
+		t.Error("Expected removed scanner to be stopped")
+	}
+	if len(manager.configs) != 0 {
+		t.Errorf("Expected removed scanner config to be dropped, got %d configs", len(manager.configs))
+	}
+}
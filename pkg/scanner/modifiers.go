@@ -0,0 +1,50 @@
+package scanner
+
+import "github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+
+// modifierTracker follows the keyboard modifiers across reports. Reports
+// without key codes press or release modifiers; the mode decides which
+// modifiers apply to the keys of the following reports:
+//
+//   - report: only the modifier byte of the key report itself
+//   - held: the modifiers pressed by earlier modifier-only reports as well,
+//     for scanners that send Shift down on its own and then a key report
+//     without the modifier
+//   - standalone: only the modifiers pressed by modifier-only reports, which
+//     ignores modifier bits ghosting into key reports
+type modifierTracker struct {
+	mode string
+	held byte
+}
+
+// modifiers returns the modifiers applying to the keys of a report and
+// updates the held state.
+func (m *modifierTracker) modifiers(report []byte) byte {
+	modifier := report[0]
+	if !hasKeyCodes(report) {
+		m.held = modifier
+		return modifier
+	}
+
+	switch m.mode {
+	case config.HIDModifierModeHeld:
+		return m.held | modifier
+	case config.HIDModifierModeStandalone:
+		return m.held
+	default:
+		return modifier
+	}
+}
+
+func (m *modifierTracker) reset() {
+	m.held = 0
+}
+
+func hasKeyCodes(report []byte) bool {
+	for i := 2; i < min(len(report), bootReportLength); i++ {
+		if report[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
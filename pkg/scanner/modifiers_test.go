@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestHIDProcessor_ModifierModes(t *testing.T) {
+	shiftOnly := []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	release := []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	keyA := []byte{0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}
+	shiftKeyA := []byte{0x02, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}
+	keyB := []byte{0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00}
+	shiftKeyB := []byte{0x02, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00}
+	enter := []byte{0x00, 0x00, hidKeyEnter, 0x00, 0x00, 0x00, 0x00, 0x00}
+
+	combined := [][]byte{shiftKeyA, release, keyB, release, enter}
+	standaloneShift := [][]byte{shiftOnly, keyA, shiftOnly, release, keyB, release, enter}
+	ghosted := [][]byte{shiftOnly, shiftKeyA, release, shiftKeyB, release, enter}
+
+	tests := []struct {
+		name     string
+		mode     string
+		reports  [][]byte
+		expected string
+	}{
+		{"report combined", "", combined, "Ab"},
+		{"report standalone shift", config.HIDModifierModeReport, standaloneShift, "ab"},
+		{"report ghosted", config.HIDModifierModeReport, ghosted, "AB"},
+		{"held combined", config.HIDModifierModeHeld, combined, "Ab"},
+		{"held standalone shift", config.HIDModifierModeHeld, standaloneShift, "Ab"},
+		{"standalone combined", config.HIDModifierModeStandalone, combined, "ab"},
+		{"standalone standalone shift", config.HIDModifierModeStandalone, standaloneShift, "Ab"},
+		{"standalone ghosted", config.HIDModifierModeStandalone, ghosted, "Ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewHIDProcessor("enter", "us", logrus.New())
+			processor.SetModifierMode(tt.mode)
+
+			var scanned string
+			processor.SetOnScanCallback(func(barcode string) { scanned = barcode })
+			for _, report := range tt.reports {
+				processor.ProcessData(report)
+			}
+
+			if scanned != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, scanned)
+			}
+		})
+	}
+}
+
+func TestHIDProcessor_ResetReleasesModifiers(t *testing.T) {
+	processor := NewHIDProcessor("enter", "us", logrus.New())
+	processor.SetModifierMode(config.HIDModifierModeHeld)
+
+	var scanned string
+	processor.SetOnScanCallback(func(barcode string) { scanned = barcode })
+	processor.ProcessData([]byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	processor.Reset()
+	processor.ProcessData([]byte{0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00})
+	processor.ProcessData([]byte{0x00, 0x00, hidKeyEnter, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+	if scanned != "a" {
+		t.Errorf("Expected %q, got %q", "a", scanned)
+	}
+}
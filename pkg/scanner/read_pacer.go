@@ -0,0 +1,57 @@
+package scanner
+
+import "time"
+
+// The keyboard read loops check for completed scans every
+// readPacerActiveInterval while a scan is buffered. Otherwise there is
+// nothing to complete, so the checks back off to readPacerIdleInterval and
+// an idle scanner wakes its loop a few times per second instead of a hundred.
+const (
+	readPacerActiveInterval = 10 * time.Millisecond
+	readPacerIdleInterval   = time.Second
+)
+
+// readPacer schedules the completion checks of a keyboard read loop. Reports
+// are still processed as soon as they arrive, only the checks are paced.
+type readPacer struct {
+	timer    *time.Timer
+	interval time.Duration
+}
+
+func newReadPacer() *readPacer {
+	return &readPacer{
+		timer:    time.NewTimer(readPacerActiveInterval),
+		interval: readPacerActiveInterval,
+	}
+}
+
+// C fires when the next completion check is due.
+func (p *readPacer) C() <-chan time.Time {
+	return p.timer.C
+}
+
+// Activity schedules the next check at the active interval, after a report
+// arrived.
+func (p *readPacer) Activity() {
+	p.interval = readPacerActiveInterval
+	p.timer.Reset(p.interval)
+}
+
+// Checked schedules the next check after one ran: at the active interval
+// while a scan is still buffered, otherwise backing off towards the idle
+// interval.
+func (p *readPacer) Checked(pending bool) {
+	p.interval = nextReadInterval(p.interval, pending)
+	p.timer.Reset(p.interval)
+}
+
+func (p *readPacer) Stop() {
+	p.timer.Stop()
+}
+
+func nextReadInterval(interval time.Duration, pending bool) time.Duration {
+	if pending {
+		return readPacerActiveInterval
+	}
+	return min(interval*2, readPacerIdleInterval)
+}
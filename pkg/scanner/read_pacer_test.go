@@ -0,0 +1,47 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextReadInterval(t *testing.T) {
+	interval := readPacerActiveInterval
+	var intervals []time.Duration
+	for range 8 {
+		interval = nextReadInterval(interval, false)
+		intervals = append(intervals, interval)
+	}
+
+	if intervals[0] != 2*readPacerActiveInterval {
+		t.Errorf("Expected the first idle check to back off to %s, got %s", 2*readPacerActiveInterval, intervals[0])
+	}
+	if last := intervals[len(intervals)-1]; last != readPacerIdleInterval {
+		t.Errorf("Expected sustained inactivity to reach the idle interval %s, got %s", readPacerIdleInterval, last)
+	}
+
+	if next := nextReadInterval(readPacerIdleInterval, true); next != readPacerActiveInterval {
+		t.Errorf("Expected a buffered scan to be checked every %s, got %s", readPacerActiveInterval, next)
+	}
+}
+
+func TestReadPacer_ActivityResetsInterval(t *testing.T) {
+	pacer := newReadPacer()
+	defer pacer.Stop()
+
+	pacer.Checked(false)
+	pacer.Checked(false)
+	if pacer.interval <= readPacerActiveInterval {
+		t.Fatalf("Expected the interval to back off while idle, got %s", pacer.interval)
+	}
+
+	pacer.Activity()
+	if pacer.interval != readPacerActiveInterval {
+		t.Errorf("Expected a report to restore the active interval, got %s", pacer.interval)
+	}
+	select {
+	case <-pacer.C():
+	case <-time.After(time.Second):
+		t.Error("Expected a completion check shortly after a report")
+	}
+}
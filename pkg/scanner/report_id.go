@@ -0,0 +1,50 @@
+package scanner
+
+import "github.com/karalabe/hid"
+
+const (
+	hidItemReportID  = 0x84 // Global item tag, with the size bits masked off
+	hidItemLong      = 0xFE
+	bootReportLength = 8
+)
+
+// reportDescriptorReader is implemented by backends able to read the HID
+// report descriptor of a device, which tells whether its reports carry a
+// report ID.
+type reportDescriptorReader interface {
+	ReportDescriptor(info *hid.DeviceInfo) ([]byte, error)
+}
+
+// descriptorUsesReportIDs reports whether a HID report descriptor declares
+// report IDs. Devices declaring any send the ID as the first byte of every
+// input report, ahead of the keyboard modifier.
+func descriptorUsesReportIDs(descriptor []byte) bool {
+	for i := 0; i < len(descriptor); {
+		prefix := descriptor[i]
+		if prefix == hidItemLong {
+			if i+1 >= len(descriptor) {
+				return false
+			}
+			i += 3 + int(descriptor[i+1])
+			continue
+		}
+
+		size := int(prefix & 0x03)
+		if size == 3 {
+			size = 4
+		}
+		if prefix&0xFC == hidItemReportID {
+			return true
+		}
+		i += 1 + size
+	}
+	return false
+}
+
+// looksReportIDPrefixed guesses from the first key report of a device whose
+// descriptor is unknown: a boot keyboard report is 8 bytes with a zero
+// reserved byte after the modifier, so a 9 byte report with a non-zero
+// first byte and a zero byte at offset 2 carries a report ID.
+func looksReportIDPrefixed(report []byte) bool {
+	return len(report) == bootReportLength+1 && report[0] != 0 && report[2] == 0
+}
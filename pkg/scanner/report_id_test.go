@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestDescriptorUsesReportIDs(t *testing.T) {
+	// Usage Page (Generic Desktop), Usage (Keyboard), Collection (Application)
+	keyboardStart := []byte{0x05, 0x01, 0x09, 0x06, 0xA1, 0x01}
+	// Modifier byte: Usage Page (Keyboard), Usage Min/Max, Report Size/Count, Input
+	modifiers := []byte{0x05, 0x07, 0x19, 0xE0, 0x29, 0xE7, 0x75, 0x01, 0x95, 0x08, 0x81, 0x02}
+	end := []byte{0xC0}
+
+	concat := func(parts ...[]byte) []byte {
+		var descriptor []byte
+		for _, part := range parts {
+			descriptor = append(descriptor, part...)
+		}
+		return descriptor
+	}
+
+	tests := []struct {
+		name       string
+		descriptor []byte
+		expected   bool
+	}{
+		{"Boot keyboard", concat(keyboardStart, modifiers, end), false},
+		{"Report ID 1", concat(keyboardStart, []byte{0x85, 0x01}, modifiers, end), true},
+		// 0x85 as the data of Usage Maximum, not an item
+		{"Report ID byte in item data", concat(keyboardStart, []byte{0x29, 0x85}, modifiers, end), false},
+		{"Long item skipped", concat([]byte{0xFE, 0x02, 0x10, 0x85, 0x01}, keyboardStart, end), false},
+		{"Four byte item", concat([]byte{0x27, 0x85, 0x85, 0x85, 0x85}, []byte{0x85, 0x02}), true},
+		{"Truncated", []byte{0x05}, false},
+		{"Empty", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := descriptorUsesReportIDs(tt.descriptor); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHIDProcessor_ReportIDPrefix(t *testing.T) {
+	prefixed := true
+	unprefixed := false
+
+	keyA := []byte{0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00}
+	shiftB := []byte{0x02, 0x00, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00}
+	enter := []byte{0x00, 0x00, hidKeyEnter, 0x00, 0x00, 0x00, 0x00, 0x00}
+	withID := func(report []byte) []byte { return append([]byte{0x01}, report...) }
+
+	tests := []struct {
+		name     string
+		prefix   *bool
+		reports  [][]byte
+		expected string
+	}{
+		{"Boot reports detected", nil, [][]byte{keyA, shiftB, enter}, "aB"},
+		{"Prefixed reports detected", nil, [][]byte{withID(keyA), withID(shiftB), withID(enter)}, "aB"},
+		{"Prefix configured", &prefixed, [][]byte{withID(keyA), withID(shiftB), withID(enter)}, "aB"},
+		{"Prefix disabled", &unprefixed, [][]byte{keyA, shiftB, enter}, "aB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewHIDProcessor("enter", "us", logrus.New())
+			processor.SetReportIDPrefix(tt.prefix)
+
+			var barcode string
+			var raw [][]byte
+			processor.SetOnScanCallback(func(scanned string) { barcode = scanned })
+			processor.SetOnRawReportCallback(func(report []byte) { raw = append(raw, report) })
+
+			for _, report := range tt.reports {
+				processor.ProcessData(report)
+			}
+
+			if barcode != tt.expected {
+				t.Errorf("Expected barcode %q, got %q", tt.expected, barcode)
+			}
+			if DetectTerminationChar(raw) != "enter" {
+				t.Errorf("Expected raw reports without the report ID, got %v", raw)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -10,29 +12,41 @@ import (
 
 	"github.com/karalabe/hid"
 	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
 )
 
 type BarcodeScanner struct {
-	vendorID          uint16
-	productID         uint16
-	requiredSerial    string
-	requiredInterface *int
+	// matches lists the configured identification first, followed by the
+	// fallbacks tried in order when it is not present.
+	matches []deviceMatch
 
-	device     *hid.Device
+	backend    HIDBackend
+	device     HIDDevice
 	deviceInfo *hid.DeviceInfo
 	connected  int32
 
-	reconnectDelay time.Duration
-	logger         *logrus.Logger
+	reconnectDelay      time.Duration
+	batteryPollInterval time.Duration
+	batteryLevel        int
+	dockDetection       string
+	dockInterface       *int
+	dockState           int
+	logger              *logrus.Logger
 
 	onScan             func(string)
 	onConnectionChange func(bool)
+	onBatteryLevel     func(int)
+	onDockChange       func(bool)
+	onError            func(string, error)
+	openFailed         atomic.Bool // An open failure was reported since the last connect
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	mutex  sync.RWMutex
 
-	hidProcessor *HIDProcessor
+	hidProcessor   *HIDProcessor
+	reportIDPrefix *bool // Configured report ID prefix, nil to detect it per connection
 }
 
 func NewBarcodeScanner(vendorID, productID uint16, terminationChar, keyboardLayout string, logger *logrus.Logger) *BarcodeScanner {
@@ -51,14 +65,20 @@ func NewBarcodeScannerWithInterface(
 	ctx, cancel := context.WithCancel(context.Background())
 
 	s := &BarcodeScanner{
-		vendorID:          vendorID,
-		productID:         productID,
-		requiredSerial:    requiredSerial,
-		requiredInterface: requiredInterface,
-		logger:            logger,
-		reconnectDelay:    time.Second,
-		ctx:               ctx,
-		cancel:            cancel,
+		matches: []deviceMatch{{
+			vendorID:  vendorID,
+			productID: productID,
+			serial:    requiredSerial,
+			iface:     requiredInterface,
+		}},
+		backend:             hidapiBackend{},
+		logger:              logger,
+		reconnectDelay:      time.Second,
+		batteryPollInterval: DefaultBatteryPollInterval,
+		batteryLevel:        -1,
+		dockState:           -1,
+		ctx:                 ctx,
+		cancel:              cancel,
 	}
 
 	s.hidProcessor = NewHIDProcessor(terminationChar, keyboardLayout, logger)
@@ -83,6 +103,101 @@ func (s *BarcodeScanner) SetOnConnectionChangeCallback(callback func(bool)) {
 	s.mutex.Unlock()
 }
 
+func (s *BarcodeScanner) SetOnBatteryLevelCallback(callback func(int)) {
+	s.mutex.Lock()
+	s.onBatteryLevel = callback
+	s.mutex.Unlock()
+}
+
+func (s *BarcodeScanner) SetOnDockChangeCallback(callback func(bool)) {
+	s.mutex.Lock()
+	s.onDockChange = callback
+	s.mutex.Unlock()
+}
+
+func (s *BarcodeScanner) SetOnErrorCallback(callback func(string, error)) {
+	s.mutex.Lock()
+	s.onError = callback
+	s.mutex.Unlock()
+}
+
+func (s *BarcodeScanner) reportError(category string, err error) {
+	s.mutex.RLock()
+	callback := s.onError
+	s.mutex.RUnlock()
+
+	if callback != nil && category != "" {
+		callback(category, err)
+	}
+}
+
+func (s *BarcodeScanner) SetOnRawReportCallback(callback func([]byte)) {
+	s.hidProcessor.SetOnRawReportCallback(callback)
+}
+
+func (s *BarcodeScanner) SetLearnedLayout(learned *LearnedLayout) {
+	s.hidProcessor.SetLearnedLayout(learned)
+}
+
+// SetReportIDPrefix sets whether the device prefixes its reports with a
+// report ID. nil detects it on every connection, from the report descriptor
+// when the backend can read it, otherwise from the first report.
+func (s *BarcodeScanner) SetReportIDPrefix(prefix *bool) {
+	s.reportIDPrefix = prefix
+}
+
+func (s *BarcodeScanner) SetCompletionTimeout(timeout time.Duration) {
+	s.hidProcessor.SetCompletionTimeout(timeout)
+}
+
+func (s *BarcodeScanner) CompletionTimeout() time.Duration {
+	return s.hidProcessor.CompletionTimeout()
+}
+
+func (s *BarcodeScanner) SetKeyboardLayout(layout string) {
+	s.hidProcessor.SetKeyboardLayout(layout)
+}
+
+func (s *BarcodeScanner) KeyboardLayout() string {
+	return s.hidProcessor.KeyboardLayout()
+}
+
+// SetModifierMode sets how keyboard modifiers are tracked across reports.
+func (s *BarcodeScanner) SetModifierMode(mode string) {
+	s.hidProcessor.SetModifierMode(mode)
+}
+
+// SetHIDBackend replaces the default hidapi backend. It must be called before Start.
+func (s *BarcodeScanner) SetHIDBackend(backend HIDBackend) {
+	s.backend = backend
+}
+
+// SetProductPattern restricts matching to devices whose USB product string
+// matches the pattern. Combined with zero vendor or product IDs it allows
+// matching a scanner model regardless of its exact VID:PID.
+func (s *BarcodeScanner) SetProductPattern(pattern *regexp.Regexp) {
+	s.matches[0].productPattern = pattern
+}
+
+// SetFallbackIdentifications adds identifications tried in order when the
+// primary one is not present, e.g. the same scanner connected through its
+// cradle or by cable with a different product ID.
+func (s *BarcodeScanner) SetFallbackIdentifications(identifications []config.ScannerIdentification) {
+	s.matches = s.matches[:1]
+	for i := range identifications {
+		s.matches = append(s.matches, newDeviceMatch(&identifications[i]))
+	}
+}
+
+// SetDockDetection enables cradle presence detection using either the
+// battery charging state or the presence of a cradle-only interface.
+func (s *BarcodeScanner) SetDockDetection(detection string, dockInterface *int) {
+	s.mutex.Lock()
+	s.dockDetection = strings.ToLower(detection)
+	s.dockInterface = dockInterface
+	s.mutex.Unlock()
+}
+
 func (s *BarcodeScanner) Start() error {
 	go s.connectionManager()
 	s.logger.Debug("Barcode scanner started successfully")
@@ -102,27 +217,36 @@ func (s *BarcodeScanner) TryInitialConnect() error {
 	return nil
 }
 
-func (s *BarcodeScanner) findAndOpenDevice() (*hid.Device, *hid.DeviceInfo, error) {
-	devices := hid.Enumerate(s.vendorID, s.productID)
+func (s *BarcodeScanner) findAndOpenDevice() (HIDDevice, *hid.DeviceInfo, error) {
+	var openErr error
+	descriptions := make([]string, 0, len(s.matches))
+
+	for i := range s.matches {
+		match := &s.matches[i]
+		descriptions = append(descriptions, match.String())
+
+		for _, deviceInfo := range s.backend.Enumerate(match.vendorID, match.productID) {
+			if !match.matches(&deviceInfo) {
+				continue
+			}
 
-	for _, deviceInfo := range devices {
-		if s.isTargetDevice(&deviceInfo) {
-			device, err := deviceInfo.Open()
+			device, err := s.backend.Open(&deviceInfo)
 			if err != nil {
+				openErr = err
 				continue // Try next device
 			}
 
+			if i > 0 {
+				s.logger.Debugf("Primary identification not present, using fallback %s", match)
+			}
 			normalizedInfo := s.normalizeDeviceInfo(&deviceInfo)
 			return device, normalizedInfo, nil
 		}
 	}
 
-	errorMsg := fmt.Sprintf("device %04x:%04x", s.vendorID, s.productID)
-	if s.requiredSerial != "" {
-		errorMsg += fmt.Sprintf(" serial '%s'", s.requiredSerial)
-	}
-	if s.requiredInterface != nil {
-		errorMsg += fmt.Sprintf(" interface %d", *s.requiredInterface)
+	errorMsg := strings.Join(descriptions, " or ")
+	if openErr != nil {
+		return nil, nil, fmt.Errorf("%w: %s: %v", ErrDeviceOpenFailed, errorMsg, openErr)
 	}
 	return nil, nil, fmt.Errorf("%s not found", errorMsg)
 }
@@ -168,13 +292,21 @@ func (s *BarcodeScanner) connectionManager() {
 func (s *BarcodeScanner) tryConnect() bool {
 	device, deviceInfo, err := s.findAndOpenDevice()
 	if err != nil {
+		// Reported once per disconnected period, not on every attempt
+		if errors.Is(err, ErrDeviceOpenFailed) && !s.openFailed.Swap(true) {
+			s.reportError(ErrorCategoryOpenFailure, err)
+		}
 		return false
 	}
+	s.openFailed.Store(false)
 
 	s.mutex.Lock()
 	s.device = device
 	s.deviceInfo = deviceInfo
 	s.mutex.Unlock()
+	s.hidProcessor.SetReportIDPrefix(s.detectReportIDPrefix(deviceInfo))
+	// A modifier held when the device went away is never released
+	s.hidProcessor.Reset()
 
 	atomic.StoreInt32(&s.connected, 1)
 
@@ -187,10 +319,35 @@ func (s *BarcodeScanner) tryConnect() bool {
 	}
 
 	interfaceInfo := fmt.Sprintf(" interface %d", deviceInfo.Interface)
-	s.logger.Debugf("Connected to device %04x:%04x%s (%s)", s.vendorID, s.productID, interfaceInfo, deviceInfo.Product)
+	s.logger.Debugf("Connected to device %04x:%04x%s (%s) via %s",
+		deviceInfo.VendorID, deviceInfo.ProductID, interfaceInfo, deviceInfo.Product, s.backend.Name())
 	return true
 }
 
+// detectReportIDPrefix returns the configured report ID prefix, or reads it
+// from the report descriptor. nil leaves it to the first report.
+func (s *BarcodeScanner) detectReportIDPrefix(deviceInfo *hid.DeviceInfo) *bool {
+	if s.reportIDPrefix != nil {
+		return s.reportIDPrefix
+	}
+
+	reader, ok := s.backend.(reportDescriptorReader)
+	if !ok {
+		return nil
+	}
+	descriptor, err := reader.ReportDescriptor(deviceInfo)
+	if err != nil {
+		s.logger.WithError(err).Debug("Failed to read report descriptor, detecting report IDs from the first report")
+		return nil
+	}
+
+	prefixed := descriptorUsesReportIDs(descriptor)
+	if prefixed {
+		s.logger.Info("Report descriptor declares report IDs, skipping them when decoding")
+	}
+	return &prefixed
+}
+
 func (s *BarcodeScanner) disconnect() {
 	atomic.StoreInt32(&s.connected, 0)
 
@@ -215,28 +372,15 @@ func (s *BarcodeScanner) disconnect() {
 	}
 }
 
-func (s *BarcodeScanner) isTargetDevice(deviceInfo *hid.DeviceInfo) bool {
-	if deviceInfo.VendorID != s.vendorID || deviceInfo.ProductID != s.productID {
-		return false
-	}
-
-	if s.requiredSerial != "" && deviceInfo.Serial != s.requiredSerial {
-		return false
-	}
-
-	if s.requiredInterface != nil && deviceInfo.Interface != *s.requiredInterface {
-		return false
-	}
-
-	return true
-}
-
 func (s *BarcodeScanner) runReadLoop() {
 	const bufferSize = 64
-	const tickerInterval = 10 * time.Millisecond
 
-	timeoutTicker := time.NewTicker(tickerInterval)
-	defer timeoutTicker.Stop()
+	pacer := newReadPacer()
+	defer pacer.Stop()
+
+	batteryTicker := time.NewTicker(s.batteryPollInterval)
+	defer batteryTicker.Stop()
+	s.pollPowerStatus()
 
 	dataChan := make(chan []byte, 10)
 	errorChan := make(chan error, 1)
@@ -248,16 +392,22 @@ func (s *BarcodeScanner) runReadLoop() {
 		case <-s.ctx.Done():
 			return
 
-		case <-timeoutTicker.C:
+		case <-pacer.C():
 			s.hidProcessor.CheckTimeout()
+			pacer.Checked(s.hidProcessor.Pending())
+
+		case <-batteryTicker.C:
+			s.pollPowerStatus()
 
 		case data := <-dataChan:
 			if len(data) > 0 && !s.isAllZeros(data) {
 				s.hidProcessor.ProcessData(data)
+				pacer.Activity()
 			}
 
 		case err := <-errorChan:
 			s.logger.Warnf("HID read error: %v", err)
+			s.reportError(classifyReadError(err), err)
 			s.disconnect()
 			return
 		}
@@ -284,6 +434,7 @@ func (s *BarcodeScanner) hidReadGoroutine(dataChan chan<- []byte, errorChan chan
 			n, err := device.Read(buffer)
 			if err != nil {
 				if err.Error() == "hid: read timeout" || err.Error() == "hid: timeout" {
+					s.reportError(ErrorCategoryReadTimeout, err)
 					continue
 				}
 				errorChan <- err
@@ -299,6 +450,86 @@ func (s *BarcodeScanner) hidReadGoroutine(dataChan chan<- []byte, errorChan chan
 	}
 }
 
+func (s *BarcodeScanner) pollPowerStatus() {
+	deviceInfo := s.GetConnectedDeviceInfo()
+	if deviceInfo == nil {
+		return
+	}
+
+	powerSupply, err := ReadPowerSupply(deviceInfo.VendorID, deviceInfo.ProductID, deviceInfo.Serial)
+	if err == nil {
+		s.updateBatteryLevel(powerSupply.Capacity)
+	}
+
+	s.mutex.RLock()
+	detection := s.dockDetection
+	dockInterface := s.dockInterface
+	s.mutex.RUnlock()
+
+	switch detection {
+	case DockDetectionCharging:
+		if err == nil {
+			s.updateDockState(powerSupply.IsCharging())
+		}
+	case DockDetectionInterface:
+		if dockInterface != nil {
+			s.updateDockState(s.isInterfacePresent(deviceInfo, *dockInterface))
+		}
+	}
+}
+
+func (s *BarcodeScanner) updateBatteryLevel(level int) {
+	s.mutex.Lock()
+	changed := level != s.batteryLevel
+	s.batteryLevel = level
+	callback := s.onBatteryLevel
+	s.mutex.Unlock()
+
+	if changed && callback != nil {
+		callback(level)
+	}
+}
+
+func (s *BarcodeScanner) updateDockState(docked bool) {
+	state := 0
+	if docked {
+		state = 1
+	}
+
+	s.mutex.Lock()
+	changed := state != s.dockState
+	s.dockState = state
+	callback := s.onDockChange
+	s.mutex.Unlock()
+
+	if changed && callback != nil {
+		callback(docked)
+	}
+}
+
+// isInterfacePresent looks for the interface on the connected device, whose
+// VID:PID and serial are exact even when it was matched by a wildcard or a
+// fallback identification.
+func (s *BarcodeScanner) isInterfacePresent(connected *hid.DeviceInfo, iface int) bool {
+	for _, deviceInfo := range s.backend.Enumerate(connected.VendorID, connected.ProductID) {
+		if connected.Serial != "" && deviceInfo.Serial != connected.Serial {
+			continue
+		}
+		if deviceInfo.Interface == iface {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBatteryLevel returns the last polled battery percentage, or -1 if the
+// device does not report one.
+func (s *BarcodeScanner) GetBatteryLevel() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.batteryLevel
+}
+
 func (s *BarcodeScanner) isAllZeros(data []byte) bool {
 	for _, b := range data {
 		if b != 0 {
@@ -320,11 +551,11 @@ func (s *BarcodeScanner) GetConnectedDeviceInfo() *hid.DeviceInfo {
 }
 
 func (s *BarcodeScanner) GetRequiredInterface() *int {
-	return s.requiredInterface
+	return s.matches[0].iface
 }
 
 func (s *BarcodeScanner) GetRequiredSerial() string {
-	return s.requiredSerial
+	return s.matches[0].serial
 }
 
 func (s *BarcodeScanner) normalizeDeviceInfo(deviceInfo *hid.DeviceInfo) *hid.DeviceInfo {
@@ -339,6 +570,12 @@ func (s *BarcodeScanner) SetReconnectDelay(delay time.Duration) {
 	s.reconnectDelay = delay
 }
 
+func (s *BarcodeScanner) SetBatteryPollInterval(interval time.Duration) {
+	if interval > 0 {
+		s.batteryPollInterval = interval
+	}
+}
+
 func ListAllDevices() []hid.DeviceInfo {
 	return hid.Enumerate(0, 0)
 }
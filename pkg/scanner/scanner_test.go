@@ -0,0 +1,245 @@
+package scanner
+
+import (
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"syscall"
+	"testing"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+type fakeHIDDevice struct {
+	info hid.DeviceInfo
+}
+
+func (d *fakeHIDDevice) Read(b []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (d *fakeHIDDevice) Close() error {
+	return nil
+}
+
+type fakeHIDBackend struct {
+	devices []hid.DeviceInfo
+	openErr error // Returned by Open when set
+}
+
+func (b *fakeHIDBackend) Name() string {
+	return "fake"
+}
+
+func (b *fakeHIDBackend) Enumerate(vendorID, productID uint16) []hid.DeviceInfo {
+	return b.devices
+}
+
+func (b *fakeHIDBackend) Open(info *hid.DeviceInfo) (HIDDevice, error) {
+	if b.openErr != nil {
+		return nil, b.openErr
+	}
+	return &fakeHIDDevice{info: *info}, nil
+}
+
+func TestBarcodeScanner_OpenFailureReportedOnce(t *testing.T) {
+	backend := &fakeHIDBackend{
+		devices: []hid.DeviceInfo{{Path: "scanner", VendorID: 0x60e, ProductID: 0x16c7}},
+		openErr: syscall.EACCES,
+	}
+	s := NewBarcodeScanner(0x60e, 0x16c7, "enter", "us", logrus.New())
+	s.SetHIDBackend(backend)
+	var reports []string
+	s.SetOnErrorCallback(func(category string, _ error) { reports = append(reports, category) })
+
+	for range 3 {
+		if s.tryConnect() {
+			t.Fatal("Expected the connection to fail")
+		}
+	}
+	if len(reports) != 1 || reports[0] != ErrorCategoryOpenFailure {
+		t.Errorf("Expected one open failure per disconnected period, got %v", reports)
+	}
+
+	backend.openErr = nil
+	if !s.tryConnect() {
+		t.Fatal("Expected the connection to succeed")
+	}
+	backend.openErr = syscall.EBUSY
+	s.tryConnect()
+	if len(reports) != 2 {
+		t.Errorf("Expected the open failure to be reported again after a connect, got %v", reports)
+	}
+}
+
+func TestBarcodeScanner_FindAndOpenDevice_Interface(t *testing.T) {
+	backend := &fakeHIDBackend{devices: []hid.DeviceInfo{
+		{Path: "if0", VendorID: 0x60e, ProductID: 0x16c7, Serial: "ABC", Interface: 0},
+		{Path: "if1", VendorID: 0x60e, ProductID: 0x16c7, Serial: "ABC", Interface: 1},
+	}}
+
+	interfaceOne := 1
+	interfaceTwo := 2
+
+	tests := []struct {
+		name         string
+		iface        *int
+		expectedPath string
+		expectError  bool
+	}{
+		{"No interface opens first match", nil, "if0", false},
+		{"Interface 1", &interfaceOne, "if1", false},
+		{"Missing interface", &interfaceTwo, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBarcodeScannerWithInterface(0x60e, 0x16c7, "", tt.iface, "enter", "us", logrus.New())
+			s.SetHIDBackend(backend)
+
+			device, info, err := s.findAndOpenDevice()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if info.Path != tt.expectedPath {
+				t.Errorf("Expected device info for %s, got %s", tt.expectedPath, info.Path)
+			}
+			if opened := device.(*fakeHIDDevice).info.Path; opened != tt.expectedPath {
+				t.Errorf("Expected to open %s, opened %s", tt.expectedPath, opened)
+			}
+		})
+	}
+}
+
+func TestBarcodeScanner_FindAndOpenDevice_Wildcard(t *testing.T) {
+	backend := &fakeHIDBackend{devices: []hid.DeviceInfo{
+		{Path: "keyboard", VendorID: 0x60e, ProductID: 0x0001, Product: "USB Keyboard"},
+		{Path: "scanner", VendorID: 0x60e, ProductID: 0x16c8, Product: "Voyager 1250g"},
+		{Path: "other", VendorID: 0x5e0, ProductID: 0x1200, Product: "Symbol Scanner"},
+	}}
+
+	tests := []struct {
+		name         string
+		vendorID     uint16
+		productID    uint16
+		pattern      string
+		expectedPath string
+		expectError  bool
+	}{
+		{"Vendor wildcard opens first vendor device", 0x60e, 0, "", "keyboard", false},
+		{"Vendor wildcard with product name", 0x60e, 0, "^Voyager", "scanner", false},
+		{"Product name only", 0, 0, "(?i)symbol", "other", false},
+		{"Exact IDs must match", 0x60e, 0x16c7, "", "", true},
+		{"Product name without match", 0x60e, 0, "Xenon", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBarcodeScannerWithInterface(tt.vendorID, tt.productID, "", nil, "enter", "us", logrus.New())
+			s.SetHIDBackend(backend)
+			if tt.pattern != "" {
+				s.SetProductPattern(regexp.MustCompile(tt.pattern))
+			}
+
+			_, info, err := s.findAndOpenDevice()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if info.Path != tt.expectedPath {
+				t.Errorf("Expected device info for %s, got %s", tt.expectedPath, info.Path)
+			}
+		})
+	}
+}
+
+func TestBarcodeScanner_FindAndOpenDevice_Fallback(t *testing.T) {
+	cable := hid.DeviceInfo{Path: "cable", VendorID: 0x60e, ProductID: 0x16c7}
+	cradle := hid.DeviceInfo{Path: "cradle", VendorID: 0x60e, ProductID: 0x16c8}
+
+	tests := []struct {
+		name         string
+		devices      []hid.DeviceInfo
+		expectedPath string
+		expectError  bool
+	}{
+		{"Primary preferred", []hid.DeviceInfo{cradle, cable}, "cable", false},
+		{"Fallback when primary missing", []hid.DeviceInfo{cradle}, "cradle", false},
+		{"Neither present", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBarcodeScannerWithInterface(0x60e, 0x16c7, "", nil, "enter", "us", logrus.New())
+			s.SetHIDBackend(&filteringHIDBackend{devices: tt.devices})
+			s.SetFallbackIdentifications([]config.ScannerIdentification{{VendorID: 0x60e, ProductID: 0x16c8}})
+
+			_, info, err := s.findAndOpenDevice()
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if info.Path != tt.expectedPath {
+				t.Errorf("Expected device info for %s, got %s", tt.expectedPath, info.Path)
+			}
+		})
+	}
+}
+
+// filteringHIDBackend filters by VID:PID like the real backends do.
+type filteringHIDBackend struct {
+	fakeHIDBackend
+	devices []hid.DeviceInfo
+}
+
+func (b *filteringHIDBackend) Enumerate(vendorID, productID uint16) []hid.DeviceInfo {
+	var devices []hid.DeviceInfo
+	for _, device := range b.devices {
+		if (vendorID == 0 || device.VendorID == vendorID) && (productID == 0 || device.ProductID == productID) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+func TestClassifyReadError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"Unplugged", &os.PathError{Op: "read", Path: "/dev/hidraw0", Err: syscall.ENODEV}, ""},
+		{"Timed out", &os.PathError{Op: "read", Path: "/dev/hidraw0", Err: syscall.ETIMEDOUT}, ErrorCategoryReadTimeout},
+		{"hidapi timeout", errors.New("hid: read timeout"), ErrorCategoryReadTimeout},
+		{"Protocol error", &os.PathError{Op: "read", Path: "/dev/hidraw0", Err: syscall.EPROTO}, ErrorCategoryIOError},
+		{"EOF", io.EOF, ErrorCategoryIOError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyReadError(tt.err); got != tt.expected {
+				t.Errorf("Expected category %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+package scanner
+
+const DefaultSerialBaudRate = 9600
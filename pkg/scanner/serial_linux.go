@@ -0,0 +1,200 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+var serialBaudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+// SerialScanner reads newline-terminated barcodes from an RS-232 or USB-CDC
+// serial port. The scanner sends plain text, so no keyboard layout applies.
+type SerialScanner struct {
+	baseScanner
+	config   *config.ScannerConfig
+	baudRate uint32
+	file     *os.File
+}
+
+func NewSerialScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	baudRate := cfg.Serial.BaudRate
+	if baudRate == 0 {
+		baudRate = DefaultSerialBaudRate
+	}
+
+	speed, supported := serialBaudRates[baudRate]
+	if !supported {
+		return nil, fmt.Errorf("unsupported baud rate %d", baudRate)
+	}
+
+	return &SerialScanner{
+		baseScanner: newBaseScanner(logger),
+		config:      cfg,
+		baudRate:    speed,
+	}, nil
+}
+
+func (s *SerialScanner) Start() error {
+	go s.runConnectionLoop(s.session)
+	s.logger.Debug("Serial scanner started successfully")
+	return nil
+}
+
+func (s *SerialScanner) Stop() error {
+	s.cancel()
+
+	s.mutex.Lock()
+	file := s.file
+	s.file = nil
+	s.mutex.Unlock()
+
+	if file != nil {
+		_ = file.Close()
+	}
+
+	s.logger.Debug("Serial scanner stopped")
+	return nil
+}
+
+func (s *SerialScanner) TryInitialConnect() error {
+	file, err := s.openPort()
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func (s *SerialScanner) openPort() (*os.File, error) {
+	file, err := os.OpenFile(s.config.Serial.Port, os.O_RDWR|unix.O_NOCTTY, 0) // #nosec G304 - port from config
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", s.config.Serial.Port, err)
+	}
+
+	if err := configureSerialPort(int(file.Fd()), s.baudRate); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to configure %s: %w", s.config.Serial.Port, err)
+	}
+
+	return file, nil
+}
+
+func (s *SerialScanner) session() error {
+	file, err := s.openPort()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	s.mutex.Lock()
+	s.file = file
+	s.mutex.Unlock()
+
+	s.setConnected(serialDeviceInfo(s.config.Serial.Port))
+	s.logger.Debugf("Connected to serial port %s", s.config.Serial.Port)
+
+	lines := bufio.NewScanner(file)
+	lines.Split(splitLines(lineTerminatorBytes(s.config.Serial.LineTerminator)))
+	for lines.Scan() {
+		if barcode := lines.Text(); barcode != "" {
+			s.emitScan(barcode)
+		}
+	}
+
+	if err := lines.Err(); err != nil {
+		return fmt.Errorf("serial read error: %w", err)
+	}
+	return fmt.Errorf("serial port %s closed", s.config.Serial.Port)
+}
+
+// configureSerialPort puts the port in raw 8N1 mode at the given speed.
+func configureSerialPort(fd int, speed uint32) error {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	termios.Oflag &^= unix.OPOST
+	termios.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	termios.Cflag &^= unix.CSIZE | unix.PARENB | unix.CBAUD
+	termios.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	termios.Ispeed = speed
+	termios.Ospeed = speed
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+}
+
+// serialDeviceInfo describes the port, filling in USB details from sysfs for
+// USB-CDC and USB-serial adapters.
+func serialDeviceInfo(port string) *hid.DeviceInfo {
+	info := &hid.DeviceInfo{Path: port, Product: "Serial Scanner"}
+
+	resolved, err := filepath.EvalSymlinks(port)
+	if err != nil {
+		return info
+	}
+
+	// The tty device sits on a USB interface; the USB device attributes are one
+	// level up. The symlink must be resolved first as Join cleans ".." lexically.
+	usbInterface, err := filepath.EvalSymlinks(filepath.Join("/sys/class/tty", filepath.Base(resolved), "device"))
+	if err != nil {
+		return info
+	}
+	usbDevice := filepath.Dir(usbInterface)
+	vendorID, err := readSysfsHex(filepath.Join(usbDevice, "idVendor"))
+	if err != nil {
+		return info
+	}
+	productID, err := readSysfsHex(filepath.Join(usbDevice, "idProduct"))
+	if err != nil {
+		return info
+	}
+
+	info.VendorID = vendorID
+	info.ProductID = productID
+	info.Manufacturer = readSysfsString(filepath.Join(usbDevice, "manufacturer"))
+	info.Serial = readSysfsString(filepath.Join(usbDevice, "serial"))
+	if product := readSysfsString(filepath.Join(usbDevice, "product")); product != "" {
+		info.Product = product
+	}
+
+	return info
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path) // #nosec G304 - sysfs attribute
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysfsHex(path string) (uint16, error) {
+	value, err := strconv.ParseUint(readSysfsString(path), 16, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(value), nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func NewSerialScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	return nil, fmt.Errorf("scanner %s: the serial driver is only available on Linux", cfg.ID)
+}
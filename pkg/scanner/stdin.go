@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+)
+
+// processStdin is the one reader of the process' standard input. A stdin
+// scanner restarted by a reload attaches to it again instead of starting a
+// second reader, which would leave the first one blocked on stdin to swallow
+// the next line.
+var processStdin = newStdinReader(os.Stdin)
+
+// StdinScanner treats each line read from standard input as a scan. It lets
+// the MQTT/Home Assistant pipeline be tested without HID access and accepts
+// barcodes piped from other tools. Once the input ends the scanner stays
+// disconnected.
+type StdinScanner struct {
+	baseScanner
+	reader *stdinReader
+}
+
+func NewStdinScanner(logger *logrus.Logger) *StdinScanner {
+	return &StdinScanner{
+		baseScanner: newBaseScanner(logger),
+		reader:      processStdin,
+	}
+}
+
+func (s *StdinScanner) Start() error {
+	s.setConnected(&hid.DeviceInfo{Path: "stdin", Product: "Standard Input"})
+	if ended := s.reader.attach(s); ended {
+		s.setDisconnected()
+	}
+	s.logger.Debug("Stdin scanner started successfully")
+	return nil
+}
+
+func (s *StdinScanner) Stop() error {
+	s.reader.detach(s)
+	s.cancel()
+	s.logger.Debug("Stdin scanner stopped")
+	return nil
+}
+
+func (s *StdinScanner) TryInitialConnect() error {
+	return nil
+}
+
+// stdinReader reads lines from its input for as long as the process runs
+// and hands them to the attached scanner. Lines read while no scanner is
+// attached are dropped.
+type stdinReader struct {
+	input  io.Reader
+	once   sync.Once
+	mutex  sync.Mutex
+	active *StdinScanner // Guarded by mutex, receives the lines
+	ended  bool          // Guarded by mutex, the input is exhausted
+}
+
+func newStdinReader(input io.Reader) *stdinReader {
+	return &stdinReader{input: input}
+}
+
+// attach makes the scanner receive the lines read from now on, starting the
+// reader on first use. It reports whether the input has already ended.
+func (r *stdinReader) attach(scanner *StdinScanner) bool {
+	r.mutex.Lock()
+	r.active = scanner
+	ended := r.ended
+	r.mutex.Unlock()
+
+	r.once.Do(func() { go r.readLines(scanner.logger) })
+	return ended
+}
+
+// detach stops handing lines to the scanner. A line being handed over
+// finishes first, so no line reaches a stopped scanner.
+func (r *stdinReader) detach(scanner *StdinScanner) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.active == scanner {
+		r.active = nil
+	}
+}
+
+func (r *stdinReader) readLines(logger *logrus.Logger) {
+	lines := bufio.NewScanner(r.input)
+	for lines.Scan() {
+		barcode := strings.TrimSpace(lines.Text())
+		if barcode == "" {
+			continue
+		}
+
+		r.mutex.Lock()
+		if r.active != nil {
+			r.active.emitScan(barcode)
+		} else {
+			logger.Debug("Dropping stdin line, no stdin scanner is running")
+		}
+		r.mutex.Unlock()
+	}
+
+	if err := lines.Err(); err != nil {
+		logger.WithError(err).Error("Failed to read from stdin")
+	} else {
+		logger.Info("Stdin closed, no more scans will be read")
+	}
+
+	r.mutex.Lock()
+	r.ended = true
+	active := r.active
+	r.mutex.Unlock()
+	if active != nil {
+		active.setDisconnected()
+	}
+}
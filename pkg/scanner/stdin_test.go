@@ -0,0 +1,103 @@
+package scanner
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStdinScanner(t *testing.T) {
+	scanner := NewStdinScanner(logrus.New())
+	scanner.reader = newStdinReader(strings.NewReader("8412345678905\n\n  ABC-123  \n"))
+
+	barcodes := make(chan string, 2)
+	scanner.SetOnScanCallback(func(barcode string) { barcodes <- barcode })
+
+	disconnected := make(chan struct{})
+	scanner.SetOnConnectionChangeCallback(func(connected bool) {
+		if !connected {
+			close(disconnected)
+		}
+	})
+
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Expected scanner to start, got: %v", err)
+	}
+	defer func() { _ = scanner.Stop() }()
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for end of input")
+	}
+
+	close(barcodes)
+	var received []string
+	for barcode := range barcodes {
+		received = append(received, barcode)
+	}
+
+	if strings.Join(received, ",") != "8412345678905,ABC-123" {
+		t.Errorf("Expected barcodes [8412345678905 ABC-123], got %v", received)
+	}
+}
+
+func TestStdinScanner_Restart(t *testing.T) {
+	input, writer := io.Pipe()
+	reader := newStdinReader(input)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	startScanner := func() (*StdinScanner, chan string) {
+		scanner := NewStdinScanner(logger)
+		scanner.reader = reader
+		barcodes := make(chan string, 1)
+		scanner.SetOnScanCallback(func(barcode string) { barcodes <- barcode })
+		if err := scanner.Start(); err != nil {
+			t.Fatalf("Expected scanner to start, got: %v", err)
+		}
+		return scanner, barcodes
+	}
+	receive := func(barcodes chan string) string {
+		select {
+		case barcode := <-barcodes:
+			return barcode
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for scan")
+			return ""
+		}
+	}
+
+	first, firstBarcodes := startScanner()
+	_, _ = io.WriteString(writer, "111\n")
+	if barcode := receive(firstBarcodes); barcode != "111" {
+		t.Errorf("Expected 111, got %s", barcode)
+	}
+
+	// A reload stops the scanner and starts a new one on the same stdin
+	_ = first.Stop()
+	second, secondBarcodes := startScanner()
+	defer func() { _ = second.Stop() }()
+	_, _ = io.WriteString(writer, "222\n")
+	if barcode := receive(secondBarcodes); barcode != "222" {
+		t.Errorf("Expected the line after the restart to reach the new scanner, got %s", barcode)
+	}
+	if len(firstBarcodes) != 0 {
+		t.Errorf("Expected no scans on the stopped scanner, got %s", <-firstBarcodes)
+	}
+
+	_ = writer.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for second.IsConnected() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if second.IsConnected() {
+		t.Error("Expected the scanner to disconnect at the end of input")
+	}
+	if third, _ := startScanner(); third.IsConnected() {
+		t.Error("Expected a scanner started after the end of input to stay disconnected")
+	}
+}
@@ -0,0 +1,104 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/karalabe/hid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	tcpDialTimeout = 5 * time.Second
+	tcpKeepAlive   = 30 * time.Second
+)
+
+// TCPScanner consumes barcodes streamed over a TCP socket by fixed-mount
+// network scanners. The bridge is the client and reconnects when the
+// connection drops.
+type TCPScanner struct {
+	baseScanner
+	config  *config.ScannerConfig
+	address string
+	conn    net.Conn
+}
+
+func NewTCPScanner(cfg *config.ScannerConfig, logger *logrus.Logger) (Scanner, error) {
+	return &TCPScanner{
+		baseScanner: newBaseScanner(logger),
+		config:      cfg,
+		address:     net.JoinHostPort(cfg.TCP.Host, strconv.Itoa(cfg.TCP.Port)),
+	}, nil
+}
+
+func (s *TCPScanner) Start() error {
+	go s.runConnectionLoop(s.session)
+	s.logger.Debug("TCP scanner started successfully")
+	return nil
+}
+
+func (s *TCPScanner) Stop() error {
+	s.cancel()
+
+	s.mutex.Lock()
+	conn := s.conn
+	s.conn = nil
+	s.mutex.Unlock()
+
+	if conn != nil {
+		_ = conn.Close()
+	}
+
+	s.logger.Debug("TCP scanner stopped")
+	return nil
+}
+
+func (s *TCPScanner) TryInitialConnect() error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (s *TCPScanner) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: tcpDialTimeout, KeepAlive: tcpKeepAlive}
+	conn, err := dialer.DialContext(s.ctx, "tcp", s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", s.address, err)
+	}
+	return conn, nil
+}
+
+func (s *TCPScanner) session() error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	s.mutex.Lock()
+	s.conn = conn
+	s.mutex.Unlock()
+
+	s.setConnected(&hid.DeviceInfo{Path: "tcp://" + s.address, Product: "TCP Scanner"})
+	s.logger.Debugf("Connected to TCP scanner at %s", s.address)
+
+	lines := bufio.NewScanner(conn)
+	lines.Split(splitLines(lineTerminatorBytes(s.config.TCP.Delimiter)))
+	for lines.Scan() {
+		if barcode := lines.Text(); barcode != "" {
+			s.emitScan(barcode)
+		}
+	}
+
+	if err := lines.Err(); err != nil {
+		return fmt.Errorf("TCP read error: %w", err)
+	}
+	return fmt.Errorf("connection to %s closed", s.address)
+}
@@ -0,0 +1,58 @@
+package scanner
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestTCPScanner(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("8412345678905\r\nABC-123\r\n"))
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	cfg := &config.ScannerConfig{
+		ID:     "tcp_scanner",
+		Driver: config.DriverTCP,
+		TCP:    config.TCPConfig{Host: "127.0.0.1", Port: addr.Port, Delimiter: config.LineTerminatorCRLF},
+	}
+
+	scanner, err := NewScanner(cfg, logrus.New())
+	if err != nil {
+		t.Fatalf("Expected scanner to be created, got: %v", err)
+	}
+
+	barcodes := make(chan string, 2)
+	scanner.SetOnScanCallback(func(barcode string) { barcodes <- barcode })
+
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Expected scanner to start, got: %v", err)
+	}
+	defer func() { _ = scanner.Stop() }()
+
+	for _, expected := range []string{"8412345678905", "ABC-123"} {
+		select {
+		case barcode := <-barcodes:
+			if barcode != expected {
+				t.Errorf("Expected barcode %s, got %s", expected, barcode)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for barcode %s", expected)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package selftest runs the Home Assistant integration against an embedded
+// MQTT broker and verifies the discovery, state and availability round trips
+// without touching the configured broker or Home Assistant.
+package selftest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/karalabe/hid"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/homeassistant"
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/mqtt"
+)
+
+const (
+	ScannerID = "selftest"
+	Barcode   = "SELFTEST-8412345678905"
+
+	// DefaultTimeout is how long each round trip may take.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Check is the outcome of one round trip.
+type Check struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+// Run starts an embedded broker on a free local port, connects the
+// integration with the given Home Assistant settings to it as a mock scanner
+// would, and checks every message Home Assistant relies on. Checks after a
+// failed one that depends on it are skipped.
+func Run(ctx context.Context, haConfig config.HomeAssistantConfig, version string, logger *logrus.Logger, timeout time.Duration) []Check {
+	// Report disconnects right away instead of after the debounce
+	haConfig.DisconnectDebounce = 0
+
+	broker, address, err := startBroker()
+	if err != nil {
+		return []Check{{Name: "Embedded broker", Err: err}}
+	}
+	defer func() { _ = broker.Close() }()
+
+	recorder := newRecorder()
+	if err := broker.Subscribe("#", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		recorder.record(pk.TopicName, string(pk.Payload))
+	}); err != nil {
+		return []Check{{Name: "Embedded broker", Err: err}}
+	}
+	checks := []Check{{Name: "Embedded broker", Detail: "listening on " + address}}
+
+	mqttConfig := &config.MQTTConfig{BrokerURL: "mqtt://" + address, ClientID: "ha-barcode-bridge-selftest"}
+	client, err := mqtt.NewClient(mqttConfig, homeassistant.GenerateBridgeAvailabilityTopic(&haConfig), logger)
+	if err != nil {
+		return append(checks, Check{Name: "MQTT connection", Err: err})
+	}
+
+	// Started before connecting, as the application does, so the connect
+	// callback finds the integration ready
+	integration := homeassistant.NewIntegration(client, &haConfig, version, logger)
+	scannerConfig := config.ScannerConfig{ID: ScannerID, Name: "Self Test Scanner", TerminationChar: "enter", KeyboardLayout: "us"}
+	integration.AddScanner(ScannerID, scannerConfig.Name, &scannerConfig)
+	if err := integration.Start(); err != nil {
+		return append(checks, Check{Name: "Integration", Err: err})
+	}
+
+	if err := client.Connect(); err != nil {
+		return append(checks, Check{Name: "MQTT connection", Err: err})
+	}
+	defer client.Disconnect()
+	checks = append(checks, Check{Name: "MQTT connection", Detail: "connected as " + mqttConfig.ClientID})
+
+	integration.SetScannerDeviceInfo(ScannerID, &hid.DeviceInfo{Manufacturer: "Self Test", Product: "Mock Scanner"})
+
+	topics := integration.ScannerTopics(ScannerID)
+	bridgeTopic := integration.GenerateBridgeAvailabilityTopic()
+	wait := func(name, topic string, expected func(string) error) bool {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := recorder.waitFor(waitCtx, topic, expected)
+		if err != nil {
+			checks = append(checks, Check{Name: name, Err: err})
+			return false
+		}
+		checks = append(checks, Check{Name: name, Detail: topic})
+		return true
+	}
+
+	if !wait("Bridge availability", bridgeTopic, equals("online")) {
+		return checks
+	}
+
+	if err := integration.SetScannerConnected(ScannerID, true); err != nil {
+		return append(checks, Check{Name: "Scanner availability", Err: err})
+	}
+	if !wait("Discovery", topics.ConfigTopic, isDiscoveryConfig(topics.BaseTopic)) ||
+		!wait("Scanner availability", topics.AvailabilityTopic, equals("online")) {
+		return checks
+	}
+
+	if err := integration.PublishBarcode(ScannerID, Barcode, homeassistant.ScanMetadata{}); err != nil {
+		return append(checks, Check{Name: "Scan state", Err: err})
+	}
+	if !wait("Scan state", topics.StateTopic, containsBarcode) {
+		return checks
+	}
+
+	if err := integration.SetScannerConnected(ScannerID, false); err != nil {
+		return append(checks, Check{Name: "Scanner disconnect", Err: err})
+	}
+	if !wait("Scanner disconnect", topics.AvailabilityTopic, equals("offline")) {
+		return checks
+	}
+
+	_ = integration.Stop()
+	wait("Bridge shutdown", bridgeTopic, equals("offline"))
+	return checks
+}
+
+// Failed returns the number of failed checks.
+func Failed(checks []Check) int {
+	failures := 0
+	for _, check := range checks {
+		if check.Err != nil {
+			failures++
+		}
+	}
+	return failures
+}
+
+func startBroker() (*mochi.Server, string, error) {
+	broker := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.DiscardHandler),
+	})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		return nil, "", err
+	}
+
+	listener := listeners.NewTCP(listeners.Config{ID: "selftest", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(listener); err != nil {
+		return nil, "", fmt.Errorf("failed to listen: %w", err)
+	}
+	if err := broker.Serve(); err != nil {
+		return nil, "", err
+	}
+	return broker, listener.Address(), nil
+}
+
+func equals(expected string) func(string) error {
+	return func(payload string) error {
+		if payload != expected {
+			return fmt.Errorf("expected %q, got %q", expected, payload)
+		}
+		return nil
+	}
+}
+
+// isDiscoveryConfig checks that the discovery config is JSON pointing Home
+// Assistant at the scanner's topics.
+func isDiscoveryConfig(baseTopic string) func(string) error {
+	return func(payload string) error {
+		var discovery homeassistant.SensorConfig
+		if err := json.Unmarshal([]byte(payload), &discovery); err != nil {
+			return fmt.Errorf("invalid discovery config: %w", err)
+		}
+		if discovery.TildeTopic != baseTopic || discovery.StateTopic == "" || discovery.UniqueID == "" {
+			return fmt.Errorf("discovery config doesn't point at %s: %s", baseTopic, payload)
+		}
+		return nil
+	}
+}
+
+// containsBarcode accepts the plain and the JSON state formats.
+func containsBarcode(payload string) error {
+	if payload == Barcode {
+		return nil
+	}
+	var state homeassistant.StatePayload
+	if json.Unmarshal([]byte(payload), &state) == nil && state.Value != nil && *state.Value == Barcode {
+		return nil
+	}
+	return fmt.Errorf("expected %q, got %q", Barcode, payload)
+}
+
+// recorder keeps the last message of every topic the broker routed.
+type recorder struct {
+	mutex    sync.Mutex
+	messages map[string]string
+	updated  chan struct{} // Closed and replaced on every message
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		messages: make(map[string]string),
+		updated:  make(chan struct{}),
+	}
+}
+
+func (r *recorder) record(topic, payload string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages[topic] = payload
+	close(r.updated)
+	r.updated = make(chan struct{})
+}
+
+// waitFor waits until the last message of the topic passes check.
+func (r *recorder) waitFor(ctx context.Context, topic string, check func(string) error) error {
+	for {
+		r.mutex.Lock()
+		payload, received := r.messages[topic]
+		updated := r.updated
+		r.mutex.Unlock()
+
+		var err error
+		if received {
+			if err = check(payload); err == nil {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if !received {
+				return errors.New("nothing was published")
+			}
+			return err
+		case <-updated:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package selftest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func TestRun(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	haConfig := config.HomeAssistantConfig{
+		DiscoveryPrefix:  "homeassistant",
+		InstanceID:       "selftest",
+		AvailabilityMode: config.AvailabilityModeAll,
+		ObjectIDTemplate: config.DefaultObjectIDTemplate,
+		UniqueIDTemplate: config.DefaultUniqueIDTemplate,
+		Topics: config.TopicsConfig{
+			Discovery: config.DefaultDiscoveryTopicTemplate,
+			State:     config.DefaultStateTopicTemplate,
+		},
+	}
+
+	checks := Run(context.Background(), haConfig, "test", logger, DefaultTimeout)
+	for _, check := range checks {
+		if check.Err != nil {
+			t.Errorf("Expected %s to pass, got: %v", check.Name, check.Err)
+		}
+	}
+	if last := checks[len(checks)-1].Name; last != "Bridge shutdown" {
+		t.Errorf("Expected all checks to run, stopped after %s", last)
+	}
+	if Failed(checks) != 0 {
+		t.Errorf("Expected no failed checks, got %d", Failed(checks))
+	}
+}
+
+func TestContainsBarcode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		valid   bool
+	}{
+		{"Plain", Barcode, true},
+		{"JSON", `{"value": "` + Barcode + `", "attributes": {}}`, true},
+		{"Unknown", "unknown", false},
+		{"JSON null", `{"value": null}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := containsBarcode(tt.payload); (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v for %s, got: %v", tt.valid, tt.payload, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+// execWaitDelay is how long a timed out command may take to close its output
+// after being killed.
+const execWaitDelay = time.Second
+
+// ExecSinkName returns the sink name of a scanner's on_scan_exec command.
+func ExecSinkName(scannerID string) string {
+	return "on_scan_exec:" + scannerID
+}
+
+// ExecSink runs a scanner's on_scan_exec command for every scan, with up to
+// MaxConcurrent commands at once. Scans wait in a queue for a free slot and
+// are dropped when it is full.
+type ExecSink struct {
+	name   string
+	config *config.ScanExecConfig
+	logger *logrus.Entry
+
+	queue  chan Event
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	health healthTracker
+}
+
+func NewExecSink(scannerID string, cfg *config.ScanExecConfig, logger *logrus.Logger) *ExecSink {
+	ctx, cancel := context.WithCancel(context.Background())
+	name := ExecSinkName(scannerID)
+
+	return &ExecSink{
+		name:   name,
+		config: cfg,
+		logger: logger.WithField("sink", name),
+		queue:  make(chan Event, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (e *ExecSink) Name() string {
+	return e.name
+}
+
+func (e *ExecSink) Start() error {
+	for range max(e.config.MaxConcurrent, 1) {
+		e.wg.Add(1)
+		go e.worker()
+	}
+	return nil
+}
+
+// Stop kills the running commands and drops the queued scans.
+func (e *ExecSink) Stop() error {
+	e.cancel()
+	e.wg.Wait()
+	return nil
+}
+
+func (e *ExecSink) Send(event Event) {
+	select {
+	case e.queue <- event:
+	default:
+		e.health.recordFailure(fmt.Errorf("queue full"))
+		e.logger.WithField("barcode", event.Barcode).Error("on_scan_exec queue full, dropping scan")
+	}
+}
+
+func (e *ExecSink) Health() Health {
+	return e.health.snapshot(len(e.queue))
+}
+
+func (e *ExecSink) worker() {
+	defer e.wg.Done()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case event := <-e.queue:
+			logger := e.logger.WithFields(map[string]any{
+				"scanner_id": event.ScannerID,
+				"barcode":    event.Barcode,
+			})
+			if err := e.run(event); err != nil {
+				e.health.recordFailure(err)
+				logger.WithError(err).Error("on_scan_exec command failed")
+				continue
+			}
+			e.health.recordSuccess()
+			logger.Debug("on_scan_exec command finished")
+		}
+	}
+}
+
+// run runs the command with the scan in its arguments, SCAN_* environment
+// variables and as JSON on stdin.
+func (e *ExecSink) run(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.config.Timeout)
+	defer cancel()
+
+	replacer := strings.NewReplacer("{barcode}", event.Barcode, "{scanner_id}", event.ScannerID)
+	args := make([]string, len(e.config.Command)-1)
+	for i, arg := range e.config.Command[1:] {
+		args[i] = replacer.Replace(arg)
+	}
+
+	command := exec.CommandContext(ctx, e.config.Command[0], args...) // #nosec G204 - command from the configuration
+	command.Stdin = bytes.NewReader(body)
+	killProcessGroup(command)
+	// Don't wait for the output pipe once killed, a child outside the group
+	// may still hold it
+	command.WaitDelay = execWaitDelay
+	command.Env = append(os.Environ(),
+		"SCAN_BARCODE="+event.Barcode,
+		"SCAN_SCANNER_ID="+event.ScannerID,
+		"SCAN_TIMESTAMP="+event.Timestamp.Format(time.RFC3339),
+	)
+	if event.Action != "" {
+		command.Env = append(command.Env, "SCAN_ACTION="+event.Action, "SCAN_QTY="+strconv.Itoa(event.Quantity))
+	}
+
+	if output, err := command.CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", e.config.Timeout)
+		}
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sink
+
+import "os/exec"
+
+// killProcessGroup is a no-op without process groups, the timeout kills the
+// command itself and WaitDelay bounds the wait for its children.
+func killProcessGroup(*exec.Cmd) {}
@@ -0,0 +1,126 @@
+package sink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func waitForHealth(t *testing.T, sink HealthReporter, done func(Health) bool) Health {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		health := sink.Health()
+		if done(health) {
+			return health
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the command, health: %+v", health)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExecSink_RunsCommand(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "scan")
+	script := `printf '%s\n%s\n%s\n' "$1" "$SCAN_SCANNER_ID" "$SCAN_BARCODE" > "$2"; cat >> "$2"`
+
+	execSink := NewExecSink("kitchen", &config.ScanExecConfig{
+		Command:       []string{"sh", "-c", script, "sh", "arg:{barcode}@{scanner_id}", output},
+		Timeout:       5 * time.Second,
+		MaxConcurrent: 1,
+		QueueSize:     10,
+	}, logrus.New())
+	if err := execSink.Start(); err != nil {
+		t.Fatalf("Expected no error starting sink, got: %v", err)
+	}
+	defer func() { _ = execSink.Stop() }()
+
+	if execSink.Name() != "on_scan_exec:kitchen" {
+		t.Errorf("Expected sink name on_scan_exec:kitchen, got %s", execSink.Name())
+	}
+
+	execSink.Send(Event{ScannerID: "kitchen", Barcode: "4006381333931", Timestamp: time.Now()})
+	waitForHealth(t, execSink, func(h Health) bool { return h.Delivered+h.Failed > 0 })
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Expected the command to write its output, got: %v", err)
+	}
+	lines := strings.SplitN(string(content), "\n", 4)
+	if len(lines) != 4 || lines[0] != "arg:4006381333931@kitchen" || lines[1] != "kitchen" || lines[2] != "4006381333931" {
+		t.Fatalf("Expected the scan in the arguments and environment, got %q", content)
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(lines[3]), &event); err != nil || event.Barcode != "4006381333931" {
+		t.Errorf("Expected the scan as JSON on stdin, got %q (%v)", lines[3], err)
+	}
+}
+
+func TestExecSink_Timeout(t *testing.T) {
+	execSink := NewExecSink("kitchen", &config.ScanExecConfig{
+		Command:       []string{"sleep", "10"},
+		Timeout:       50 * time.Millisecond,
+		MaxConcurrent: 1,
+		QueueSize:     1,
+	}, logrus.New())
+	if err := execSink.Start(); err != nil {
+		t.Fatalf("Expected no error starting sink, got: %v", err)
+	}
+	defer func() { _ = execSink.Stop() }()
+
+	execSink.Send(Event{ScannerID: "kitchen", Barcode: "1"})
+	health := waitForHealth(t, execSink, func(h Health) bool { return h.Failed > 0 })
+	if !strings.Contains(health.LastError, "timed out") {
+		t.Errorf("Expected a timeout error, got %q", health.LastError)
+	}
+}
+
+func TestExecSink_TimeoutKillsChildren(t *testing.T) {
+	execSink := NewExecSink("kitchen", &config.ScanExecConfig{
+		Command:       []string{"sh", "-c", "sleep 10; true"},
+		Timeout:       100 * time.Millisecond,
+		MaxConcurrent: 1,
+		QueueSize:     1,
+	}, logrus.New())
+	if err := execSink.Start(); err != nil {
+		t.Fatalf("Expected no error starting sink, got: %v", err)
+	}
+	defer func() { _ = execSink.Stop() }()
+
+	start := time.Now()
+	execSink.Send(Event{ScannerID: "kitchen", Barcode: "1"})
+	health := waitForHealth(t, execSink, func(h Health) bool { return h.Failed > 0 })
+	if !strings.Contains(health.LastError, "timed out") {
+		t.Errorf("Expected a timeout error, got %q", health.LastError)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the worker to be freed after the timeout, took %s", elapsed)
+	}
+}
+
+func TestExecSink_QueueFull(t *testing.T) {
+	// Not started, so nothing drains the queue
+	execSink := NewExecSink("kitchen", &config.ScanExecConfig{
+		Command:       []string{"true"},
+		Timeout:       time.Second,
+		MaxConcurrent: 1,
+		QueueSize:     1,
+	}, logrus.New())
+
+	execSink.Send(Event{ScannerID: "kitchen", Barcode: "1"})
+	execSink.Send(Event{ScannerID: "kitchen", Barcode: "2"})
+
+	health := execSink.Health()
+	if health.Queued != 1 || health.Failed != 1 {
+		t.Errorf("Expected one queued and one dropped scan, got %+v", health)
+	}
+}
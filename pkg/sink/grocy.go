@@ -0,0 +1,166 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const GrocyAPIKeyHeader = "GROCY-API-KEY"
+
+// GrocySink books scans into Grocy stock by barcode. Each scanner purchases
+// or consumes according to its mode mapping; pantry mode actions take
+// precedence so the Home Assistant select keeps working with Grocy.
+type GrocySink struct {
+	config *config.GrocyConfig
+	client *http.Client
+	logger *logrus.Entry
+
+	queue  chan Event
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	health healthTracker
+}
+
+type grocyStockRequest struct {
+	Amount          int    `json:"amount"`
+	TransactionType string `json:"transaction_type"`
+}
+
+type grocyError struct {
+	ErrorMessage string `json:"error_message"`
+}
+
+func NewGrocySink(cfg *config.GrocyConfig, logger *logrus.Logger) *GrocySink {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &GrocySink{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger.WithField("sink", cfg.Name),
+		queue:  make(chan Event, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (g *GrocySink) Name() string {
+	return g.config.Name
+}
+
+func (g *GrocySink) Start() error {
+	g.wg.Add(1)
+	go g.worker()
+	return nil
+}
+
+func (g *GrocySink) Stop() error {
+	g.cancel()
+	g.wg.Wait()
+	return nil
+}
+
+func (g *GrocySink) Send(event Event) {
+	select {
+	case g.queue <- event:
+	default:
+		g.health.recordFailure(fmt.Errorf("queue full"))
+		g.logger.WithField("barcode", event.Barcode).Error("Grocy queue full, dropping scan")
+	}
+}
+
+func (g *GrocySink) Health() Health {
+	return g.health.snapshot(len(g.queue))
+}
+
+func (g *GrocySink) worker() {
+	defer g.wg.Done()
+
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case event := <-g.queue:
+			logger := g.logger.WithFields(map[string]any{
+				"scanner_id": event.ScannerID,
+				"barcode":    event.Barcode,
+			})
+			mode := g.modeFor(event)
+			if err := g.book(event, mode); err != nil {
+				g.health.recordFailure(err)
+				logger.WithError(err).Errorf("Failed to %s product in Grocy", mode)
+				continue
+			}
+			g.health.recordSuccess()
+			logger.WithField("mode", mode).Info("Stock booked in Grocy")
+		}
+	}
+}
+
+// modeFor resolves the stock operation for a scan: pantry mode action first,
+// then the scanner's mode mapping, then the default mode.
+func (g *GrocySink) modeFor(event Event) string {
+	switch event.Action {
+	case config.PantryActionAdd:
+		return config.GrocyModePurchase
+	case config.PantryActionConsume:
+		return config.GrocyModeConsume
+	}
+	if mode, exists := g.config.Modes[event.ScannerID]; exists {
+		return mode
+	}
+	return g.config.DefaultMode
+}
+
+func (g *GrocySink) book(event Event, mode string) error {
+	operation := "add"
+	if mode == config.GrocyModeConsume {
+		operation = "consume"
+	}
+
+	body, err := json.Marshal(grocyStockRequest{
+		Amount:          max(event.Quantity, 1),
+		TransactionType: mode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/stock/products/by-barcode/%s/%s",
+		strings.TrimSuffix(g.config.URL, "/"), url.PathEscape(event.Barcode), operation)
+
+	req, err := http.NewRequestWithContext(g.ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(GrocyAPIKeyHeader, g.config.APIKey)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiError grocyError
+		if data, readErr := io.ReadAll(io.LimitReader(resp.Body, 4096)); readErr == nil &&
+			json.Unmarshal(data, &apiError) == nil && apiError.ErrorMessage != "" {
+			return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, apiError.ErrorMessage)
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+type grocyCall struct {
+	path    string
+	apiKey  string
+	request grocyStockRequest
+}
+
+func TestGrocySink_BooksStock(t *testing.T) {
+	calls := make(chan grocyCall, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := grocyCall{path: r.URL.Path, apiKey: r.Header.Get(GrocyAPIKeyHeader)}
+		_ = json.NewDecoder(r.Body).Decode(&call.request)
+		calls <- call
+	}))
+	defer server.Close()
+
+	grocy := NewGrocySink(&config.GrocyConfig{
+		Name:        "grocy",
+		URL:         server.URL + "/",
+		APIKey:      "key",
+		DefaultMode: config.GrocyModePurchase,
+		Modes:       map[string]string{"kitchen": config.GrocyModeConsume},
+		Timeout:     time.Second,
+		QueueSize:   10,
+	}, logrus.New())
+	if err := grocy.Start(); err != nil {
+		t.Fatalf("Expected no error starting sink, got: %v", err)
+	}
+	defer func() { _ = grocy.Stop() }()
+
+	tests := []struct {
+		event    Event
+		path     string
+		mode     string
+		quantity int
+	}{
+		{Event{ScannerID: "pantry", Barcode: "123"}, "/api/stock/products/by-barcode/123/add", config.GrocyModePurchase, 1},
+		{Event{ScannerID: "kitchen", Barcode: "456"}, "/api/stock/products/by-barcode/456/consume", config.GrocyModeConsume, 1},
+		{
+			Event{ScannerID: "kitchen", Barcode: "789", Action: config.PantryActionAdd, Quantity: 3},
+			"/api/stock/products/by-barcode/789/add", config.GrocyModePurchase, 3,
+		},
+	}
+
+	for _, tt := range tests {
+		grocy.Send(tt.event)
+
+		select {
+		case call := <-calls:
+			if call.path != tt.path {
+				t.Errorf("Expected path %s, got %s", tt.path, call.path)
+			}
+			if call.apiKey != "key" {
+				t.Errorf("Expected API key header 'key', got %q", call.apiKey)
+			}
+			if call.request.TransactionType != tt.mode {
+				t.Errorf("Expected transaction type %s, got %s", tt.mode, call.request.TransactionType)
+			}
+			if call.request.Amount != tt.quantity {
+				t.Errorf("Expected amount %d, got %d", tt.quantity, call.request.Amount)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected Grocy to be called for %s", tt.event.Barcode)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"sync"
+	"time"
+)
+
+// Health is the delivery state of a sink, shown in the bridge diagnostics.
+type Health struct {
+	Delivered   int64      `json:"delivered"`
+	Failed      int64      `json:"failed"`
+	Queued      int        `json:"queued"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// HealthReporter is implemented by sinks that track their deliveries.
+type HealthReporter interface {
+	Health() Health
+}
+
+// healthTracker counts the deliveries of a sink. It is safe for concurrent
+// use, the sink worker records while the diagnostics read.
+type healthTracker struct {
+	mutex  sync.Mutex
+	health Health
+}
+
+func (t *healthTracker) recordSuccess() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.health.Delivered++
+	t.health.LastSuccess = &now
+}
+
+func (t *healthTracker) recordFailure(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.health.Failed++
+	t.health.LastFailure = &now
+	t.health.LastError = err.Error()
+}
+
+func (t *healthTracker) snapshot(queued int) Health {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	health := t.health
+	health.Queued = queued
+	return health
+}
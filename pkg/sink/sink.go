@@ -0,0 +1,142 @@
+package sink
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event is a single scan delivered to the configured sinks.
+type Event struct {
+	ScannerID string    `json:"scanner_id"`
+	Barcode   string    `json:"barcode"`
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action,omitempty"` // Pantry mode only: "add" or "consume"
+	Quantity  int       `json:"qty,omitempty"`    // Pantry mode only
+	// Attributes are added by enrich pipeline stages.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type Sink interface {
+	Name() string
+	Start() error
+	Stop() error
+	Send(event Event)
+}
+
+type route struct {
+	sink     Sink
+	scanners []string
+}
+
+// Manager fans scan events out to every sink interested in the scanner.
+// Sinks can be added and removed while it runs, e.g. by a configuration
+// reload, without interrupting the others.
+type Manager struct {
+	mutex   sync.RWMutex
+	routes  []route
+	started bool
+	logger  *logrus.Logger
+}
+
+func NewManager(logger *logrus.Logger) *Manager {
+	return &Manager{
+		logger: logger,
+	}
+}
+
+// Add registers a sink for the given scanner IDs, or for all scanners when
+// empty. A sink added to a running manager is started first, and left out
+// if it fails to start.
+func (m *Manager) Add(sink Sink, scanners []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.started {
+		if err := sink.Start(); err != nil {
+			m.logger.WithField("sink", sink.Name()).WithError(err).Error("Failed to start sink")
+			return
+		}
+		m.logger.WithField("sink", sink.Name()).Debug("Sink started")
+	}
+	m.routes = append(m.routes, route{sink: sink, scanners: scanners})
+}
+
+// Remove stops and unregisters the sink with the given name. Scans
+// dispatched afterwards no longer reach it.
+func (m *Manager) Remove(name string) bool {
+	m.mutex.Lock()
+	index := slices.IndexFunc(m.routes, func(r route) bool { return r.sink.Name() == name })
+	if index < 0 {
+		m.mutex.Unlock()
+		return false
+	}
+	removed := m.routes[index].sink
+	m.routes = slices.Delete(m.routes, index, index+1)
+	started := m.started
+	m.mutex.Unlock()
+
+	// Stopping waits for the delivery in progress, outside the lock so
+	// dispatching to the other sinks goes on meanwhile.
+	if started {
+		if err := removed.Stop(); err != nil {
+			m.logger.WithField("sink", name).WithError(err).Error("Failed to stop sink")
+		}
+	}
+	return true
+}
+
+func (m *Manager) Start() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, r := range m.routes {
+		if err := r.sink.Start(); err != nil {
+			return err
+		}
+		m.logger.WithField("sink", r.sink.Name()).Debug("Sink started")
+	}
+	m.started = true
+	return nil
+}
+
+func (m *Manager) Stop() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, r := range m.routes {
+		if err := r.sink.Stop(); err != nil {
+			m.logger.WithField("sink", r.sink.Name()).WithError(err).Error("Failed to stop sink")
+		}
+	}
+	m.started = false
+	return nil
+}
+
+func (m *Manager) Dispatch(event Event) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, r := range m.routes {
+		if len(r.scanners) > 0 && !slices.Contains(r.scanners, event.ScannerID) {
+			continue
+		}
+		r.sink.Send(event)
+	}
+}
+
+// Health returns the delivery state of the sinks that track it, by name.
+func (m *Manager) Health() map[string]Health {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	health := make(map[string]Health)
+	for _, r := range m.routes {
+		if reporter, ok := r.sink.(HealthReporter); ok {
+			health[r.sink.Name()] = reporter.Health()
+		}
+	}
+	return health
+}
@@ -0,0 +1,215 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+const (
+	SignatureHeader = "X-Signature-256"
+	TimestampHeader = "X-Timestamp"
+)
+
+// WebhookSink POSTs scans as JSON, signing each request with HMAC-SHA256 and
+// retrying with exponential backoff. Scans that cannot be delivered are
+// appended to a dead-letter file.
+type WebhookSink struct {
+	config *config.WebhookConfig
+	client *http.Client
+	logger *logrus.Entry
+
+	queue  chan Event
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	deadLetterMutex sync.Mutex
+	health          healthTracker
+}
+
+func NewWebhookSink(cfg *config.WebhookConfig, logger *logrus.Logger) *WebhookSink {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookSink{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger.WithField("sink", cfg.Name),
+		queue:  make(chan Event, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (w *WebhookSink) Name() string {
+	return w.config.Name
+}
+
+func (w *WebhookSink) Start() error {
+	w.wg.Add(1)
+	go w.worker()
+	return nil
+}
+
+func (w *WebhookSink) Stop() error {
+	w.cancel()
+	w.wg.Wait()
+
+	// Persist whatever is still queued so nothing is silently lost on shutdown
+	for {
+		select {
+		case event := <-w.queue:
+			w.deadLetter(event, fmt.Errorf("bridge shutting down"))
+		default:
+			return nil
+		}
+	}
+}
+
+func (w *WebhookSink) Send(event Event) {
+	select {
+	case w.queue <- event:
+	default:
+		err := fmt.Errorf("delivery queue full")
+		w.health.recordFailure(err)
+		w.deadLetter(event, err)
+	}
+}
+
+func (w *WebhookSink) Health() Health {
+	return w.health.snapshot(len(w.queue))
+}
+
+func (w *WebhookSink) worker() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case event := <-w.queue:
+			if err := w.deliverWithRetry(event); err != nil {
+				w.health.recordFailure(err)
+				w.deadLetter(event, err)
+				continue
+			}
+			w.health.recordSuccess()
+		}
+	}
+}
+
+func (w *WebhookSink) deliverWithRetry(event Event) error {
+	backoff := w.config.InitialBackoff
+
+	var err error
+	for attempt := 0; attempt <= w.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			w.logger.WithError(err).WithField("attempt", attempt+1).Warn("Retrying webhook delivery")
+			select {
+			case <-w.ctx.Done():
+				return fmt.Errorf("delivery aborted: %w", err)
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, w.config.MaxBackoff)
+		}
+
+		if err = w.deliver(event); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", w.config.MaxRetries+1, err)
+}
+
+func (w *WebhookSink) deliver(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	if w.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.config.Secret, timestamp, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *WebhookSink) deadLetter(event Event, reason error) {
+	logger := w.logger.WithFields(map[string]any{
+		"scanner_id": event.ScannerID,
+		"barcode":    event.Barcode,
+	}).WithError(reason)
+
+	if w.config.DeadLetterFile == "" {
+		logger.Error("Dropping undeliverable scan")
+		return
+	}
+
+	record, err := json.Marshal(map[string]any{
+		"event":  event,
+		"error":  reason.Error(),
+		"failed": time.Now().Format(time.RFC3339),
+		"sink":   w.config.Name,
+	})
+	if err != nil {
+		logger.Error("Failed to marshal dead-letter record")
+		return
+	}
+
+	w.deadLetterMutex.Lock()
+	defer w.deadLetterMutex.Unlock()
+
+	file, err := os.OpenFile(w.config.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		logger.Errorf("Failed to open dead-letter file: %v", err)
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := file.Write(append(record, '\n')); err != nil {
+		logger.Errorf("Failed to write dead-letter record: %v", err)
+		return
+	}
+	logger.Warn("Scan written to dead-letter file")
+}
+
+// Sign returns the signature header value for a request body. Receivers
+// recompute HMAC-SHA256 over "<timestamp>.<body>" to authenticate the request
+// and reject replays with stale timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,210 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/miguelangel-nubla/homeassistant-barcode-scanner/pkg/config"
+)
+
+func newTestWebhookConfig(url string) *config.WebhookConfig {
+	return &config.WebhookConfig{
+		Name:           "test",
+		URL:            url,
+		Secret:         "s3cret",
+		Timeout:        time.Second,
+		MaxRetries:     2,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		QueueSize:      10,
+	}
+}
+
+func TestWebhookSink_SignsRequests(t *testing.T) {
+	received := make(chan bool, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := Sign("s3cret", r.Header.Get(TimestampHeader), body)
+		received <- r.Header.Get(SignatureHeader) == expected
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookSink(newTestWebhookConfig(server.URL), logrus.New())
+	if err := webhook.Start(); err != nil {
+		t.Fatalf("Expected no error starting sink, got: %v", err)
+	}
+	defer func() { _ = webhook.Stop() }()
+
+	webhook.Send(Event{ScannerID: "test", Barcode: "12345", Timestamp: time.Now()})
+
+	select {
+	case valid := <-received:
+		if !valid {
+			t.Error("Expected request signature to be valid")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected webhook to be called")
+	}
+}
+
+func TestWebhookSink_RetriesOnFailure(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookSink(newTestWebhookConfig(server.URL), logrus.New())
+
+	if err := webhook.deliverWithRetry(Event{ScannerID: "test", Barcode: "12345"}); err != nil {
+		t.Errorf("Expected delivery to succeed after retries, got: %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestWebhookSink_DeadLetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := newTestWebhookConfig(server.URL)
+	cfg.DeadLetterFile = filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	webhook := NewWebhookSink(cfg, logrus.New())
+
+	event := Event{ScannerID: "test", Barcode: "12345"}
+	if err := webhook.deliverWithRetry(event); err == nil {
+		t.Fatal("Expected delivery to fail")
+	} else {
+		webhook.deadLetter(event, err)
+	}
+
+	data, err := os.ReadFile(cfg.DeadLetterFile)
+	if err != nil {
+		t.Fatalf("Expected dead-letter file to be written, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 dead-letter record, got %d", len(lines))
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected valid JSON record, got: %v", err)
+	}
+	if record["sink"] != "test" {
+		t.Errorf("Expected sink name 'test', got %v", record["sink"])
+	}
+}
+
+func TestManager_DispatchFiltersScanners(t *testing.T) {
+	all := &recordingSink{name: "all"}
+	filtered := &recordingSink{name: "filtered"}
+
+	manager := NewManager(logrus.New())
+	manager.Add(all, nil)
+	manager.Add(filtered, []string{"dock"})
+
+	manager.Dispatch(Event{ScannerID: "office", Barcode: "1"})
+	manager.Dispatch(Event{ScannerID: "dock", Barcode: "2"})
+
+	if len(all.events) != 2 {
+		t.Errorf("Expected 2 events for unfiltered sink, got %d", len(all.events))
+	}
+	if len(filtered.events) != 1 || filtered.events[0].ScannerID != "dock" {
+		t.Errorf("Expected only the dock event for filtered sink, got %v", filtered.events)
+	}
+}
+
+func TestManager_AddRemoveWhileRunning(t *testing.T) {
+	manager := NewManager(logrus.New())
+	first := &recordingSink{name: "first"}
+	manager.Add(first, nil)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Expected no error starting manager, got: %v", err)
+	}
+
+	second := &recordingSink{name: "second"}
+	manager.Add(second, nil)
+	if !second.running {
+		t.Error("Expected a sink added to a running manager to be started")
+	}
+
+	manager.Dispatch(Event{ScannerID: "office", Barcode: "1"})
+	if !manager.Remove("first") {
+		t.Error("Expected first sink to be removed")
+	}
+	if first.running {
+		t.Error("Expected a removed sink to be stopped")
+	}
+	if manager.Remove("missing") {
+		t.Error("Expected removing an unknown sink to report false")
+	}
+	manager.Dispatch(Event{ScannerID: "office", Barcode: "2"})
+
+	if len(first.events) != 1 || len(second.events) != 2 {
+		t.Errorf("Expected 1 event for the removed sink and 2 for the added one, got %d and %d", len(first.events), len(second.events))
+	}
+
+	_ = manager.Stop()
+	if second.running {
+		t.Error("Expected sinks to be stopped with the manager")
+	}
+}
+
+func TestManager_Health(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	webhook := NewWebhookSink(newTestWebhookConfig(server.URL), logrus.New())
+	manager := NewManager(logrus.New())
+	manager.Add(webhook, nil)
+	manager.Add(&recordingSink{name: "recording"}, nil)
+	if err := manager.Start(); err != nil {
+		t.Fatalf("Expected no error starting manager, got: %v", err)
+	}
+	defer func() { _ = manager.Stop() }()
+
+	manager.Dispatch(Event{ScannerID: "office", Barcode: "1"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for webhook.Health().Delivered == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	health := manager.Health()
+	if len(health) != 1 {
+		t.Fatalf("Expected health of the webhook sink only, got %v", health)
+	}
+	if health["test"].Delivered != 1 || health["test"].Failed != 0 || health["test"].LastSuccess == nil {
+		t.Errorf("Expected one successful delivery, got %+v", health["test"])
+	}
+}
+
+type recordingSink struct {
+	name    string
+	events  []Event
+	running bool
+}
+
+func (r *recordingSink) Name() string     { return r.name }
+func (r *recordingSink) Start() error     { r.running = true; return nil }
+func (r *recordingSink) Stop() error      { r.running = false; return nil }
+func (r *recordingSink) Send(event Event) { r.events = append(r.events, event) }
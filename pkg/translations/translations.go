@@ -0,0 +1,131 @@
+package translations
+
+import (
+	"embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultLanguage is the language entities are named in when none is configured.
+const DefaultLanguage = "en"
+
+//go:embed *.yaml
+var translationFiles embed.FS
+
+// EntityNames are the names of the entities the bridge publishes, without
+// the scanner device name they are prefixed with.
+type EntityNames struct {
+	Language        string `yaml:"language"`
+	Scanner         string `yaml:"scanner"` // Device name of scanners without a USB product name
+	LastScan        string `yaml:"last_scan"`
+	Health          string `yaml:"health"`
+	Diagnostics     string `yaml:"diagnostics"`
+	Battery         string `yaml:"battery"`
+	Docked          string `yaml:"docked"`
+	Connected       string `yaml:"connected"`
+	Symbology       string `yaml:"symbology"`
+	PantryMode      string `yaml:"pantry_mode"`
+	Scans           string `yaml:"scans"`
+	ScansLastHour   string `yaml:"scans_last_hour"`
+	ReadQuality     string `yaml:"read_quality"`
+	PausePublishing string `yaml:"pause_publishing"`
+	RestartScanners string `yaml:"restart_scanners"`
+	ScannerEnabled  string `yaml:"scanner_enabled"`
+	ScanTimeout     string `yaml:"scan_timeout"`
+	KeyboardLayout  string `yaml:"keyboard_layout"`
+	Uptime          string `yaml:"uptime"`
+	Memory          string `yaml:"memory"`
+	Goroutines      string `yaml:"goroutines"`
+	Update          string `yaml:"update"`
+}
+
+// GetAvailableLanguages returns the codes of the embedded translations.
+func GetAvailableLanguages() ([]string, error) {
+	entries, err := translationFiles.ReadDir(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded translations directory: %w", err)
+	}
+
+	var languages []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		languages = append(languages, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+
+	slices.Sort(languages)
+	return languages, nil
+}
+
+// LoadEntityNames returns the entity names in the given language. Names
+// missing from the translation are taken from English, so a translation
+// lagging behind a new entity still names it.
+func LoadEntityNames(language string) (*EntityNames, error) {
+	names, err := loadFile(DefaultLanguage)
+	if err != nil {
+		return nil, err
+	}
+	if language == "" || language == DefaultLanguage {
+		return names, nil
+	}
+
+	translated, err := loadFile(language)
+	if err != nil {
+		return nil, err
+	}
+	names.merge(translated)
+	return names, nil
+}
+
+func loadFile(language string) (*EntityNames, error) {
+	data, err := translationFiles.ReadFile(language + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("no translation for language '%s'", language)
+	}
+
+	var names EntityNames
+	if err := yaml.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("failed to parse translation %s.yaml: %w", language, err)
+	}
+	return &names, nil
+}
+
+// merge overrides the names set in translated.
+func (n *EntityNames) merge(translated *EntityNames) {
+	fields := []struct {
+		target *string
+		value  string
+	}{
+		{&n.Language, translated.Language},
+		{&n.Scanner, translated.Scanner},
+		{&n.LastScan, translated.LastScan},
+		{&n.Health, translated.Health},
+		{&n.Diagnostics, translated.Diagnostics},
+		{&n.Battery, translated.Battery},
+		{&n.Docked, translated.Docked},
+		{&n.Connected, translated.Connected},
+		{&n.Symbology, translated.Symbology},
+		{&n.PantryMode, translated.PantryMode},
+		{&n.Scans, translated.Scans},
+		{&n.ScansLastHour, translated.ScansLastHour},
+		{&n.ReadQuality, translated.ReadQuality},
+		{&n.PausePublishing, translated.PausePublishing},
+		{&n.RestartScanners, translated.RestartScanners},
+		{&n.ScannerEnabled, translated.ScannerEnabled},
+		{&n.ScanTimeout, translated.ScanTimeout},
+		{&n.KeyboardLayout, translated.KeyboardLayout},
+		{&n.Uptime, translated.Uptime},
+		{&n.Memory, translated.Memory},
+		{&n.Goroutines, translated.Goroutines},
+		{&n.Update, translated.Update},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+}
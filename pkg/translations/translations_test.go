@@ -0,0 +1,81 @@
+package translations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranslationsComplete(t *testing.T) {
+	languages, err := GetAvailableLanguages()
+	if err != nil {
+		t.Fatalf("Expected no error listing languages, got: %v", err)
+	}
+	if len(languages) < 2 {
+		t.Fatalf("Expected several embedded languages, got: %v", languages)
+	}
+
+	for _, language := range languages {
+		t.Run(language, func(t *testing.T) {
+			names, err := loadFile(language)
+			if err != nil {
+				t.Fatalf("Expected no error loading %s, got: %v", language, err)
+			}
+
+			fields := reflect.ValueOf(*names)
+			for i := range fields.NumField() {
+				if fields.Field(i).String() == "" {
+					t.Errorf("Expected %s.yaml to translate %s", language, fields.Type().Field(i).Tag.Get("yaml"))
+				}
+			}
+		})
+	}
+}
+
+func TestLoadEntityNames(t *testing.T) {
+	tests := []struct {
+		language    string
+		health      string
+		expectError bool
+	}{
+		{"", "Health", false},
+		{"en", "Health", false},
+		{"es", "Estado", false},
+		{"de", "Zustand", false},
+		{"xx", "", true},
+		{"../en", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			names, err := LoadEntityNames(tt.language)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for language %q", tt.language)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error for language %q, got: %v", tt.language, err)
+			}
+			if names.Health != tt.health {
+				t.Errorf("Expected health name %q, got %q", tt.health, names.Health)
+			}
+		})
+	}
+}
+
+func TestEntityNamesMerge(t *testing.T) {
+	names, err := LoadEntityNames(DefaultLanguage)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	names.merge(&EntityNames{Health: "Estado"})
+
+	if names.Health != "Estado" {
+		t.Errorf("Expected translated health name, got %q", names.Health)
+	}
+	if names.Battery != "Battery" {
+		t.Errorf("Expected missing names to stay English, got %q", names.Battery)
+	}
+}